@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopack/internal"
+)
+
+var (
+	applyDryRun   bool
+	applyYes      bool
+	applyManifest string
+	applyForce    bool
+	applyPaste    bool
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply <pack> [dir]",
+	Short: "Apply the changes in an edited pack or an LLM's response to the working tree",
+	Long: `Apply compares each file in a pack (typically one an LLM edited) with the
+working tree under dir (the current directory by default), shows a unified
+diff for every new or changed file, and asks before writing each one.
+Files missing from the pack are left untouched. Use "-" to read the pack
+from stdin.
+
+Instead of a pack, the input may be an LLM's response: Markdown whose code
+blocks hold whole files, named in the block's info string, on the line
+before it, or in a comment on its first line, or unified diffs. With
+--from-clipboard, the response is read from the system clipboard and the
+only argument is the optional dir.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if applyPaste {
+			return cobra.MaximumNArgs(1)(cmd, args)
+		}
+		return cobra.RangeArgs(1, 2)(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		source, dir := "", "."
+		if applyPaste {
+			source = "the clipboard"
+			if len(args) == 1 {
+				dir = args[0]
+			}
+		} else {
+			source = args[0]
+			if len(args) == 2 {
+				dir = args[1]
+			}
+		}
+		files, err := readResponse(source, dir)
+		if err != nil {
+			return err
+		}
+
+		// Refuse to apply edits made against a different version of the tree
+		if applyManifest != "" && !applyForce {
+			baseline, err := internal.ReadManifest(applyManifest)
+			if err != nil {
+				return fmt.Errorf("failed to read manifest: %w", err)
+			}
+			changed, err := baseline.Changed(dir)
+			if err != nil {
+				return fmt.Errorf("failed to check manifest: %w", err)
+			}
+			if len(changed) > 0 {
+				return fmt.Errorf("files changed since the pack was made: %s (use --force to apply anyway)", strings.Join(changed, ", "))
+			}
+		}
+
+		changes, err := packChanges(files, dir)
+		if err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			statusf("No changes to apply.\n")
+			return nil
+		}
+
+		// Prompts are read from the terminal when the pack comes from stdin
+		prompt := bufio.NewReader(os.Stdin)
+		if source == "-" && !applyYes && !applyDryRun {
+			tty, err := os.Open("/dev/tty")
+			if err != nil {
+				return fmt.Errorf("can't ask for confirmation with the pack on stdin; use --yes or --dry-run")
+			}
+			defer tty.Close()
+			prompt = bufio.NewReader(tty)
+		}
+
+		var applied int
+		for _, change := range changes {
+			if applyDryRun {
+				fmt.Print(change.diff)
+				continue
+			}
+			content := change.content
+			var quit bool
+			if !applyYes {
+				// Review the changes hunk by hunk, like "git add -p"
+				var accepted []internal.Hunk
+				accepted, quit = reviewHunks(prompt, change)
+				content = internal.MergeHunks(change.old, accepted)
+				if len(accepted) == 0 {
+					content = nil
+				}
+			} else {
+				fmt.Print(change.diff)
+			}
+
+			if content != nil {
+				if err := writeChange(change.path, content, change.mode); err != nil {
+					return err
+				}
+				applied++
+			}
+			if quit {
+				statusf("Applied changes to %d of %d changed files.\n", applied, len(changes))
+				return nil
+			}
+		}
+
+		if applyDryRun {
+			statusf("%d files would change (dry run).\n", len(changes))
+		} else {
+			statusf("Done! Applied changes to %d of %d changed files.\n", applied, len(changes))
+		}
+		return nil
+	},
+}
+
+// readResponse reads a pack or an LLM's response from source, a file name,
+// "-" for stdin, or "the clipboard", and extracts its files. Diffs in a
+// response are applied to the files under dir.
+func readResponse(source, dir string) ([]internal.File, error) {
+	var text string
+	if applyPaste {
+		pasted, err := pasteText()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the clipboard: %w", err)
+		}
+		text = pasted
+	} else {
+		data, err := readPackData(source)
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
+	}
+
+	read := func(path string) ([]byte, error) {
+		return os.ReadFile(filepath.Join(dir, filepath.FromSlash(path)))
+	}
+	files, unlabeled, err := internal.ParseResponse(text, read)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", source, err)
+	}
+	if unlabeled > 0 {
+		warnf("Ignored %d code blocks that don't name a file", unlabeled)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files or diffs found in %s", source)
+	}
+	return files, nil
+}
+
+// packChange is a file whose content in a pack differs from disk.
+type packChange struct {
+	path    string
+	name    string // path in the pack
+	old     []byte // content on disk; nil for a new file
+	content []byte
+	mode    os.FileMode // from a pack made with --file-modes, or 0
+	diff    string
+}
+
+// hunkHelp explains the answers to reviewHunks' prompt.
+const hunkHelp = `y - apply this hunk
+n - don't apply this hunk
+e - edit this hunk, then apply it
+a - apply this and the remaining hunks in the file
+d - don't apply this or the remaining hunks in the file
+q - quit; don't apply this or any remaining hunks
+? - show this help
+`
+
+// reviewHunks shows each hunk of a change and asks whether to apply it,
+// returning the accepted hunks and whether the user chose to quit.
+func reviewHunks(prompt *bufio.Reader, change packChange) (accepted []internal.Hunk, quit bool) {
+	header, _, _ := strings.Cut(change.diff, "@@")
+	fmt.Print(header)
+
+	hunks := internal.DiffHunks(change.old, change.content)
+	for i := 0; i < len(hunks); i++ {
+		hunk := hunks[i]
+		fmt.Print(hunk)
+		fmt.Fprintf(os.Stderr, "(%d/%d) Apply this hunk to %s [y,n,e,a,d,q,?]? ", i+1, len(hunks), change.name)
+		answer, err := prompt.ReadString('\n')
+		if err != nil && answer == "" {
+			fmt.Fprintln(os.Stderr)
+			return accepted, true
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			accepted = append(accepted, hunk)
+		case "n", "no":
+		case "e", "edit":
+			edited, err := editHunk(hunk)
+			if err != nil {
+				warnf("%v", err)
+				i-- // ask again
+				continue
+			}
+			accepted = append(accepted, edited)
+		case "a":
+			return append(accepted, hunks[i:]...), false
+		case "d":
+			return accepted, false
+		case "q", "quit":
+			return accepted, true
+		default:
+			fmt.Fprint(os.Stderr, hunkHelp)
+			i--
+		}
+	}
+	return accepted, false
+}
+
+// editHunk opens a hunk in the user's editor and returns the edited hunk.
+func editHunk(hunk internal.Hunk) (internal.Hunk, error) {
+	tmp, err := os.CreateTemp("", "gopack-hunk-*.diff")
+	if err != nil {
+		return hunk, err
+	}
+	defer os.Remove(tmp.Name())
+
+	text := hunk.String() + `# Edit the hunk, then save and quit.
+# To skip an added ('+') line, delete it. To keep a removed ('-') line,
+# replace its '-' with a space. Lines starting with # are ignored.
+`
+	if _, err := tmp.WriteString(text); err != nil {
+		tmp.Close()
+		return hunk, err
+	}
+	if err := tmp.Close(); err != nil {
+		return hunk, err
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	// The user takes as long as they need, whatever --timeout says
+	cmd := shellCommand(context.Background(), editor+" "+internal.ShellQuote(tmp.Name()))
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stderr, os.Stderr
+	if tty, err := os.Open("/dev/tty"); err == nil {
+		defer tty.Close()
+		cmd.Stdin = tty
+	}
+	if err := cmd.Run(); err != nil {
+		return hunk, fmt.Errorf("editor failed: %w", err)
+	}
+
+	data, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return hunk, err
+	}
+	return hunk.Edit(string(data))
+}
+
+// writeChange writes the new content of a changed file, with the given
+// permission bits unless mode is 0.
+func writeChange(path string, content []byte, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if mode != 0 {
+		if err := os.Chmod(path, mode); err != nil {
+			return fmt.Errorf("failed to set file mode: %w", err)
+		}
+	}
+	return nil
+}
+
+// packChanges compares the files in a pack with those under dir and returns
+// the new and changed ones.
+func packChanges(files []internal.File, dir string) ([]packChange, error) {
+	var changes []packChange
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file.Path))
+		old, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		oldName := "a/" + file.Path
+		if os.IsNotExist(err) {
+			oldName = "/dev/null"
+		} else if internal.SameContent(old, file.Content) {
+			continue
+		}
+
+		changes = append(changes, packChange{
+			path:    path,
+			name:    file.Path,
+			old:     old,
+			content: file.Content,
+			mode:    file.Mode,
+			diff:    internal.UnifiedDiff(oldName, "b/"+file.Path, old, file.Content),
+		})
+	}
+	return changes, nil
+}
+
+func init() {
+	addIdentityFlag(applyCmd)
+	applyCmd.Flags().BoolVar(&applyDryRun, "dry-run", false, "Show the diffs without changing any files")
+	applyCmd.Flags().BoolVarP(&applyYes, "yes", "y", false, "Apply every change without asking")
+	applyCmd.Flags().StringVar(&applyManifest, "manifest", "", "Check the working tree against the manifest written with the original pack first")
+	applyCmd.Flags().BoolVar(&applyPaste, "from-clipboard", false, "Read the pack or LLM response from the system clipboard")
+	applyCmd.Flags().BoolVar(&applyForce, "force", false, "Apply even if the tree no longer matches --manifest")
+	rootCmd.AddCommand(applyCmd)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gopack/internal"
+)
+
+func TestReadResponse(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nvar x = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	response := filepath.Join(t.TempDir(), "answer.md")
+	answer := "Change `main.go`:\n\n```diff\n--- a/main.go\n+++ b/main.go\n@@ -3 +3 @@\n-var x = 1\n+var x = 2\n```\n\nAnd add:\n\n```go util/u.go\npackage util\n```\n"
+	if err := os.WriteFile(response, []byte(answer), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := readResponse(response, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Path != "main.go" || string(files[0].Content) != "package main\n\nvar x = 2\n" || files[1].Path != "util/u.go" {
+		t.Errorf("readResponse() = %+v, want the patched main.go and util/u.go", files)
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty.md")
+	if err := os.WriteFile(empty, []byte("No changes needed.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readResponse(empty, dir); err == nil {
+		t.Error("readResponse() of a response without files succeeded, want error")
+	}
+}
+
+func TestReviewHunks(t *testing.T) {
+	var old, edited strings.Builder
+	for i := 1; i <= 20; i++ {
+		fmt.Fprintf(&old, "line %d\n", i)
+		if i == 2 || i == 10 || i == 18 {
+			fmt.Fprintf(&edited, "LINE %d\n", i)
+		} else {
+			fmt.Fprintf(&edited, "line %d\n", i)
+		}
+	}
+	change := packChange{name: "a.txt", old: []byte(old.String()), content: []byte(edited.String())}
+	change.diff = internal.UnifiedDiff("a/a.txt", "b/a.txt", change.old, change.content)
+	if n := len(internal.DiffHunks(change.old, change.content)); n != 3 {
+		t.Fatalf("test change has %d hunks, want 3", n)
+	}
+
+	tests := []struct {
+		answers string
+		changed []int // lines changed in the result
+		quit    bool
+	}{
+		{"y\nn\ny\n", []int{2, 18}, false},
+		{"?\nn\na\n", []int{10, 18}, false},
+		{"y\nd\n", []int{2}, false},
+		{"n\nq\n", nil, true},
+		{"y\n", []int{2}, true}, // input ends
+	}
+	for _, tt := range tests {
+		accepted, quit := reviewHunks(bufio.NewReader(strings.NewReader(tt.answers)), change)
+		got := string(internal.MergeHunks(change.old, accepted))
+		want := old.String()
+		for _, n := range tt.changed {
+			want = strings.Replace(want, fmt.Sprintf("line %d\n", n), fmt.Sprintf("LINE %d\n", n), 1)
+		}
+		if got != want || quit != tt.quit {
+			t.Errorf("reviewHunks(%q) = %q, quit %v; want %q, quit %v", tt.answers, got, quit, want, tt.quit)
+		}
+	}
+
+	// Editing a hunk in $VISUAL
+	if runtime.GOOS == "windows" {
+		return
+	}
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("VISUAL", "sed -i.bak 's/^+LINE 2$/+Line two/'")
+	accepted, _ := reviewHunks(bufio.NewReader(strings.NewReader("e\nn\nn\n")), change)
+	if got := string(internal.MergeHunks(change.old, accepted)); !strings.Contains(got, "line 1\nLine two\nline 3\n") {
+		t.Errorf("reviewHunks() with an edited hunk = %q", got)
+	}
+}
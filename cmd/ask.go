@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopack/internal"
+)
+
+var (
+	askProvider string
+	askModel    string
+	askBaseURL  string
+)
+
+var askCmd = &cobra.Command{
+	Use:   "ask <question> [path...]",
+	Short: "Pack the tree and ask an LLM a question about it",
+	Long: `Ask packs the given paths (the current directory by default) with the
+usual filters, puts the question in front of the pack, sends it to an LLM,
+and streams the answer to stdout.
+
+The provider defaults to Anthropic if ANTHROPIC_API_KEY is set, then OpenAI
+if OPENAI_API_KEY is set, and otherwise a local Ollama server.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		question, paths := args[0], args[1:]
+
+		walker, extras, err := newWalker(cmd.Context(), paths)
+		if err != nil {
+			return err
+		}
+		files, err := collectFiles(cmd.Context(), walker, extras)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			return withExitCode(exitNoFiles, fmt.Errorf("no files to ask about"))
+		}
+
+		prompt := askPrompt(question, internal.NewFormatter(files).Format())
+		return askLLM(cmd.Context(), prompt, len(files), os.Stdout)
+	},
+}
+
+// askPrompt puts a question in front of the pack it is about.
+func askPrompt(question, pack string) string {
+	return strings.TrimSpace(question) + "\n\nThe code is below.\n\n" + pack
+}
+
+// askProviderName returns the provider --provider names, or the default.
+func askProviderName() string {
+	if askProvider != "" {
+		return askProvider
+	}
+	return internal.DefaultProvider()
+}
+
+// askLLM sends a prompt about a pack of n files to the provider, streaming
+// the answer to w until it ends or the user interrupts.
+func askLLM(ctx context.Context, prompt string, n int, w io.Writer) error {
+	provider := askProviderName()
+	statusf("Asking %s about %d files (~%s tokens)...\n",
+		provider, n, internal.FormatWithCommas(internal.EstimateTokens(prompt)))
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	err := internal.Ask(ctx, internal.AskRequest{
+		Provider: provider,
+		Model:    askModel,
+		BaseURL:  askBaseURL,
+		Prompt:   prompt,
+	}, w)
+	fmt.Fprintln(w)
+	return err
+}
+
+func init() {
+	askCmd.Flags().StringVar(&askProvider, "provider", "", "LLM provider: "+strings.Join(internal.Providers, "|")+" (default: from API keys in the environment)")
+	completeValues(askCmd, "provider", internal.Providers)
+	askCmd.Flags().StringVar(&askModel, "model", "", "Model to ask (default: the provider's default, e.g. gpt-4o)")
+	askCmd.Flags().StringVar(&askBaseURL, "base-url", "", "API base URL, for proxies and compatible servers (e.g. http://localhost:11434 for Ollama)")
+	addFilterFlags(askCmd)
+	rootCmd.AddCommand(askCmd)
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopack/internal"
+)
+
+// ciReport is what --ci reports about a pack to GitHub Actions.
+type ciReport struct {
+	Root      string // the packed tree, which the paths are relative to
+	Files     int
+	Tokens    int
+	Bytes     int
+	Limit     int    // the warning threshold, 0 for none
+	LimitName string // what Limit is, as in "the gpt-4o context window"
+	MaxTokens int    // --max-tokens, 0 for none
+	Dropped   []skippedPath
+	Secrets   []internal.Redaction
+	Redacted  bool // whether Secrets were redacted or packed as they are
+}
+
+// ciAnnotations returns the pack's workflow commands, one per line: a
+// notice with the totals (a warning over the threshold, an error over
+// --max-tokens), a warning for each file dropped after the walk, and one
+// for each secret packed. Redacted secrets are only noticed. The secrets
+// themselves are never repeated.
+func ciAnnotations(r ciReport) string {
+	var b strings.Builder
+	totals := fmt.Sprintf("Packed %d files, ~%s tokens (%s)", r.Files,
+		internal.FormatWithCommas(r.Tokens), internal.FormatBytes(int64(r.Bytes)))
+	switch {
+	case r.MaxTokens > 0 && r.Tokens > r.MaxTokens:
+		b.WriteString(ciCommand("error", "", 0, "Pack over --max-tokens", fmt.Sprintf("%s, over --max-tokens (%s)", totals, internal.FormatWithCommas(r.MaxTokens))))
+	case r.Limit > 0 && r.Tokens > r.Limit:
+		b.WriteString(ciCommand("warning", "", 0, "Pack over the token limit", fmt.Sprintf("%s, over %s (%s)", totals, r.LimitName, internal.FormatWithCommas(r.Limit))))
+	default:
+		b.WriteString(ciCommand("notice", "", 0, "gopack", totals))
+	}
+	for _, dropped := range r.Dropped {
+		b.WriteString(ciCommand("warning", r.path(dropped.Path), 0, "Dropped from pack", dropped.Reason))
+	}
+	for _, secret := range r.Secrets {
+		if r.Redacted {
+			b.WriteString(ciCommand("notice", r.path(secret.Path), secret.Line, "Secret redacted", "Redacted a "+secret.Rule+" from the pack"))
+		} else {
+			b.WriteString(ciCommand("warning", r.path(secret.Path), secret.Line, "Secret packed", "Possible "+secret.Rule+" packed as it is; use --redact to hide it"))
+		}
+	}
+	return b.String()
+}
+
+// path returns a path in the pack relative to the working directory, the
+// checkout in a workflow, which is what annotations are placed by.
+func (r ciReport) path(path string) string {
+	if r.Root == "" {
+		return path
+	}
+	abs, err := filepath.Abs(filepath.Join(r.Root, path))
+	if err != nil {
+		return path
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, abs)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// ciCommand formats a GitHub Actions workflow command such as
+// "::warning file=a.go,line=3,title=T::message".
+func ciCommand(level, file string, line int, title, message string) string {
+	var props []string
+	if file != "" {
+		props = append(props, "file="+escapeCIProperty(file))
+	}
+	if line > 0 {
+		props = append(props, fmt.Sprintf("line=%d", line))
+	}
+	if title != "" {
+		props = append(props, "title="+escapeCIProperty(title))
+	}
+	command := "::" + level
+	if len(props) > 0 {
+		command += " " + strings.Join(props, ",")
+	}
+	return command + "::" + escapeCIData(message) + "\n"
+}
+
+// escapeCIData escapes a workflow command's message.
+func escapeCIData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeCIProperty escapes a workflow command's property value.
+func escapeCIProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// ciSummary renders the job summary: a table of the totals, then the files
+// dropped after the walk and the secrets found, if any.
+func ciSummary(r ciReport) string {
+	var b strings.Builder
+	b.WriteString("### gopack\n\n")
+	b.WriteString("| Files | Tokens | Size | Dropped | Secrets |\n")
+	b.WriteString("| ---: | ---: | ---: | ---: | ---: |\n")
+	secrets := fmt.Sprint(len(r.Secrets))
+	if r.Redacted && len(r.Secrets) > 0 {
+		secrets += " (redacted)"
+	}
+	fmt.Fprintf(&b, "| %d | ~%s | %s | %d | %s |\n", r.Files, internal.FormatWithCommas(r.Tokens),
+		internal.FormatBytes(int64(r.Bytes)), len(r.Dropped), secrets)
+	if r.MaxTokens > 0 {
+		fmt.Fprintf(&b, "\nBudget: ~%s of %s tokens (--max-tokens)\n", internal.FormatWithCommas(r.Tokens), internal.FormatWithCommas(r.MaxTokens))
+	}
+	if len(r.Dropped) > 0 {
+		b.WriteString("\n| Dropped file | Reason |\n| --- | --- |\n")
+		for _, dropped := range r.Dropped {
+			fmt.Fprintf(&b, "| `%s` | %s |\n", r.path(dropped.Path), strings.ReplaceAll(dropped.Reason, "|", `\|`))
+		}
+	}
+	if len(r.Secrets) > 0 {
+		b.WriteString("\n| Secret | Rule |\n| --- | --- |\n")
+		for _, secret := range r.Secrets {
+			fmt.Fprintf(&b, "| `%s:%d` | %s |\n", r.path(secret.Path), secret.Line, secret.Rule)
+		}
+	}
+	return b.String()
+}
+
+// reportCI writes the annotations to stderr, where the Actions runner reads
+// them as it does stdout, keeping stdout for the pack. The job summary is
+// appended to $GITHUB_STEP_SUMMARY when the job has one.
+func reportCI(r ciReport) error {
+	fmt.Fprint(os.Stderr, ciAnnotations(r))
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write the job summary: %w", err)
+	}
+	if _, err := f.WriteString(ciSummary(r) + "\n"); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write the job summary: %w", err)
+	}
+	return f.Close()
+}
+
+// droppedAfterWalk returns the paths a skip report lists as taken out
+// after the walk, by a plugin, --collapse-dirs, the budget, or --fit; the
+// walk's own skips are mostly ignored files no one expected in the pack,
+// and --since-manifest leaves out files the model has already seen.
+func droppedAfterWalk(report *skipReport) []skippedPath {
+	var dropped []skippedPath
+	for _, skipped := range report.Skipped {
+		if skipped.Stage != stageWalk && skipped.Stage != stageManifest {
+			dropped = append(dropped, skipped)
+		}
+	}
+	return dropped
+}
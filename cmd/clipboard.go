@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"gopack/internal"
+)
+
+// Copy targets accepted by --copy-to.
+const (
+	copyToClipboard = "clipboard"
+	copyToOSC52     = "osc52"
+	copyToTmux      = "tmux"
+)
+
+// copyTargets lists the values accepted by --copy-to.
+var copyTargets = []string{copyToClipboard, copyToOSC52, copyToTmux}
+
+// copyTargetLimits are the largest texts, in bytes, that copy targets are
+// known to take. Many terminals drop OSC 52 sequences over 100,000 bytes,
+// which leaves room for 74,994 bytes of text once base64-encoded.
+var copyTargetLimits = map[string]int64{copyToOSC52: 74_994}
+
+// copyLimitFor returns the size above which copying to the --copy-to
+// target needs confirmation, and what sets it: --copy-limit or the
+// target's own limit, whichever is lower. Zero means no limit.
+func copyLimitFor(target string, copyLimit int64) (int64, string) {
+	if limit, ok := copyTargetLimits[target]; ok && (copyLimit == 0 || limit < copyLimit) {
+		return limit, target + " limit"
+	}
+	return copyLimit, "--copy-limit"
+}
+
+// checkCopySize asks for confirmation on the terminal before copying a
+// pack larger than the copy limit, which can freeze the desktop or be
+// dropped by the terminal. Without a terminal to ask on, or if the answer
+// is no, it fails, suggesting other ways to get the pack across.
+func checkCopySize(size int64) error {
+	limit, source := copyLimitFor(copyTo, copyLimitBytes)
+	if limit == 0 || size <= limit {
+		return nil
+	}
+	problem := fmt.Sprintf("output is %s, over the %s of %s", internal.FormatBytes(size), source, internal.FormatBytes(limit))
+	refused := withExitCode(exitTooLarge, fmt.Errorf("%s; use --output to write it to a file, or --chunk-tokens to copy it in parts", problem))
+
+	if confirm(fmt.Sprintf("⚠ Warning: The %s. Copy it anyway?", problem)) {
+		return nil
+	}
+	return refused
+}
+
+// copyText copies text to the --copy-to target and returns a description of
+// where it went. For the system clipboard under SSH, where there is usually
+// no clipboard to talk to, it falls back to asking the local terminal to set
+// its clipboard via OSC 52.
+func copyText(text string) (string, error) {
+	switch copyTo {
+	case copyToOSC52:
+		return "terminal clipboard (OSC 52)", writeOSC52(text)
+	case copyToTmux:
+		return "tmux paste buffer", pipeTo(strings.NewReader(text), "tmux", "load-buffer", "-")
+	}
+
+	err := clipboard.WriteAll(text)
+	if err == nil {
+		return "clipboard", nil
+	}
+
+	// Backends the clipboard library misses
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		if pipeErr := pipeTo(strings.NewReader(text), "wl-copy"); pipeErr == nil {
+			return "clipboard", nil
+		}
+	}
+	if isWSL() {
+		// clip.exe reads the console code page unless given UTF-16
+		if pipeErr := pipeTo(bytes.NewReader(internal.UTF16LE(text)), "clip.exe"); pipeErr == nil {
+			return "clipboard", nil
+		}
+	}
+
+	if os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != "" {
+		if osc52Err := writeOSC52(text); osc52Err == nil {
+			return "terminal clipboard (OSC 52)", nil
+		}
+	}
+	return "", err
+}
+
+// pasteText returns the text on the system clipboard.
+func pasteText() (string, error) {
+	text, err := clipboard.ReadAll()
+	if err == nil {
+		return text, nil
+	}
+
+	// Backends the clipboard library misses
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		if out, pasteErr := exec.Command("wl-paste", "--no-newline").Output(); pasteErr == nil {
+			return string(out), nil
+		}
+	}
+	if isWSL() {
+		if out, pasteErr := exec.Command("powershell.exe", "-NoProfile", "-Command", "Get-Clipboard -Raw").Output(); pasteErr == nil {
+			return strings.ReplaceAll(string(out), "\r\n", "\n"), nil
+		}
+	}
+	return "", err
+}
+
+// isWSL reports whether gopack is running under Windows Subsystem for Linux.
+func isWSL() bool {
+	if os.Getenv("WSL_DISTRO_NAME") != "" {
+		return true
+	}
+	version, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	return err == nil && strings.Contains(strings.ToLower(string(version)), "microsoft")
+}
+
+// pipeTo runs a command with input on its standard input.
+func pipeTo(input io.Reader, name string, args ...string) error {
+	if _, err := exec.LookPath(name); err != nil {
+		return err
+	}
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = input
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s: %s", name, msg)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// writeOSC52 sends text to the terminal's clipboard with an OSC 52 escape
+// sequence, written to the controlling terminal so it isn't mixed into
+// redirected output.
+func writeOSC52(text string) error {
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("OSC 52 needs a terminal: %w", err)
+	}
+	defer tty.Close()
+
+	_, err = tty.WriteString(internal.OSC52(text, os.Getenv("TMUX") != ""))
+	return err
+}
+
+// copyInParts splits the files into parts of at most maxTokens and copies
+// them to the clipboard one at a time, waiting for Enter between parts so
+// each can be pasted before the next replaces it. Each part is formatted
+// as the output flags have it, with the instructions, threads, and license
+// headers only in the first.
+func copyInParts(ctx context.Context, files []internal.File, notes []string, maxTokens int) error {
+	parts := internal.AddOverlap(internal.SplitFiles(files, maxTokens), overlap)
+	stdin := bufio.NewReader(os.Stdin)
+
+	for i, part := range parts {
+		formatter := newFormatter(formatFlag, part, notes)
+		formatter.Part, formatter.Parts = i+1, len(parts)
+		if i > 0 {
+			formatter.Instructions = ""
+			formatter.Threads = nil
+			formatter.LicenseHeaders = nil
+		}
+		output, err := formatter.FormatContext(ctx)
+		if err != nil {
+			return err
+		}
+
+		if _, err := copyText(output); err != nil {
+			return fmt.Errorf("failed to copy part %d/%d to clipboard: %w", i+1, len(parts), err)
+		}
+
+		count := internal.EstimateTokens
+		if formatter.Tokenizer != nil {
+			count = formatter.Tokenizer
+		}
+		tokens := internal.FormatWithCommas(count(output))
+		if i == len(parts)-1 {
+			fmt.Fprintf(os.Stderr, "Copied part %d/%d (~%s tokens).\n", i+1, len(parts), tokens)
+			break
+		}
+		fmt.Fprintf(os.Stderr, "Copied part %d/%d (~%s tokens). Paste it, then press Enter to copy part %d/%d...", i+1, len(parts), tokens, i+2, len(parts))
+		if _, err := stdin.ReadString('\n'); err != nil {
+			fmt.Fprintln(os.Stderr)
+			return fmt.Errorf("stopped after part %d/%d: %w", i+1, len(parts), err)
+		}
+	}
+
+	statusf("Done! Context packed to clipboard in %d parts.\n", len(parts))
+	return nil
+}
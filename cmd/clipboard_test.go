@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"gopack/internal"
+)
+
+func TestCopyLimitFor(t *testing.T) {
+	tests := []struct {
+		target     string
+		copyLimit  int64
+		wantLimit  int64
+		wantSource string
+	}{
+		{copyToClipboard, 10 << 20, 10 << 20, "--copy-limit"},
+		{copyToClipboard, 0, 0, "--copy-limit"},
+		{copyToTmux, 1 << 20, 1 << 20, "--copy-limit"},
+		{copyToOSC52, 10 << 20, 74_994, "osc52 limit"},
+		{copyToOSC52, 0, 74_994, "osc52 limit"},
+		{copyToOSC52, 50_000, 50_000, "--copy-limit"},
+	}
+	for _, tt := range tests {
+		limit, source := copyLimitFor(tt.target, tt.copyLimit)
+		if limit != tt.wantLimit || source != tt.wantSource {
+			t.Errorf("copyLimitFor(%s, %d) = %d, %q; want %d, %q", tt.target, tt.copyLimit, limit, source, tt.wantLimit, tt.wantSource)
+		}
+	}
+}
+
+func TestNewFormatter(t *testing.T) {
+	defer func(format string, meta *internal.FrontMatter, weighted bool) {
+		formatFlag, frontMatter, estimateWeighted = format, meta, weighted
+	}(formatFlag, frontMatter, estimateWeighted)
+	formatFlag = internal.FormatMarkdown
+	frontMatter = &internal.FrontMatter{Repo: "github.com/acme/widget"}
+	estimateWeighted = true
+
+	// Parts copied with --chunk-size are formatted like the whole pack
+	part := []internal.File{{Path: "main.go", Content: []byte("package main\n")}}
+	formatter := newFormatter(formatFlag, part, nil)
+	formatter.Part, formatter.Parts = 1, 2
+	if formatter.Tokenizer == nil {
+		t.Error("newFormatter() has no tokenizer with --estimate-weighted")
+	}
+	if output := formatter.Format(); !strings.HasPrefix(output, "---\n") || !strings.Contains(output, "github.com/acme/widget") {
+		t.Errorf("part output has no front matter:\n%s", output)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopack/internal"
+)
+
+// completeValues completes a flag with a fixed list of values, each
+// optionally followed by a tab and a description.
+func completeValues(cmd *cobra.Command, flag string, values []string) {
+	cmd.RegisterFlagCompletionFunc(flag, func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var matches []string
+		for _, value := range values {
+			if strings.HasPrefix(value, toComplete) {
+				matches = append(matches, value)
+			}
+		}
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// completePresets completes --preset with the built-in presets and those
+// in the configuration of the tree being packed, described.
+func completePresets(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	// A broken configuration still completes the built-in presets
+	config, _ := localConfig(args)
+	var matches []string
+	for _, name := range internal.PresetNames(config) {
+		if !strings.HasPrefix(name, toComplete) {
+			continue
+		}
+		preset, _ := internal.LookupPreset(name, config)
+		if preset.Description != "" {
+			name += "\t" + preset.Description
+		}
+		matches = append(matches, name)
+	}
+	return matches, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"gopack/internal"
+)
+
+func TestCompletePresets(t *testing.T) {
+	dir := t.TempDir()
+	config := `{"presets": {"mine": {"description": "My flags", "flags": {"summary": true}}, "minimal": {"flags": {}}}}`
+	if err := os.WriteFile(filepath.Join(dir, internal.ConfigFile), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The tree's own presets are completed, with the built-in ones
+	got, directive := completePresets(rootCmd, []string{dir}, "")
+	if directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("completePresets() directive = %v, want no file completion", directive)
+	}
+	for _, want := range []string{"mine\tMy flags", "minimal", "docs\t" + internal.Presets["docs"].Description} {
+		if !slices.Contains(got, want) {
+			t.Errorf("completePresets() = %q, missing %q", got, want)
+		}
+	}
+
+	got, _ = completePresets(rootCmd, []string{dir}, "min")
+	if want := []string{"mine\tMy flags", "minimal"}; !slices.Equal(got, want) {
+		t.Errorf("completePresets(min) = %q, want %q", got, want)
+	}
+
+	// Elsewhere, only the built-in presets are offered
+	got, _ = completePresets(rootCmd, []string{t.TempDir()}, "")
+	if len(got) != len(internal.Presets) {
+		t.Errorf("completePresets() without a config = %q, want the %d built-in presets", got, len(internal.Presets))
+	}
+}
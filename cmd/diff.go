@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"gopack/internal"
+)
+
+var diffNameStatus bool
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <old> [new]",
+	Short: "Compare two packs, or a pack with the working tree",
+	Long: `Diff compares two packs and prints a unified diff for every added,
+removed, or changed file, followed by a summary on stderr.
+
+Either argument may be a directory instead of a pack; new defaults to the
+current directory. A directory side contains the files named in the other
+pack, as they are on disk now, so "gopack diff context.md" shows what has
+changed in the tree since the pack was made.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		oldName, newName := args[0], "."
+		if len(args) == 2 {
+			newName = args[1]
+		}
+		if isDir(oldName) && isDir(newName) {
+			return fmt.Errorf("at least one side of the diff must be a pack")
+		}
+
+		var old, new []internal.File
+		var err error
+		if !isDir(oldName) {
+			if old, err = readPack(oldName); err != nil {
+				return err
+			}
+		}
+		if !isDir(newName) {
+			if new, err = readPack(newName); err != nil {
+				return err
+			}
+		}
+		if isDir(oldName) {
+			if old, err = readTree(oldName, new); err != nil {
+				return err
+			}
+		}
+		if isDir(newName) {
+			if new, err = readTree(newName, old); err != nil {
+				return err
+			}
+		}
+
+		counts := make(map[string]int)
+		for _, change := range internal.CompareFiles(old, new) {
+			counts[change.Kind]++
+			if diffNameStatus {
+				fmt.Printf("%s\t%s\n", nameStatus[change.Kind], change.Path)
+				continue
+			}
+
+			oldLabel, newLabel := "a/"+change.Path, "b/"+change.Path
+			switch change.Kind {
+			case internal.Added:
+				oldLabel = "/dev/null"
+			case internal.Removed:
+				newLabel = "/dev/null"
+			}
+			fmt.Print(internal.UnifiedDiff(oldLabel, newLabel, change.Old, change.New))
+		}
+
+		statusf("%d added, %d removed, %d changed\n",
+			counts[internal.Added], counts[internal.Removed], counts[internal.Changed])
+		return nil
+	},
+}
+
+// nameStatus is the --name-status letter for each kind of change, as in
+// git diff.
+var nameStatus = map[string]string{
+	internal.Added:   "A",
+	internal.Removed: "D",
+	internal.Changed: "M",
+}
+
+// isDir reports whether name is an existing directory.
+func isDir(name string) bool {
+	info, err := os.Stat(name)
+	return err == nil && info.IsDir()
+}
+
+// readTree reads the files named in a pack from dir. Files that no longer
+// exist are left out, so they show up as removed.
+func readTree(dir string, pack []internal.File) ([]internal.File, error) {
+	var files []internal.File
+	for _, file := range pack {
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file.Path)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		files = append(files, internal.File{Path: file.Path, Content: content})
+	}
+	return files, nil
+}
+
+func init() {
+	addIdentityFlag(diffCmd)
+	diffCmd.Flags().BoolVar(&diffNameStatus, "name-status", false, "Only list each file with A (added), D (removed), or M (changed)")
+	rootCmd.AddCommand(diffCmd)
+}
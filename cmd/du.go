@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopack/internal"
+)
+
+var (
+	duLevels int
+	duJSON   bool
+)
+
+var duCmd = &cobra.Command{
+	Use:   "du [path...]",
+	Short: "Show the files, bytes, and tokens under each directory",
+	Long: `Du walks the tree using the same filters as packing and prints its
+directories as a tree, each with the number of files, bytes, and estimated
+tokens under it, subdirectories included. Subdirectories are listed
+largest first, so the subtrees worth leaving out, or packing on their own,
+stand out before the full command is run.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if duLevels < 0 {
+			return withExitCode(exitUsage, fmt.Errorf("--levels can't be negative"))
+		}
+		walker, extras, err := newWalker(cmd.Context(), args)
+		if err != nil {
+			return err
+		}
+		files, err := collectFiles(cmd.Context(), walker, extras)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			return withExitCode(exitNoFiles, fmt.Errorf("no files matched"))
+		}
+
+		var dirs []internal.DirUsage
+		for _, dir := range internal.DirectoryUsage(files) {
+			if duLevels == 0 || dir.Depth <= duLevels {
+				dirs = append(dirs, dir)
+			}
+		}
+		if duJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			for _, dir := range dirs {
+				if err := encoder.Encode(dir); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		fmt.Print(formatDirUsage(dirs))
+		return nil
+	},
+}
+
+// formatDirUsage renders directory totals as a table, with each directory
+// indented under its parent and the counts right-aligned.
+func formatDirUsage(dirs []internal.DirUsage) string {
+	table := [][]string{{"Directory", "Files", "Bytes", "Tokens"}}
+	for _, dir := range dirs {
+		name := "."
+		if dir.Path != "." {
+			name = strings.Repeat("  ", dir.Depth) + path.Base(dir.Path) + "/"
+		}
+		table = append(table, []string{
+			name,
+			internal.FormatWithCommas(dir.Files),
+			internal.FormatBytes(int64(dir.Bytes)),
+			internal.FormatWithCommas(dir.Tokens),
+		})
+	}
+	return formatTable(table)
+}
+
+func init() {
+	duCmd.Flags().IntVarP(&duLevels, "levels", "L", 0, "Only show directories up to N levels below the top (0 shows all)")
+	duCmd.Flags().BoolVar(&duJSON, "json", false, "Print the directories as JSON lines")
+	addFilterFlags(duCmd)
+	rootCmd.AddCommand(duCmd)
+}
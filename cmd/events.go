@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// Events written to stderr, one JSON object per line, by --json.
+type (
+	// includeEvent reports a file added to the pack.
+	includeEvent struct {
+		Event       string `json:"event"` // "include"
+		Path        string `json:"path"`
+		Bytes       int    `json:"bytes"`
+		Tokens      int    `json:"tokens"`
+		DuplicateOf string `json:"duplicate_of,omitempty"`
+	}
+
+	// skipEvent reports a file or directory the walker excluded.
+	skipEvent struct {
+		Event  string `json:"event"` // "skip"
+		Path   string `json:"path"`
+		Reason string `json:"reason"`
+	}
+
+	// totalsEvent summarizes the pack once it has been formatted.
+	totalsEvent struct {
+		Event   string `json:"event"` // "totals"
+		Files   int    `json:"files"`
+		Skipped int    `json:"skipped"`
+		Bytes   int    `json:"bytes"`
+		Tokens  int    `json:"tokens"`
+	}
+)
+
+// emitEvent writes an event to stderr as a line of JSON.
+func emitEvent(event any) {
+	// Encoding these structs can't fail
+	json.NewEncoder(os.Stderr).Encode(event)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"gopack/internal"
+)
+
+// runExec runs command through the shell with input on its standard input,
+// forwarding its output to gopack's own. It's killed once ctx is done.
+func runExec(ctx context.Context, command string, input string) error {
+	cmd := shellCommand(ctx, command)
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return commandStopped(ctx, fmt.Errorf("--exec %q failed: %w", command, err))
+	}
+	return nil
+}
+
+// commandWaitDelay is how long a killed command's output is waited for,
+// in case something it started still holds it open.
+const commandWaitDelay = time.Second
+
+// shellCommand returns a command that runs command through the platform's
+// shell, killed once ctx is done, such as when --timeout passes.
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.WaitDelay = commandWaitDelay
+	return cmd
+}
+
+// commandStopped returns why ctx ended, such as --timeout passing, when
+// that's what stopped a command that failed with err, and err otherwise.
+func commandStopped(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return withExitCode(exitCanceled, context.Cause(ctx))
+	}
+	return err
+}
+
+// runCapture runs command through the shell and returns its combined
+// output as a pseudo-file labeled "$ command". A command that fails is
+// still included, with its exit status noted at the end, since failing
+// diagnostics are usually what the pack is for. It's killed once ctx is
+// done.
+func runCapture(ctx context.Context, command string) (internal.File, error) {
+	var output bytes.Buffer
+	cmd := shellCommand(ctx, command)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+	if err != nil && ctx.Err() != nil {
+		return internal.File{}, commandStopped(ctx, err)
+	}
+	if exitErr := (*exec.ExitError)(nil); errors.As(err, &exitErr) {
+		if output.Len() > 0 && !bytes.HasSuffix(output.Bytes(), []byte("\n")) {
+			output.WriteByte('\n')
+		}
+		fmt.Fprintf(&output, "[%s]\n", exitErr)
+	} else if err != nil {
+		return internal.File{}, fmt.Errorf("--run %q failed: %w", command, err)
+	}
+	if output.Len() == 0 {
+		output.WriteString("[no output]\n")
+	}
+	return internal.File{Path: "$ " + command, Content: output.Bytes()}, nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"testing"
+)
+
+func TestRunCapture(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("commands use sh")
+	}
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"echo ok", "ok\n"},
+		{"echo out; echo err >&2", "out\nerr\n"},
+		{"printf partial; exit 2", "partial\n[exit status 2]\n"},
+		{"true", "[no output]\n"},
+		{"exit 1", "[exit status 1]\n"},
+	}
+	for _, tt := range tests {
+		file, err := runCapture(context.Background(), tt.command)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if file.Path != "$ "+tt.command || string(file.Content) != tt.want {
+			t.Errorf("runCapture(%q) = %s %q, want %q", tt.command, file.Path, file.Content, tt.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+)
+
+// Exit codes, so scripts can branch on the outcome without parsing stderr.
+const (
+	exitOK         = 0
+	exitError      = 1 // any other failure
+	exitUsage      = 2 // invalid flags or arguments
+	exitNoFiles    = 3 // no files matched the paths and filters
+	exitTooLarge   = 4 // the pack exceeded --max-tokens, --max-output-bytes, or --max-files
+	exitUnreadable = 5 // a file or directory couldn't be read (--strict)
+	exitCanceled   = 6 // interrupted with Ctrl-C or stopped by --timeout
+)
+
+// codedError is an error that ends the program with a specific exit code.
+type codedError struct {
+	code int
+	err  error
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+func (e *codedError) Unwrap() error { return e.err }
+
+// withExitCode wraps err so that the program exits with code.
+func withExitCode(code int, err error) error {
+	return &codedError{code: code, err: err}
+}
+
+// exitCode returns the exit code for an error returned by a command.
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var coded *codedError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+	return exitError
+}
+
+// interruptibly runs fn with a context that Ctrl-C cancels as well as
+// ctx, for work that stops cleanly when canceled, such as walking, fetching,
+// or formatting. Outside of it Ctrl-C quits as usual, so prompts and
+// commands run later aren't left waiting. If the context ends early, the
+// error says why: --timeout passed or the user pressed Ctrl-C.
+func interruptibly(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	err := fn(ctx)
+	if ctx.Err() == nil {
+		return err
+	}
+	err = context.Cause(ctx)
+	if errors.Is(err, context.Canceled) {
+		err = errors.New("interrupted")
+	}
+	return withExitCode(exitCanceled, err)
+}
+
+// exit prints err, if any, and exits with the matching code.
+func exit(err error) {
+	if stopTimeout != nil {
+		stopTimeout()
+	}
+	if err != nil {
+		logger.Error(err.Error())
+		if exitCode(err) == exitUsage {
+			fmt.Fprintln(os.Stderr, "Run 'gopack --help' for usage.")
+		}
+	}
+	os.Exit(exitCode(err))
+}
+
+// markUsageErrors makes invalid flags and arguments for cmd and its
+// subcommands exit with exitUsage.
+func markUsageErrors(cmd *cobra.Command) {
+	cmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return withExitCode(exitUsage, err)
+	})
+	if validate := cmd.Args; validate != nil {
+		cmd.Args = func(cmd *cobra.Command, args []string) error {
+			if err := validate(cmd, args); err != nil {
+				return withExitCode(exitUsage, err)
+			}
+			return nil
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		markUsageErrors(sub)
+	}
+}
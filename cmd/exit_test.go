@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "success", err: nil, want: exitOK},
+		{name: "plain error", err: errors.New("boom"), want: exitError},
+		{name: "coded", err: withExitCode(exitNoFiles, errors.New("no files matched")), want: exitNoFiles},
+		{name: "wrapped", err: fmt.Errorf("packing: %w", withExitCode(exitTooLarge, errors.New("too big"))), want: exitTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInterruptibly(t *testing.T) {
+	boom := errors.New("boom")
+	if err := interruptibly(context.Background(), func(ctx context.Context) error { return boom }); err != boom {
+		t.Errorf("interruptibly(failing) = %v, want %v", err, boom)
+	}
+
+	ctx, cancel := context.WithTimeoutCause(context.Background(), time.Millisecond, errors.New("timed out after 1ms (--timeout)"))
+	defer cancel()
+	err := interruptibly(ctx, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if exitCode(err) != exitCanceled || err.Error() != "timed out after 1ms (--timeout)" {
+		t.Errorf("interruptibly(timed out) = %v (exit %d), want the timeout cause (exit %d)", err, exitCode(err), exitCanceled)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	err = interruptibly(ctx, func(ctx context.Context) error { return ctx.Err() })
+	if exitCode(err) != exitCanceled || err.Error() != "interrupted" {
+		t.Errorf("interruptibly(canceled) = %v (exit %d), want interrupted (exit %d)", err, exitCode(err), exitCanceled)
+	}
+}
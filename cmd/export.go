@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopack/internal"
+)
+
+var exportOverwrite bool
+
+var exportCmd = &cobra.Command{
+	Use:   "export <dest> [path...]",
+	Short: "Copy the files a pack would hold into a directory or archive",
+	Long: `Export walks the tree using the same filters as packing and copies the
+files it finds, as they are, into a directory rather than formatting them
+into a single pack: a clean, minimal snapshot of the source for LLM tools
+that take file uploads.
+
+A destination ending in .tar, .tar.gz or .tgz, or .zip is written as that
+archive instead; otherwise it's a directory, created if need be. Files
+already in the directory are left alone unless --overwrite is given. Files
+keep their permission bits and modification times. The
+tree walked is the current directory unless paths are given.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dest, paths := args[0], args[1:]
+		walker, extras, err := newWalker(cmd.Context(), paths)
+		if err != nil {
+			return err
+		}
+		// A copy of the tree keeps its scripts executable
+		walker.FileModes = true
+		files, err := collectFiles(cmd.Context(), walker, extras)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			return withExitCode(exitNoFiles, fmt.Errorf("no files matched"))
+		}
+
+		if format := internal.ArchiveFor(dest); format != "" {
+			err := internal.WriteFileAtomic(dest, func(w io.Writer) error {
+				return internal.WriteArchive(w, files, format)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to write %s: %w", dest, err)
+			}
+			statusf("Done! Exported %d files to %s\n", len(files), dest)
+			return nil
+		}
+
+		if info, err := os.Stat(dest); err == nil && !info.IsDir() {
+			return withExitCode(exitUsage, fmt.Errorf("%s is a file; export to a directory or a .tar, .tar.gz, or .zip archive", dest))
+		}
+		written, existing, err := internal.ExportDir(files, dest, exportOverwrite)
+		if err != nil {
+			return err
+		}
+		for _, path := range existing {
+			warnf("Skipping %s (already exists, use --overwrite)", path)
+		}
+		statusf("Done! Exported %d files to %s\n", written, dest)
+		return nil
+	},
+}
+
+func init() {
+	exportCmd.Flags().BoolVar(&exportOverwrite, "overwrite", false, "Replace files that already exist in the destination directory")
+	addFilterFlags(exportCmd)
+	rootCmd.AddCommand(exportCmd)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"gopack/internal"
+)
+
+var (
+	contextDepth int
+	focusLSP     bool
+)
+
+var focusCmd = &cobra.Command{
+	Use:   "focus <file> [path...]",
+	Short: "Pack a file with the files it depends on and those depending on it",
+	Long: `Focus packs one file together with its neighbourhood: the files it
+depends on and the files that depend on it, for a prompt about that file
+which needs the code around it but not the whole repository. The pack is
+written to stdout, the file first, then its dependencies, then its
+dependents.
+
+Go files are linked by the package-level names they use, in their own
+package or from packages of the tree they import; JavaScript, TypeScript,
+and Python files by the modules of the tree they import. As with uses,
+this reads the syntax of the files rather than type-checking them.
+
+--context-depth follows the links further: at 2, the dependencies of the
+file's dependencies and the dependents of its dependents come too. The
+tree searched is the current directory unless paths are given.
+
+With --lsp, gopls also finds the files referring to a Go file's
+declarations, methods included, which reading the syntax can't follow
+through variables. They're added to the dependents. gopls must be
+installed; without it, focus warns and reads the syntax alone.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target, paths := args[0], args[1:]
+		if contextDepth < 1 {
+			return withExitCode(exitUsage, fmt.Errorf("--context-depth must be at least 1"))
+		}
+
+		walker, extras, err := newWalker(cmd.Context(), paths)
+		if err != nil {
+			return err
+		}
+		files, err := collectFiles(cmd.Context(), walker, extras)
+		if err != nil {
+			return err
+		}
+
+		// The target is named from the current directory, files by their
+		// path from the walk's root
+		if abs, err := filepath.Abs(target); err == nil {
+			if rel, err := filepath.Rel(walker.Root(), abs); err == nil {
+				target = rel
+			}
+		}
+		focus, err := internal.FindFocus(files, walker.Root(), target, contextDepth)
+		if err != nil {
+			return withExitCode(exitNoFiles, fmt.Errorf("%w (is it ignored, or outside the paths given?)", err))
+		}
+		if focusLSP {
+			if !internal.HasGopls() {
+				warnf("--lsp: gopls isn't installed; finding dependents from the syntax alone")
+			} else if focus, err = internal.ExpandFocusLSP(focus, files, walker.Root()); err != nil {
+				return err
+			}
+		}
+		pack, err := formatPack(cmd.Context(), focus.Files, nil)
+		if err != nil {
+			return err
+		}
+		fmt.Print(pack)
+		statusf("Packed %s with %d files it depends on and %d that depend on it\n", args[0], focus.Dependencies, focus.Dependents)
+		return nil
+	},
+}
+
+func init() {
+	focusCmd.Flags().IntVar(&contextDepth, "context-depth", 1, "How many links to follow from the file to its dependencies and dependents")
+	focusCmd.Flags().BoolVar(&focusLSP, "lsp", false, "Also ask gopls for the files referring to a Go file's declarations, methods included")
+	addFilterFlags(focusCmd)
+	rootCmd.AddCommand(focusCmd)
+}
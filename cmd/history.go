@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopack/internal"
+)
+
+var (
+	noHistory   bool
+	historyLast int
+	historyJSON bool
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List past pack runs and how to repeat them",
+	Long: `History lists the packs made so far, newest last: when and where each
+was made, how many files and tokens it held, where it went, and the
+command that made it, ready to run again from the same directory.
+
+Every pack run is recorded in gopack/history.jsonl under the user's cache
+directory, or the file named by GOPACK_HISTORY. Use --no-history to leave a
+run out.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := internal.HistoryFile()
+		if err != nil {
+			return err
+		}
+		entries, err := internal.ReadHistory(path)
+		if err != nil {
+			return fmt.Errorf("failed to read history: %w", err)
+		}
+		if historyLast > 0 && len(entries) > historyLast {
+			entries = entries[len(entries)-historyLast:]
+		}
+
+		if historyJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			for _, entry := range entries {
+				if err := encoder.Encode(entry); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if len(entries) == 0 {
+			statusf("No packs recorded in %s yet.\n", path)
+			return nil
+		}
+		fmt.Print(formatHistory(entries))
+		return nil
+	},
+}
+
+// recordHistory adds a pack run to the history file, unless --no-history
+// was given. Failing to record it only warrants a warning.
+func recordHistory(files, tokens, bytes int, output string) {
+	if noHistory {
+		return
+	}
+	path, err := internal.HistoryFile()
+	if err == nil {
+		dir, _ := os.Getwd()
+		err = internal.AppendHistory(path, internal.HistoryEntry{
+			Time:   time.Now(),
+			Dir:    dir,
+			Args:   os.Args[1:],
+			Files:  files,
+			Tokens: tokens,
+			Bytes:  bytes,
+			Output: output,
+		})
+	}
+	if err != nil {
+		warnf("Failed to record the pack in the history: %v", err)
+	}
+}
+
+// formatHistory renders history entries, each on a line of its own
+// followed by the command that made it.
+func formatHistory(entries []internal.HistoryEntry) string {
+	var out strings.Builder
+	for _, entry := range entries {
+		fmt.Fprintf(&out, "%s  %s  %d files  ~%s tokens  → %s\n",
+			entry.Time.Local().Format("2006-01-02 15:04"), entry.Dir, entry.Files, internal.FormatWithCommas(entry.Tokens), entry.Output)
+		fmt.Fprintf(&out, "    %s\n", commandLine(append([]string{"gopack"}, entry.Args...)))
+	}
+	return out.String()
+}
+
+// commandLine joins arguments into a command to paste into a shell,
+// quoting those that need it.
+func commandLine(args []string) string {
+	words := make([]string, len(args))
+	for i, arg := range args {
+		words[i] = arg
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'\\$`|&;<>()*?[]{}~#!") {
+			words[i] = internal.ShellQuote(arg)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func init() {
+	historyCmd.Flags().IntVarP(&historyLast, "last", "n", 0, "Only list the N most recent packs")
+	historyCmd.Flags().BoolVar(&historyJSON, "json", false, "Print the entries as JSON lines")
+	rootCmd.AddCommand(historyCmd)
+}
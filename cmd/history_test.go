@@ -0,0 +1,26 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestCommandLine(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("POSIX quoting")
+	}
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"gopack", ".", "-f", "markdown"}, "gopack . -f markdown"},
+		{[]string{"gopack", "--ignore-pattern", "*.log"}, "gopack --ignore-pattern '*.log'"},
+		{[]string{"gopack", "--instructions", "Don't guess"}, `gopack --instructions 'Don'\''t guess'`},
+		{[]string{"gopack", "--stdin-label", ""}, "gopack --stdin-label ''"},
+	}
+	for _, tt := range tests {
+		if got := commandLine(tt.args); got != tt.want {
+			t.Errorf("commandLine(%q) = %s, want %s", tt.args, got, tt.want)
+		}
+	}
+}
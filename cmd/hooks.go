@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+
+	"gopack/internal"
+)
+
+// configTrusted reports whether the hooks and plugins in the loaded config
+// may run. They only run with --run-hooks, as a .gopack.json comes with
+// whatever tree was cloned, and a .gopack.json fetched with a remote
+// repository, directory, or pull request, or copied out of a container
+// image, is never trusted to run commands.
+func configTrusted() bool {
+	commands := config.Hooks != (internal.Hooks{}) || len(config.Plugins) > 0
+	if slices.ContainsFunc(repos, internal.IsRemoteRepo) || remoteDir != "" || dockerImage != "" || prURL != "" {
+		if commands {
+			warnf("Ignoring hooks and plugins in %s of a remote repository or directory", internal.ConfigFile)
+		}
+		return false
+	}
+	if !runHooks {
+		if commands {
+			warnf("Ignoring hooks and plugins in %s; pass --run-hooks to run them", internal.ConfigFile)
+		}
+		return false
+	}
+	return true
+}
+
+// runHook runs a hook command through the shell in dir, with env added to
+// its environment. Its output goes to stderr so it never mixes into a pack
+// printed to stdout. It's killed once ctx is done.
+func runHook(ctx context.Context, name, command, dir string, env ...string) error {
+	if command == "" {
+		return nil
+	}
+	cmd := shellCommand(ctx, command)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return commandStopped(ctx, fmt.Errorf("%s hook %q failed: %w", name, command, err))
+	}
+	return nil
+}
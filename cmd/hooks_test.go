@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"gopack/internal"
+)
+
+func TestRunHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook commands use sh")
+	}
+	dir := t.TempDir()
+
+	if err := runHook(context.Background(), "post-pack", `echo "$GOPACK_OUTPUT" > out.txt`, dir, "GOPACK_OUTPUT=/tmp/context.md"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "out.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "/tmp/context.md" {
+		t.Errorf("hook saw GOPACK_OUTPUT=%q, want /tmp/context.md", got)
+	}
+
+	if err := runHook(context.Background(), "pre-pack", "", dir); err != nil {
+		t.Errorf("runHook() with no command = %v, want nil", err)
+	}
+	err = runHook(context.Background(), "pre-pack", "exit 3", dir)
+	if err == nil || !strings.Contains(err.Error(), `pre-pack hook "exit 3" failed`) {
+		t.Errorf("runHook(exit 3) = %v, want a pre-pack hook error", err)
+	}
+}
+
+func TestConfigTrusted(t *testing.T) {
+	config = internal.Config{Hooks: internal.Hooks{Pre: "make"}}
+	defer func() { config, repos, runHooks = internal.Config{}, nil, false }()
+
+	repos = []string{"./service"}
+	if configTrusted() {
+		t.Error("configTrusted() = true without --run-hooks")
+	}
+	runHooks = true
+	if !configTrusted() {
+		t.Error("configTrusted() = false for a local repository with --run-hooks")
+	}
+	repos = []string{"./service", "https://github.com/org/lib"}
+	if configTrusted() {
+		t.Error("configTrusted() = true with a remote repository")
+	}
+}
+
+func TestRunHooksFlag(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook commands use sh")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, internal.ConfigFile), []byte(`{"hooks": {"pre": "touch ran"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { config = internal.Config{} }()
+
+	// A cloned tree's config can't run commands until asked to
+	path := filepath.Join(t.TempDir(), "context.txt")
+	if err := runRoot(t, dir, "-o", path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ran")); !os.IsNotExist(err) {
+		t.Errorf("pre hook ran without --run-hooks (%v)", err)
+	}
+	if err := runRoot(t, dir, "--run-hooks", "-o", path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ran")); err != nil {
+		t.Errorf("pre hook didn't run with --run-hooks: %v", err)
+	}
+}
+
+func TestRunHooksFlagPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin commands use sh")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	skipAll := `while read -r line; do echo '{\"skip\": true}'; done`
+	if err := os.WriteFile(filepath.Join(dir, internal.ConfigFile), []byte(`{"plugins": ["`+skipAll+`"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { config = internal.Config{} }()
+
+	// The config's plugins only see the files with --run-hooks
+	path := filepath.Join(t.TempDir(), "context.txt")
+	for _, tt := range []struct {
+		args   []string
+		packed bool
+	}{
+		{[]string{dir, "-o", path}, true},
+		{[]string{dir, "--run-hooks", "-o", path}, false},
+	} {
+		if err := runRoot(t, tt.args...); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if packed := strings.Contains(string(data), "package main"); packed != tt.packed {
+			t.Errorf("%q packed main.go = %v, want %v", tt.args, packed, tt.packed)
+		}
+	}
+}
+
+func TestRunHookTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook commands use sh")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// exec, so no child outlives the killed shell holding the test's stderr
+	if err := os.WriteFile(filepath.Join(dir, internal.ConfigFile), []byte(`{"hooks": {"pre": "exec sleep 5"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { config = internal.Config{} }()
+
+	// --timeout stops the hook, not just the pack after it
+	start := time.Now()
+	err := runRoot(t, dir, "--run-hooks", "--timeout", "200ms", "-o", filepath.Join(t.TempDir(), "context.txt"))
+	if exitCode(err) != exitCanceled || !strings.Contains(err.Error(), "--timeout") {
+		t.Errorf("hook past --timeout = %v (exit %d), want exit %d", err, exitCode(err), exitCanceled)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("hook past --timeout took %s to stop", elapsed)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var lintIgnoresCmd = &cobra.Command{
+	Use:   "lint-ignores [path...]",
+	Short: "Report ignore patterns that never match or can't be interpreted",
+	Long: `Lint-ignores walks the tree using the same filters as packing and
+reports the patterns in .gitignore and .gopackignore files, and those given
+with --ignore-pattern, that matched nothing during the walk or that gopack
+can't interpret as written, such as an unclosed "[" or a POSIX character
+class. A pattern that only matches inside a directory that is already
+ignored counts as matching nothing, since the walk never goes there.
+
+It exits with status 1 if it finds any problems, so it can run in CI.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		walker, _, err := newWalker(cmd.Context(), args)
+		if err != nil {
+			return err
+		}
+
+		problems, err := walker.LintIgnores()
+		if err != nil {
+			return fmt.Errorf("failed to walk directory: %w", err)
+		}
+		for _, problem := range problems {
+			fmt.Printf("%s: %q: %s\n", problem.Source, problem.Pattern, problem.Problem)
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("%d ignore patterns need attention", len(problems))
+		}
+		statusf("All ignore patterns matched something.\n")
+		return nil
+	},
+}
+
+func init() {
+	addFilterFlags(lintIgnoresCmd)
+	rootCmd.AddCommand(lintIgnoresCmd)
+}
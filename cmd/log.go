@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Formats accepted by --log-format.
+const (
+	logText = "text"
+	logJSON = "json"
+)
+
+var (
+	// logLevels lists the values accepted by --log-level.
+	logLevels = []string{"debug", "info", "warn", "error"}
+
+	// logFormats lists the values accepted by --log-format.
+	logFormats = []string{logText, logJSON}
+)
+
+var (
+	logLevel  string
+	logFormat string
+	logFile   string
+
+	// logger is shared by everything that reports on what gopack is doing:
+	// status messages, warnings, skipped files, and --editor requests.
+	// setupLogging replaces it once the flags are parsed.
+	logger = slog.New(&consoleHandler{w: os.Stderr})
+
+	// minLevel is the --log-level, below which records are dropped.
+	minLevel = new(slog.LevelVar)
+)
+
+// setupLogging configures logger from --log-level, --log-format, and
+// --log-file. Unless --log-format json is given, records go to stderr as
+// gopack has always printed them; --log-file also appends them to a file,
+// in --log-format, so a long-running --editor server keeps a record.
+func setupLogging() error {
+	if err := minLevel.UnmarshalText([]byte(logLevel)); err != nil || !slices.Contains(logLevels, strings.ToLower(logLevel)) {
+		return fmt.Errorf("unknown log level %q (expected one of: %s)", logLevel, strings.Join(logLevels, ", "))
+	}
+	if !slices.Contains(logFormats, logFormat) {
+		return fmt.Errorf("unknown log format %q (expected one of: %s)", logFormat, strings.Join(logFormats, ", "))
+	}
+
+	var handlers []slog.Handler
+	if logFile == "" && logFormat == logJSON {
+		handlers = append(handlers, newLogHandler(os.Stderr))
+	} else {
+		handlers = append(handlers, &consoleHandler{w: os.Stderr})
+	}
+	if logFile != "" {
+		// Left open until exit; records are written as they come
+		file, err := os.OpenFile(logFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open --log-file: %w", err)
+		}
+		handlers = append(handlers, newLogHandler(file))
+	}
+	if len(handlers) == 1 {
+		logger = slog.New(handlers[0])
+	} else {
+		logger = slog.New(teeHandler(handlers))
+	}
+	return nil
+}
+
+// newLogHandler returns a handler writing records to w in --log-format.
+func newLogHandler(w io.Writer) slog.Handler {
+	opts := &slog.HandlerOptions{Level: minLevel}
+	if logFormat == logJSON {
+		return slog.NewJSONHandler(w, opts)
+	}
+	return slog.NewTextHandler(w, opts)
+}
+
+// warnf logs a warning, which --quiet doesn't hide.
+func warnf(format string, args ...any) {
+	logger.Warn(fmt.Sprintf(format, args...))
+}
+
+// consoleHandler prints records for a person at a terminal: warnings
+// flagged as such, other messages as they are, and attributes after them
+// as key=value. Messages below warnings are left out with --quiet, and
+// with --json, so stderr holds only events and warnings.
+type consoleHandler struct {
+	w     io.Writer
+	attrs []slog.Attr
+}
+
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= minLevel.Level() && (level >= slog.LevelWarn || !quiet && !jsonEvents)
+}
+
+func (h *consoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	switch {
+	case r.Level >= slog.LevelError:
+		b.WriteString("Error: ")
+	case r.Level >= slog.LevelWarn:
+		b.WriteString("⚠ Warning: ")
+	}
+	b.WriteString(r.Message)
+	writeAttr := func(a slog.Attr) bool {
+		fmt.Fprintf(&b, " %s=%v", a.Key, a.Value)
+		return true
+	}
+	for _, a := range h.attrs {
+		writeAttr(a)
+	}
+	r.Attrs(writeAttr)
+	b.WriteByte('\n')
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &consoleHandler{w: h.w, attrs: append(slices.Clip(h.attrs), attrs...)}
+}
+
+// WithGroup is not needed by gopack's records, which aren't grouped.
+func (h *consoleHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// teeHandler passes each record to every handler that takes its level.
+type teeHandler []slog.Handler
+
+func (t teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return slices.ContainsFunc(t, func(h slog.Handler) bool { return h.Enabled(ctx, level) })
+}
+
+func (t teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs error
+	for _, h := range t {
+		if h.Enabled(ctx, r.Level) {
+			errs = errors.Join(errs, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return errs
+}
+
+func (t teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (t teeHandler) WithGroup(name string) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
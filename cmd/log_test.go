@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConsoleHandler(t *testing.T) {
+	defer func() { quiet = false; minLevel.Set(slog.LevelInfo) }()
+
+	tests := []struct {
+		name  string
+		quiet bool
+		level slog.Level
+		log   func(*slog.Logger)
+		want  string
+	}{
+		{"status", false, slog.LevelInfo, func(l *slog.Logger) { l.Info("Done!") }, "Done!\n"},
+		{"warning", false, slog.LevelInfo, func(l *slog.Logger) { l.Warn("Too big") }, "⚠ Warning: Too big\n"},
+		{"error", false, slog.LevelInfo, func(l *slog.Logger) { l.Error("failed") }, "Error: failed\n"},
+		{"attributes", false, slog.LevelDebug, func(l *slog.Logger) { l.With("path", "a.go").Debug("skipped", "reason", "empty") }, "skipped path=a.go reason=empty\n"},
+		{"debug hidden at info", false, slog.LevelInfo, func(l *slog.Logger) { l.Debug("skipped") }, ""},
+		{"quiet hides status", true, slog.LevelInfo, func(l *slog.Logger) { l.Info("Done!") }, ""},
+		{"quiet keeps warnings", true, slog.LevelInfo, func(l *slog.Logger) { l.Warn("Too big") }, "⚠ Warning: Too big\n"},
+	}
+	for _, tt := range tests {
+		quiet = tt.quiet
+		minLevel.Set(tt.level)
+		var buf bytes.Buffer
+		tt.log(slog.New(&consoleHandler{w: &buf}))
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s: logged %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSetupLogging(t *testing.T) {
+	saved := logger
+	defer func() {
+		logger, logLevel, logFormat, logFile = saved, "info", logText, ""
+		minLevel.Set(slog.LevelInfo)
+	}()
+
+	logLevel, logFormat = "loud", logText
+	if err := setupLogging(); err == nil {
+		t.Error("setupLogging() with --log-level loud succeeded, want error")
+	}
+	logLevel, logFormat = "info", "xml"
+	if err := setupLogging(); err == nil {
+		t.Error("setupLogging() with --log-format xml succeeded, want error")
+	}
+
+	// A log file gets every record at the level, in the format, as well as
+	// the console
+	logLevel, logFormat, logFile = "warn", logJSON, filepath.Join(t.TempDir(), "gopack.log")
+	if err := setupLogging(); err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("Done!")
+	logger.Warn("Too big", "tokens", 12)
+	var record struct {
+		Level  string `json:"level"`
+		Msg    string `json:"msg"`
+		Tokens int    `json:"tokens"`
+	}
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("log file has %d records, want 1: %q", len(lines), lines)
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Level != "WARN" || record.Msg != "Too big" || record.Tokens != 12 {
+		t.Errorf("log file record = %+v, want a WARN of Too big with 12 tokens", record)
+	}
+}
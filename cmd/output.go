@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopack/internal"
+)
+
+// stdoutPath is the --output that names stdout, as in "-o -".
+const stdoutPath = "-"
+
+// outputTarget is a file the pack is written to, in its own format.
+type outputTarget struct {
+	path        string
+	format      string
+	compression string // "" for none
+}
+
+// outputTargets resolves --output and --formats into the files to write.
+// A single --output uses --format, as it always has. Several --output files
+// each take the format their extension implies (context.md is Markdown),
+// falling back to --format. --formats writes one context file per format
+// into the --output directory. An --output of "-" is stdout.
+func outputTargets() ([]outputTarget, error) {
+	for _, format := range formats {
+		if !slices.Contains(internal.Formats, format) {
+			return nil, fmt.Errorf("unknown format %q (expected one of: %s)", format, strings.Join(internal.Formats, ", "))
+		}
+		if format == internal.FormatDiff {
+			return nil, fmt.Errorf("--formats can't include diff, which replaces file contents; use --format diff")
+		}
+		if format == internal.FormatObsidian {
+			return nil, fmt.Errorf("--formats can't include obsidian, which writes a folder of notes; use --format obsidian")
+		}
+	}
+
+	// A vault is a directory of notes rather than a file
+	if formatFlag == internal.FormatObsidian {
+		switch {
+		case len(outputs) != 1 || len(formats) > 0:
+			return nil, fmt.Errorf("--format obsidian writes a folder of notes; name it with a single --output")
+		case compressAs != "":
+			return nil, fmt.Errorf("--format obsidian can't be compressed")
+		case encryptTo != "":
+			return nil, fmt.Errorf("--format obsidian can't be encrypted")
+		case outputs[0] == stdoutPath:
+			return nil, fmt.Errorf("--format obsidian writes a folder of notes, which can't go to stdout")
+		case internal.IsBucketURL(outputs[0]):
+			return nil, fmt.Errorf("--format obsidian writes a folder of notes, which can't be uploaded")
+		}
+		if info, err := os.Stat(outputs[0]); err == nil && !info.IsDir() {
+			return nil, fmt.Errorf("--format obsidian requires --output to be a directory, not the file %s", outputs[0])
+		}
+		return []outputTarget{{path: outputs[0], format: internal.FormatObsidian}}, nil
+	}
+
+	var targets []outputTarget
+	if len(formats) > 0 {
+		if len(outputs) != 1 || outputs[0] == stdoutPath {
+			return nil, fmt.Errorf("--formats requires a single --output directory")
+		}
+		dir := outputs[0]
+		if internal.IsBucketURL(dir) {
+			for _, format := range formats {
+				name := "context" + internal.FormatExt(format) + outputExt(compressAs)
+				path := strings.TrimSuffix(dir, "/") + "/" + name
+				targets = append(targets, outputTarget{path: path, format: format, compression: compressAs})
+			}
+			return dedupeTargets(targets)
+		}
+		if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+			return nil, fmt.Errorf("--formats requires --output to be a directory, not the file %s", dir)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+		for _, format := range formats {
+			name := "context" + internal.FormatExt(format) + outputExt(compressAs)
+			targets = append(targets, outputTarget{path: filepath.Join(dir, name), format: format, compression: compressAs})
+		}
+		return dedupeTargets(targets)
+	}
+
+	for _, output := range outputs {
+		target := outputTarget{format: formatFlag, compression: compressAs}
+		if target.compression == "" {
+			target.compression = internal.CompressionFor(output)
+		}
+		defaultName := "context.txt"
+		if len(outputs) > 1 {
+			if format := internal.FormatFor(output); format != "" {
+				target.format = format
+			}
+			defaultName = "context" + internal.FormatExt(target.format)
+		}
+
+		if output == stdoutPath {
+			target.path = stdoutPath
+			targets = append(targets, target)
+			continue
+		}
+		if internal.IsBucketURL(output) {
+			target.path = internal.BucketObject(output, defaultName+outputExt(target.compression))
+			targets = append(targets, target)
+			continue
+		}
+		var err error
+		if target.path, err = resolveOutputPath(output, defaultName+outputExt(target.compression)); err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+	return dedupeTargets(targets)
+}
+
+// spillPath returns the file to write a pack of the given tokens to in
+// place of the clipboard or the terminal, if it's over --file-over, or ""
+// to send it there as usual. Packs piped into another command, and those
+// copied in parts, go where they were sent whatever their size.
+func spillPath(tokens int, terminal bool) string {
+	if fileOver == 0 || tokens <= fileOver || chunkSize > 0 || !copy && !terminal {
+		return ""
+	}
+	return "context" + internal.FormatExt(formatFlag) + outputExt(compressAs)
+}
+
+// outputExt returns the extension added to default output names for the
+// given compression and --encrypt, as in context.txt.gz.age.
+func outputExt(compression string) string {
+	ext := internal.CompressionExt(compression)
+	if encryptTo != "" {
+		ext += internal.EncryptExt
+	}
+	return ext
+}
+
+// encryptOutput encrypts data to the --encrypt recipient, or returns it
+// unchanged without one.
+func encryptOutput(data string) (string, error) {
+	if encryptTo == "" {
+		return data, nil
+	}
+	encrypted, err := internal.Encrypt([]byte(data), encryptTo)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt output: %w", err)
+	}
+	return string(encrypted), nil
+}
+
+// dedupeTargets rejects targets that would overwrite each other.
+func dedupeTargets(targets []outputTarget) ([]outputTarget, error) {
+	seen := make(map[string]bool)
+	for _, target := range targets {
+		path := filepath.Clean(target.path)
+		if seen[path] {
+			return nil, fmt.Errorf("output %s is given more than once", target.path)
+		}
+		seen[path] = true
+	}
+	return targets, nil
+}
+
+// writeTargets writes the pack to each output target, formatting it once
+// per format. output is the pack already formatted as --format. s3:// and
+// gs:// targets are uploaded. It returns the path of the first local file
+// written, or "" if the pack only went to stdout or object storage.
+func writeTargets(ctx context.Context, targets []outputTarget, files []internal.File, notes []string, output string) (string, error) {
+	rendered := map[string]string{formatFlag: output}
+	for _, target := range targets {
+		if target.format == internal.FormatObsidian {
+			n, err := internal.WriteVault(target.path, files, notes)
+			if err != nil {
+				return "", fmt.Errorf("failed to write vault: %w", err)
+			}
+			statusf("Done! Wrote %d notes to %s (start at %s)\n", n, target.path, internal.VaultIndex)
+			continue
+		}
+		data, ok := rendered[target.format]
+		if !ok {
+			var err error
+			if data, err = formatPackAs(ctx, target.format, files, notes); err != nil {
+				return "", err
+			}
+			rendered[target.format] = data
+		}
+		if target.compression != "" {
+			compressed, err := internal.Compress([]byte(data), target.compression)
+			if err != nil {
+				return "", fmt.Errorf("failed to compress output: %w", err)
+			}
+			data = string(compressed)
+		}
+		data, err := encryptOutput(data)
+		if err != nil {
+			return "", err
+		}
+		if target.path == stdoutPath {
+			if err := checkTerminalSize(int64(len(data))); err != nil {
+				return "", err
+			}
+			os.Stdout.WriteString(data)
+			continue
+		}
+		if internal.IsBucketURL(target.path) {
+			if err := internal.Upload(ctx, target.path, []byte(data)); err != nil {
+				return "", fmt.Errorf("failed to upload output: %w", err)
+			}
+			statusf("Done! Context uploaded to %s\n", target.path)
+			continue
+		}
+		if err := writeOutput(target.path, data); err != nil {
+			return "", fmt.Errorf("failed to write output file: %w", err)
+		}
+		statusf("Done! Context written to %s\n", target.path)
+	}
+	for _, target := range targets {
+		if target.path != stdoutPath && !internal.IsBucketURL(target.path) {
+			return target.path, nil
+		}
+	}
+	return "", nil
+}
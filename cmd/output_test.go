@@ -0,0 +1,153 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopack/internal"
+)
+
+func TestOutputTargets(t *testing.T) {
+	dir := t.TempDir()
+	defer func() { outputs, formats, formatFlag, compressAs = nil, nil, internal.FormatText, "" }()
+
+	tests := []struct {
+		name    string
+		outputs []string
+		formats []string
+		format  string
+		want    []outputTarget
+	}{
+		{
+			"single output keeps --format",
+			[]string{filepath.Join(dir, "a.md")}, nil, internal.FormatText,
+			[]outputTarget{{filepath.Join(dir, "a.md"), internal.FormatText, ""}},
+		},
+		{
+			"several outputs use their extensions",
+			[]string{filepath.Join(dir, "b.md"), filepath.Join(dir, "b.txt.gz"), filepath.Join(dir, "b.out")}, nil, internal.FormatMarkdown,
+			[]outputTarget{
+				{filepath.Join(dir, "b.md"), internal.FormatMarkdown, ""},
+				{filepath.Join(dir, "b.txt.gz"), internal.FormatText, internal.CompressGzip},
+				{filepath.Join(dir, "b.out"), internal.FormatMarkdown, ""},
+			},
+		},
+		{
+			"formats into a directory",
+			[]string{filepath.Join(dir, "out")}, []string{"markdown", "text"}, internal.FormatText,
+			[]outputTarget{
+				{filepath.Join(dir, "out", "context.md"), internal.FormatMarkdown, ""},
+				{filepath.Join(dir, "out", "context.txt"), internal.FormatText, ""},
+			},
+		},
+		{
+			"stdout",
+			[]string{"-"}, nil, internal.FormatMarkdown,
+			[]outputTarget{{"-", internal.FormatMarkdown, ""}},
+		},
+		{
+			"stdout and a file",
+			[]string{"-", filepath.Join(dir, "d.txt")}, nil, internal.FormatMarkdown,
+			[]outputTarget{
+				{"-", internal.FormatMarkdown, ""},
+				{filepath.Join(dir, "d.txt"), internal.FormatText, ""},
+			},
+		},
+		{
+			"obsidian writes a vault directory",
+			[]string{filepath.Join(dir, "vault")}, nil, internal.FormatObsidian,
+			[]outputTarget{{filepath.Join(dir, "vault"), internal.FormatObsidian, ""}},
+		},
+		{
+			"bucket objects",
+			[]string{"s3://bucket/packs/context.md.gz", "gs://bucket/packs/"}, nil, internal.FormatMarkdown,
+			[]outputTarget{
+				{"s3://bucket/packs/context.md.gz", internal.FormatMarkdown, internal.CompressGzip},
+				{"gs://bucket/packs/context.md", internal.FormatMarkdown, ""},
+			},
+		},
+		{
+			"formats into a bucket prefix",
+			[]string{"s3://bucket/packs"}, []string{"markdown", "text"}, internal.FormatText,
+			[]outputTarget{
+				{"s3://bucket/packs/context.md", internal.FormatMarkdown, ""},
+				{"s3://bucket/packs/context.txt", internal.FormatText, ""},
+			},
+		},
+	}
+	for _, tt := range tests {
+		outputs, formats, formatFlag = tt.outputs, tt.formats, tt.format
+		got, err := outputTargets()
+		if err != nil {
+			t.Errorf("%s: outputTargets() error: %v", tt.name, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: outputTargets() = %+v, want %+v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: target %d = %+v, want %+v", tt.name, i, got[i], tt.want[i])
+			}
+		}
+	}
+
+	file := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []struct {
+		outputs, formats []string
+		format           string
+	}{
+		{[]string{file}, []string{"text"}, internal.FormatText},
+		{[]string{dir, dir + "/x"}, []string{"text"}, internal.FormatText},
+		{[]string{dir}, []string{"pdf"}, internal.FormatText},
+		{[]string{filepath.Join(dir, "c.md"), filepath.Join(dir, ".", "c.md")}, nil, internal.FormatText},
+		{[]string{dir}, []string{"obsidian"}, internal.FormatText},
+		{nil, nil, internal.FormatObsidian},
+		{[]string{file}, nil, internal.FormatObsidian},
+		{[]string{"-"}, []string{"text"}, internal.FormatText},
+		{[]string{"-"}, nil, internal.FormatObsidian},
+		{[]string{"-", "-"}, nil, internal.FormatText},
+		{[]string{"s3://bucket/vault/"}, nil, internal.FormatObsidian},
+	} {
+		outputs, formats, formatFlag = bad.outputs, bad.formats, bad.format
+		if _, err := outputTargets(); err == nil {
+			t.Errorf("outputTargets(%q, %q) succeeded, want error", bad.outputs, bad.formats)
+		}
+	}
+}
+
+func TestSpillPath(t *testing.T) {
+	defer func() {
+		fileOver, copy, chunkSize, formatFlag, compressAs = 1_000_000, false, 0, internal.FormatText, ""
+	}()
+
+	tests := []struct {
+		name      string
+		fileOver  int
+		copy      bool
+		chunkSize int
+		format    string
+		compress  string
+		terminal  bool
+		want      string
+	}{
+		{"under the limit", 100, true, 0, internal.FormatText, "", true, ""},
+		{"copy over the limit", 10, true, 0, internal.FormatText, "", false, "context.txt"},
+		{"terminal over the limit", 10, false, 0, internal.FormatMarkdown, "", true, "context.md"},
+		{"compressed", 10, false, 0, internal.FormatText, internal.CompressGzip, true, "context.txt.gz"},
+		{"piped", 10, false, 0, internal.FormatText, "", false, ""},
+		{"copied in parts", 10, true, 5, internal.FormatText, "", false, ""},
+		{"disabled", 0, true, 0, internal.FormatText, "", true, ""},
+	}
+	for _, tt := range tests {
+		fileOver, copy, chunkSize, formatFlag, compressAs = tt.fileOver, tt.copy, tt.chunkSize, tt.format, tt.compress
+		if got := spillPath(50, tt.terminal); got != tt.want {
+			t.Errorf("%s: spillPath(50) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopack/pack"
+)
+
+var parseJSON bool
+
+var parseCmd = &cobra.Command{
+	Use:   "parse <pack>",
+	Short: "Read a pack back into its files",
+	Long: `Parse reads a pack produced by gopack (text, Markdown, HTML, or JSON Lines,
+optionally gzip or zstd compressed, or encrypted with --encrypt given
+--identity) and lists its files with their sizes.
+With --json it prints the whole pack as JSON instead, for other tools:
+
+  {"format": "markdown", "files": [{"path": "main.go", "content": "..."}]}
+
+Use "-" to read the pack from stdin. Go programs can use the gopack/pack
+package, which this command is built on, directly.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := readPackData(args[0])
+		if err != nil {
+			return err
+		}
+		parsed, err := pack.Parse(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", args[0], err)
+		}
+
+		if parseJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(parsed)
+		}
+		for _, file := range parsed.Files {
+			fmt.Printf("%8d  %s\n", len(file.Content), file.Path)
+		}
+		return nil
+	},
+}
+
+func init() {
+	addIdentityFlag(parseCmd)
+	parseCmd.Flags().BoolVar(&parseJSON, "json", false, "Print the format and every file's path and content as JSON")
+	rootCmd.AddCommand(parseCmd)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+
+	"gopack/internal"
+)
+
+// runPlugins passes files through each --plugin command in turn, followed
+// by the plugins in the config when it is trusted (see configTrusted).
+// Plugins run through the shell from dir, and are killed once ctx is done.
+func runPlugins(ctx context.Context, files []internal.File, dir string, trusted bool) ([]internal.File, error) {
+	commands := slices.Clip(plugins)
+	if trusted {
+		commands = append(commands, config.Plugins...)
+	}
+	for _, command := range commands {
+		cmd := shellCommand(ctx, command)
+		cmd.Dir = dir
+
+		var skipped int
+		var err error
+		if files, skipped, err = internal.ApplyPlugin(files, cmd); err != nil {
+			return nil, commandStopped(ctx, fmt.Errorf("plugin %q failed: %w", command, err))
+		}
+		if verbose && !jsonEvents && skipped > 0 {
+			fmt.Fprintf(os.Stderr, "Plugin %q skipped %d files\n", command, skipped)
+		}
+	}
+	return files, nil
+}
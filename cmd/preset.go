@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"gopack/internal"
+)
+
+// applyPreset sets each flag of the named preset that wasn't given on the
+// command line. Presets defined in the configuration of the local tree
+// being packed take precedence over the built-in ones; a remote
+// repository's are never used, since a preset can run commands.
+func applyPreset(cmd *cobra.Command, name string, args []string) error {
+	projectConfig, err := localConfig(args)
+	if err != nil {
+		return err
+	}
+	preset, err := internal.LookupPreset(name, projectConfig)
+	if err != nil {
+		return err
+	}
+	values, err := preset.FlagValues()
+	if err != nil {
+		return fmt.Errorf("preset %q: %w", name, err)
+	}
+	for _, flagName := range slices.Sorted(maps.Keys(values)) {
+		flag := cmd.Flags().Lookup(flagName)
+		if flag == nil || flagName == "preset" {
+			return fmt.Errorf("preset %q: unknown flag --%s", name, flagName)
+		}
+		if flag.Changed {
+			continue
+		}
+		for _, value := range values[flagName] {
+			if err := cmd.Flags().Set(flagName, value); err != nil {
+				return fmt.Errorf("preset %q: invalid value %q for --%s: %w", name, value, flagName, err)
+			}
+		}
+	}
+	return nil
+}
+
+// localConfig loads the configuration of the local tree args would pack,
+// leaving out remote repositories. It is empty if the paths don't exist.
+func localConfig(args []string) (internal.Config, error) {
+	var local []string
+	for _, arg := range args {
+		if !internal.IsRemoteRepo(arg) {
+			local = append(local, arg)
+		}
+	}
+	if len(local) == 0 && len(args) > 0 {
+		return internal.Config{}, nil
+	}
+	walker, err := internal.NewWalker(local...)
+	if err != nil {
+		return internal.Config{}, nil
+	}
+	return internal.LoadConfig(walker.Root())
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"gopack/internal"
+)
+
+func TestApplyPreset(t *testing.T) {
+	dir := t.TempDir()
+	config := `{"presets": {"mine": {"flags": {"format": "html", "ignore-pattern": ["a/", "b/"], "summary": true}}}}`
+	if err := os.WriteFile(filepath.Join(dir, internal.ConfigFile), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	newCmd := func() (*cobra.Command, *string, *[]string, *bool) {
+		cmd := &cobra.Command{}
+		format := cmd.Flags().String("format", "text", "")
+		patterns := cmd.Flags().StringArray("ignore-pattern", nil, "")
+		summary := cmd.Flags().Bool("summary", false, "")
+		return cmd, format, patterns, summary
+	}
+
+	cmd, format, patterns, summary := newCmd()
+	if err := cmd.Flags().Parse([]string{"--format", "markdown"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyPreset(cmd, "mine", []string{dir}); err != nil {
+		t.Fatal(err)
+	}
+	if *format != "markdown" || !slices.Equal(*patterns, []string{"a/", "b/"}) || !*summary {
+		t.Errorf("applyPreset() = format %q, patterns %q, summary %v; want markdown (given), [a/ b/], true", *format, *patterns, *summary)
+	}
+
+	// The built-in docs preset sets flags this command doesn't have
+	cmd, _, _, _ = newCmd()
+	if err := applyPreset(cmd, "docs", []string{dir}); err == nil {
+		t.Error("applyPreset(docs) with missing flags succeeded, want error")
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gopack/internal"
+)
+
+const (
+	// progressDelay is how long a walk runs before progress is shown, so
+	// small trees stay quiet.
+	progressDelay = 1500 * time.Millisecond
+
+	// progressInterval is how often the progress line is redrawn.
+	progressInterval = 200 * time.Millisecond
+)
+
+// progress reports how many files have been read on stderr while a long
+// walk is running.
+type progress struct {
+	files atomic.Int64
+	bytes atomic.Int64
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// startProgress begins reporting the files walker reads. It returns nil,
+// which is safe to stop, when stderr isn't a terminal.
+func startProgress(walker *internal.Walker) *progress {
+	if quiet || jsonEvents || !isTerminal(os.Stderr) {
+		return nil
+	}
+	return newProgress(walker, os.Stderr, progressDelay)
+}
+
+// newProgress reports the files walker reads on w, once it has run for
+// delay.
+func newProgress(walker *internal.Walker, w io.Writer, delay time.Duration) *progress {
+	p := &progress{stop: make(chan struct{}), done: make(chan struct{})}
+	walker.OnRead = func(path string, size int) {
+		p.files.Add(1)
+		p.bytes.Add(int64(size))
+	}
+
+	go func() {
+		defer close(p.done)
+		start := time.Now()
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+
+		shown := false
+		for {
+			select {
+			case <-p.stop:
+				if shown {
+					// Clear the progress line
+					fmt.Fprint(w, "\r\033[K")
+				}
+				return
+			case <-ticker.C:
+				if time.Since(start) < delay {
+					continue
+				}
+				fmt.Fprintf(w, "\r\033[KRead %s files (%.1f MB)...",
+					internal.FormatWithCommas(int(p.files.Load())), float64(p.bytes.Load())/(1024*1024))
+				shown = true
+			}
+		}
+	}()
+	return p
+}
+
+// Stop ends reporting and clears the progress line.
+func (p *progress) Stop() {
+	if p == nil {
+		return
+	}
+	close(p.stop)
+	<-p.done
+}
+
+// isTerminal reports whether f is connected to a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// isPipe reports whether f is a pipe, such as stdout in "gopack | llm".
+func isPipe(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeNamedPipe != 0
+}
+
+// confirm asks a yes/no question on the terminal, defaulting to no. It
+// reads the answer from the terminal rather than stdin, which may be
+// carrying input, and answers no when there is no terminal to ask on.
+func confirm(question string) bool {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return false
+	}
+	defer tty.Close()
+	fmt.Fprintf(tty, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(tty).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopack/internal"
+)
+
+func TestIsPipe(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	if !isPipe(w) {
+		t.Error("isPipe(pipe) = false, want true")
+	}
+
+	file, err := os.Create(filepath.Join(t.TempDir(), "out.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if isPipe(file) || isTerminal(file) {
+		t.Error("isPipe or isTerminal(regular file) = true, want false")
+	}
+	if err := checkTerminalSize(2 * terminalLimit); err != nil {
+		t.Errorf("checkTerminalSize() with stdout not a terminal = %v, want nil", err)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.go", "b.go"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("package a\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	walker, err := internal.NewWalker(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a terminal on stderr, as under go test, nothing is shown
+	if p := startProgress(walker); p != nil || walker.OnRead != nil {
+		t.Errorf("startProgress() without a terminal = %v, want nil", p)
+	}
+	var p *progress
+	p.Stop()
+
+	// Once shown, the line counts what was read and is cleared at the end
+	var out bytes.Buffer
+	p = newProgress(walker, &out, 0)
+	if _, err := walker.Walk(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(progressInterval + progressInterval/2)
+	p.Stop()
+	got := out.String()
+	if !strings.Contains(got, "Read 2 files (0.0 MB)...") || !strings.HasSuffix(got, "\r\033[K") {
+		t.Errorf("progress output = %q, want a count of 2 files, then cleared", got)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"gopack/internal"
+)
+
+var (
+	refreshDryRun    bool
+	refreshChangelog bool
+)
+
+var refreshCmd = &cobra.Command{
+	Use:   "refresh <pack> [dir]",
+	Short: "Update the files in an existing Markdown pack in place",
+	Long: `Refresh re-reads the files listed in a Markdown pack from dir (the current
+directory by default) and rewrites the sections of those that changed,
+leaving the rest of the pack as it is, so notes added between the sections
+survive. Files that no longer exist have their sections dropped, and new
+files in the directories the pack's files are in (not their subdirectories)
+are added before the summary, with the usual ignore rules. The summary
+isn't recomputed.
+
+Files are read as they are on disk, so a pack made with transformations
+such as --strip-license shows every transformed file as changed.
+
+With --changelog, a section listing the files added, changed, and removed
+since the pack was last written, with the lines added and removed in each,
+is put at the top of the pack, replacing the one the last refresh put
+there, so a model given the pack again can tell what's new. A refresh that
+finds nothing new drops it.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, dir := args[0], "."
+		if len(args) == 2 {
+			dir = args[1]
+		}
+		if internal.CompressionFor(name) != "" {
+			return withExitCode(exitUsage, fmt.Errorf("can't refresh a compressed pack in place"))
+		}
+		// Keep other runs from writing the pack between reading and
+		// rewriting it
+		if !refreshDryRun {
+			unlock, err := internal.LockFile(name, outputLockWait)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("failed to read pack: %w", err)
+		}
+
+		present, err := filesBeside(data, dir, name)
+		if err != nil {
+			return fmt.Errorf("failed to look for new files: %w", err)
+		}
+		refreshed, result, err := internal.RefreshPack(data, func(path string) ([]byte, error) {
+			return os.ReadFile(filepath.Join(dir, filepath.FromSlash(path)))
+		}, present)
+		if err != nil {
+			return fmt.Errorf("failed to refresh %s: %w", name, err)
+		}
+		for _, change := range result.Changes {
+			fmt.Printf("%s (%s)\n", change.Path, change.Kind)
+		}
+		// The changelog is replaced even when nothing changed, so an old
+		// one doesn't describe this pack
+		if refreshChangelog {
+			refreshed = internal.PrependChangelog(refreshed, internal.Changelog(result.Changes))
+		}
+
+		if refreshDryRun {
+			statusf("%d of %d files would be refreshed, %d added, %d removed (dry run).\n",
+				len(result.Updated), len(result.Updated)+result.Unchanged, len(result.Added), len(result.Removed))
+			return nil
+		}
+		if bytes.Equal(refreshed, data) {
+			statusf("%s is up to date.\n", name)
+			return nil
+		}
+		err = internal.WriteFileAtomic(name, func(w io.Writer) error {
+			_, err := w.Write(refreshed)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		statusf("Done! Refreshed %d of %d files in %s, %d added, %d removed\n",
+			len(result.Updated), len(result.Updated)+result.Unchanged, name, len(result.Added), len(result.Removed))
+		return nil
+	},
+}
+
+// filesBeside returns the files refresh may add to a pack: those in the
+// directories under dir holding the pack's files, but not in their
+// subdirectories, other than the pack itself.
+func filesBeside(data []byte, dir, pack string) ([]internal.File, error) {
+	packed, err := internal.ParsePack(data)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, file := range packed {
+		d := filepath.Join(dir, filepath.Dir(filepath.FromSlash(file.Path)))
+		if seen[d] {
+			continue
+		}
+		seen[d] = true
+		if info, err := os.Stat(d); err == nil && info.IsDir() {
+			dirs = append(dirs, d)
+		}
+	}
+	if len(dirs) == 0 {
+		return nil, nil
+	}
+
+	walker, err := internal.NewWalker(dirs...)
+	if err != nil {
+		return nil, err
+	}
+	if err := walker.SetRoot(dir); err != nil {
+		return nil, err
+	}
+	walker.MaxDepth = 1
+	files, err := walker.Walk()
+	if err != nil {
+		return nil, err
+	}
+	packPath, err := filepath.Abs(pack)
+	if err != nil {
+		return nil, err
+	}
+	var present []internal.File
+	for _, file := range files {
+		path := filepath.Join(walker.Root(), file.Path)
+		if path == packPath || path == packPath+".lock" {
+			continue
+		}
+		file.Path = filepath.ToSlash(file.Path)
+		present = append(present, file)
+	}
+	return present, nil
+}
+
+func init() {
+	refreshCmd.Flags().BoolVar(&refreshDryRun, "dry-run", false, "List the files that changed without rewriting the pack")
+	refreshCmd.Flags().BoolVar(&refreshChangelog, "changelog", false, "Put a section listing what changed since the last pack, with diff stats, at the top of the pack")
+	rootCmd.AddCommand(refreshCmd)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestFilesBeside(t *testing.T) {
+	dir := t.TempDir()
+	pack := "## File: main.go\n\n```go\npackage main\n```\n\n## File: lib/lib.go\n\n```go\npackage lib\n```\n"
+	for name, content := range map[string]string{
+		"main.go":            "package main\n",
+		"new.go":             "package main\n",
+		"context.md":         pack,
+		"lib/lib.go":         "package lib\n",
+		"lib/extra.go":       "package lib\n",
+		"lib/deeper/deep.go": "package deeper\n",
+		"other/other.go":     "package other\n",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := filesBeside([]byte(pack), dir, filepath.Join(dir, "context.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, file := range files {
+		got = append(got, file.Path)
+	}
+	slices.Sort(got)
+	// The pack itself, subdirectories, and directories the pack has no
+	// files in are left out
+	want := []string{"lib/extra.go", "lib/lib.go", "main.go", "new.go"}
+	if !slices.Equal(got, want) {
+		t.Errorf("filesBeside() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopack/internal"
+)
+
+// repoRoot is a repository given with --repo, and where its files are on
+// disk.
+type repoRoot struct {
+	name string // prefix for the repository's paths in the pack
+	dir  string // absolute path of its working tree
+}
+
+var (
+	repos      []string
+	refresh    bool
+	remotePath string
+
+	repoRoots   []repoRoot // resolved from repos by resolveRepos
+	remoteDir   string     // the [user@]host:/path argument fetched over ssh, if any
+	dockerImage string
+	prURL       string
+	prComments  bool
+)
+
+// resolveRepos fetches any remote --repo arguments into the clone cache and
+// returns the directory to walk in each repository, recording their names
+// for repoPath.
+func resolveRepos(ctx context.Context) ([]string, error) {
+	var dirs []string
+	names := make(map[string]int)
+	for _, repo := range repos {
+		dir, target := repo, repo
+		name := internal.RepoName(repo)
+		if internal.IsRemoteRepo(repo) {
+			remote := internal.ParseRemoteRepo(repo)
+			if remotePath != "" {
+				remote.Path = strings.Trim(filepath.ToSlash(remotePath), "/")
+			}
+			name = internal.RepoName(remote.URL)
+
+			cacheDir, err := internal.RepoCacheDir()
+			if err != nil {
+				return nil, fmt.Errorf("failed to find the cache directory: %w", err)
+			}
+			if _, err := os.Stat(internal.CachedRepoDir(cacheDir, remote)); err == nil && !refresh {
+				statusf("Using cached clone of %s (use --refresh to fetch again)\n", repo)
+			} else {
+				statusf("Cloning %s...\n", repo)
+			}
+			if dir, _, err = internal.CachedClone(ctx, cacheDir, remote, refresh); err != nil {
+				return nil, fmt.Errorf("failed to clone %s: %w", repo, err)
+			}
+			target = filepath.Join(dir, filepath.FromSlash(remote.Path))
+		} else {
+			target = dir
+		}
+
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+		if info, err := os.Stat(abs); err != nil {
+			return nil, fmt.Errorf("--repo %s: %w", repo, err)
+		} else if !info.IsDir() {
+			return nil, fmt.Errorf("--repo %s is not a directory", repo)
+		}
+		if target, err = filepath.Abs(target); err != nil {
+			return nil, err
+		}
+
+		// Keep prefixes unique when two repositories share a name
+		if names[name]++; names[name] > 1 {
+			name = fmt.Sprintf("%s-%d", name, names[name])
+		}
+		repoRoots = append(repoRoots, repoRoot{name: name, dir: abs})
+		dirs = append(dirs, target)
+	}
+	return dirs, nil
+}
+
+// repoPath rewrites a walked path, relative to root, to be relative to the
+// --repo it came from and prefixed with that repository's name.
+func repoPath(root, path string) string {
+	abs := filepath.Join(root, path)
+	for _, repo := range repoRoots {
+		if rel, err := filepath.Rel(repo.dir, abs); err == nil && filepath.IsLocal(rel) {
+			return filepath.Join(repo.name, rel)
+		}
+	}
+	return path
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveRepos(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"service", "lib", "other/lib"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repos = []string{filepath.Join(dir, "service"), filepath.Join(dir, "lib"), filepath.Join(dir, "other", "lib")}
+	defer func() { repos, repoRoots = nil, nil }()
+
+	dirs, err := resolveRepos(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) != 3 {
+		t.Fatalf("resolveRepos() = %q, want 3 directories", dirs)
+	}
+
+	tests := map[string]string{
+		"service/main.go":   "service/main.go",
+		"lib/lib.go":        "lib/lib.go",
+		"other/lib/lib.go":  "lib-2/lib.go",
+		"other/README.md":   "other/README.md",
+		"service/a/b/c.txt": "service/a/b/c.txt",
+	}
+	for path, want := range tests {
+		got := repoPath(dir, filepath.FromSlash(path))
+		if filepath.ToSlash(got) != want {
+			t.Errorf("repoPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+
+	repos, repoRoots = []string{filepath.Join(dir, "missing")}, nil
+	if _, err := resolveRepos(context.Background()); err == nil {
+		t.Error("resolveRepos() with a missing directory succeeded, want error")
+	}
+}
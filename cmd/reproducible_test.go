@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReproducible(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"main.go":     "package main\n",
+		"lib/util.go": "package lib\n",
+		"README.md":   "# demo\n",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Packing again after the files are touched gives the same bytes
+	path := filepath.Join(t.TempDir(), "context.txt")
+	pack := func() []byte {
+		if err := runRoot(t, dir, "--reproducible", "-o", path); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	first := pack()
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "main.go"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if second := pack(); string(second) != string(first) {
+		t.Errorf("packs differ after touching a file:\n%s\n---\n%s", first, second)
+	}
+
+	if err := runRoot(t, dir, "--reproducible", "--sort", "mtime", "-o", path); exitCode(err) != exitUsage {
+		t.Errorf("--sort mtime --reproducible = %v (exit %d), want exit %d", err, exitCode(err), exitUsage)
+	}
+}
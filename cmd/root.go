@@ -1,125 +1,1146 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-	"strconv"
+	"runtime"
+	"slices"
 	"strings"
+	"time"
 
-	"github.com/atotto/clipboard"
 	"github.com/spf13/cobra"
 	"gopack/internal"
 )
 
 var (
-	copy       bool
-	estimate   bool
-	verbose    bool
-	ignorePat  string
-	outputFlag string
+	copy              bool
+	estimate          bool
+	estimateFormat    string
+	estimateWeighted  bool
+	histogram         bool
+	verbose           bool
+	outputs           []string
+	formats           []string
+	why               []string
+	dedupe            bool
+	topN              int
+	showTimings       bool
+	formatFlag        string
+	summary           bool
+	symbolIndex       bool
+	preset            string
+	instructions      string
+	findDuplicates    bool
+	modelName         string
+	compareModels     []string
+	warnTokens        int
+	compressAs        string
+	encryptTo         string
+	chunkSize         int
+	overlap           int
+	copyOSC52         bool
+	copyTo            string
+	copyLimit         string
+	copyLimitBytes    int64 // parsed from copyLimit
+	fileOver          int
+	execCmd           string
+	manifest          string
+	fileModes         bool
+	sinceManifest     string
+	reproduce         bool
+	nativePaths       bool
+	quiet             bool
+	stderrQuietOnPipe bool
+	noColor           bool
+	runTimeout        time.Duration
+	stopTimeout       context.CancelFunc // releases the --timeout timer, called by exit
+	jsonEvents        bool
+	ciMode            bool
+	stream            bool
+	maxTokens         int
+	fitTokens         int
+	collapseShare     float64
+	diffContext       int
+	editorMode        bool
+	plugins           []string
+	runHooks          bool
+
+	redactAudit    bool
+	outline        []string
+	stripLicense   bool
+	sanitize       bool
+	maxLineLength  int
+	longLines      string
+	licenseHeaders []internal.LicenseHeader // removed by --strip-license
+	collapseLines  int
+
+	withFrontMatter bool
+	frontMatter     *internal.FrontMatter // set by --front-matter
+	collapsible     bool
+	permalinks      bool
+	blameMode       string
+	skipReportPath  string
 )
 
 var rootCmd = &cobra.Command{
-	Use:   "gopack [path]",
+	Use:   "gopack [path...]",
 	Short: "Aggregate directory contents into a single formatted string",
 	Long: `GoContextPacker is a CLI tool that traverses a directory,
 respects .gitignore rules, and aggregates file contents into
-a single Markdown-formatted string for easy pasting into LLMs.`,
-	Args: cobra.MaximumNArgs(1),
+a single Markdown-formatted string for easy pasting into LLMs.
+
+Several paths (directories and individual files) may be given; they are
+merged into one pack with paths relative to their common parent directory.
+Paths may also be include globs such as 'internal/**/*.go', relative to
+the current directory like other paths; '*' doesn't cross directories, even
+with --diff.`,
+	Args: cobra.ArbitraryArgs,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Piped output is read by a program, so keep status messages and
+		// progress out of the terminal unless asked for, or told not to
+		// with --stderr-quiet-on-pipe=false
+		if stderrQuietOnPipe && isPipe(os.Stdout) && !cmd.Flags().Changed("quiet") {
+			quiet = true
+		}
+		if err := setupLogging(); err != nil {
+			return withExitCode(exitUsage, err)
+		}
+
+		// Bound the whole run; walking, fetching, formatting, and asking
+		// stop cleanly once it's over
+		if runTimeout > 0 {
+			var ctx context.Context
+			ctx, stopTimeout = context.WithTimeoutCause(cmd.Context(), runTimeout,
+				fmt.Errorf("timed out after %s (--timeout)", runTimeout))
+			cmd.SetContext(ctx)
+		}
+		return nil
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Get the target path (default to current directory)
-		targetPath := "."
-		if len(args) > 0 {
-			targetPath = args[0]
+		// Answer requests from an editor extension until it shuts us down
+		if editorMode {
+			return internal.Serve(os.Stdin, os.Stdout, logger)
 		}
 
-		// Create walker
-		walker, err := internal.NewWalker(targetPath)
+		// Fill in the flags the user left alone from the preset
+		if preset != "" {
+			if err := applyPreset(cmd, preset, args); err != nil {
+				return withExitCode(exitUsage, err)
+			}
+		}
+
+		if !slices.Contains(internal.Formats, formatFlag) {
+			return withExitCode(exitUsage, fmt.Errorf("unknown format %q (expected one of: %s)", formatFlag, strings.Join(internal.Formats, ", ")))
+		}
+		if formatFlag == internal.FormatDiff && diffRef == "" {
+			return withExitCode(exitUsage, fmt.Errorf("--format diff needs --diff <ref> to compare against"))
+		}
+		if stream {
+			switch {
+			case formatFlag != internal.FormatJSONL:
+				return withExitCode(exitUsage, fmt.Errorf("--stream requires --format jsonl"))
+			case redactAudit:
+				return withExitCode(exitUsage, fmt.Errorf("--audit can't be used with --stream"))
+			case ciMode:
+				return withExitCode(exitUsage, fmt.Errorf("--ci can't be used with --stream, which writes files before the totals are known"))
+			case sinceManifest != "":
+				return withExitCode(exitUsage, fmt.Errorf("--since-manifest can't be used with --stream"))
+			case len(outputs) > 0 && !slices.Equal(outputs, []string{stdoutPath}) || copy || copyOSC52 || cmd.Flags().Changed("copy-to") || execCmd != "" || compressAs != "" || encryptTo != "":
+				return withExitCode(exitUsage, fmt.Errorf("--stream writes to stdout; it can't be combined with --output, --copy, --exec, --compress-output, or --encrypt"))
+			}
+		}
+		if diffContext < 0 {
+			return withExitCode(exitUsage, fmt.Errorf("--diff-context can't be negative"))
+		}
+
+		// Compress output if asked to, or if the output file name implies it
+		compression := compressAs
+		if compression != "" && !slices.Contains(internal.Compressions, compression) {
+			return withExitCode(exitUsage, fmt.Errorf("unknown compression %q (expected one of: %s)", compression, strings.Join(internal.Compressions, ", ")))
+		}
+		if encryptTo != "" {
+			if _, err := internal.ParseRecipient(encryptTo); err != nil {
+				return withExitCode(exitUsage, fmt.Errorf("--encrypt: %w", err))
+			}
+		}
+		targets, err := outputTargets()
 		if err != nil {
-			return fmt.Errorf("failed to initialize walker: %w", err)
+			return withExitCode(exitUsage, err)
+		}
+		if copyOSC52 {
+			copyTo = copyToOSC52
+		}
+		if !slices.Contains(copyTargets, copyTo) {
+			return withExitCode(exitUsage, fmt.Errorf("unknown copy target %q (expected one of: %s)", copyTo, strings.Join(copyTargets, ", ")))
+		}
+		if copyLimitBytes, err = parseBytes(copyLimit); err != nil {
+			return withExitCode(exitUsage, fmt.Errorf("invalid --copy-limit: %w", err))
+		}
+		if fileOver < 0 {
+			return withExitCode(exitUsage, fmt.Errorf("--file-over must not be negative"))
+		}
+		if copyTo != copyToClipboard {
+			copy = true
+		}
+		if chunkSize > 0 && (!copy || len(outputs) > 0) {
+			return withExitCode(exitUsage, fmt.Errorf("--chunk-tokens requires --copy"))
+		}
+		if chunkSize < 0 {
+			return withExitCode(exitUsage, fmt.Errorf("--chunk-tokens must be positive"))
+		}
+		if collapseShare != 0 && maxTokens == 0 {
+			return withExitCode(exitUsage, fmt.Errorf("--collapse-dirs requires --max-tokens"))
+		}
+		if redactAudit && !redact {
+			return withExitCode(exitUsage, fmt.Errorf("--audit requires --redact"))
+		}
+		if fitTokens < 0 {
+			return withExitCode(exitUsage, fmt.Errorf("--fit must be positive"))
+		}
+		if collapseShare < 0 || collapseShare > 1 {
+			return withExitCode(exitUsage, fmt.Errorf("--collapse-dirs must be between 0 and 1"))
+		}
+		if overlap != 0 && chunkSize == 0 {
+			return withExitCode(exitUsage, fmt.Errorf("--chunk-overlap requires --chunk-tokens"))
+		}
+		if withFrontMatter && !writesMarkdown(targets) {
+			return withExitCode(exitUsage, fmt.Errorf("--front-matter requires Markdown output (--format markdown)"))
+		}
+		if collapsible && !writesMarkdown(targets) {
+			return withExitCode(exitUsage, fmt.Errorf("--collapsible requires Markdown output (--format markdown)"))
+		}
+		if blameMode != "" && !slices.Contains(internal.BlameModes, blameMode) {
+			return withExitCode(exitUsage, fmt.Errorf("unknown blame mode %q (expected one of: %s)", blameMode, strings.Join(internal.BlameModes, ", ")))
+		}
+		if stripLicense && formatFlag == internal.FormatJSONL {
+			return withExitCode(exitUsage, fmt.Errorf("--strip-license can't be used with --format jsonl, which has nowhere to list the headers"))
+		}
+		if !slices.Contains(internal.LongLineModes, longLines) {
+			return withExitCode(exitUsage, fmt.Errorf("unknown long-lines mode %q (expected one of: %s)", longLines, strings.Join(internal.LongLineModes, ", ")))
+		}
+		if maxLineLength < 0 {
+			return withExitCode(exitUsage, fmt.Errorf("--max-line-length must not be negative"))
+		}
+		if cmd.Flags().Changed("long-lines") && maxLineLength == 0 {
+			return withExitCode(exitUsage, fmt.Errorf("--long-lines requires --max-line-length"))
+		}
+		if blameMode != "" && formatFlag == internal.FormatDiff {
+			return withExitCode(exitUsage, fmt.Errorf("--blame can't annotate --format diff"))
+		}
+		if !slices.Contains(estimateFormats, estimateFormat) {
+			return withExitCode(exitUsage, fmt.Errorf("unknown estimate format %q (expected one of: %s)", estimateFormat, strings.Join(estimateFormats, ", ")))
+		}
+		if cmd.Flags().Changed("estimate-format") || len(compareModels) > 0 {
+			estimate = true
+		}
+		if estimate && estimateFormat == estimateFooter {
+			summary = true
+		}
+		if histogram && !estimate {
+			return withExitCode(exitUsage, fmt.Errorf("--histogram requires --estimate"))
+		}
+		if overlap < 0 {
+			return withExitCode(exitUsage, fmt.Errorf("--chunk-overlap must be positive"))
+		}
+		if compression != "" && len(outputs) == 0 && copy {
+			return withExitCode(exitUsage, fmt.Errorf("compressed output can't be copied to the clipboard; use --output instead"))
+		}
+		if encryptTo != "" && len(outputs) == 0 && copy {
+			return withExitCode(exitUsage, fmt.Errorf("encrypted output can't be copied to the clipboard; use --output instead"))
+		}
+
+		// Resolve the models to compare the estimate across
+		var models []internal.Model
+		for _, name := range compareModels {
+			model, err := internal.LookupModel(name)
+			if err != nil {
+				return err
+			}
+			models = append(models, model)
+		}
+
+		// Determine the token limit to warn about
+		limit, limitName := warnTokens, "the warning threshold"
+		if modelName != "" {
+			model, err := internal.LookupModel(modelName)
+			if err != nil {
+				return err
+			}
+			if !cmd.Flags().Changed("warn-tokens") {
+				limit, limitName = model.ContextWindow, "the "+model.Name+" context window"
+			}
+		}
+
+		// The same tree must always produce the same bytes, so order files by
+		// path unless a deterministic order was chosen
+		if reproduce {
+			if sortOrder == "mtime" {
+				return withExitCode(exitUsage, fmt.Errorf("--sort mtime can't be used with --reproducible (modification times differ between checkouts)"))
+			}
+			if sortOrder == "" {
+				sortOrder = "path"
+			}
+		}
+
+		if showTimings {
+			timings = &runTimings{start: time.Now()}
+		}
+		walker, extras, err := newWalker(cmd.Context(), args)
+		if err != nil {
+			return err
+		}
+
+		// Explain inclusion decisions instead of packing
+		if len(why) > 0 {
+			for _, path := range why {
+				explanation, err := walker.Explain(path)
+				if err != nil {
+					return fmt.Errorf("failed to walk directory: %w", err)
+				}
+				fmt.Printf("%s: %s\n", path, explanation)
+			}
+			return nil
+		}
+
+		// Let the project generate code before the tree is walked
+		trusted := configTrusted()
+		if trusted {
+			if err := runHook(cmd.Context(), "pre-pack", config.Hooks.Pre, walker.Root()); err != nil {
+				return err
+			}
+		}
+
+		// Describe where the pack came from for indexing tools
+		if withFrontMatter {
+			frontMatter = newFrontMatter(cmd.Context(), walker.Root())
+		}
+
+		var skipped int
+		var report *skipReport
+		if skipReportPath != "" || ciMode {
+			root, err := filepath.Abs(walker.Root())
+			if err != nil {
+				return err
+			}
+			report = &skipReport{Root: root}
+		}
+		walker.OnSkip = func(path, reason string) {
+			skipped++
+			logger.Debug("skipped", "path", path, "reason", reason)
+			if jsonEvents {
+				emitEvent(skipEvent{Event: "skip", Path: path, Reason: reason})
+			}
+			if report != nil {
+				report.add(path, reason)
+			}
 		}
 
-		// Walk the directory
-		files, err := walker.Walk()
+		// Write the files out as they're read, leaving out everything that
+		// needs them all at once
+		if stream {
+			var rules []internal.RedactRule
+			if redact {
+				if rules, err = internal.RedactRules(config.Redact); err != nil {
+					return fmt.Errorf("invalid %s: %w", internal.ConfigFile, err)
+				}
+			}
+			count, tokens, err := streamPack(cmd.Context(), walker, extras, rules)
+			if err != nil {
+				return err
+			}
+			if count == 0 {
+				return withExitCode(exitNoFiles, fmt.Errorf("no files matched"))
+			}
+			recordHistory(count, tokens, 0, "stdout, streamed")
+			return nil
+		}
+
+		files, err := collectFiles(cmd.Context(), walker, extras)
 		if err != nil {
-			return fmt.Errorf("failed to walk directory: %w", err)
+			return err
+		}
+		if partial {
+			// Say so in the pack, not just on stderr
+			summary = true
+		}
+		if report != nil {
+			for _, e := range walker.ReadErrors() {
+				report.add(e.Path, "unreadable: "+e.Err.Error())
+			}
+		}
+		if len(files) == 0 {
+			return withExitCode(exitNoFiles, fmt.Errorf("no files matched"))
+		}
+
+		// Packs from Windows read like packs from elsewhere, and apply there
+		for i := range files {
+			files[i].Path = outputPath(files[i].Path)
+		}
+
+		// Show what changed in each file rather than all of it
+		if formatFlag == internal.FormatDiff {
+			diffs, err := internal.FileDiffs(cmd.Context(), walker.Root(), diffRef, diffContext)
+			if err != nil {
+				return fmt.Errorf("failed to diff against %s: %w", diffRef, err)
+			}
+			for i, file := range files {
+				if diff, ok := diffs[filepath.ToSlash(file.Path)]; ok {
+					files[i].Content = []byte(diff)
+				}
+			}
+			// The diffs replace content collectFiles already redacted
+			if redact {
+				redactions = slices.DeleteFunc(redactions, func(r internal.Redaction) bool {
+					_, ok := diffs[filepath.ToSlash(r.Path)]
+					return ok
+				})
+				if files, err = redactFiles(files); err != nil {
+					return err
+				}
+			}
+		}
+
+		// List the secrets collectFiles hid for review. --ci reports the
+		// secrets found even when they aren't hidden
+		if redactAudit {
+			printRedactions(redactions)
+			return nil
+		}
+		secrets := redactions
+		if ciMode && !redact {
+			rules, err := internal.RedactRules(config.Redact)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", internal.ConfigFile, err)
+			}
+			_, secrets = internal.Redact(files, rules)
+		}
+
+		// Leave out what the model already saw in an earlier pack. Its
+		// manifest was written at this point, so hashes compare alike
+		baseline := files
+		var deltaNotes []string
+		if sinceManifest != "" {
+			previous, err := internal.ReadManifest(sinceManifest)
+			if err != nil {
+				return fmt.Errorf("failed to read --since-manifest: %w", err)
+			}
+			var removed []string
+			var unchanged int
+			before := files
+			files, removed, unchanged = previous.Delta(files)
+			if report != nil {
+				report.removed(before, files, stageManifest, "unchanged since --since-manifest "+sinceManifest)
+			}
+			if len(files) == 0 && len(removed) == 0 {
+				return withExitCode(exitNoFiles, fmt.Errorf("nothing changed since %s", sinceManifest))
+			}
+			statusf("%d files added or changed and %d removed since %s; %d unchanged left out\n", len(files), len(removed), sinceManifest, unchanged)
+			deltaNotes = append(deltaNotes, fmt.Sprintf("Delta: only files added or changed since %s; %d unchanged files left out", filepath.Base(sinceManifest), unchanged))
+			if len(removed) > 0 {
+				deltaNotes = append(deltaNotes, "Removed: "+strings.Join(removed, ", "))
+			}
+			// Say what was removed in the pack itself
+			summary = true
 		}
 
 		// Show verbose info
-		if verbose {
+		if verbose && !jsonEvents {
 			fmt.Fprintf(os.Stderr, "Found %d files\n", len(files))
 			for _, file := range files {
 				fmt.Fprintf(os.Stderr, "  %s\n", file.Path)
 			}
 		}
 
+		// Record the original contents before any transformation. A delta
+		// pack records every file, so the next delta starts from here
+		if manifest != "" {
+			if err := writeManifest(manifest, baseline); err != nil {
+				return err
+			}
+		}
+
+		// Annotate lines with the commit, author, and date that last changed them
+		var blamed int
+		if blameMode != "" {
+			if blamed, err = internal.AddBlame(cmd.Context(), files, walker.Root(), blameMode); err != nil {
+				warnf("Can't add blame: %v", err)
+			}
+		}
+
+		// Apply the filter plugins
+		before := files
+		if files, err = runPlugins(cmd.Context(), files, walker.Root(), trusted); err != nil {
+			return err
+		}
+		if report != nil {
+			report.removed(before, files, stagePlugin, "excluded by a --plugin")
+		}
+
+		// Label the workspace modules, then replace duplicate contents with
+		// references
+		notes := append(moduleNotes(files), deltaNotes...)
+		if partial {
+			notes = append(notes, fmt.Sprintf("Partial: the walk stopped after --walk-timeout (%s), so files it hadn't reached are missing", walkTimeout))
+		}
+		if len(redactions) > 0 {
+			notes = append(notes, fmt.Sprintf("Redacted: %d lines in %d files", len(redactions), redactedFiles(redactions)))
+		}
+		if blamed > 0 {
+			notes = append(notes, fmt.Sprintf("Blame: %d files annotated with the last change to each %s", blamed, strings.TrimSuffix(blameMode, "s")))
+		}
+		if sanitize {
+			var removed []internal.InvisibleChar
+			files, removed = internal.SanitizeUnicode(files)
+			notes = append(notes, sanitizeNotes(removed)...)
+		}
+		if patterns := append(slices.Clip(outline), config.Outline...); len(patterns) > 0 || !noDirectives {
+			var count, directed int
+			files, count = internal.OutlineFiles(files, patterns)
+			if !noDirectives {
+				files, directed = internal.OutlineDirected(files)
+				count += directed
+			}
+			if count > 0 {
+				notes = append(notes, fmt.Sprintf("Outlined: %d files reduced to their declarations, without function bodies", count))
+			}
+		}
+		if stripLicense {
+			files, licenseHeaders = internal.StripLicenseHeaders(files)
+			var count int
+			for _, header := range licenseHeaders {
+				count += header.Files
+			}
+			if count > 0 {
+				notes = append(notes, fmt.Sprintf("License headers: removed from %d files and listed once at the top", count))
+			}
+		}
+		if maxLineLength > 0 {
+			var lines, count int
+			files, lines, count = internal.LimitLineLength(files, maxLineLength, longLines)
+			if lines > 0 {
+				verb := "truncated"
+				if longLines == internal.LongLinesWrap {
+					verb = "wrapped"
+				}
+				notes = append(notes, fmt.Sprintf("Long lines: %d lines in %d files %s at %s characters", lines, count, verb, internal.FormatWithCommas(maxLineLength)))
+			}
+		}
+		if collapseLines > 0 || len(config.Transforms) > 0 {
+			var fallback []string
+			if collapseLines > 0 {
+				fallback = append(fallback, fmt.Sprintf("collapse-imports=%d", collapseLines))
+			}
+			var changed map[string]int
+			if files, changed, err = internal.ApplyTransforms(files, config.Transforms, fallback); err != nil {
+				return fmt.Errorf("invalid %s: %w", internal.ConfigFile, err)
+			}
+			for _, name := range internal.Transforms {
+				if n := changed[name]; n > 0 {
+					notes = append(notes, fmt.Sprintf("Transform %s: %d files changed", name, n))
+				}
+			}
+		}
+		if dedupe {
+			var count int
+			files, count = internal.Dedupe(files)
+			if verbose && !jsonEvents {
+				fmt.Fprintf(os.Stderr, "Deduplicated %d identical files\n", count)
+			}
+			if count > 0 {
+				notes = append(notes, fmt.Sprintf("Deduplicated: %d identical files replaced with references", count))
+			}
+		}
+
+		if findDuplicates {
+			found := duplicationNotes(files)
+			for _, note := range found {
+				statusf("%s\n", note)
+			}
+			notes = append(notes, found...)
+		}
+
+		// Link each file to its source on GitHub
+		if permalinks {
+			if _, err := internal.AddPermalinks(cmd.Context(), files, walker.Root()); err != nil {
+				warnf("Can't add permalinks: %v", err)
+			}
+		}
+
+		// Replace directories too big for their share of --max-tokens with a
+		// placeholder
+		if collapseShare > 0 {
+			var collapsed []internal.CollapsedDir
+			before := files
+			files, collapsed = internal.CollapseDirs(files, maxTokens, collapseShare, priorityPatterns())
+			if report != nil {
+				report.removed(before, files, stageCollapse, fmt.Sprintf("directory over %g%% of --max-tokens (--collapse-dirs)", collapseShare*100))
+			}
+			for _, dir := range collapsed {
+				statusf("Collapsed %s/: %d files, ~%s tokens\n", dir.Path, dir.Files, internal.FormatWithCommas(dir.Tokens))
+			}
+			if len(collapsed) > 0 {
+				notes = append(notes, fmt.Sprintf("Collapsed: %d directories over %g%% of the token budget omitted", len(collapsed), collapseShare*100))
+			}
+		}
+
+		// Trim to --max-tokens by area when the project defines a budget
+		if maxTokens > 0 {
+			if len(config.Budget) > 0 {
+				rules, err := internal.ParseBudget(config.Budget)
+				if err != nil {
+					return fmt.Errorf("%s: %w", internal.ConfigFile, err)
+				}
+				var dropped []internal.File
+				if files, dropped, err = fitBudget(cmd.Context(), files, rules, notes); err != nil {
+					return err
+				}
+				if report != nil {
+					report.removed(dropped, nil, stageBudget, fmt.Sprintf("dropped to fit --max-tokens by the budget in %s", internal.ConfigFile))
+				}
+				if len(dropped) > 0 {
+					warnf("Dropped %d files to fit --max-tokens using the budget in %s", len(dropped), internal.ConfigFile)
+					if verbose {
+						for _, file := range dropped {
+							fmt.Fprintf(os.Stderr, "  %s\n", file.Path)
+						}
+					}
+					notes = append(notes, fmt.Sprintf("Trimmed: %d files dropped to fit the token budget", len(dropped)))
+				}
+			}
+		}
+
+		// Shrink the pack, losing as little as possible, until it fits
+		if fitTokens > 0 {
+			before := files
+			var fitted []string
+			if files, fitted, err = fitPack(cmd.Context(), files, notes); err != nil {
+				return err
+			}
+			if report != nil {
+				report.removed(before, files, stageFit, fmt.Sprintf("dropped to fit --fit %d", fitTokens))
+			}
+			for _, note := range fitted {
+				statusf("%s\n", note)
+			}
+			notes = append(notes, fitted...)
+		}
+
+		if jsonEvents {
+			for _, file := range files {
+				emitEvent(includeEvent{
+					Event:       "include",
+					Path:        filepath.ToSlash(file.Path),
+					Bytes:       len(file.Content),
+					Tokens:      internal.FileTokens(file),
+					DuplicateOf: file.DuplicateOf,
+				})
+			}
+		}
+
 		// Format the output
-		formatter := internal.NewFormatter(files)
-		output := formatter.Format()
+		output, err := formatPack(cmd.Context(), files, notes)
+		if err != nil {
+			return err
+		}
 
 		// Show token estimate if requested
+		tokenCount := estimateTokens(files, output)
 		if estimate {
-			tokenCount := formatter.TokenCount()
-			fmt.Fprintln(os.Stderr, formatTokenEstimate(tokenCount))
+			switch estimateFormat {
+			case estimateBox:
+				fmt.Fprintln(os.Stderr, formatTokenEstimate(tokenCount))
+			case estimatePlain:
+				fmt.Println(tokenCount)
+			case estimateJSON:
+				line := estimateLine{Tokens: tokenCount, Files: len(files), Bytes: len(output)}
+				for _, model := range models {
+					tokens := model.Tokens(len(output))
+					line.Models = append(line.Models, modelEstimate{Model: model.Name, Tokens: tokens, Window: model.ContextWindow, Fits: tokens <= model.ContextWindow})
+				}
+				data, _ := json.Marshal(line)
+				fmt.Println(string(data))
+			}
+			if len(models) > 0 && estimateFormat != estimateJSON {
+				fmt.Fprint(os.Stderr, formatModelEstimates(models, len(output)))
+			}
+			if histogram {
+				languages, _ := internal.Stats(files)
+				shares := make([]internal.TokenShare, len(languages))
+				for i, stats := range languages {
+					shares[i] = internal.TokenShare{Name: stats.Language, Tokens: stats.Tokens}
+				}
+				fmt.Fprint(os.Stderr, "\n"+formatHistogram("directory", internal.DirectoryTokens(files))+"\n"+formatHistogram("language", shares))
+			}
+		}
+		if jsonEvents {
+			emitEvent(totalsEvent{Event: "totals", Files: len(files), Skipped: skipped, Bytes: len(output), Tokens: tokenCount})
+		}
+		if ciMode {
+			r := ciReport{
+				Root:  walker.Root(),
+				Files: len(files), Tokens: tokenCount, Bytes: len(output),
+				Limit: limit, LimitName: limitName, MaxTokens: maxTokens,
+				Dropped: droppedAfterWalk(report), Secrets: secrets, Redacted: redact,
+			}
+			if err := reportCI(r); err != nil {
+				return err
+			}
 		}
 
-		// Output the result
-		if outputFlag != "" {
-			// Write to file
-			filePath, err := resolveOutputPath(outputFlag, targetPath)
+		if maxBytes > 0 && int64(len(output)) > maxBytes {
+			return withExitCode(exitTooLarge, fmt.Errorf("output is %s, over --max-output-bytes (%s)",
+				internal.FormatBytes(int64(len(output))), internal.FormatBytes(maxBytes)))
+		}
+		if maxTokens > 0 && tokenCount > maxTokens {
+			return withExitCode(exitTooLarge, fmt.Errorf("estimated ~%s tokens exceeds --max-tokens (%s)",
+				internal.FormatWithCommas(tokenCount), internal.FormatWithCommas(maxTokens)))
+		}
+
+		// Warn when the pack won't fit the context window
+		if limit > 0 && tokenCount > limit {
+			warnf("Estimated ~%s tokens exceeds %s (%s tokens).\n"+
+				"  Hint: use --top to find the largest files, then narrow the pack with --exclude-regex, --ignore-pattern, --max-depth, or --dedupe.",
+				internal.FormatWithCommas(tokenCount), limitName, internal.FormatWithCommas(limit))
+		}
+
+		// Keep the output as a string from here on; a []byte copy of a big
+		// pack would double its memory
+		data := output
+		if compression != "" {
+			compressed, err := internal.Compress([]byte(output), compression)
 			if err != nil {
-				return err
+				return fmt.Errorf("failed to compress output: %w", err)
 			}
+			data = string(compressed)
+		}
+		if data, err = encryptOutput(data); err != nil {
+			return err
+		}
 
-			if err := os.WriteFile(filePath, []byte(output), 0644); err != nil {
+		// Output the result. Print it unless --estimate was used alone
+		// (without --verbose)
+		printing := !estimate || verbose || estimateFormat == estimateFooter
+		var filePath, destination string
+		if len(targets) > 0 {
+			// Write to files
+			if filePath, err = writeTargets(cmd.Context(), targets, files, notes, output); err != nil {
+				return err
+			}
+			for _, target := range targets {
+				if target.path == stdoutPath {
+					destination += ", stdout"
+				} else {
+					destination += ", " + target.path
+				}
+			}
+			// Stream the pack into --exec as well
+			if execCmd != "" {
+				if err := runExec(cmd.Context(), execCmd, data); err != nil {
+					return err
+				}
+				destination += ", exec " + execCmd
+			}
+			destination = strings.TrimPrefix(destination, ", ")
+		} else if execCmd != "" {
+			// Stream the pack into another program
+			if err := runExec(cmd.Context(), execCmd, data); err != nil {
+				return err
+			}
+			destination = "exec " + execCmd
+		} else if spill := spillPath(tokenCount, printing && isTerminal(os.Stdout)); spill != "" {
+			// Too big to copy or scroll past; keep it in a file instead
+			if err := writeOutput(spill, data); err != nil {
 				return fmt.Errorf("failed to write output file: %w", err)
 			}
-			fmt.Fprintf(os.Stderr, "Done! Context written to %s\n", filePath)
+			verb := "print to the terminal"
+			if copy {
+				verb = "copy"
+			}
+			warnf("Estimated ~%s tokens is over --file-over (%s), too much to %s. Wrote it to %s instead.",
+				internal.FormatWithCommas(tokenCount), internal.FormatWithCommas(fileOver), verb, spill)
+			filePath, destination = spill, spill
+		} else if copy && chunkSize > 0 {
+			if err := copyInParts(cmd.Context(), files, notes, chunkSize); err != nil {
+				return err
+			}
+			destination = "clipboard, in parts"
 		} else if copy {
-			if err := clipboard.WriteAll(output); err != nil {
-				fmt.Fprintf(os.Stderr, "⚠ Warning: Failed to copy to clipboard (%v). Printing to terminal instead.\n", err)
+			if err := checkCopySize(int64(len(output))); err != nil {
+				return err
+			}
+			if target, err := copyText(output); err != nil {
+				warnf("Failed to copy to clipboard (%v). Printing to terminal instead.", err)
 				fmt.Print(output)
+				destination = "stdout"
 			} else {
-				fmt.Fprintln(os.Stderr, "Done! Context packed to clipboard.")
+				statusf("Done! Context packed to %s.\n", target)
+				destination = target
+			}
+		} else if printing {
+			if err := checkTerminalSize(int64(len(data))); err != nil {
+				return err
+			}
+			os.Stdout.WriteString(data)
+			destination = "stdout"
+		} else {
+			destination = "estimate only"
+		}
+		recordHistory(len(files), tokenCount, len(output), destination)
+
+		// List what was left out, for reviewing what was sent
+		if report != nil && skipReportPath != "" {
+			if err := report.write(skipReportPath, len(files)); err != nil {
+				return fmt.Errorf("failed to write --skip-report: %w", err)
 			}
-		} else if !estimate || verbose {
-			// Print output unless --estimate was used alone (without --verbose)
-			fmt.Print(output)
+			statusf("Listed %d skipped paths in %s\n", len(report.Skipped), skipReportPath)
+		}
+
+		// Let the project distribute the pack
+		if trusted {
+			if filePath != "" {
+				// The hook runs from the packed root, not the working directory
+				if filePath, err = filepath.Abs(filePath); err != nil {
+					return err
+				}
+			}
+			if err := runHook(cmd.Context(), "post-pack", config.Hooks.Post, walker.Root(), "GOPACK_OUTPUT="+filePath); err != nil {
+				return err
+			}
+		}
+
+		// Report the largest contributors
+		if topN > 0 {
+			report, err := formatTopFiles(cmd.Context(), files, topN)
+			if err != nil {
+				return err
+			}
+			fmt.Fprint(os.Stderr, report)
+		}
+
+		// Show where the time went
+		if timings != nil {
+			fmt.Fprint(os.Stderr, "\n"+formatTimings(timings, time.Since(timings.start)))
 		}
 
 		return nil
 	},
 }
 
-// formatWithCommas adds thousand separators to a number
-func formatWithCommas(num int) string {
-	str := strconv.Itoa(num)
-	var result strings.Builder
+// duplicationNotes describes the near-duplicate files and repeated blocks
+// among files, for the summary.
+func duplicationNotes(files []internal.File) []string {
+	var notes []string
+	near, blocks := internal.FindDuplication(files)
+	for _, pair := range near {
+		notes = append(notes, fmt.Sprintf("Near-duplicate: %s and %s are %.0f%% alike", pair.A, pair.B, pair.Similarity*100))
+	}
+	for _, block := range blocks {
+		notes = append(notes, fmt.Sprintf("Repeated block: %s:%d-%d and %s:%d-%d",
+			block.A, block.StartA, block.EndA, block.B, block.StartB, block.EndB))
+	}
+	return notes
+}
 
-	for i, ch := range str {
-		if i > 0 && (len(str)-i)%3 == 0 {
-			result.WriteRune(',')
+// sanitizeNotes reports the invisible characters --sanitize-unicode
+// removed: bidi controls, which can hide what code does, each with a
+// warning, and the rest with --verbose. It returns a note for the summary.
+func sanitizeNotes(removed []internal.InvisibleChar) []string {
+	if len(removed) == 0 {
+		return nil
+	}
+	files := make(map[string]bool)
+	var bidi []string
+	for _, c := range removed {
+		files[c.Path] = true
+		if c.Kind() == internal.InvisibleBidi {
+			bidi = append(bidi, c.String())
+		} else if verbose && !jsonEvents {
+			fmt.Fprintf(os.Stderr, "  removed %s\n", c)
 		}
-		result.WriteRune(ch)
 	}
+	if len(bidi) > 0 {
+		warnf("Removed %d bidirectional control characters, which can make code read differently from how it runs; review these lines:\n  %s",
+			len(bidi), strings.Join(bidi, "\n  "))
+	}
+	note := fmt.Sprintf("Sanitized: %d invisible characters removed from %d files", len(removed), len(files))
+	if len(bidi) > 0 {
+		note += fmt.Sprintf(", %d of them bidi controls", len(bidi))
+	}
+	return []string{note}
+}
+
+// formatPack formats files as configured by the output flags. Formatting
+// stops when ctx is done or on Ctrl-C.
+func formatPack(ctx context.Context, files []internal.File, notes []string) (string, error) {
+	return formatPackAs(ctx, formatFlag, files, notes)
+}
+
+// formatPackAs formats files as configured by the output flags, but in the
+// given format.
+func formatPackAs(ctx context.Context, format string, files []internal.File, notes []string) (string, error) {
+	formatter := newFormatter(format, files, notes)
+	var output string
+	start := time.Now()
+	err := interruptibly(ctx, func(ctx context.Context) (err error) {
+		output, err = formatter.FormatContext(ctx)
+		return err
+	})
+	if timings != nil {
+		timings.formatting.Add(start)
+	}
+	logger.Debug("formatted", "format", format, "files", len(files), "bytes", len(output), "duration", time.Since(start))
+	return output, err
+}
+
+// newFormatter returns a formatter for files configured by the output
+// flags, in the given format.
+func newFormatter(format string, files []internal.File, notes []string) *internal.Formatter {
+	formatter := internal.NewFormatter(files)
+	formatter.OutputFormat = format
+	formatter.Summary = summary
+	formatter.SummaryNotes = notes
+	formatter.Instructions = instructions
+	formatter.Threads = threads
+	formatter.LicenseHeaders = licenseHeaders
+	formatter.FrontMatter = frontMatter
+	formatter.Collapsible = collapsible
+	formatter.SymbolIndex = symbolIndex
+	formatter.FileHeader = fileHeader
+	if estimateWeighted {
+		formatter.Tokenizer = internal.WeightedTokenizer(files)
+	}
+	return formatter
+}
+
+// outputPath returns a file's path as packs show it: with forward slashes,
+// unless --native-paths asks for the OS separator and --reproducible
+// doesn't rule it out.
+func outputPath(path string) string {
+	if nativePaths && !reproduce {
+		return path
+	}
+	return filepath.ToSlash(path)
+}
+
+// writesMarkdown reports whether any of the pack's output is Markdown.
+func writesMarkdown(targets []outputTarget) bool {
+	return formatFlag == internal.FormatMarkdown ||
+		slices.ContainsFunc(targets, func(t outputTarget) bool { return t.format == internal.FormatMarkdown })
+}
 
-	return result.String()
+// newFrontMatter describes the pack of the tree at root for --front-matter.
+func newFrontMatter(ctx context.Context, root string) *internal.FrontMatter {
+	remote, ref, commit := internal.RepoInfo(ctx, root)
+	if remote == "" {
+		if abs, err := filepath.Abs(root); err == nil {
+			remote = filepath.Base(abs)
+		}
+	}
+	meta := &internal.FrontMatter{Repo: remote, Ref: ref, Commit: commit, Version: version}
+	if !reproduce {
+		meta.Generated = time.Now()
+	}
+	return meta
+}
+
+// fitBudget trims files to --max-tokens according to budget rules. The
+// budget only counts file contents and headers, so it is tightened until
+// the formatted pack, separators and summary included, fits too.
+func fitBudget(ctx context.Context, files []internal.File, rules []internal.BudgetRule, notes []string) (kept, dropped []internal.File, err error) {
+	budget := maxTokens
+	for {
+		kept, dropped = internal.ApplyBudget(files, rules, budget, priorityPatterns())
+		packNotes := notes
+		if len(dropped) > 0 {
+			packNotes = append(slices.Clip(notes), fmt.Sprintf("Trimmed: %d files dropped to fit the token budget", len(dropped)))
+		}
+		output, err := formatPack(ctx, kept, packNotes)
+		if err != nil {
+			return nil, nil, err
+		}
+		tokens := estimateTokens(kept, output)
+		if tokens <= maxTokens || budget <= 0 {
+			return kept, dropped, nil
+		}
+		budget -= tokens - maxTokens
+	}
+}
+
+// printRedactions lists the lines --redact hides for --audit, one per line
+// as "path:line: rule: text", and sums them up on stderr.
+func printRedactions(redactions []internal.Redaction) {
+	for _, r := range redactions {
+		fmt.Printf("%s:%d: %s: %s\n", r.Path, r.Line, r.Rule, r.Text)
+	}
+	if len(redactions) == 0 {
+		statusf("No lines would be redacted.\n")
+		return
+	}
+	statusf("%d lines in %d files would be redacted.\n", len(redactions), redactedFiles(redactions))
+}
+
+// redactedFiles counts the files with redacted lines.
+func redactedFiles(redactions []internal.Redaction) int {
+	files := make(map[string]bool)
+	for _, r := range redactions {
+		files[r.Path] = true
+	}
+	return len(files)
+}
+
+// fitPack shrinks files until the pack fits --fit, returning them with
+// notes on the strategies applied. Like fitBudget, it aims lower when the
+// notes added to the summary push the pack over.
+func fitPack(ctx context.Context, files []internal.File, notes []string) ([]internal.File, []string, error) {
+	budget := fitTokens
+	for {
+		fitted, steps, err := internal.Fit(files, budget, priorityPatterns(), func(files []internal.File) (int, error) {
+			output, err := formatPack(ctx, files, notes)
+			return estimateTokens(files, output), err
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		fitNotes := fitNotes(steps, fitTokens)
+		output, err := formatPack(ctx, fitted, append(slices.Clip(notes), fitNotes...))
+		if err != nil {
+			return nil, nil, err
+		}
+		tokens := estimateTokens(fitted, output)
+		if tokens <= fitTokens || budget <= 0 || len(steps) == 0 {
+			if tokens > fitTokens {
+				warnf("Estimated ~%s tokens still exceeds --fit %s; the files left are too big to shrink further.",
+					internal.FormatWithCommas(tokens), internal.FormatWithCommas(fitTokens))
+			}
+			return fitted, fitNotes, nil
+		}
+		budget -= tokens - fitTokens
+	}
+}
+
+// fitNotes describes the strategies --fit applied, for stderr and the
+// summary.
+func fitNotes(steps []internal.FitStep, budget int) []string {
+	var notes []string
+	for _, step := range steps {
+		var what string
+		switch step.Strategy {
+		case internal.FitDropIgnored:
+			what = fmt.Sprintf("dropped %d files gopack ignores by default", step.Files)
+		case internal.FitStripComments:
+			what = fmt.Sprintf("stripped comments from %d files", step.Files)
+		case internal.FitOutline:
+			what = fmt.Sprintf("outlined %d large files", step.Files)
+		case internal.FitTruncate:
+			what = fmt.Sprintf("truncated %d files", step.Files)
+		case internal.FitDropFiles:
+			what = fmt.Sprintf("dropped the last %d files", step.Files)
+		}
+		notes = append(notes, fmt.Sprintf("Fit to %s tokens: %s (~%s tokens)", internal.FormatWithCommas(budget), what, internal.FormatWithCommas(step.Tokens)))
+	}
+	return notes
+}
+
+// isDevice reports whether an output path names a Windows device, such as
+// NUL, rather than a file.
+func isDevice(path string) bool {
+	return runtime.GOOS == "windows" && internal.IsReservedName(path) && !strings.ContainsAny(path, `.\/`)
+}
+
+// outputLockWait is how long writeOutput waits for another run writing
+// the same file to finish.
+const outputLockWait = 30 * time.Second
+
+// writeOutput writes data to the file at path, replacing it all at once,
+// so a reader never sees a half-written pack. Runs writing the same file
+// at the same time take turns. Devices and pipes, such as /dev/stdout, are
+// written to as they are.
+func writeOutput(path, data string) error {
+	write := func(w io.Writer) error {
+		_, err := io.WriteString(w, data)
+		return err
+	}
+	if info, err := os.Stat(path); isDevice(path) || err == nil && !info.Mode().IsRegular() {
+		file, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		if err := write(file); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	}
+
+	unlock, err := internal.LockFile(path, outputLockWait)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return internal.WriteFileAtomic(internal.LongPath(path), write)
+}
+
+// writeManifest writes a JSON manifest of files to path.
+func writeManifest(path string, files []internal.File) error {
+	data, err := json.MarshalIndent(internal.NewManifest(files), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// formatTopFiles returns a report of the n files contributing the most tokens
+func formatTopFiles(ctx context.Context, files []internal.File, n int) (string, error) {
+	sorted := slices.Clone(files)
+	if err := internal.SortFiles(ctx, sorted, "tokens", false, "", time.Time{}); err != nil {
+		return "", err
+	}
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+
+	width := 0
+	if len(sorted) > 0 {
+		width = len(internal.FormatWithCommas(internal.FileTokens(sorted[0])))
+	}
+
+	var report strings.Builder
+	fmt.Fprintf(&report, "Top %d files by tokens:\n", len(sorted))
+	for _, file := range sorted {
+		fmt.Fprintf(&report, "  %*s  %s\n", width, internal.FormatWithCommas(internal.FileTokens(file)), file.Path)
+	}
+	return report.String(), nil
 }
 
 // resolveOutputPath determines the final output file path
 // If outputPath is empty, returns empty string
-// If outputPath is a directory, returns path/context.txt
+// If outputPath is a directory, returns path/defaultName
 // Otherwise returns the outputPath as-is
-func resolveOutputPath(outputPath string, targetPath string) (string, error) {
+func resolveOutputPath(outputPath string, defaultName string) (string, error) {
 	if outputPath == "" {
 		return "", nil
 	}
 
+	// Writing to a device such as NUL is fine, but a file can't be named
+	// like one on Windows
+	if isDevice(outputPath) {
+		return outputPath, nil
+	}
+	if runtime.GOOS == "windows" && internal.IsReservedName(filepath.Base(outputPath)) {
+		return "", fmt.Errorf("can't write to %s: %s is a reserved device name on Windows", outputPath, filepath.Base(outputPath))
+	}
+
 	// Check if it's a directory
-	info, err := os.Stat(outputPath)
+	info, err := os.Stat(internal.LongPath(outputPath))
 	if err == nil && info.IsDir() {
-		return filepath.Join(outputPath, "context.txt"), nil
+		return filepath.Join(outputPath, defaultName), nil
 	}
 
 	// If the path doesn't exist, treat it as a file path
@@ -127,7 +1148,7 @@ func resolveOutputPath(outputPath string, targetPath string) (string, error) {
 		// Ensure the directory exists
 		dir := filepath.Dir(outputPath)
 		if dir != "." && dir != "" {
-			if err := os.MkdirAll(dir, 0755); err != nil {
+			if err := os.MkdirAll(internal.LongPath(dir), 0755); err != nil {
 				return "", fmt.Errorf("failed to create output directory: %w", err)
 			}
 		}
@@ -143,15 +1164,58 @@ func resolveOutputPath(outputPath string, targetPath string) (string, error) {
 	return outputPath, nil
 }
 
+// Token estimate formats for --estimate-format.
+const (
+	estimateBox    = "box"
+	estimatePlain  = "plain"
+	estimateJSON   = "json"
+	estimateFooter = "footer"
+)
+
+var estimateFormats = []string{estimateBox, estimatePlain, estimateJSON, estimateFooter}
+
+// estimateLine is the token estimate printed by --estimate-format json.
+type estimateLine struct {
+	Tokens int             `json:"tokens"`
+	Files  int             `json:"files"`
+	Bytes  int             `json:"bytes"`
+	Models []modelEstimate `json:"models,omitempty"` // with --models
+}
+
+// modelEstimate is the token estimate for one of the --models.
+type modelEstimate struct {
+	Model  string `json:"model"`
+	Tokens int    `json:"tokens"`
+	Window int    `json:"window"`
+	Fits   bool   `json:"fits"`
+}
+
+// formatModelEstimates renders a table comparing the tokens a pack of the
+// given length takes in each model, and how much of its window that is.
+func formatModelEstimates(models []internal.Model, chars int) string {
+	table := [][]string{{"Model", "Tokens", "Window", "Used", "Fits"}}
+	for _, model := range models {
+		tokens := model.Tokens(chars)
+		used := internal.FormatWithCommas((tokens*100+model.ContextWindow-1)/model.ContextWindow) + "%"
+		fits := "yes"
+		if tokens > model.ContextWindow {
+			fits = "no"
+		}
+		table = append(table, []string{model.Name, "~" + internal.FormatWithCommas(tokens), internal.FormatWithCommas(model.ContextWindow), used, fits})
+	}
+	return formatTable(table)
+}
+
 // formatTokenEstimate returns a professionally formatted token estimate box
 func formatTokenEstimate(tokenCount int) string {
-	formattedCount := formatWithCommas(tokenCount)
+	formattedCount := internal.FormatWithCommas(tokenCount)
 	message := fmt.Sprintf("TOKEN ESTIMATE: ~%s tokens", formattedCount)
 
 	// ANSI color codes
-	cyan := "\033[36m"
-	bold := "\033[1m"
-	reset := "\033[0m"
+	cyan, bold, reset := "\033[36m", "\033[1m", "\033[0m"
+	if !useColor() {
+		cyan, bold, reset = "", "", ""
+	}
 
 	// Calculate box width
 	boxWidth := len(message) + 4
@@ -170,17 +1234,135 @@ func formatTokenEstimate(tokenCount int) string {
 	return box
 }
 
+// modelCompletions returns the known model names for shell completion,
+// described by their context windows.
+func modelCompletions() []string {
+	var models []string
+	for _, model := range internal.Models {
+		models = append(models, model.Name+"\t"+internal.FormatWithCommas(model.ContextWindow)+" tokens")
+	}
+	return models
+}
+
+// useColor reports whether output may be decorated with ANSI colors,
+// honoring --no-color and the NO_COLOR convention (https://no-color.org).
+func useColor() bool {
+	return !noColor && os.Getenv("NO_COLOR") == ""
+}
+
+// statusf logs a status message, which --quiet keeps off stderr.
+// Warnings and errors are always printed.
+func statusf(format string, args ...any) {
+	logger.Info(strings.TrimSuffix(fmt.Sprintf(format, args...), "\n"))
+}
+
+// terminalLimit is the largest output printed to a terminal without asking
+// first.
+const terminalLimit = 1 << 20
+
+// checkTerminalSize asks for confirmation before printing output larger
+// than terminalLimit to a terminal, where megabytes of code scroll by
+// uselessly. Without a terminal to ask on, or if the answer is no, it fails
+// with a hint.
+func checkTerminalSize(size int64) error {
+	if size <= terminalLimit || !isTerminal(os.Stdout) {
+		return nil
+	}
+	problem := fmt.Sprintf("output is %s", internal.FormatBytes(size))
+	if confirm(fmt.Sprintf("⚠ Warning: The %s. Print it to the terminal anyway?", problem)) {
+		return nil
+	}
+	return withExitCode(exitTooLarge, fmt.Errorf("%s, too much for the terminal; use --output to write it to a file, --copy, or pipe it into another command", problem))
+}
+
 func init() {
 	rootCmd.Flags().BoolVarP(&copy, "copy", "c", false, "Copy output to system clipboard")
-	rootCmd.Flags().StringVarP(&outputFlag, "output", "o", "", "Write output to a file (defaults to context.txt in the target directory if a directory is provided)")
+	rootCmd.Flags().StringVar(&execCmd, "exec", "", "Pipe the pack into a shell command's stdin and show its output (e.g. 'llm -m gpt-4o')")
+	rootCmd.Flags().StringVar(&copyLimit, "copy-limit", "10MB", "Ask before copying output larger than this to the clipboard (e.g. 2MB; 0 = no limit)")
+	rootCmd.Flags().IntVar(&fileOver, "file-over", 1_000_000, "Write the pack to context.<ext> instead when it's over this many estimated tokens, rather than copying it or printing it to a terminal (0 = never)")
+	rootCmd.Flags().StringVar(&copyTo, "copy-to", copyToClipboard, "Where --copy puts the pack: "+strings.Join(copyTargets, "|")+" (implies --copy)")
+	completeValues(rootCmd, "copy-to", copyTargets)
+	rootCmd.Flags().BoolVar(&copyOSC52, "copy-osc52", false, "Shorthand for --copy-to osc52; copy via the terminal's OSC 52 escape sequence (works over SSH)")
+	rootCmd.MarkFlagsMutuallyExclusive("copy-osc52", "copy-to")
+	rootCmd.Flags().IntVar(&overlap, "chunk-overlap", 0, "With --chunk-tokens, repeat the last N lines of each part at the start of the next")
+	rootCmd.Flags().IntVar(&chunkSize, "chunk-tokens", 0, "With --copy, copy the pack in parts of at most N tokens, pressing Enter between parts")
+	rootCmd.Flags().StringArrayVarP(&outputs, "output", "o", nil, "Write output to a file, or upload it to an s3:// or gs:// URL (defaults to context.txt in the target directory if a directory is provided; repeatable, with the format taken from each extension)")
+	rootCmd.Flags().StringSliceVar(&formats, "formats", nil, "With --output DIR, write the pack in each of these formats ("+strings.Join(internal.Formats, ",")+") to DIR/context.<ext>")
+	completeValues(rootCmd, "formats", internal.Formats)
+	rootCmd.Flags().StringArrayVar(&plugins, "plugin", nil, "Pass the files through a filter plugin command that can rewrite or skip them (repeatable)")
+	rootCmd.Flags().BoolVar(&runHooks, "run-hooks", false, "Run the hooks and plugins in .gopack.json, which are ignored otherwise")
+	rootCmd.Flags().StringVar(&sinceManifest, "since-manifest", "", "Only pack the files added or changed since the --manifest of an earlier pack, listing those removed in the summary")
+	rootCmd.Flags().StringVar(&manifest, "manifest", "", "Also write a JSON manifest of the packed files with their SHA-256 hashes and sizes")
+	rootCmd.Flags().BoolVar(&fileModes, "file-modes", false, "Record each file's permission bits, such as 0755 for scripts, in its header and the --manifest, for unpack and apply to restore")
+	rootCmd.Flags().BoolVar(&reproduce, "reproducible", false, "Produce byte-identical output for the same tree: sort by path and use forward-slash paths")
+	rootCmd.Flags().BoolVar(&nativePaths, "native-paths", false, "Write file paths with the OS separator (backslashes on Windows) instead of forward slashes")
 	rootCmd.Flags().BoolVar(&estimate, "estimate", false, "Calculate token count and display to stderr")
+	rootCmd.Flags().StringVar(&estimateFormat, "estimate-format", estimateBox, "How --estimate reports the tokens: box (stderr), plain or json (a line on stdout), footer (in the pack's summary); implies --estimate")
+	completeValues(rootCmd, "estimate-format", estimateFormats)
+	rootCmd.Flags().BoolVar(&estimateWeighted, "estimate-weighted", false, "Estimate the pack's tokens at per-file-type ratios (code 3.2, prose 4.3, JSON 2.5 characters per token) instead of 4 for everything")
+	rootCmd.Flags().BoolVar(&histogram, "histogram", false, "With --estimate, chart the tokens by top-level directory and by language")
 	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show which files are being packed")
-	rootCmd.Flags().StringVar(&ignorePat, "ignore-pattern", "", "Add temporary ignore patterns (e.g., *.test.go)")
+	rootCmd.Flags().StringVar(&skipReportPath, "skip-report", "", "Also write a JSON list of every file and directory left out of the pack, with the reason, to this file (e.g. skipped.json)")
+	rootCmd.Flags().BoolVar(&jsonEvents, "json", false, "Write diagnostics (included and skipped files, totals) to stderr as JSON lines, in place of status messages")
+	rootCmd.Flags().BoolVar(&ciMode, "ci", false, "Report the token total, files dropped after the walk, and secrets found as GitHub Actions annotations, and add a table to the job summary")
+	rootCmd.Flags().StringVar(&compressAs, "compress-output", "", "Compress the output with "+strings.Join(internal.Compressions, "|")+" (implied by a .gz or .zst --output name)")
+	completeValues(rootCmd, "compress-output", internal.Compressions)
+	rootCmd.Flags().StringVar(&encryptTo, "encrypt", "", "Encrypt the output to an age recipient (age1...) before writing it, after any compression; default file names get a .age extension")
+	rootCmd.Flags().StringVarP(&formatFlag, "format", "f", internal.FormatText, "Output format: "+strings.Join(internal.Formats, "|")+" (diff needs --diff)")
+	rootCmd.Flags().BoolVar(&stream, "stream", false, "With --format jsonl, write each file to stdout as soon as it's read, before the walk finishes (skips sorting, transformations, and the other steps that need every file)")
+	rootCmd.Flags().IntVar(&diffContext, "diff-context", 3, "With --format diff, lines of unchanged context around each change")
+	completeValues(rootCmd, "format", internal.Formats)
+	rootCmd.Flags().BoolVar(&withFrontMatter, "front-matter", false, "Start Markdown output with a YAML front-matter block (repo, ref, commit, time, file and token counts, version)")
+	rootCmd.Flags().BoolVar(&collapsible, "collapsible", false, "Wrap each file of Markdown output in a collapsible <details> block, for pasting into GitHub or Notion")
+	rootCmd.Flags().BoolVar(&permalinks, "permalinks", false, "Link each file header to the file on GitHub at the current commit")
+	rootCmd.Flags().StringVar(&blameMode, "blame", "", "Annotate each line (or with --blame=blocks, each run of lines from one commit) with its last change from git blame")
+	rootCmd.Flags().Lookup("blame").NoOptDefVal = internal.BlameLines
+	completeValues(rootCmd, "blame", internal.BlameModes)
+	rootCmd.Flags().BoolVar(&symbolIndex, "symbol-index", false, "Append an index of the exported Go types, functions, constants, and variables, with the file and line of each")
+	rootCmd.Flags().StringVar(&preset, "preset", "", "Apply a task preset's flags: "+strings.Join(internal.PresetNames(internal.Config{}), "|")+" (or one from "+internal.ConfigFile+"); flags given override it")
+	rootCmd.RegisterFlagCompletionFunc("preset", completePresets)
+	rootCmd.Flags().StringVar(&instructions, "instructions", "", "Start the pack with instructions telling the model what to do with it")
+	rootCmd.Flags().BoolVar(&summary, "summary", false, "Append a summary section (file count, lines, tokens, transformations)")
+	rootCmd.Flags().StringVar(&modelName, "model", "", "Target model; warns when the pack exceeds its context window (e.g. gpt-4o, claude-sonnet-4)")
+	completeValues(rootCmd, "model", modelCompletions())
+	rootCmd.Flags().StringSliceVar(&compareModels, "models", nil, "Compare the token estimate and window fit across these models (e.g. gpt-4o,claude-sonnet-4,gemini-1.5-pro); implies --estimate")
+	completeValues(rootCmd, "models", modelCompletions())
+	rootCmd.Flags().IntVar(&fitTokens, "fit", 0, "Shrink the pack until its estimated tokens are at most N: drop default-ignored files, strip comments, outline large files, truncate files, then drop the last files, stopping once it fits")
+	rootCmd.Flags().IntVar(&maxTokens, "max-tokens", 0, "Fail (exit code 4) instead of producing output when the estimated tokens exceed N")
+	rootCmd.Flags().Float64Var(&collapseShare, "collapse-dirs", 0, "With --max-tokens, replace the files of directories taking more than this share (0-1) of the budget with a one-line listing")
+	rootCmd.Flags().IntVar(&warnTokens, "warn-tokens", 128_000, "Warn when the estimated tokens exceed this threshold (0 disables)")
+	rootCmd.Flags().StringArrayVar(&outline, "outline", nil, "Pack files matching a path or glob as outlines, keeping declarations but not function bodies (repeatable; e.g. 'github.com/**' for --with-deps modules)")
+	rootCmd.Flags().BoolVar(&redactAudit, "audit", false, "With --redact, list every line that would be redacted, as path:line: rule: text, instead of packing")
+	rootCmd.Flags().BoolVar(&sanitize, "sanitize-unicode", false, "Remove byte order marks, zero-width characters, soft hyphens, and bidi controls from files, warning about the bidi controls")
+	rootCmd.Flags().BoolVar(&stripLicense, "strip-license", false, "Remove license headers repeated across files and list them once at the top")
+	rootCmd.Flags().IntVar(&maxLineLength, "max-line-length", 0, "Shorten lines longer than N characters, such as minified code or embedded base64 (0 disables)")
+	rootCmd.Flags().StringVar(&longLines, "long-lines", internal.LongLinesTruncate, "How --max-line-length shortens long lines: "+strings.Join(internal.LongLineModes, "|")+" (truncate cuts them with a marker, wrap breaks them into lines)")
+	completeValues(rootCmd, "long-lines", internal.LongLineModes)
+	rootCmd.Flags().IntVar(&collapseLines, "collapse-imports", 0, "Replace import blocks longer than N lines with a count of the imports (0 disables)")
+	rootCmd.Flags().BoolVar(&findDuplicates, "find-duplicates", false, "Report near-identical files and blocks of code repeated across files, on stderr and in the summary")
+	rootCmd.Flags().BoolVar(&dedupe, "dedupe", false, "Pack identical file contents once and reference them from duplicates")
+	rootCmd.Flags().IntVar(&topN, "top", 0, "After packing, list the N files contributing the most tokens (stderr)")
+	rootCmd.Flags().BoolVar(&showTimings, "timings", false, "After packing, report how long each phase took, to find where a slow pack spends its time (stderr)")
+	rootCmd.Flags().BoolVar(&editorMode, "editor-server", false, "Serve pack requests as JSON-RPC over stdin/stdout, for editor extensions")
+	rootCmd.Flags().BoolVar(&noHistory, "no-history", false, "Don't record this run in the history listed by gopack history")
+	rootCmd.Flags().StringArrayVar(&why, "why", nil, "Explain which rule includes or excludes a path instead of packing (repeatable)")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Don't print status messages or progress to stderr (warnings and errors are still shown)")
+	rootCmd.PersistentFlags().BoolVar(&stderrQuietOnPipe, "stderr-quiet-on-pipe", true, "Be --quiet when stdout is a pipe, unless --quiet is given either way")
+	rootCmd.PersistentFlags().DurationVar(&runTimeout, "timeout", 0, "Give up if the command takes longer than this, e.g. 30s or 2m (0 means no limit)")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Log messages at this level and above: "+strings.Join(logLevels, "|")+" (debug also logs each skipped file and timings)")
+	completeValues(rootCmd, "log-level", logLevels)
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", logText, "Log format for --log-file, or for stderr if there's no --log-file: "+strings.Join(logFormats, "|"))
+	completeValues(rootCmd, "log-format", logFormats)
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "Also append log messages to this file")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable ANSI colors (also set by the NO_COLOR environment variable)")
+	addFilterFlags(rootCmd)
 }
 
 func main() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
-	}
+	// Errors are reported by exit, with a code scripts can check
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	markUsageErrors(rootCmd)
+	rootCmd.Version = version
+	exit(rootCmd.Execute())
 }
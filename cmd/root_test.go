@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"gopack/internal"
+)
+
+// runRoot runs the root command with args as the command line, then
+// sets its flags back to their defaults for the next run.
+func runRoot(t *testing.T, args ...string) error {
+	t.Helper()
+	defer func() {
+		for _, flags := range []*pflag.FlagSet{rootCmd.Flags(), rootCmd.PersistentFlags()} {
+			resetFlags(t, flags)
+		}
+		rootCmd.SetArgs(nil)
+		rootCmd.SilenceErrors, rootCmd.SilenceUsage = false, false
+		// Drop the --timeout context a run set up
+		if stopTimeout != nil {
+			stopTimeout()
+			stopTimeout = nil
+		}
+		rootCmd.SetContext(context.Background())
+	}()
+	rootCmd.SilenceErrors, rootCmd.SilenceUsage = true, true
+	rootCmd.SetArgs(args)
+	return rootCmd.Execute()
+}
+
+// runRootOutput runs the root command like runRoot and returns what it
+// printed to stdout.
+func runRootOutput(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	stdout, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdout.Close()
+	defer func(f *os.File) { os.Stdout = f }(os.Stdout)
+	os.Stdout = stdout
+	err = runRoot(t, args...)
+	data, readErr := os.ReadFile(stdout.Name())
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	return string(data), err
+}
+
+// resetFlags sets flags back to their defaults, including those a run
+// changed itself, such as --estimate when --estimate-format is given.
+func resetFlags(t *testing.T, flags *pflag.FlagSet) {
+	t.Helper()
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			var values []string
+			if s := strings.Trim(flag.DefValue, "[]"); s != "" {
+				values = strings.Split(s, ",")
+			}
+			slice.Replace(values)
+		} else if err := flag.Value.Set(flag.DefValue); err != nil {
+			t.Errorf("resetting --%s: %v", flag.Name, err)
+		}
+		flag.Changed = false
+	})
+}
+
+func TestExecWithOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("commands use sh")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The pack is written with -o and piped into --exec, not just written
+	out := t.TempDir()
+	path, piped := filepath.Join(out, "context.txt"), filepath.Join(out, "piped.txt")
+	if err := runRoot(t, dir, "-o", path, "--exec", "cat > "+piped); err != nil {
+		t.Fatal(err)
+	}
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(piped)
+	if err != nil {
+		t.Fatalf("--exec didn't run with -o: %v", err)
+	}
+	if string(got) != string(written) || !strings.Contains(string(got), "package main") {
+		t.Errorf("--exec got %q, want the pack written to -o, %q", got, written)
+	}
+}
+
+func TestCopyFlagsExclusive(t *testing.T) {
+	// --copy-osc52 is --copy-to osc52, so another target contradicts it
+	err := runRoot(t, t.TempDir(), "--copy-osc52", "--copy-to", copyToTmux)
+	if err == nil || !strings.Contains(err.Error(), "copy-osc52") || !strings.Contains(err.Error(), "copy-to") {
+		t.Errorf("--copy-osc52 --copy-to tmux error = %v, want the flags named as exclusive", err)
+	}
+}
+
+func TestFormatTokenEstimateColor(t *testing.T) {
+	defer func() { noColor = false }()
+
+	// The box is colored unless --no-color or NO_COLOR turns it off
+	tests := []struct {
+		name    string
+		noColor bool
+		env     string
+		colored bool
+	}{
+		{"default", false, "", true},
+		{"--no-color", true, "", false},
+		{"NO_COLOR", false, "1", false},
+	}
+	for _, tt := range tests {
+		noColor = tt.noColor
+		t.Setenv("NO_COLOR", tt.env)
+		box := formatTokenEstimate(12345)
+		if !strings.Contains(box, "TOKEN ESTIMATE: ~12,345 tokens") {
+			t.Errorf("%s: formatTokenEstimate() = %q, missing the estimate", tt.name, box)
+		}
+		if colored := strings.Contains(box, "\033["); colored != tt.colored {
+			t.Errorf("%s: formatTokenEstimate() = %q, colored %v, want %v", tt.name, box, colored, tt.colored)
+		}
+	}
+}
+
+func TestStatusfQuiet(t *testing.T) {
+	defer func(l *slog.Logger) { logger, quiet = l, false }(logger)
+
+	// --quiet drops status messages but not warnings
+	for _, q := range []bool{false, true} {
+		quiet = q
+		var buf bytes.Buffer
+		logger = slog.New(&consoleHandler{w: &buf})
+		statusf("Done! Context written to %s\n", "context.txt")
+		warnf("Too big")
+		want := "Done! Context written to context.txt\n⚠ Warning: Too big\n"
+		if q {
+			want = "⚠ Warning: Too big\n"
+		}
+		if got := buf.String(); got != want {
+			t.Errorf("--quiet=%v: logged %q, want %q", q, got, want)
+		}
+	}
+}
+
+func TestJSONEvents(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"main.go":    "package main\n",
+		".gitignore": "debug.log\n",
+		"debug.log":  "noise\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stderr, err := os.Create(filepath.Join(t.TempDir(), "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stderr.Close()
+	defer func(f *os.File) { os.Stderr = f }(os.Stderr)
+	os.Stderr = stderr
+
+	// Every line on stderr is an event a wrapper can decode
+	if err := runRoot(t, dir, "--verbose", "--json", "-o", filepath.Join(t.TempDir(), "context.txt")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(stderr.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []map[string]any
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		var event map[string]any
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("stderr line %q isn't JSON: %v", line, err)
+		}
+		events = append(events, event)
+	}
+	var included, skipped []string
+	for _, event := range events {
+		switch event["event"] {
+		case "include":
+			included = append(included, event["path"].(string))
+		case "skip":
+			skipped = append(skipped, event["path"].(string))
+		}
+	}
+	slices.Sort(included)
+	if want := []string{".gitignore", "main.go"}; !slices.Equal(included, want) {
+		t.Errorf("included %q, want %q", included, want)
+	}
+	if !slices.Contains(skipped, "debug.log") {
+		t.Errorf("skipped %q, want debug.log", skipped)
+	}
+	last := events[len(events)-1]
+	if last["event"] != "totals" || last["files"] != float64(2) || last["skipped"] != float64(len(skipped)) {
+		t.Errorf("last event = %v, want totals of 2 files and %d skipped", last, len(skipped))
+	}
+}
+
+func TestEstimateFormat(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// plain and json print one line on stdout, instead of the pack
+	out, err := runRootOutput(t, dir, "--estimate-format", "plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := strconv.Atoi(strings.TrimSuffix(out, "\n"))
+	if err != nil || tokens <= 0 {
+		t.Fatalf("--estimate-format plain printed %q, want a token count", out)
+	}
+
+	out, err = runRootOutput(t, dir, "--estimate-format", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var line estimateLine
+	if err := json.Unmarshal([]byte(out), &line); err != nil || line.Tokens != tokens || line.Files != 1 || line.Bytes == 0 {
+		t.Errorf("--estimate-format json printed %q (%v), want %d tokens in 1 file", out, err, tokens)
+	}
+
+	// footer puts it in the pack's summary
+	path := filepath.Join(t.TempDir(), "context.txt")
+	if err := runRoot(t, dir, "--estimate-format", "footer", "-o", path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "Estimated tokens: ~") {
+		t.Errorf("--estimate-format footer wrote %q, want the estimate in the summary", data)
+	}
+
+	if err := runRoot(t, dir, "--estimate-format", "xml"); exitCode(err) != exitUsage {
+		t.Errorf("--estimate-format xml = %v (exit %d), want exit %d", err, exitCode(err), exitUsage)
+	}
+}
+
+func TestOutputPath(t *testing.T) {
+	defer func() { nativePaths, reproduce = false, false }()
+	path := filepath.Join("internal", "sub", "a.go")
+
+	// Forward slashes on every OS, unless --native-paths keeps the OS's,
+	// which --reproducible overrides
+	tests := []struct {
+		native, reproducible bool
+		want                 string
+	}{
+		{false, false, "internal/sub/a.go"},
+		{true, false, path},
+		{true, true, "internal/sub/a.go"},
+	}
+	for _, tt := range tests {
+		nativePaths, reproduce = tt.native, tt.reproducible
+		if got := outputPath(path); got != tt.want {
+			t.Errorf("outputPath(%q) with --native-paths=%v --reproducible=%v = %q, want %q", path, tt.native, tt.reproducible, got, tt.want)
+		}
+	}
+	nativePaths, reproduce = false, false
+	if runtime.GOOS == "windows" && outputPath(`internal\a.go`) != "internal/a.go" {
+		t.Errorf(`outputPath("internal\a.go") = %q, want forward slashes`, outputPath(`internal\a.go`))
+	}
+}
+
+func TestFormatTopFiles(t *testing.T) {
+	// Each file's tokens are (len(path) + len("File: \n") + len(content)) / 4
+	files := []internal.File{
+		{Path: "small.go", Content: []byte(strings.Repeat("x", 5))},  // 5 tokens
+		{Path: "a.go", Content: []byte(strings.Repeat("x", 47989))},  // 12,000 tokens
+		{Path: "mid.go", Content: []byte(strings.Repeat("x", 1187))}, // 300 tokens
+	}
+
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{2, "Top 2 files by tokens:\n  12,000  a.go\n     300  mid.go\n"},
+		{10, "Top 3 files by tokens:\n  12,000  a.go\n     300  mid.go\n       5  small.go\n"},
+	}
+	for _, tt := range tests {
+		got, err := formatTopFiles(context.Background(), files, tt.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("formatTopFiles(%d) =\n%s\nwant\n%s", tt.n, got, tt.want)
+		}
+	}
+	if files[0].Path != "small.go" {
+		t.Errorf("formatTopFiles() reordered the files it was given")
+	}
+}
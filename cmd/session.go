@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopack/internal"
+)
+
+var (
+	sessionAsk bool
+	sessionDir string
+)
+
+var sessionCmd = &cobra.Command{
+	Use:   "session <prompt> [path...]",
+	Short: "Pack the tree and keep the pack, prompt, and response together",
+	Long: `Session packs the given paths (the current directory by default) with
+the usual filters and saves the pack, the prompt, and a description of the
+run (time, directory, arguments, files, and tokens) in a new bundle
+directory named after the time, so the exact context of a question can be
+audited or reused later.
+
+With --ask, the prompt and pack are also sent to an LLM as "gopack ask"
+does, and the answer is streamed to stdout and saved in the bundle.
+Bundles go in gopack/sessions under the user's cache directory unless
+--dir names another.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		question, paths := args[0], args[1:]
+
+		walker, extras, err := newWalker(cmd.Context(), paths)
+		if err != nil {
+			return err
+		}
+		files, err := collectFiles(cmd.Context(), walker, extras)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			return withExitCode(exitNoFiles, fmt.Errorf("no files to pack"))
+		}
+
+		base := sessionDir
+		if base == "" {
+			if base, err = internal.SessionDir(); err != nil {
+				return err
+			}
+		}
+
+		pack := internal.NewFormatter(files).Format()
+		prompt := askPrompt(question, pack)
+		dir, _ := os.Getwd()
+		session := internal.Session{
+			Time:   time.Now(),
+			Dir:    dir,
+			Args:   os.Args[1:],
+			Tokens: internal.EstimateTokens(prompt),
+		}
+		for _, file := range files {
+			session.Files = append(session.Files, filepath.ToSlash(file.Path))
+		}
+		if sessionAsk {
+			session.Provider = askProviderName()
+			session.Model = askModel
+			if session.Model == "" {
+				session.Model = internal.DefaultModel(session.Provider)
+			}
+		}
+
+		bundle, err := internal.WriteSession(base, session, pack, question+"\n")
+		if err != nil {
+			return fmt.Errorf("failed to save session: %w", err)
+		}
+		if !sessionAsk {
+			statusf("Done! Saved the pack of %d files and the prompt to %s\n", len(files), bundle)
+			return nil
+		}
+
+		response, err := os.Create(filepath.Join(bundle, internal.SessionResponseFile))
+		if err != nil {
+			return fmt.Errorf("failed to save response: %w", err)
+		}
+		err = askLLM(cmd.Context(), prompt, len(files), io.MultiWriter(os.Stdout, response))
+		if closeErr := response.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("failed to save response: %w", closeErr)
+		}
+		statusf("Saved the session to %s\n", bundle)
+		return err
+	},
+}
+
+func init() {
+	sessionCmd.Flags().BoolVar(&sessionAsk, "ask", false, "Send the prompt and pack to an LLM and save its response too")
+	sessionCmd.Flags().StringVar(&sessionDir, "dir", "", "Directory to create session bundles in (default: gopack/sessions in the user cache directory)")
+	sessionCmd.Flags().StringVar(&askProvider, "provider", "", "With --ask, the LLM provider: "+strings.Join(internal.Providers, "|")+" (default: from API keys in the environment)")
+	completeValues(sessionCmd, "provider", internal.Providers)
+	sessionCmd.Flags().StringVar(&askModel, "model", "", "With --ask, the model to ask (default: the provider's default, e.g. gpt-4o)")
+	sessionCmd.Flags().StringVar(&askBaseURL, "base-url", "", "With --ask, the API base URL, for proxies and compatible servers")
+	addFilterFlags(sessionCmd)
+	rootCmd.AddCommand(sessionCmd)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopack/internal"
+)
+
+// skipReport lists what was left out of a pack and why, for --skip-report.
+type skipReport struct {
+	Root        string        `json:"root"` // absolute path of the packed tree
+	GeneratedAt time.Time     `json:"generated_at"`
+	Packed      int           `json:"packed"` // files in the pack
+	Skipped     []skippedPath `json:"skipped"`
+}
+
+// skippedPath is a file or directory left out of the pack. A directory
+// stands for everything in it.
+type skippedPath struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+	Stage  string `json:"stage"` // "walk", "manifest", "plugin", "collapse", "budget", or "fit"
+}
+
+// Stages at which files leave the pack.
+const (
+	stageWalk     = "walk"
+	stageManifest = "manifest"
+	stagePlugin   = "plugin"
+	stageCollapse = "collapse"
+	stageBudget   = "budget"
+	stageFit      = "fit"
+)
+
+// add records a path the walker skipped.
+func (r *skipReport) add(path, reason string) {
+	r.Skipped = append(r.Skipped, skippedPath{Path: path, Reason: reason, Stage: stageWalk})
+}
+
+// removed records the files of before that are missing from after, which
+// a later stage of the pipeline took out for reason.
+func (r *skipReport) removed(before, after []internal.File, stage, reason string) {
+	kept := make(map[string]bool, len(after))
+	for _, file := range after {
+		kept[filepath.ToSlash(file.Path)] = true
+	}
+	for _, file := range before {
+		if path := filepath.ToSlash(file.Path); !kept[path] {
+			r.Skipped = append(r.Skipped, skippedPath{Path: path, Reason: reason, Stage: stage})
+		}
+	}
+}
+
+// write saves the report as indented JSON.
+func (r *skipReport) write(path string, packed int) error {
+	r.Packed = packed
+	r.GeneratedAt = time.Now().UTC()
+	if r.Skipped == nil {
+		r.Skipped = []skippedPath{}
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopack/internal"
+)
+
+func TestSkipReport(t *testing.T) {
+	report := &skipReport{Root: "/src"}
+	report.add("node_modules", "matched built-in default pattern \"node_modules/\"")
+	before := []internal.File{{Path: "a.go"}, {Path: filepath.Join("gen", "b.go")}, {Path: "c.go"}}
+	report.removed(before, before[:1], stagePlugin, "excluded by a --plugin")
+	report.removed(nil, before, stageBudget, "unused")
+
+	path := filepath.Join(t.TempDir(), "skipped.json")
+	if err := report.write(path, 1); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got skipReport
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []skippedPath{
+		{"node_modules", "matched built-in default pattern \"node_modules/\"", stageWalk},
+		{"gen/b.go", "excluded by a --plugin", stagePlugin},
+		{"c.go", "excluded by a --plugin", stagePlugin},
+	}
+	if got.Root != "/src" || got.Packed != 1 || got.GeneratedAt.IsZero() || !reflect.DeepEqual(got.Skipped, want) {
+		t.Errorf("report = %+v, want root /src, 1 packed, and skipped %+v", got, want)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"gopack/internal"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats [path...]",
+	Short: "Report per-language file, line, byte, and token totals",
+	Long: `Stats walks the tree using the same filters as packing and reports
+file counts, lines, bytes, and estimated tokens per language, without
+producing a pack. Use it to size up a repository before deciding how to
+pack it.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		walker, extras, err := newWalker(cmd.Context(), args)
+		if err != nil {
+			return err
+		}
+
+		files, err := collectFiles(cmd.Context(), walker, extras)
+		if err != nil {
+			return err
+		}
+
+		languages, total := internal.Stats(files)
+		fmt.Print(formatStatsTable(append(languages, total)))
+		return nil
+	},
+}
+
+// formatStatsTable renders language summaries as a table with the language
+// column left-aligned and the counts right-aligned.
+func formatStatsTable(rows []internal.LanguageStats) string {
+	table := [][]string{{"Language", "Files", "Lines", "Bytes", "Tokens"}}
+	for _, stats := range rows {
+		table = append(table, []string{
+			stats.Language,
+			internal.FormatWithCommas(stats.Files),
+			internal.FormatWithCommas(stats.Lines),
+			internal.FormatWithCommas(stats.Bytes),
+			internal.FormatWithCommas(stats.Tokens),
+		})
+	}
+	return formatTable(table)
+}
+
+// formatTable renders rows of cells with the first column left-aligned and
+// the rest right-aligned.
+func formatTable(table [][]string) string {
+	widths := make([]int, len(table[0]))
+	for _, row := range table {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+
+	var out strings.Builder
+	for _, row := range table {
+		fmt.Fprintf(&out, "%-*s", widths[0], row[0])
+		for i, cell := range row[1:] {
+			fmt.Fprintf(&out, "  %*s", widths[i+1], cell)
+		}
+		out.WriteString("\n")
+	}
+	return out.String()
+}
+
+// histogramRows and histogramWidth bound the size of a --histogram chart.
+const (
+	histogramRows  = 10
+	histogramWidth = 30
+)
+
+// formatHistogram renders token shares as a bar chart, scaled so the
+// largest bar is histogramWidth wide. Rows past histogramRows are summed
+// into a final "(N more)" row.
+func formatHistogram(title string, shares []internal.TokenShare) string {
+	if len(shares) > histogramRows {
+		rest := internal.TokenShare{Name: fmt.Sprintf("(%d more)", len(shares)-histogramRows+1)}
+		for _, share := range shares[histogramRows-1:] {
+			rest.Tokens += share.Tokens
+		}
+		shares = append(slices.Clone(shares[:histogramRows-1]), rest)
+	}
+
+	total, largest, nameWidth, tokenWidth := 0, 0, 0, 0
+	for _, share := range shares {
+		total += share.Tokens
+		largest = max(largest, share.Tokens)
+		nameWidth = max(nameWidth, utf8.RuneCountInString(share.Name))
+		tokenWidth = max(tokenWidth, len(internal.FormatWithCommas(share.Tokens)))
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "Tokens by %s:\n", title)
+	for _, share := range shares {
+		bar, percent := 0, 0.0
+		if largest > 0 {
+			bar = (share.Tokens*histogramWidth + largest/2) / largest
+			percent = float64(share.Tokens) * 100 / float64(total)
+		}
+		if bar == 0 && share.Tokens > 0 {
+			bar = 1 // every non-empty group is visible
+		}
+		padding := nameWidth - utf8.RuneCountInString(share.Name)
+		fmt.Fprintf(&out, "  %s%s  %-*s  %*s  %4.1f%%\n", share.Name, strings.Repeat(" ", padding),
+			histogramWidth, strings.Repeat("█", bar), tokenWidth, internal.FormatWithCommas(share.Tokens), percent)
+	}
+	return out.String()
+}
+
+func init() {
+	addFilterFlags(statsCmd)
+	rootCmd.AddCommand(statsCmd)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"gopack/internal"
+)
+
+func TestFormatHistogram(t *testing.T) {
+	files := []internal.File{
+		{Path: "internal/a.go", Content: []byte(strings.Repeat("x", 400))},
+		{Path: "internal/sub/b.go", Content: []byte(strings.Repeat("x", 200))},
+		{Path: "cmd/main.go", Content: []byte(strings.Repeat("x", 200))},
+		{Path: "README.md", Content: []byte("x")},
+	}
+	shares := internal.DirectoryTokens(files)
+	if len(shares) != 3 || shares[0].Name != "internal/" || shares[2].Name != internal.RootGroup {
+		t.Fatalf("DirectoryTokens() = %+v", shares)
+	}
+
+	got := formatHistogram("directory", shares)
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	if len(lines) != 4 || lines[0] != "Tokens by directory:" {
+		t.Fatalf("formatHistogram() = %q", got)
+	}
+	if bars := strings.Count(lines[1], "█"); bars != histogramWidth {
+		t.Errorf("largest bar has %d blocks, want %d", bars, histogramWidth)
+	}
+	if bars := strings.Count(lines[2], "█"); bars != histogramWidth/3 {
+		t.Errorf("cmd/ bar has %d blocks, want %d", bars, histogramWidth/3)
+	}
+	if !strings.Contains(lines[3], "█") {
+		t.Errorf("small group has no bar: %q", lines[3])
+	}
+
+	// Groups beyond the row limit are summed into one row
+	var many []internal.TokenShare
+	for i := 0; i < histogramRows+5; i++ {
+		many = append(many, internal.TokenShare{Name: strings.Repeat("d", i+1), Tokens: 10})
+	}
+	got = formatHistogram("directory", many)
+	if n := strings.Count(got, "\n"); n != histogramRows+1 || !strings.Contains(got, "(6 more)") {
+		t.Errorf("formatHistogram() of %d groups = %q", len(many), got)
+	}
+}
+
+func TestFormatStatsTable(t *testing.T) {
+	got := formatStatsTable([]internal.LanguageStats{
+		{Language: "Go", Files: 12, Lines: 1500, Bytes: 48000, Tokens: 15000},
+		{Language: "Markdown", Files: 1, Lines: 20, Bytes: 900, Tokens: 210},
+		{Language: "Total", Files: 13, Lines: 1520, Bytes: 48900, Tokens: 15210},
+	})
+	want := "" +
+		"Language  Files  Lines   Bytes  Tokens\n" +
+		"Go           12  1,500  48,000  15,000\n" +
+		"Markdown      1     20     900     210\n" +
+		"Total        13  1,520  48,900  15,210\n"
+	if got != want {
+		t.Errorf("formatStatsTable() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"gopack/internal"
+)
+
+// streamPack writes each file to stdout as a line of JSON as soon as the
+// walker reads it, for --stream, so consumers can start on a big tree
+// before the walk finishes. Files that only exist once it has, such as
+// --url and --with-deps ones, follow at the end. Secrets matching rules,
+// if any, are redacted. It returns the number of files and tokens written.
+func streamPack(ctx context.Context, walker *internal.Walker, extras []internal.File, rules []internal.RedactRule) (int, int, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	streamed := make(map[string]bool)
+	var count, tokens int
+	var writeErr error
+	write := func(file internal.File) {
+		file.Path = outputPath(file.Path)
+		if writeErr != nil || streamed[file.Path] {
+			return
+		}
+		streamed[file.Path] = true
+		if len(rules) > 0 {
+			redacted, _ := internal.Redact([]internal.File{file}, rules)
+			file = redacted[0]
+		}
+		if writeErr = internal.WriteJSONLine(os.Stdout, file); writeErr != nil {
+			// Stop walking once the consumer has gone away
+			cancel(writeErr)
+			return
+		}
+		count++
+		tokens += internal.FileTokens(file)
+	}
+	walker.OnFile = func(file internal.File) {
+		if len(repoRoots) > 0 {
+			file.Path = repoPath(walker.Root(), file.Path)
+		}
+		write(file)
+	}
+
+	files, err := collectFiles(ctx, walker, extras)
+	if writeErr != nil {
+		return count, tokens, fmt.Errorf("failed to write output: %w", writeErr)
+	}
+	if err != nil {
+		return count, tokens, err
+	}
+	for _, file := range files {
+		write(file)
+	}
+	if writeErr != nil {
+		return count, tokens, fmt.Errorf("failed to write output: %w", writeErr)
+	}
+	return count, tokens, nil
+}
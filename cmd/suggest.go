@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopack/internal"
+)
+
+var (
+	suggestWrite bool
+	suggestYes   bool
+)
+
+var suggestCmd = &cobra.Command{
+	Use:   "suggest [path...]",
+	Short: "Recommend ignore patterns that would save the most tokens",
+	Long: `Suggest walks the tree using the same filters as packing and looks for
+files that are rarely worth their tokens: vendored dependencies, generated
+or build output, lockfiles the defaults miss, test snapshots, and single
+very large files. It lists an ignore pattern for each group with the files
+and estimated tokens it would leave out, largest saving first.
+
+With --write, the patterns are appended to .gopackignore at the top of the
+tree after asking for confirmation (or without asking, with --yes).`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		walker, extras, err := newWalker(cmd.Context(), args)
+		if err != nil {
+			return err
+		}
+		files, err := collectFiles(cmd.Context(), walker, extras)
+		if err != nil {
+			return err
+		}
+
+		suggestions := internal.SuggestIgnores(files)
+		if len(suggestions) == 0 {
+			statusf("No ignore patterns to suggest; nothing stands out as wasted tokens.\n")
+			return nil
+		}
+		total := 0
+		for _, file := range files {
+			total += internal.FileTokens(file)
+		}
+		fmt.Print(formatSuggestions(suggestions, total))
+
+		if !suggestWrite {
+			return nil
+		}
+		path := filepath.Join(walker.Root(), ".gopackignore")
+		if !suggestYes && !confirm(fmt.Sprintf("Add these %d patterns to %s?", len(suggestions), path)) {
+			statusf("Left %s unchanged.\n", path)
+			return nil
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to update .gopackignore: %w", err)
+		}
+		if info, statErr := file.Stat(); statErr == nil && info.Size() > 0 {
+			fmt.Fprintln(file) // set the suggestions apart from the existing patterns
+		}
+		err = internal.WriteSuggestions(file, suggestions)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update .gopackignore: %w", err)
+		}
+		statusf("Done! Added %d patterns to %s\n", len(suggestions), path)
+		return nil
+	},
+}
+
+// formatSuggestions renders suggestions as a table, each row ending with
+// its reason, followed by their combined saving out of the total tokens of
+// the files walked.
+func formatSuggestions(suggestions []internal.Suggestion, total int) string {
+	table := [][]string{{"Pattern", "Files", "Tokens saved"}}
+	reasons := []string{"Reason"}
+	saved := 0
+	for _, s := range suggestions {
+		saved += s.Tokens
+		table = append(table, []string{
+			s.Pattern,
+			internal.FormatWithCommas(s.Files),
+			internal.FormatWithCommas(s.Tokens),
+		})
+		reasons = append(reasons, s.Reason)
+	}
+	percent := 0.0
+	if total > 0 {
+		percent = float64(saved) * 100 / float64(total)
+	}
+
+	var out strings.Builder
+	for i, row := range strings.SplitAfter(formatTable(table), "\n") {
+		if i < len(reasons) {
+			row = strings.TrimSuffix(row, "\n") + "  " + reasons[i] + "\n"
+		}
+		out.WriteString(row)
+	}
+	return out.String() + fmt.Sprintf("\nIgnoring all of these would save ~%s of ~%s tokens (%.0f%%).\n",
+		internal.FormatWithCommas(saved), internal.FormatWithCommas(total), percent)
+}
+
+func init() {
+	suggestCmd.Flags().BoolVar(&suggestWrite, "write", false, "Append the suggested patterns to .gopackignore, after asking")
+	suggestCmd.Flags().BoolVarP(&suggestYes, "yes", "y", false, "With --write, don't ask first")
+	addFilterFlags(suggestCmd)
+	rootCmd.AddCommand(suggestCmd)
+}
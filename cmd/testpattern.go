@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"gopack/internal"
+)
+
+var (
+	testPatterns     []string
+	testPatternFiles []string
+)
+
+var testPatternCmd = &cobra.Command{
+	Use:   "test-pattern [PATTERN] [path...]",
+	Short: "Show which paths ignore patterns match",
+	Long: `Test-pattern checks paths against ignore patterns with the same matcher
+gopack uses when walking, and prints for each path whether it is ignored
+or included and which pattern decided it. The first argument is the
+pattern unless --pattern or --from gives them; the paths don't need to
+exist. Without paths, they are read from stdin one per line, so the output
+of "git ls-files" or "find" can be checked in one go.
+
+The patterns are matched as if written in a single ignore file at the
+root: later patterns take precedence, and a "!" pattern re-includes what
+earlier ones ignored. A path ending in "/", or naming an existing
+directory, is matched as a directory.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tester := &internal.PatternTester{IgnoreCase: ignoreCase}
+		for _, path := range testPatternFiles {
+			if err := tester.AddFile(path); err != nil {
+				return fmt.Errorf("failed to read patterns: %w", err)
+			}
+		}
+		patterns := testPatterns
+		if len(testPatterns) == 0 && len(testPatternFiles) == 0 {
+			if len(args) == 0 {
+				return withExitCode(exitUsage, fmt.Errorf("give a pattern, or use --pattern or --from"))
+			}
+			patterns, args = args[:1], args[1:]
+		}
+		for _, pattern := range patterns {
+			tester.Add(pattern)
+		}
+		for _, pattern := range tester.Patterns() {
+			if problem := internal.PatternProblem(pattern.Pattern); problem != "" {
+				warnf("pattern %q: %s", pattern.Pattern, problem)
+			}
+		}
+
+		paths := args
+		if len(paths) == 0 {
+			if isTerminal(os.Stdin) {
+				return withExitCode(exitUsage, fmt.Errorf("give paths to test, or pipe them to stdin"))
+			}
+			scanner := bufio.NewScanner(os.Stdin)
+			for scanner.Scan() {
+				if line := strings.TrimSpace(scanner.Text()); line != "" {
+					paths = append(paths, line)
+				}
+			}
+			if err := scanner.Err(); err != nil {
+				return fmt.Errorf("failed to read stdin: %w", err)
+			}
+		}
+
+		for _, path := range paths {
+			path = filepath.ToSlash(path)
+			if !strings.HasSuffix(path, "/") {
+				if info, err := os.Stat(path); err == nil && info.IsDir() {
+					path += "/"
+				}
+			}
+			fmt.Println(tester.Match(path))
+		}
+		return nil
+	},
+}
+
+func init() {
+	testPatternCmd.Flags().StringArrayVarP(&testPatterns, "pattern", "p", nil, "Pattern to test (repeatable; later patterns take precedence)")
+	testPatternCmd.Flags().StringArrayVar(&testPatternFiles, "from", nil, "Read patterns from an ignore file (repeatable); a .dockerignore follows Docker's rules")
+	testPatternCmd.Flags().BoolVar(&ignoreCase, "ignore-case", false, "Match patterns case-insensitively (like git's core.ignoreCase)")
+	rootCmd.AddCommand(testPatternCmd)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"gopack/internal"
+)
+
+// runTimings collects --timings over a run.
+type runTimings struct {
+	start      time.Time
+	walk       internal.WalkTimings
+	tokenizing internal.Phase
+	formatting internal.Phase
+}
+
+// timings is set while --timings is collecting, and nil otherwise.
+var timings *runTimings
+
+// estimateTokens estimates the tokens of output, files formatted as a
+// pack, timing it for --timings. --estimate-weighted counts the files at
+// the ratio of their kind. Both only look at lengths, so there's nothing
+// to gain from counting alongside the walk until a real tokenizer is used.
+func estimateTokens(files []internal.File, output string) int {
+	if timings != nil {
+		defer timings.tokenizing.Add(time.Now())
+	}
+	if estimateWeighted {
+		return internal.WeightedTokens(files, output)
+	}
+	return internal.EstimateTokens(output)
+}
+
+// formatTimings renders --timings as a table of phases, with how often
+// each ran and its share of the run, which took total.
+func formatTimings(t *runTimings, total time.Duration) string {
+	walk := t.walk
+	table := [][]string{{"Phase", "Time", "Share", "Count"}}
+	row := func(name string, d time.Duration, count string) {
+		share := "-"
+		if total > 0 {
+			share = fmt.Sprintf("%.0f%%", 100*d.Seconds()/total.Seconds())
+		}
+		table = append(table, []string{name, formatDuration(d), share, count})
+	}
+	row("discovery", walk.Discovery(), countOf(walk.Paths, "path"))
+	row("ignore matching", walk.Ignores.Duration, countOf(walk.Ignores.Count, "path"))
+	row("reading", walk.Reading.Duration-walk.Binary.Duration,
+		countOf(walk.Reading.Count, "file")+", "+internal.FormatBytes(walk.Bytes))
+	row("binary detection", walk.Binary.Duration, countOf(walk.Binary.Count, "file"))
+	row("tokenizing", t.tokenizing.Duration, countOf(t.tokenizing.Count, "estimate"))
+	row("formatting", t.formatting.Duration, countOf(t.formatting.Count, "pack"))
+	other := total - walk.Total.Duration - t.tokenizing.Duration - t.formatting.Duration
+	row("other", max(other, 0), "")
+	row("total", total, "")
+	return "Timings:\n" + formatTable(table)
+}
+
+// countOf writes n of a noun, such as "1 file" or "1,024 files".
+func countOf(n int, noun string) string {
+	if n != 1 {
+		noun += "s"
+	}
+	return internal.FormatWithCommas(n) + " " + noun
+}
+
+// formatDuration rounds a duration for --timings: to the millisecond, or
+// the microsecond below one.
+func formatDuration(d time.Duration) string {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gopack/internal"
+)
+
+func TestFormatTimings(t *testing.T) {
+	timings := &runTimings{
+		walk: internal.WalkTimings{
+			Total:   internal.Phase{Duration: 600 * time.Millisecond, Count: 1},
+			Ignores: internal.Phase{Duration: 100 * time.Millisecond, Count: 1200},
+			Reading: internal.Phase{Duration: 300 * time.Millisecond, Count: 1},
+			Binary:  internal.Phase{Duration: 50 * time.Millisecond, Count: 1},
+			Paths:   1234,
+			Bytes:   2048,
+		},
+		tokenizing: internal.Phase{Duration: 1500 * time.Nanosecond, Count: 2},
+		formatting: internal.Phase{Duration: 200 * time.Millisecond, Count: 1},
+	}
+	got := formatTimings(timings, time.Second)
+
+	// Compare the cells, not the padding between them
+	var rows []string
+	for _, line := range strings.Split(strings.TrimSpace(got), "\n") {
+		rows = append(rows, strings.Join(strings.Fields(line), " "))
+	}
+	want := []string{
+		"Timings:",
+		"Phase Time Share Count",
+		"discovery 200ms 20% 1,234 paths",
+		"ignore matching 100ms 10% 1,200 paths",
+		"reading 250ms 25% 1 file, 2.0 KB",
+		"binary detection 50ms 5% 1 file",
+		"tokenizing 2µs 0% 2 estimates",
+		"formatting 200ms 20% 1 pack",
+		"other 200ms 20%",
+		"total 1s 100%",
+	}
+	if !slices.Equal(rows, want) {
+		t.Errorf("formatTimings() =\n%s\nwant rows %q", got, want)
+	}
+}
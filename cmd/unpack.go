@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopack/internal"
+)
+
+var (
+	unpackOutput    string
+	unpackOverwrite bool
+
+	// identityFile is the age identity file --identity gives commands
+	// reading packs written with --encrypt.
+	identityFile string
+)
+
+var unpackCmd = &cobra.Command{
+	Use:   "unpack <pack>",
+	Short: "Restore the files in a pack to disk",
+	Long: `Unpack parses a pack produced by gopack (text, Markdown, HTML, or JSON Lines,
+optionally gzip or zstd compressed, or encrypted with --encrypt given
+--identity) and writes each file under the output directory.
+Use "-" to read the pack from stdin. Existing files are left alone unless
+--overwrite is given.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		files, err := readPack(args[0])
+		if err != nil {
+			return err
+		}
+
+		var written int
+		for _, file := range files {
+			path := filepath.Join(unpackOutput, filepath.FromSlash(file.Path))
+			if _, err := os.Stat(path); err == nil && !unpackOverwrite {
+				warnf("Skipping %s (already exists, use --overwrite)", path)
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return fmt.Errorf("failed to create directory: %w", err)
+			}
+			if err := os.WriteFile(path, file.Content, 0644); err != nil {
+				return fmt.Errorf("failed to write file: %w", err)
+			}
+			// Packs made with --file-modes say which files are executable
+			if file.Mode != 0 {
+				if err := os.Chmod(path, file.Mode); err != nil {
+					return fmt.Errorf("failed to set file mode: %w", err)
+				}
+			}
+			if verbose {
+				fmt.Fprintf(os.Stderr, "  %s\n", path)
+			}
+			written++
+		}
+
+		statusf("Done! Unpacked %d files to %s\n", written, unpackOutput)
+		return nil
+	},
+}
+
+// readPack reads and parses a pack file, decrypting it with --identity if
+// it is age-encrypted and decompressing it if its name ends in .gz or .zst
+// or its content is compressed. "-" reads from stdin.
+func readPack(name string) ([]internal.File, error) {
+	data, err := readPackData(name)
+	if err != nil {
+		return nil, err
+	}
+	files, err := internal.ParsePack(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return files, nil
+}
+
+// readPackData reads a pack file like readPack, without parsing it.
+func readPackData(name string) ([]byte, error) {
+	var data []byte
+	var err error
+	if name == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pack: %w", err)
+	}
+
+	// Packs written with --encrypt were compressed first, so they're
+	// decrypted first
+	if internal.IsEncrypted(data) {
+		if identityFile == "" {
+			return nil, withExitCode(exitUsage, fmt.Errorf("%s is encrypted; give the age identity to decrypt it with --identity", name))
+		}
+		identities, err := os.ReadFile(identityFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read --identity: %w", err)
+		}
+		if data, err = internal.Decrypt(data, string(identities)); err != nil {
+			return nil, fmt.Errorf("failed to decrypt pack: %w", err)
+		}
+		name = strings.TrimSuffix(name, internal.EncryptExt)
+	}
+
+	compression := internal.CompressionFor(name)
+	if compression == "" {
+		compression = internal.SniffCompression(data)
+	}
+	if compression != "" {
+		if data, err = internal.Decompress(data, compression); err != nil {
+			return nil, fmt.Errorf("failed to decompress pack: %w", err)
+		}
+	}
+	return data, nil
+}
+
+// addIdentityFlag adds --identity to a command that reads packs.
+func addIdentityFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&identityFile, "identity", "i", "", "Decrypt packs written with --encrypt using this age identity file (as made by age-keygen)")
+}
+
+func init() {
+	addIdentityFlag(unpackCmd)
+	unpackCmd.Flags().StringVarP(&unpackOutput, "output", "o", ".", "Directory to restore the files into")
+	unpackCmd.Flags().BoolVar(&unpackOverwrite, "overwrite", false, "Replace files that already exist")
+	unpackCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "List the files being written")
+	rootCmd.AddCommand(unpackCmd)
+}
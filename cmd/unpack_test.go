@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"gopack/internal"
+)
+
+func TestReadPackEncrypted(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key.txt")
+	if err := os.WriteFile(keyFile, []byte("# created: 2024-06-01T12:00:00Z\n"+identity.String()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Written as --compress-output gzip --encrypt leaves it
+	compressed, err := internal.Compress([]byte("File: main.go\npackage main\n"), internal.CompressGzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := internal.Encrypt(compressed, identity.Recipient().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(dir, "context.txt.gz.age")
+	if err := os.WriteFile(name, encrypted, 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { identityFile = "" }()
+
+	if _, err := readPack(name); exitCode(err) != exitUsage || !strings.Contains(err.Error(), "--identity") {
+		t.Errorf("readPack() without --identity = %v, want a usage error asking for it", err)
+	}
+
+	identityFile = keyFile
+	files, err := readPack(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Path != "main.go" || string(files[0].Content) != "package main\n" {
+		t.Errorf("readPack() = %+v, want main.go", files)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"gopack/internal"
+)
+
+var updateCheck bool
+
+var updateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: "Update gopack to the latest release",
+	Long: `Self-update checks GitHub for the latest gopack release and, if it is
+newer than this binary, downloads the build for this platform, verifies it
+against the release's checksums, and replaces the running executable.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		release, err := internal.LatestRelease(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to check for updates: %w", err)
+		}
+		if !internal.IsNewer(release.Tag, version) {
+			statusf("Already up to date (%s).\n", version)
+			return nil
+		}
+		if updateCheck {
+			fmt.Printf("%s is available (current: %s). Run 'gopack self-update' to install it.\n", release.Tag, version)
+			return nil
+		}
+
+		exe, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("failed to locate the gopack binary: %w", err)
+		}
+		if exe, err = filepath.EvalSymlinks(exe); err != nil {
+			return fmt.Errorf("failed to locate the gopack binary: %w", err)
+		}
+
+		statusf("Downloading %s...\n", release.Tag)
+		data, err := release.DownloadBinary(ctx)
+		if err != nil {
+			return err
+		}
+		if err := internal.ReplaceExecutable(exe, data); err != nil {
+			return fmt.Errorf("failed to replace %s: %w", exe, err)
+		}
+
+		statusf("Done! Updated %s from %s to %s\n", exe, version, release.Tag)
+		return nil
+	},
+}
+
+func init() {
+	updateCmd.Flags().BoolVar(&updateCheck, "check", false, "Only report whether a newer release is available")
+	rootCmd.AddCommand(updateCmd)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"gopack/internal"
+)
+
+var (
+	usesFunctions bool
+	usesLSP       bool
+)
+
+var usesCmd = &cobra.Command{
+	Use:   "uses <symbol> [path...]",
+	Short: "Pack a Go symbol's declaration and the code that uses it",
+	Long: `Uses finds the declaration of a Go type, function, method, constant,
+or variable in the tree and packs the files declaring it followed by every
+file that refers to it, for refactoring prompts that need the call sites
+without the whole repository. The pack is written to stdout.
+
+The symbol may be qualified by its package name or import path, as in
+internal.Walker, gopack/internal.Walker, or internal.Walker.Walk for a
+method; an unqualified name must be declared in one package only. Uses
+reads the syntax of the files rather than type-checking them, so a local
+variable of the same name as the symbol counts as a reference.
+
+With --functions, each file is cut down to the declarations that declare
+or refer to the symbol, each marked with the line it starts on.
+
+With --lsp, the references are found by gopls, which type-checks the
+code, so locals named like the symbol are left out and method calls
+through any variable are found. gopls must be installed; without it,
+uses warns and reads the syntax as usual.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		symbol, paths := args[0], args[1:]
+
+		walker, extras, err := newWalker(cmd.Context(), paths)
+		if err != nil {
+			return err
+		}
+		files, err := collectFiles(cmd.Context(), walker, extras)
+		if err != nil {
+			return err
+		}
+
+		find := internal.FindUses
+		if usesLSP {
+			if internal.HasGopls() {
+				find = internal.FindUsesLSP
+			} else {
+				warnf("--lsp: gopls isn't installed; finding references from the syntax instead")
+			}
+		}
+		uses, err := find(files, walker.Root(), symbol, usesFunctions)
+		if err != nil {
+			return withExitCode(exitNoFiles, err)
+		}
+		pack, err := formatPack(cmd.Context(), uses.Files, nil)
+		if err != nil {
+			return err
+		}
+		fmt.Print(pack)
+		statusf("Found %d references to %s (%s) in %d files\n", uses.References, symbol, uses.Package, len(uses.Files))
+		return nil
+	},
+}
+
+func init() {
+	usesCmd.Flags().BoolVar(&usesFunctions, "functions", false, "Only pack the declarations that declare or refer to the symbol, not whole files")
+	usesCmd.Flags().BoolVar(&usesLSP, "lsp", false, "Find the references with gopls, type-checking the code, rather than from the syntax")
+	addFilterFlags(usesCmd)
+	rootCmd.AddCommand(usesCmd)
+}
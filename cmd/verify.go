@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopack/internal"
+)
+
+var verifyManifest string
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify <pack>",
+	Short: "Check that a pack is well-formed",
+	Long: `Verify checks that a pack produced by gopack (text, Markdown, HTML, or JSON Lines,
+optionally gzip or zstd compressed) parses cleanly: every file section is
+complete, no code fence is cut off, pieces of split files join up, dedupe
+references resolve, and the summary's file count, if any, is right.
+
+With --manifest, each packed file's content must also match the hash the
+manifest recorded for it. Problems are listed on stderr, and the command
+fails if there are any, so scripts can check a pack before using it.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := readPackData(args[0])
+		if err != nil {
+			return err
+		}
+
+		var manifest *internal.Manifest
+		if verifyManifest != "" {
+			m, err := internal.ReadManifest(verifyManifest)
+			if err != nil {
+				return fmt.Errorf("failed to read manifest: %w", err)
+			}
+			manifest = &m
+		}
+
+		problems := internal.VerifyPack(data, manifest)
+		for _, problem := range problems {
+			fmt.Fprintf(os.Stderr, "  %s\n", problem)
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("%s failed verification: %d problems found", args[0], len(problems))
+		}
+
+		files, err := internal.ParsePack(data)
+		if err != nil {
+			return err
+		}
+		statusf("%s is a valid pack of %d files.\n", args[0], len(files))
+		return nil
+	},
+}
+
+func init() {
+	addIdentityFlag(verifyCmd)
+	verifyCmd.Flags().StringVar(&verifyManifest, "manifest", "", "Also check the files against the manifest written with the pack")
+	rootCmd.AddCommand(verifyCmd)
+}
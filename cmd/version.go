@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/spf13/cobra"
+	"gopack/internal"
+)
+
+// Build metadata, set at build time with e.g.
+//
+//	go build -ldflags "-X main.version=v1.2.3 -X main.commit=$(git rev-parse HEAD) -X main.date=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd
+var (
+	version = "dev"
+	commit  = ""
+	date    = ""
+)
+
+var versionCheck bool
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print version and build information",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		builtCommit, builtDate := buildInfo()
+		fmt.Printf("gopack %s\n", version)
+		fmt.Printf("  commit:  %s\n", builtCommit)
+		fmt.Printf("  built:   %s\n", builtDate)
+		fmt.Printf("  go:      %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+
+		if versionCheck {
+			release, err := internal.LatestRelease(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("failed to check for updates: %w", err)
+			}
+			if internal.IsNewer(release.Tag, version) {
+				fmt.Printf("\n%s is available. Run 'gopack self-update' to install it.\n", release.Tag)
+			} else {
+				fmt.Println("\nThis is the latest release.")
+			}
+		}
+		return nil
+	},
+}
+
+// buildInfo returns the commit and build date, falling back to the VCS
+// information Go embeds in binaries built from a checkout when they
+// weren't set with -ldflags.
+func buildInfo() (string, string) {
+	builtCommit, builtDate := commit, date
+	if info, ok := debug.ReadBuildInfo(); ok && builtCommit == "" {
+		var modified bool
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				builtCommit = setting.Value
+			case "vcs.time":
+				if builtDate == "" {
+					builtDate = setting.Value
+				}
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+		if modified && builtCommit != "" {
+			builtCommit += " (modified)"
+		}
+	}
+	if builtCommit == "" {
+		builtCommit = "unknown"
+	}
+	if builtDate == "" {
+		builtDate = "unknown"
+	}
+	return builtCommit, builtDate
+}
+
+func init() {
+	versionCmd.Flags().BoolVar(&versionCheck, "check", false, "Also check whether a newer release is available")
+	rootCmd.AddCommand(versionCmd)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gopack/internal"
+)
+
+func TestBuildInfo(t *testing.T) {
+	defer func(c, d string) { commit, date = c, d }(commit, date)
+
+	// Values set with -ldflags win
+	commit, date = "0123abc", "2024-06-01T12:00:00Z"
+	if gotCommit, gotDate := buildInfo(); gotCommit != commit || gotDate != date {
+		t.Errorf("buildInfo() = %q, %q; want %q, %q", gotCommit, gotDate, commit, date)
+	}
+
+	// Test binaries carry no VCS information to fall back on
+	commit, date = "", ""
+	if gotCommit, gotDate := buildInfo(); gotCommit != "unknown" || gotDate != "unknown" {
+		t.Errorf("buildInfo() without metadata = %q, %q; want unknown", gotCommit, gotDate)
+	}
+}
+
+func TestVersionCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"tag_name":"v1.4.0","assets":[]}`)
+	}))
+	defer server.Close()
+	defer func(url, v string) { internal.ReleasesURL, version, versionCheck = url, v, false }(internal.ReleasesURL, version)
+	internal.ReleasesURL = server.URL
+
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"v1.3.2", "v1.4.0 is available. Run 'gopack self-update' to install it.\n"},
+		{"v1.4.0", "This is the latest release.\n"},
+		{"dev", "v1.4.0 is available."},
+	}
+	for _, tt := range tests {
+		version = tt.version
+		got, err := runRootOutput(t, "version", "--check")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(got, "gopack "+tt.version+"\n") || !strings.Contains(got, "  go:      "+runtime.Version()) {
+			t.Errorf("version %s printed %q, want the version and build information", tt.version, got)
+		}
+		if !strings.Contains(got, "\n\n"+tt.want) {
+			t.Errorf("version %s --check printed %q, want %q", tt.version, got, tt.want)
+		}
+	}
+}
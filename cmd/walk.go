@@ -0,0 +1,952 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopack/internal"
+)
+
+// Flags controlling which files are selected, shared by every command that
+// walks a tree.
+var (
+	ignorePat          []string
+	ignoreFile         []string
+	vcsDirs            []string
+	excludeRegex       []string
+	ignoreCase         bool
+	maxDepth           int
+	since              string
+	sinceGit           bool
+	author             string
+	authorShare        float64
+	fromPatch          string
+	withPatch          bool
+	fromTrace          string
+	filesFrom          []string
+	filesFromClipboard bool
+	redact             bool
+	testsFor           []string
+	diffRef            string
+	followLinks        bool
+	externalLinks      bool
+	hidden             bool
+	noHidden           bool
+	noDefaults         bool
+	includeGen         bool
+	includeVend        bool
+	includeEmpty       bool
+	noDirectives       bool
+	directedPriority   []string // files with a "gopack:priority high" directive
+	minBytes           int64
+	noTests            bool
+	goTags             []string
+	withDeps           []string
+	submodules         bool
+	lfsMode            string
+	maxOutput          string
+	maxFiles           int
+	modules            []string
+	sortOrder          string
+	reverse            bool
+	strict             bool
+	stdinLabel         string
+	churnWindow        string
+	priority           []string
+	urls               []string
+	issues             []string
+	concurrency        int
+	rateLimit          float64
+	runs               []string
+	walkTimeout        time.Duration
+
+	churnSince time.Time          // parsed from churnWindow by newWalker
+	config     internal.Config    // loaded from the walk root by newWalker
+	fileHeader *template.Template // config's file_header, parsed by newWalker
+	threads    []internal.Thread  // fetched for --issue by newWalker
+	maxBytes   int64              // parsed from maxOutput by newWalker
+	partial    bool               // set by collectFiles when --walk-timeout cut the walk short
+
+	redactions []internal.Redaction // the lines collectFiles hid for --redact
+
+	workspaceModules []internal.WorkspaceModule // selected by --modules
+)
+
+// errWalkTimeout is why a walk stopped at --walk-timeout.
+var errWalkTimeout = errors.New("--walk-timeout passed")
+
+// fetchTimeout bounds how long each --url or --pr file may take to
+// download, and fetchRetries how often a rate-limited one is tried again.
+const (
+	fetchTimeout = 30 * time.Second
+	fetchRetries = 5
+)
+
+// addFilterFlags registers the file selection flags on a command.
+func addFilterFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.StringArrayVar(&ignorePat, "ignore-pattern", nil, "Add temporary ignore patterns (e.g., *.test.go, repeatable)")
+	flags.StringArrayVar(&ignoreFile, "ignore-file", nil, "Read ignore patterns from files with this name instead of .gitignore and .gopackignore (e.g. .dockerignore, repeatable)")
+	flags.StringSliceVar(&vcsDirs, "vcs-dirs", nil, "Names of the version control directories never packed, replacing .git,.hg,.svn,.jj,.bzr (\"\" packs them all)")
+	flags.StringArrayVar(&excludeRegex, "exclude-regex", nil, "Exclude paths matching a regular expression (relative path, repeatable)")
+	flags.DurationVar(&walkTimeout, "walk-timeout", 0, "Stop looking for files after this long, e.g. 10s, and pack those found so far, marked as partial (0 means no limit)")
+	flags.IntVar(&maxDepth, "max-depth", 0, "Only descend N directory levels below each path (0 = unlimited)")
+	flags.StringVar(&since, "since", "", "Only include files modified after a time (e.g. 2w, 3d, 36h, 2024-06-01)")
+	flags.BoolVar(&sinceGit, "since-git", false, "Use each file's last git commit time for --since instead of its modification time")
+	flags.StringVar(&author, "author", "", "Only include files whose last commit author matches (substring of \"Name <email>\")")
+	flags.Float64Var(&authorShare, "author-share", 0, "With --author, include files where at least this fraction of lines (0-1) are blamed on the author")
+	flags.StringVar(&fromPatch, "from-patch", "", "Pack the full contents of every file touched by a unified diff")
+	flags.BoolVar(&withPatch, "with-patch", false, "With --from-patch, append the diff itself to the output")
+	flags.StringVar(&stdinLabel, "stdin-label", "", "Add text piped to stdin to the pack as a pseudo-file with this name (e.g. \"test output\")")
+	flags.StringArrayVar(&issues, "issue", nil, "Fetch a GitHub issue or pull request thread and write it before the files, with its title, description, and comments (repeatable)")
+	flags.StringArrayVar(&urls, "url", nil, "Fetch a web page and add it to the pack as a pseudo-file, with HTML converted to Markdown (repeatable)")
+	flags.IntVar(&concurrency, "concurrency", 4, "Fetch up to N --url pages or --pr files at once")
+	flags.Float64Var(&rateLimit, "rate-limit", 0, "Start at most N --url or --pr requests per second (0 = unlimited); throttled requests are retried with backoff")
+	flags.StringArrayVar(&runs, "run", nil, "Run a shell command and add its output to the pack as a pseudo-file (e.g. \"go vet ./...\", repeatable)")
+	flags.StringVar(&diffRef, "diff", "", "Only pack files changed between a git ref and the working tree (e.g. main, HEAD~3)")
+	flags.StringVar(&fromTrace, "from-trace", "", "Pack the files mentioned in a stack trace or log, most frequent first")
+	flags.StringArrayVar(&filesFrom, "files-from", nil, "Pack the files, directories, and globs listed in a file, one per line, with # comments (repeatable; - reads stdin)")
+	flags.BoolVar(&filesFromClipboard, "files-from-clipboard", false, "Pack the files listed in the clipboard, one per line, as copied from an editor's file explorer")
+	flags.StringArrayVar(&testsFor, "with-tests-for", nil, "Pack an implementation file together with its tests, found by name and by their imports (repeatable)")
+	flags.BoolVar(&followLinks, "follow-symlinks", false, "Follow symlinked files and directories (cycles are detected)")
+	flags.BoolVar(&externalLinks, "allow-external-symlinks", false, "Include what symlinks to outside the root point to, under "+internal.ExternalPrefix+"/<link path> (implies --follow-symlinks)")
+	flags.BoolVar(&hidden, "hidden", true, "Include dotfiles and dot-directories (other than version control directories)")
+	flags.BoolVar(&noHidden, "no-hidden", false, "Exclude dotfiles and dot-directories")
+	cmd.MarkFlagsMutuallyExclusive("hidden", "no-hidden")
+	flags.BoolVar(&submodules, "submodules", false, "Descend into initialized git submodules, applying their own ignore files")
+	flags.StringVar(&lfsMode, "lfs", internal.LFSSkip, "Handle Git LFS pointer files: "+strings.Join(internal.LFSModes, "|")+" (smudge fetches the real content with git-lfs)")
+	completeValues(cmd, "lfs", internal.LFSModes)
+	flags.BoolVar(&noDefaults, "no-default-ignores", false, "Don't skip lockfiles, minified assets, dist/, and coverage/ by default")
+	flags.BoolVar(&includeGen, "include-generated", false, "Include generated code (DO NOT EDIT headers, *.pb.go, mocks, linguist-generated in .gitattributes)")
+	flags.BoolVar(&includeVend, "include-vendored", false, "Include files marked linguist-vendored in .gitattributes")
+	flags.BoolVar(&noDirectives, "no-directives", false, "Disregard gopack:ignore, gopack:outline-only, and gopack:priority comments in files")
+	flags.BoolVar(&includeEmpty, "include-empty", false, "Include empty and whitespace-only files (.gitkeep, bare __init__.py)")
+	flags.Int64Var(&minBytes, "min-bytes", 0, "Exclude files smaller than this many bytes")
+	flags.BoolVar(&noTests, "no-tests", false, "Exclude test files and directories (*_test.go, *.spec.ts, test_*.py, __tests__/, ...)")
+	flags.StringSliceVar(&goTags, "go-tags", nil, "Exclude Go files not built for these GOOS/GOARCH values and build tags (e.g. linux,amd64,integration)")
+	flags.StringArrayVar(&repos, "repo", nil, "Pack a local or remote git repository, with paths prefixed by its name (repeatable)")
+	flags.StringVar(&remotePath, "remote-path", "", "Only fetch and pack this subdirectory of remote repositories, or this directory of a --docker-image (e.g. services/auth)")
+	flags.StringVar(&prURL, "pr", "", "Pack the files a GitHub pull request changes, with their full contents at its head commit (a pull request URL)")
+	flags.BoolVar(&prComments, "pr-comments", false, "With --pr, also pack the pull request's description and review comments")
+	flags.StringVar(&dockerImage, "docker-image", "", "Pack a directory of a container image's filesystem, its WORKDIR unless --remote-path is given (e.g. myapp:latest)")
+	flags.BoolVar(&refresh, "refresh", false, "Fetch remote --repo repositories again instead of using cached clones")
+	flags.StringSliceVar(&modules, "modules", nil, "Pack only these modules of the go.work workspace in the current directory (e.g. api,worker)")
+	flags.StringArrayVar(&withDeps, "with-deps", nil, "Also pack the source of a Go module dependency, from vendor/ or the module cache (repeatable)")
+	flags.StringVar(&sortOrder, "sort", "", "Order files by "+strings.Join(internal.SortOrders, "|")+" (default: discovery order)")
+	completeValues(cmd, "sort", internal.SortOrders)
+	flags.StringVar(&churnWindow, "churn-window", "", "With --sort git-churn, only count commits in this window (e.g. 90d, 6w, 2024-01-01)")
+	flags.BoolVar(&reverse, "reverse", false, "Reverse the output order")
+	flags.StringArrayVar(&priority, "priority", nil, "Put files matching a path or glob first and never drop them when trimming (repeatable)")
+	flags.BoolVar(&redact, "redact", false, "Replace secrets (private keys, cloud and API tokens, passwords, and the redact patterns in "+internal.ConfigFile+") with [REDACTED:rule]")
+	flags.BoolVar(&strict, "strict", false, "Fail if any file or directory can't be read, instead of skipping it with a warning")
+	flags.IntVar(&maxFiles, "max-files", 20000, "Abort once more than N files would be packed, listing the directories with the most (0 = no limit)")
+	flags.StringVar(&maxOutput, "max-output-bytes", "50MB", "Abort once the files read or the output exceed this size (e.g. 200MB, 2GB; 0 = no limit)")
+	flags.BoolVar(&ignoreCase, "ignore-case", false, "Match ignore patterns, globs, and regexes case-insensitively (like git's core.ignoreCase)")
+}
+
+// newWalker creates a walker for the given path arguments configured from
+// the filter flags. It also returns any extra pseudo-files (such as the
+// --with-patch diff) to append after the walked files. Fetching --url pages
+// stops when ctx is done.
+func newWalker(ctx context.Context, args []string) (*internal.Walker, []internal.File, error) {
+	if sortOrder != "" && !slices.Contains(internal.SortOrders, sortOrder) {
+		return nil, nil, fmt.Errorf("unknown sort order %q (expected one of: %s)", sortOrder, strings.Join(internal.SortOrders, ", "))
+	}
+	if !slices.Contains(internal.LFSModes, lfsMode) {
+		return nil, nil, fmt.Errorf("unknown --lfs mode %q (expected one of: %s)", lfsMode, strings.Join(internal.LFSModes, ", "))
+	}
+	if authorShare < 0 || authorShare > 1 {
+		return nil, nil, fmt.Errorf("--author-share must be between 0 and 1")
+	}
+
+	if maxFiles < 0 {
+		return nil, nil, fmt.Errorf("--max-files can't be negative")
+	}
+
+	var err error
+	if maxBytes, err = parseBytes(maxOutput); err != nil {
+		return nil, nil, fmt.Errorf("invalid --max-output-bytes: %w", err)
+	}
+
+	if churnWindow != "" {
+		var err error
+		if churnSince, err = parseSince(churnWindow, time.Now()); err != nil {
+			return nil, nil, fmt.Errorf("invalid --churn-window value %q (use e.g. 90d, 6w, or 2024-01-01)", churnWindow)
+		}
+	}
+
+	var extras []internal.File
+	var fromCwd bool
+
+	// Remote repositories given as paths are packed like --repo
+	var local []string
+	for _, arg := range args {
+		if internal.IsRemoteRepo(arg) {
+			repos = append(repos, arg)
+		} else {
+			local = append(local, arg)
+		}
+	}
+	args = local
+
+	// A directory on another machine is copied over ssh and packed in its
+	// place
+	for i, arg := range args {
+		remote, ok := internal.ParseRemoteDir(arg)
+		if _, err := os.Stat(arg); !ok || err == nil {
+			continue
+		}
+		if len(args) > 1 {
+			return nil, nil, fmt.Errorf("a remote directory (%s) can't be combined with other paths", arg)
+		}
+		cacheDir, err := internal.RemoteCacheDir()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to find the cache directory: %w", err)
+		}
+		statusf("Fetching %s over ssh...\n", arg)
+		if args[i], err = internal.FetchRemoteDir(ctx, remote, cacheDir); err != nil {
+			return nil, nil, fmt.Errorf("failed to fetch %s: %w", arg, err)
+		}
+		remoteDir = arg
+	}
+	if remotePath != "" && !slices.ContainsFunc(repos, internal.IsRemoteRepo) && dockerImage == "" {
+		return nil, nil, fmt.Errorf("--remote-path requires a remote repository or --docker-image")
+	}
+
+	// Copy a directory out of a container image and pack it in place of
+	// the paths
+	if dockerImage != "" {
+		if len(args) > 0 || len(repos) > 0 {
+			return nil, nil, fmt.Errorf("--docker-image can't be combined with paths or --repo")
+		}
+		dir := remotePath
+		if dir == "" {
+			if dir, err = internal.ImageWorkdir(ctx, dockerImage); err != nil {
+				return nil, nil, fmt.Errorf("failed to inspect %s: %w", dockerImage, err)
+			}
+			if dir == "" || dir == "/" {
+				return nil, nil, fmt.Errorf("%s has no WORKDIR; give the directory to pack with --remote-path", dockerImage)
+			}
+		}
+		cacheDir, err := internal.RemoteCacheDir()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to find the cache directory: %w", err)
+		}
+		statusf("Copying %s out of %s...\n", dir, dockerImage)
+		root, err := internal.FetchImagePath(ctx, dockerImage, filepath.ToSlash(dir), cacheDir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to copy %s out of %s: %w", dir, dockerImage, err)
+		}
+		args = []string{root}
+	}
+
+	// Pack the files a pull request changes, as of its head commit
+	if prComments && prURL == "" {
+		return nil, nil, fmt.Errorf("--pr-comments requires --pr")
+	}
+	if prURL != "" {
+		if len(args) > 0 || len(repos) > 0 {
+			return nil, nil, fmt.Errorf("--pr can't be combined with paths, --repo, or --docker-image")
+		}
+		pr, err := internal.ParsePullRequest(prURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		cacheDir, err := internal.RemoteCacheDir()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to find the cache directory: %w", err)
+		}
+		fetcher, err := newFetcher()
+		if err != nil {
+			return nil, nil, err
+		}
+		statusf("Fetching %s...\n", pr)
+		var root string
+		var discussion internal.File
+		err = interruptibly(ctx, func(ctx context.Context) error {
+			info, err := internal.FetchPullRequest(ctx, pr)
+			if err != nil {
+				return err
+			}
+			if root, err = internal.FetchPullRequestFiles(ctx, fetcher, pr, info, cacheDir); err != nil {
+				return err
+			}
+			if prComments {
+				discussion, err = internal.PullRequestDiscussion(ctx, pr, info)
+			}
+			return err
+		})
+		if exitCode(err) == exitCanceled {
+			return nil, nil, err
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to fetch %s: %w", pr, err)
+		}
+		args = []string{root}
+		if prComments {
+			extras = append(extras, discussion)
+		}
+	}
+
+	// Merge several repositories, cloning remote ones
+	if len(repos) > 0 {
+		if len(args) > 0 || len(modules) > 0 || fromPatch != "" || fromTrace != "" || len(filesFrom) > 0 || filesFromClipboard || diffRef != "" || len(testsFor) > 0 {
+			return nil, nil, fmt.Errorf("--repo can't be combined with paths, --modules, --from-patch, --from-trace, --files-from, --files-from-clipboard, --diff, or --with-tests-for")
+		}
+		dirs, err := resolveRepos(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		args = dirs
+	}
+
+	// Select members of a go.work workspace
+	if len(modules) > 0 {
+		if len(args) > 0 || fromPatch != "" || fromTrace != "" || len(filesFrom) > 0 || filesFromClipboard {
+			return nil, nil, fmt.Errorf("--modules can't be combined with paths, --from-patch, --from-trace, --files-from, or --files-from-clipboard")
+		}
+		all, err := internal.ReadWorkspace(".")
+		if os.IsNotExist(err) {
+			return nil, nil, fmt.Errorf("--modules requires a %s file in the current directory", internal.WorkspaceFile)
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", internal.WorkspaceFile, err)
+		}
+		if workspaceModules, err = internal.SelectModules(all, modules); err != nil {
+			return nil, nil, err
+		}
+		for _, module := range workspaceModules {
+			args = append(args, filepath.FromSlash(module.Dir))
+		}
+		fromCwd = true
+	}
+
+	// Narrow the paths to the files changed since a ref
+	if diffRef != "" {
+		paths, err := internal.ChangedFiles(ctx, ".", diffRef, args...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list changes since %s: %w", diffRef, err)
+		}
+		if len(paths) == 0 {
+			return nil, nil, withExitCode(exitNoFiles, fmt.Errorf("no files changed since %s", diffRef))
+		}
+		args = make([]string, len(paths))
+		for i, path := range paths {
+			args[i] = filepath.FromSlash(path)
+		}
+		fromCwd = true
+	}
+
+	// Add files touched by a patch
+	if fromPatch != "" {
+		paths, patch, err := readPatch(fromPatch)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(args) == 0 && len(paths) == 0 {
+			return nil, nil, fmt.Errorf("patch %s doesn't touch any existing files", fromPatch)
+		}
+		args = append(args, paths...)
+		fromCwd = true
+		if withPatch {
+			extras = append(extras, internal.File{Path: filepath.ToSlash(filepath.Clean(fromPatch)), Content: patch})
+		}
+	}
+
+	// Add files mentioned in a stack trace or log
+	if fromTrace != "" {
+		paths, err := readTrace(fromTrace)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(args) == 0 && len(paths) == 0 {
+			return nil, nil, fmt.Errorf("no files from %s were found under the current directory", fromTrace)
+		}
+		args = append(args, paths...)
+		fromCwd = true
+	}
+
+	// Add the paths listed in curated context files
+	for _, list := range filesFrom {
+		paths, err := readFilesFrom(list)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(paths) == 0 {
+			return nil, nil, fmt.Errorf("--files-from %s lists no paths", list)
+		}
+		args = append(args, paths...)
+		fromCwd = true
+	}
+	if filesFromClipboard {
+		paths, err := readClipboardFiles()
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(paths) == 0 {
+			return nil, nil, fmt.Errorf("the clipboard lists no paths")
+		}
+		statusf("Packing %d paths from the clipboard\n", len(paths))
+		args = append(args, paths...)
+		fromCwd = true
+	}
+
+	// Add implementation files and the tests that go with them
+	if len(testsFor) > 0 {
+		if noTests {
+			return nil, nil, fmt.Errorf("--with-tests-for can't be combined with --no-tests")
+		}
+		for _, path := range testsFor {
+			if info, err := os.Stat(path); err != nil {
+				return nil, nil, fmt.Errorf("--with-tests-for: %w", err)
+			} else if info.IsDir() {
+				return nil, nil, fmt.Errorf("--with-tests-for %s: give a file, not a directory", path)
+			}
+		}
+		tests, err := internal.TestsFor(".", testsFor)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to find tests: %w", err)
+		}
+		statusf("Found %d test files for %d files\n", len(tests), len(testsFor))
+		args = append(args, testsFor...)
+		for _, path := range tests {
+			args = append(args, filepath.FromSlash(path))
+		}
+		fromCwd = true
+	}
+
+	// Attach piped input, such as test output or logs, next to the code
+	if stdinLabel != "" {
+		if isTerminal(os.Stdin) {
+			return nil, nil, fmt.Errorf("--stdin-label needs input piped to stdin")
+		}
+		input, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read stdin: %w", err)
+		}
+		extras = append(extras, internal.File{Path: stdinLabel, Content: input})
+	}
+
+	// Fetch documentation that lives outside the repository
+	for _, rawURL := range urls {
+		if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
+			return nil, nil, fmt.Errorf("--url %q must be an http or https URL", rawURL)
+		}
+	}
+	if len(urls) > 0 {
+		fetcher, err := newFetcher()
+		if err != nil {
+			return nil, nil, err
+		}
+		statusf("Fetching %d URLs...\n", len(urls))
+		var pages []internal.File
+		err = interruptibly(ctx, func(ctx context.Context) (err error) {
+			pages, err = fetcher.FetchAll(ctx, urls)
+			return err
+		})
+		if exitCode(err) == exitCanceled {
+			return nil, nil, err
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to fetch --url: %w", err)
+		}
+		for _, page := range pages {
+			logger.Debug("fetched", "url", page.Path, "bytes", len(page.Content))
+		}
+		extras = append(extras, pages...)
+	}
+
+	// Fetch the issues the pack is for
+	threads = nil
+	for _, rawURL := range issues {
+		statusf("Fetching %s...\n", rawURL)
+		var thread internal.Thread
+		err := interruptibly(ctx, func(ctx context.Context) (err error) {
+			thread, err = internal.FetchIssue(ctx, rawURL)
+			return err
+		})
+		if exitCode(err) == exitCanceled {
+			return nil, nil, err
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to fetch --issue: %w", err)
+		}
+		threads = append(threads, thread)
+	}
+
+	// Attach diagnostics, such as vet or test output
+	for _, command := range runs {
+		statusf("Running %s...\n", command)
+		output, err := runCapture(ctx, command)
+		if err != nil {
+			return nil, nil, err
+		}
+		extras = append(extras, output)
+	}
+
+	// Create walker (defaults to the current directory)
+	walker, err := internal.NewWalker(args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize walker: %w", err)
+	}
+
+	// Diff, patch, trace, and test paths are relative to the current directory, so keep
+	// them that way rather than rooting at their common parent
+	if fromCwd {
+		if err := walker.SetRoot("."); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	walker.IgnoreCase = ignoreCase
+	walker.MaxDepth = maxDepth
+	walker.FollowSymlinks = followLinks || externalLinks
+	walker.AllowExternalSymlinks = externalLinks
+	walker.SkipHidden = noHidden || !hidden
+	if noDefaults {
+		walker.DefaultIgnores = nil
+	}
+	walker.IncludeGenerated = includeGen
+	walker.IncludeVendored = includeVend
+	walker.IncludeEmpty = includeEmpty
+	walker.NoDirectives = noDirectives
+	walker.FileModes = fileModes
+	walker.MinBytes = minBytes
+	walker.SkipTests = noTests
+	walker.Submodules = submodules
+	walker.LFS = lfsMode
+	walker.MaxBytes = maxBytes
+	walker.MaxFiles = maxFiles
+	if len(goTags) > 0 {
+		ctx := internal.GoBuildContext(goTags)
+		walker.GoBuild = &ctx
+	}
+	walker.IgnorePatterns = ignorePat
+	if len(ignoreFile) > 0 {
+		for _, name := range ignoreFile {
+			if name == "" || strings.ContainsAny(name, `/\`) {
+				return nil, nil, fmt.Errorf("invalid --ignore-file %q: give a file name, which is looked for in every directory", name)
+			}
+		}
+		walker.IgnoreFiles = ignoreFile
+	}
+	if since != "" {
+		walker.Since, err = parseSince(since, time.Now())
+		if err != nil {
+			return nil, nil, err
+		}
+		walker.SinceGit = sinceGit
+	}
+	walker.Strict = strict
+	walker.Author = author
+	walker.AuthorShare = authorShare
+	for _, expr := range excludeRegex {
+		pattern := expr
+		if ignoreCase {
+			pattern = "(?i)" + expr
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid --exclude-regex %q: %w", expr, err)
+		}
+		walker.ExcludeRegexps = append(walker.ExcludeRegexps, re)
+	}
+
+	if config, err = internal.LoadConfig(walker.Root()); err != nil {
+		return nil, nil, err
+	}
+	if fileHeader, err = internal.ParseFileHeader(config.FileHeader); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", internal.ConfigFile, err)
+	}
+	if config.VCSDirs != nil {
+		walker.VCSDirs = config.VCSDirs
+	}
+	if vcsDirs != nil {
+		walker.VCSDirs = vcsDirs
+	}
+
+	return walker, extras, nil
+}
+
+// newFetcher returns the Fetcher for --url and --pr, limited by
+// --concurrency and --rate-limit.
+func newFetcher() (*internal.Fetcher, error) {
+	if concurrency < 1 || rateLimit < 0 {
+		return nil, fmt.Errorf("--concurrency must be at least 1 and --rate-limit can't be negative")
+	}
+	return &internal.Fetcher{
+		Concurrency: concurrency,
+		RateLimit:   rateLimit,
+		Retries:     fetchRetries,
+		Timeout:     fetchTimeout,
+		OnRetry: func(url string, wait time.Duration) {
+			statusf("Rate limited by %s; retrying in %s\n", url, wait.Round(time.Second))
+		},
+	}, nil
+}
+
+// largestDirsShown bounds the directories listed when --max-files is hit.
+const largestDirsShown = 5
+
+// largestDirs lists the top-level directories holding the most files, one
+// per line, to point at what blew past --max-files.
+func largestDirs(files []internal.File) string {
+	var dirs []internal.DirUsage
+	for _, dir := range internal.DirectoryUsage(files) {
+		if dir.Depth == 1 {
+			dirs = append(dirs, dir)
+		}
+	}
+	slices.SortStableFunc(dirs, func(a, b internal.DirUsage) int { return b.Files - a.Files })
+	if len(dirs) > largestDirsShown {
+		dirs = dirs[:largestDirsShown]
+	}
+	width := 0
+	for _, dir := range dirs {
+		width = max(width, len(dir.Path)+1)
+	}
+	var out strings.Builder
+	for _, dir := range dirs {
+		fmt.Fprintf(&out, "  %-*s  %s files\n", width, dir.Path+"/", internal.FormatWithCommas(dir.Files))
+	}
+	return out.String()
+}
+
+// collectFiles walks the tree, orders the files as requested, and appends
+// the extra pseudo-files. The walk stops when ctx is done or on Ctrl-C.
+func collectFiles(ctx context.Context, walker *internal.Walker, extras []internal.File) ([]internal.File, error) {
+	walkCtx := ctx
+	if walkTimeout > 0 {
+		var cancel context.CancelFunc
+		walkCtx, cancel = context.WithTimeoutCause(ctx, walkTimeout, errWalkTimeout)
+		defer cancel()
+	}
+	if timings != nil {
+		walker.Timings = &timings.walk
+	}
+	progress := startProgress(walker)
+	var files []internal.File
+	start := time.Now()
+	err := interruptibly(walkCtx, func(ctx context.Context) (err error) {
+		files, err = walker.WalkContext(ctx)
+		return err
+	})
+	progress.Stop()
+	logger.Debug("walked", "root", walker.Root(), "files", len(files), "duration", time.Since(start))
+
+	// Out of time, but with files to show for it
+	if errors.Is(err, errWalkTimeout) {
+		partial, err = true, nil
+		warnf("Stopped looking for files after --walk-timeout (%s); the pack is partial, with the %s found so far.", walkTimeout, internal.FormatWithCommas(len(files)))
+	}
+	if exitCode(err) == exitCanceled {
+		return nil, err
+	}
+	if limitErr := (internal.SizeLimitError{}); errors.As(err, &limitErr) {
+		return nil, withExitCode(exitTooLarge, fmt.Errorf("%w; narrow the paths or raise --max-output-bytes", limitErr))
+	}
+	if limitErr := (internal.FileLimitError{}); errors.As(err, &limitErr) {
+		hint := "narrow the paths, ignore the directories that shouldn't be packed, or raise --max-files"
+		if dirs := largestDirs(files); dirs != "" {
+			return nil, withExitCode(exitTooLarge, fmt.Errorf("%w; the most are under:\n%s%s", limitErr, dirs, hint))
+		}
+		return nil, withExitCode(exitTooLarge, fmt.Errorf("%w; %s", limitErr, hint))
+	}
+	if readErr := (internal.ReadError{}); errors.As(err, &readErr) {
+		return nil, withExitCode(exitUnreadable, fmt.Errorf("failed to read %s (--strict)", readErr))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory: %w", err)
+	}
+	if len(repoRoots) > 0 {
+		for i := range files {
+			files[i].Path = repoPath(walker.Root(), files[i].Path)
+		}
+	}
+	if unread := walker.ReadErrors(); len(unread) > 0 {
+		var list strings.Builder
+		for _, e := range unread {
+			fmt.Fprintf(&list, "\n  %s", e)
+		}
+		warnf("Skipped %d unreadable files or directories (use --strict to fail instead):%s", len(unread), list.String())
+	}
+
+	// Reorder files if requested
+	if sortOrder != "" {
+		if err := internal.SortFiles(ctx, files, sortOrder, reverse, walker.Root(), churnSince); err != nil {
+			return nil, err
+		}
+	} else if reverse {
+		slices.Reverse(files)
+	}
+	// Files can ask to come first or last
+	if !noDirectives {
+		directedPriority, files = internal.DirectedPriority(files)
+	}
+	files = internal.Prioritize(files, priorityPatterns())
+
+	for _, path := range withDeps {
+		deps, err := walkModule(walker, path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, deps...)
+	}
+	files = append(files, extras...)
+
+	// Hide secrets before the files go anywhere
+	if redact {
+		if files, err = redactFiles(files); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// redactFiles hides the secrets in files for --redact, adding the lines it
+// hid to redactions.
+func redactFiles(files []internal.File) ([]internal.File, error) {
+	rules, err := internal.RedactRules(config.Redact)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", internal.ConfigFile, err)
+	}
+	files, found := internal.Redact(files, rules)
+	redactions = append(redactions, found...)
+	return files, nil
+}
+
+// walkModule packs the non-test source of a Go module dependency of the
+// walked tree, filtered like the tree itself, with paths under the module's
+// "path@version" label.
+func walkModule(walker *internal.Walker, path string) ([]internal.File, error) {
+	module, err := internal.FindModule(walker.Root(), path)
+	if err != nil {
+		return nil, fmt.Errorf("--with-deps: %w", err)
+	}
+
+	deps, err := internal.NewWalker(module.Dir)
+	if err != nil {
+		return nil, err
+	}
+	deps.IgnoreCase = walker.IgnoreCase
+	deps.IncludeGenerated = walker.IncludeGenerated
+	deps.IncludeVendored = walker.IncludeVendored
+	deps.NoDirectives = walker.NoDirectives
+	deps.FileModes = walker.FileModes
+	deps.GoBuild = walker.GoBuild
+	deps.SkipTests = true
+	files, err := deps.Walk()
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", module.Label(), err)
+	}
+	for i := range files {
+		files[i].Path = module.Label() + "/" + filepath.ToSlash(files[i].Path)
+	}
+	statusf("Including %d files from %s\n", len(files), module.Label())
+	return files, nil
+}
+
+// moduleNotes labels each --modules workspace member in the summary with
+// the files and tokens it contributes.
+func moduleNotes(files []internal.File) []string {
+	var notes []string
+	for _, module := range workspaceModules {
+		var count, tokens int
+		for _, file := range files {
+			path := filepath.ToSlash(file.Path)
+			if module.Dir == "." || strings.HasPrefix(path, module.Dir+"/") {
+				count++
+				tokens += internal.FileTokens(file)
+			}
+		}
+		name := module.Dir
+		if module.Path != "" {
+			name += " (" + module.Path + ")"
+		}
+		notes = append(notes, fmt.Sprintf("Module %s: %d files, ~%s tokens", name, count, internal.FormatWithCommas(tokens)))
+	}
+	return notes
+}
+
+// priorityPatterns returns the --priority patterns followed by those from
+// the project configuration and the files whose directives ask for it.
+func priorityPatterns() []string {
+	return append(append(slices.Clip(priority), config.Priority...), directedPriority...)
+}
+
+// readPatch reads a unified diff and returns the files it touches that
+// still exist, along with the raw patch.
+func readPatch(patchPath string) ([]string, []byte, error) {
+	patch, err := os.ReadFile(patchPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read patch: %w", err)
+	}
+
+	touched, err := internal.PatchFiles(bytes.NewReader(patch))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse patch: %w", err)
+	}
+
+	var paths []string
+	for _, path := range touched {
+		if _, err := os.Stat(path); err != nil {
+			warnf("Skipping %s from patch (%v)", path, err)
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths, patch, nil
+}
+
+// readTrace reads a stack trace or log and returns the files it mentions,
+// most frequently mentioned first.
+func readTrace(tracePath string) ([]string, error) {
+	file, err := os.Open(tracePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trace: %w", err)
+	}
+	defer file.Close()
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := internal.TraceFiles(file, cwd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse trace: %w", err)
+	}
+	return paths, nil
+}
+
+// readFilesFrom reads a --files-from list: a path, directory, or glob per
+// line, relative to the current directory, with blank lines and lines
+// starting with "#" ignored. "-" reads the list from stdin. Paths that
+// don't exist are reported with their line, so a stale list fails
+// clearly; globs may match nothing.
+func readFilesFrom(listPath string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if listPath != "-" {
+		file, err := os.Open(listPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read --files-from: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+	return parseFileList(r, listPath)
+}
+
+// readClipboardFiles reads a --files-from-clipboard list, as copied from
+// an editor's file explorer or a code search page. It is read as
+// readFilesFrom reads a list.
+func readClipboardFiles() ([]string, error) {
+	text, err := pasteText()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the clipboard: %w", err)
+	}
+	return parseFileList(strings.NewReader(text), "clipboard")
+}
+
+// parseFileList parses a list of paths, one per line, naming it in errors
+// as name. Besides plain paths, lines may be quoted, as Windows' "Copy as
+// path" writes them, or file:// URLs, as file managers copy files.
+func parseFileList(r io.Reader, name string) ([]string, error) {
+	var paths []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if len(line) > 1 && (line[0] == '"' || line[0] == '\'') && line[len(line)-1] == line[0] {
+			line = line[1 : len(line)-1]
+		}
+		if u, err := url.Parse(line); err == nil && u.Scheme == "file" {
+			line = u.Path
+			if len(line) > 2 && line[2] == ':' {
+				line = line[1:] // file:///C:/src on Windows
+			}
+		}
+		path := filepath.Clean(filepath.FromSlash(line))
+		if _, err := os.Stat(path); err != nil && !strings.ContainsAny(line, "*?[") {
+			return nil, fmt.Errorf("%s:%d: %s does not exist", name, n, line)
+		}
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return paths, nil
+}
+
+// parseSince parses a --since value: either a duration before now such as
+// "2w", "3d", or "36h", or a date such as "2024-06-01" (RFC 3339 timestamps
+// are accepted too).
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	// Days and weeks aren't understood by time.ParseDuration
+	units := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if n := len(value); n > 1 {
+		if unit, ok := units[value[n-1]]; ok {
+			if count, err := strconv.Atoi(value[:n-1]); err == nil && count >= 0 {
+				return now.Add(-time.Duration(count) * unit), nil
+			}
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid --since value %q (use e.g. 2w, 3d, 36h, or 2024-06-01)", value)
+}
+
+// parseBytes parses a size such as --max-output-bytes: a byte count with
+// an optional unit such as "50MB" or "2 GiB" (units are powers of 1024).
+func parseBytes(value string) (int64, error) {
+	units := []struct {
+		suffix string
+		size   int64
+	}{
+		{"GIB", 1 << 30}, {"MIB", 1 << 20}, {"KIB", 1 << 10},
+		{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+		{"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1},
+	}
+
+	number, size := strings.ToUpper(strings.TrimSpace(value)), int64(1)
+	for _, unit := range units {
+		if rest, ok := strings.CutSuffix(number, unit.suffix); ok {
+			number, size = strings.TrimSpace(rest), unit.size
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 || n*float64(size) > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %q (use e.g. 50MB, 2GB, or 0 for no limit)", value)
+	}
+	return int64(n * float64(size)), nil
+}
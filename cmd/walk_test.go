@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gopack/internal"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "2024-06-01", want: time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)},
+		{value: "2024-06-01T08:30:00Z", want: time.Date(2024, 6, 1, 8, 30, 0, 0, time.UTC)},
+		{value: "3d", want: now.Add(-3 * 24 * time.Hour)},
+		{value: "2w", want: now.Add(-14 * 24 * time.Hour)},
+		{value: "0d", want: now},
+		{value: "36h", want: now.Add(-36 * time.Hour)},
+		{value: "90m", want: now.Add(-90 * time.Minute)},
+		{value: "1h30m", want: now.Add(-90 * time.Minute)},
+		{value: "-3d", wantErr: true},
+		{value: "-1h", wantErr: true},
+		{value: "d", wantErr: true},
+		{value: "3x", wantErr: true},
+		{value: "yesterday", wantErr: true},
+		{value: "2024-13-01", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseSince(tt.value, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseSince(%q) = %v, want error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSince(%q) error = %v", tt.value, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseSince(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{value: "0", want: 0},
+		{value: "1024", want: 1024},
+		{value: "50MB", want: 50 << 20},
+		{value: "50mb", want: 50 << 20},
+		{value: "2 GiB", want: 2 << 30},
+		{value: "1.5K", want: 1536},
+		{value: "10B", want: 10},
+		{value: "-1MB", wantErr: true},
+		{value: "MB", wantErr: true},
+		{value: "lots", wantErr: true},
+		{value: "99999999999GB", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseBytes(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseBytes(%q) = %d, %v; want %d, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestLargestDirs(t *testing.T) {
+	var files []internal.File
+	add := func(dir string, n int) {
+		for i := 0; i < n; i++ {
+			files = append(files, internal.File{Path: dir + "/f" + strings.Repeat("x", i) + ".js"})
+		}
+	}
+	add("node_modules/react", 9)
+	add("src", 3)
+	add("docs", 1)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		add(name, 2)
+	}
+	files = append(files, internal.File{Path: "README.md"})
+
+	lines := strings.Split(strings.TrimSuffix(largestDirs(files), "\n"), "\n")
+	if len(lines) != largestDirsShown {
+		t.Fatalf("largestDirs() = %q, want %d lines", lines, largestDirsShown)
+	}
+	if lines[0] != "  node_modules/  9 files" || !strings.HasPrefix(lines[1], "  src/") {
+		t.Errorf("largestDirs() = %q, want node_modules/ then src/ first", lines)
+	}
+
+	if got := largestDirs([]internal.File{{Path: "README.md"}}); got != "" {
+		t.Errorf("largestDirs(root files only) = %q, want nothing", got)
+	}
+}
+
+func TestReadFilesFrom(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	for _, name := range []string{"internal/a.go", "README.md"} {
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		list    string
+		want    []string
+		wantErr string
+	}{
+		{
+			"paths, comments, and blank lines",
+			"# the auth flow\ninternal/a.go\n\n  README.md  \n./internal/a.go\n",
+			[]string{filepath.FromSlash("internal/a.go"), "README.md"},
+			"",
+		},
+		{"directories and globs", "internal\ncmd/**/*.go\n", []string{"internal", filepath.FromSlash("cmd/**/*.go")}, ""},
+		{"missing path", "README.md\n# gone\ninternal/b.go\n", nil, "auth.txt:3: internal/b.go does not exist"},
+	}
+	for _, tt := range tests {
+		if err := os.WriteFile("auth.txt", []byte(tt.list), 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := readFilesFrom("auth.txt")
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("%s: readFilesFrom() error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("%s: readFilesFrom() = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseFileList(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.WriteFile("my notes.md", []byte("x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	abs := filepath.Join(dir, "my notes.md")
+
+	tests := []struct {
+		name    string
+		list    string
+		want    []string
+		wantErr string
+	}{
+		{"quoted", "\"my notes.md\"\n'my notes.md'\n", []string{"my notes.md"}, ""},
+		{"file URL", (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String() + "\r\n", []string{abs}, ""},
+		{"not a list", "func main() {\n", nil, "clipboard:1: func main() { does not exist"},
+	}
+	for _, tt := range tests {
+		got, err := parseFileList(strings.NewReader(tt.list), "clipboard")
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("%s: parseFileList() error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("%s: parseFileList() = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestCollectFilesWalkTimeout(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.go", "b.go"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("package a\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func() { walkTimeout, partial = 0, false }()
+
+	for _, tt := range []struct {
+		timeout     time.Duration
+		wantPartial bool
+	}{
+		{0, false},
+		{time.Nanosecond, true},
+	} {
+		walkTimeout, partial = tt.timeout, false
+		walker, err := internal.NewWalker(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files, err := collectFiles(context.Background(), walker, nil)
+		if err != nil {
+			t.Fatalf("--walk-timeout %s: collectFiles() error: %v", tt.timeout, err)
+		}
+		if partial != tt.wantPartial || !tt.wantPartial && len(files) != 2 {
+			t.Errorf("--walk-timeout %s: collectFiles() = %d files, partial %v; want partial %v", tt.timeout, len(files), partial, tt.wantPartial)
+		}
+	}
+}
+
+func TestCollectFilesRedact(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("db:\n  password: \"hunter2hunter2\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { redact, redactions = false, nil }()
+
+	// Every command packing through collectFiles redacts, not just the root
+	for _, enabled := range []bool{false, true} {
+		redact, redactions = enabled, nil
+		walker, err := internal.NewWalker(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files, err := collectFiles(context.Background(), walker, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 {
+			t.Fatalf("collectFiles() = %d files, want 1", len(files))
+		}
+		hidden := !strings.Contains(string(files[0].Content), "hunter2")
+		if hidden != enabled || (len(redactions) == 1) != enabled {
+			t.Errorf("--redact=%v: collectFiles() = %q, redactions %v", enabled, files[0].Content, redactions)
+		}
+	}
+}
+
+func TestNewWalkerIgnoreCase(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"README.md", "main.go"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func() { ignoreCase, excludeRegex = false, nil }()
+
+	// --ignore-case folds --exclude-regex too
+	for _, tt := range []struct {
+		ignoreCase bool
+		want       []string
+	}{
+		{false, []string{"README.md", "main.go"}},
+		{true, []string{"main.go"}},
+	} {
+		ignoreCase, excludeRegex = tt.ignoreCase, []string{`^readme\.md$`}
+		walker, _, err := newWalker(context.Background(), []string{dir})
+		if err != nil {
+			t.Fatal(err)
+		}
+		files, err := walker.Walk()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, file := range files {
+			got = append(got, file.Path)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("--ignore-case=%v: Walk() = %q, want %q", tt.ignoreCase, got, tt.want)
+		}
+	}
+}
+
+func TestNewWalkerHidden(t *testing.T) {
+	defer func() { hidden, noHidden = true, false }()
+
+	// Dotfiles are packed unless --no-hidden or --hidden=false says not to
+	for _, tt := range []struct {
+		hidden, noHidden bool
+		wantSkip         bool
+	}{
+		{true, false, false},
+		{false, false, true},
+		{true, true, true},
+	} {
+		hidden, noHidden = tt.hidden, tt.noHidden
+		walker, _, err := newWalker(context.Background(), []string{t.TempDir()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if walker.SkipHidden != tt.wantSkip {
+			t.Errorf("--hidden=%v --no-hidden=%v: SkipHidden = %v, want %v", tt.hidden, tt.noHidden, walker.SkipHidden, tt.wantSkip)
+		}
+	}
+}
+
+func TestNewWalkerStdinLabel(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdin, err := os.CreateTemp(t.TempDir(), "stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	if _, err := stdin.WriteString("--- FAIL: TestMain (0.00s)\n"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stdin.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer func(f *os.File) { os.Stdin, stdinLabel = f, "" }(os.Stdin)
+	os.Stdin, stdinLabel = stdin, "test output"
+
+	// Piped text joins the pack as a pseudo-file under the label
+	walker, extras, err := newWalker(context.Background(), []string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(extras) != 1 || extras[0].Path != "test output" || string(extras[0].Content) != "--- FAIL: TestMain (0.00s)\n" {
+		t.Fatalf("newWalker() extras = %+v, want the piped text as \"test output\"", extras)
+	}
+	files, err := collectFiles(context.Background(), walker, extras)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	if want := []string{"main.go", "test output"}; !slices.Equal(paths, want) {
+		t.Errorf("collectFiles() = %q, want %q", paths, want)
+	}
+}
@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const (
+	// lockPoll is how often LockFile retries a lock held by another run.
+	lockPoll = 50 * time.Millisecond
+
+	// lockStale is the age from which a lock file is taken to be left
+	// behind by a run that died, and is broken.
+	lockStale = time.Minute
+)
+
+// lockTouch is how often a held lock file's modification time is brought
+// up to date, so a run holding it for longer than lockStale, such as a
+// refresh reading a large tree, isn't taken to have died.
+var lockTouch = lockStale / 4
+
+// WriteFileAtomic writes the file at path with write, by way of a temporary
+// file in the same directory that is renamed over it once complete, so
+// readers see the old content or the new but never part of either. A file
+// being replaced keeps its permissions, and a symbolic link keeps pointing
+// at the file it names, which is the one replaced. Paths that exist but
+// aren't regular files, such as devices and named pipes, are written to
+// directly.
+func WriteFileAtomic(path string, write func(w io.Writer) error) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	mode := fs.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		if !info.Mode().IsRegular() {
+			return writeFileDirect(path, write)
+		}
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // once renamed, there's nothing to remove
+	err = write(tmp)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), mode)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// writeFileDirect writes the file at path with write in place.
+func writeFileDirect(path string, write func(w io.Writer) error) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// LockFile takes a lock on path, so concurrent runs writing the same file
+// take turns, and returns the function that releases it. The lock is the
+// file path+".lock", holding the process ID, created exclusively; if
+// another run holds it, LockFile waits up to wait for its release. Until
+// released, the lock file is touched every lockTouch; one not touched for
+// a minute was left behind by a run that died, and is taken over. A path
+// that is a symbolic link is locked as the file it names, which is the
+// one WriteFileAtomic replaces.
+func LockFile(path string, wait time.Duration) (func(), error) {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	lock := LongPath(path) + ".lock"
+	deadline := time.Now().Add(wait)
+	for {
+		file, err := os.OpenFile(lock, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = file.WriteString(strconv.Itoa(os.Getpid()) + "\n")
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(lock)
+				return nil, err
+			}
+			done, stopped := make(chan struct{}), make(chan struct{})
+			go touchLock(lock, lockTouch, done, stopped)
+			return func() {
+				close(done)
+				<-stopped
+				os.Remove(lock)
+			}, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > lockStale {
+			os.Remove(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is being written by another gopack run (if none is running, remove %s)", path, lock)
+		}
+		time.Sleep(lockPoll)
+	}
+}
+
+// touchLock brings the modification time of the lock file up to date
+// every interval until done is closed, then closes stopped.
+func touchLock(lock string, interval time.Duration, done <-chan struct{}, stopped chan<- struct{}) {
+	defer close(stopped)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			os.Chtimes(lock, now, now)
+		}
+	}
+}
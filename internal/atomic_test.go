@@ -0,0 +1,157 @@
+package internal
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	write := func(s string) func(io.Writer) error {
+		return func(w io.Writer) error {
+			_, err := io.WriteString(w, s)
+			return err
+		}
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "context.txt")
+
+	if err := WriteFileAtomic(path, write("first\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path); got != "first\n" {
+		t.Errorf("content = %q, want %q", got, "first\n")
+	}
+
+	// Replacing a file keeps its permissions
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(path, write("second\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path); got != "second\n" {
+		t.Errorf("content = %q, want %q", got, "second\n")
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// A failed write leaves the old file, and no temporary file, behind
+	if err := WriteFileAtomic(path, func(w io.Writer) error {
+		io.WriteString(w, "half")
+		return io.ErrShortWrite
+	}); err != io.ErrShortWrite {
+		t.Errorf("WriteFileAtomic() error = %v, want %v", err, io.ErrShortWrite)
+	}
+	if got := readFile(t, path); got != "second\n" {
+		t.Errorf("content after a failed write = %q, want %q", got, "second\n")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory holds %d entries after a failed write, want 1", len(entries))
+	}
+
+	// A symbolic link keeps pointing at the file it names
+	link := filepath.Join(dir, "link.txt")
+	if err := os.Symlink(path, link); err != nil {
+		t.Skip("symbolic links unsupported:", err)
+	}
+	if err := WriteFileAtomic(link, write("third\n")); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("link replaced by a file (%v)", err)
+	}
+	if got := readFile(t, path); got != "third\n" {
+		t.Errorf("content through link = %q, want %q", got, "third\n")
+	}
+}
+
+func TestLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "context.txt")
+
+	unlock, err := LockFile(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LockFile(path, 2*lockPoll); err == nil || !strings.Contains(err.Error(), "another gopack run") {
+		t.Errorf("LockFile() while locked error = %v, want it to be held by another run", err)
+	}
+
+	// Waiting runs get the lock once it's released
+	release := unlock
+	go func() {
+		time.Sleep(lockPoll)
+		release()
+	}()
+	unlock, err = LockFile(path, 5*time.Second)
+	if err != nil {
+		t.Fatalf("LockFile() after release error = %v", err)
+	}
+	unlock()
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("lock file left behind (%v)", err)
+	}
+
+	// A lock left by a run that died is taken over
+	if err := os.WriteFile(path+".lock", []byte("1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * lockStale)
+	if err := os.Chtimes(path+".lock", old, old); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err = LockFile(path, 0)
+	if err != nil {
+		t.Fatalf("LockFile() with a stale lock error = %v", err)
+	}
+	unlock()
+}
+
+func TestLockFileTouch(t *testing.T) {
+	defer func(touch time.Duration) { lockTouch = touch }(lockTouch)
+	lockTouch = 10 * time.Millisecond
+	path := filepath.Join(t.TempDir(), "context.txt")
+
+	// A lock held past lockStale is kept up to date, so it isn't broken
+	unlock, err := LockFile(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+	old := time.Now().Add(-2 * lockStale)
+	if err := os.Chtimes(path+".lock", old, old); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(lockTouch) {
+		if info, err := os.Stat(path + ".lock"); err == nil && time.Since(info.ModTime()) < lockStale {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("held lock file was never touched")
+		}
+	}
+	if other, err := LockFile(path, 0); err == nil {
+		other()
+		t.Error("LockFile() broke a lock that is still held")
+	}
+
+	// Locking a link to the file waits for the same lock
+	link := filepath.Join(filepath.Dir(path), "link.txt")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(path, link); err != nil {
+		t.Skip("symbolic links unsupported:", err)
+	}
+	if other, err := LockFile(link, 0); err == nil {
+		other()
+		t.Error("LockFile() through a link took a lock already held")
+	}
+}
@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// attributesFile is the per-directory file git attributes are read from.
+const attributesFile = ".gitattributes"
+
+// Linguist attributes, with which a repository marks files GitHub should
+// treat as generated or vendored, such as hiding them in diffs.
+const (
+	attrGenerated = "linguist-generated"
+	attrVendored  = "linguist-vendored"
+)
+
+// attrState is the state of an attribute for a path, as git defines it.
+type attrState int
+
+const (
+	attrUnspecified attrState = iota // no rule sets it, or "!attr" reset it
+	attrSet                          // "attr" or "attr=true"
+	attrUnset                        // "-attr" or "attr=false"
+)
+
+// attrRule is a .gitattributes line, keeping only the linguist attributes.
+type attrRule struct {
+	source string // e.g. "api/.gitattributes:3"
+	ignorePattern
+	attrs map[string]attrState
+}
+
+// attrValue is an attribute's state for a path and the rule that decided it.
+type attrValue struct {
+	state  attrState
+	source string
+}
+
+// loadAttributes loads the linguist attributes from the .gitattributes file
+// in the directory.
+func (w *Walker) loadAttributes(dirPath string) {
+	file, err := w.open(filepath.Join(dirPath, attributesFile))
+	if err != nil {
+		return // File doesn't exist or can't be read
+	}
+	defer file.Close()
+
+	name, err := filepath.Rel(w.rootPath, filepath.Join(dirPath, attributesFile))
+	if err != nil {
+		name = attributesFile
+	}
+	name = filepath.ToSlash(name)
+
+	var rules []attrRule
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		if rule, ok := parseAttrLine(scanner.Text()); ok {
+			rule.source = fmt.Sprintf("%s:%d", name, lineNum)
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) > 0 {
+		w.attributes[dirPath] = rules
+	}
+}
+
+// parseAttrLine parses a .gitattributes line, a pattern followed by
+// attributes, keeping the linguist ones. As in git, negative patterns and
+// patterns ending in "/" match nothing, so they're dropped too.
+func parseAttrLine(line string) (attrRule, bool) {
+	fields := strings.Fields(strings.TrimSuffix(line, "\r"))
+	if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+		return attrRule{}, false
+	}
+	pattern := fields[0]
+	if strings.HasPrefix(pattern, "!") || strings.HasSuffix(pattern, "/") {
+		return attrRule{}, false
+	}
+
+	attrs := make(map[string]attrState)
+	for _, field := range fields[1:] {
+		state := attrSet
+		switch {
+		case strings.HasPrefix(field, "-"):
+			state, field = attrUnset, field[1:]
+		case strings.HasPrefix(field, "!"):
+			state, field = attrUnspecified, field[1:]
+		}
+		name, value, ok := strings.Cut(field, "=")
+		if ok && value == "false" {
+			state = attrUnset
+		}
+		if name == attrGenerated || name == attrVendored {
+			attrs[name] = state
+		}
+	}
+	if len(attrs) == 0 {
+		return attrRule{}, false
+	}
+	return attrRule{ignorePattern: compilePattern(pattern), attrs: attrs}, true
+}
+
+// matchFile reports whether the pattern matches a file, split into its
+// slash-separated parts. Unlike ignore rules, attributes set on a
+// directory don't reach the files inside it.
+func (p ignorePattern) matchFile(parts []string, ignoreCase bool) bool {
+	segments := p.segments
+	if ignoreCase {
+		segments = p.folded
+	}
+	if !p.anchored {
+		return globMatch(segments[0], parts[len(parts)-1])
+	}
+	return matchSegments(segments, parts)
+}
+
+// linguist returns the linguist-generated and linguist-vendored attributes
+// of a file. As in git, rules from deeper .gitattributes files take
+// precedence over those from their parents, and later lines over earlier
+// ones; a submodule's files only follow its own.
+func (w *Walker) linguist(relPath string) (generated, vendored attrValue) {
+	relPath = filepath.ToSlash(relPath)
+
+	dir := ""
+	for rest := relPath; ; {
+		dirPath := filepath.Join(w.rootPath, filepath.FromSlash(dir))
+		if dir != "" && w.submodules[dirPath] {
+			generated, vendored = attrValue{}, attrValue{}
+		}
+		if rules := w.attributes[dirPath]; len(rules) > 0 {
+			path := NormalizePath(strings.TrimPrefix(relPath, dir))
+			if w.IgnoreCase {
+				path = strings.ToLower(path)
+			}
+			parts := strings.Split(path, "/")
+			for _, rule := range rules {
+				if !rule.matchFile(parts, w.IgnoreCase) {
+					continue
+				}
+				if state, ok := rule.attrs[attrGenerated]; ok {
+					generated = attrValue{state, rule.source}
+				}
+				if state, ok := rule.attrs[attrVendored]; ok {
+					vendored = attrValue{state, rule.source}
+				}
+			}
+		}
+
+		segment, remaining, ok := strings.Cut(rest, "/")
+		if !ok {
+			break
+		}
+		dir += segment + "/"
+		rest = remaining
+	}
+	return generated, vendored
+}
@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseAttrLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want map[string]attrState // nil if the line is dropped
+	}{
+		{"*.pb.go linguist-generated", map[string]attrState{attrGenerated: attrSet}},
+		{"vendor/** linguist-vendored=true", map[string]attrState{attrVendored: attrSet}},
+		{"api/*.go -linguist-generated linguist-vendored=false", map[string]attrState{attrGenerated: attrUnset, attrVendored: attrUnset}},
+		{"gen.go !linguist-generated text", map[string]attrState{attrGenerated: attrUnspecified}},
+		{"*.sh text eol=lf", nil},
+		{"# *.go linguist-generated", nil},
+		{"!*.go linguist-generated", nil},
+		{"docs/ linguist-vendored", nil},
+		{"*.go", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		rule, ok := parseAttrLine(tt.line)
+		if ok != (tt.want != nil) || !maps.Equal(rule.attrs, tt.want) {
+			t.Errorf("parseAttrLine(%q) = %v, %v; want %v", tt.line, rule.attrs, ok, tt.want)
+		}
+	}
+}
+
+func TestWalkLinguistAttributes(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".gitattributes":       "*.gen.go linguist-generated\nthird_party/** linguist-vendored\nthird_party/keep.go -linguist-vendored\n",
+		"main.go":              "package main\n",
+		"api.gen.go":           "package main\n",
+		"third_party/lib.go":   "package lib\n",
+		"third_party/keep.go":  "package lib\n",
+		"mocks/.gitattributes": "mock.go -linguist-generated\n",
+		"mocks/mock.go":        "// Code generated by mockgen. DO NOT EDIT.\npackage mocks\n",
+		"mocks/other.go":       "// Code generated by mockgen. DO NOT EDIT.\npackage mocks\n",
+	})
+
+	tests := []struct {
+		name                string
+		generated, vendored bool
+		want                []string
+	}{
+		{"default", false, false, []string{"main.go", "mocks/mock.go", "third_party/keep.go"}},
+		{"include generated", true, false, []string{"api.gen.go", "main.go", "mocks/mock.go", "mocks/other.go", "third_party/keep.go"}},
+		{"include vendored", false, true, []string{"main.go", "mocks/mock.go", "third_party/keep.go", "third_party/lib.go"}},
+	}
+	for _, tt := range tests {
+		walker, err := NewWalker(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		walker.IncludeGenerated, walker.IncludeVendored = tt.generated, tt.vendored
+		files, err := walker.Walk()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, file := range files {
+			if filepath.Base(file.Path) != attributesFile {
+				got = append(got, filepath.ToSlash(file.Path))
+			}
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: Walk() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Naming a file explicitly keeps it regardless
+	walker, err := NewWalker(filepath.Join(dir, "third_party", "lib.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files, err := walker.Walk(); err != nil || len(files) != 1 {
+		t.Errorf("Walk(third_party/lib.go) = %d files, %v; want the file", len(files), err)
+	}
+}
@@ -0,0 +1,34 @@
+package internal
+
+import (
+	"path"
+	"strings"
+)
+
+// binaryExtensions are file extensions that are always binary, so files
+// with them can be skipped without being opened. On trees full of assets,
+// sniffing every file's first bytes would dominate the walk.
+var binaryExtensions = map[string]bool{
+	// Images
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".bmp": true, ".ico": true,
+	".webp": true, ".tif": true, ".tiff": true, ".psd": true, ".heic": true, ".avif": true,
+	// Fonts
+	".woff": true, ".woff2": true, ".ttf": true, ".otf": true, ".eot": true,
+	// Archives
+	".zip": true, ".gz": true, ".tgz": true, ".bz2": true, ".xz": true, ".zst": true,
+	".7z": true, ".rar": true, ".tar": true, ".jar": true, ".war": true,
+	// Executables, libraries, and compiled code
+	".exe": true, ".dll": true, ".so": true, ".dylib": true, ".a": true, ".o": true,
+	".obj": true, ".lib": true, ".class": true, ".pyc": true, ".pyo": true, ".wasm": true,
+	// Audio and video
+	".mp3": true, ".mp4": true, ".mov": true, ".avi": true, ".mkv": true, ".wav": true,
+	".flac": true, ".ogg": true, ".webm": true,
+	// Documents and databases
+	".pdf": true, ".doc": true, ".docx": true, ".xls": true, ".xlsx": true, ".ppt": true,
+	".pptx": true, ".sqlite": true, ".db": true,
+}
+
+// hasBinaryExtension reports whether a file's extension says it's binary.
+func hasBinaryExtension(name string) bool {
+	return binaryExtensions[strings.ToLower(path.Ext(strings.ReplaceAll(name, `\`, "/")))]
+}
@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestHasBinaryExtension(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"logo.png", true},
+		{"assets/Photo.JPG", true},
+		{`fonts\inter.woff2`, true},
+		{"lib/libfoo.so", true},
+		{"main.go", false},
+		{"icon.svg", false},
+		{"Makefile", false},
+		{"archive.zip.txt", false},
+	}
+	for _, tt := range tests {
+		if got := hasBinaryExtension(tt.name); got != tt.want {
+			t.Errorf("hasBinaryExtension(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWalkBinaryExtensions(t *testing.T) {
+	dir := t.TempDir()
+	pointer := lfsPointerVersion + "\noid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393\nsize 12345\n"
+	writeTree(t, dir, map[string]string{
+		"main.go":         "package main\n",
+		"text.png":        "not really an image\n", // skipped by name, without being read
+		"assets/logo.png": pointer,
+	})
+
+	walk := func(lfs string, paths ...string) []string {
+		t.Helper()
+		walker, err := NewWalker(paths...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		walker.LFS = lfs
+		files, err := walker.Walk()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, file := range files {
+			got = append(got, filepath.ToSlash(file.Path))
+		}
+		slices.Sort(got)
+		return got
+	}
+
+	if got := walk("", dir); !slices.Equal(got, []string{"main.go"}) {
+		t.Errorf("Walk() = %v, want only main.go", got)
+	}
+	if got := walk(LFSPointer, dir); !slices.Equal(got, []string{"assets/logo.png", "main.go"}) {
+		t.Errorf("Walk(LFS pointer) = %v, want the pointer kept", got)
+	}
+	if got := walk("", filepath.Join(dir, "text.png")); !slices.Equal(got, []string{"text.png"}) {
+		t.Errorf("Walk(text.png) = %v, want the file named explicitly", got)
+	}
+}
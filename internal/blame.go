@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Modes of AddBlame.
+const (
+	BlameLines  = "lines"  // prefix every line with its last change
+	BlameBlocks = "blocks" // head each run of lines from one commit with it
+)
+
+// BlameModes lists the modes AddBlame accepts.
+var BlameModes = []string{BlameLines, BlameBlocks}
+
+// blameAuthorWidth caps how much of an author's name a line prefix shows.
+const blameAuthorWidth = 16
+
+// blameLine is the last change to one line of a file.
+type blameLine struct {
+	Commit  string
+	Author  string
+	Time    time.Time
+	Summary string
+}
+
+// uncommitted reports whether the line has changed since the last commit.
+func (b blameLine) uncommitted() bool {
+	return strings.Trim(b.Commit, "0") == ""
+}
+
+// label describes the change compactly: short commit, author, and date.
+func (b blameLine) label(authorWidth int) string {
+	if b.uncommitted() {
+		return fmt.Sprintf("%-7s %-*s %s", "-------", authorWidth, "uncommitted", "----------")
+	}
+	author := []rune(b.Author)
+	if len(author) > blameAuthorWidth {
+		author = author[:blameAuthorWidth]
+	}
+	return fmt.Sprintf("%.7s %-*s %s", b.Commit, authorWidth, string(author), b.Time.UTC().Format("2006-01-02"))
+}
+
+// blameFile returns the last change to each line of file, relative to dir.
+func blameFile(ctx context.Context, dir, file string) ([]blameLine, error) {
+	out, err := runGit(ctx, dir, "blame", "--line-porcelain", "--", file)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []blameLine
+	var current blameLine
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "\t"):
+			// Content line: ends the header for one blamed line
+			lines = append(lines, current)
+			current = blameLine{}
+		case strings.HasPrefix(line, "author "):
+			current.Author = strings.TrimPrefix(line, "author ")
+		case strings.HasPrefix(line, "author-time "):
+			if secs, err := strconv.ParseInt(strings.TrimPrefix(line, "author-time "), 10, 64); err == nil {
+				current.Time = time.Unix(secs, 0)
+			}
+		case strings.HasPrefix(line, "summary "):
+			current.Summary = strings.TrimPrefix(line, "summary ")
+		case current.Commit == "" && len(line) >= 40 && !strings.Contains(line[:40], " "):
+			current.Commit, _, _ = strings.Cut(line, " ")
+		}
+	}
+	return lines, nil
+}
+
+// AddBlame annotates the contents of each file tracked in the git
+// repository at dir with the last change to its lines, as git blame finds
+// them: the commit, author, and date before every line in BlameLines mode,
+// or a header with the commit's summary above each run of lines from one
+// commit in BlameBlocks mode. Paths are relative to dir; untracked files,
+// pseudo-files, and files whose contents no longer match the working tree
+// are left alone. It returns how many files were annotated.
+func AddBlame(ctx context.Context, files []File, dir, mode string) (int, error) {
+	if mode != BlameLines && mode != BlameBlocks {
+		return 0, fmt.Errorf("unknown blame mode %q", mode)
+	}
+	out, err := runGit(ctx, dir, "ls-files", "-z")
+	if err != nil {
+		return 0, err
+	}
+	tracked := make(map[string]bool)
+	for _, name := range strings.Split(out, "\x00") {
+		tracked[name] = true
+	}
+
+	annotated := 0
+	for i, file := range files {
+		name := strings.ReplaceAll(file.Path, `\`, "/")
+		if !tracked[name] || len(file.Content) == 0 {
+			continue
+		}
+		blame, err := blameFile(ctx, dir, name)
+		if err != nil {
+			return annotated, err
+		}
+		content := strings.TrimSuffix(string(file.Content), "\n")
+		lines := strings.Split(content, "\n")
+		if len(lines) != len(blame) {
+			continue
+		}
+		files[i].Content = []byte(annotateBlame(lines, blame, mode))
+		annotated++
+	}
+	return annotated, nil
+}
+
+// annotateBlame renders lines with the changes blamed for them.
+func annotateBlame(lines []string, blame []blameLine, mode string) string {
+	var out strings.Builder
+	if mode == BlameBlocks {
+		for i, line := range lines {
+			if i == 0 || blame[i].Commit != blame[i-1].Commit {
+				if b := blame[i]; b.uncommitted() {
+					out.WriteString("── uncommitted changes ──\n")
+				} else {
+					fmt.Fprintf(&out, "── %s: %s ──\n", b.label(0), b.Summary)
+				}
+			}
+			out.WriteString(line)
+			out.WriteByte('\n')
+		}
+		return out.String()
+	}
+
+	width := 0
+	for _, b := range blame {
+		n := min(len([]rune(b.Author)), blameAuthorWidth)
+		if b.uncommitted() {
+			n = len("uncommitted")
+		}
+		width = max(width, n)
+	}
+	for i, line := range lines {
+		fmt.Fprintf(&out, "%s │ %s\n", blame[i].label(width), line)
+	}
+	return out.String()
+}
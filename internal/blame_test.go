@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddBlame(t *testing.T) {
+	dir := gitRepo(t)
+	if err := os.WriteFile(filepath.Join(dir, "b.go"), []byte("package main\n\nvar b = 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	today := time.Now().UTC().Format("2006-01-02")
+
+	tests := []struct {
+		mode string
+		want []string
+	}{
+		{BlameLines, []string{
+			"Alice       " + today + " │ package main",
+			"Bob         " + today + " │ ",
+			"-------",
+			"uncommitted ---------- │ var b = 2",
+		}},
+		{BlameBlocks, []string{
+			"Alice " + today + ": one ──\npackage main\n",
+			"Bob " + today + ": two ──\n\n",
+			"── uncommitted changes ──\nvar b = 2\n",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			files := []File{
+				{Path: "b.go", Content: []byte("package main\n\nvar b = 2\n")},
+				{Path: "untracked.go", Content: []byte("package main\n")},
+				{Path: "héllo.go", Content: []byte("package main\n\nfunc main() {}\n")},
+			}
+			n, err := AddBlame(context.Background(), files, dir, tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != 1 {
+				t.Errorf("AddBlame() = %d, want 1", n)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(files[0].Content), want) {
+					t.Errorf("b.go missing %q:\n%s", want, files[0].Content)
+				}
+			}
+			if string(files[1].Content) != "package main\n" {
+				t.Errorf("untracked file changed:\n%s", files[1].Content)
+			}
+			if string(files[2].Content) != "package main\n\nfunc main() {}\n" {
+				t.Errorf("file out of step with git blame changed:\n%s", files[2].Content)
+			}
+		})
+	}
+
+	if _, err := AddBlame(context.Background(), nil, dir, "words"); err == nil {
+		t.Error("AddBlame() with an unknown mode succeeded")
+	}
+}
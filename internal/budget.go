@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// budgetRest is the budget key for files outside every listed directory.
+const budgetRest = "rest"
+
+// BudgetRule gives the files under a directory a share of the token budget.
+type BudgetRule struct {
+	Prefix string  // slash-separated directory ending in "/", or "" for the rest
+	Share  float64 // fraction of the budget, 0-1
+}
+
+// ParseBudget parses budget rules such as {"internal/": "60%", "rest":
+// "40%"}. If "rest" is omitted it gets whatever the other rules leave.
+func ParseBudget(budget map[string]string) ([]BudgetRule, error) {
+	var rules []BudgetRule
+	var total float64
+	rest := -1.0
+	for key, value := range budget {
+		percent, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(value, "%")), 64)
+		if err != nil || percent < 0 {
+			return nil, fmt.Errorf("invalid budget %q for %q (use a percentage such as \"60%%\")", value, key)
+		}
+		total += percent
+		if key == budgetRest {
+			rest = percent / 100
+			continue
+		}
+		prefix := strings.TrimSuffix(filepath.ToSlash(key), "/") + "/"
+		rules = append(rules, BudgetRule{Prefix: prefix, Share: percent / 100})
+	}
+	if total > 100.0001 {
+		return nil, fmt.Errorf("budget shares add up to %g%%, more than 100%%", total)
+	}
+	if rest < 0 {
+		rest = max(0, 1-total/100)
+	}
+	rules = append(rules, BudgetRule{Prefix: "", Share: rest})
+
+	// Longest prefix first, so nested directories take precedence
+	sort.SliceStable(rules, func(i, j int) bool { return len(rules[i].Prefix) > len(rules[j].Prefix) })
+	return rules, nil
+}
+
+// ApplyBudget trims files to fit maxTokens, dividing the budget between
+// areas according to rules. Files matching a priority pattern are always
+// kept, and count against their area first. Within an area, other files are
+// kept in order while they fit. Budget an area doesn't use is then handed
+// to the dropped files of other areas, again in order. It returns the kept
+// files, in their original order, and the dropped ones.
+func ApplyBudget(files []File, rules []BudgetRule, maxTokens int, priority []string) (kept, dropped []File) {
+	area := func(file File) int {
+		path := filepath.ToSlash(file.Path)
+		for i, rule := range rules {
+			if strings.HasPrefix(path, rule.Prefix) {
+				return i
+			}
+		}
+		return len(rules) - 1
+	}
+
+	remaining := make([]int, len(rules))
+	for i, rule := range rules {
+		remaining[i] = int(rule.Share * float64(maxTokens))
+	}
+
+	keep := make([]bool, len(files))
+	used := 0
+	for i, file := range files {
+		if IsPriority(file.Path, priority) {
+			tokens := FileTokens(file)
+			remaining[area(file)] -= tokens
+			used += tokens
+			keep[i] = true
+		}
+	}
+	for i, file := range files {
+		tokens := FileTokens(file)
+		if a := area(file); !keep[i] && tokens <= remaining[a] {
+			remaining[a] -= tokens
+			used += tokens
+			keep[i] = true
+		}
+	}
+
+	// Share out what's left
+	for i, file := range files {
+		if tokens := FileTokens(file); !keep[i] && used+tokens <= maxTokens {
+			used += tokens
+			keep[i] = true
+		}
+	}
+
+	for i, file := range files {
+		if keep[i] {
+			kept = append(kept, file)
+		} else {
+			dropped = append(dropped, file)
+		}
+	}
+	return kept, dropped
+}
@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBudget(t *testing.T) {
+	rules, err := ParseBudget(map[string]string{"internal": "60%", "internal/llm/": "10%", "docs/": "10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []BudgetRule{
+		{Prefix: "internal/llm/", Share: 0.1},
+		{Prefix: "internal/", Share: 0.6},
+		{Prefix: "docs/", Share: 0.1},
+		{Prefix: "", Share: 0.2},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("ParseBudget() = %v, want %v", rules, want)
+	}
+	for i := range want {
+		if rules[i].Prefix != want[i].Prefix || rules[i].Share-want[i].Share > 1e-9 || want[i].Share-rules[i].Share > 1e-9 {
+			t.Errorf("rule %d = %+v, want %+v", i, rules[i], want[i])
+		}
+	}
+
+	for _, budget := range []map[string]string{
+		{"internal/": "70%", "docs/": "40%"},
+		{"internal/": "lots"},
+		{"internal/": "-5%"},
+	} {
+		if _, err := ParseBudget(budget); err == nil {
+			t.Errorf("ParseBudget(%v) succeeded, want error", budget)
+		}
+	}
+}
+
+func TestApplyBudget(t *testing.T) {
+	// Each file is 100 tokens with its header
+	file := func(path string) File {
+		return File{Path: path, Content: []byte(strings.Repeat("x", 400-len(path)-len("File: \n")))}
+	}
+	files := []File{
+		file("docs/a.md"), file("docs/b.md"), file("docs/c.md"),
+		file("internal/a.go"), file("internal/b.go"),
+		file("main.go"),
+	}
+	rules, err := ParseBudget(map[string]string{"internal/": "50%", "docs/": "20%", "rest": "30%"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths := func(files []File) []string {
+		var paths []string
+		for _, file := range files {
+			paths = append(paths, file.Path)
+		}
+		return paths
+	}
+
+	// 450 tokens: internal gets 225 (both files), docs 90 (none fit), and
+	// the rest 135 (main.go); the 150 left over admits docs/a.md
+	kept, dropped := ApplyBudget(files, rules, 450, nil)
+	if want := []string{"docs/a.md", "internal/a.go", "internal/b.go", "main.go"}; !reflect.DeepEqual(paths(kept), want) {
+		t.Errorf("kept = %q, want %q", paths(kept), want)
+	}
+	if want := []string{"docs/b.md", "docs/c.md"}; !reflect.DeepEqual(paths(dropped), want) {
+		t.Errorf("dropped = %q, want %q", paths(dropped), want)
+	}
+
+	// Priority files are kept even when their area is over budget, and use
+	// up its share first
+	kept, _ = ApplyBudget(files, rules, 450, []string{"docs/c.md", "main.go"})
+	if want := []string{"docs/c.md", "internal/a.go", "internal/b.go", "main.go"}; !reflect.DeepEqual(paths(kept), want) {
+		t.Errorf("kept with priority = %q, want %q", paths(kept), want)
+	}
+
+	// Everything fits
+	kept, dropped = ApplyBudget(files, rules, 600, nil)
+	if len(kept) != len(files) || len(dropped) != 0 {
+		t.Errorf("kept %q, dropped %q; want everything kept", paths(kept), paths(dropped))
+	}
+}
@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// FileCache remembers file contents between walks, so a long-running
+// process only re-reads files whose size or modification time changed.
+// Prune forgets the files a walk no longer reads, so it doesn't keep the
+// contents of deleted files or of trees packed long ago. It is safe for
+// concurrent use.
+type FileCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedFile
+	used    map[string]bool // read since the last Prune
+}
+
+// cachedFile is a file's content as of a given size and modification time.
+type cachedFile struct {
+	size    int64
+	modTime time.Time
+	binary  bool
+	content []byte
+}
+
+// NewFileCache creates an empty cache.
+func NewFileCache() *FileCache {
+	return &FileCache{entries: make(map[string]cachedFile), used: make(map[string]bool)}
+}
+
+// Prune drops the files that haven't been read since the last Prune, such
+// as ones deleted, now ignored, or under another root, and returns how
+// many it dropped. Call it after a walk to keep only what that walk read.
+func (c *FileCache) Prune() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pruned := 0
+	for path := range c.entries {
+		if !c.used[path] {
+			delete(c.entries, path)
+			pruned++
+		}
+	}
+	clear(c.used)
+	return pruned
+}
+
+// read returns the cached content of the file at path if info shows it is
+// unchanged, and otherwise reads it with readContent and caches the result.
+func (c *FileCache) read(path string, info os.FileInfo) (bool, []byte, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[path]
+	c.used[path] = true
+	c.mu.Unlock()
+	if ok && entry.size == info.Size() && entry.modTime.Equal(info.ModTime()) {
+		return entry.binary, entry.content, nil
+	}
+
+	binary, content, err := readContent(path, isBinaryContent)
+	if err != nil {
+		return false, nil, err
+	}
+	c.mu.Lock()
+	c.entries[path] = cachedFile{size: info.Size(), modTime: info.ModTime(), binary: binary, content: content}
+	c.mu.Unlock()
+	return binary, content, nil
+}
@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ChangelogHeading opens the section Changelog writes.
+const ChangelogHeading = "## Changes since the last pack"
+
+// changelogTotal is the line closing a changelog.
+var changelogTotal = regexp.MustCompile(`^\d+ files? changed, \d+ insertions?\(\+\), \d+ deletions?\(-\)$`)
+
+// Changelog renders changes as a Markdown section listing each file added,
+// changed, or removed with the lines added and removed, and the totals
+// after them as git diff --stat has them, so a model given a pack again in
+// an iterative session can tell what's new. It returns "" without changes.
+func Changelog(changes []FileChange) string {
+	if len(changes) == 0 {
+		return ""
+	}
+	var buf strings.Builder
+	buf.WriteString(ChangelogHeading + "\n\n")
+	insertions, deletions := 0, 0
+	for _, change := range changes {
+		added, removed := DiffStat(change.Old, change.New)
+		insertions += added
+		deletions += removed
+		fmt.Fprintf(&buf, "- `%s`: %s, +%d -%d\n", change.Path, change.Kind, added, removed)
+	}
+	fmt.Fprintf(&buf, "\n%d %s changed, %d %s(+), %d %s(-)\n\n",
+		len(changes), plural(len(changes), "file", "files"),
+		insertions, plural(insertions, "insertion", "insertions"),
+		deletions, plural(deletions, "deletion", "deletions"))
+	return buf.String()
+}
+
+// PrependChangelog puts changelog at the top of a Markdown pack, after its
+// front matter if it has any, in place of the changelog an earlier call
+// put there.
+func PrependChangelog(pack []byte, changelog string) []byte {
+	text := string(pack)
+	var front string
+	if strings.HasPrefix(text, "---\n") {
+		if end := strings.Index(text[len("---\n"):], "\n---\n"); end >= 0 {
+			n := len("---\n") + end + len("\n---\n")
+			if strings.HasPrefix(text[n:], "\n") {
+				n++
+			}
+			front, text = text[:n], text[n:]
+		}
+	}
+
+	if strings.HasPrefix(text, ChangelogHeading+"\n") {
+		lines := strings.SplitAfter(text, "\n")
+		for i, line := range lines {
+			if changelogTotal.MatchString(strings.TrimSuffix(line, "\n")) {
+				rest := strings.Join(lines[i+1:], "")
+				text = strings.TrimPrefix(rest, "\n")
+				break
+			}
+		}
+	}
+	return []byte(front + changelog + text)
+}
+
+// plural returns one or many by n.
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,46 @@
+package internal
+
+import "testing"
+
+func TestChangelog(t *testing.T) {
+	changes := []FileChange{
+		{Path: "a.go", Kind: Changed, Old: []byte("package a\n\nvar x = 1\n"), New: []byte("package a\n\nvar x = 2\nvar y = 3\n")},
+		{Path: "new.go", Kind: Added, New: []byte("package a\n")},
+		{Path: "old.go", Kind: Removed, Old: []byte("package a\n\nfunc Old() {}\n")},
+	}
+	want := ChangelogHeading + "\n\n" +
+		"- `a.go`: changed, +2 -1\n" +
+		"- `new.go`: added, +1 -0\n" +
+		"- `old.go`: removed, +0 -3\n" +
+		"\n3 files changed, 3 insertions(+), 4 deletions(-)\n\n"
+	if got := Changelog(changes); got != want {
+		t.Errorf("Changelog() =\n%s\nwant\n%s", got, want)
+	}
+	if got := Changelog(nil); got != "" {
+		t.Errorf("Changelog(nil) = %q, want \"\"", got)
+	}
+}
+
+func TestPrependChangelog(t *testing.T) {
+	body := "## File: a.go\n\n```go\npackage a\n```\n"
+	first := Changelog([]FileChange{{Path: "a.go", Kind: Changed, Old: []byte("x\n"), New: []byte("y\n")}})
+	second := Changelog([]FileChange{{Path: "b.go", Kind: Removed, Old: []byte("x\n")}})
+
+	tests := []struct {
+		name string
+		pack string
+		want string
+	}{
+		{"plain", body, first + body},
+		{"earlier changelog", second + body, first + body},
+		{"front matter", "---\nfiles: 1\n---\n\n" + second + body, "---\nfiles: 1\n---\n\n" + first + body},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(PrependChangelog([]byte(tt.pack), first)); got != tt.want {
+				t.Errorf("PrependChangelog() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
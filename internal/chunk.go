@@ -0,0 +1,145 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// SplitFiles groups files into parts of at most about maxTokens estimated
+// tokens each, keeping their order. A file too large for a part of its own
+// is split at line boundaries into pieces labelled with their line range,
+// e.g. "internal/walker.go (lines 1-180)".
+func SplitFiles(files []File, maxTokens int) [][]File {
+	var parts [][]File
+	var current []File
+	currentTokens := 0
+
+	flush := func() {
+		if len(current) > 0 {
+			parts = append(parts, current)
+			current, currentTokens = nil, 0
+		}
+	}
+
+	for _, file := range files {
+		pieces := []File{file}
+		if FileTokens(file) > maxTokens {
+			pieces = splitFile(file, maxTokens)
+		}
+
+		for _, piece := range pieces {
+			tokens := FileTokens(piece)
+			if currentTokens+tokens > maxTokens {
+				flush()
+			}
+			current = append(current, piece)
+			currentTokens += tokens
+		}
+	}
+	flush()
+
+	return parts
+}
+
+// splitFile splits a file's content at line boundaries into pieces of at
+// most about maxTokens each. Pieces end before a top-level declaration
+// where possible, so each holds whole functions or types; otherwise (and
+// within a declaration too large for one piece) any line will do. A single
+// line longer than maxTokens becomes a piece of its own.
+func splitFile(file File, maxTokens int) []File {
+	lines := bytes.SplitAfter(file.Content, []byte("\n"))
+	if len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	boundaries := declBoundaries(file.Path, file.Content, lines)
+
+	// Leave room for the header, whose line range isn't known yet
+	budget := maxTokens*4 - len(file.Path) - len("File:  (lines 000000-000000)\n")
+
+	var pieces []File
+	start := 0
+	for start < len(lines) {
+		end, size := start, 0
+		for end < len(lines) && (end == start || size+len(lines[end]) <= budget) {
+			size += len(lines[end])
+			end++
+		}
+
+		// Back up to the last declaration that starts in this piece
+		if end < len(lines) && !boundaries[end] {
+			for b := end - 1; b > start; b-- {
+				if boundaries[b] {
+					end = b
+					break
+				}
+			}
+		}
+
+		piece := file
+		piece.Path = fmt.Sprintf("%s (lines %d-%d)", file.Path, start+1, end)
+		piece.Content = bytes.Join(lines[start:end], nil)
+		pieces = append(pieces, piece)
+		start = end
+	}
+	return pieces
+}
+
+// AddOverlap repeats the last lines of each part at the start of the next,
+// so a part read on its own keeps the context just before it. The repeated
+// lines are labelled with their line range like any other piece, and parts
+// may grow past their token limit by that much.
+func AddOverlap(parts [][]File, lines int) [][]File {
+	if lines <= 0 || len(parts) < 2 {
+		return parts
+	}
+
+	result := make([][]File, len(parts))
+	result[0] = parts[0]
+	for i := 1; i < len(parts); i++ {
+		prev := parts[i-1][len(parts[i-1])-1]
+		name, start, end := pieceRange(prev)
+		tail := lastLines(prev.Content, lines)
+		if len(tail) == 0 {
+			result[i] = parts[i]
+			continue
+		}
+		from := max(start, end-countLines(tail)+1)
+
+		part := slices.Clone(parts[i])
+		if nextName, nextStart, nextEnd := pieceRange(part[0]); nextName == name && nextStart == end+1 {
+			// The next part continues the same file: start it earlier
+			part[0].Path = fmt.Sprintf("%s (lines %d-%d)", name, from, nextEnd)
+			part[0].Content = append(slices.Clip(tail), part[0].Content...)
+		} else {
+			overlap := prev
+			overlap.Path = fmt.Sprintf("%s (lines %d-%d)", name, from, end)
+			overlap.Content = tail
+			overlap.DuplicateOf = ""
+			part = append([]File{overlap}, part...)
+		}
+		result[i] = part
+	}
+	return result
+}
+
+// pieceRange returns the file name and line range of a file or piece of a
+// file produced by SplitFiles.
+func pieceRange(file File) (name string, start, end int) {
+	if m := pieceName.FindStringSubmatch(file.Path); m != nil {
+		start, _ = strconv.Atoi(m[2])
+		end, _ = strconv.Atoi(m[3])
+		return m[1], start, end
+	}
+	return file.Path, 1, countLines(file.Content)
+}
+
+// lastLines returns the last n lines of content.
+func lastLines(content []byte, n int) []byte {
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	if len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	return bytes.Join(lines[max(0, len(lines)-n):], nil)
+}
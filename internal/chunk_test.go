@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSplitFiles(t *testing.T) {
+	small := func(name string) File {
+		return File{Path: name, Content: []byte(strings.Repeat("x", 60) + "\n")}
+	}
+	files := []File{small("a.go"), small("b.go"), small("c.go")}
+
+	// Each small file is (4 + 7 + 61) / 4 = 18 tokens
+	tests := []struct {
+		maxTokens int
+		want      [][]string
+	}{
+		{1000, [][]string{{"a.go", "b.go", "c.go"}}},
+		{40, [][]string{{"a.go", "b.go"}, {"c.go"}}},
+		{18, [][]string{{"a.go"}, {"b.go"}, {"c.go"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.maxTokens), func(t *testing.T) {
+			parts := SplitFiles(files, tt.maxTokens)
+			if got := partPaths(parts); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("SplitFiles(%d) = %q, want %q", tt.maxTokens, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitFilesLargeFile(t *testing.T) {
+	var content strings.Builder
+	for i := 1; i <= 10; i++ {
+		fmt.Fprintf(&content, "line %02d %s\n", i, strings.Repeat("x", 30))
+	}
+	large := File{Path: "big.go", Content: []byte(content.String())}
+
+	parts := SplitFiles([]File{large}, 40)
+	want := [][]string{{"big.go (lines 1-3)"}, {"big.go (lines 4-6)"}, {"big.go (lines 7-9)"}, {"big.go (lines 10-10)"}}
+	if got := partPaths(parts); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("SplitFiles() = %q, want %q", got, want)
+	}
+
+	var joined strings.Builder
+	for _, part := range parts {
+		for _, file := range part {
+			if tokens := FileTokens(file); tokens > 40 {
+				t.Errorf("%s is %d tokens, want at most 40", file.Path, tokens)
+			}
+			joined.Write(file.Content)
+		}
+	}
+	if joined.String() != content.String() {
+		t.Error("split pieces don't add up to the original content")
+	}
+}
+
+// partPaths returns the file paths in each part.
+func partPaths(parts [][]File) [][]string {
+	var paths [][]string
+	for _, part := range parts {
+		var names []string
+		for _, file := range part {
+			names = append(names, file.Path)
+		}
+		paths = append(paths, names)
+	}
+	return paths
+}
+
+func TestAddOverlap(t *testing.T) {
+	parts := [][]File{
+		{{Path: "a.go", Content: []byte("1\n2\n3\n")}, {Path: "big.go (lines 1-4)", Content: []byte("l1\nl2\nl3\nl4\n")}},
+		{{Path: "big.go (lines 5-6)", Content: []byte("l5\nl6\n")}},
+		{{Path: "c.go", Content: []byte("c\n")}},
+	}
+
+	got := AddOverlap(parts, 2)
+	want := [][]string{
+		{"a.go", "big.go (lines 1-4)"},
+		{"big.go (lines 3-6)"},
+		{"big.go (lines 5-6)", "c.go"},
+	}
+	if fmt.Sprint(partPaths(got)) != fmt.Sprint(want) {
+		t.Fatalf("AddOverlap() = %q, want %q", partPaths(got), want)
+	}
+	if content := string(got[1][0].Content); content != "l3\nl4\nl5\nl6\n" {
+		t.Errorf("continued piece = %q, want lines 3-6", content)
+	}
+	if content := string(got[2][0].Content); content != "l5\nl6\n" {
+		t.Errorf("overlap piece = %q, want lines 5-6", content)
+	}
+	if string(parts[1][0].Content) != "l5\nl6\n" {
+		t.Error("AddOverlap modified its input")
+	}
+
+	if got := AddOverlap(parts, 0); fmt.Sprint(partPaths(got)) != fmt.Sprint(partPaths(parts)) {
+		t.Errorf("AddOverlap(0) = %q, want parts unchanged", partPaths(got))
+	}
+}
+
+func TestSplitFilesAtDeclarations(t *testing.T) {
+	body := strings.Repeat("\tx++\n", 6)
+	goSource := "package p\n\n// A does a.\nfunc A() {\n" + body + "}\n\nfunc B() {\n" + body + "}\n\ntype C struct{}\n"
+	pySource := "import os\n\n\ndef a():\n" + strings.Repeat("    pass\n", 6) + "\n\nclass B:\n" + strings.Repeat("    x = 1\n", 6)
+
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		// Split by line count alone, the first piece would end inside B
+		{"p.go", goSource, []string{"p.go (lines 1-12)", "p.go (lines 13-22)"}},
+		{"p.py", pySource, []string{"p.py (lines 1-12)", "p.py (lines 13-19)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := SplitFiles([]File{{Path: tt.name, Content: []byte(tt.content)}}, 30)
+			var got []string
+			var joined strings.Builder
+			for _, part := range parts {
+				for _, file := range part {
+					got = append(got, file.Path)
+					joined.Write(file.Content)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("SplitFiles() = %q, want %q", got, tt.want)
+			}
+			if joined.String() != tt.content {
+				t.Error("split pieces don't add up to the original content")
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package internal
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"strings"
+	"unicode/utf16"
+)
+
+// OSC52 returns the OSC 52 escape sequence asking the terminal to put text
+// on the system clipboard, which works across SSH since the sequence travels
+// with the rest of the terminal output. inTmux wraps the sequence in a tmux
+// passthrough so tmux forwards it to the outer terminal.
+func OSC52(text string, inTmux bool) string {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\x07"
+	if !inTmux {
+		return seq
+	}
+	// Passthrough requires every ESC inside it to be doubled
+	return "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+}
+
+// UTF16LE encodes text as little-endian UTF-16 with a byte order mark, the
+// encoding Windows tools such as clip.exe recognize regardless of the
+// console code page.
+func UTF16LE(text string) []byte {
+	units := utf16.Encode([]rune(text))
+	buf := make([]byte, 2, 2+2*len(units))
+	binary.LittleEndian.PutUint16(buf, 0xFEFF)
+	for _, unit := range units {
+		buf = binary.LittleEndian.AppendUint16(buf, unit)
+	}
+	return buf
+}
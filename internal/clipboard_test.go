@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOSC52(t *testing.T) {
+	tests := []struct {
+		text   string
+		inTmux bool
+		want   string
+	}{
+		{"hello", false, "\x1b]52;c;aGVsbG8=\x07"},
+		{"hello", true, "\x1bPtmux;\x1b\x1b]52;c;aGVsbG8=\x07\x1b\\"},
+		{"", false, "\x1b]52;c;\x07"},
+	}
+
+	for _, tt := range tests {
+		if got := OSC52(tt.text, tt.inTmux); got != tt.want {
+			t.Errorf("OSC52(%q, %v) = %q, want %q", tt.text, tt.inTmux, got, tt.want)
+		}
+	}
+}
+
+func TestUTF16LE(t *testing.T) {
+	tests := []struct {
+		text string
+		want []byte
+	}{
+		{"", []byte{0xFF, 0xFE}},
+		{"hi", []byte{0xFF, 0xFE, 'h', 0, 'i', 0}},
+		{"é", []byte{0xFF, 0xFE, 0xE9, 0}},
+		{"😀", []byte{0xFF, 0xFE, 0x3D, 0xD8, 0x00, 0xDE}},
+	}
+
+	for _, tt := range tests {
+		if got := UTF16LE(tt.text); !bytes.Equal(got, tt.want) {
+			t.Errorf("UTF16LE(%q) = % x, want % x", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"cmp"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// omittedRef matches the placeholder CollapseDirs leaves for a directory.
+var omittedRef = regexp.MustCompile(`^\[directory (.+): [\d,]+ files, .+, omitted\]\n?$`)
+
+// CollapsedDir is a directory whose files CollapseDirs replaced with a
+// placeholder.
+type CollapsedDir struct {
+	Path   string // slash-separated, without a trailing "/"
+	Files  int
+	Bytes  int64
+	Tokens int
+}
+
+// CollapseDirs fits files into maxTokens by replacing the files of any
+// directory taking more than share of the budget with a single placeholder
+// listing what was left out, e.g. "[directory testdata: 214 files, 1.2 MB,
+// omitted]". Deeper directories are collapsed first, and only while the
+// files are over budget, so a large testdata/ goes before the package that
+// holds it. Directories holding a file matching a priority pattern are
+// never collapsed. The placeholder takes the place of the directory's first
+// file.
+func CollapseDirs(files []File, maxTokens int, share float64, priority []string) ([]File, []CollapsedDir) {
+	total := 0
+	tokens := make(map[string]int) // by directory, including subdirectories
+	pinned := make(map[string]bool)
+	for _, file := range files {
+		n := FileTokens(file)
+		total += n
+		for _, dir := range parentDirs(file.Path) {
+			tokens[dir] += n
+			if IsPriority(file.Path, priority) {
+				pinned[dir] = true
+			}
+		}
+	}
+
+	// Deepest first, then largest
+	dirs := make([]string, 0, len(tokens))
+	for dir := range tokens {
+		dirs = append(dirs, dir)
+	}
+	slices.SortFunc(dirs, func(a, b string) int {
+		if c := cmp.Compare(strings.Count(b, "/"), strings.Count(a, "/")); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(tokens[b], tokens[a]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+
+	limit := int(share * float64(maxTokens))
+	var collapsed []CollapsedDir
+	for _, dir := range dirs {
+		if total <= maxTokens {
+			break
+		}
+		n := tokens[dir]
+		if n <= limit || pinned[dir] {
+			continue
+		}
+		// Collapsing it takes its tokens off every directory above it
+		for _, parent := range parentDirs(dir) {
+			tokens[parent] -= n
+		}
+		total -= n
+
+		// It takes in any directory collapsed below it
+		entry := CollapsedDir{Path: dir, Tokens: n}
+		collapsed = slices.DeleteFunc(collapsed, func(inner CollapsedDir) bool {
+			if strings.HasPrefix(inner.Path, dir+"/") {
+				entry.Tokens += inner.Tokens
+				return true
+			}
+			return false
+		})
+		collapsed = append(collapsed, entry)
+	}
+	if len(collapsed) == 0 {
+		return files, nil
+	}
+
+	owner := func(file File) int {
+		p := filepath.ToSlash(file.Path)
+		for i, dir := range collapsed {
+			if strings.HasPrefix(p, dir.Path+"/") {
+				return i
+			}
+		}
+		return -1
+	}
+	for _, file := range files {
+		if i := owner(file); i >= 0 {
+			collapsed[i].Files++
+			collapsed[i].Bytes += int64(len(file.Content))
+		}
+	}
+
+	result := make([]File, 0, len(files))
+	placed := make([]bool, len(collapsed))
+	for _, file := range files {
+		i := owner(file)
+		if i < 0 {
+			result = append(result, file)
+			continue
+		}
+		if !placed[i] {
+			placed[i] = true
+			result = append(result, File{
+				Path:    collapsed[i].Path + "/",
+				Content: []byte(collapsed[i].placeholder()),
+				Omitted: true,
+			})
+		}
+	}
+	return result, collapsed
+}
+
+// placeholder returns the text standing in for the directory's files.
+func (d CollapsedDir) placeholder() string {
+	return fmt.Sprintf("[directory %s: %s files, %s, omitted]\n", d.Path, FormatWithCommas(d.Files), FormatBytes(d.Bytes))
+}
+
+// parentDirs returns the slash-separated directories containing a path,
+// innermost first, not counting the root.
+func parentDirs(p string) []string {
+	var dirs []string
+	for dir := path.Dir(filepath.ToSlash(p)); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCollapseDirs(t *testing.T) {
+	big := strings.Repeat("x", 4000) // ~1,000 tokens
+	files := []File{
+		{Path: "main.go", Content: []byte("package main\n")},
+		{Path: "internal/a.go", Content: []byte(big)},
+		{Path: "internal/testdata/one.json", Content: []byte(big)},
+		{Path: "internal/testdata/golden/two.json", Content: []byte(big)},
+		{Path: "internal/b.go", Content: []byte("package internal\n")},
+		{Path: "docs/guide.md", Content: []byte(big)},
+	}
+
+	tests := []struct {
+		name      string
+		maxTokens int
+		share     float64
+		priority  []string
+		want      []string
+		collapsed []string
+	}{
+		{
+			name:      "fits already",
+			maxTokens: 10_000,
+			share:     0.1,
+			want:      []string{"main.go", "internal/a.go", "internal/testdata/one.json", "internal/testdata/golden/two.json", "internal/b.go", "docs/guide.md"},
+		},
+		{
+			name:      "deepest directory over its share first",
+			maxTokens: 3_500,
+			share:     0.25,
+			want:      []string{"main.go", "internal/a.go", "internal/testdata/one.json", "internal/testdata/golden/", "internal/b.go", "docs/guide.md"},
+			collapsed: []string{"internal/testdata/golden"},
+		},
+		{
+			name:      "parent takes in a collapsed subdirectory",
+			maxTokens: 900,
+			share:     0.25,
+			want:      []string{"main.go", "internal/", "docs/"},
+			collapsed: []string{"internal", "docs"},
+		},
+		{
+			name:      "priority files keep their directories",
+			maxTokens: 2_000,
+			share:     0.25,
+			priority:  []string{"internal/a.go"},
+			want:      []string{"main.go", "internal/a.go", "internal/testdata/", "internal/b.go", "docs/"},
+			collapsed: []string{"internal/testdata", "docs"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, collapsed := CollapseDirs(files, tt.maxTokens, tt.share, tt.priority)
+			var paths, dirs []string
+			for _, file := range got {
+				paths = append(paths, file.Path)
+			}
+			for _, dir := range collapsed {
+				dirs = append(dirs, dir.Path)
+			}
+			if !slices.Equal(paths, tt.want) {
+				t.Errorf("CollapseDirs() files = %q, want %q", paths, tt.want)
+			}
+			if !slices.Equal(dirs, tt.collapsed) {
+				t.Errorf("CollapseDirs() collapsed = %q, want %q", dirs, tt.collapsed)
+			}
+		})
+	}
+
+	got, _ := CollapseDirs(files, 900, 0.25, nil)
+	want := "[directory internal: 4 files, 12 KB, omitted]\n"
+	if string(got[1].Content) != want || !got[1].Omitted {
+		t.Errorf("placeholder = %q (Omitted=%v), want %q", got[1].Content, got[1].Omitted, want)
+	}
+}
+
+func TestParsePackCollapsed(t *testing.T) {
+	files := []File{
+		{Path: "main.go", Content: []byte("package main\n")},
+		{Path: "testdata/", Content: []byte("[directory testdata: 214 files, 1.2 MB, omitted]\n"), Omitted: true},
+		{Path: "util.go", Content: []byte("package main\n")},
+	}
+	for _, format := range Formats {
+		formatter := NewFormatter(files)
+		formatter.OutputFormat = format
+		output := formatter.Format()
+		if format == FormatMarkdown && !strings.Contains(output, "## File: testdata/\n\n[directory testdata: 214 files, 1.2 MB, omitted]\n") {
+			t.Errorf("Format(markdown) = %q, want the placeholder outside a code fence", output)
+		}
+
+		got, err := ParsePack([]byte(output))
+		if err != nil {
+			t.Fatalf("ParsePack(%s) error = %v", format, err)
+		}
+		assertFiles(t, got, []File{files[0], files[2]}, format)
+	}
+}
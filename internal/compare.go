@@ -0,0 +1,53 @@
+package internal
+
+import "bytes"
+
+// Change kinds reported by CompareFiles.
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+// FileChange is a file that differs between two sets of files.
+type FileChange struct {
+	Path     string
+	Kind     string
+	Old, New []byte
+}
+
+// CompareFiles returns the files added, removed, or changed going from old
+// to new, matched by path: changed and added files in new's order, then
+// removed files in old's order.
+func CompareFiles(old, new []File) []FileChange {
+	before := make(map[string][]byte, len(old))
+	for _, file := range old {
+		before[file.Path] = file.Content
+	}
+	after := make(map[string]bool, len(new))
+
+	var changes []FileChange
+	for _, file := range new {
+		after[file.Path] = true
+		content, ok := before[file.Path]
+		switch {
+		case !ok:
+			changes = append(changes, FileChange{Path: file.Path, Kind: Added, New: file.Content})
+		case !SameContent(content, file.Content):
+			changes = append(changes, FileChange{Path: file.Path, Kind: Changed, Old: content, New: file.Content})
+		}
+	}
+	for _, file := range old {
+		if !after[file.Path] {
+			changes = append(changes, FileChange{Path: file.Path, Kind: Removed, Old: file.Content})
+		}
+	}
+	return changes
+}
+
+// SameContent reports whether two versions of a file are equal, ignoring a
+// single final newline that one has and the other lacks (Markdown packs
+// always end files with one).
+func SameContent(a, b []byte) bool {
+	return bytes.Equal(bytes.TrimSuffix(a, []byte("\n")), bytes.TrimSuffix(b, []byte("\n")))
+}
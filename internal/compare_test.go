@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareFiles(t *testing.T) {
+	old := []File{
+		{Path: "a.go", Content: []byte("package a\n")},
+		{Path: "b.go", Content: []byte("package b\n")},
+		{Path: "c.go", Content: []byte("package c")},
+	}
+	new := []File{
+		{Path: "d.go", Content: []byte("package d\n")},
+		{Path: "a.go", Content: []byte("package a // edited\n")},
+		{Path: "c.go", Content: []byte("package c\n")},
+	}
+
+	var got []string
+	for _, change := range CompareFiles(old, new) {
+		got = append(got, change.Kind+" "+change.Path)
+	}
+	want := []string{"added d.go", "changed a.go", "removed b.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CompareFiles() = %q, want %q", got, want)
+	}
+}
+
+func TestSameContent(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"x\n", "x\n", true},
+		{"x", "x\n", true},
+		{"x\n", "x", true},
+		{"x\n", "x\n\n", false},
+		{"x", "y", false},
+		{"", "\n", true},
+	}
+	for _, tt := range tests {
+		if got := SameContent([]byte(tt.a), []byte(tt.b)); got != tt.want {
+			t.Errorf("SameContent(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Output compression methods.
+const (
+	CompressGzip = "gzip"
+	CompressZstd = "zstd"
+)
+
+// Compressions lists the supported output compression methods.
+var Compressions = []string{CompressGzip, CompressZstd}
+
+// compressionExts maps file extensions to the compression they imply.
+var compressionExts = map[string]string{
+	".gz":   CompressGzip,
+	".gzip": CompressGzip,
+	".zst":  CompressZstd,
+	".zstd": CompressZstd,
+}
+
+// CompressionFor returns the compression implied by a file name's
+// extension, or "" if none.
+func CompressionFor(path string) string {
+	return compressionExts[strings.ToLower(filepath.Ext(path))]
+}
+
+// SniffCompression returns the compression data starts with the magic
+// number of, or "" if none, for input without a telling name.
+func SniffCompression(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		return CompressGzip
+	case bytes.HasPrefix(data, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return CompressZstd
+	}
+	return ""
+}
+
+// CompressionExt returns the conventional file extension for a method.
+func CompressionExt(method string) string {
+	switch method {
+	case CompressGzip:
+		return ".gz"
+	case CompressZstd:
+		return ".zst"
+	}
+	return ""
+}
+
+// Compress compresses data with the given method. zstd has no standard
+// library implementation, so it runs the zstd command, which must be
+// installed.
+func Compress(data []byte, method string) ([]byte, error) {
+	switch method {
+	case CompressGzip:
+		var buf bytes.Buffer
+		writer, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := writer.Write(data); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+
+	case CompressZstd:
+		return runZstd(data, "-19")
+	}
+
+	return nil, fmt.Errorf("unknown compression %q (expected one of: %s)", method, strings.Join(Compressions, ", "))
+}
+
+// Decompress reverses Compress.
+func Decompress(data []byte, method string) ([]byte, error) {
+	switch method {
+	case CompressGzip:
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+
+	case CompressZstd:
+		return runZstd(data, "-d")
+	}
+
+	return nil, fmt.Errorf("unknown compression %q (expected one of: %s)", method, strings.Join(Compressions, ", "))
+}
+
+// runZstd runs the zstd command on data with the given mode flag.
+func runZstd(data []byte, mode string) ([]byte, error) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		return nil, fmt.Errorf("zstd compression requires the zstd command in PATH")
+	}
+	cmd := exec.Command("zstd", "-q", "-c", mode)
+	cmd.Stdin = bytes.NewReader(data)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("zstd: %s", msg)
+		}
+		return nil, fmt.Errorf("zstd: %w", err)
+	}
+	return out, nil
+}
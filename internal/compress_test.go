@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os/exec"
+	"testing"
+)
+
+func TestCompressionFor(t *testing.T) {
+	tests := map[string]string{
+		"context.md.gz":  CompressGzip,
+		"context.txt.GZ": CompressGzip,
+		"pack.zst":       CompressZstd,
+		"pack.zstd":      CompressZstd,
+		"context.txt":    "",
+		"gz":             "",
+	}
+	for path, want := range tests {
+		if got := CompressionFor(path); got != want {
+			t.Errorf("CompressionFor(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestCompressGzip(t *testing.T) {
+	data := bytes.Repeat([]byte("File: main.go\npackage main\n"), 100)
+
+	compressed, err := Compress(data, CompressGzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("gzip round trip changed the data")
+	}
+
+	got, err = Decompress(compressed, CompressGzip)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("Decompress(gzip) = %d bytes, %v; want the original data", len(got), err)
+	}
+}
+
+func TestCompressZstd(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd not installed")
+	}
+	data := bytes.Repeat([]byte("File: main.go\npackage main\n"), 100)
+
+	compressed, err := Compress(data, CompressZstd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("zstd", "-d", "-q", "-c")
+	cmd.Stdin = bytes.NewReader(compressed)
+	got, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("zstd round trip changed the data")
+	}
+
+	got, err = Decompress(compressed, CompressZstd)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("Decompress(zstd) = %d bytes, %v; want the original data", len(got), err)
+	}
+}
+
+func TestCompressUnknown(t *testing.T) {
+	if _, err := Compress([]byte("x"), "lz4"); err == nil {
+		t.Error("Compress(lz4) succeeded, want error")
+	}
+}
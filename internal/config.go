@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ConfigFile is the name of the per-project configuration file, read from
+// the root of the packed tree.
+const ConfigFile = ".gopack.json"
+
+// Config holds per-project settings.
+type Config struct {
+	// Budget maps directory prefixes (such as "internal/") to the share of
+	// --max-tokens their files may use, as percentages like "60%". The
+	// special key "rest" covers every other file.
+	Budget map[string]string `json:"budget"`
+
+	// Priority lists paths or globs to put first in the pack and never drop
+	// when trimming to a budget, like --priority.
+	Priority []string `json:"priority"`
+
+	// Hooks are shell commands run before and after packing.
+	Hooks Hooks `json:"hooks"`
+
+	// Plugins are filter plugin commands the files pass through, like
+	// --plugin.
+	Plugins []string `json:"plugins"`
+
+	// Outline lists paths or globs of files to pack as outlines, keeping
+	// declarations but not function bodies, like --outline: typically
+	// dependencies and sibling modules the packed code only calls.
+	Outline []string `json:"outline"`
+
+	// Transforms maps file types (a file name, an extension such as "json",
+	// a language such as "go", or "*") to the transforms their files pass
+	// through, such as ["strip-comments", "collapse-imports"]. A matching
+	// entry replaces --collapse-imports for those files.
+	Transforms map[string][]string `json:"transforms"`
+
+	// FileHeader is a template for the header line of each file in text and
+	// Markdown output, such as "### {{.Path}} ({{.Lines}} lines)", in place
+	// of "File: path". See FileHeaderData for the fields it can use.
+	FileHeader string `json:"file_header"`
+
+	// VCSDirs replaces the names of the version control directories that
+	// are never packed (.git, .hg, .svn, .jj, and .bzr), like --vcs-dirs.
+	VCSDirs []string `json:"vcs_dirs"`
+
+	// Redact maps names to regular expressions of secrets --redact hides
+	// along with the built-in ones (see RedactRules), such as internal host
+	// names.
+	Redact map[string]string `json:"redact"`
+
+	// Presets defines presets for --preset, replacing any built-in preset
+	// of the same name.
+	Presets map[string]Preset `json:"presets"`
+}
+
+// Hooks holds the commands run around a pack, from the root of the packed
+// tree.
+type Hooks struct {
+	// Pre runs before the tree is walked, so that files it generates are
+	// packed (such as "go generate ./...").
+	Pre string `json:"pre"`
+
+	// Post runs once the pack has been written, copied, or printed, with
+	// GOPACK_OUTPUT set to the output file, if any.
+	Post string `json:"post"`
+}
+
+// LoadConfig reads the ConfigFile in dir. A missing file yields an empty
+// configuration.
+func LoadConfig(dir string) (Config, error) {
+	var config Config
+	data, err := os.ReadFile(filepath.Join(dir, ConfigFile))
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("invalid %s: %w", ConfigFile, err)
+	}
+	return config, nil
+}
@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+)
+
+// Dedupe replaces the content of files that are byte-identical to an
+// earlier file with a short reference to it, e.g. "[identical to foo/bar.go]".
+// It returns the updated files and how many were deduplicated. Files too
+// small to benefit are left alone.
+func Dedupe(files []File) ([]File, int) {
+	firstSeen := make(map[[sha256.Size]byte]string)
+	result := make([]File, len(files))
+	count := 0
+
+	for i, file := range files {
+		result[i] = file
+
+		sum := sha256.Sum256(file.Content)
+		original, ok := firstSeen[sum]
+		if !ok {
+			firstSeen[sum] = file.Path
+			continue
+		}
+
+		reference := fmt.Sprintf("[identical to %s]\n", filepath.ToSlash(original))
+		if len(reference) < len(file.Content) {
+			result[i].Content = []byte(reference)
+			result[i].DuplicateOf = filepath.ToSlash(original)
+			count++
+		}
+	}
+
+	return result, count
+}
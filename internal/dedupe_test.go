@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDedupe(t *testing.T) {
+	long := "package a\n\nfunc A() {}\n"
+	files := []File{
+		{Path: "a.go", Content: []byte(long)},
+		{Path: "b/a.go", Content: []byte(long)},
+		{Path: "tiny1.txt", Content: []byte("x\n")},
+		{Path: "tiny2.txt", Content: []byte("x\n")},
+		{Path: "c.go", Content: []byte("package c\n")},
+	}
+
+	got, count := Dedupe(files)
+	if count != 1 {
+		t.Errorf("Dedupe() count = %d, want 1", count)
+	}
+
+	tests := []struct {
+		path        string
+		content     string
+		duplicateOf string
+	}{
+		{"a.go", long, ""},
+		{"b/a.go", "[identical to a.go]\n", "a.go"},
+		{"tiny1.txt", "x\n", ""},
+		{"tiny2.txt", "x\n", ""}, // a reference would be longer than the file
+		{"c.go", "package c\n", ""},
+	}
+	for i, tt := range tests {
+		if got[i].Path != tt.path || string(got[i].Content) != tt.content || got[i].DuplicateOf != tt.duplicateOf {
+			t.Errorf("Dedupe()[%d] = {%q, %q, %q}, want {%q, %q, %q}",
+				i, got[i].Path, got[i].Content, got[i].DuplicateOf, tt.path, tt.content, tt.duplicateOf)
+		}
+	}
+
+	if string(files[1].Content) != long {
+		t.Error("Dedupe() modified its input")
+	}
+}
+
+func TestFormatMarkdownDuplicate(t *testing.T) {
+	files, _ := Dedupe([]File{
+		{Path: "a.go", Content: []byte("package a\n\nfunc A() {}\n")},
+		{Path: "b.go", Content: []byte("package a\n\nfunc A() {}\n")},
+	})
+	formatter := NewFormatter(files)
+	formatter.OutputFormat = FormatMarkdown
+	output := formatter.Format()
+
+	want := "## File: b.go\n\n[identical to a.go]\n"
+	if !strings.HasSuffix(output, want) {
+		t.Errorf("Format() = %q, want suffix %q", output, want)
+	}
+	if strings.Count(output, "```") != 2 {
+		t.Errorf("Format() = %q, want only a.go fenced", output)
+	}
+}
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Module is a Go module dependency located on disk.
+type Module struct {
+	Path    string // module path, such as "github.com/spf13/cobra"
+	Version string // resolved version, or "" for local replacements
+	Dir     string // directory holding the module's source
+}
+
+// Label returns the name the module's files are packed under:
+// "path@version", or just the path if the version is unknown.
+func (m Module) Label() string {
+	if m.Version == "" {
+		return m.Path
+	}
+	return m.Path + "@" + m.Version
+}
+
+// FindModule locates the source of a dependency of the Go module in dir,
+// preferring its vendor directory and otherwise asking "go list -m" for the
+// copy in the module cache, which must already be downloaded.
+func FindModule(dir, path string) (Module, error) {
+	vendored := filepath.Join(dir, "vendor", filepath.FromSlash(path))
+	if info, err := os.Stat(vendored); err == nil && info.IsDir() {
+		return Module{Path: path, Version: vendorVersion(dir, path), Dir: vendored}, nil
+	}
+
+	if _, err := exec.LookPath("go"); err != nil {
+		return Module{}, fmt.Errorf("finding %s requires the go command in PATH", path)
+	}
+	cmd := exec.Command("go", "list", "-mod=mod", "-m", "-json", path)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return Module{}, fmt.Errorf("go list %s: %s", path, msg)
+		}
+		return Module{}, fmt.Errorf("go list %s: %w", path, err)
+	}
+
+	var listed struct {
+		Path    string
+		Version string
+		Dir     string
+		Replace *struct {
+			Version string
+			Dir     string
+		}
+	}
+	if err := json.Unmarshal(out, &listed); err != nil {
+		return Module{}, fmt.Errorf("go list %s: %w", path, err)
+	}
+	module := Module{Path: listed.Path, Version: listed.Version, Dir: listed.Dir}
+	if listed.Replace != nil {
+		module.Version, module.Dir = listed.Replace.Version, listed.Replace.Dir
+	}
+	if module.Dir == "" {
+		return Module{}, fmt.Errorf("%s is not downloaded (run 'go mod download %s')", path, path)
+	}
+	return module, nil
+}
+
+// vendorVersion reads a vendored module's version from vendor/modules.txt,
+// returning "" if it isn't listed.
+func vendorVersion(dir, path string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "vendor", "modules.txt"))
+	if err != nil {
+		return ""
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		// Module lines look like "# github.com/foo/bar v1.2.3"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 3 && fields[0] == "#" && fields[1] == path {
+			return fields[2]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindModuleVendored(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"go.mod":                            "module example.com/app\n",
+		"vendor/modules.txt":                "# example.com/dep v1.2.3\n## explicit\nexample.com/dep\n",
+		"vendor/example.com/dep/dep.go":     "package dep\n",
+		"vendor/example.com/other/other.go": "package other\n",
+	})
+
+	module, err := FindModule(dir, "example.com/dep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Module{Path: "example.com/dep", Version: "v1.2.3", Dir: filepath.Join(dir, "vendor", "example.com", "dep")}
+	if module != want {
+		t.Errorf("FindModule() = %+v, want %+v", module, want)
+	}
+	if got := module.Label(); got != "example.com/dep@v1.2.3" {
+		t.Errorf("Label() = %q", got)
+	}
+
+	module, err = FindModule(dir, "example.com/other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if module.Version != "" || module.Label() != "example.com/other" {
+		t.Errorf("unlisted vendored module = %+v, label %q", module, module.Label())
+	}
+}
+
+func TestFindModuleReplaced(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not installed")
+	}
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"app/go.mod": "module example.com/app\n\ngo 1.21\n\nrequire example.com/dep v0.0.0\n\nreplace example.com/dep => ../dep\n",
+		"dep/go.mod": "module example.com/dep\n",
+		"dep/dep.go": "package dep\n",
+	})
+
+	module, err := FindModule(filepath.Join(dir, "app"), "example.com/dep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if module.Dir != filepath.Join(dir, "dep") {
+		t.Errorf("FindModule().Dir = %q, want %q", module.Dir, filepath.Join(dir, "dep"))
+	}
+
+	if _, err := FindModule(filepath.Join(dir, "app"), "example.com/missing"); err == nil {
+		t.Error("FindModule(missing) succeeded, want error")
+	}
+}
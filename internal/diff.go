@@ -0,0 +1,313 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// edit is one line of a line-based diff: ' ' kept, '-' removed, '+' added.
+type edit struct {
+	op   byte
+	line string
+}
+
+// UnifiedDiff returns a unified diff from old to new, labelled with the
+// given names, or "" if they are identical.
+func UnifiedDiff(oldName, newName string, old, new []byte) string {
+	if bytes.Equal(old, new) {
+		return ""
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+	for _, hunk := range DiffHunks(old, new) {
+		hunk.write(&out)
+	}
+	return out.String()
+}
+
+// Hunk is one group of nearby changes between two versions of a file, with
+// diffContext unchanged lines around them.
+type Hunk struct {
+	oldStart, newStart int // first line of each side, counting from 1
+	edits              []edit
+}
+
+// DiffHunks returns the changes from old to new as the hunks UnifiedDiff
+// shows.
+func DiffHunks(old, new []byte) []Hunk {
+	edits := diffLines(splitLines(old), splitLines(new))
+
+	// Group changes whose context would overlap into one hunk
+	var hunks []Hunk
+	oldLine, newLine := 1, 1
+	for start, next := 0, 0; start < len(edits); {
+		for start < len(edits) && edits[start].op == ' ' {
+			start++
+		}
+		if start == len(edits) {
+			break
+		}
+
+		first := max(next, start-diffContext)
+		end, kept := start, 0
+		for end < len(edits) && kept <= 2*diffContext {
+			if edits[end].op == ' ' {
+				kept++
+			} else {
+				kept = 0
+			}
+			end++
+		}
+		// Trim trailing context to diffContext lines
+		if kept > diffContext {
+			end -= kept - diffContext
+		}
+
+		for _, e := range edits[next:first] {
+			oldLine, newLine = advance(e, oldLine, newLine)
+		}
+		hunk := Hunk{oldStart: oldLine, newStart: newLine, edits: edits[first:end]}
+		for _, e := range hunk.edits {
+			oldLine, newLine = advance(e, oldLine, newLine)
+		}
+		hunks = append(hunks, hunk)
+		start, next = end, end
+	}
+	return hunks
+}
+
+// advance returns the line numbers after edit e on each side.
+func advance(e edit, oldLine, newLine int) (int, int) {
+	if e.op != '+' {
+		oldLine++
+	}
+	if e.op != '-' {
+		newLine++
+	}
+	return oldLine, newLine
+}
+
+// String returns the hunk in unified diff form, with its "@@" header.
+func (h Hunk) String() string {
+	var out strings.Builder
+	h.write(&out)
+	return out.String()
+}
+
+// write writes the hunk with its "@@" header.
+func (h Hunk) write(out *strings.Builder) {
+	oldStart, newStart := h.oldStart, h.newStart
+	oldLines, newLines := h.lengths()
+	// An empty range starts at the line before it
+	if oldLines == 0 {
+		oldStart--
+	}
+	if newLines == 0 {
+		newStart--
+	}
+
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", oldStart, oldLines, newStart, newLines)
+	for _, e := range h.edits {
+		out.WriteByte(e.op)
+		out.WriteString(e.line)
+		if !strings.HasSuffix(e.line, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// lengths returns the number of old and new lines the hunk covers.
+func (h Hunk) lengths() (oldLines, newLines int) {
+	for _, e := range h.edits {
+		oldLines, newLines = advance(e, oldLines, newLines)
+	}
+	return oldLines, newLines
+}
+
+// side returns the old ('-') or new ('+') lines of the hunk.
+func (h Hunk) side(op byte) []string {
+	var lines []string
+	for _, e := range h.edits {
+		if e.op == ' ' || e.op == op {
+			lines = append(lines, e.line)
+		}
+	}
+	return lines
+}
+
+// Edit returns the hunk with its changes replaced by those in text, an
+// edited copy of the hunk's String. As with "git add -p", added lines may
+// be changed or deleted and removed lines kept by turning their "-" into a
+// space, but the lines the hunk removes or keeps must stay the same. Lines
+// starting with "#" and the "@@" header are ignored.
+func (h Hunk) Edit(text string) (Hunk, error) {
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	var edits []edit
+	for _, line := range strings.SplitAfter(text, "\n") {
+		switch {
+		case line == "" || line[0] == '#' || strings.HasPrefix(line, "@@"):
+		case line[0] == '\\':
+			// "\ No newline at end of file" applies to the line before
+			if len(edits) > 0 {
+				edits[len(edits)-1].line = strings.TrimSuffix(edits[len(edits)-1].line, "\n")
+			}
+		case line[0] == ' ' || line[0] == '-' || line[0] == '+':
+			edits = append(edits, edit{op: line[0], line: line[1:]})
+		case line == "\n":
+			// A blank context line whose leading space was lost
+			edits = append(edits, edit{op: ' ', line: line})
+		default:
+			return h, fmt.Errorf("unexpected line %q; lines must start with ' ', '-', or '+'", strings.TrimSuffix(line, "\n"))
+		}
+	}
+
+	edited := Hunk{oldStart: h.oldStart, newStart: h.newStart, edits: edits}
+	if !slices.Equal(edited.side('-'), h.side('-')) {
+		return h, fmt.Errorf("the edited hunk doesn't match the file; only change '+' lines, or turn '-' lines into context")
+	}
+	return edited, nil
+}
+
+// MergeHunks returns old with some of the hunks from DiffHunks(old, new)
+// applied, in order.
+func MergeHunks(old []byte, hunks []Hunk) []byte {
+	lines := splitLines(old)
+	var out strings.Builder
+	pos := 0
+	for _, hunk := range hunks {
+		at := hunk.oldStart - 1
+		for _, line := range lines[pos:at] {
+			out.WriteString(line)
+		}
+		for _, line := range hunk.side('+') {
+			out.WriteString(line)
+		}
+		oldLines, _ := hunk.lengths()
+		pos = at + oldLines
+	}
+	for _, line := range lines[pos:] {
+		out.WriteString(line)
+	}
+	return []byte(out.String())
+}
+
+// DiffStat counts the lines added and removed going from old to new, as
+// in git diff --stat.
+func DiffStat(old, new []byte) (added, removed int) {
+	for _, e := range diffLines(splitLines(old), splitLines(new)) {
+		switch e.op {
+		case '+':
+			added++
+		case '-':
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// splitLines splits content into lines, each keeping its newline.
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes a shortest edit script from a to b using Myers'
+// algorithm, after trimming any common prefix and suffix.
+func diffLines(a, b []string) []edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var edits []edit
+	for _, line := range a[:prefix] {
+		edits = append(edits, edit{' ', line})
+	}
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, edit{' ', line})
+	}
+	return edits
+}
+
+// myers returns the edit script from a to b.
+func myers(a, b []string) []edit {
+	n, m := len(a), len(b)
+	limit := n + m
+	offset := limit + 1
+	v := make([]int, 2*limit+2)
+	var trace [][]int
+
+search:
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // down: insertion
+			} else {
+				x = v[offset+k-1] + 1 // right: deletion
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk the trace backwards to recover the path
+	var edits []edit
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			edits = append(edits, edit{' ', a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				edits = append(edits, edit{'+', b[prevY]})
+			} else {
+				edits = append(edits, edit{'-', a[prevX]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{
+			name: "identical",
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+			want: "",
+		},
+		{
+			name: "change in the middle",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			new:  "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			want: "--- a\n+++ b\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name: "new file",
+			old:  "",
+			new:  "x\ny\n",
+			want: "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+x\n+y\n",
+		},
+		{
+			name: "deleted content",
+			old:  "x\n",
+			new:  "",
+			want: "--- a\n+++ b\n@@ -1,1 +0,0 @@\n-x\n",
+		},
+		{
+			name: "missing newline",
+			old:  "a\nb",
+			new:  "a\nb\n",
+			want: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			name: "separate hunks",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			new:  "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			want: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff("a", "b", []byte(tt.old), []byte(tt.new)); got != tt.want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiffApplies(t *testing.T) {
+	if _, err := exec.LookPath("patch"); err != nil {
+		t.Skip("patch not installed")
+	}
+	old := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n\nfunc a() {}\nfunc b() {}\nfunc c() {}\nfunc d() {}\n"
+	new := "package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nfunc main() {\n\tfmt.Println(\"hi\", os.Args)\n}\n\nfunc a() {}\nfunc c() {}\nfunc d() {}\nfunc e() {}\n"
+
+	dir := t.TempDir()
+	writeFiles(t, dir, "main.go")
+	if err := writeFile(dir+"/main.go", old); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("patch", "-s", "main.go")
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(UnifiedDiff("a/main.go", "b/main.go", []byte(old), []byte(new)))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("patch failed: %v\n%s", err, out)
+	}
+	if got := readFile(t, dir+"/main.go"); got != new {
+		t.Errorf("patched file =\n%s\nwant\n%s", got, new)
+	}
+}
+
+func TestDiffHunks(t *testing.T) {
+	var oldLines, newLines []string
+	for i := 1; i <= 20; i++ {
+		oldLines = append(oldLines, fmt.Sprintf("line %d\n", i))
+	}
+	newLines = slices.Clone(oldLines)
+	newLines[1] = "line two\n"
+	newLines[17] = "line eighteen\n"
+	old, new := []byte(strings.Join(oldLines, "")), []byte(strings.Join(newLines, ""))
+
+	hunks := DiffHunks(old, new)
+	if len(hunks) != 2 {
+		t.Fatalf("DiffHunks() = %d hunks, want 2", len(hunks))
+	}
+	if !strings.HasPrefix(hunks[1].String(), "@@ -15,6 +15,6 @@\n line 15\n") {
+		t.Errorf("second hunk = %q", hunks[1])
+	}
+
+	// Applying every hunk gives the new version, and a subset only those changes
+	if got := MergeHunks(old, hunks); string(got) != string(new) {
+		t.Errorf("MergeHunks(all) = %q, want %q", got, new)
+	}
+	want := strings.Replace(string(old), "line 18\n", "line eighteen\n", 1)
+	if got := MergeHunks(old, hunks[1:]); string(got) != want {
+		t.Errorf("MergeHunks(second) = %q, want %q", got, want)
+	}
+	if got := MergeHunks(old, nil); string(got) != string(old) {
+		t.Errorf("MergeHunks(none) changed the file")
+	}
+
+	// A new file is one hunk
+	if hunks := DiffHunks(nil, []byte("a\nb\n")); len(hunks) != 1 || string(MergeHunks(nil, hunks)) != "a\nb\n" {
+		t.Errorf("DiffHunks() of a new file = %v", hunks)
+	}
+}
+
+func TestHunkEdit(t *testing.T) {
+	old := []byte("a\nb\nc\n")
+	hunk := DiffHunks(old, []byte("a\nB\nc\nd\n"))[0]
+
+	// Change an added line, drop another, and turn a removal into context
+	edited, err := hunk.Edit("@@ -1,3 +1,4 @@\n a\n b\n+Bee\n c\n# a comment\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(MergeHunks(old, []Hunk{edited})); got != "a\nb\nBee\nc\n" {
+		t.Errorf("MergeHunks(edited) = %q", got)
+	}
+
+	for _, text := range []string{
+		" a\n-x\n c\n", // removes a line that isn't there
+		" a\n-b\n+B\n", // drops context
+		" a\n?b\n c\n", // bad prefix
+	} {
+		if _, err := hunk.Edit(text); err == nil {
+			t.Errorf("Edit(%q) succeeded, want error", text)
+		}
+	}
+}
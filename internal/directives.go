@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// Directives a file can give about how it's packed, as a comment line
+// near its top such as "// gopack:outline-only".
+const (
+	DirectiveIgnore      = "ignore"       // leave the file out, unless named explicitly
+	DirectiveOutlineOnly = "outline-only" // pack the file's outline, as --outline would
+	DirectivePriority    = "priority"     // "high" packs it first and keeps it under a budget; "low" packs it last
+)
+
+// Priorities a priority directive takes.
+const (
+	PriorityHigh = "high"
+	PriorityLow  = "low"
+)
+
+// directiveMarker matches a whole comment line holding a directive, with
+// any of the comment leaders generated-code markers are found after.
+var directiveMarker = regexp.MustCompile(`^(?://|#|--|;|/\*|<!--) ?gopack:([a-z-]+)(?: +([a-z]+))?(?: ?(?:\*/|-->))?$`)
+
+// Directives are the directives a file gives.
+type Directives struct {
+	Ignore      bool
+	OutlineOnly bool
+	Priority    string // PriorityHigh, PriorityLow, or "" for neither
+}
+
+// ParseDirectives reads the directives in the comment lines of a file's
+// header, as far down as generated-code markers are looked for. Only the
+// header is read so that code and documentation mentioning a directive,
+// like this comment, don't trigger it; unknown directives are ignored.
+func ParseDirectives(content []byte) Directives {
+	var d Directives
+	if !bytes.Contains(content, []byte("gopack:")) {
+		return d
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for i := 0; i < generatedHeaderLines && scanner.Scan(); i++ {
+		m := directiveMarker.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if m == nil {
+			continue
+		}
+		switch {
+		case m[1] == DirectiveIgnore && m[2] == "":
+			d.Ignore = true
+		case m[1] == DirectiveOutlineOnly && m[2] == "":
+			d.OutlineOnly = true
+		case m[1] == DirectivePriority && (m[2] == PriorityHigh || m[2] == PriorityLow):
+			d.Priority = m[2]
+		}
+	}
+	return d
+}
+
+// OutlineDirected reduces the files with an outline-only directive to
+// their outlines, as OutlineFiles does for patterns, and returns the files
+// with the number changed.
+func OutlineDirected(files []File) ([]File, int) {
+	result := make([]File, len(files))
+	changed := 0
+	for i, file := range files {
+		result[i] = file
+		if file.DuplicateOf != "" || file.Omitted || !ParseDirectives(file.Content).OutlineOnly {
+			continue
+		}
+		if content, ok := outlineFile(file.Path, file.Content); ok {
+			result[i].Content = content
+			changed++
+		}
+	}
+	return result, changed
+}
+
+// DirectedPriority returns the paths of the files with a "priority high"
+// directive, to treat as priority patterns, and moves those with "priority
+// low" to the end, keeping the order of the rest.
+func DirectedPriority(files []File) ([]string, []File) {
+	var high []string
+	var normal, low []File
+	for _, file := range files {
+		switch ParseDirectives(file.Content).Priority {
+		case PriorityHigh:
+			high = append(high, file.Path)
+			normal = append(normal, file)
+		case PriorityLow:
+			low = append(low, file)
+		default:
+			normal = append(normal, file)
+		}
+	}
+	return high, append(normal, low...)
+}
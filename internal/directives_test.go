@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseDirectives(t *testing.T) {
+	tests := map[string]Directives{
+		"// gopack:ignore\npackage main\n":                                    {Ignore: true},
+		"#!/usr/bin/env python\n# gopack:outline-only\n":                      {OutlineOnly: true},
+		"/* gopack:priority high */\nbody {}\n":                               {Priority: PriorityHigh},
+		"<!-- gopack:priority low -->\n# Notes\n":                             {Priority: PriorityLow},
+		"-- gopack:ignore\n// gopack:priority high\n":                         {Ignore: true, Priority: PriorityHigh},
+		"// gopack:priority urgent\n":                                         {},
+		"// gopack:ignore this file\n":                                        {},
+		"// See gopack:ignore in the docs.\n":                                 {},
+		"x := \"// gopack:ignore\"\n":                                         {},
+		strings.Repeat("line\n", generatedHeaderLines) + "// gopack:ignore\n": {},
+	}
+	for content, want := range tests {
+		if got := ParseDirectives([]byte(content)); got != want {
+			t.Errorf("ParseDirectives(%q) = %+v, want %+v", content, got, want)
+		}
+	}
+}
+
+func TestDirectedPriority(t *testing.T) {
+	files := []File{
+		{Path: "a.go", Content: []byte("// gopack:priority low\npackage a\n")},
+		{Path: "b.go", Content: []byte("package b\n")},
+		{Path: "c.go", Content: []byte("// gopack:priority high\npackage c\n")},
+		{Path: "d.go", Content: []byte("package d\n")},
+	}
+	high, files := DirectedPriority(files)
+	if !slices.Equal(high, []string{"c.go"}) {
+		t.Errorf("high = %q, want [c.go]", high)
+	}
+	var got []string
+	for _, file := range Prioritize(files, high) {
+		got = append(got, file.Path)
+	}
+	if want := []string{"c.go", "b.go", "d.go", "a.go"}; !slices.Equal(got, want) {
+		t.Errorf("order = %q, want %q", got, want)
+	}
+}
+
+func TestOutlineDirected(t *testing.T) {
+	files := []File{
+		{Path: "big.go", Content: []byte("// gopack:outline-only\npackage big\n\nfunc F() int {\n\treturn 1\n}\n")},
+		{Path: "small.go", Content: []byte("package small\n\nfunc G() int {\n\treturn 2\n}\n")},
+	}
+	got, changed := OutlineDirected(files)
+	if changed != 1 || strings.Contains(string(got[0].Content), "return 1") || !strings.Contains(string(got[1].Content), "return 2") {
+		t.Errorf("OutlineDirected() changed %d files: %q, %q", changed, got[0].Content, got[1].Content)
+	}
+}
+
+func TestWalkIgnoreDirective(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"src/keep.go":   "package main\n",
+		"src/secret.go": "// gopack:ignore\npackage main\n",
+		"explicit.go":   "// gopack:ignore\npackage main\n",
+	})
+
+	for _, noDirectives := range []bool{false, true} {
+		walker, err := NewWalker(filepath.Join(dir, "src"), filepath.Join(dir, "explicit.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		walker.NoDirectives = noDirectives
+		var skipped []string
+		walker.OnSkip = func(path, reason string) { skipped = append(skipped, path+": "+reason) }
+		files, err := walker.Walk()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, file := range files {
+			got = append(got, filepath.ToSlash(file.Path))
+		}
+		slices.Sort(got)
+		want := []string{"explicit.go", "src/keep.go"}
+		if noDirectives {
+			want = []string{"explicit.go", "src/keep.go", "src/secret.go"}
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("Walk(NoDirectives=%v) = %q, want %q (skipped %q)", noDirectives, got, want, skipped)
+		}
+	}
+}
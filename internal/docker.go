@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// dockerCommand is the program FetchImagePath runs. Podman takes the same
+// commands.
+var dockerCommand = "docker"
+
+// ImageWorkdir returns the working directory an image's containers start
+// in, as set by WORKDIR, or "" if it's unset.
+func ImageWorkdir(ctx context.Context, image string) (string, error) {
+	out, err := runDocker(ctx, "image", "inspect", "--format", "{{.Config.WorkingDir}}", image)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// FetchImagePath copies a directory out of a container image's filesystem
+// into a directory under cacheDir named after the image and a hash of it
+// and the path, and returns that directory. The image is read through a
+// container that's created but never started, and removed again; "docker
+// cp" streams the directory as a tar archive, so symbolic links within it
+// are skipped. Any earlier copy is replaced.
+func FetchImagePath(ctx context.Context, image, dir, cacheDir string) (string, error) {
+	dir = path.Clean("/" + dir)
+	name := strings.NewReplacer("/", "-", ":", "-", "@", "-").Replace(image)
+	return fetchInto(cacheDir, name, image+":"+dir, func(target string) error {
+		// The entrypoint is never run, but an image without a command
+		// can't be made into a container otherwise
+		out, err := runDocker(ctx, "create", "--entrypoint", "true", image)
+		if err != nil {
+			return err
+		}
+		container := strings.TrimSpace(out)
+		defer runDocker(context.WithoutCancel(ctx), "rm", "--force", container)
+
+		// The archive holds the directory itself, under its own name
+		archive := target + ".tar"
+		if err := runTar(exec.CommandContext(ctx, dockerCommand, "cp", container+":"+dir, "-"), archive); err != nil {
+			return err
+		}
+		root := archive
+		if dir != "/" {
+			root = filepath.Join(archive, path.Base(dir))
+		}
+		if info, err := os.Stat(root); err != nil || !info.IsDir() {
+			return fmt.Errorf("%s is not a directory in %s", dir, image)
+		}
+		return os.Rename(root, target)
+	})
+}
+
+// runDocker runs a docker command and returns its output.
+func runDocker(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, dockerCommand, args...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s %s: %s", dockerCommand, args[0], msg)
+		}
+		return "", fmt.Errorf("%s %s: %w", dockerCommand, args[0], err)
+	}
+	return string(out), nil
+}
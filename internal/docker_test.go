@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// fakeDocker replaces dockerCommand with a script serving an "image" whose
+// filesystem is root and whose WORKDIR is workdir.
+func fakeDocker(t *testing.T, root, workdir string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	if _, err := exec.LookPath("tar"); err != nil {
+		t.Skip("tar not installed")
+	}
+	script := `#!/bin/sh
+case "$1" in
+image) echo "` + workdir + `" ;;
+create) echo c0ffee ;;
+cp) p="${2#c0ffee:}"
+    [ -e "` + root + `$p" ] || { echo "no such path: $p" >&2; exit 1; }
+    cd "` + root + `$(dirname "$p")" && exec tar -cf - "$(basename "$p")" ;;
+rm) ;;
+esac
+`
+	fake := filepath.Join(t.TempDir(), "docker")
+	if err := os.WriteFile(fake, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	old := dockerCommand
+	dockerCommand = fake
+	t.Cleanup(func() { dockerCommand = old })
+}
+
+func TestFetchImagePath(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"app/main.py":     "print('hi')\n",
+		"app/lib/util.py": "x = 1\n",
+		"etc/hosts":       "127.0.0.1 localhost\n",
+	})
+	fakeDocker(t, root, "/app")
+
+	workdir, err := ImageWorkdir(context.Background(), "myapp:latest")
+	if err != nil || workdir != "/app" {
+		t.Fatalf("ImageWorkdir() = %q, %v; want /app", workdir, err)
+	}
+
+	cacheDir := t.TempDir()
+	dir, err := FetchImagePath(context.Background(), "myapp:latest", "/app", cacheDir)
+	if err != nil {
+		t.Fatalf("FetchImagePath() error = %v", err)
+	}
+	for name, want := range map[string]string{"main.py": "print('hi')\n", "lib/util.py": "x = 1\n"} {
+		got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", name, got, err, want)
+		}
+	}
+
+	if _, err := FetchImagePath(context.Background(), "myapp:latest", "/etc/hosts", cacheDir); err == nil {
+		t.Error("FetchImagePath() of a file succeeded")
+	}
+	if _, err := FetchImagePath(context.Background(), "myapp:latest", "/missing", cacheDir); err == nil {
+		t.Error("FetchImagePath() of a missing path succeeded")
+	}
+}
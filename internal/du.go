@@ -0,0 +1,63 @@
+package internal
+
+import (
+	"cmp"
+	"path"
+	"slices"
+)
+
+// DirUsage totals the files under a directory, its subdirectories
+// included.
+type DirUsage struct {
+	Path   string `json:"path"`  // slash-separated, "." for the top of the tree
+	Depth  int    `json:"depth"` // 0 for the top of the tree
+	Files  int    `json:"files"`
+	Bytes  int    `json:"bytes"`
+	Tokens int    `json:"tokens"`
+}
+
+// DirectoryUsage totals files by directory, like du, and returns every
+// directory holding any of them in tree order: each directory comes before
+// its subdirectories, which are ordered by token count (largest first).
+func DirectoryUsage(files []File) []DirUsage {
+	usage := map[string]*DirUsage{".": {Path: "."}}
+	children := make(map[string][]string)
+	for _, file := range files {
+		tokens := FileTokens(file)
+		for _, dir := range append(parentDirs(file.Path), ".") {
+			u, ok := usage[dir]
+			if !ok {
+				u = &DirUsage{Path: dir}
+				usage[dir] = u
+				parent := path.Dir(dir)
+				if parent == "/" {
+					parent = "."
+				}
+				children[parent] = append(children[parent], dir)
+			}
+			u.Files++
+			u.Bytes += len(file.Content)
+			u.Tokens += tokens
+		}
+	}
+
+	var result []DirUsage
+	var visit func(dir string, depth int)
+	visit = func(dir string, depth int) {
+		u := usage[dir]
+		u.Depth = depth
+		result = append(result, *u)
+		subdirs := children[dir]
+		slices.SortFunc(subdirs, func(a, b string) int {
+			if c := cmp.Compare(usage[b].Tokens, usage[a].Tokens); c != 0 {
+				return c
+			}
+			return cmp.Compare(a, b)
+		})
+		for _, subdir := range subdirs {
+			visit(subdir, depth+1)
+		}
+	}
+	visit(".", 0)
+	return result
+}
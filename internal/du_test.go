@@ -0,0 +1,38 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDirectoryUsage(t *testing.T) {
+	files := []File{
+		{Path: "README.md", Content: []byte("readme\n")},
+		{Path: "cmd/main.go", Content: []byte(strings.Repeat("x", 100))},
+		{Path: "internal/a.go", Content: []byte(strings.Repeat("x", 400))},
+		{Path: "internal/sub/deep/b.go", Content: []byte(strings.Repeat("x", 40))},
+		{Path: "internal/z/c.go", Content: []byte(strings.Repeat("x", 80))},
+	}
+	want := []DirUsage{
+		{Path: ".", Depth: 0, Files: 5, Bytes: 627},
+		{Path: "internal", Depth: 1, Files: 3, Bytes: 520},
+		{Path: "internal/z", Depth: 2, Files: 1, Bytes: 80},
+		{Path: "internal/sub", Depth: 2, Files: 1, Bytes: 40},
+		{Path: "internal/sub/deep", Depth: 3, Files: 1, Bytes: 40},
+		{Path: "cmd", Depth: 1, Files: 1, Bytes: 100},
+	}
+
+	got := DirectoryUsage(files)
+	if len(got) != len(want) {
+		t.Fatalf("DirectoryUsage() = %+v, want %d directories", got, len(want))
+	}
+	for i, dir := range got {
+		w := want[i]
+		if dir.Path != w.Path || dir.Depth != w.Depth || dir.Files != w.Files || dir.Bytes != w.Bytes {
+			t.Errorf("DirectoryUsage()[%d] = %+v, want %+v", i, dir, w)
+		}
+	}
+	if total := FileTokens(files[0]) + FileTokens(files[1]) + FileTokens(files[2]) + FileTokens(files[3]) + FileTokens(files[4]); got[0].Tokens != total {
+		t.Errorf("root tokens = %d, want %d", got[0].Tokens, total)
+	}
+}
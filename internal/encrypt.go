@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// EncryptExt is the conventional extension of an age-encrypted file.
+const EncryptExt = ".age"
+
+// ParseRecipient parses an age X25519 recipient, as printed by age-keygen
+// (age1...).
+func ParseRecipient(s string) (*age.X25519Recipient, error) {
+	recipient, err := age.ParseX25519Recipient(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid age recipient: %w", err)
+	}
+	return recipient, nil
+}
+
+// Encrypt encrypts data to an age recipient. Only the holder of the
+// matching identity can read the result, with `age -d -i key.txt`.
+func Encrypt(data []byte, recipient string) ([]byte, error) {
+	r, err := ParseRecipient(recipient)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	writer, err := age.Encrypt(&buf, r)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encryptedHeader starts every age-encrypted file.
+const encryptedHeader = "age-encryption.org/v1\n"
+
+// IsEncrypted reports whether data is age-encrypted, as Encrypt leaves it.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(encryptedHeader))
+}
+
+// Decrypt reverses Encrypt with age identities (AGE-SECRET-KEY-1...), given
+// as the content of an identity file written by age-keygen: one per line,
+// with "#" comments.
+func Decrypt(data []byte, identities string) ([]byte, error) {
+	ids, err := age.ParseIdentities(strings.NewReader(identities))
+	if err != nil {
+		return nil, fmt.Errorf("invalid age identity: %w", err)
+	}
+	reader, err := age.Decrypt(bytes.NewReader(data), ids...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/age"
+)
+
+func TestEncryptRoundTrip(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("File: main.go\npackage main\n")
+
+	encrypted, err := Encrypt(data, identity.Recipient().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(encrypted, []byte("package main")) {
+		t.Error("encrypted output contains the plaintext")
+	}
+
+	if !IsEncrypted(encrypted) || IsEncrypted(data) {
+		t.Errorf("IsEncrypted() = %v for the encrypted data, %v for the plaintext", IsEncrypted(encrypted), IsEncrypted(data))
+	}
+
+	got, err := Decrypt(encrypted, identity.String())
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("Decrypt = %q, %v; want the original data", got, err)
+	}
+
+	// Identity files as age-keygen writes them decrypt too
+	file := "# created: 2024-06-01T12:00:00Z\n# public key: " + identity.Recipient().String() + "\n" + identity.String() + "\n"
+	if got, err := Decrypt(encrypted, file); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("Decrypt with an identity file = %q, %v; want the original data", got, err)
+	}
+
+	other, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decrypt(encrypted, other.String()); err == nil {
+		t.Error("Decrypt with the wrong identity succeeded")
+	}
+}
+
+func TestParseRecipientInvalid(t *testing.T) {
+	for _, s := range []string{"", "age1nope", "ssh-ed25519 AAAA"} {
+		if _, err := ParseRecipient(s); err == nil {
+			t.Errorf("ParseRecipient(%q) succeeded, want an error", s)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Export archive formats, chosen by the destination's name.
+const (
+	ArchiveTar   = "tar"
+	ArchiveTarGz = "tar.gz"
+	ArchiveZip   = "zip"
+)
+
+// ArchiveFor returns the archive format a destination's name implies, or
+// "" for a directory.
+func ArchiveFor(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return ArchiveTarGz
+	case strings.HasSuffix(lower, ".tar"):
+		return ArchiveTar
+	case strings.HasSuffix(lower, ".zip"):
+		return ArchiveZip
+	}
+	return ""
+}
+
+// ExportDir copies files into dir under their paths in the pack, creating
+// directories as needed. Files that already exist are left alone unless
+// overwrite is set. It returns the number of files written and the paths
+// of those skipped.
+func ExportDir(files []File, dir string, overwrite bool) (int, []string, error) {
+	written := 0
+	var existing []string
+	for _, file := range files {
+		if err := checkPackPath(file.Path); err != nil {
+			return written, existing, err
+		}
+		path := filepath.Join(dir, filepath.FromSlash(file.Path))
+		if _, err := os.Lstat(path); err == nil && !overwrite {
+			existing = append(existing, path)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return written, existing, fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := os.WriteFile(path, file.Content, 0644); err != nil {
+			return written, existing, fmt.Errorf("failed to write file: %w", err)
+		}
+		if file.Mode != 0 {
+			os.Chmod(path, file.Mode)
+		}
+		if !file.ModTime.IsZero() {
+			os.Chtimes(path, file.ModTime, file.ModTime)
+		}
+		written++
+	}
+	return written, existing, nil
+}
+
+// WriteArchive writes files to w as an archive in the given format, each
+// under its path in the pack with its modification time.
+func WriteArchive(w io.Writer, files []File, format string) error {
+	for _, file := range files {
+		if err := checkPackPath(file.Path); err != nil {
+			return err
+		}
+	}
+	switch format {
+	case ArchiveTar:
+		return writeTar(w, files)
+	case ArchiveTarGz:
+		gz, err := gzip.NewWriterLevel(w, gzip.BestCompression)
+		if err != nil {
+			return err
+		}
+		if err := writeTar(gz, files); err != nil {
+			return err
+		}
+		return gz.Close()
+	case ArchiveZip:
+		archive := zip.NewWriter(w)
+		for _, file := range files {
+			header := &zip.FileHeader{Name: filepath.ToSlash(file.Path), Method: zip.Deflate, Modified: file.ModTime}
+			header.SetMode(archiveMode(file))
+			entry, err := archive.CreateHeader(header)
+			if err != nil {
+				return err
+			}
+			if _, err := entry.Write(file.Content); err != nil {
+				return err
+			}
+		}
+		return archive.Close()
+	}
+	return fmt.Errorf("unknown archive format %q", format)
+}
+
+// archiveMode returns the permission bits of a file in an archive: its own,
+// if the walk recorded them, or 0644.
+func archiveMode(file File) os.FileMode {
+	if file.Mode != 0 {
+		return file.Mode
+	}
+	return 0644
+}
+
+// writeTar writes files to w as a tar archive.
+func writeTar(w io.Writer, files []File) error {
+	archive := tar.NewWriter(w)
+	for _, file := range files {
+		header := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     filepath.ToSlash(file.Path),
+			Mode:     int64(archiveMode(file)),
+			Size:     int64(len(file.Content)),
+			ModTime:  file.ModTime,
+		}
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := archive.Write(file.Content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
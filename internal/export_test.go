@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestArchiveFor(t *testing.T) {
+	tests := map[string]string{
+		"snapshot.tar":    ArchiveTar,
+		"snapshot.tar.gz": ArchiveTarGz,
+		"snapshot.TGZ":    ArchiveTarGz,
+		"snapshot.zip":    ArchiveZip,
+		"snapshot":        "",
+		"snapshot.gz":     "",
+	}
+	for name, want := range tests {
+		if got := ArchiveFor(name); got != want {
+			t.Errorf("ArchiveFor(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestExportDir(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"keep.go": "old\n"})
+	mtime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	files := []File{
+		{Path: "keep.go", Content: []byte("new\n")},
+		{Path: "pkg/a.go", Content: []byte("package pkg\n"), ModTime: mtime},
+	}
+
+	written, existing, err := ExportDir(files, dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != 1 || !reflect.DeepEqual(existing, []string{filepath.Join(dir, "keep.go")}) {
+		t.Errorf("ExportDir() = %d written, %q existing; want 1, [keep.go]", written, existing)
+	}
+	if got := readFile(t, filepath.Join(dir, "keep.go")); got != "old\n" {
+		t.Errorf("existing file = %q, want it left alone", got)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "pkg", "a.go")); err != nil || !info.ModTime().Equal(mtime) {
+		t.Errorf("pkg/a.go: %v, want it written with its modification time", err)
+	}
+
+	if written, _, err := ExportDir(files, dir, true); err != nil || written != 2 {
+		t.Errorf("ExportDir(overwrite) = %d, %v; want 2 written", written, err)
+	}
+	if _, _, err := ExportDir([]File{{Path: "../escape.go"}}, dir, true); err == nil {
+		t.Error("ExportDir wrote a path outside the directory")
+	}
+}
+
+func TestWriteArchive(t *testing.T) {
+	files := []File{
+		{Path: "main.go", Content: []byte("package main\n")},
+		{Path: "internal/lib.go", Content: []byte("package internal\n")},
+	}
+	want := map[string]string{"main.go": "package main\n", "internal/lib.go": "package internal\n"}
+
+	for _, format := range []string{ArchiveTar, ArchiveTarGz, ArchiveZip} {
+		var buf bytes.Buffer
+		if err := WriteArchive(&buf, files, format); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		got := make(map[string]string)
+		switch format {
+		case ArchiveZip:
+			archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, entry := range archive.File {
+				r, err := entry.Open()
+				if err != nil {
+					t.Fatal(err)
+				}
+				content, _ := io.ReadAll(r)
+				got[entry.Name] = string(content)
+			}
+		default:
+			var r io.Reader = &buf
+			if format == ArchiveTarGz {
+				gz, err := gzip.NewReader(r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				r = gz
+			}
+			archive := tar.NewReader(r)
+			for {
+				header, err := archive.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				content, _ := io.ReadAll(archive)
+				got[header.Name] = string(content)
+			}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s archive holds %q, want %q", format, got, want)
+		}
+	}
+}
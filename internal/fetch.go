@@ -0,0 +1,359 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxFetchSize bounds the size of a page fetched by FetchURL.
+const maxFetchSize = 10 << 20
+
+// FetchURL downloads a web page as a pseudo-file labeled with its URL. HTML
+// is converted to Markdown-style text; other text types are kept as they
+// are.
+func FetchURL(ctx context.Context, rawURL string) (File, error) {
+	file := File{Path: rawURL}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return file, err
+	}
+	req.Header.Set("Accept", "text/html, text/plain;q=0.9, */*;q=0.5")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return file, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return file, &StatusError{URL: rawURL, Status: resp.Status, Code: resp.StatusCode, RetryAfter: retryAfter(resp.Header, time.Now())}
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchSize+1))
+	if err != nil {
+		return file, err
+	}
+	if len(body) > maxFetchSize {
+		return file, fmt.Errorf("%s is larger than %s", rawURL, FormatBytes(maxFetchSize))
+	}
+
+	mediaType := "text/plain"
+	if header := resp.Header.Get("Content-Type"); header != "" {
+		if mediaType, _, err = mime.ParseMediaType(header); err != nil {
+			return file, fmt.Errorf("%s has an invalid content type %q", rawURL, header)
+		}
+	}
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		file.Content = []byte(htmlToMarkdown(string(body), resp.Request.URL))
+	case strings.HasPrefix(mediaType, "text/") || isTextMediaType(mediaType):
+		file.Content = body
+	default:
+		return file, fmt.Errorf("%s has unsupported content type %s", rawURL, mediaType)
+	}
+	return file, nil
+}
+
+// isTextMediaType reports whether a non-text/* media type holds text.
+func isTextMediaType(mediaType string) bool {
+	switch mediaType {
+	case "application/json", "application/xml", "application/yaml", "application/x-yaml", "application/javascript", "application/toml":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
+
+var (
+	htmlSpace = regexp.MustCompile(`[ \t\r\n\f]+`)
+	htmlHref  = regexp.MustCompile(`(?is)\shref\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	blankRuns = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlSkipped lists elements whose content is never text worth packing.
+var htmlSkipped = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true,
+	"svg": true, "template": true, "iframe": true, "button": true,
+}
+
+// htmlBlocks lists elements that start and end a paragraph.
+var htmlBlocks = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "main": true,
+	"header": true, "footer": true, "nav": true, "aside": true, "table": true,
+	"ul": true, "ol": true, "dl": true, "dt": true, "dd": true, "blockquote": true,
+	"figure": true, "figcaption": true, "form": true, "hr": true, "details": true,
+	"summary": true,
+}
+
+// markdownWriter accumulates text converted from HTML, managing the
+// whitespace between inline text and blocks. Spaces and line breaks are
+// held back until more text arrives, so they never pile up.
+type markdownWriter struct {
+	out      strings.Builder
+	trailing int  // line breaks at the end of out
+	breaks   int  // line breaks wanted before the next text
+	space    bool // a space is wanted before the next text
+	glued    bool // the next text follows an opening marker such as "["
+}
+
+// text writes inline text, collapsing whitespace as a browser would.
+func (m *markdownWriter) text(s string) {
+	s = htmlSpace.ReplaceAllString(s, " ")
+	word := strings.TrimSpace(s)
+	if word == "" {
+		m.space = m.space || (s != "" && !m.glued)
+		return
+	}
+	m.space = m.space || (s[0] == ' ' && !m.glued)
+	m.raw(word)
+	m.space = s[len(s)-1] == ' '
+}
+
+// raw writes s as it is, after any pending whitespace.
+func (m *markdownWriter) raw(s string) {
+	if s == "" {
+		return
+	}
+	if m.out.Len() > 0 {
+		if m.breaks > m.trailing {
+			m.out.WriteString(strings.Repeat("\n", m.breaks-m.trailing))
+		} else if m.space && m.trailing == 0 {
+			m.out.WriteByte(' ')
+		}
+	}
+	m.breaks, m.space, m.glued = 0, false, false
+	m.out.WriteString(s)
+	if rest := strings.TrimRight(s, "\n"); rest == "" {
+		m.trailing += len(s)
+	} else {
+		m.trailing = len(s) - len(rest)
+	}
+}
+
+// open writes a marker that opens inline formatting, so that text right
+// after it doesn't start with a space.
+func (m *markdownWriter) open(marker string) {
+	m.raw(marker)
+	m.glued = true
+}
+
+// close writes a marker that closes inline formatting, moving any space
+// before it to after it.
+func (m *markdownWriter) close(marker string) {
+	space := m.space
+	m.space = false
+	m.raw(marker)
+	m.space = space
+}
+
+// inline writes an opening or closing formatting marker.
+func (m *markdownWriter) inline(marker string, closing bool) {
+	if closing {
+		m.close(marker)
+	} else {
+		m.open(marker)
+	}
+}
+
+// lines makes sure there are n line breaks before whatever comes next.
+func (m *markdownWriter) lines(n int) {
+	m.breaks = max(m.breaks, n)
+}
+
+// htmlToMarkdown converts an HTML page to readable Markdown-style text:
+// headings, paragraphs, lists, links, emphasis, and code are kept, and
+// scripts, styles, and markup are dropped. Relative links are resolved
+// against base.
+func htmlToMarkdown(src string, base *url.URL) string {
+	var m markdownWriter
+	var skip []string // open elements whose content is dropped
+	var hrefs []string
+	var lists, cells int
+	var pre, preStart bool
+
+	for len(src) > 0 {
+		lt := strings.IndexByte(src, '<')
+		if lt < 0 {
+			lt = len(src)
+		}
+		if lt > 0 {
+			if len(skip) == 0 {
+				text := html.UnescapeString(src[:lt])
+				if pre {
+					// As in browsers, a line break right after <pre> is dropped
+					if preStart {
+						text = strings.TrimPrefix(strings.TrimPrefix(text, "\r"), "\n")
+					}
+					m.raw(text)
+					preStart = false
+				} else {
+					m.text(text)
+				}
+			}
+			src = src[lt:]
+			continue
+		}
+
+		// Comments and declarations
+		if strings.HasPrefix(src, "<!--") {
+			end := strings.Index(src, "-->")
+			if end < 0 {
+				break
+			}
+			src = src[end+3:]
+			continue
+		}
+		gt := strings.IndexByte(src, '>')
+		if len(src) < 2 || !isTagStart(src[1]) || gt < 0 {
+			// A lone "<" is text, as in "a < b"
+			if len(skip) == 0 {
+				m.raw("<")
+			}
+			src = src[1:]
+			continue
+		}
+		tag := src[1:gt]
+		src = src[gt+1:]
+		if tag[0] == '!' || tag[0] == '?' {
+			continue
+		}
+
+		closing := tag[0] == '/'
+		tag = strings.TrimPrefix(tag, "/")
+		name := tag
+		if i := strings.IndexAny(tag, " \t\r\n/"); i >= 0 {
+			name = tag[:i]
+		}
+		name = strings.ToLower(name)
+		selfClosing := strings.HasSuffix(tag, "/")
+
+		// Drop the content of scripts, styles, and the like
+		if htmlSkipped[name] {
+			if closing {
+				if len(skip) > 0 && skip[len(skip)-1] == name {
+					skip = skip[:len(skip)-1]
+				}
+			} else if !selfClosing {
+				skip = append(skip, name)
+			}
+			continue
+		}
+		if len(skip) > 0 {
+			continue
+		}
+
+		// Inside <pre>, only the end of the block matters
+		if pre && name != "pre" {
+			continue
+		}
+
+		switch {
+		case len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6':
+			m.lines(2)
+			if !closing {
+				m.raw(strings.Repeat("#", int(name[1]-'0')) + " ")
+			}
+		case name == "pre":
+			if closing {
+				m.lines(1)
+				m.raw("```")
+				m.lines(2)
+			} else {
+				m.lines(2)
+				m.raw("```\n")
+			}
+			pre, preStart = !closing, !closing
+		case name == "br":
+			m.lines(1)
+		case name == "li":
+			if !closing {
+				m.lines(1)
+				m.raw(strings.Repeat("  ", max(lists-1, 0)) + "- ")
+			}
+		case name == "ul" || name == "ol":
+			if closing {
+				lists = max(lists-1, 0)
+			} else {
+				lists++
+			}
+			if lists == 0 || (!closing && lists == 1) {
+				m.lines(2)
+			} else {
+				m.lines(1)
+			}
+		case name == "tr":
+			m.lines(1)
+			cells = 0
+		case name == "td" || name == "th":
+			if !closing {
+				if cells > 0 {
+					m.space = false
+					m.raw(" |")
+					m.space = true
+				}
+				cells++
+			}
+		case name == "a":
+			if closing {
+				if len(hrefs) > 0 {
+					if href := hrefs[len(hrefs)-1]; href != "" {
+						m.close("](" + href + ")")
+					}
+					hrefs = hrefs[:len(hrefs)-1]
+				}
+			} else if !selfClosing {
+				href := linkTarget(tag, base)
+				if href != "" {
+					m.open("[")
+				}
+				hrefs = append(hrefs, href)
+			}
+		case name == "code" || name == "kbd" || name == "samp":
+			m.inline("`", closing)
+		case name == "strong" || name == "b":
+			m.inline("**", closing)
+		case name == "em" || name == "i":
+			m.inline("_", closing)
+		case htmlBlocks[name]:
+			m.lines(2)
+		}
+	}
+
+	out := blankRuns.ReplaceAllString(m.out.String(), "\n\n")
+	var lines []string
+	for _, line := range strings.Split(out, "\n") {
+		lines = append(lines, strings.TrimRight(line, " \t"))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")) + "\n"
+}
+
+// isTagStart reports whether c, following a "<", starts a tag.
+func isTagStart(c byte) bool {
+	return c == '/' || c == '!' || c == '?' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// linkTarget returns the absolute URL an <a> tag links to, or "" for
+// anchors and script links that mean nothing outside the page.
+func linkTarget(tag string, base *url.URL) string {
+	match := htmlHref.FindStringSubmatch(tag)
+	if match == nil {
+		return ""
+	}
+	href := strings.TrimSpace(html.UnescapeString(match[1] + match[2] + match[3]))
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return ""
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		ref = base.ResolveReference(ref)
+	}
+	return ref.String()
+}
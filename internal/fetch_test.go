@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestHTMLToMarkdown(t *testing.T) {
+	base, _ := url.Parse("https://example.com/docs/page")
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			"headings and paragraphs",
+			"<html><head><title>T</title><style>p{}</style></head><body><h1>Guide</h1>\n<p>First\n   paragraph.</p><p>Second.</p></body></html>",
+			"# Guide\n\nFirst paragraph.\n\nSecond.\n",
+		},
+		{
+			"links",
+			`<p>See <a href="/api">the <b>API</b></a> and <a href="#top">top</a>.</p>`,
+			"See [the **API**](https://example.com/api) and top.\n",
+		},
+		{
+			"lists",
+			"<ul><li>one</li><li>two<ul><li>nested</li></ul></li></ul><p>after</p>",
+			"- one\n- two\n  - nested\n\nafter\n",
+		},
+		{
+			"code",
+			"<p>Run <code>go test</code>:</p><pre>\nfunc main() {\n\tx := a &lt; b\n}\n</pre><p>done</p>",
+			"Run `go test`:\n\n```\nfunc main() {\n\tx := a < b\n}\n```\n\ndone\n",
+		},
+		{
+			"scripts and comments",
+			"<p>a<!-- hidden --><script>if (a < b) alert(1)</script> b &amp; c</p>",
+			"a b & c\n",
+		},
+		{
+			"tables",
+			"<table><tr><th>Flag</th><th>Meaning</th></tr><tr><td>-v</td><td>verbose</td></tr></table>",
+			"Flag | Meaning\n-v | verbose\n",
+		},
+		{
+			"stray brackets",
+			"<p>if a < b then <em> c </em> > d</p>",
+			"if a < b then _c_ > d\n",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := htmlToMarkdown(tt.html, base); got != tt.want {
+			t.Errorf("%s: htmlToMarkdown() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFetchURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(`<h2>Title</h2><p>Body <a href="other">link</a></p>`))
+		case "/notes.txt":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("plain <b>text</b>\n"))
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte{0x89, 'P', 'N', 'G'})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	file, err := FetchURL(ctx, server.URL+"/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "## Title\n\nBody [link](" + server.URL + "/other)\n"
+	if file.Path != server.URL+"/page" || string(file.Content) != want {
+		t.Errorf("FetchURL(page) = %s %q, want %q", file.Path, file.Content, want)
+	}
+
+	if file, err = FetchURL(ctx, server.URL+"/notes.txt"); err != nil || string(file.Content) != "plain <b>text</b>\n" {
+		t.Errorf("FetchURL(notes.txt) = %q, %v; want the text unchanged", file.Content, err)
+	}
+
+	for _, path := range []string{"/image.png", "/missing"} {
+		if _, err := FetchURL(ctx, server.URL+path); err == nil {
+			t.Errorf("FetchURL(%s) succeeded, want error", path)
+		} else if !strings.Contains(err.Error(), server.URL+path) {
+			t.Errorf("FetchURL(%s) error %q doesn't name the URL", path, err)
+		}
+	}
+}
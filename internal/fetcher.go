@@ -0,0 +1,195 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// StatusError is the error FetchURL returns when a server answers with a
+// status other than 200 OK.
+type StatusError struct {
+	URL    string
+	Status string
+	Code   int
+
+	// RetryAfter is how long the server asked clients to wait, from a
+	// Retry-After or X-RateLimit-Reset header, or -1 if it didn't say.
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned %s", e.URL, e.Status)
+}
+
+// rateLimited reports whether the error means the server is throttling
+// requests: a 429 or 503, or a 403 that says when to retry (as GitHub's
+// rate limit does).
+func (e *StatusError) rateLimited() bool {
+	switch e.Code {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusForbidden:
+		return e.RetryAfter >= 0
+	}
+	return false
+}
+
+// retryAfter reads how long a response asks clients to wait before trying
+// again, or -1 if it doesn't say.
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		if at, err := http.ParseTime(value); err == nil {
+			return max(at.Sub(now), 0)
+		}
+	}
+	if header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return max(time.Unix(reset, 0).Sub(now), 0)
+		}
+	}
+	return -1
+}
+
+// Fetcher downloads several URLs with FetchURL at once, without tripping
+// servers' rate limits: it runs at most Concurrency requests at a time,
+// starts no more than RateLimit per second, and retries throttled requests
+// with exponential backoff, or after the delay the server asks for.
+type Fetcher struct {
+	Concurrency int           // requests at once; 0 means 1
+	RateLimit   float64       // requests started per second; 0 means unlimited
+	Retries     int           // retries of each throttled request
+	Timeout     time.Duration // for each attempt; 0 means none
+	MaxWait     time.Duration // longest delay to wait for; 0 means 2 minutes
+
+	// OnRetry, if set, is called before waiting to retry a URL.
+	OnRetry func(url string, wait time.Duration)
+
+	// Get, if set, downloads a URL in place of FetchURL, for APIs that
+	// need their own headers. Throttling is recognized by the StatusError
+	// it returns, as with FetchURL.
+	Get func(ctx context.Context, url string) (File, error)
+
+	backoff time.Duration // first retry delay; 0 means 1 second
+
+	mu   sync.Mutex
+	next time.Time // earliest start of the next request under RateLimit
+}
+
+// FetchAll fetches urls, returning the files in the same order. It stops
+// at the first URL that fails for good.
+func (f *Fetcher) FetchAll(ctx context.Context, urls []string) ([]File, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	files := make([]File, len(urls))
+	errs := make([]error, len(urls))
+	limit := make(chan struct{}, max(f.Concurrency, 1))
+	var wg sync.WaitGroup
+	for i, rawURL := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			if files[i], errs[i] = f.fetch(ctx, rawURL); errs[i] != nil {
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Report the failure that caused the others to be canceled
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// fetch downloads one URL, retrying while the server throttles it.
+func (f *Fetcher) fetch(ctx context.Context, rawURL string) (File, error) {
+	backoff := f.backoff
+	if backoff == 0 {
+		backoff = time.Second
+	}
+	maxWait := f.MaxWait
+	if maxWait == 0 {
+		maxWait = 2 * time.Minute
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := f.wait(ctx); err != nil {
+			return File{Path: rawURL}, err
+		}
+		attemptCtx, cancel := ctx, func() {}
+		if f.Timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, f.Timeout)
+		}
+		get := FetchURL
+		if f.Get != nil {
+			get = f.Get
+		}
+		file, err := get(attemptCtx, rawURL)
+		cancel()
+
+		var status *StatusError
+		if err == nil || !errors.As(err, &status) || !status.rateLimited() || attempt >= f.Retries {
+			return file, err
+		}
+		delay := status.RetryAfter
+		if delay < 0 {
+			delay = backoff << attempt
+		}
+		if delay > maxWait {
+			return file, fmt.Errorf("%w (rate limited; retry in %s)", err, delay.Round(time.Second))
+		}
+		if f.OnRetry != nil {
+			f.OnRetry(rawURL, delay)
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return file, err
+		}
+	}
+}
+
+// wait blocks until RateLimit allows another request to start.
+func (f *Fetcher) wait(ctx context.Context) error {
+	if f.RateLimit <= 0 {
+		return ctx.Err()
+	}
+	f.mu.Lock()
+	now := time.Now()
+	start := now
+	if f.next.After(now) {
+		start = f.next
+	}
+	f.next = start.Add(time.Duration(float64(time.Second) / f.RateLimit))
+	f.mu.Unlock()
+	return sleep(ctx, start.Sub(now))
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
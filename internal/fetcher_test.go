@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header http.Header
+		want   time.Duration
+	}{
+		{http.Header{"Retry-After": {"7"}}, 7 * time.Second},
+		{http.Header{"Retry-After": {now.Add(time.Minute).Format(http.TimeFormat)}}, time.Minute},
+		{http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {fmt.Sprint(now.Add(30 * time.Second).Unix())}}, 30 * time.Second},
+		{http.Header{"X-Ratelimit-Remaining": {"12"}, "X-Ratelimit-Reset": {fmt.Sprint(now.Unix())}}, -1},
+		{http.Header{}, -1},
+	}
+	for _, tt := range tests {
+		if got := retryAfter(tt.header, now); got != tt.want {
+			t.Errorf("retryAfter(%v) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestFetcher(t *testing.T) {
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	var running, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		attempts[r.URL.Path]++
+		attempt := attempts[r.URL.Path]
+		mu.Unlock()
+		switch {
+		case r.URL.Path == "/throttled" && attempt < 3:
+			w.WriteHeader(http.StatusTooManyRequests)
+		case r.URL.Path == "/github" && attempt == 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusForbidden)
+		case r.URL.Path == "/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			fmt.Fprintf(w, "page %s\n", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	var urls []string
+	for _, path := range []string{"/a", "/throttled", "/b", "/github", "/c", "/d"} {
+		urls = append(urls, server.URL+path)
+	}
+	var retries atomic.Int32
+	fetcher := &Fetcher{Concurrency: 2, Retries: 3, backoff: time.Millisecond, OnRetry: func(string, time.Duration) { retries.Add(1) }}
+	files, err := fetcher.FetchAll(context.Background(), urls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, file := range files {
+		if file.Path != urls[i] || string(file.Content) != "page "+strings.TrimPrefix(urls[i], server.URL)+"\n" {
+			t.Errorf("file %d = %s %q", i, file.Path, file.Content)
+		}
+	}
+	if p := peak.Load(); p > 2 {
+		t.Errorf("%d requests ran at once, want at most 2", p)
+	}
+	if n := retries.Load(); n != 3 {
+		t.Errorf("retried %d times, want 3", n)
+	}
+
+	// A plain 403 isn't retried, and gives up the whole fetch
+	fetcher = &Fetcher{Concurrency: 4, Retries: 3, backoff: time.Millisecond}
+	if _, err := fetcher.FetchAll(context.Background(), []string{server.URL + "/a", server.URL + "/forbidden"}); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("FetchAll() with a forbidden URL error = %v, want 403", err)
+	}
+	if attempts["/forbidden"] != 1 {
+		t.Errorf("forbidden URL fetched %d times, want 1", attempts["/forbidden"])
+	}
+
+	// The rate limit spaces out the starts of requests
+	fetcher = &Fetcher{Concurrency: 4, RateLimit: 50}
+	start := time.Now()
+	if _, err := fetcher.FetchAll(context.Background(), urls[:4]); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("4 requests at 50/s took %v, want at least 60ms", elapsed)
+	}
+}
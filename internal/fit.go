@@ -0,0 +1,193 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Strategies Fit escalates through, in order, from the one losing the
+// least to the one losing the most.
+const (
+	FitDropIgnored   = "drop-default-ignored"
+	FitStripComments = "strip-comments"
+	FitOutline       = "outline"
+	FitTruncate      = "truncate"
+	FitDropFiles     = "drop-files"
+)
+
+// FitStrategies lists the strategies in the order Fit applies them.
+var FitStrategies = []string{FitDropIgnored, FitStripComments, FitOutline, FitTruncate, FitDropFiles}
+
+const (
+	// fitLargeFile is the size, in estimated tokens, from which the outline
+	// strategy applies to a file.
+	fitLargeFile = 1000
+
+	// fitMinTruncate is the fewest tokens the truncate strategy cuts a
+	// file to, however many files share the budget.
+	fitMinTruncate = 200
+)
+
+// FitStep is a strategy Fit applied.
+type FitStep struct {
+	Strategy string
+	Files    int // files changed or dropped
+	Tokens   int // the pack's tokens afterwards
+}
+
+// Fit shrinks files until measure, which returns the tokens of the pack the
+// files would make, reports no more than maxTokens. It escalates through
+// FitStrategies, stopping as soon as the pack fits: files matching
+// DefaultIgnorePatterns (packed with --no-default-ignores or named
+// explicitly) are dropped; lines holding only comments are stripped; files
+// of a thousand tokens or more are outlined; every file is truncated to its
+// share of the budget; and finally files are dropped from the end, where
+// the least important ones are. Files matching the priority patterns are
+// spared every strategy but the first two, and are the last to be dropped.
+// It returns the files and the strategies that changed anything. The pack
+// may still not fit if even one file is too big.
+func Fit(files []File, maxTokens int, priority []string, measure func([]File) (int, error)) ([]File, []FitStep, error) {
+	tokens, err := measure(files)
+	if err != nil || tokens <= maxTokens {
+		return files, nil, err
+	}
+
+	var steps []FitStep
+	shrink := func(strategy string, fn func(File) ([]byte, bool), all bool) error {
+		result := make([]File, len(files))
+		changed := 0
+		for i, file := range files {
+			result[i] = file
+			if file.DuplicateOf != "" || file.Omitted || !all && IsPriority(file.Path, priority) {
+				continue
+			}
+			if content, ok := fn(file); ok {
+				result[i].Content = content
+				changed++
+			}
+		}
+		if changed == 0 {
+			return nil
+		}
+		files = result
+		if tokens, err = measure(files); err != nil {
+			return err
+		}
+		steps = append(steps, FitStep{Strategy: strategy, Files: changed, Tokens: tokens})
+		return nil
+	}
+
+	// Drop what gopack would have ignored
+	defaults := make([]ignorePattern, len(DefaultIgnorePatterns))
+	for i, pattern := range DefaultIgnorePatterns {
+		defaults[i] = compilePattern(pattern)
+	}
+	var kept []File
+	for _, file := range files {
+		parts := strings.Split(filepath.ToSlash(file.Path), "/")
+		if !slices.ContainsFunc(defaults, func(p ignorePattern) bool { return p.match(parts, false, false) }) {
+			kept = append(kept, file)
+		}
+	}
+	if dropped := len(files) - len(kept); dropped > 0 && len(kept) > 0 {
+		files = kept
+		if tokens, err = measure(files); err != nil {
+			return nil, nil, err
+		}
+		steps = append(steps, FitStep{Strategy: FitDropIgnored, Files: dropped, Tokens: tokens})
+	}
+
+	if tokens > maxTokens {
+		err = shrink(FitStripComments, func(file File) ([]byte, bool) {
+			return applyTransform(transformStep{name: "strip-comments"}, file.Path, file.Content)
+		}, true)
+	}
+	if err == nil && tokens > maxTokens {
+		err = shrink(FitOutline, func(file File) ([]byte, bool) {
+			if FileTokens(file) < fitLargeFile {
+				return nil, false
+			}
+			return outlineFile(file.Path, file.Content)
+		}, false)
+	}
+	if err == nil && tokens > maxTokens {
+		limit := truncateLimit(files, tokens, maxTokens, priority)
+		err = shrink(FitTruncate, func(file File) ([]byte, bool) {
+			return truncateContent(file.Content, limit*4)
+		}, false)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Drop files from the end, the others before the priority ones, until
+	// the estimate fits, then measure again in case it was short
+	dropped := 0
+	for tokens > maxTokens && len(files) > 1 {
+		excess := tokens - maxTokens
+		for excess > 0 && len(files) > 1 {
+			i := len(files) - 1
+			for i > 0 && IsPriority(files[i].Path, priority) {
+				i--
+			}
+			excess -= FileTokens(files[i])
+			files = append(files[:i:i], files[i+1:]...)
+			dropped++
+		}
+		if tokens, err = measure(files); err != nil {
+			return nil, nil, err
+		}
+	}
+	if dropped > 0 {
+		steps = append(steps, FitStep{Strategy: FitDropFiles, Files: dropped, Tokens: tokens})
+	}
+	return files, steps, nil
+}
+
+// truncateLimit returns the size, in tokens, to truncate the files to so
+// that the pack, now of the given tokens, fits maxTokens: the largest that
+// cuts only as much as needed from the biggest files, but no less than
+// fitMinTruncate.
+func truncateLimit(files []File, tokens, maxTokens int, priority []string) int {
+	var sizes []int
+	available := maxTokens - tokens
+	for _, file := range files {
+		if file.DuplicateOf == "" && !file.Omitted && !IsPriority(file.Path, priority) {
+			size := len(file.Content) / 4
+			sizes = append(sizes, size)
+			available += size
+		}
+	}
+	slices.Sort(sizes)
+
+	// Files smaller than an even share of what's left keep all of it
+	for i, size := range sizes {
+		n := len(sizes) - i
+		share := (available - n*truncateNoteTokens) / n
+		if size > share {
+			return max(share, fitMinTruncate)
+		}
+		available -= size
+	}
+	return fitMinTruncate
+}
+
+// truncateNoteTokens is about the size of the note truncateContent
+// leaves in place of what it cuts.
+const truncateNoteTokens = 16
+
+// truncateContent cuts content at the end of the last whole line within
+// limit bytes, and notes how many lines were cut. It reports whether
+// anything was.
+func truncateContent(content []byte, limit int) ([]byte, bool) {
+	if len(content) <= limit {
+		return content, false
+	}
+	cut := bytes.LastIndexByte(content[:limit], '\n') + 1
+	rest := countLines(content[cut:])
+	result := append(content[:cut:cut], fmt.Sprintf("[... %d more lines truncated to fit the token budget ...]\n", rest)...)
+	return result, true
+}
@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFit(t *testing.T) {
+	var body strings.Builder
+	for i := range 400 {
+		fmt.Fprintf(&body, "\tx%d := %d\n", i, i)
+	}
+	big := "package big\n\n// Big does a lot.\nfunc Big() {\n" + body.String() + "}\n"
+	files := []File{
+		{Path: "main.go", Content: []byte("package main\n\n// main runs.\nfunc main() {}\n")},
+		{Path: "big.go", Content: []byte(big)},
+		{Path: "notes.txt", Content: []byte(strings.Repeat("note\n", 400))},
+		{Path: "go.sum", Content: []byte(strings.Repeat("sum\n", 100))},
+	}
+	measure := func(files []File) (int, error) {
+		tokens := 0
+		for _, file := range files {
+			tokens += FileTokens(file)
+		}
+		return tokens, nil
+	}
+	total, _ := measure(files)
+
+	tests := []struct {
+		name       string
+		maxTokens  int
+		priority   []string
+		strategies []string
+		paths      []string
+	}{
+		{"fits", total, nil, nil, []string{"main.go", "big.go", "notes.txt", "go.sum"}},
+		{"drop ignored", total - 50, nil, []string{FitDropIgnored}, []string{"main.go", "big.go", "notes.txt"}},
+		{"outline", 1000, nil, []string{FitDropIgnored, FitStripComments, FitOutline}, []string{"main.go", "big.go", "notes.txt"}},
+		{"truncate", 400, nil, []string{FitDropIgnored, FitStripComments, FitOutline, FitTruncate}, []string{"main.go", "big.go", "notes.txt"}},
+		{"drop files", 100, nil, FitStrategies, []string{"main.go", "big.go"}},
+		{"priority kept", 100, []string{"notes.txt"}, []string{FitDropIgnored, FitStripComments, FitOutline, FitDropFiles}, []string{"notes.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, steps, err := Fit(files, tt.maxTokens, tt.priority, measure)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var strategies, paths []string
+			for _, step := range steps {
+				strategies = append(strategies, step.Strategy)
+			}
+			for _, file := range got {
+				paths = append(paths, file.Path)
+			}
+			if !slices.Equal(strategies, tt.strategies) {
+				t.Errorf("Fit() strategies = %v, want %v", strategies, tt.strategies)
+			}
+			if !slices.Equal(paths, tt.paths) {
+				t.Errorf("Fit() files = %v, want %v", paths, tt.paths)
+			}
+			if tokens, _ := measure(got); len(got) > 1 && tokens > tt.maxTokens {
+				t.Errorf("Fit() left %d tokens, want at most %d", tokens, tt.maxTokens)
+			}
+		})
+	}
+
+	// The files passed in are left alone
+	if string(files[1].Content) != big {
+		t.Error("Fit() modified its input")
+	}
+}
+
+func TestTruncateContent(t *testing.T) {
+	content := []byte("one\ntwo\nthree\nfour\n")
+	if got, ok := truncateContent(content, 100); ok || string(got) != string(content) {
+		t.Errorf("truncateContent(short) = %q, %v; want it unchanged", got, ok)
+	}
+	got, ok := truncateContent(content, 10)
+	if want := "one\ntwo\n[... 2 more lines truncated to fit the token budget ...]\n"; !ok || string(got) != want {
+		t.Errorf("truncateContent() = %q, %v; want %q", got, ok, want)
+	}
+	if string(content) != "one\ntwo\nthree\nfour\n" {
+		t.Error("truncateContent() modified its input")
+	}
+}
@@ -0,0 +1,282 @@
+package internal
+
+import (
+	"fmt"
+	"go/ast"
+	"maps"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// jsExtensions are tried in turn for a relative import, which may leave
+// out the extension or, in TypeScript, write ".js" for a .ts file.
+var jsExtensions = []string{".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs"}
+
+// Focus is what FindFocus found around a file.
+type Focus struct {
+	Files        []File // the target first, then the files it depends on, then those depending on it
+	Dependencies int
+	Dependents   int
+}
+
+// FindFocus finds the neighbourhood of a file of a pack walked from root:
+// the files it depends on and those that depend on it, up to depth steps
+// away in either direction, for a prompt about one file that needs the
+// code around it but not the whole repository. target is the file's path
+// in the pack.
+//
+// Go files depend on the files declaring the package-level names they
+// use, unqualified in their own package or qualified by an imported
+// package of the tree. As in FindUses, references are found from the
+// syntax rather than by type-checking, so a local variable named like a
+// package-level declaration counts as one, while method calls don't.
+// JavaScript, TypeScript, and Python files depend on the modules of the
+// pack they import.
+func FindFocus(files []File, root, target string, depth int) (Focus, error) {
+	target = path.Clean(filepath.ToSlash(target))
+	start := slices.IndexFunc(files, func(file File) bool {
+		return filepath.ToSlash(file.Path) == target && file.DuplicateOf == ""
+	})
+	if start < 0 {
+		return Focus{}, fmt.Errorf("%s is not among the files walked", target)
+	}
+
+	dependencies := referenceGraph(files, root)
+	dependents := make(map[int][]int)
+	for _, from := range slices.Sorted(maps.Keys(dependencies)) {
+		for _, to := range dependencies[from] {
+			dependents[to] = append(dependents[to], from)
+		}
+	}
+
+	included := map[int]bool{start: true}
+	order := []int{start}
+	// reach adds the files up to depth steps from the target, nearest
+	// first, and returns how many were new
+	reach := func(graph map[int][]int) int {
+		added := 0
+		visited := map[int]bool{start: true}
+		frontier := []int{start}
+		for step := 0; step < depth && len(frontier) > 0; step++ {
+			var next []int
+			for _, i := range frontier {
+				for _, j := range graph[i] {
+					if !visited[j] {
+						visited[j] = true
+						next = append(next, j)
+					}
+				}
+			}
+			slices.Sort(next)
+			for _, i := range next {
+				if !included[i] {
+					included[i] = true
+					order = append(order, i)
+					added++
+				}
+			}
+			frontier = next
+		}
+		return added
+	}
+
+	focus := Focus{Dependencies: reach(dependencies), Dependents: reach(dependents)}
+	for _, i := range order {
+		focus.Files = append(focus.Files, files[i])
+	}
+	return focus, nil
+}
+
+// referenceGraph maps the index of each file of a pack to the indexes of
+// the files it depends on, in order.
+func referenceGraph(files []File, root string) map[int][]int {
+	edges := make(map[int]map[int]bool)
+	link := func(from, to int) {
+		if from == to {
+			return
+		}
+		if edges[from] == nil {
+			edges[from] = make(map[int]bool)
+		}
+		edges[from][to] = true
+	}
+	goReferences(files, root, link)
+	importReferences(files, link)
+
+	graph := make(map[int][]int, len(edges))
+	for from, to := range edges {
+		graph[from] = slices.Sorted(maps.Keys(to))
+	}
+	return graph
+}
+
+// goReferences links each Go file to the files declaring the package-level
+// names it refers to.
+func goReferences(files []File, root string, link func(from, to int)) {
+	sources := parseGoSources(files)
+
+	// Packages are told apart by directory, and external test packages
+	// from the package they test by name
+	pkgKey := func(src goSource) string {
+		if strings.HasSuffix(src.file.Name.Name, "_test") {
+			return src.dir + "_test"
+		}
+		return src.dir
+	}
+	declared := make(map[string][]int) // package key and name to the files declaring it
+	pkgNames := make(map[string]string)
+	isTest := make(map[int]bool)
+	for _, src := range sources {
+		isTest[src.index] = strings.HasSuffix(src.path, "_test.go")
+		key := pkgKey(src)
+		if key == src.dir {
+			pkgNames[src.dir] = src.file.Name.Name
+		}
+		for _, name := range declaredNames(src.file) {
+			declared[key+"\x00"+name] = append(declared[key+"\x00"+name], src.index)
+		}
+	}
+
+	prefix := goImportPrefix(root)
+	dirs := slices.Sorted(maps.Keys(pkgNames))
+	inTree := func(importPath string) (string, bool) {
+		for _, dir := range dirs {
+			if prefix != "" && importPath == path.Join(prefix, dir) ||
+				prefix == "" && (importPath == dir || strings.HasSuffix(importPath, "/"+dir)) {
+				return dir, true
+			}
+		}
+		return "", false
+	}
+
+	for _, src := range sources {
+		imported := make(map[string]string) // name the package is imported under, to its directory
+		for _, spec := range src.file.Imports {
+			p, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			dir, ok := inTree(p)
+			if !ok {
+				continue
+			}
+			if spec.Name == nil {
+				imported[pkgNames[dir]] = dir
+			} else if spec.Name.Name != "_" && spec.Name.Name != "." {
+				imported[spec.Name.Name] = dir
+			}
+		}
+
+		// Only tests see what tests declare
+		refer := func(declaring []int) {
+			for _, i := range declaring {
+				if isTest[src.index] || !isTest[i] {
+					link(src.index, i)
+				}
+			}
+		}
+		key := pkgKey(src)
+		selected := make(map[*ast.Ident]bool) // fields and methods, which aren't package-level names
+		ast.Inspect(src.file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.SelectorExpr:
+				selected[n.Sel] = true
+				if x, ok := n.X.(*ast.Ident); ok {
+					if dir, ok := imported[x.Name]; ok {
+						refer(declared[dir+"\x00"+n.Sel.Name])
+						return false
+					}
+				}
+			case *ast.Ident:
+				if !selected[n] {
+					refer(declared[key+"\x00"+n.Name])
+				}
+			}
+			return true
+		})
+	}
+}
+
+// declaredNames returns the package-level names a Go file declares, other
+// than methods and the init and main functions.
+func declaredNames(file *ast.File) []string {
+	var names []string
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil && decl.Name.Name != "init" && decl.Name.Name != "main" {
+				names = append(names, decl.Name.Name)
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					names = append(names, spec.Name.Name)
+				case *ast.ValueSpec:
+					for _, id := range spec.Names {
+						if id.Name != "_" {
+							names = append(names, id.Name)
+						}
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+// importReferences links each JavaScript, TypeScript, and Python file to
+// the files of the pack it imports.
+func importReferences(files []File, link func(from, to int)) {
+	byPath := make(map[string]int)
+	for i, file := range files {
+		if file.DuplicateOf == "" {
+			byPath[filepath.ToSlash(file.Path)] = i
+		}
+	}
+
+	for i, file := range files {
+		name := filepath.ToSlash(file.Path)
+		if file.DuplicateOf != "" {
+			continue
+		}
+		switch {
+		case path.Ext(name) == ".py":
+			for _, m := range pyImport.FindAllStringSubmatch(string(file.Content), -1) {
+				for _, module := range pyModules(path.Dir(name), m) {
+					if j, ok := resolveImport(byPath, module+".py", module+"/__init__.py"); ok {
+						link(i, j)
+					}
+				}
+			}
+		case extFamily(path.Ext(name)) == ".js":
+			for _, m := range jsRelativeImport.FindAllStringSubmatch(string(file.Content), -1) {
+				target := path.Join(path.Dir(name), m[1])
+				stem := target
+				if targetStem, ext := splitExt(target); extFamily(ext) == ".js" {
+					stem = targetStem
+				}
+				candidates := []string{target}
+				for _, ext := range jsExtensions {
+					candidates = append(candidates, stem+ext, stem+"/index"+ext)
+				}
+				if j, ok := resolveImport(byPath, candidates...); ok {
+					link(i, j)
+				}
+			}
+		}
+	}
+}
+
+// resolveImport returns the index of the first candidate path in the pack.
+func resolveImport(byPath map[string]int, candidates ...string) (int, bool) {
+	for _, candidate := range candidates {
+		if i, ok := byPath[candidate]; ok {
+			return i, true
+		}
+	}
+	return 0, false
+}
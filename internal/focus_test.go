@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindFocus(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files := []File{
+		{Path: "cmd/main.go", Content: []byte("package main\n\nimport \"example.com/app/store\"\n\nfunc main() {\n\ts := store.New()\n\ts.Get(\"a\")\n}\n")},
+		{Path: "store/store.go", Content: []byte("package store\n\n// Store keeps values.\ntype Store struct{ c *cache }\n\nfunc New() *Store { return &Store{c: newCache()} }\n\nfunc (s *Store) Get(key string) string { return key }\n")},
+		{Path: "store/cache.go", Content: []byte("package store\n\nimport \"example.com/app/util\"\n\ntype cache struct{}\n\nfunc newCache() *cache { util.Log(); return &cache{} }\n")},
+		{Path: "store/store_test.go", Content: []byte("package store_test\n\nimport st \"example.com/app/store\"\n\nvar _ = st.New()\n")},
+		{Path: "store/helpers_test.go", Content: []byte("package store\n\nvar key = \"k\"\n")},
+		{Path: "util/log.go", Content: []byte("package util\n\nfunc Log() {}\n\nfunc New() {}\n")},
+		{Path: "web/web.go", Content: []byte("package web\n\nfunc Get() {}\n\nvar c = struct{ New int }{New: 1}\n")},
+		{Path: "README.md", Content: []byte("store.New\n")},
+	}
+
+	tests := []struct {
+		target           string
+		depth            int
+		wantPaths        []string
+		wantDependencies int
+		wantDependents   int
+		wantErr          string
+	}{
+		{"store/store.go", 1, []string{"store/store.go", "store/cache.go", "cmd/main.go", "store/store_test.go"}, 1, 2, ""},
+		{"store/store.go", 2, []string{"store/store.go", "store/cache.go", "util/log.go", "cmd/main.go", "store/store_test.go"}, 2, 2, ""},
+		{"util/log.go", 1, []string{"util/log.go", "store/cache.go"}, 0, 1, ""},
+		{"util/log.go", 3, []string{"util/log.go", "store/cache.go", "store/store.go", "cmd/main.go", "store/store_test.go"}, 0, 4, ""},
+		{"./cmd/main.go", 1, []string{"cmd/main.go", "store/store.go"}, 1, 0, ""},
+		{"web/web.go", 1, []string{"web/web.go"}, 0, 0, ""},
+		{"missing.go", 1, nil, 0, 0, "not among the files walked"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			focus, err := FindFocus(files, root, tt.target, tt.depth)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("FindFocus() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var paths []string
+			for _, file := range focus.Files {
+				paths = append(paths, file.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.wantPaths) || focus.Dependencies != tt.wantDependencies || focus.Dependents != tt.wantDependents {
+				t.Errorf("FindFocus() = %q, %d dependencies, %d dependents; want %q, %d, %d",
+					paths, focus.Dependencies, focus.Dependents, tt.wantPaths, tt.wantDependencies, tt.wantDependents)
+			}
+		})
+	}
+}
+
+func TestFindFocusImports(t *testing.T) {
+	files := []File{
+		{Path: "web/app.ts", Content: []byte("import { api } from './api.js'\nimport type { User } from \"./types\"\nimport React from 'react'\nconst lazy = import('../lib/lazy')\n")},
+		{Path: "web/api.ts", Content: []byte("export const api = 1\n")},
+		{Path: "web/types/index.ts", Content: []byte("export type User = {}\n")},
+		{Path: "lib/lazy.js", Content: []byte("module.exports = require('./helpers')\n")},
+		{Path: "lib/helpers.js", Content: []byte("")},
+		{Path: "pkg/__init__.py", Content: []byte("")},
+		{Path: "pkg/main.py", Content: []byte("import os\nimport pkg.models\nfrom . import views\nfrom .util import (\n    slug,\n    helpers as h,\n)\n")},
+		{Path: "pkg/models.py", Content: []byte("")},
+		{Path: "pkg/views.py", Content: []byte("from pkg.models import User  # the model\n")},
+		{Path: "pkg/util/__init__.py", Content: []byte("")},
+		{Path: "pkg/util/helpers.py", Content: []byte("")},
+	}
+
+	tests := []struct {
+		target string
+		want   []string
+	}{
+		{"web/app.ts", []string{"web/app.ts", "web/api.ts", "web/types/index.ts", "lib/lazy.js"}},
+		{"lib/helpers.js", []string{"lib/helpers.js", "lib/lazy.js"}},
+		{"pkg/main.py", []string{"pkg/main.py", "pkg/__init__.py", "pkg/models.py", "pkg/views.py", "pkg/util/__init__.py", "pkg/util/helpers.py"}},
+		{"pkg/models.py", []string{"pkg/models.py", "pkg/main.py", "pkg/views.py"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			focus, err := FindFocus(files, t.TempDir(), tt.target, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var paths []string
+			for _, file := range focus.Files {
+				paths = append(paths, file.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.want) {
+				t.Errorf("FindFocus(%q) = %q, want %q", tt.target, paths, tt.want)
+			}
+		})
+	}
+}
@@ -2,43 +2,590 @@ package internal
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
 )
 
+// Output formats supported by Formatter. FormatDiff is laid out like
+// FormatText, for files whose content has been replaced by their diff
+// against a git ref (see FileDiffs): "File: path" headers are ignored by
+// git apply, so the whole pack can still be applied as a patch.
+// FormatObsidian is written as a folder of notes by WriteVault; Formatter
+// lays it out like FormatMarkdown, which measures it about right.
+// FormatJSONL writes each file as a line of JSON (see WriteJSONLine) for
+// tools rather than models, so it has no other sections.
+const (
+	FormatText     = "text"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatDiff     = "diff"
+	FormatObsidian = "obsidian"
+	FormatJSONL    = "jsonl"
+)
+
+// Formats lists the supported output formats.
+var Formats = []string{FormatText, FormatMarkdown, FormatHTML, FormatDiff, FormatObsidian, FormatJSONL}
+
+// formatExts maps output formats to their conventional file extensions.
+var formatExts = map[string]string{
+	FormatText:     ".txt",
+	FormatMarkdown: ".md",
+	FormatHTML:     ".html",
+	FormatDiff:     ".diff",
+	FormatJSONL:    ".jsonl",
+}
+
+// FormatExt returns the conventional file extension for a format.
+func FormatExt(format string) string {
+	return formatExts[format]
+}
+
+// FormatFor returns the format implied by an output file name, ignoring a
+// compression extension, or "" if the name doesn't imply one.
+func FormatFor(name string) string {
+	if CompressionFor(name) != "" {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".txt":
+		return FormatText
+	case ".md", ".markdown":
+		return FormatMarkdown
+	case ".html", ".htm":
+		return FormatHTML
+	case ".jsonl", ".ndjson":
+		return FormatJSONL
+	}
+	return ""
+}
+
 // Formatter handles converting files to output format.
 type Formatter struct {
+	// OutputFormat is one of Formats; empty means FormatText.
+	OutputFormat string
+
+	// Summary appends a section listing the file count, total lines, and
+	// estimated tokens, followed by SummaryNotes.
+	Summary bool
+
+	// SummaryNotes describe transformations applied to the files, such as
+	// deduplication, for the summary section.
+	SummaryNotes []string
+
+	// Instructions, if set, are written before the files to tell the model
+	// what to do with them.
+	Instructions string
+
+	// Threads, such as the discussion of the issue the files are needed
+	// for, are written after the instructions, each under its title.
+	Threads []Thread
+
+	// LicenseHeaders, the headers StripLicenseHeaders removed from the
+	// files, are listed once before them.
+	LicenseHeaders []LicenseHeader
+
+	// Collapsible wraps each file of Markdown output in a
+	// <details><summary>path</summary> block, in place of its heading, so
+	// the pack stays navigable when pasted into GitHub or Notion.
+	Collapsible bool
+
+	// SymbolIndex appends an index of the exported Go declarations in the
+	// files, by package, with the file and line declaring each.
+	SymbolIndex bool
+
+	// FrontMatter, when set, is written as a YAML front-matter block at the
+	// top of Markdown output, with the file count and token estimate added.
+	FrontMatter *FrontMatter
+
+	// FileHeader, if set, renders the header line of each file of text and
+	// Markdown output in place of "File: path" (see ParseFileHeader). It
+	// isn't used for collapsible Markdown, or for diff output, which must
+	// still apply as a patch.
+	FileHeader *template.Template
+
+	// Tokenizer, if set, counts the tokens of text for the summary, the
+	// front matter, and TokenCount, in place of EstimateTokens, such as
+	// with a model's own tokenizer.
+	Tokenizer func(text string) int
+
+	// Part and Parts, when Parts > 1, mark the output as one part of a pack
+	// split across several pastes with a "Part X/Y" header.
+	Part, Parts int
+
 	files []File
 }
 
+// FrontMatter describes a pack for its YAML front-matter block, so tools
+// indexing packs can tell where one came from. Empty fields are omitted.
+type FrontMatter struct {
+	Repo      string    // where the packed tree came from, such as its git remote
+	Ref       string    // the branch or tag checked out
+	Commit    string    // the commit checked out
+	Generated time.Time // when the pack was made
+	Version   string    // the gopack version
+}
+
 // NewFormatter creates a new Formatter with the given files.
 func NewFormatter(files []File) *Formatter {
 	return &Formatter{files: files}
 }
 
-// Format returns the formatted output as a string.
+// Format renders the files, and the summary if requested, as a string.
 func (f *Formatter) Format() string {
-	var buf bytes.Buffer
+	var buf strings.Builder
+	buf.Grow(f.sizeHint())
+	f.WriteTo(&buf) // strings.Builder never fails
+	return buf.String()
+}
+
+// FormatContext is like Format, but gives up once ctx is done, returning
+// ctx's error.
+func (f *Formatter) FormatContext(ctx context.Context) (string, error) {
+	var buf strings.Builder
+	buf.Grow(f.sizeHint())
+	if _, err := f.WriteToContext(ctx, &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// WriteTo renders the same output as Format to w, file by file, so it
+// never has to be held in memory as a whole.
+func (f *Formatter) WriteTo(w io.Writer) (int64, error) {
+	return f.WriteToContext(context.Background(), w)
+}
+
+// WriteToContext is like WriteTo, but stops writing once ctx is done,
+// returning ctx's error.
+func (f *Formatter) WriteToContext(ctx context.Context, w io.Writer) (int64, error) {
+	// A tokenizer needs the text itself, not just its length, to count
+	// the summary's tokens
+	var written *strings.Builder
+	if f.Tokenizer != nil && f.Summary {
+		written = &strings.Builder{}
+		w = io.MultiWriter(w, written)
+	}
+	out := &countingWriter{w: w, ctx: ctx}
+	if f.OutputFormat == FormatJSONL {
+		f.writeJSONL(out)
+		return out.n, out.err
+	}
+	if f.FrontMatter != nil && f.OutputFormat == FormatMarkdown {
+		f.writeFrontMatter(out)
+	}
+	tail := ""
+	if f.OutputFormat == FormatHTML {
+		f.writeHTMLHead(out)
+		tail = htmlTail
+	}
+	if f.Parts > 1 {
+		f.writePartHeader(out)
+	}
+	if f.Instructions != "" {
+		f.writeInstructions(out)
+	}
+	if len(f.Threads) > 0 {
+		f.writeThreads(out)
+	}
+	if len(f.LicenseHeaders) > 0 {
+		f.writeLicenseHeaders(out)
+	}
+	switch f.OutputFormat {
+	case FormatMarkdown, FormatObsidian:
+		f.writeMarkdown(out)
+	case FormatHTML:
+		f.writeHTML(out)
+	default:
+		f.writeText(out)
+	}
+	if f.SymbolIndex {
+		f.writeSymbolIndex(out)
+	}
+
+	if f.Summary && out.err == nil {
+		// The summary reports the tokens of the whole output, itself
+		// included, so grow the estimate until it accounts for the
+		// summary's own length
+		tokens := int(out.n / 4)
+		if written != nil {
+			tokens = f.Tokenizer(written.String())
+		}
+		for {
+			summary := f.formatSummary(tokens)
+			total := (int(out.n) + len(summary) + len(tail)) / 4
+			if written != nil {
+				total = f.Tokenizer(written.String() + summary + tail)
+			}
+			if total <= tokens {
+				io.WriteString(out, summary)
+				break
+			}
+			tokens = total
+		}
+	}
+	io.WriteString(out, tail)
+	return out.n, out.err
+}
+
+// sizeHint estimates the length of the output, to size buffers up front.
+func (f *Formatter) sizeHint() int {
+	size := 0
+	for _, header := range f.LicenseHeaders {
+		size += len(header.Text) + 64
+	}
+	for _, file := range f.files {
+		size += fileChars(file) + 32 // headers, fences, and separators
+	}
+	return size
+}
+
+// countingWriter counts the bytes written through it and remembers the
+// first error, after which further writes are dropped.
+type countingWriter struct {
+	w   io.Writer
+	ctx context.Context // once done, its error is the writer's
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err == nil && c.ctx != nil {
+		c.err = c.ctx.Err()
+	}
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+// writeFrontMatter writes the YAML front-matter block. Its token count
+// covers the whole output, the block included, so the rest of the output
+// is measured first.
+func (f *Formatter) writeFrontMatter(w io.Writer) {
+	rest := *f
+	rest.FrontMatter = nil
+	if f.Tokenizer != nil {
+		text := rest.Format()
+		tokens := f.Tokenizer(text)
+		for {
+			block := f.formatFrontMatter(tokens)
+			if total := f.Tokenizer(block + text); total > tokens {
+				tokens = total
+				continue
+			}
+			io.WriteString(w, block)
+			return
+		}
+	}
+	n, _ := rest.WriteTo(io.Discard)
+
+	tokens := int(n / 4)
+	for {
+		block := f.formatFrontMatter(tokens)
+		total := (int(n) + len(block)) / 4
+		if total <= tokens {
+			io.WriteString(w, block)
+			return
+		}
+		tokens = total
+	}
+}
+
+// formatFrontMatter renders the front-matter block reporting the given
+// token estimate. Strings are double-quoted, which YAML reads like JSON.
+func (f *Formatter) formatFrontMatter(tokens int) string {
+	var buf strings.Builder
+	buf.WriteString("---\n")
+	field := func(key, value string) {
+		if value != "" {
+			fmt.Fprintf(&buf, "%s: %s\n", key, strconv.Quote(value))
+		}
+	}
+	field("repo", f.FrontMatter.Repo)
+	field("ref", f.FrontMatter.Ref)
+	field("commit", f.FrontMatter.Commit)
+	if !f.FrontMatter.Generated.IsZero() {
+		fmt.Fprintf(&buf, "generated_at: %s\n", f.FrontMatter.Generated.UTC().Format(time.RFC3339))
+	}
+	fmt.Fprintf(&buf, "files: %d\n", len(f.files))
+	fmt.Fprintf(&buf, "tokens: %d\n", tokens)
+	field("gopack_version", f.FrontMatter.Version)
+	buf.WriteString("---\n\n")
+	return buf.String()
+}
+
+// writePartHeader writes the "Part X/Y" header for split output.
+func (f *Formatter) writePartHeader(w io.Writer) {
+	switch f.OutputFormat {
+	case FormatHTML:
+		fmt.Fprintf(w, "<h1>Part %d/%d</h1>\n", f.Part, f.Parts)
+		return
+	case FormatMarkdown:
+		fmt.Fprintf(w, "# Part %d/%d\n\n", f.Part, f.Parts)
+		return
+	}
+	fmt.Fprintf(w, "=== Part %d/%d ===\n\n", f.Part, f.Parts)
+}
+
+// instructionsSection is the heading of the text format's instructions.
+const instructionsSection = "=== Instructions ===\n"
+
+// writeInstructions writes the instructions for the model.
+func (f *Formatter) writeInstructions(w io.Writer) {
+	text := strings.TrimRight(f.Instructions, "\n")
+	switch f.OutputFormat {
+	case FormatHTML:
+		fmt.Fprintf(w, "<h2>Instructions</h2>\n<p style=\"white-space:pre-wrap\">%s</p>\n", html.EscapeString(text))
+	case FormatMarkdown:
+		fmt.Fprintf(w, "## Instructions\n\n%s\n\n", text)
+	default:
+		fmt.Fprintf(w, "%s%s\n\n", instructionsSection, text)
+	}
+}
+
+// writeThreads writes the threads, each under its title and a link to it.
+func (f *Formatter) writeThreads(w io.Writer) {
+	for _, thread := range f.Threads {
+		content := strings.Trim(thread.Content, "\n")
+		switch f.OutputFormat {
+		case FormatHTML:
+			fmt.Fprintf(w, "<h2>%s</h2>\n<p><a href=\"%s\">%s</a></p>\n<p style=\"white-space:pre-wrap\">%s</p>\n",
+				html.EscapeString(thread.Title), html.EscapeString(thread.URL), html.EscapeString(thread.URL), html.EscapeString(content))
+		case FormatMarkdown:
+			fmt.Fprintf(w, "## %s\n\n<%s>\n\n%s\n\n", thread.Title, thread.URL, content)
+		default:
+			fmt.Fprintf(w, "=== %s ===\n%s\n\n%s\n\n", thread.Title, thread.URL, content)
+		}
+	}
+}
+
+// licenseSection is the heading of the text format's license header
+// section.
+const licenseSection = "=== License Headers ===\n"
+
+// writeLicenseHeaders writes the license headers stripped from the files.
+func (f *Formatter) writeLicenseHeaders(w io.Writer) {
+	switch f.OutputFormat {
+	case FormatHTML:
+		io.WriteString(w, "<h2>License Headers</h2>\n")
+		for _, header := range f.LicenseHeaders {
+			fmt.Fprintf(w, "<p>Removed from the top of %s files:</p>\n<pre>%s</pre>\n", FormatWithCommas(header.Files), html.EscapeString(header.Text))
+		}
+		return
+	case FormatMarkdown:
+		io.WriteString(w, "## License Headers\n\n")
+		for _, header := range f.LicenseHeaders {
+			fence := codeFence([]byte(header.Text))
+			fmt.Fprintf(w, "Removed from the top of %s files:\n\n%s\n%s\n%s\n\n", FormatWithCommas(header.Files), fence, header.Text, fence)
+		}
+		return
+	}
+	io.WriteString(w, licenseSection)
+	for _, header := range f.LicenseHeaders {
+		fmt.Fprintf(w, "Removed from the top of %s files:\n%s\n\n", FormatWithCommas(header.Files), header.Text)
+	}
+}
 
+// writeText writes each file under a "File: path" header.
+func (f *Formatter) writeText(w io.Writer) {
 	for i, file := range f.files {
 		// Write file header
-		fmt.Fprintf(&buf, "File: %s\n", file.Path)
+		if f.FileHeader != nil && f.OutputFormat != FormatDiff {
+			fmt.Fprintf(w, "%s\n", fileHeader(f.FileHeader, file))
+		} else if file.URL != "" {
+			fmt.Fprintf(w, "File: %s (%s)%s\n", file.Path, file.URL, modeSuffix(file))
+		} else {
+			fmt.Fprintf(w, "File: %s%s\n", file.Path, modeSuffix(file))
+		}
 		// Write file content
-		buf.Write(file.Content)
+		w.Write(file.Content)
 		// Add blank line between files (except after the last one)
 		if i < len(f.files)-1 {
-			buf.WriteString("\n\n")
+			io.WriteString(w, "\n\n")
+		}
+	}
+}
+
+// writeMarkdown writes each file as a heading followed by a fenced code
+// block tagged with the file's language.
+func (f *Formatter) writeMarkdown(w io.Writer) {
+	for i, file := range f.files {
+		end := ""
+		switch {
+		case f.Collapsible:
+			fmt.Fprintf(w, "<details>\n<summary>%s%s</summary>\n\n", htmlFileLink(file), modeSuffix(file))
+			end = "\n</details>\n"
+		case f.FileHeader != nil:
+			fmt.Fprintf(w, "%s\n\n", fileHeader(f.FileHeader, file))
+		case file.URL != "":
+			fmt.Fprintf(w, "## File: [%s](%s)%s\n\n", file.Path, file.URL, modeSuffix(file))
+		default:
+			fmt.Fprintf(w, "## File: %s%s\n\n", file.Path, modeSuffix(file))
+		}
+
+		// Dedupe references and placeholders aren't code, so keep them out
+		// of the fence
+		if file.DuplicateOf != "" || file.Omitted {
+			w.Write(file.Content)
+			io.WriteString(w, end)
+			if i < len(f.files)-1 {
+				io.WriteString(w, "\n")
+			}
+			continue
+		}
+
+		language := DetectLanguage(file.Path, file.Content)
+		fence := codeFence(file.Content)
+		fmt.Fprintf(w, "%s%s\n", fence, language.Fence)
+		w.Write(file.Content)
+		if len(file.Content) > 0 && !bytes.HasSuffix(file.Content, []byte("\n")) {
+			io.WriteString(w, "\n")
+		}
+		io.WriteString(w, fence+"\n"+end)
+		if i < len(f.files)-1 {
+			io.WriteString(w, "\n")
 		}
 	}
+}
+
+// formatSummary renders the summary section reporting the given token
+// estimate.
+func (f *Formatter) formatSummary(tokens int) string {
+	lines := 0
+	for _, file := range f.files {
+		lines += countLines(file.Content)
+	}
 
+	items := []string{
+		fmt.Sprintf("Files: %s", FormatWithCommas(len(f.files))),
+		fmt.Sprintf("Lines: %s", FormatWithCommas(lines)),
+		fmt.Sprintf("Estimated tokens: ~%s", FormatWithCommas(tokens)),
+	}
+	items = append(items, f.SummaryNotes...)
+
+	var buf bytes.Buffer
+	switch f.OutputFormat {
+	case FormatHTML:
+		buf.WriteString("<h2>Summary</h2>\n<ul>\n")
+		for _, item := range items {
+			fmt.Fprintf(&buf, "<li>%s</li>\n", html.EscapeString(item))
+		}
+		buf.WriteString("</ul>\n")
+	case FormatMarkdown:
+		buf.WriteString("\n## Summary\n\n")
+		for _, item := range items {
+			fmt.Fprintf(&buf, "- %s\n", item)
+		}
+	default:
+		// A distinct header, so it can't be mistaken for the end of the
+		// last file
+		buf.WriteString("\n\n=== Pack Summary ===\n")
+		for _, item := range items {
+			fmt.Fprintf(&buf, "%s\n", item)
+		}
+	}
 	return buf.String()
 }
 
-// TokenCount returns an estimated token count (character count / 4).
+// codeFence returns a backtick fence longer than any backtick run in
+// content, so embedded code blocks can't close it early.
+func codeFence(content []byte) string {
+	longest, run := 0, 0
+	for _, b := range content {
+		if b == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// TokenCount returns the estimated token count of the formatted output.
 func (f *Formatter) TokenCount() int {
-	totalChars := 0
-	for _, file := range f.files {
-		totalChars += len(file.Path) + len("File: \n")
-		totalChars += len(file.Content)
+	if f.Tokenizer != nil {
+		return f.Tokenizer(f.Format())
+	}
+	return EstimateTokens(f.Format())
+}
+
+// EstimateTokens returns an estimated token count for text
+// (character count / 4).
+func EstimateTokens(text string) int {
+	return len(text) / 4
+}
+
+// FileTokens returns the estimated number of tokens a single file
+// contributes to text output, header included.
+func FileTokens(file File) int {
+	return fileChars(file) / 4
+}
+
+// fileChars returns the number of characters a file contributes to the
+// output, header included.
+func fileChars(file File) int {
+	chars := len(file.Path) + len("File: \n") + len(file.Content)
+	if file.URL != "" {
+		chars += len(file.URL) + len(" ()")
+	}
+	return chars
+}
+
+// FileModeString renders permission bits as a file's header and the
+// manifest show them, as in "0755".
+func FileModeString(mode os.FileMode) string {
+	return fmt.Sprintf("%04o", mode.Perm())
+}
+
+// modeSuffix returns what a file's header adds after its name for its
+// mode, as in " (mode 0755)", or "" if it has none.
+func modeSuffix(file File) string {
+	if file.Mode == 0 {
+		return ""
+	}
+	return " (mode " + FileModeString(file.Mode) + ")"
+}
+
+// FormatWithCommas adds thousand separators to a number.
+func FormatWithCommas(num int) string {
+	str := strconv.Itoa(num)
+	var result strings.Builder
+
+	for i, ch := range str {
+		if i > 0 && (len(str)-i)%3 == 0 {
+			result.WriteRune(',')
+		}
+		result.WriteRune(ch)
+	}
+
+	return result.String()
+}
+
+// FormatBytes renders a byte count with a binary unit, such as "42 MB".
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, prefix := float64(n)/unit, 0
+	for value >= unit && prefix < len("KMGTPE")-1 {
+		value /= unit
+		prefix++
+	}
+	if value < 9.95 { // would round to "10.0"
+		return fmt.Sprintf("%.1f %cB", value, "KMGTPE"[prefix])
 	}
-	return totalChars / 4
+	return fmt.Sprintf("%.0f %cB", value, "KMGTPE"[prefix])
 }
@@ -0,0 +1,174 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatSummary(t *testing.T) {
+	files := []File{
+		{Path: "a.go", Content: []byte("package a\n")},
+		{Path: "b.go", Content: []byte("package b\n\nfunc B() {}\n")},
+	}
+
+	for _, format := range Formats {
+		if format == FormatJSONL {
+			continue // has no summary
+		}
+		t.Run(format, func(t *testing.T) {
+			formatter := NewFormatter(files)
+			formatter.OutputFormat = format
+			formatter.Summary = true
+			output := formatter.Format()
+
+			header := "\n\n=== Pack Summary ===\n"
+			switch format {
+			case FormatMarkdown:
+				header = "\n## Summary\n\n"
+			case FormatHTML:
+				header = "<h2>Summary</h2>\n<ul>\n"
+			}
+			if !strings.Contains(output, header) {
+				t.Errorf("Format() = %q, missing summary header %q", output, header)
+			}
+
+			// The reported estimate covers the whole output, summary included
+			want := "Estimated tokens: ~" + FormatWithCommas(EstimateTokens(output))
+			if !strings.Contains(output, want+"\n") && !strings.Contains(output, want+"</li>") {
+				t.Errorf("Format() = %q, want it to report %q", output, want)
+			}
+			if got := formatter.TokenCount(); got != EstimateTokens(output) {
+				t.Errorf("TokenCount() = %d, want %d", got, EstimateTokens(output))
+			}
+		})
+	}
+}
+
+func TestFormatPartHeader(t *testing.T) {
+	files := []File{{Path: "a.go", Content: []byte("package a\n")}}
+
+	tests := []struct {
+		format      string
+		part, parts int
+		want        string
+	}{
+		{FormatText, 2, 3, "=== Part 2/3 ===\n\nFile: a.go\n"},
+		{FormatMarkdown, 1, 2, "# Part 1/2\n\n## File: a.go\n"},
+		{FormatText, 1, 1, "File: a.go\n"},
+	}
+
+	for _, tt := range tests {
+		formatter := NewFormatter(files)
+		formatter.OutputFormat = tt.format
+		formatter.Part, formatter.Parts = tt.part, tt.parts
+		if got := formatter.Format(); !strings.HasPrefix(got, tt.want) {
+			t.Errorf("Format(%s, part %d/%d) = %q, want prefix %q", tt.format, tt.part, tt.parts, got, tt.want)
+		}
+	}
+}
+
+func TestFormatFrontMatter(t *testing.T) {
+	files := []File{{Path: "a.go", Content: []byte("package a\n")}}
+	meta := &FrontMatter{
+		Repo:      "https://github.com/example/app.git",
+		Ref:       "main",
+		Generated: time.Date(2024, 6, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60)),
+		Version:   "v1.2.3",
+	}
+
+	formatter := NewFormatter(files)
+	formatter.OutputFormat = FormatMarkdown
+	formatter.FrontMatter = meta
+	formatter.Summary = true
+	output := formatter.Format()
+	want := "---\nrepo: \"https://github.com/example/app.git\"\nref: \"main\"\ngenerated_at: 2024-06-01T10:00:00Z\nfiles: 1\n" +
+		"tokens: " + strconv.Itoa(EstimateTokens(output)) + "\ngopack_version: \"v1.2.3\"\n---\n\n## File: a.go\n"
+	if !strings.HasPrefix(output, want) {
+		t.Errorf("Format() = %q, want prefix %q", output, want)
+	}
+
+	// Text output has no front matter
+	formatter.OutputFormat = FormatText
+	if output := formatter.Format(); !strings.HasPrefix(output, "File: a.go\n") {
+		t.Errorf("Format(text) = %q, want no front matter", output)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:                "0 B",
+		1023:             "1023 B",
+		1024:             "1.0 KB",
+		1536:             "1.5 KB",
+		44040192:         "42 MB",
+		5 << 30:          "5.0 GB",
+		3 << 50:          "3.0 PB",
+		1<<63 - 1:        "8.0 EB",
+		1024*1024*10 - 1: "10 MB",
+	}
+	for n, want := range tests {
+		if got := FormatBytes(n); got != want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestFormatterWriteTo(t *testing.T) {
+	files := []File{
+		{Path: "a.go", Content: []byte("package a\n")},
+		{Path: "big.txt", Content: []byte(strings.Repeat("line\n", 5000))},
+	}
+	for _, format := range Formats {
+		formatter := NewFormatter(files)
+		formatter.OutputFormat = format
+		formatter.Summary = true
+		formatter.Part, formatter.Parts = 1, 2
+
+		var buf bytes.Buffer
+		n, err := formatter.WriteTo(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := formatter.Format(); buf.String() != want || n != int64(len(want)) {
+			t.Errorf("%s: WriteTo() wrote %d bytes differing from Format()", format, n)
+		}
+
+		// Writing stops at the first error
+		failing := &failingWriter{limit: 100}
+		if _, err := formatter.WriteTo(failing); err == nil || failing.written > 100 {
+			t.Errorf("%s: WriteTo(failing) = %v after %d bytes, want error at 100", format, err, failing.written)
+		}
+	}
+}
+
+func TestFormatContext(t *testing.T) {
+	files := []File{{Path: "a.go", Content: []byte("package a\n")}}
+	formatter := NewFormatter(files)
+	if got, err := formatter.FormatContext(context.Background()); err != nil || got != formatter.Format() {
+		t.Errorf("FormatContext() = %q, %v; want Format()'s output", got, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if got, err := formatter.FormatContext(ctx); !errors.Is(err, context.Canceled) || got != "" {
+		t.Errorf("FormatContext(canceled) = %q, %v; want context.Canceled", got, err)
+	}
+}
+
+// failingWriter accepts limit bytes, then fails.
+type failingWriter struct {
+	limit, written int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.written+len(p) > w.limit {
+		return 0, errors.New("disk full")
+	}
+	w.written += len(p)
+	return len(p), nil
+}
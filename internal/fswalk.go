@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// NewFSWalker creates a Walker over a file system other than the host's,
+// such as an archive, an in-memory tree, or a test fixture. Paths are
+// slash-separated and relative to the root of fsys, as fs.FS requires, and
+// may be directories, files, or include globs; with no paths all of fsys is
+// walked. Output paths are relative to the root of fsys.
+//
+// Filters that need a git checkout (SinceGit, Author, and LFSSmudge) can't
+// be used, symlinks are never followed, and SetRoot isn't supported.
+func NewFSWalker(fsys fs.FS, paths ...string) (*Walker, error) {
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	var targets []target
+	for _, arg := range paths {
+		p := path.Clean(arg)
+		var glob string
+		if _, err := fs.Stat(fsys, p); errors.Is(err, fs.ErrNotExist) && isGlob(p) {
+			var dir string
+			dir, glob = splitGlob(p)
+			p = filepath.ToSlash(dir)
+		}
+		if !fs.ValidPath(p) {
+			return nil, fmt.Errorf("invalid path %q: paths in a file system are relative and slash-separated", arg)
+		}
+		if _, err := fs.Stat(fsys, p); err != nil {
+			return nil, err
+		}
+		targets = append(targets, target{arg: arg, path: filepath.FromSlash(p), glob: glob})
+	}
+
+	w := &Walker{
+		DefaultIgnores: append([]string(nil), DefaultIgnorePatterns...),
+		IgnoreFiles:    append([]string(nil), ignoreFiles...),
+		VCSDirs:        append([]string(nil), DefaultVCSDirs...),
+		rootPath:       ".",
+		targets:        targets,
+		patterns:       make(map[string][]ignoreRule),
+		attributes:     make(map[string][]attrRule),
+		fsys:           fsys,
+	}
+	w.loadIgnoreFiles(w.rootPath)
+	return w, nil
+}
+
+// checkFS reports the options a walk of an fs.FS can't honor.
+func (w *Walker) checkFS() error {
+	if w.fsys == nil {
+		return nil
+	}
+	switch {
+	case !w.Since.IsZero() && w.SinceGit:
+		return errors.New("SinceGit needs a git checkout, not an fs.FS")
+	case w.Author != "":
+		return errors.New("Author needs a git checkout, not an fs.FS")
+	case w.LFS == LFSSmudge:
+		return errors.New("LFSSmudge needs a git checkout, not an fs.FS")
+	}
+	return nil
+}
+
+// fsPath converts a path in the walker's terms to one in its fs.FS.
+func fsPath(p string) string {
+	return path.Clean(filepath.ToSlash(p))
+}
+
+// open opens the file at path for reading, from the walker's fs.FS if it
+// has one.
+func (w *Walker) open(p string) (fs.File, error) {
+	if w.fsys != nil {
+		return w.fsys.Open(fsPath(p))
+	}
+	return os.Open(LongPath(p))
+}
+
+// lstat describes the file at path without following a final symlink,
+// except in an fs.FS, which can't tell.
+func (w *Walker) lstat(p string) (fs.FileInfo, error) {
+	if w.fsys != nil {
+		return fs.Stat(w.fsys, fsPath(p))
+	}
+	return os.Lstat(LongPath(p))
+}
+
+// walkDir calls fn for every file and directory under root, as
+// filepath.Walk does, in the walker's fs.FS if it has one.
+func (w *Walker) walkDir(root string, fn filepath.WalkFunc) error {
+	if w.fsys == nil {
+		return filepath.Walk(root, fn)
+	}
+	return fs.WalkDir(w.fsys, fsPath(root), func(p string, d fs.DirEntry, err error) error {
+		var info fs.FileInfo
+		if err == nil {
+			info, err = d.Info()
+		}
+		return fn(filepath.FromSlash(p), info, err)
+	})
+}
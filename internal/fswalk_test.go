@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFSWalker(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitignore":             {Data: []byte("*.log\n")},
+		"main.go":                {Data: []byte("package main\n")},
+		"debug.log":              {Data: []byte("x\n")},
+		"go.sum":                 {Data: []byte("x\n")},
+		"web/.gopackignore":      {Data: []byte("/fixtures/\n")},
+		"web/app.ts":             {Data: []byte("export {}\n")},
+		"web/fixtures/data.json": {Data: []byte("{}\n")},
+		"web/logo.png":           {Data: []byte("\x89PNG\r\n\x1a\n\x00\x00")},
+		"web/current":            {Data: []byte("app.ts"), Mode: fs.ModeSymlink},
+		"docs/guide.md":          {Data: []byte("# Guide\n")},
+	}
+
+	tests := []struct {
+		paths []string
+		want  []string
+	}{
+		{nil, []string{".gitignore", "docs/guide.md", "main.go", "web/.gopackignore", "web/app.ts"}},
+		{[]string{"web"}, []string{"web/.gopackignore", "web/app.ts"}},
+		{[]string{"**/*.md", "main.go"}, []string{"docs/guide.md", "main.go"}},
+	}
+	for _, tt := range tests {
+		walker, err := NewFSWalker(fsys, tt.paths...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var skipped []string
+		walker.OnSkip = func(path, reason string) { skipped = append(skipped, path+": "+reason) }
+		files, err := walker.Walk()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, file := range files {
+			got = append(got, filepath.ToSlash(file.Path))
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Walk(%q) = %q, want %q (skipped %q)", tt.paths, got, tt.want, skipped)
+		}
+	}
+
+	walker, err := NewFSWalker(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := walker.Explain("web/fixtures/data.json"); err != nil || !strings.Contains(got, "web/.gopackignore:1") {
+		t.Errorf("Explain() = %q, %v; want exclusion by web/.gopackignore:1", got, err)
+	}
+	walker.Author = "someone"
+	if _, err := walker.Walk(); err == nil {
+		t.Error("Walk() with Author in an fs.FS succeeded, want error")
+	}
+
+	for _, path := range []string{"/etc", "../up", "missing"} {
+		if _, err := NewFSWalker(fsys, path); err == nil {
+			t.Errorf("NewFSWalker(%q) succeeded, want error", path)
+		}
+	}
+}
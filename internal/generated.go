@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// generatedHeaderLines is how far into a file generated-code markers are
+// looked for; tools put them in the header, usually on the first line.
+const generatedHeaderLines = 50
+
+var (
+	// Go's convention (a whole comment line matching
+	// "^// Code generated .* DO NOT EDIT\.$"), also used with other comment
+	// leaders by many non-Go generators
+	goGeneratedMarker = regexp.MustCompile(`^(//|#|--|;|/\*|<!--) ?Code generated .* DO NOT EDIT\.( ?(\*/|-->))?$`)
+	// Facebook/Meta convention, used by Buck, Relay, Thrift, etc.
+	atGeneratedMarker = regexp.MustCompile(`(^|\W)@generated(\W|$)`)
+)
+
+// generatedNames are file name patterns produced by common code generators.
+// Mocks aren't matched by name, as they're often written by hand; mockgen
+// and mockery mark theirs as generated.
+var generatedNames = []string{
+	"*.pb.go",
+	"*.pb.gw.go",
+	"*_pb2.py",
+	"*_pb2_grpc.py",
+}
+
+// isGenerated reports whether a file looks machine-generated, based on its
+// name or a generated-code marker in a comment near the top of the file.
+func isGenerated(name string, content []byte) bool {
+	base := path.Base(strings.ReplaceAll(name, `\`, "/"))
+	for _, pattern := range generatedNames {
+		if matched, _ := path.Match(pattern, base); matched {
+			return true
+		}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for i := 0; i < generatedHeaderLines && scanner.Scan(); i++ {
+		line := strings.TrimSpace(scanner.Text())
+		if !isCommentLine(line) {
+			continue
+		}
+		if goGeneratedMarker.MatchString(line) || atGeneratedMarker.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCommentLine reports whether a trimmed line starts with a common
+// comment leader.
+func isCommentLine(line string) bool {
+	for _, leader := range []string{"//", "#", "/*", "*", "--", "<!--", ";", "%"} {
+		if strings.HasPrefix(line, leader) {
+			return true
+		}
+	}
+	return false
+}
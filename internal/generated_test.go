@@ -0,0 +1,36 @@
+package internal
+
+import "testing"
+
+func TestIsGenerated(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"api.pb.go", "package api\n", true},
+		{"service_pb2.py", "", true},
+		{"internal/mock_store.go", "package internal\n", false},
+		{"store_mock.go", "package store\n", false},
+		{"internal/mock_store.go", "// Code generated by MockGen. DO NOT EDIT.\n\npackage internal\n", true},
+		{"main.go", "package main\n", false},
+		{"gen.go", "// Code generated by stringer; DO NOT EDIT.\n\npackage gen\n", true},
+		{"gen.go", "// Code generated by protoc-gen-go. DO NOT EDIT.\n", true},
+		{"gen.py", "# Code generated by tool. DO NOT EDIT.\n", true},
+		{"gen.ts", "/* Code generated by tool. DO NOT EDIT. */\n", true},
+		{"gen.html", "<!-- Code generated by tool. DO NOT EDIT. -->\n", true},
+		{"Foo.java", "/**\n * @generated\n */\nclass Foo {}\n", true},
+		{"walker.go", "// Files with a \"Code generated by X DO NOT EDIT\" header are skipped.\npackage internal\n", false},
+		{"walker.go", "// Code generated by hand, please edit.\n", false},
+		{"main.go", "package main\n\nvar s = \"// Code generated by x. DO NOT EDIT.\"\n", false},
+		{"main.go", "package main\n\nvar marker = \"@generated\"\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isGenerated(tt.name, []byte(tt.content)); got != tt.want {
+				t.Errorf("isGenerated(%q, %q) = %v, want %v", tt.name, tt.content, got, tt.want)
+			}
+		})
+	}
+}
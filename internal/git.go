@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// runGit runs a git command in dir and returns its standard output.
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
+
+// changedSince returns the set of files (relative to dir, slash-separated,
+// in NFC) touched by commits made after t.
+func changedSince(ctx context.Context, dir string, t time.Time) (map[string]bool, error) {
+	// -z keeps git from quoting unusual paths such as "h\303\251llo.go"
+	out, err := runGit(ctx, dir, "log", "-z", "--since="+t.Format(time.RFC3339), "--name-only", "--pretty=format:", "--relative")
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make(map[string]bool)
+	for _, name := range strings.Split(out, "\x00") {
+		if name != "" {
+			changed[NormalizePath(name)] = true
+		}
+	}
+	return changed, nil
+}
+
+// lastAuthors returns the author ("Name <email>") of the most recent commit
+// touching each file under dir, keyed by slash-separated relative path in
+// NFC.
+func lastAuthors(ctx context.Context, dir string) (map[string]string, error) {
+	// With -z each commit is "\x01Name <email>\nfirst-file\x00other-file\x00..."
+	out, err := runGit(ctx, dir, "log", "-z", "--pretty=format:%x01%an <%ae>", "--name-only", "--relative")
+	if err != nil {
+		return nil, err
+	}
+
+	authors := make(map[string]string)
+	var current string
+	for _, name := range strings.Split(out, "\x00") {
+		if header, ok := strings.CutPrefix(name, "\x01"); ok {
+			current, name, _ = strings.Cut(header, "\n")
+		}
+		// Log is newest first, so the first author seen for a file wins
+		name = NormalizePath(name)
+		if name != "" {
+			if _, ok := authors[name]; !ok {
+				authors[name] = current
+			}
+		}
+	}
+	return authors, nil
+}
+
+// blameShare returns the fraction of a file's lines (relative to dir) last
+// changed by an author matching query.
+func blameShare(ctx context.Context, dir, file, query string) (float64, error) {
+	out, err := runGit(ctx, dir, "blame", "--line-porcelain", "--", file)
+	if err != nil {
+		return 0, err
+	}
+
+	var total, matched int
+	var author string
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "author "):
+			author = strings.TrimPrefix(line, "author ")
+		case strings.HasPrefix(line, "author-mail "):
+			author += " " + strings.TrimPrefix(line, "author-mail ")
+		case strings.HasPrefix(line, "\t"):
+			// Content line: ends the header for one blamed line
+			total++
+			if authorMatches(author, query) {
+				matched++
+			}
+		}
+	}
+
+	if total == 0 {
+		return 0, nil
+	}
+	return float64(matched) / float64(total), nil
+}
+
+// authorMatches reports whether an author ("Name <email>") contains query,
+// ignoring case.
+func authorMatches(author, query string) bool {
+	return strings.Contains(strings.ToLower(author), strings.ToLower(query))
+}
+
+// commitCounts returns how many commits touched each file under dir, keyed
+// by slash-separated relative path. If since is non-zero, only commits made
+// after it are counted.
+func commitCounts(ctx context.Context, dir string, since time.Time) (map[string]int, error) {
+	args := []string{"log", "-z", "--name-only", "--pretty=format:", "--relative"}
+	if !since.IsZero() {
+		args = append(args, "--since="+since.Format(time.RFC3339))
+	}
+	out, err := runGit(ctx, dir, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, name := range strings.Split(out, "\x00") {
+		if name != "" {
+			counts[name]++
+		}
+	}
+	return counts, nil
+}
+
+// RepoInfo describes the git checkout at dir: its origin remote (without
+// any credentials in the URL), the branch checked out, and the commit.
+// Anything unknown, such as the branch of a detached HEAD, is empty.
+func RepoInfo(ctx context.Context, dir string) (remote, ref, commit string) {
+	if out, err := runGit(ctx, dir, "remote", "get-url", "origin"); err == nil {
+		remote = strings.TrimSpace(out)
+		if u, err := url.Parse(remote); err == nil && u.User != nil {
+			u.User = nil
+			remote = u.String()
+		}
+	}
+	if out, err := runGit(ctx, dir, "symbolic-ref", "--short", "-q", "HEAD"); err == nil {
+		ref = strings.TrimSpace(out)
+	}
+	if out, err := runGit(ctx, dir, "rev-parse", "-q", "--verify", "HEAD"); err == nil {
+		commit = strings.TrimSpace(out)
+	}
+	return remote, ref, commit
+}
@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// gitRepo creates a repository with two commits: Alice adds héllo.go and
+// b.go, then Bob changes b.go.
+func gitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q")
+	write("héllo.go", "package main\n")
+	write("b.go", "package main\n")
+	git("add", ".")
+	git("-c", "user.name=Alice", "-c", "user.email=alice@example.com", "commit", "-q", "-m", "one")
+	write("b.go", "package main\n\nvar b = 1\n")
+	git("-c", "user.name=Bob", "-c", "user.email=bob@example.com", "commit", "-q", "-am", "two")
+	return dir
+}
+
+func TestChangedSince(t *testing.T) {
+	dir := gitRepo(t)
+
+	changed, err := changedSince(context.Background(), dir, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"héllo.go", "b.go"} {
+		if !changed[name] {
+			t.Errorf("changedSince() missing %q, got %v", name, changed)
+		}
+	}
+
+	changed, err = changedSince(context.Background(), dir, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 0 {
+		t.Errorf("changedSince(future) = %v, want none", changed)
+	}
+}
+
+func TestLastAuthors(t *testing.T) {
+	dir := gitRepo(t)
+
+	authors, err := lastAuthors(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"héllo.go": "Alice <alice@example.com>",
+		"b.go":     "Bob <bob@example.com>",
+	}
+	for name, author := range want {
+		if authors[name] != author {
+			t.Errorf("lastAuthors()[%q] = %q, want %q", name, authors[name], author)
+		}
+	}
+}
+
+func TestCommitCounts(t *testing.T) {
+	dir := gitRepo(t)
+
+	counts, err := commitCounts(context.Background(), dir, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"héllo.go": 1, "b.go": 2}
+	for name, count := range want {
+		if counts[name] != count {
+			t.Errorf("commitCounts()[%q] = %d, want %d", name, counts[name], count)
+		}
+	}
+
+	// Both commits are older than a window starting in the future
+	counts, err = commitCounts(context.Background(), dir, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 0 {
+		t.Errorf("commitCounts(future) = %v, want none", counts)
+	}
+}
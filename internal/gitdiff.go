@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ChangedFiles returns the files under dir that differ between ref and the
+// working tree, slash-separated and relative to dir, leaving out deleted
+// files. With paths, relative to dir, only changes under them are listed;
+// include globs among them match as they do given to NewWalker.
+func ChangedFiles(ctx context.Context, dir, ref string, paths ...string) ([]string, error) {
+	if err := checkRef(ref); err != nil {
+		return nil, err
+	}
+	// -z keeps git from quoting unusual paths such as "h\303\251llo.go"
+	args := []string{"diff", "--name-only", "-z", "--no-renames", "--diff-filter=d", "--relative", ref, "--"}
+	for _, p := range paths {
+		// A plain pathspec's "*" would match across directories
+		if _, err := os.Stat(filepath.Join(dir, p)); os.IsNotExist(err) && isGlob(p) {
+			p = ":(glob)" + filepath.ToSlash(p)
+		}
+		args = append(args, p)
+	}
+	out, err := runGit(ctx, dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, name := range strings.Split(out, "\x00") {
+		if name != "" {
+			files = append(files, name)
+		}
+	}
+	return files, nil
+}
+
+// FileDiffs returns the unified diff of each file under dir between ref and
+// the working tree, with lines of unchanged context around each change,
+// keyed by slash-separated path relative to dir. Each diff starts with its
+// "diff --git" header, so they can be applied as they are.
+func FileDiffs(ctx context.Context, dir, ref string, contextLines int) (map[string]string, error) {
+	if err := checkRef(ref); err != nil {
+		return nil, err
+	}
+	out, err := runGit(ctx, dir, "diff", "--no-color", "--no-ext-diff", "--no-renames", fmt.Sprintf("-U%d", contextLines), "--relative", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	return splitDiff(out), nil
+}
+
+// splitDiff splits git diff output into the diff of each file, keyed by
+// its new path, or its old one if it was deleted.
+func splitDiff(out string) map[string]string {
+	diffs := make(map[string]string)
+	var section strings.Builder
+	var oldPath, newPath string
+	flush := func() {
+		path := newPath
+		if path == "" {
+			path = oldPath
+		}
+		if path != "" {
+			diffs[path] = section.String()
+		}
+		section.Reset()
+		oldPath, newPath = "", ""
+	}
+
+	inHeader := false
+	for _, line := range strings.SplitAfter(out, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			flush()
+			inHeader = true
+		}
+		if inHeader {
+			switch {
+			case strings.HasPrefix(line, "--- "):
+				oldPath = patchPath(unquotePath(strings.TrimSuffix(line[4:], "\n")), "a/")
+			case strings.HasPrefix(line, "+++ "):
+				newPath = patchPath(unquotePath(strings.TrimSuffix(line[4:], "\n")), "b/")
+				inHeader = false
+			}
+		}
+		section.WriteString(line)
+	}
+	flush()
+	return diffs
+}
+
+// unquotePath undoes the C-style quoting git applies to unusual paths in
+// diff headers, such as "a/h\303\251llo.go".
+func unquotePath(value string) string {
+	if strings.HasPrefix(value, `"`) {
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			return unquoted
+		}
+	}
+	return value
+}
+
+// checkRef rejects refs git would take for an option.
+func checkRef(ref string) error {
+	if ref == "" || strings.HasPrefix(ref, "-") {
+		return fmt.Errorf("invalid git ref %q", ref)
+	}
+	return nil
+}
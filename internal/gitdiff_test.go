@@ -0,0 +1,63 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestChangedFilesAndDiffs(t *testing.T) {
+	dir := gitRepo(t)
+	if err := os.Remove(filepath.Join(dir, "héllo.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := ChangedFiles(context.Background(), dir, "HEAD~1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(changed, []string{"b.go"}) {
+		t.Errorf("ChangedFiles(HEAD~1) = %v, want [b.go] (deleted files left out)", changed)
+	}
+	if changed, err = ChangedFiles(context.Background(), dir, "HEAD~1", "héllo.go"); err != nil || len(changed) != 0 {
+		t.Errorf("ChangedFiles(HEAD~1, héllo.go) = %v, %v; want none", changed, err)
+	}
+
+	diffs, err := FileDiffs(context.Background(), dir, "HEAD~1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := diffs["b.go"]; !strings.HasPrefix(diff, "diff --git a/b.go b/b.go\n") || !strings.HasSuffix(diff, "\n+\n+var b = 1\n") {
+		t.Errorf("FileDiffs()[b.go] = %q", diff)
+	}
+	if diff := diffs["héllo.go"]; !strings.Contains(diff, "-package main\n") {
+		t.Errorf("FileDiffs()[héllo.go] = %q, want the deletion under its old path", diff)
+	}
+
+	// Globs match as walker arguments do: "*" stays within a directory
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "c.go"), []byte("package sub\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runGit(context.Background(), dir, "add", "sub"); err != nil {
+		t.Fatal(err)
+	}
+	for glob, want := range map[string][]string{
+		"*.go":     {"b.go"},
+		"**/*.go":  {"b.go", "sub/c.go"},
+		"sub/*.go": {"sub/c.go"},
+	} {
+		if changed, err = ChangedFiles(context.Background(), dir, "HEAD~1", glob); err != nil || !slices.Equal(changed, want) {
+			t.Errorf("ChangedFiles(HEAD~1, %q) = %v, %v; want %v", glob, changed, err, want)
+		}
+	}
+
+	if _, err := ChangedFiles(context.Background(), dir, "--output=x"); err == nil {
+		t.Error("ChangedFiles(--output=x) succeeded, want an invalid ref error")
+	}
+}
@@ -0,0 +1,199 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// githubAPI is the base URL of a GitHub REST API: api.github.com, or a
+// GitHub Enterprise server's /api/v3.
+type githubAPI string
+
+// githubAPIFor returns the API of the GitHub server at host: github.com,
+// or a GitHub Enterprise server named in GH_HOST (several may be given,
+// separated by commas). Other hosts are rejected, as requests carry the
+// user's GitHub token.
+func githubAPIFor(host string) (githubAPI, error) {
+	host = strings.ToLower(host)
+	if host == "github.com" || host == "www.github.com" {
+		return "https://api.github.com", nil
+	}
+	for _, allowed := range strings.Split(os.Getenv("GH_HOST"), ",") {
+		if strings.EqualFold(strings.TrimSpace(allowed), host) {
+			return githubAPI("https://" + host + "/api/v3"), nil
+		}
+	}
+	return "", fmt.Errorf("%s is not github.com; set GH_HOST=%s to use it as a GitHub Enterprise server", host, host)
+}
+
+// get fetches an API path and decodes its JSON into v.
+func (api githubAPI) get(ctx context.Context, apiPath string, v any) error {
+	data, err := api.request(ctx, apiPath, "application/vnd.github+json")
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid response from %s: %w", apiPath, err)
+	}
+	return nil
+}
+
+// getPages fetches every page of an API list, 100 items at a time.
+func getPages[T any](ctx context.Context, api githubAPI, apiPath string) ([]T, error) {
+	var all []T
+	for page := 1; ; page++ {
+		var items []T
+		if err := api.get(ctx, fmt.Sprintf("%s?per_page=100&page=%d", apiPath, page), &items); err != nil {
+			return all, err
+		}
+		all = append(all, items...)
+		if len(items) < 100 {
+			return all, nil
+		}
+	}
+}
+
+// raw fetches the raw content of an API contents path.
+func (api githubAPI) raw(ctx context.Context, apiPath string) ([]byte, error) {
+	return api.request(ctx, apiPath, "application/vnd.github.raw")
+}
+
+// request makes a GET request to the API, authenticated with GITHUB_TOKEN
+// or GH_TOKEN if either is set.
+func (api githubAPI) request(ctx context.Context, apiPath, accept string) ([]byte, error) {
+	rawURL := string(api) + apiPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if token := githubToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		status := &StatusError{URL: rawURL, Status: resp.Status, Code: resp.StatusCode, RetryAfter: retryAfter(resp.Header, time.Now())}
+		if (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusUnauthorized) && githubToken() == "" {
+			return nil, fmt.Errorf("%w (set GITHUB_TOKEN for a private repository)", status)
+		}
+		return nil, status
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// githubToken returns the token to authenticate to GitHub with, if any.
+func githubToken() string {
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		return token
+	}
+	return os.Getenv("GH_TOKEN")
+}
+
+// escapePath escapes each element of a slash-separated path for a URL.
+func escapePath(p string) string {
+	parts := strings.Split(path.Clean(p), "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+// threadComment is a comment on an issue or pull request, a review, or a
+// review comment on a line of a pull request's diff.
+type threadComment struct {
+	Body string `json:"body"`
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	State     string `json:"state"`          // reviews
+	Path      string `json:"path"`           // review comments
+	Line      int    `json:"line"`           // review comments
+	InReplyTo int    `json:"in_reply_to_id"` // review comments
+}
+
+// thread is the conversation on an issue or pull request.
+type thread struct {
+	conversation   []threadComment
+	reviews        []threadComment
+	reviewComments []threadComment
+}
+
+// fetchThread fetches the conversation on an issue or pull request, with
+// the reviews and review comments too for a pull request.
+func fetchThread(ctx context.Context, api githubAPI, owner, repo string, number int, pull bool) (thread, error) {
+	type list struct {
+		path     string
+		comments *[]threadComment
+	}
+	var t thread
+	lists := []list{{fmt.Sprintf("/repos/%s/%s/issues/%d/comments", owner, repo, number), &t.conversation}}
+	if pull {
+		lists = append(lists,
+			list{fmt.Sprintf("/repos/%s/%s/pulls/%d/reviews", owner, repo, number), &t.reviews},
+			list{fmt.Sprintf("/repos/%s/%s/pulls/%d/comments", owner, repo, number), &t.reviewComments},
+		)
+	}
+	for _, list := range lists {
+		var err error
+		if *list.comments, err = getPages[threadComment](ctx, api, list.path); err != nil {
+			return t, err
+		}
+	}
+	return t, nil
+}
+
+// write renders the conversation, reviews, and review comments, each in
+// order under a heading of the given level ("##").
+func (t thread) write(buf *strings.Builder, level string) {
+	if len(t.conversation) > 0 {
+		fmt.Fprintf(buf, "\n%s Conversation\n", level)
+		for _, c := range t.conversation {
+			fmt.Fprintf(buf, "\n**@%s:**\n\n%s\n", c.User.Login, strings.TrimSpace(c.Body))
+		}
+	}
+	var reviewed []threadComment
+	for _, r := range t.reviews {
+		if strings.TrimSpace(r.Body) != "" || r.State == "APPROVED" || r.State == "CHANGES_REQUESTED" {
+			reviewed = append(reviewed, r)
+		}
+	}
+	if len(reviewed) > 0 {
+		fmt.Fprintf(buf, "\n%s Reviews\n", level)
+		for _, r := range reviewed {
+			state := strings.ToLower(strings.ReplaceAll(r.State, "_", " "))
+			fmt.Fprintf(buf, "\n**@%s (%s):**\n", r.User.Login, state)
+			if body := strings.TrimSpace(r.Body); body != "" {
+				fmt.Fprintf(buf, "\n%s\n", body)
+			}
+		}
+	}
+	if len(t.reviewComments) > 0 {
+		fmt.Fprintf(buf, "\n%s Review comments\n", level)
+		for _, c := range t.reviewComments {
+			where := c.Path
+			if c.Line > 0 {
+				where += ":" + strconv.Itoa(c.Line)
+			}
+			reply := ""
+			if c.InReplyTo != 0 {
+				reply = " (reply)"
+			}
+			fmt.Fprintf(buf, "\n**@%s on %s%s:**\n\n%s\n", c.User.Login, where, reply, strings.TrimSpace(c.Body))
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// goplsCommand is the program the gopls queries run.
+var goplsCommand = "gopls"
+
+// Location is a position in a file of a pack, as gopls reports it.
+type Location struct {
+	Path   string // relative to the root, with forward slashes
+	Line   int    // 1-based
+	Column int    // 1-based, in bytes
+}
+
+// HasGopls reports whether gopls is installed.
+func HasGopls() bool {
+	_, err := exec.LookPath(goplsCommand)
+	return err == nil
+}
+
+// GoplsReferences asks gopls for the references to the identifier at a
+// position in a file under root, including its declaration. gopls
+// type-checks the packages involved, so unlike FindUses it isn't fooled
+// by local variables or methods of the same name. References outside root
+// are left out.
+func GoplsReferences(root string, at Location) ([]Location, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	pos := fmt.Sprintf("%s:%d:%d", filepath.Join(abs, filepath.FromSlash(at.Path)), at.Line, at.Column)
+	cmd := exec.Command(goplsCommand, "references", "-d", pos)
+	cmd.Dir = abs
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("gopls: %s", msg)
+		}
+		return nil, fmt.Errorf("gopls: %w", err)
+	}
+	return parseGoplsLocations(string(out), abs), nil
+}
+
+// goplsLocation matches a location gopls prints: path:line:col, with the
+// end column after a dash.
+var goplsLocation = regexp.MustCompile(`^(.+):(\d+):(\d+)(?:-\d+)?$`)
+
+// parseGoplsLocations parses gopls's output, one location per line, into
+// the locations under root.
+func parseGoplsLocations(out, root string) []Location {
+	var locations []Location
+	for _, line := range strings.Split(out, "\n") {
+		m := goplsLocation.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		rel, err := filepath.Rel(root, m[1])
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		lineNum, _ := strconv.Atoi(m[2])
+		column, _ := strconv.Atoi(m[3])
+		locations = append(locations, Location{Path: filepath.ToSlash(rel), Line: lineNum, Column: column})
+	}
+	return locations
+}
+
+// FindUsesLSP is FindUses with the references found by gopls rather than
+// from the syntax: the declaration is found as FindUses finds it, then
+// gopls lists every reference to it. Files with references that aren't in
+// the pack, ignored ones for example, are left out.
+func FindUsesLSP(files []File, root, symbol string, enclosing bool) (SymbolUses, error) {
+	sources := parseGoSources(files)
+	decl, err := resolveSymbol(sources, root, symbol)
+	if err != nil {
+		return SymbolUses{}, err
+	}
+	var at Location
+	for _, src := range sources {
+		if decls := decl.decls[src.index]; len(decls) > 0 {
+			ident := declaredIdent(decls[0], decl.ref)
+			pos := src.fset.Position(ident.Pos())
+			at = Location{Path: src.path, Line: pos.Line, Column: pos.Column}
+			break
+		}
+	}
+	locations, err := GoplsReferences(root, at)
+	if err != nil {
+		return SymbolUses{}, err
+	}
+
+	lines := make(map[string][]int)
+	for _, loc := range locations {
+		if loc != at {
+			lines[loc.Path] = append(lines[loc.Path], loc.Line)
+		}
+	}
+	uses := SymbolUses{Package: decl.pkg}
+	var declaring, referring []File
+	for _, src := range sources {
+		found := lines[src.path]
+		if len(found) == 0 && len(decl.decls[src.index]) == 0 {
+			continue
+		}
+		uses.References += len(found)
+
+		file := files[src.index]
+		if enclosing {
+			keep := slices.Clone(decl.decls[src.index])
+			for _, d := range src.file.Decls {
+				start, end := src.fset.Position(d.Pos()).Line, src.fset.Position(d.End()).Line
+				if slices.ContainsFunc(found, func(line int) bool { return line >= start && line <= end }) && !slices.Contains(keep, d) {
+					keep = append(keep, d)
+				}
+			}
+			file.Content = keepDecls(src, keep)
+		}
+		if len(decl.decls[src.index]) > 0 {
+			declaring = append(declaring, file)
+		} else {
+			referring = append(referring, file)
+		}
+	}
+	uses.Files = append(declaring, referring...)
+	return uses, nil
+}
+
+// declaredIdent returns the name a declaration declares the symbol with.
+func declaredIdent(decl ast.Decl, ref symbolRef) *ast.Ident {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		return decl.Name
+	case *ast.GenDecl:
+		for _, spec := range decl.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				if spec.Name.Name == ref.name {
+					return spec.Name
+				}
+			case *ast.ValueSpec:
+				for _, ident := range spec.Names {
+					if ident.Name == ref.name {
+						return ident
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ExpandFocusLSP adds to a focus the files gopls finds referring to the
+// top-level declarations of its target, a Go file, methods included, which
+// FindFocus can't see from the syntax alone. The files added count as
+// dependents.
+func ExpandFocusLSP(focus Focus, files []File, root string) (Focus, error) {
+	target := filepath.ToSlash(focus.Files[0].Path)
+	if path.Ext(target) != ".go" {
+		return focus, nil
+	}
+	sources := parseGoSources(focus.Files[:1])
+	if len(sources) == 0 {
+		return focus, nil
+	}
+	src := sources[0]
+
+	included := make(map[string]bool)
+	for _, file := range focus.Files {
+		included[filepath.ToSlash(file.Path)] = true
+	}
+	referring := make(map[string]bool)
+	for _, ident := range topLevelIdents(src.file) {
+		pos := src.fset.Position(ident.Pos())
+		locations, err := GoplsReferences(root, Location{Path: target, Line: pos.Line, Column: pos.Column})
+		if err != nil {
+			return focus, err
+		}
+		for _, loc := range locations {
+			referring[loc.Path] = true
+		}
+	}
+
+	for _, file := range files {
+		name := filepath.ToSlash(file.Path)
+		if referring[name] && !included[name] && file.DuplicateOf == "" {
+			included[name] = true
+			focus.Files = append(focus.Files, file)
+			focus.Dependents++
+		}
+	}
+	return focus, nil
+}
+
+// topLevelIdents returns the names of a file's top-level declarations,
+// methods included, but not init, main, or blank names.
+func topLevelIdents(file *ast.File) []*ast.Ident {
+	var idents []*ast.Ident
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv != nil || decl.Name.Name != "init" && decl.Name.Name != "main" {
+				idents = append(idents, decl.Name)
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					idents = append(idents, spec.Name)
+				case *ast.ValueSpec:
+					for _, id := range spec.Names {
+						if id.Name != "_" {
+							idents = append(idents, id)
+						}
+					}
+				}
+			}
+		}
+	}
+	return idents
+}
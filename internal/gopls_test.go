@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// fakeGopls replaces goplsCommand with a script that answers every
+// references query with refs, locations relative to the directory it's run
+// in.
+func fakeGopls(t *testing.T, refs string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	script := "#!/bin/sh\n[ \"$1\" = references ] || exit 2\nfor ref in " + refs + "; do echo \"$PWD/$ref\"; done\n"
+	fake := filepath.Join(t.TempDir(), "gopls")
+	if err := os.WriteFile(fake, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	old := goplsCommand
+	goplsCommand = fake
+	t.Cleanup(func() { goplsCommand = old })
+}
+
+func TestParseGoplsLocations(t *testing.T) {
+	root := filepath.FromSlash("/src/app")
+	out := filepath.FromSlash("/src/app/store/store.go") + ":7:6-9\n" +
+		filepath.FromSlash("/src/app/cmd/main.go") + ":6:13\n" +
+		filepath.FromSlash("/src/other/x.go") + ":1:1-2\n" +
+		"gopls: some warning\n"
+	want := []Location{{"store/store.go", 7, 6}, {"cmd/main.go", 6, 13}}
+	if got := parseGoplsLocations(out, root); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGoplsLocations() = %v, want %v", got, want)
+	}
+}
+
+func TestFindUsesLSP(t *testing.T) {
+	root := t.TempDir()
+	fakeGopls(t, "store/store.go:4:6-9 cmd/main.go:6:7-10 cmd/main.go:7:2-3 ../elsewhere.go:1:1")
+	files := []File{
+		{Path: "cmd/main.go", Content: []byte("package main\n\nimport \"store\"\n\nfunc main() {\n\ts := store.New()\n\ts.Get()\n}\n\nfunc other() {}\n")},
+		{Path: "store/store.go", Content: []byte("package store\n\n// New returns a Store.\nfunc New() *Store { return &Store{} }\n")},
+		{Path: "web/web.go", Content: []byte("package web\n")},
+	}
+
+	uses, err := FindUsesLSP(files, root, "store.New", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, file := range uses.Files {
+		paths = append(paths, file.Path)
+	}
+	if want := []string{"store/store.go", "cmd/main.go"}; !reflect.DeepEqual(paths, want) || uses.References != 2 {
+		t.Errorf("FindUsesLSP() = %q, %d references; want %q, 2", paths, uses.References, want)
+	}
+	if want := "package main\n\n// line 5\nfunc main() {\n\ts := store.New()\n\ts.Get()\n}\n"; string(uses.Files[1].Content) != want {
+		t.Errorf("enclosing declarations = %q, want %q", uses.Files[1].Content, want)
+	}
+}
+
+func TestExpandFocusLSP(t *testing.T) {
+	root := t.TempDir()
+	fakeGopls(t, "store/store.go:4:6-9 api/api.go:9:3-6")
+	files := []File{
+		{Path: "store/store.go", Content: []byte("package store\n\ntype Store struct{}\n\nfunc (s *Store) Get() {}\n")},
+		{Path: "api/api.go", Content: []byte("package api\n")},
+		{Path: "web/web.go", Content: []byte("package web\n")},
+	}
+
+	focus, err := ExpandFocusLSP(Focus{Files: files[:1]}, files, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(focus.Files) != 2 || focus.Files[1].Path != "api/api.go" || focus.Dependents != 1 {
+		t.Errorf("ExpandFocusLSP() = %d files, %d dependents; want api/api.go added", len(focus.Files), focus.Dependents)
+	}
+}
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"bytes"
+	"go/build"
+	"io"
+	"path"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// knownOS and knownArch list the GOOS and GOARCH values recognized in Go
+// build tags and file name suffixes.
+var (
+	knownOS = []string{
+		"aix", "android", "darwin", "dragonfly", "freebsd", "hurd", "illumos", "ios", "js",
+		"linux", "nacl", "netbsd", "openbsd", "plan9", "solaris", "wasip1", "windows", "zos",
+	}
+	knownArch = []string{
+		"386", "amd64", "amd64p32", "arm", "armbe", "arm64", "arm64be", "loong64", "mips",
+		"mipsle", "mips64", "mips64le", "mips64p32", "mips64p32le", "ppc", "ppc64", "ppc64le",
+		"riscv", "riscv64", "s390", "s390x", "sparc", "sparc64", "wasm",
+	}
+)
+
+// GoBuildContext returns a build context for tags such as "linux,amd64":
+// values naming an operating system or architecture select GOOS and GOARCH
+// (defaulting to the host's), "cgo" enables cgo, and the rest are custom
+// build tags.
+func GoBuildContext(tags []string) build.Context {
+	ctx := build.Context{
+		GOOS:        runtime.GOOS,
+		GOARCH:      runtime.GOARCH,
+		Compiler:    "gc",
+		ReleaseTags: build.Default.ReleaseTags,
+	}
+	for _, tag := range tags {
+		switch {
+		case tag == "":
+		case slices.Contains(knownOS, tag):
+			ctx.GOOS = tag
+		case slices.Contains(knownArch, tag):
+			ctx.GOARCH = tag
+		case tag == "cgo":
+			ctx.CgoEnabled = true
+		default:
+			ctx.BuildTags = append(ctx.BuildTags, tag)
+		}
+	}
+	return ctx
+}
+
+// matchesGoBuild reports whether a Go file would be compiled in ctx, going
+// by its //go:build constraints and _GOOS/_GOARCH file name suffixes.
+// Files other than Go sources always match.
+func matchesGoBuild(ctx build.Context, name string, content []byte) bool {
+	name = strings.ReplaceAll(name, `\`, "/")
+	if !strings.HasSuffix(name, ".go") {
+		return true
+	}
+	ctx.OpenFile = func(string) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(content)), nil
+	}
+	match, err := ctx.MatchFile(path.Dir(name), path.Base(name))
+	// Keep files whose constraints can't be read rather than guess
+	return match || err != nil
+}
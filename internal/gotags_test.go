@@ -0,0 +1,34 @@
+package internal
+
+import "testing"
+
+func TestMatchesGoBuild(t *testing.T) {
+	tests := []struct {
+		tags    []string
+		name    string
+		content string
+		want    bool
+	}{
+		{[]string{"linux", "amd64"}, "a.go", "package a\n", true},
+		{[]string{"linux", "amd64"}, "sys_linux.go", "package a\n", true},
+		{[]string{"linux", "amd64"}, "sys_windows.go", "package a\n", false},
+		{[]string{"linux", "amd64"}, "sys_linux_arm64.go", "package a\n", false},
+		{[]string{"linux", "amd64"}, "pkg/asm_amd64.go", "package a\n", true},
+		{[]string{"linux", "amd64"}, "a.go", "//go:build darwin\n\npackage a\n", false},
+		{[]string{"linux", "amd64"}, "a.go", "//go:build unix && !arm64\n\npackage a\n", true},
+		{[]string{"linux", "amd64"}, "a.go", "//go:build integration\n\npackage a\n", false},
+		{[]string{"linux", "integration"}, "a.go", "//go:build integration\n\npackage a\n", true},
+		{[]string{"windows"}, "a.go", "//go:build cgo\n\npackage a\n", false},
+		{[]string{"windows", "cgo"}, "a.go", "//go:build cgo\n\npackage a\n", true},
+		{[]string{"windows"}, "a.go", "//go:build go1.1\n\npackage a\n", true},
+		{[]string{"windows"}, "README.md", "//go:build linux\n", true},
+		{[]string{"windows"}, `pkg\x_linux.go`, "package a\n", false},
+	}
+
+	for _, tt := range tests {
+		ctx := GoBuildContext(tt.tags)
+		if got := matchesGoBuild(ctx, tt.name, []byte(tt.content)); got != tt.want {
+			t.Errorf("matchesGoBuild(%q, %q, %q) = %v, want %v", tt.tags, tt.name, tt.content, got, tt.want)
+		}
+	}
+}
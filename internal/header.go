@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// FileHeaderData is what a file header template can refer to.
+type FileHeaderData struct {
+	Path     string
+	URL      string // the file's permalink, if --permalinks added one
+	Language string // as DetectLanguage names it, such as "Go"
+	Lines    int
+	Bytes    int
+	Tokens   int    // estimated, as FileTokens counts them
+	Mode     string // the file's permission bits, as in "0755", with --file-modes
+}
+
+// ParseFileHeader parses a file header template, such as
+// "### {{.Path}} ({{.Lines}} lines)", which is executed with a
+// FileHeaderData for each file. The header must fit on one line. An empty
+// text yields a nil template, for the default header.
+func ParseFileHeader(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	if strings.ContainsAny(text, "\r\n") {
+		return nil, fmt.Errorf("invalid file_header: must be a single line")
+	}
+	tmpl, err := template.New("file_header").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file_header: %w", err)
+	}
+	// Catch references to fields that don't exist before any file is packed
+	if err := tmpl.Execute(new(bytes.Buffer), FileHeaderData{}); err != nil {
+		return nil, fmt.Errorf("invalid file_header: %w", err)
+	}
+	return tmpl, nil
+}
+
+// fileModeField returns a file's mode as FileModeString renders it, or ""
+// if it has none.
+func fileModeField(file File) string {
+	if file.Mode == 0 {
+		return ""
+	}
+	return FileModeString(file.Mode)
+}
+
+// fileHeader renders the header line of a file from the template, without
+// a trailing newline.
+func fileHeader(tmpl *template.Template, file File) string {
+	var buf strings.Builder
+	tmpl.Execute(&buf, FileHeaderData{
+		Path:     file.Path,
+		URL:      file.URL,
+		Language: DetectLanguage(file.Path, file.Content).Name,
+		Lines:    countLines(file.Content),
+		Bytes:    len(file.Content),
+		Tokens:   FileTokens(file),
+		Mode:     fileModeField(file),
+	}) // checked against FileHeaderData by ParseFileHeader
+	return strings.TrimRight(buf.String(), "\r\n")
+}
@@ -0,0 +1,54 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFileHeader(t *testing.T) {
+	tests := []struct {
+		text    string
+		wantErr string
+	}{
+		{"", ""},
+		{"### {{.Path}} ({{.Lines}} lines)", ""},
+		{"{{.Path}} {{.URL}} {{.Language}} {{.Bytes}} {{.Tokens}}", ""},
+		{"{{.Name}}", "can't evaluate field Name"},
+		{"{{.Path", "unclosed action"},
+		{"{{.Path}}\n{{.Lines}}", "single line"},
+	}
+	for _, tt := range tests {
+		_, err := ParseFileHeader(tt.text)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("ParseFileHeader(%q) error = %v", tt.text, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("ParseFileHeader(%q) error = %v, want %q", tt.text, err, tt.wantErr)
+		}
+	}
+}
+
+func TestFormatFileHeader(t *testing.T) {
+	tmpl, err := ParseFileHeader("### {{.Path}} ({{.Lines}} lines, {{.Language}})")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []File{{Path: "main.go", Content: []byte("package main\n\nfunc main() {}\n")}}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatText, "### main.go (3 lines, Go)\npackage main\n"},
+		{FormatMarkdown, "### main.go (3 lines, Go)\n\n```go\npackage main\n"},
+		{FormatDiff, "File: main.go\npackage main\n"},
+	}
+	for _, tt := range tests {
+		formatter := NewFormatter(files)
+		formatter.OutputFormat = tt.format
+		formatter.FileHeader = tmpl
+		if got := formatter.Format(); !strings.HasPrefix(got, tt.want) {
+			t.Errorf("Format(%s) =\n%s\nwant prefix\n%s", tt.format, got, tt.want)
+		}
+	}
+}
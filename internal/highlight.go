@@ -0,0 +1,207 @@
+package internal
+
+import (
+	"html"
+	"strings"
+)
+
+// syntax describes a language closely enough to color its tokens.
+type syntax struct {
+	lineComments []string
+	blockComment [2]string // opening and closing delimiters, if any
+	quotes       string    // characters that open strings
+	multiline    string    // quotes whose strings may span lines
+	keywords     map[string]bool
+}
+
+// words builds a keyword set.
+func words(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(list) {
+		set[word] = true
+	}
+	return set
+}
+
+var (
+	cSyntax = syntax{
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       `"'`,
+		keywords: words(`auto break case char const continue default do double else enum extern float for goto if
+			inline int long register return short signed sizeof static struct switch typedef union unsigned void volatile while
+			bool class namespace template typename public private protected virtual new delete this try catch throw using
+			nullptr true false override final constexpr`),
+	}
+	jsSyntax = syntax{
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+		multiline:    "`",
+		keywords: words(`async await break case catch class const continue debugger default delete do else export
+			extends false finally for from function if import in instanceof let new null of return static super switch this
+			throw true try typeof undefined var void while yield interface type enum implements private public protected
+			readonly as any string number boolean unknown never`),
+	}
+	jvmSyntax = syntax{
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       `"'`,
+		keywords: words(`abstract boolean break byte case catch char class const continue default do double else enum
+			extends final finally float for if implements import instanceof int interface long native new null package private
+			protected public return short static super switch synchronized this throw throws try void volatile while true false
+			var val fun object when is in override data sealed companion def trait`),
+	}
+	hashSyntax = syntax{
+		lineComments: []string{"#"},
+		quotes:       `"'`,
+		keywords: words(`if then else elif fi for while do done case esac in function return local export echo exit
+			set unset readonly shift break continue`),
+	}
+)
+
+// syntaxes maps code fence tags (see Language) to their syntax.
+var syntaxes = map[string]syntax{
+	"go": {
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+		multiline:    "`",
+		keywords: words(`break case chan const continue default defer else fallthrough for func go goto if import
+			interface map package range return select struct switch type var nil true false iota`),
+	},
+	"python": {
+		lineComments: []string{"#"},
+		quotes:       `"'`,
+		keywords: words(`False None True and as assert async await break class continue def del elif else except
+			finally for from global if import in is lambda nonlocal not or pass raise return try while with yield self`),
+	},
+	"ruby": {
+		lineComments: []string{"#"},
+		quotes:       `"'`,
+		keywords: words(`alias and begin break case class def defined? do else elsif end ensure false for if in module
+			next nil not or redo rescue retry return self super then true undef unless until when while yield require`),
+	},
+	"rust": {
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       `"`,
+		keywords: words(`as async await break const continue crate dyn else enum extern false fn for if impl in let
+			loop match mod move mut pub ref return self Self static struct super trait true type unsafe use where while`),
+	},
+	"sql": {
+		lineComments: []string{"--"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       `'"`,
+		keywords: words(`select from where and or not insert into values update set delete create table index drop
+			alter join left right inner outer on group by order having limit as null primary key references SELECT FROM
+			WHERE AND OR NOT INSERT INTO VALUES UPDATE SET DELETE CREATE TABLE INDEX DROP ALTER JOIN LEFT RIGHT INNER OUTER ON
+			GROUP BY ORDER HAVING LIMIT AS NULL PRIMARY KEY REFERENCES`),
+	},
+	"javascript": jsSyntax, "typescript": jsSyntax, "jsx": jsSyntax, "tsx": jsSyntax,
+	"java": jvmSyntax, "kotlin": jvmSyntax, "scala": jvmSyntax, "groovy": jvmSyntax,
+	"c": cSyntax, "cpp": cSyntax, "csharp": cSyntax,
+	"bash": hashSyntax, "yaml": {lineComments: []string{"#"}, quotes: `"'`}, "toml": {lineComments: []string{"#"}, quotes: `"'`},
+	"makefile": hashSyntax, "dockerfile": hashSyntax,
+}
+
+// highlight renders content as HTML, with comments, strings, numbers, and
+// keywords wrapped in spans of class "c", "s", "n", and "k". Languages it
+// doesn't know are only escaped. It's a lexer, not a parser, so the odd
+// token may be miscolored; the text is always exact.
+func highlight(language Language, content string) string {
+	syn, ok := syntaxes[language.Fence]
+	if !ok {
+		return html.EscapeString(content)
+	}
+
+	var out strings.Builder
+	out.Grow(len(content) + len(content)/4)
+	span := func(class, text string) {
+		out.WriteString(`<span class="` + class + `">`)
+		out.WriteString(html.EscapeString(text))
+		out.WriteString("</span>")
+	}
+
+	for i := 0; i < len(content); {
+		rest := content[i:]
+		c := content[i]
+
+		// Comments
+		if open := syn.blockComment[0]; open != "" && strings.HasPrefix(rest, open) {
+			end := strings.Index(rest[len(open):], syn.blockComment[1])
+			if end < 0 {
+				end = len(rest)
+			} else {
+				end += len(open) + len(syn.blockComment[1])
+			}
+			span("c", rest[:end])
+			i += end
+			continue
+		}
+		if hasAnyPrefix(rest, syn.lineComments) {
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			span("c", rest[:end])
+			i += end
+			continue
+		}
+
+		// Strings, ending at the closing quote or, for most quotes, the line
+		if strings.IndexByte(syn.quotes, c) >= 0 {
+			multiline := strings.IndexByte(syn.multiline, c) >= 0
+			end := 1
+			for end < len(rest) && rest[end] != c && (multiline || rest[end] != '\n') {
+				if rest[end] == '\\' && !multiline && end+1 < len(rest) {
+					end++
+				}
+				end++
+			}
+			if end < len(rest) && rest[end] == c {
+				end++
+			}
+			span("s", rest[:end])
+			i += end
+			continue
+		}
+
+		// Words and numbers
+		if isWordByte(c) {
+			end := 1
+			for end < len(rest) && (isWordByte(rest[end]) || rest[end] == '.' && c >= '0' && c <= '9') {
+				end++
+			}
+			switch word := rest[:end]; {
+			case c >= '0' && c <= '9':
+				span("n", word)
+			case syn.keywords[word]:
+				span("k", word)
+			default:
+				out.WriteString(word)
+			}
+			i += end
+			continue
+		}
+
+		out.WriteString(html.EscapeString(string(c)))
+		i++
+	}
+	return out.String()
+}
+
+// hasAnyPrefix reports whether s starts with any of prefixes.
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isWordByte reports whether b can be part of an identifier or number.
+func isWordByte(b byte) bool {
+	return b == '_' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b >= 0x80
+}
@@ -0,0 +1,42 @@
+package internal
+
+import "testing"
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			"a.go",
+			"func f() string { return `a<b` + \"c\\\"d\" } // x < y\n",
+			`<span class="k">func</span> f() string { <span class="k">return</span> <span class="s">` + "`a&lt;b`" + `</span> + <span class="s">&#34;c\&#34;d&#34;</span> } <span class="c">// x &lt; y</span>` + "\n",
+		},
+		{
+			"a.py",
+			"def f(x):\n    return x + 1.5  # 'not a string'\n",
+			`<span class="k">def</span> f(x):` + "\n    " + `<span class="k">return</span> x + <span class="n">1.5</span>  <span class="c"># &#39;not a string&#39;</span>` + "\n",
+		},
+		{
+			"a.c",
+			"/* a\n * b */ int x_1;\n",
+			"<span class=\"c\">/* a\n * b */</span> <span class=\"k\">int</span> x_1;\n",
+		},
+		{
+			"a.js",
+			"let s = 'unterminated\nlet t\n",
+			`<span class="k">let</span> s = <span class="s">&#39;unterminated</span>` + "\n" + `<span class="k">let</span> t` + "\n",
+		},
+		{
+			"notes.txt",
+			"if <b> & func\n",
+			"if &lt;b&gt; &amp; func\n",
+		},
+	}
+	for _, tt := range tests {
+		if got := highlight(DetectLanguage(tt.name, nil), tt.content); got != tt.want {
+			t.Errorf("highlight(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// HistoryEntry records one pack run, so the same context can be produced
+// again later.
+type HistoryEntry struct {
+	Time   time.Time `json:"time"`
+	Dir    string    `json:"dir"`    // working directory the command ran in
+	Args   []string  `json:"args"`   // command-line arguments, without the program name
+	Files  int       `json:"files"`  // files packed
+	Tokens int       `json:"tokens"` // estimated tokens of the pack
+	Bytes  int       `json:"bytes"`
+	Output string    `json:"output"` // where the pack went: files, clipboard, stdout, or a command
+}
+
+// HistoryFile returns the file pack runs are recorded in: gopack/history.jsonl
+// under the user's cache directory (~/.cache on Linux), unless the
+// GOPACK_HISTORY environment variable names another.
+func HistoryFile() (string, error) {
+	if path := os.Getenv("GOPACK_HISTORY"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gopack", "history.jsonl"), nil
+}
+
+// AppendHistory adds an entry to the history file at path, one JSON object
+// per line, creating the file if needed.
+func AppendHistory(path string, entry HistoryEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// ReadHistory reads the entries of the history file at path, oldest first.
+// A missing file has no entries.
+func ReadHistory(path string) ([]HistoryEntry, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNum, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gopack", "history.jsonl")
+
+	entries, err := ReadHistory(path)
+	if err != nil || entries != nil {
+		t.Fatalf("ReadHistory(missing) = %v, %v; want no entries", entries, err)
+	}
+
+	want := []HistoryEntry{
+		{Time: time.Date(2026, 3, 3, 9, 30, 0, 0, time.UTC), Dir: "/src/app", Args: []string{".", "-f", "markdown"}, Files: 12, Tokens: 3456, Bytes: 13824, Output: "stdout"},
+		{Time: time.Date(2026, 3, 4, 17, 5, 0, 0, time.UTC), Dir: "/src/app", Args: []string{"-o", "ctx.md"}, Files: 3, Tokens: 90, Bytes: 360, Output: "ctx.md"},
+	}
+	for _, entry := range want {
+		if err := AppendHistory(path, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := ReadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.EqualFunc(got, want, func(a, b HistoryEntry) bool {
+		return a.Time.Equal(b.Time) && a.Dir == b.Dir && slices.Equal(a.Args, b.Args) &&
+			a.Files == b.Files && a.Tokens == b.Tokens && a.Bytes == b.Bytes && a.Output == b.Output
+	}) {
+		t.Errorf("ReadHistory() = %+v, want %+v", got, want)
+	}
+
+	if err := os.WriteFile(path, []byte("{not json\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadHistory(path); err == nil {
+		t.Error("ReadHistory(corrupt) succeeded, want error")
+	}
+}
+
+func TestHistoryFileEnv(t *testing.T) {
+	t.Setenv("GOPACK_HISTORY", "/tmp/packs.jsonl")
+	if got, err := HistoryFile(); err != nil || got != "/tmp/packs.jsonl" {
+		t.Errorf("HistoryFile() = %q, %v; want /tmp/packs.jsonl", got, err)
+	}
+}
@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// htmlStyle is the stylesheet embedded in HTML output, which needs no
+// other files or scripts.
+const htmlStyle = `body{margin:0;display:flex;font:14px/1.5 system-ui,sans-serif;color:#1f2328}
+nav{position:sticky;top:0;height:100vh;overflow:auto;min-width:16em;max-width:24em;padding:1em;box-sizing:border-box;background:#f6f8fa;border-right:1px solid #d0d7de}
+nav ul{list-style:none;margin:0;padding-left:1em}nav>ul{padding:0}nav a{color:inherit;text-decoration:none}nav a:hover{text-decoration:underline}
+main{flex:1;min-width:0;padding:1em 2em}
+section>details{margin:0 0 1em;border:1px solid #d0d7de;border-radius:6px}
+section>details>summary{padding:.4em .8em;background:#f6f8fa;font-family:ui-monospace,monospace;cursor:pointer}
+pre{margin:0;padding:.8em;overflow:auto;font:13px/1.45 ui-monospace,SFMono-Regular,Menlo,monospace}
+.k{color:#cf222e}.s{color:#0a3069}.c{color:#6e7781;font-style:italic}.n{color:#0550ae}
+@media(prefers-color-scheme:dark){body{background:#0d1117;color:#e6edf3}nav,section>details>summary{background:#161b22}
+nav,section>details{border-color:#30363d}.k{color:#ff7b72}.s{color:#a5d6ff}.c{color:#8b949e}.n{color:#79c0ff}}
+`
+
+// htmlTail closes the document opened by writeHTMLHead.
+const htmlTail = "</main>\n</body>\n</html>\n"
+
+// writeHTMLHead opens an HTML document with a collapsible tree of the files
+// linking to each of them.
+func (f *Formatter) writeHTMLHead(w io.Writer) {
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n%s</style>\n</head>\n<body>\n",
+		html.EscapeString(f.htmlTitle()), htmlStyle)
+
+	io.WriteString(w, "<nav>\n")
+	root := &htmlDir{}
+	for i, file := range f.files {
+		root.add(strings.Split(strings.ReplaceAll(file.Path, `\`, "/"), "/"), i)
+	}
+	root.write(w)
+	io.WriteString(w, "</nav>\n<main>\n")
+}
+
+// htmlTitle names the document after the part and file count.
+func (f *Formatter) htmlTitle() string {
+	title := fmt.Sprintf("Pack of %s files", FormatWithCommas(len(f.files)))
+	if f.Parts > 1 {
+		title += fmt.Sprintf(" (part %d/%d)", f.Part, f.Parts)
+	}
+	return title
+}
+
+// htmlDir is a directory in the file tree of HTML output.
+type htmlDir struct {
+	name    string
+	dirs    []*htmlDir
+	entries []any // subdirectories and files in the order first seen
+}
+
+// htmlFile is a file in the tree, linking to the file's section.
+type htmlFile struct {
+	name  string
+	index int
+}
+
+// add places the file with the given path components and index.
+func (d *htmlDir) add(parts []string, index int) {
+	if len(parts) == 1 {
+		d.entries = append(d.entries, htmlFile{name: parts[0], index: index})
+		return
+	}
+	for _, dir := range d.dirs {
+		if dir.name == parts[0] {
+			dir.add(parts[1:], index)
+			return
+		}
+	}
+	dir := &htmlDir{name: parts[0]}
+	d.dirs = append(d.dirs, dir)
+	d.entries = append(d.entries, dir)
+	dir.add(parts[1:], index)
+}
+
+// write renders the directory's entries as a list, each subdirectory
+// collapsible.
+func (d *htmlDir) write(w io.Writer) {
+	io.WriteString(w, "<ul>\n")
+	for _, entry := range d.entries {
+		switch entry := entry.(type) {
+		case *htmlDir:
+			fmt.Fprintf(w, "<li><details open><summary>%s/</summary>\n", html.EscapeString(entry.name))
+			entry.write(w)
+			io.WriteString(w, "</details></li>\n")
+		case htmlFile:
+			fmt.Fprintf(w, "<li><a href=\"#f%d\">%s</a></li>\n", entry.index, html.EscapeString(entry.name))
+		}
+	}
+	io.WriteString(w, "</ul>\n")
+}
+
+// htmlFileLink renders a file's path for an HTML heading, linked to its
+// URL if it has one.
+func htmlFileLink(file File) string {
+	if file.URL == "" {
+		return html.EscapeString(file.Path)
+	}
+	return fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(file.URL), html.EscapeString(file.Path))
+}
+
+// writeHTML writes each file as a collapsible section holding its
+// highlighted content.
+func (f *Formatter) writeHTML(w io.Writer) {
+	for i, file := range f.files {
+		fmt.Fprintf(w, "<section id=\"f%d\"><details open><summary>%s%s</summary>\n", i, htmlFileLink(file), modeSuffix(file))
+		if file.DuplicateOf != "" || file.Omitted {
+			fmt.Fprintf(w, "<p>%s</p>\n", html.EscapeString(strings.TrimSpace(string(file.Content))))
+		} else {
+			language := DetectLanguage(file.Path, file.Content)
+			fmt.Fprintf(w, "<pre><code class=\"language-%s\">%s</code></pre>\n", language.Fence, highlight(language, string(file.Content)))
+		}
+		io.WriteString(w, "</details></section>\n")
+	}
+}
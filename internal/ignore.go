@@ -0,0 +1,392 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreFiles are the per-directory files that ignore patterns are read
+// from. Later files take precedence, so .gopackignore can refine .gitignore
+// (for example re-including a file with a "!" pattern).
+var ignoreFiles = []string{".gitignore", ".gopackignore"}
+
+// ignoreRule is a single ignore pattern and where it came from.
+type ignoreRule struct {
+	pattern string // as written, for provenance reports
+	source  string // e.g. ".gitignore:3", "--ignore-pattern", "built-in default"
+	ignorePattern
+}
+
+// newIgnoreRule compiles a pattern into a rule.
+func newIgnoreRule(pattern, source string) ignoreRule {
+	return ignoreRule{pattern: pattern, source: source, ignorePattern: compilePattern(pattern)}
+}
+
+// ignorePattern is a gitignore pattern compiled for matching, so patterns
+// are parsed once rather than for every path.
+type ignorePattern struct {
+	segments []string // slash-separated globs; "**" matches any number
+	folded   []string // segments in lower case, for IgnoreCase
+	anchored bool     // matched from the ignore file's directory, not at any depth
+	dirOnly  bool     // only matches directories (a trailing "/")
+	negate   bool     // re-includes paths ("!pattern")
+}
+
+// negated reports whether the rule re-includes paths ("!pattern").
+func (p ignorePattern) negated() bool {
+	return p.negate
+}
+
+// compilePattern parses a gitignore pattern. As in git, a leading "!"
+// negates it, a trailing "/" restricts it to directories, and a slash
+// anywhere else anchors it to the ignore file's directory; otherwise it
+// matches a file or directory name at any depth. A backslash escapes a
+// leading "!" or "#", or any character of the globs. Character classes
+// take git's syntax, as in "[!a-z]" and "[[:digit:]]".
+func compilePattern(pattern string) ignorePattern {
+	var p ignorePattern
+	pattern = NormalizePath(pattern)
+	if rest, ok := strings.CutPrefix(pattern, "!"); ok {
+		p.negate, pattern = true, rest
+	} else if strings.HasPrefix(pattern, `\!`) || strings.HasPrefix(pattern, `\#`) {
+		pattern = pattern[1:]
+	}
+	if rest, ok := strings.CutSuffix(pattern, "/"); ok {
+		p.dirOnly, pattern = true, rest
+	}
+	p.anchored = strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	p.segments = strings.Split(pattern, "/")
+	for i, segment := range p.segments {
+		p.segments[i] = translateClasses(segment)
+	}
+	p.folded = make([]string, len(p.segments))
+	for i, segment := range p.segments {
+		p.folded[i] = strings.ToLower(segment)
+	}
+	return p
+}
+
+// posixClasses are the character classes git's globs name, as in
+// "[[:digit:]]", written as path.Match ranges.
+var posixClasses = map[string]string{
+	"alnum":  "0-9A-Za-z",
+	"alpha":  "A-Za-z",
+	"blank":  " \t",
+	"cntrl":  "\x00-\x1f\x7f",
+	"digit":  "0-9",
+	"graph":  "!-~",
+	"lower":  "a-z",
+	"print":  " -~",
+	"punct":  "!-/:-@\\[-`{-~",
+	"space":  " \t\n\v\f\r",
+	"upper":  "A-Z",
+	"xdigit": "0-9A-Fa-f",
+}
+
+// translateClasses rewrites the character classes of a glob in git's
+// syntax into path.Match's: "[!...]" negates a class as "[^...]" does, a
+// "]" first in a class is literal, and "[:name:]" inside one is a POSIX
+// class. A class that isn't closed, or names an unknown POSIX class, is
+// left as it is, so the glob matches nothing, as in git.
+func translateClasses(glob string) string {
+	if !strings.Contains(glob, "[") {
+		return glob
+	}
+	var out strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '\\':
+			out.WriteByte(c)
+			if i+1 < len(glob) {
+				i++
+				out.WriteByte(glob[i])
+			}
+		case '[':
+			class, n, ok := translateClass(glob[i+1:])
+			if !ok {
+				return glob
+			}
+			out.WriteString(class)
+			i += n
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String()
+}
+
+// translateClass translates the character class opening a glob, after its
+// "[", returning it with its brackets and the length it took in the glob.
+func translateClass(glob string) (string, int, bool) {
+	var out strings.Builder
+	out.WriteByte('[')
+	i := 0
+	if i < len(glob) && (glob[i] == '!' || glob[i] == '^') {
+		out.WriteByte('^')
+		i++
+	}
+	if i < len(glob) && glob[i] == ']' {
+		out.WriteString(`\]`)
+		i++
+	}
+	for ; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case c == ']':
+			out.WriteByte(']')
+			return out.String(), i + 1, true
+		case c == '\\' && i+1 < len(glob):
+			out.WriteString(glob[i : i+2])
+			i++
+		case strings.HasPrefix(glob[i:], "[:"):
+			end := strings.Index(glob[i+2:], ":]")
+			if end < 0 {
+				return "", 0, false
+			}
+			ranges, ok := posixClasses[glob[i+2:i+2+end]]
+			if !ok {
+				return "", 0, false
+			}
+			out.WriteString(ranges)
+			i += 2 + end + 1
+		case c == '^':
+			out.WriteString(`\^`)
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return "", 0, false
+}
+
+// match reports whether a path, split into its slash-separated parts, is
+// matched by the pattern, either itself or through a parent directory.
+// isDir tells whether the path itself is a directory; its parents always
+// are.
+func (p ignorePattern) match(parts []string, isDir, ignoreCase bool) bool {
+	segments := p.segments
+	if ignoreCase {
+		segments = p.folded
+	}
+
+	if !p.anchored {
+		for i, part := range parts {
+			if (isDir || !p.dirOnly || i < len(parts)-1) && globMatch(segments[0], part) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for n := len(parts); n > 0; n-- {
+		if (isDir || !p.dirOnly || n < len(parts)) && matchSegments(segments, parts[:n]) {
+			return true
+		}
+	}
+	return false
+}
+
+// globMatch matches a single path segment against a glob.
+func globMatch(pattern, name string) bool {
+	matched, _ := path.Match(pattern, name)
+	return matched
+}
+
+// key identifies the rule among all the walker's rules.
+func (r ignoreRule) key() string {
+	return r.source + "\x00" + r.pattern
+}
+
+// String describes the rule for provenance reports.
+func (r ignoreRule) String() string {
+	if strings.HasPrefix(r.source, "--") {
+		return fmt.Sprintf("%s %q", r.source, r.pattern)
+	}
+	return fmt.Sprintf("%s pattern %q", r.source, r.pattern)
+}
+
+// loadIgnoreFiles loads patterns from the ignore files in the directory,
+// and linguist attributes from its .gitattributes.
+func (w *Walker) loadIgnoreFiles(dirPath string) {
+	w.loadAttributes(dirPath)
+	var rules []ignoreRule
+	for _, name := range w.IgnoreFiles {
+		rules = append(rules, w.readIgnoreFile(filepath.Join(dirPath, name))...)
+	}
+
+	if len(rules) > 0 {
+		w.patterns[dirPath] = rules
+	}
+}
+
+// readIgnoreFile reads the patterns in a single ignore file.
+func (w *Walker) readIgnoreFile(path string) []ignoreRule {
+	file, err := w.open(path)
+	if err != nil {
+		return nil // File doesn't exist or can't be read
+	}
+	defer file.Close()
+
+	name, err := filepath.Rel(w.rootPath, path)
+	if err != nil {
+		name = path
+	}
+	name = filepath.ToSlash(name)
+
+	return parseIgnoreFile(file, name, isDockerignore(path))
+}
+
+// parseIgnoreFile reads the patterns in an ignore file, naming each rule's
+// source after the file and line. Docker's rules apply if docker is set.
+func parseIgnoreFile(r io.Reader, name string, docker bool) []ignoreRule {
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := trimTrailingSpace(strings.TrimSuffix(scanner.Text(), "\r"))
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := newIgnoreRule(line, fmt.Sprintf("%s:%d", name, lineNum))
+		if docker {
+			rule.ignorePattern = compilePattern(dockerPattern(line))
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// isDockerignore reports whether an ignore file follows Docker's rules
+// rather than git's.
+func isDockerignore(path string) bool {
+	return strings.HasSuffix(filepath.Base(path), ".dockerignore")
+}
+
+// dockerPattern rewrites a .dockerignore pattern as the gitignore pattern
+// that matches the same paths. Docker cleans each pattern and matches it
+// from the build context's root, so "*.log" only matches there and "dist/"
+// matches a file named dist too.
+func dockerPattern(pattern string) string {
+	negate := ""
+	if rest, ok := strings.CutPrefix(pattern, "!"); ok {
+		negate, pattern = "!", rest
+	}
+	pattern = path.Clean(strings.TrimPrefix(pattern, "/"))
+	return negate + "/" + pattern
+}
+
+// trimTrailingSpace removes trailing spaces from an ignore file line,
+// except those escaped with a backslash, which are kept escaped for the
+// globs, as git does. Tabs aren't trimmed.
+func trimTrailingSpace(line string) string {
+	end := len(line)
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case ' ':
+			if end == len(line) {
+				end = i
+			}
+			continue
+		case '\\':
+			i++
+		}
+		end = len(line)
+	}
+	return line[:end]
+}
+
+// ignoredBy returns the rule that excludes a path, if any. isDir tells
+// whether the path is a directory, which patterns ending in "/" require.
+func (w *Walker) ignoredBy(relPath string, isDir bool) (ignoreRule, bool) {
+	rule, ok := w.decidingRule(relPath, isDir)
+	if !ok || rule.negated() {
+		return ignoreRule{}, false
+	}
+	return rule, true
+}
+
+// decidingRule returns the last ignore rule matching a path, which decides
+// whether it is ignored: a negated rule re-includes it. As in git, rules
+// from deeper ignore files take precedence over those from their parents,
+// and later rules in a file over earlier ones. Built-in defaults have the
+// lowest precedence and --ignore-pattern the highest. Paths and patterns
+// are compared in Unicode NFC, so accented names match however the file
+// system spells them.
+func (w *Walker) decidingRule(relPath string, isDir bool) (ignoreRule, bool) {
+	relPath = NormalizePath(filepath.ToSlash(relPath))
+
+	var decided ignoreRule
+	var found bool
+	apply := func(rules []ignoreRule, path string) {
+		if len(rules) == 0 {
+			return
+		}
+		if w.IgnoreCase {
+			path = strings.ToLower(path)
+		}
+		parts := strings.Split(path, "/")
+		for _, rule := range rules {
+			if rule.match(parts, isDir, w.IgnoreCase) {
+				decided, found = rule, true
+				if w.matched != nil {
+					w.matched[rule.key()] = true
+				}
+			}
+		}
+	}
+
+	defaults, flags := w.defaultRules, w.flagRules
+
+	apply(defaults, relPath)
+
+	// Rules from each ignore file apply to paths below its directory,
+	// matched relative to it. A submodule starts afresh, as rules from the
+	// enclosing repository don't reach into it.
+	dir := ""
+	for rest := relPath; ; {
+		dirPath := filepath.Join(w.rootPath, filepath.FromSlash(dir))
+		if dir != "" && w.submodules[dirPath] {
+			decided, found = ignoreRule{}, false
+			apply(defaults, strings.TrimPrefix(relPath, dir))
+		}
+		apply(w.patterns[dirPath], strings.TrimPrefix(relPath, dir))
+
+		segment, remaining, ok := strings.Cut(rest, "/")
+		if !ok {
+			break
+		}
+		dir += segment + "/"
+		rest = remaining
+	}
+
+	apply(flags, relPath)
+
+	return decided, found
+}
+
+// compileRules compiles DefaultIgnores and IgnorePatterns, which may be
+// changed between walks.
+func (w *Walker) compileRules() {
+	w.defaultRules, w.flagRules = nil, nil
+	for _, pattern := range w.DefaultIgnores {
+		w.defaultRules = append(w.defaultRules, newIgnoreRule(pattern, "built-in default"))
+	}
+	for _, pattern := range w.IgnorePatterns {
+		w.flagRules = append(w.flagRules, newIgnoreRule(pattern, "--ignore-pattern"))
+	}
+}
+
+// excludedBy returns the first exclusion expression matching a path, if any.
+func (w *Walker) excludedBy(relPath string) (string, bool) {
+	relPath = filepath.ToSlash(relPath)
+	for _, re := range w.ExcludeRegexps {
+		if re.MatchString(relPath) {
+			return re.String(), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,254 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWalkIgnoreFiles(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string
+		ignoreFiles []string
+		want        []string
+	}{
+		{
+			name: "root gitignore",
+			files: map[string]string{
+				".gitignore": "*.log\n",
+				"a.go":       "x",
+				"a.log":      "x",
+				"sub/b.log":  "x",
+			},
+			want: []string{".gitignore", "a.go"},
+		},
+		{
+			name: "gopackignore negates gitignore",
+			files: map[string]string{
+				".gitignore":    "*.txt\n",
+				".gopackignore": "!keep.txt\n",
+				"keep.txt":      "x",
+				"drop.txt":      "x",
+			},
+			want: []string{".gitignore", ".gopackignore", "keep.txt"},
+		},
+		{
+			name: "nested rules apply relative to their directory",
+			files: map[string]string{
+				"sub/.gitignore":        "/only-here.go\n",
+				"sub/only-here.go":      "x",
+				"only-here.go":          "x",
+				"sub/deep/only-here.go": "x",
+			},
+			want: []string{"only-here.go", "sub/.gitignore", "sub/deep/only-here.go"},
+		},
+		{
+			name: "deeper file overrides parent",
+			files: map[string]string{
+				".gitignore":     "*.log\n",
+				"sub/.gitignore": "!keep.log\n",
+				"sub/keep.log":   "x",
+				"keep.log":       "x",
+			},
+			want: []string{".gitignore", "sub/.gitignore", "sub/keep.log"},
+		},
+		{
+			name: "later rule wins within a file",
+			files: map[string]string{
+				".gitignore": "!a.log\n*.log\n",
+				"a.log":      "x",
+			},
+			want: []string{".gitignore"},
+		},
+		{
+			name: "excluded directory can't be re-included into",
+			files: map[string]string{
+				".gitignore":    "build/\n!build/keep.go\n",
+				"build/keep.go": "x",
+			},
+			want: []string{".gitignore"},
+		},
+		{
+			name: "dockerignore replaces gitignore",
+			files: map[string]string{
+				".gitignore":    "*.md\n",
+				".dockerignore": "*.log\n/tmp/\n!keep.log\n",
+				"a.log":         "x",
+				"keep.log":      "x",
+				"sub/b.log":     "x", // Docker patterns are anchored to the context
+				"tmp":           "x", // a trailing "/" doesn't require a directory
+				"README.md":     "x",
+			},
+			ignoreFiles: []string{".dockerignore"},
+			want:        []string{".dockerignore", ".gitignore", "README.md", "keep.log", "sub/b.log"},
+		},
+		{
+			name: "later ignore file takes precedence",
+			files: map[string]string{
+				".gitignore": "*.txt\n",
+				".npmignore": "!keep.txt\n",
+				"keep.txt":   "x",
+				"drop.txt":   "x",
+			},
+			ignoreFiles: []string{".gitignore", ".npmignore"},
+			want:        []string{".gitignore", ".npmignore", "keep.txt"},
+		},
+		{
+			// As macOS names files, with the accent decomposed
+			name: "accented names match in either normalization form",
+			files: map[string]string{
+				".gitignore":            "caf\u00e9/\n",
+				"cafe\u0301/a.go":       "x",
+				"re\u0301sume\u0301.md": "x",
+			},
+			want: []string{".gitignore", "r\u00e9sum\u00e9.md"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(dir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			walker, err := NewWalker(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.ignoreFiles != nil {
+				walker.IgnoreFiles = tt.ignoreFiles
+			}
+			files, err := walker.Walk()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, file := range files {
+				got = append(got, filepath.ToSlash(file.Path))
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Walk() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnorePattern(t *testing.T) {
+	tests := []struct {
+		path    string
+		isDir   bool
+		pattern string
+		want    bool
+	}{
+		{"dist", true, "dist/", true},
+		{"dist", false, "dist/", false},
+		{"web/dist", true, "dist/", true},
+		{"web/dist", false, "dist/", false},
+		{"dist/app.js", false, "dist/", true}, // inside a dist directory
+		{"coverage", false, "coverage/", false},
+		{"a.log", false, "*.log", true},
+		{"sub/a.log", false, "*.log", true},
+		{"sub/a.log", false, "/a.log", false},
+		{"a.log", false, "/a.log", true},
+		{"docs/api", true, "docs/api/", true},
+		{"docs/api", false, "docs/api/", false},
+		{"docs/api", false, "docs/api", true},
+		{"docs/api/x.go", false, "docs/api", true}, // inside an anchored directory
+		{"web/docs/api", true, "docs/api", false},  // anchored, so not at depth
+		{"src/main.go", false, "main.go", true},
+		{"src/main.go", false, "*.txt", false},
+		{"a/b/c/foo", false, "**/foo", true},
+		{"foo", false, "**/foo", true},
+		{"foo/x/y.go", false, "foo/**", true},
+		{"a/b", false, "a/**/b", true},
+		{"a/x/y/b", false, "a/**/b", true},
+		{"a/x/y/c", false, "a/**/b", false},
+		{"a/*.go", false, "a/\\*.go", true}, // escaped wildcard
+		{"a/b.go", false, "a/\\*.go", false},
+		{"!important", false, "\\!important", true},
+		{"#notes", false, "\\#notes", true},
+		{"#notes.txt", false, "\\#notes.txt", true},
+		{"my notes.txt", false, "my\\ notes.txt", true},
+		{"name ", false, "name\\ ", true},
+		{"name", false, "name\\ ", false},
+		{"Debug", true, "[Dd]ebug/", true},
+		{"src/debug", true, "[Dd]ebug/", true},
+		{"xebug", true, "[Dd]ebug/", false},
+		{"a.o", false, "*.[oa]", true},
+		{"a.c", false, "*.[!oa]", true},
+		{"a.o", false, "*.[!oa]", false},
+		{"a.o", false, "*.[^oa]", false},
+		{"log7", false, "log[[:digit:]]", true},
+		{"logs", false, "log[[:digit:]]", false},
+		{"V2", false, "[[:upper:]][0-9]", true},
+		{"]x", false, "[]]x", true},
+		{"ax", false, "[!]]x", true},
+		{"]x", false, "[!]]x", false},
+		{"a^", false, "a[x^]", true},
+		{"[x", false, "[x", false}, // an unclosed class matches nothing
+		{"a7", false, "a[[:nope:]]", false},
+	}
+
+	for _, tt := range tests {
+		parts := strings.Split(tt.path, "/")
+		if got := compilePattern(tt.pattern).match(parts, tt.isDir, false); got != tt.want {
+			t.Errorf("match(%q, isDir=%v, %q) = %v, want %v", tt.path, tt.isDir, tt.pattern, got, tt.want)
+		}
+	}
+
+	// Negation and case folding
+	if p := compilePattern("!*.md"); !p.negated() || !p.match([]string{"README.md"}, false, false) {
+		t.Errorf("compilePattern(!*.md) = %+v, want a negated pattern matching README.md", p)
+	}
+	if compilePattern("Build/").match([]string{"build"}, true, false) {
+		t.Error("Build/ matched build case-sensitively")
+	}
+	if !compilePattern("Build/").match([]string{"build"}, true, true) {
+		t.Error("Build/ didn't match build with ignoreCase")
+	}
+}
+
+func TestDockerPattern(t *testing.T) {
+	tests := map[string]string{
+		"*.log":           "/*.log",
+		"/build/":         "/build",
+		"!docs/./x.md":    "!/docs/x.md",
+		"**/node_modules": "/**/node_modules",
+	}
+	for pattern, want := range tests {
+		if got := dockerPattern(pattern); got != want {
+			t.Errorf("dockerPattern(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
+
+func TestTrimTrailingSpace(t *testing.T) {
+	tests := map[string]string{
+		"*.log":      "*.log",
+		"*.log  ":    "*.log",
+		"*.log \t":   "*.log \t", // git only trims spaces
+		"name\\ ":    "name\\ ",
+		"name\\   ":  "name\\ ",
+		"name\\\\ ":  "name\\\\", // an escaped backslash, then a space
+		"  lead":     "  lead",
+		"a\\b":       "a\\b",
+		"trailing\\": "trailing\\",
+	}
+	for line, want := range tests {
+		if got := trimTrailingSpace(line); got != want {
+			t.Errorf("trimTrailingSpace(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+)
+
+// importStyle describes how a language writes imports.
+type importStyle struct {
+	statement *regexp.Regexp // the first line of an import statement
+	comment   string         // line comment marker for the placeholder
+}
+
+var (
+	cStyleImports  = importStyle{regexp.MustCompile(`^#\s*include\s`), "//"}
+	jsStyleImports = importStyle{regexp.MustCompile(`^(import[\s{"'*]|export\s+(\*|\{[^}]*\}?)\s*(from\b|$)|(const|let|var)\s+.*=\s*require\()`), "//"}
+	jvmImports     = importStyle{regexp.MustCompile(`^import\s`), "//"}
+)
+
+// importStyles maps code fence tags (see Language) to their import style.
+// Go is handled separately.
+var importStyles = map[string]importStyle{
+	"python":     {regexp.MustCompile(`^(import|from)\s+\S`), "#"},
+	"ruby":       {regexp.MustCompile(`^require(_relative)?[\s(]`), "#"},
+	"rust":       {regexp.MustCompile(`^(pub(\([^)]*\))?\s+)?use\s`), "//"},
+	"javascript": jsStyleImports,
+	"typescript": jsStyleImports,
+	"jsx":        jsStyleImports,
+	"tsx":        jsStyleImports,
+	"java":       jvmImports,
+	"kotlin":     jvmImports,
+	"scala":      jvmImports,
+	"c":          cStyleImports,
+	"cpp":        cStyleImports,
+}
+
+// CollapseImports replaces import blocks longer than maxLines lines with a
+// placeholder counting the imports, such as "import ( ...24 imports... )"
+// in Go or "// ...24 imports..." elsewhere, since long import lists are
+// rarely needed and add up across many files. It returns the updated files
+// and the number of blocks collapsed.
+func CollapseImports(files []File, maxLines int) ([]File, int) {
+	result := make([]File, len(files))
+	total := 0
+	for i, file := range files {
+		result[i] = file
+		content, n := collapseImports(file.Path, file.Content, maxLines)
+		if n > 0 {
+			result[i].Content = content
+			total += n
+		}
+	}
+	return result, total
+}
+
+// collapseImports collapses the long import blocks in one file.
+func collapseImports(name string, content []byte, maxLines int) ([]byte, int) {
+	language := DetectLanguage(name, content)
+	if language == goLang {
+		return collapseGoImports(content, maxLines)
+	}
+	style, ok := importStyles[language.Fence]
+	if !ok {
+		return content, 0
+	}
+
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	var out bytes.Buffer
+	collapsed := 0
+	for i := 0; i < len(lines); {
+		if !style.statement.Match(lines[i]) {
+			out.Write(lines[i])
+			i++
+			continue
+		}
+
+		// A run of import statements, which may span lines and be separated
+		// by blank lines
+		end, last, count := i, i, 0
+		for end < len(lines) {
+			if len(bytes.TrimSpace(lines[end])) == 0 {
+				end++
+				continue
+			}
+			if !style.statement.Match(lines[end]) {
+				break
+			}
+			count++
+			depth := 0
+			for {
+				depth += bracketDepth(lines[end])
+				end++
+				if depth <= 0 || end == len(lines) {
+					break
+				}
+			}
+			last = end
+		}
+
+		if last-i > maxLines {
+			fmt.Fprintf(&out, "%s ...%d imports...\n", style.comment, count)
+			collapsed++
+		} else {
+			for _, line := range lines[i:last] {
+				out.Write(line)
+			}
+		}
+		i = last
+	}
+	return out.Bytes(), collapsed
+}
+
+// collapseGoImports collapses Go's parenthesized import declarations.
+func collapseGoImports(content []byte, maxLines int) ([]byte, int) {
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	var out bytes.Buffer
+	collapsed := 0
+	for i := 0; i < len(lines); i++ {
+		if string(bytes.TrimSpace(lines[i])) != "import (" {
+			out.Write(lines[i])
+			continue
+		}
+		end := i + 1
+		for end < len(lines) && string(bytes.TrimSpace(lines[end])) != ")" {
+			end++
+		}
+		if end == len(lines) || end-i-1 <= maxLines {
+			out.Write(lines[i])
+			continue
+		}
+
+		count := 0
+		for _, line := range lines[i+1 : end] {
+			if line := bytes.TrimSpace(line); len(line) > 0 && !bytes.HasPrefix(line, []byte("//")) {
+				count++
+			}
+		}
+		fmt.Fprintf(&out, "import ( ...%d imports... )\n", count)
+		collapsed++
+		i = end
+	}
+	return out.Bytes(), collapsed
+}
+
+// bracketDepth returns how many more brackets a line opens than it closes.
+func bracketDepth(line []byte) int {
+	depth := 0
+	for _, b := range line {
+		switch b {
+		case '(', '{', '[':
+			depth++
+		case ')', '}', ']':
+			depth--
+		}
+	}
+	return depth
+}
@@ -0,0 +1,49 @@
+package internal
+
+import "testing"
+
+func TestCollapseImports(t *testing.T) {
+	tests := []struct {
+		path    string
+		content string
+		want    string
+	}{
+		{
+			"a.go",
+			"package a\n\nimport (\n\t\"fmt\"\n\t\"os\"\n\n\t// local\n\t\"a/b\"\n)\n\nfunc f() {}\n",
+			"package a\n\nimport ( ...3 imports... )\n\nfunc f() {}\n",
+		},
+		{
+			"b.go",
+			"package a\n\nimport (\n\t\"fmt\"\n)\n",
+			"package a\n\nimport (\n\t\"fmt\"\n)\n",
+		},
+		{
+			"a.py",
+			"import os\nimport sys\n\nfrom a import (\n    b,\n    c,\n)\n\ndef f():\n    import json\n",
+			"# ...3 imports...\n\ndef f():\n    import json\n",
+		},
+		{
+			"a.ts",
+			"import { a } from './a';\nimport {\n  b,\n} from './b';\nconst x = 1;\n",
+			"// ...2 imports...\nconst x = 1;\n",
+		},
+		{
+			"a.c",
+			"#include <stdio.h>\n#include <stdlib.h>\n#include \"a.h\"\n#include \"b.h\"\nint main() {}\n",
+			"// ...4 imports...\nint main() {}\n",
+		},
+		{
+			"a.swift",
+			"import a\nimport b\nimport c\nimport d\n",
+			"import a\nimport b\nimport c\nimport d\n",
+		},
+	}
+
+	for _, tt := range tests {
+		files, _ := CollapseImports([]File{{Path: tt.path, Content: []byte(tt.content)}}, 3)
+		if got := string(files[0].Content); got != tt.want {
+			t.Errorf("%s: CollapseImports() = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// issueURL matches links to an issue or a pull request.
+var issueURL = regexp.MustCompile(`^https?://([^/]+)/([^/]+)/([^/]+)/(issues|pull)/(\d+)(?:[/?#].*)?$`)
+
+// Thread is the discussion of an issue or pull request, which Formatter
+// writes before the files.
+type Thread struct {
+	Title   string // such as "Issue #12: Crash on empty input"
+	URL     string
+	Content string // Markdown: who opened it, its description, and the comments
+}
+
+// FetchIssue fetches the discussion of a GitHub issue or pull request from
+// its URL: the title, description, and comments, and for a pull request
+// the reviews and review comments too.
+func FetchIssue(ctx context.Context, rawURL string) (Thread, error) {
+	m := issueURL.FindStringSubmatch(rawURL)
+	if m == nil {
+		return Thread{}, fmt.Errorf("%q is not an issue or pull request URL (expected e.g. https://github.com/org/repo/issues/123)", rawURL)
+	}
+	api, err := githubAPIFor(m[1])
+	if err != nil {
+		return Thread{}, err
+	}
+	owner, repo := m[2], m[3]
+	number, _ := strconv.Atoi(m[5])
+	return fetchIssue(ctx, api, owner, repo, number)
+}
+
+// fetchIssue fetches the discussion of an issue or pull request.
+func fetchIssue(ctx context.Context, api githubAPI, owner, repo string, number int) (Thread, error) {
+	var issue struct {
+		Title   string `json:"title"`
+		Body    string `json:"body"`
+		State   string `json:"state"`
+		HTMLURL string `json:"html_url"`
+		User    struct {
+			Login string `json:"login"`
+		} `json:"user"`
+		Labels []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+		PullRequest *struct{} `json:"pull_request"`
+	}
+	if err := api.get(ctx, fmt.Sprintf("/repos/%s/%s/issues/%d", owner, repo, number), &issue); err != nil {
+		return Thread{}, err
+	}
+	pull := issue.PullRequest != nil
+	t, err := fetchThread(ctx, api, owner, repo, number, pull)
+	if err != nil {
+		return Thread{}, err
+	}
+
+	kind := "Issue"
+	if pull {
+		kind = "Pull request"
+	}
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "%s by @%s, %s", kind, issue.User.Login, issue.State)
+	if len(issue.Labels) > 0 {
+		var labels []string
+		for _, label := range issue.Labels {
+			labels = append(labels, label.Name)
+		}
+		fmt.Fprintf(&buf, ", labeled %s", strings.Join(labels, ", "))
+	}
+	buf.WriteString(".\n")
+	if body := strings.TrimSpace(issue.Body); body != "" {
+		fmt.Fprintf(&buf, "\n%s\n", body)
+	}
+	t.write(&buf, "###")
+
+	return Thread{
+		Title:   fmt.Sprintf("%s #%d: %s", kind, number, issue.Title),
+		URL:     issue.HTMLURL,
+		Content: buf.String(),
+	}, nil
+}
@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetchIssue(t *testing.T) {
+	responses := map[string]string{
+		"/repos/org/repo/issues/12": `{"title": "Crash on empty input", "body": "Run it with no args.", "state": "open",
+			"html_url": "https://github.com/org/repo/issues/12", "user": {"login": "alice"}, "labels": [{"name": "bug"}, {"name": "p1"}]}`,
+		"/repos/org/repo/issues/12/comments": `[{"user": {"login": "bob"}, "body": "Same here."}]`,
+		"/repos/org/repo/issues/5": `{"title": "Add retries", "body": "", "state": "closed",
+			"html_url": "https://github.com/org/repo/pull/5", "user": {"login": "carol"}, "pull_request": {}}`,
+		"/repos/org/repo/issues/5/comments": `[]`,
+		"/repos/org/repo/pulls/5/reviews":   `[{"user": {"login": "dan"}, "state": "APPROVED", "body": ""}]`,
+		"/repos/org/repo/pulls/5/comments":  `[]`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	api := githubAPI(server.URL)
+
+	tests := []struct {
+		number int
+		want   Thread
+	}{
+		{12, Thread{
+			Title:   "Issue #12: Crash on empty input",
+			URL:     "https://github.com/org/repo/issues/12",
+			Content: "Issue by @alice, open, labeled bug, p1.\n\nRun it with no args.\n\n### Conversation\n\n**@bob:**\n\nSame here.\n",
+		}},
+		{5, Thread{
+			Title:   "Pull request #5: Add retries",
+			URL:     "https://github.com/org/repo/pull/5",
+			Content: "Pull request by @carol, closed.\n\n### Reviews\n\n**@dan (approved):**\n",
+		}},
+	}
+	for _, tt := range tests {
+		got, err := fetchIssue(context.Background(), api, "org", "repo", tt.number)
+		if err != nil {
+			t.Fatalf("fetchIssue(%d) error = %v", tt.number, err)
+		}
+		if got != tt.want {
+			t.Errorf("fetchIssue(%d) = %+v, want %+v", tt.number, got, tt.want)
+		}
+	}
+
+	if _, err := FetchIssue(context.Background(), "https://github.com/org/repo/wiki/Home"); err == nil || !strings.Contains(err.Error(), "not an issue") {
+		t.Errorf("FetchIssue() of a wiki page error = %v", err)
+	}
+}
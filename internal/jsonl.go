@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// jsonLine is a file as written by FormatJSONL, one object per line.
+type jsonLine struct {
+	Path        string `json:"path"`
+	URL         string `json:"url,omitempty"`
+	Language    string `json:"language,omitempty"`
+	Tokens      int    `json:"tokens"`
+	DuplicateOf string `json:"duplicate_of,omitempty"`
+	Omitted     bool   `json:"omitted,omitempty"`
+	Mode        string `json:"mode,omitempty"`
+	Content     string `json:"content"`
+}
+
+// WriteJSONLine writes a file to w as a single line of JSON, as
+// FormatJSONL lays out each file: its path, permalink, language, token
+// estimate, and content.
+func WriteJSONLine(w io.Writer, file File) error {
+	line := jsonLine{
+		Path:        file.Path,
+		URL:         file.URL,
+		Tokens:      FileTokens(file),
+		DuplicateOf: file.DuplicateOf,
+		Omitted:     file.Omitted,
+		Mode:        fileModeField(file),
+		Content:     string(file.Content),
+	}
+	if language := DetectLanguage(file.Path, file.Content); language != unknownLanguage {
+		line.Language = language.Name
+	}
+	// Keep code readable: "<" and "&" needn't be escaped outside HTML
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(line); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeJSONL writes each file as a line of JSON.
+func (f *Formatter) writeJSONL(w io.Writer) {
+	for _, file := range f.files {
+		if err := WriteJSONLine(w, file); err != nil {
+			return
+		}
+	}
+}
+
+// parseJSONLPack parses the JSON Lines format, one file per line. Blank
+// lines are ignored.
+func parseJSONLPack(text string) ([]File, error) {
+	var files []File
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(nil, len(text)+1)
+	for n := 1; scanner.Scan(); n++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var line jsonLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		if line.Path == "" {
+			return nil, fmt.Errorf("line %d: missing path", n)
+		}
+		mode, err := parseFileMode(line.Mode)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		files = append(files, File{
+			Path:        line.Path,
+			Content:     []byte(line.Content),
+			URL:         line.URL,
+			DuplicateOf: line.DuplicateOf,
+			Omitted:     line.Omitted,
+			Mode:        mode,
+		})
+	}
+	return files, scanner.Err()
+}
@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteJSONLine(t *testing.T) {
+	tests := []struct {
+		name string
+		file File
+		want string
+	}{
+		{
+			name: "code",
+			file: File{Path: "main.go", Content: []byte("if a < b && c {\n")},
+			want: `{"path":"main.go","language":"Go","tokens":7,"content":"if a < b && c {\n"}` + "\n",
+		},
+		{
+			name: "unknown language",
+			file: File{Path: "notes", Content: []byte("x")},
+			want: `{"path":"notes","tokens":3,"content":"x"}` + "\n",
+		},
+		{
+			name: "duplicate with permalink",
+			file: File{Path: "b.txt", Content: []byte("[identical to a.txt]\n"), DuplicateOf: "a.txt", URL: "https://example.com/b.txt"},
+			want: `{"path":"b.txt","url":"https://example.com/b.txt","language":"Text","tokens":` +
+				`15,"duplicate_of":"a.txt","content":"[identical to a.txt]\n"}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf strings.Builder
+			if err := WriteJSONLine(&buf, tt.file); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("WriteJSONLine() = %s, want %s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJSONLPackErrors(t *testing.T) {
+	tests := []struct {
+		name, pack, want string
+	}{
+		{"invalid JSON", `{"path":"a.go","content":"x"}` + "\n{oops\n", "line 2"},
+		{"missing path", `{"path":"a.go","content":"x"}` + "\n\n" + `{"content":"y"}` + "\n", "line 3: missing path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePack([]byte(tt.pack))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParsePack() error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package internal
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+)
+
+// Language describes a detected language: a display name for reports and
+// the tag used on Markdown code fences.
+type Language struct {
+	Name  string
+	Fence string
+}
+
+// unknownLanguage is used when nothing more specific can be detected.
+var unknownLanguage = Language{Name: "Other"}
+
+// lang is shorthand for building the language tables.
+func lang(name, fence string) Language {
+	return Language{Name: name, Fence: fence}
+}
+
+var (
+	goLang         = lang("Go", "go")
+	pythonLang     = lang("Python", "python")
+	javaScriptLang = lang("JavaScript", "javascript")
+	typeScriptLang = lang("TypeScript", "typescript")
+	rubyLang       = lang("Ruby", "ruby")
+	shellLang      = lang("Shell", "bash")
+	cLang          = lang("C", "c")
+	cppLang        = lang("C++", "cpp")
+	yamlLang       = lang("YAML", "yaml")
+	markdownLang   = lang("Markdown", "markdown")
+	htmlLang       = lang("HTML", "html")
+	xmlLang        = lang("XML", "xml")
+	makefileLang   = lang("Makefile", "makefile")
+	dockerfileLang = lang("Dockerfile", "dockerfile")
+	textLang       = lang("Text", "text")
+)
+
+// languagesByExt maps lowercase file extensions to languages.
+var languagesByExt = map[string]Language{
+	".go":         goLang,
+	".mod":        lang("Go Module", "go"),
+	".py":         pythonLang,
+	".pyi":        pythonLang,
+	".js":         javaScriptLang,
+	".mjs":        javaScriptLang,
+	".cjs":        javaScriptLang,
+	".jsx":        lang("JSX", "jsx"),
+	".ts":         typeScriptLang,
+	".mts":        typeScriptLang,
+	".tsx":        lang("TSX", "tsx"),
+	".rb":         rubyLang,
+	".rs":         lang("Rust", "rust"),
+	".java":       lang("Java", "java"),
+	".kt":         lang("Kotlin", "kotlin"),
+	".kts":        lang("Kotlin", "kotlin"),
+	".scala":      lang("Scala", "scala"),
+	".groovy":     lang("Groovy", "groovy"),
+	".swift":      lang("Swift", "swift"),
+	".c":          cLang,
+	".h":          cLang,
+	".cc":         cppLang,
+	".cpp":        cppLang,
+	".cxx":        cppLang,
+	".hpp":        cppLang,
+	".cs":         lang("C#", "csharp"),
+	".php":        lang("PHP", "php"),
+	".lua":        lang("Lua", "lua"),
+	".pl":         lang("Perl", "perl"),
+	".r":          lang("R", "r"),
+	".dart":       lang("Dart", "dart"),
+	".ex":         lang("Elixir", "elixir"),
+	".exs":        lang("Elixir", "elixir"),
+	".erl":        lang("Erlang", "erlang"),
+	".hs":         lang("Haskell", "haskell"),
+	".clj":        lang("Clojure", "clojure"),
+	".zig":        lang("Zig", "zig"),
+	".sh":         shellLang,
+	".bash":       shellLang,
+	".zsh":        shellLang,
+	".fish":       lang("Fish", "fish"),
+	".ps1":        lang("PowerShell", "powershell"),
+	".bat":        lang("Batch", "batch"),
+	".sql":        lang("SQL", "sql"),
+	".proto":      lang("Protocol Buffers", "protobuf"),
+	".graphql":    lang("GraphQL", "graphql"),
+	".tf":         lang("Terraform", "hcl"),
+	".hcl":        lang("HCL", "hcl"),
+	".json":       lang("JSON", "json"),
+	".yaml":       yamlLang,
+	".yml":        yamlLang,
+	".toml":       lang("TOML", "toml"),
+	".ini":        lang("INI", "ini"),
+	".xml":        xmlLang,
+	".html":       htmlLang,
+	".htm":        htmlLang,
+	".css":        lang("CSS", "css"),
+	".scss":       lang("SCSS", "scss"),
+	".vue":        lang("Vue", "vue"),
+	".svelte":     lang("Svelte", "svelte"),
+	".md":         markdownLang,
+	".markdown":   markdownLang,
+	".rst":        lang("reStructuredText", "rst"),
+	".tex":        lang("TeX", "latex"),
+	".txt":        textLang,
+	".csv":        lang("CSV", "csv"),
+	".mk":         makefileLang,
+	".cmake":      lang("CMake", "cmake"),
+	".dockerfile": dockerfileLang,
+}
+
+// languagesByName maps well-known extensionless (or unusually named) files
+// to languages.
+var languagesByName = map[string]Language{
+	"makefile":       makefileLang,
+	"gnumakefile":    makefileLang,
+	"dockerfile":     dockerfileLang,
+	"containerfile":  dockerfileLang,
+	"jenkinsfile":    lang("Groovy", "groovy"),
+	"gemfile":        rubyLang,
+	"rakefile":       rubyLang,
+	"vagrantfile":    rubyLang,
+	"cmakelists.txt": lang("CMake", "cmake"),
+	".bashrc":        shellLang,
+	".bash_profile":  shellLang,
+	".zshrc":         shellLang,
+	".profile":       shellLang,
+	".gitignore":     lang("Ignore List", "gitignore"),
+	".gopackignore":  lang("Ignore List", "gitignore"),
+	".dockerignore":  lang("Ignore List", "gitignore"),
+	".gitattributes": lang("Git Attributes", "gitattributes"),
+	".editorconfig":  lang("INI", "ini"),
+	"license":        textLang,
+	"go.sum":         lang("Go Checksums", "text"),
+}
+
+// languagesByInterpreter maps shebang interpreters to languages.
+var languagesByInterpreter = map[string]Language{
+	"sh":      shellLang,
+	"bash":    shellLang,
+	"zsh":     shellLang,
+	"dash":    shellLang,
+	"ksh":     shellLang,
+	"python":  pythonLang,
+	"node":    javaScriptLang,
+	"deno":    typeScriptLang,
+	"ts-node": typeScriptLang,
+	"ruby":    rubyLang,
+	"perl":    lang("Perl", "perl"),
+	"php":     lang("PHP", "php"),
+	"lua":     lang("Lua", "lua"),
+	"fish":    lang("Fish", "fish"),
+	"make":    makefileLang,
+}
+
+// DetectLanguage determines a file's language from its name, falling back
+// to its shebang line or content for extensionless files such as scripts.
+func DetectLanguage(path string, content []byte) Language {
+	base := strings.ToLower(filepath.Base(filepath.FromSlash(path)))
+	if language, ok := languagesByName[base]; ok {
+		return language
+	}
+
+	// Variants such as Dockerfile.prod or Makefile.local
+	for _, prefix := range []string{"dockerfile", "makefile", "containerfile"} {
+		if strings.HasPrefix(base, prefix+".") {
+			return languagesByName[prefix]
+		}
+	}
+
+	if language, ok := languagesByExt[strings.ToLower(filepath.Ext(base))]; ok {
+		return language
+	}
+
+	if language, ok := detectShebang(content); ok {
+		return language
+	}
+	return sniffContent(content)
+}
+
+// detectShebang detects a script's language from its "#!" line.
+func detectShebang(content []byte) (Language, bool) {
+	if !bytes.HasPrefix(content, []byte("#!")) {
+		return Language{}, false
+	}
+	line, _, _ := bytes.Cut(content[2:], []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return Language{}, false
+	}
+
+	// "#!/usr/bin/env python3" names the interpreter as an argument
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") {
+				interpreter = field
+				break
+			}
+		}
+	}
+
+	// Strip version suffixes such as python3 or python3.12
+	interpreter = strings.TrimRight(interpreter, "0123456789.")
+	language, ok := languagesByInterpreter[interpreter]
+	return language, ok
+}
+
+// sniffContent recognizes a few formats from their opening bytes.
+func sniffContent(content []byte) Language {
+	head := bytes.ToLower(bytes.TrimSpace(content[:min(len(content), 512)]))
+	switch {
+	case bytes.HasPrefix(head, []byte("<?xml")):
+		return xmlLang
+	case bytes.HasPrefix(head, []byte("<!doctype html")), bytes.HasPrefix(head, []byte("<html")):
+		return htmlLang
+	case bytes.HasPrefix(head, []byte("<?php")):
+		return languagesByExt[".php"]
+	}
+	return unknownLanguage
+}
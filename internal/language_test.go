@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		path    string
+		content string
+		want    string
+	}{
+		{"main.go", "", "Go"},
+		{"go.mod", "", "Go Module"},
+		{"go.sum", "", "Go Checksums"},
+		{"web/App.TSX", "", "TSX"},
+		{"Dockerfile", "", "Dockerfile"},
+		{"Dockerfile.prod", "", "Dockerfile"},
+		{"Makefile.local", "", "Makefile"},
+		{"LICENSE", "", "Text"},
+		{".gitignore", "", "Ignore List"},
+		{"bin/deploy", "#!/bin/bash\necho hi\n", "Shell"},
+		{"bin/tool", "#!/usr/bin/env python3.12\n", "Python"},
+		{"bin/tool", "#!/usr/bin/env -S node --experimental\n", "JavaScript"},
+		{"bin/tool", "#!/usr/bin/unknown\n", "Other"},
+		{"data", "<?xml version=\"1.0\"?>\n<root/>\n", "XML"},
+		{"page", "<!DOCTYPE html>\n<html></html>\n", "HTML"},
+		{"notes", "just some words\n", "Other"},
+		{"", "", "Other"},
+	}
+
+	for _, tt := range tests {
+		if got := DetectLanguage(tt.path, []byte(tt.content)); got.Name != tt.want {
+			t.Errorf("DetectLanguage(%q, %q) = %q, want %q", tt.path, tt.content, got.Name, tt.want)
+		}
+	}
+}
+
+func TestFormatMarkdownFenceTags(t *testing.T) {
+	// Code fences are tagged with the detected language, found by name or
+	// shebang for files without an extension
+	files := []File{
+		{Path: "main.go", Content: []byte("package main\n")},
+		{Path: "Makefile", Content: []byte("all:\n\tgo build\n")},
+		{Path: "Dockerfile.prod", Content: []byte("FROM scratch\n")},
+		{Path: "bin/deploy", Content: []byte("#!/bin/bash\necho hi\n")},
+		{Path: "notes", Content: []byte("just some words\n")},
+	}
+	formatter := NewFormatter(files)
+	formatter.OutputFormat = FormatMarkdown
+	output := formatter.Format()
+	for path, fence := range map[string]string{
+		"main.go":         "```go\n",
+		"Makefile":        "```makefile\n",
+		"Dockerfile.prod": "```dockerfile\n",
+		"bin/deploy":      "```bash\n",
+		"notes":           "```\n",
+	} {
+		if want := "## File: " + path + "\n\n" + fence; !strings.Contains(output, want) {
+			t.Errorf("Format() = %q, want %s fenced as %q", output, path, fence)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Ways of handling Git LFS pointer files, for Walker.LFS.
+const (
+	LFSSkip    = "skip"
+	LFSPointer = "pointer"
+	LFSSmudge  = "smudge"
+)
+
+// LFSModes lists the accepted Walker.LFS values.
+var LFSModes = []string{LFSSkip, LFSPointer, LFSSmudge}
+
+// lfsPointerVersion is the first line of every Git LFS pointer file.
+const lfsPointerVersion = "version https://git-lfs.github.com/spec/v1"
+
+// lfsPointerMax bounds the size of pointer files; real ones are ~130 bytes.
+const lfsPointerMax = 1024
+
+// parseLFSPointer reports whether content is a Git LFS pointer file, and
+// if so the size of the object it stands for.
+func parseLFSPointer(content []byte) (size int64, ok bool) {
+	if len(content) > lfsPointerMax || !bytes.HasPrefix(content, []byte(lfsPointerVersion+"\n")) {
+		return 0, false
+	}
+	var oid bool
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), " ")
+		switch key {
+		case "oid":
+			oid = strings.HasPrefix(value, "sha256:")
+		case "size":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || n < 0 {
+				return 0, false
+			}
+			size, ok = n, true
+		}
+	}
+	if !ok || !oid {
+		return 0, false
+	}
+	return size, true
+}
+
+// lfsNote prefixes a pointer file with a note saying what it stands for,
+// so a model reading the pack doesn't mistake it for the real content.
+func lfsNote(content []byte, size int64) []byte {
+	note := fmt.Sprintf("[LFS object, %s]\n", FormatBytes(size))
+	return append([]byte(note), content...)
+}
+
+// lfsSmudge fetches the object a pointer file stands for with
+// "git lfs smudge", run in dir.
+func lfsSmudge(ctx context.Context, dir string, pointer []byte) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", "lfs", "smudge")
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(pointer)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if strings.Contains(msg, "'lfs' is not a git command") {
+			return nil, fmt.Errorf("--lfs smudge requires git-lfs to be installed")
+		}
+		if msg != "" {
+			return nil, fmt.Errorf("git lfs smudge: %s", msg)
+		}
+		return nil, fmt.Errorf("git lfs smudge: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const testPointer = "version https://git-lfs.github.com/spec/v1\noid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393\nsize 44040192\n"
+
+func TestParseLFSPointer(t *testing.T) {
+	tests := []struct {
+		content  string
+		wantSize int64
+		wantOK   bool
+	}{
+		{testPointer, 44040192, true},
+		{"version https://git-lfs.github.com/spec/v1\noid sha256:abc\next-0-foo sha256:def\nsize 12\n", 12, true},
+		{"version https://git-lfs.github.com/spec/v1\noid sha256:abc\n", 0, false},
+		{"version https://git-lfs.github.com/spec/v1\nsize 12\n", 0, false},
+		{"version https://git-lfs.github.com/spec/v1\noid sha256:abc\nsize -1\n", 0, false},
+		{"package main\n", 0, false},
+		{testPointer + strings.Repeat("x", 2000), 0, false},
+	}
+	for _, tt := range tests {
+		size, ok := parseLFSPointer([]byte(tt.content))
+		if size != tt.wantSize || ok != tt.wantOK {
+			t.Errorf("parseLFSPointer(%.40q) = %d, %v, want %d, %v", tt.content, size, ok, tt.wantSize, tt.wantOK)
+		}
+	}
+}
+
+func TestWalkLFS(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"model.bin": testPointer, "main.go": "package main\n"})
+
+	tests := []struct {
+		mode    string
+		want    []string
+		content string
+	}{
+		{"", []string{"main.go"}, ""},
+		{LFSSkip, []string{"main.go"}, ""},
+		{LFSPointer, []string{"main.go", "model.bin"}, "[LFS object, 42 MB]\n" + testPointer},
+	}
+	for _, tt := range tests {
+		walker, err := NewWalker(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		walker.LFS = tt.mode
+		files, err := walker.Walk()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, file := range files {
+			got = append(got, filepath.ToSlash(file.Path))
+			if file.Path == "model.bin" && string(file.Content) != tt.content {
+				t.Errorf("LFS=%q: model.bin = %q, want %q", tt.mode, file.Content, tt.content)
+			}
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Walk(LFS=%q) = %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+}
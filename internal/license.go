@@ -0,0 +1,182 @@
+package internal
+
+import (
+	"bytes"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// LicenseHeader is a license or copyright header that StripLicenseHeaders
+// removed from several files.
+type LicenseHeader struct {
+	Text  string // the header without comment markers
+	Files int    // how many files it was removed from
+}
+
+var (
+	licenseWords = regexp.MustCompile(`(?i)copyright|licen[cs]ed?|spdx-license-identifier`)
+	licenseYears = regexp.MustCompile(`\b(19|20)\d{2}\b`)
+)
+
+// lineCommentMarkers lists the prefixes of line comments that may hold a
+// license header.
+var lineCommentMarkers = []string{"//", "#", "--", ";", "%"}
+
+// StripLicenseHeaders removes license and copyright headers that are
+// repeated across files: the leading comment block of a file (after any
+// "#!" line) when it mentions a copyright or license and at least one other
+// file starts with the same text. Comment syntax doesn't matter, and
+// neither do the years, so a header in Go and Python files, or with
+// different copyright years, counts as one. It returns the updated files
+// and the headers removed, most common first.
+func StripLicenseHeaders(files []File) ([]File, []LicenseHeader) {
+	type header struct {
+		start, end int // byte range in the file, trailing blank lines included
+		key        string
+	}
+	headers := make([]header, len(files))
+	counts := make(map[string]int)
+	texts := make(map[string]string)
+	var order []string
+	for i, file := range files {
+		start, end, text := leadingComment(file.Content)
+		if end == 0 || !licenseWords.MatchString(text) {
+			continue
+		}
+		key := licenseYears.ReplaceAllString(text, "YYYY")
+		headers[i] = header{start: start, end: end, key: key}
+		if counts[key]++; counts[key] == 1 {
+			texts[key] = text
+			order = append(order, key)
+		}
+	}
+
+	result := make([]File, len(files))
+	stripped := make(map[string]int)
+	for i, file := range files {
+		result[i] = file
+		h := headers[i]
+		if h.key == "" || counts[h.key] < 2 {
+			continue
+		}
+		content := make([]byte, 0, len(file.Content)-(h.end-h.start))
+		content = append(content, file.Content[:h.start]...)
+		result[i].Content = append(content, file.Content[h.end:]...)
+		stripped[h.key]++
+	}
+
+	var removed []LicenseHeader
+	for _, key := range order {
+		if n := stripped[key]; n > 0 {
+			removed = append(removed, LicenseHeader{Text: texts[key], Files: n})
+		}
+	}
+	// Most common first, keeping discovery order for ties
+	slices.SortStableFunc(removed, func(a, b LicenseHeader) int { return b.Files - a.Files })
+	return result, removed
+}
+
+// leadingComment finds the comment block at the top of content, after any
+// "#!" line, returning its byte range (with the blank lines after it) and
+// its text without comment markers. end is 0 if there is none.
+func leadingComment(content []byte) (start, end int, text string) {
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	i := 0
+	if len(lines) > 0 && bytes.HasPrefix(lines[0], []byte("#!")) {
+		start += len(lines[0])
+		i++
+	}
+
+	var body []string
+	offset := start
+	for ; i < len(lines) && len(bytes.TrimSpace(lines[i])) == 0 && len(lines[i]) > 0; i++ {
+		offset += len(lines[i])
+	}
+	first := ""
+	if i < len(lines) {
+		first = strings.TrimSpace(string(lines[i]))
+	}
+	switch {
+	case strings.HasPrefix(first, "/*") || strings.HasPrefix(first, "<!--"):
+		closing := "*/"
+		if strings.HasPrefix(first, "<!--") {
+			closing = "-->"
+		}
+		for ; i < len(lines); i++ {
+			line := string(lines[i])
+			offset += len(line)
+			body = append(body, line)
+			if strings.Contains(line, closing) {
+				i++
+				break
+			}
+		}
+		if !strings.Contains(strings.Join(body, ""), closing) {
+			return 0, 0, ""
+		}
+	default:
+		marker := ""
+		for _, m := range lineCommentMarkers {
+			if strings.HasPrefix(first, m) && !strings.HasPrefix(first, "#!") {
+				marker = m
+				break
+			}
+		}
+		if marker == "" {
+			return 0, 0, ""
+		}
+		for ; i < len(lines); i++ {
+			line := string(lines[i])
+			if !strings.HasPrefix(strings.TrimSpace(line), marker) || isDirective(line) {
+				break
+			}
+			offset += len(line)
+			body = append(body, line)
+		}
+	}
+	if len(body) == 0 {
+		return 0, 0, ""
+	}
+
+	// Take the blank lines after the header with it
+	for ; i < len(lines) && len(bytes.TrimSpace(lines[i])) == 0 && len(lines[i]) > 0; i++ {
+		offset += len(lines[i])
+	}
+	return start, offset, commentText(body)
+}
+
+// isDirective reports whether a comment line is a compiler or tool
+// directive that must stay, such as "//go:build" or "# -*- coding -*-".
+func isDirective(line string) bool {
+	line = strings.TrimSpace(line)
+	return strings.HasPrefix(line, "//go:") || strings.HasPrefix(line, "// +build") ||
+		strings.HasPrefix(line, "//nolint") || strings.Contains(line, "-*-")
+}
+
+// commentText strips comment markers and surrounding blank lines from a
+// comment block.
+func commentText(body []string) string {
+	var lines []string
+	for _, line := range body {
+		line = strings.TrimSpace(line)
+		for _, marker := range []string{"/**", "/*", "<!--", "-->", "*/"} {
+			line = strings.ReplaceAll(line, marker, "")
+		}
+		line = strings.TrimSpace(line)
+		for _, marker := range append([]string{"*"}, lineCommentMarkers...) {
+			if rest, ok := strings.CutPrefix(line, marker); ok {
+				line = strings.TrimLeft(rest, marker[:1])
+				break
+			}
+		}
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStripLicenseHeaders(t *testing.T) {
+	goHeader := "// Copyright 2023 Example Corp.\n//\n// Licensed under the Apache License, Version 2.0.\n\n"
+	pyHeader := "#!/usr/bin/env python3\n# Copyright 2021 Example Corp.\n#\n# Licensed under the Apache License, Version 2.0.\n\n"
+	cHeader := "/*\n * SPDX-License-Identifier: MIT\n */\n"
+	files := []File{
+		{Path: "a.go", Content: []byte(goHeader + "package a\n")},
+		{Path: "b.go", Content: []byte(goHeader + "//go:build linux\n\npackage b\n")},
+		{Path: "tool.py", Content: []byte(pyHeader + "print(1)\n")},
+		{Path: "x.c", Content: []byte(cHeader + "int x;\n")},
+		{Path: "y.h", Content: []byte(cHeader + "int y;\n")},
+		{Path: "z.h", Content: []byte("/* Copyright 2020 Someone Else */\nint z;\n")}, // not repeated
+		{Path: "doc.go", Content: []byte("// Package doc explains things.\npackage doc\n")},
+	}
+
+	got, headers := StripLicenseHeaders(files)
+	want := []string{
+		"package a\n",
+		"//go:build linux\n\npackage b\n",
+		"#!/usr/bin/env python3\nprint(1)\n",
+		"int x;\n",
+		"int y;\n",
+		"/* Copyright 2020 Someone Else */\nint z;\n",
+		"// Package doc explains things.\npackage doc\n",
+	}
+	for i := range got {
+		if string(got[i].Content) != want[i] {
+			t.Errorf("%s: content = %q, want %q", got[i].Path, got[i].Content, want[i])
+		}
+	}
+	if strings.HasPrefix(string(files[0].Content), "package") {
+		t.Error("StripLicenseHeaders modified its input")
+	}
+
+	if len(headers) != 2 {
+		t.Fatalf("StripLicenseHeaders() headers = %+v, want 2", headers)
+	}
+	if headers[0].Files != 3 || headers[0].Text != "Copyright 2023 Example Corp.\n\nLicensed under the Apache License, Version 2.0." {
+		t.Errorf("first header = %+v", headers[0])
+	}
+	if headers[1].Files != 2 || headers[1].Text != "SPDX-License-Identifier: MIT" {
+		t.Errorf("second header = %+v", headers[1])
+	}
+}
+
+func TestLicenseHeadersRoundTrip(t *testing.T) {
+	files := []File{{Path: "a.go", Content: []byte("package a\n")}, {Path: "b.go", Content: []byte("package b\n")}}
+	headers := []LicenseHeader{{Text: "Copyright 2024 Example Corp.", Files: 2}}
+
+	for _, format := range Formats {
+		if format == FormatJSONL {
+			continue // has nowhere to list them; gopack won't strip them for it
+		}
+		formatter := NewFormatter(files)
+		formatter.OutputFormat = format
+		formatter.LicenseHeaders = headers
+		output := formatter.Format()
+		if !strings.Contains(output, "Removed from the top of 2 files:") || !strings.Contains(output, "Copyright 2024 Example Corp.") {
+			t.Errorf("%s output doesn't list the license header:\n%s", format, output)
+		}
+
+		parsed, err := ParsePack([]byte(output))
+		if err != nil {
+			t.Fatalf("%s: ParsePack() error = %v", format, err)
+		}
+		if len(parsed) != 2 || parsed[0].Path != "a.go" || parsed[1].Path != "b.go" {
+			t.Errorf("%s: ParsePack() = %+v, want a.go and b.go", format, parsed)
+		}
+	}
+}
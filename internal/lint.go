@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// IgnoreProblem is an ignore rule that LintIgnores found fault with.
+type IgnoreProblem struct {
+	Source  string // where the rule is written, such as ".gitignore:3" or "--ignore-pattern"
+	Pattern string
+	Problem string
+}
+
+// LintIgnores walks the tree as Walk does and reports the rules from ignore
+// files and IgnorePatterns that gopack can't interpret as written, and those
+// that matched no path the walk visited. A rule that only matches inside a
+// directory already ignored counts as unused, since the walk never goes
+// there. Built-in defaults aren't reported.
+func (w *Walker) LintIgnores() ([]IgnoreProblem, error) {
+	w.matched = make(map[string]bool)
+	defer func() { w.matched = nil }()
+	if _, err := w.Walk(); err != nil {
+		return nil, err
+	}
+
+	var rules []ignoreRule
+	for _, dir := range slices.Sorted(maps.Keys(w.patterns)) {
+		rules = append(rules, w.patterns[dir]...)
+	}
+	rules = append(rules, w.flagRules...)
+
+	var problems []IgnoreProblem
+	for _, rule := range rules {
+		problem := PatternProblem(rule.pattern)
+		if problem == "" && !w.matched[rule.key()] {
+			problem = "matched nothing"
+		}
+		if problem != "" {
+			problems = append(problems, IgnoreProblem{Source: rule.source, Pattern: rule.pattern, Problem: problem})
+		}
+	}
+	return problems, nil
+}
+
+// posixClass matches a POSIX class named inside a character class, as in
+// "[[:alpha:]]".
+var posixClass = regexp.MustCompile(`\[:(\w*):\]`)
+
+// PatternProblem describes why an ignore pattern won't work as its author
+// likely meant, or returns "" if nothing is wrong with it.
+func PatternProblem(pattern string) string {
+	body := strings.TrimPrefix(pattern, "!")
+	switch {
+	case strings.Trim(body, "/") == "":
+		return "matches nothing"
+	case strings.HasSuffix(body, `\`) && !strings.HasSuffix(body, `\\`):
+		return "ends with an unescaped backslash"
+	}
+	for _, segment := range strings.Split(strings.Trim(body, "/"), "/") {
+		for _, m := range posixClass.FindAllStringSubmatch(segment, -1) {
+			if _, ok := posixClasses[m[1]]; !ok {
+				return fmt.Sprintf("unknown character class %s", m[0])
+			}
+		}
+		if _, err := path.Match(translateClasses(segment), ""); err != nil {
+			return fmt.Sprintf("invalid glob %q (unclosed bracket?)", segment)
+		}
+		if segment != "**" && strings.Contains(segment, "**") {
+			return fmt.Sprintf("%q matches like \"*\": \"**\" only spans directories as a whole path segment", segment)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLintIgnores(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".gitignore":      "*.log\nnode_modules/\nbuild/\nbuild/keep.txt\n!important.log\nfoo[\n**.js\n",
+		"app.go":          "package app\n",
+		"debug.log":       "x\n",
+		"important.log":   "x\n",
+		"build/out.txt":   "x\n",
+		"build/keep.txt":  "x\n",
+		"web/.gitignore":  "# comment\n*.map\n/dist\n",
+		"web/app.js.map":  "x\n",
+		"web/src/main.ts": "x\n",
+	})
+
+	walker, err := NewWalker(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	walker.IgnorePatterns = []string{"*.go", "*.tmp"}
+	problems, err := walker.LintIgnores()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, problem := range problems {
+		got = append(got, fmt.Sprintf("%s %s: %s", problem.Source, problem.Pattern, problem.Problem))
+	}
+	want := []string{
+		".gitignore:2 node_modules/: matched nothing",
+		".gitignore:4 build/keep.txt: matched nothing", // inside an ignored directory
+		`.gitignore:6 foo[: invalid glob "foo[" (unclosed bracket?)`,
+		`.gitignore:7 **.js: "**.js" matches like "*": "**" only spans directories as a whole path segment`,
+		"web/.gitignore:3 /dist: matched nothing",
+		"--ignore-pattern *.tmp: matched nothing",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("LintIgnores() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Linting leaves later walks alone
+	if walker.matched != nil {
+		t.Error("LintIgnores() left rule tracking on")
+	}
+}
+
+func TestPatternProblem(t *testing.T) {
+	tests := map[string]string{
+		"*.log":          "",
+		"**/build/":      "",
+		"a/**/b":         "",
+		`\#notes`:        "",
+		"!":              "matches nothing",
+		"/":              "matches nothing",
+		`trailing\`:      "ends with an unescaped backslash",
+		"[[:space:]]*":   "",
+		"[Dd]ebug/":      "",
+		"[!.]*":          "",
+		"[[:nope:]]":     "unknown character class [:nope:]",
+		"src/[a-/x.go":   `invalid glob "[a-" (unclosed bracket?)`,
+		"src/**.go":      `"**.go" matches like "*": "**" only spans directories as a whole path segment`,
+		"escaped\\\\":    "",
+		"!negated/path/": "",
+	}
+	for pattern, want := range tests {
+		if got := PatternProblem(pattern); got != want {
+			t.Errorf("PatternProblem(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// LLM providers supported by Ask.
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+	ProviderOllama    = "ollama"
+)
+
+// Providers lists the supported LLM providers.
+var Providers = []string{ProviderOpenAI, ProviderAnthropic, ProviderOllama}
+
+// providerDefaults holds each provider's default endpoint, model, and the
+// environment variable its API key is read from.
+var providerDefaults = map[string]struct {
+	baseURL, model, keyEnv string
+}{
+	ProviderOpenAI:    {"https://api.openai.com/v1", "gpt-4o", "OPENAI_API_KEY"},
+	ProviderAnthropic: {"https://api.anthropic.com/v1", "claude-sonnet-4-0", "ANTHROPIC_API_KEY"},
+	ProviderOllama:    {"http://localhost:11434", "llama3.1", ""},
+}
+
+// AskRequest describes a prompt to send to an LLM provider. Empty fields
+// take the provider's defaults; the API key is read from the provider's
+// usual environment variable (OPENAI_API_KEY or ANTHROPIC_API_KEY).
+type AskRequest struct {
+	Provider  string
+	Model     string
+	BaseURL   string
+	Prompt    string
+	MaxTokens int // Anthropic requires a limit on the answer length
+}
+
+// DefaultProvider picks a provider from the API keys in the environment,
+// falling back to a local Ollama server.
+func DefaultProvider() string {
+	switch {
+	case os.Getenv("ANTHROPIC_API_KEY") != "":
+		return ProviderAnthropic
+	case os.Getenv("OPENAI_API_KEY") != "":
+		return ProviderOpenAI
+	}
+	return ProviderOllama
+}
+
+// DefaultModel returns the model Ask uses for a provider when none is given.
+func DefaultModel(provider string) string {
+	return providerDefaults[provider].model
+}
+
+// Ask sends the prompt to the provider and streams the answer to w as it
+// arrives.
+func Ask(ctx context.Context, req AskRequest, w io.Writer) error {
+	defaults, ok := providerDefaults[req.Provider]
+	if !ok {
+		return fmt.Errorf("unknown provider %q (expected one of: %s)", req.Provider, strings.Join(Providers, ", "))
+	}
+	if req.Model == "" {
+		req.Model = defaults.model
+	}
+	if req.BaseURL == "" {
+		req.BaseURL = defaults.baseURL
+	}
+	req.BaseURL = strings.TrimSuffix(req.BaseURL, "/")
+	if req.MaxTokens == 0 {
+		req.MaxTokens = 4096
+	}
+
+	var apiKey string
+	if defaults.keyEnv != "" {
+		if apiKey = os.Getenv(defaults.keyEnv); apiKey == "" {
+			return fmt.Errorf("%s requires the %s environment variable", req.Provider, defaults.keyEnv)
+		}
+	}
+
+	message := map[string]string{"role": "user", "content": req.Prompt}
+	var url string
+	var body any
+	headers := map[string]string{"Content-Type": "application/json"}
+	switch req.Provider {
+	case ProviderOpenAI:
+		url = req.BaseURL + "/chat/completions"
+		body = map[string]any{"model": req.Model, "stream": true, "messages": []any{message}}
+		headers["Authorization"] = "Bearer " + apiKey
+	case ProviderAnthropic:
+		url = req.BaseURL + "/messages"
+		body = map[string]any{"model": req.Model, "stream": true, "max_tokens": req.MaxTokens, "messages": []any{message}}
+		headers["x-api-key"] = apiKey
+		headers["anthropic-version"] = "2023-06-01"
+	case ProviderOllama:
+		url = req.BaseURL + "/api/chat"
+		body = map[string]any{"model": req.Model, "stream": true, "messages": []any{message}}
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	for name, value := range headers {
+		httpReq.Header.Set(name, value)
+	}
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", req.Provider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s returned %s: %s", req.Provider, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	if req.Provider == ProviderOllama {
+		return streamOllama(resp.Body, w)
+	}
+	return streamSSE(resp.Body, w, req.Provider)
+}
+
+// streamSSE copies the text deltas from an OpenAI or Anthropic
+// server-sent event stream to w.
+func streamSSE(r io.Reader, w io.Writer, provider string) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var event struct {
+			// OpenAI
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+			// Anthropic
+			Type  string `json:"type"`
+			Delta struct {
+				Text string `json:"text"`
+			} `json:"delta"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("invalid %s stream event: %w", provider, err)
+		}
+		if event.Error != nil {
+			return fmt.Errorf("%s: %s", provider, event.Error.Message)
+		}
+
+		var text string
+		if len(event.Choices) > 0 {
+			text = event.Choices[0].Delta.Content
+		} else if event.Type == "content_block_delta" {
+			text = event.Delta.Text
+		}
+		if _, err := io.WriteString(w, text); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// streamOllama copies the message chunks from an Ollama JSON-lines stream
+// to w.
+func streamOllama(r io.Reader, w io.Writer) error {
+	decoder := json.NewDecoder(r)
+	for {
+		var chunk struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			Done  bool   `json:"done"`
+			Error string `json:"error"`
+		}
+		if err := decoder.Decode(&chunk); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("invalid ollama stream: %w", err)
+		}
+		if chunk.Error != "" {
+			return fmt.Errorf("ollama: %s", chunk.Error)
+		}
+		if _, err := io.WriteString(w, chunk.Message.Content); err != nil {
+			return err
+		}
+		if chunk.Done {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAsk(t *testing.T) {
+	tests := []struct {
+		provider string
+		path     string
+		stream   string
+	}{
+		{
+			provider: ProviderOpenAI,
+			path:     "/chat/completions",
+			stream: `data: {"choices":[{"delta":{"role":"assistant"}}]}
+
+data: {"choices":[{"delta":{"content":"Hello"}}]}
+
+data: {"choices":[{"delta":{"content":", world"}}]}
+
+data: [DONE]
+`,
+		},
+		{
+			provider: ProviderAnthropic,
+			path:     "/messages",
+			stream: `event: message_start
+data: {"type":"message_start","message":{}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":", world"}}
+
+event: message_stop
+data: {"type":"message_stop"}
+`,
+		},
+		{
+			provider: ProviderOllama,
+			path:     "/api/chat",
+			stream: `{"message":{"role":"assistant","content":"Hello"},"done":false}
+{"message":{"role":"assistant","content":", world"},"done":false}
+{"message":{"role":"assistant","content":""},"done":true}
+`,
+		},
+	}
+
+	t.Setenv("OPENAI_API_KEY", "test-key")
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.path {
+					t.Errorf("request path = %q, want %q", r.URL.Path, tt.path)
+				}
+				var body struct {
+					Messages []struct{ Content string } `json:"messages"`
+				}
+				data, _ := io.ReadAll(r.Body)
+				if err := json.Unmarshal(data, &body); err != nil || len(body.Messages) != 1 || body.Messages[0].Content != "the prompt" {
+					t.Errorf("request body = %s", data)
+				}
+				io.WriteString(w, tt.stream)
+			}))
+			defer server.Close()
+
+			var answer strings.Builder
+			err := Ask(context.Background(), AskRequest{Provider: tt.provider, BaseURL: server.URL, Prompt: "the prompt"}, &answer)
+			if err != nil {
+				t.Fatalf("Ask() error = %v", err)
+			}
+			if answer.String() != "Hello, world" {
+				t.Errorf("Ask() streamed %q, want %q", answer.String(), "Hello, world")
+			}
+		})
+	}
+}
+
+func TestAskErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"bad model"}`, http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	err := Ask(context.Background(), AskRequest{Provider: ProviderOllama, BaseURL: server.URL}, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "bad model") {
+		t.Errorf("Ask() error = %v, want the server's message", err)
+	}
+
+	t.Setenv("OPENAI_API_KEY", "")
+	if err := Ask(context.Background(), AskRequest{Provider: ProviderOpenAI}, io.Discard); err == nil {
+		t.Error("Ask() without an API key succeeded, want error")
+	}
+	if err := Ask(context.Background(), AskRequest{Provider: "nope"}, io.Discard); err == nil {
+		t.Error("Ask() with an unknown provider succeeded, want error")
+	}
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Ways LimitLineLength shortens long lines.
+const (
+	LongLinesTruncate = "truncate"
+	LongLinesWrap     = "wrap"
+)
+
+// LongLineModes lists the ways LimitLineLength can shorten long lines.
+var LongLineModes = []string{LongLinesTruncate, LongLinesWrap}
+
+// LimitLineLength shortens lines longer than maxLength characters, such as
+// minified bundles or base64 blobs embedded in source, where a single line
+// can cost more tokens than the rest of the pack. In truncate mode the rest
+// of the line is cut and noted; in wrap mode the line is broken into lines
+// of maxLength characters, keeping all of it. Dedupe references and
+// collapsed directories are left alone. It returns the files, the number
+// of lines shortened, and the number of files they were in.
+func LimitLineLength(files []File, maxLength int, mode string) ([]File, int, int) {
+	result := make([]File, len(files))
+	var lines, changed int
+	for i, file := range files {
+		result[i] = file
+		if file.DuplicateOf != "" || file.Omitted {
+			continue
+		}
+		if content, n := limitLines(file.Content, maxLength, mode); n > 0 {
+			result[i].Content = content
+			lines += n
+			changed++
+		}
+	}
+	return result, lines, changed
+}
+
+// limitLines shortens the long lines of content, and returns it along with
+// the number of lines shortened.
+func limitLines(content []byte, maxLength int, mode string) ([]byte, int) {
+	// Most files have no line that long, even in bytes
+	long := false
+	for rest := content; len(rest) > maxLength; {
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 || i > maxLength {
+			long = true
+			break
+		}
+		rest = rest[i+1:]
+	}
+	if !long {
+		return content, 0
+	}
+
+	var out bytes.Buffer
+	n := 0
+	for _, line := range bytes.SplitAfter(content, []byte("\n")) {
+		text := bytes.TrimRight(line, "\r\n")
+		ending := line[len(text):]
+		length := utf8.RuneCount(text)
+		if length <= maxLength {
+			out.Write(line)
+			continue
+		}
+		n++
+		if mode == LongLinesWrap {
+			for utf8.RuneCount(text) > maxLength {
+				cut := runeOffset(text, maxLength)
+				out.Write(text[:cut])
+				out.WriteByte('\n')
+				text = text[cut:]
+			}
+			out.Write(text)
+		} else {
+			out.Write(text[:runeOffset(text, maxLength)])
+			fmt.Fprintf(&out, " [... %s more characters truncated ...]", FormatWithCommas(length-maxLength))
+		}
+		out.Write(ending)
+	}
+	return out.Bytes(), n
+}
+
+// runeOffset returns the byte offset of the nth character of text.
+func runeOffset(text []byte, n int) int {
+	offset := 0
+	for ; n > 0 && offset < len(text); n-- {
+		_, size := utf8.DecodeRune(text[offset:])
+		offset += size
+	}
+	return offset
+}
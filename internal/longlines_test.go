@@ -0,0 +1,35 @@
+package internal
+
+import "testing"
+
+func TestLimitLineLength(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		mode      string
+		want      string
+		wantLines int
+	}{
+		{"short lines kept", "abc\ndefgh\n", LongLinesTruncate, "abc\ndefgh\n", 0},
+		{"truncate", "abcdefghij\nok\n", LongLinesTruncate, "abcdef [... 4 more characters truncated ...]\nok\n", 1},
+		{"truncate keeps crlf", "abcdefghij\r\n", LongLinesTruncate, "abcdef [... 4 more characters truncated ...]\r\n", 1},
+		{"truncate without final newline", "ok\nabcdefgh", LongLinesTruncate, "ok\nabcdef [... 2 more characters truncated ...]", 1},
+		{"wrap", "abcdefghijklmn\nok\n", LongLinesWrap, "abcdef\nghijkl\nmn\nok\n", 1},
+		{"wrap exact multiple", "abcdefghijkl\n", LongLinesWrap, "abcdef\nghijkl\n", 1},
+		{"counts characters, not bytes", "héllo wörld\n", LongLinesTruncate, "héllo  [... 5 more characters truncated ...]\n", 1},
+		{"multibyte within limit", "éééééé\n", LongLinesTruncate, "éééééé\n", 0},
+	}
+	for _, tt := range tests {
+		files := []File{{Path: "a.js", Content: []byte(tt.content)}, {Path: "b.js", DuplicateOf: "a.js"}}
+		got, lines, changed := LimitLineLength(files, 6, tt.mode)
+		if string(got[0].Content) != tt.want || lines != tt.wantLines {
+			t.Errorf("%s: LimitLineLength() = %q, %d lines; want %q, %d", tt.name, got[0].Content, lines, tt.want, tt.wantLines)
+		}
+		if wantChanged := min(tt.wantLines, 1); changed != wantChanged {
+			t.Errorf("%s: LimitLineLength() changed %d files, want %d", tt.name, changed, wantChanged)
+		}
+		if got[1].DuplicateOf != "a.js" || got[1].Content != nil {
+			t.Errorf("%s: LimitLineLength() changed a dedupe reference: %+v", tt.name, got[1])
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// windowsMaxPath is the length from which Windows needs the \\?\ prefix to
+// open a path (MAX_PATH, less room for the 8.3 name of a file in a
+// directory).
+const windowsMaxPath = 248
+
+// reservedName matches the names Windows reserves for devices, which it
+// opens instead of a file of that name, with or without an extension:
+// CON, PRN, AUX, NUL, COM1-COM9, and LPT1-LPT9.
+var reservedName = regexp.MustCompile(`(?i)^(con|prn|aux|nul|com[1-9¹²³]|lpt[1-9¹²³])(\..*)?$`)
+
+// IsReservedName reports whether name, a file name without directories,
+// names a device on Windows. Trailing spaces and dots are ignored, as
+// Windows does.
+func IsReservedName(name string) bool {
+	return reservedName.MatchString(strings.TrimRight(name, " ."))
+}
+
+// LongPath returns path in a form Windows can open even when it is longer
+// than MAX_PATH or its last element is a reserved name such as CON or
+// aux.js: absolute, with the \\?\ prefix that lifts both limits. Other
+// paths, and all paths on other systems, are returned unchanged.
+func LongPath(path string) string {
+	if runtime.GOOS != "windows" {
+		return path
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return windowsLongPath(path)
+}
+
+// windowsLongPath prefixes an absolute, clean Windows path with \\?\ (or
+// \\?\UNC\ for a network share) if it needs it.
+func windowsLongPath(path string) string {
+	path = strings.ReplaceAll(path, "/", `\`)
+	base := path[strings.LastIndexByte(path, '\\')+1:]
+	if strings.HasPrefix(path, `\\?\`) || len(path) < windowsMaxPath && !IsReservedName(base) {
+		return path
+	}
+	switch {
+	case strings.HasPrefix(path, `\\`):
+		return `\\?\UNC\` + path[2:]
+	case len(path) >= 3 && path[1] == ':' && path[2] == '\\':
+		return `\\?\` + path
+	}
+	return path // relative, which \\?\ doesn't allow
+}
@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsReservedName(t *testing.T) {
+	tests := map[string]bool{
+		"CON":        true,
+		"nul":        true,
+		"con.txt":    true,
+		"aux.tar.gz": true,
+		"lpt1 .":     true,
+		"COM9":       true,
+		"com0":       false,
+		"console.go": false,
+		"nul_test":   false,
+		"main.go":    false,
+	}
+	for name, want := range tests {
+		if got := IsReservedName(name); got != want {
+			t.Errorf("IsReservedName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestWindowsLongPath(t *testing.T) {
+	long := `C:\src\` + strings.Repeat(`deep\`, 50) + "main.go"
+	tests := []struct {
+		path string
+		want string
+	}{
+		{`C:\src\main.go`, `C:\src\main.go`},
+		{`C:/src/main.go`, `C:\src\main.go`},
+		{`C:\src\aux.js`, `\\?\C:\src\aux.js`},
+		{long, `\\?\` + long},
+		{`\\server\share\con`, `\\?\UNC\server\share\con`},
+		{`\\?\C:\src\aux.js`, `\\?\C:\src\aux.js`},
+		{`src\nul`, `src\nul`},
+	}
+	for _, tt := range tests {
+		if got := windowsLongPath(tt.path); got != tt.want {
+			t.Errorf("windowsLongPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Manifest records the files in a pack and their hashes, so that a later
+// apply can check it is working from the same baseline.
+type Manifest struct {
+	Files []ManifestEntry `json:"files"`
+}
+
+// ManifestEntry describes one packed file.
+type ManifestEntry struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Bytes  int    `json:"bytes"`
+	Tokens int    `json:"tokens"`
+	Mode   string `json:"mode,omitempty"` // permission bits, as in "0755", with --file-modes
+}
+
+// NewManifest builds a manifest for files, which should be their original
+// contents (before deduplication or other transformations).
+func NewManifest(files []File) Manifest {
+	manifest := Manifest{Files: make([]ManifestEntry, 0, len(files))}
+	for _, file := range files {
+		manifest.Files = append(manifest.Files, ManifestEntry{
+			Path:   filepath.ToSlash(file.Path),
+			SHA256: hashContent(file.Content),
+			Bytes:  len(file.Content),
+			Tokens: FileTokens(file),
+			Mode:   fileModeField(file),
+		})
+	}
+	return manifest
+}
+
+// ReadManifest loads a manifest written as JSON.
+func ReadManifest(path string) (Manifest, error) {
+	var manifest Manifest
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return manifest, err
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	return manifest, nil
+}
+
+// Changed returns the paths of the manifest's files whose content under dir
+// no longer matches the recorded hash, including files that were removed.
+func (m Manifest) Changed(dir string) ([]string, error) {
+	var changed []string
+	for _, entry := range m.Files {
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(entry.Path)))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err != nil || hashContent(content) != entry.SHA256 {
+			changed = append(changed, entry.Path)
+		}
+	}
+	return changed, nil
+}
+
+// hashContent returns the hex SHA-256 of content.
+func hashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Delta compares files with the manifest of an earlier pack, for a
+// follow-up pack of only what changed: it returns the files that are new
+// or whose content differs from the recorded hash, the paths of the
+// manifest's files no longer among them, and how many were unchanged.
+func (m Manifest) Delta(files []File) (changed []File, removed []string, unchanged int) {
+	recorded := make(map[string]string, len(m.Files))
+	for _, entry := range m.Files {
+		recorded[entry.Path] = entry.SHA256
+	}
+	present := make(map[string]bool, len(files))
+	for _, file := range files {
+		path := filepath.ToSlash(file.Path)
+		present[path] = true
+		if hash, ok := recorded[path]; ok && hash == hashContent(file.Content) {
+			unchanged++
+			continue
+		}
+		changed = append(changed, file)
+	}
+	for _, entry := range m.Files {
+		if !present[entry.Path] {
+			removed = append(removed, entry.Path)
+		}
+	}
+	return changed, removed, unchanged
+}
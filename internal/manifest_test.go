@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestManifestChanged(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "a.go", "b.go", "c.go")
+
+	var files []File
+	for _, name := range []string{"a.go", "b.go", "c.go"} {
+		files = append(files, File{Path: name, Content: []byte(readFile(t, filepath.Join(dir, name)))})
+	}
+	manifest := NewManifest(files)
+	if got := manifest.Files[0]; got.Bytes != len(files[0].Content) || len(got.SHA256) != 64 {
+		t.Errorf("NewManifest() entry = %+v", got)
+	}
+
+	// Round-trip through JSON as the sidecar file does
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err = ReadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFile(filepath.Join(dir, "b.go"), "edited\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "c.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := manifest.Changed(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"b.go", "c.go"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("Changed() = %q, want %q", changed, want)
+	}
+}
+
+func TestManifestDelta(t *testing.T) {
+	manifest := NewManifest([]File{
+		{Path: "a.go", Content: []byte("package a\n")},
+		{Path: "b.go", Content: []byte("package b\n")},
+		{Path: "c.go", Content: []byte("package c\n")},
+	})
+	files := []File{
+		{Path: "a.go", Content: []byte("package a\n")},
+		{Path: "b.go", Content: []byte("package b // edited\n")},
+		{Path: "d.go", Content: []byte("package d\n")},
+	}
+
+	changed, removed, unchanged := manifest.Delta(files)
+	var paths []string
+	for _, file := range changed {
+		paths = append(paths, file.Path)
+	}
+	if !reflect.DeepEqual(paths, []string{"b.go", "d.go"}) || !reflect.DeepEqual(removed, []string{"c.go"}) || unchanged != 1 {
+		t.Errorf("Delta() = %q, removed %q, %d unchanged; want [b.go d.go], [c.go], 1", paths, removed, unchanged)
+	}
+}
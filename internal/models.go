@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Model describes an LLM and the size of its context window in tokens.
+type Model struct {
+	Name          string
+	ContextWindow int
+}
+
+// Models lists well-known models. Windows are the providers' published
+// input limits and are only used for warnings and comparisons.
+var Models = []Model{
+	{"gpt-3.5-turbo", 16_385},
+	{"gpt-4", 8_192},
+	{"gpt-4-turbo", 128_000},
+	{"gpt-4o", 128_000},
+	{"gpt-4o-mini", 128_000},
+	{"gpt-4.1", 1_047_576},
+	{"o1", 200_000},
+	{"o3-mini", 200_000},
+	{"claude-3-haiku", 200_000},
+	{"claude-3-opus", 200_000},
+	{"claude-3.5-sonnet", 200_000},
+	{"claude-3.5-haiku", 200_000},
+	{"claude-3.7-sonnet", 200_000},
+	{"claude-sonnet-4", 200_000},
+	{"claude-opus-4", 200_000},
+	{"gemini-1.5-flash", 1_048_576},
+	{"gemini-1.5-pro", 2_097_152},
+	{"gemini-2.0-flash", 1_048_576},
+	{"gemini-2.5-pro", 1_048_576},
+	{"llama-3.1-70b", 131_072},
+	{"mistral-large", 131_072},
+	{"deepseek-v3", 128_000},
+}
+
+// charsPerToken approximates how many characters of code one token of a
+// model family's tokenizer covers, by model name prefix, first match wins.
+// Newer OpenAI tokenizers (o200k) are close to EstimateTokens' 4; Claude's
+// splits code finer.
+var charsPerToken = []struct {
+	prefix string
+	chars  float64
+}{
+	{"gpt-4o", 4.0},
+	{"gpt-4.1", 4.0},
+	{"o1", 4.0},
+	{"o3", 4.0},
+	{"gpt-", 3.7}, // cl100k
+	{"claude-", 3.5},
+	{"gemini-", 4.0},
+	{"llama-", 3.8},
+	{"mistral-", 3.6},
+	{"deepseek-", 3.6},
+}
+
+// Tokens estimates how many of the model's tokens text of the given length
+// takes, from its tokenizer's typical characters per token. Models of
+// unknown families count like EstimateTokens.
+func (m Model) Tokens(chars int) int {
+	for _, family := range charsPerToken {
+		if strings.HasPrefix(m.Name, family.prefix) {
+			return int(float64(chars) / family.chars)
+		}
+	}
+	return chars / 4
+}
+
+// LookupModel finds a model by name, case-insensitively. A unique prefix is
+// accepted, so "gemini-1.5-p" selects gemini-1.5-pro; an exact name always
+// wins, so "gpt-4" isn't ambiguous with gpt-4o.
+func LookupModel(name string) (Model, error) {
+	name = strings.ToLower(name)
+
+	var matches []Model
+	for _, model := range Models {
+		if model.Name == name {
+			return model, nil
+		}
+		if strings.HasPrefix(model.Name, name) {
+			matches = append(matches, model)
+		}
+	}
+
+	switch len(matches) {
+	case 1:
+		return matches[0], nil
+	case 0:
+		return Model{}, fmt.Errorf("unknown model %q (known models: %s)", name, strings.Join(ModelNames(), ", "))
+	default:
+		var names []string
+		for _, model := range matches {
+			names = append(names, model.Name)
+		}
+		return Model{}, fmt.Errorf("ambiguous model %q (matches %s)", name, strings.Join(names, ", "))
+	}
+}
+
+// ModelNames returns the names of all known models.
+func ModelNames() []string {
+	names := make([]string, len(Models))
+	for i, model := range Models {
+		names[i] = model.Name
+	}
+	return names
+}
@@ -0,0 +1,49 @@
+package internal
+
+import "testing"
+
+func TestLookupModel(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"gpt-4o", "gpt-4o", false},
+		{"GPT-4o", "gpt-4o", false},
+		{"gpt-4", "gpt-4", false}, // exact match beats the gpt-4o prefix
+		{"gemini-1.5-p", "gemini-1.5-pro", false},
+		{"claude-3.5", "", true}, // sonnet and haiku
+		{"no-such-model", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model, err := LookupModel(tt.name)
+			if (err != nil) != tt.wantErr || model.Name != tt.want {
+				t.Errorf("LookupModel(%q) = %q, %v; want %q, error %v", tt.name, model.Name, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestModelTokens(t *testing.T) {
+	tests := []struct {
+		model string
+		chars int
+		want  int
+	}{
+		{"gpt-4o", 400, 100},
+		{"claude-3.5-sonnet", 350, 100},
+		{"gpt-4", 370, 100},
+		{"unknown-model", 400, 100},
+		{"gpt-4o", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			if got := (Model{Name: tt.model}).Tokens(tt.chars); got != tt.want {
+				t.Errorf("Model{%q}.Tokens(%d) = %d; want %d", tt.model, tt.chars, got, tt.want)
+			}
+		})
+	}
+}
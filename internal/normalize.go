@@ -0,0 +1,14 @@
+package internal
+
+import "golang.org/x/text/unicode/norm"
+
+// NormalizePath returns a path in Unicode Normalization Form C, with
+// accents composed onto the letters they follow, as most systems write
+// names. macOS hands out names decomposed (NFD), so "café" read from its
+// disk is "cafe" followed by a combining acute accent; normalized, it
+// matches patterns and paths typed elsewhere, and packs made on any system
+// show the same paths. Text that is already NFC or NFD comes out as NFC;
+// ASCII is returned as is.
+func NormalizePath(s string) string {
+	return norm.NFC.String(s)
+}
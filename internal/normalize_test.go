@@ -0,0 +1,24 @@
+package internal
+
+import "testing"
+
+func TestNormalizePath(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"ascii", "internal/walker.go", "internal/walker.go"},
+		{"already composed", "caf\u00e9/men\u00fc.go", "caf\u00e9/men\u00fc.go"},
+		{"decomposed", "cafe\u0301/menu\u0308.go", "caf\u00e9/men\u00fc.go"},
+		{"marks in canonical order", "a\u0323\u0302", "\u1ead"},
+		{"blocked by a mark of the same class", "a\u0301\u0301", "\u00e1\u0301"},
+		{"mark with nothing to compose", "x\u0301", "x\u0301"},
+		{"leading mark", "\u0301a", "\u0301a"},
+		{"composition exclusion", "\u0915\u093c", "\u0915\u093c"},
+		{"hangul jamo", "\u1112\u1161\u11ab", "\ud55c"},
+	}
+	for _, tt := range tests {
+		if got := NormalizePath(tt.in); got != tt.want {
+			t.Errorf("%s: NormalizePath(%+q) = %+q, want %+q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// VaultIndex is the note WriteVault lists every other note in.
+const VaultIndex = "gopack-index.md"
+
+// vaultUnsafe replaces the characters Obsidian doesn't allow in note names,
+// or can't link to, in each part of a note's path.
+var vaultUnsafe = strings.NewReplacer(
+	"[", "_", "]", "_", "#", "_", "^", "_", "|", "_",
+	":", "_", "*", "_", "?", "_", `"`, "_", "<", "_", ">", "_", `\`, "_",
+)
+
+// vaultNote returns the slash-separated path in a vault of the note for a
+// file: the file's own path, made safe, with ".md" added, so that a.go and
+// a.md get notes of their own. Obsidian hides dotfiles, so a leading dot
+// becomes an underscore.
+func vaultNote(name string) string {
+	var parts []string
+	for _, part := range strings.Split(path.Clean("/"+strings.ReplaceAll(name, `\`, "/")), "/") {
+		if part == "" {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(part, "."); ok {
+			part = "_" + rest
+		}
+		parts = append(parts, vaultUnsafe.Replace(part))
+	}
+	return strings.Join(parts, "/") + ".md"
+}
+
+// vaultLink returns a wikilink to a note, labeled with its path.
+func vaultLink(note string) string {
+	return "[[" + note + "|" + strings.TrimSuffix(note, ".md") + "]]"
+}
+
+// WriteVault writes the files into dir as a vault of Markdown notes, for
+// browsing and annotating a snapshot of the tree in Obsidian or another
+// note-taking tool that reads Markdown folders: one note per file, at the
+// file's path with ".md" added, holding its content in a code block under
+// YAML properties (path, language, lines, tokens), and VaultIndex linking
+// to every note by directory, followed by the summary notes. Notes link
+// back to the index, and a deduplicated file links to the note of the file
+// it repeats. Existing notes of the same names are replaced; others are
+// left alone. It returns the number of notes written, the index included.
+func WriteVault(dir string, files []File, notes []string) (int, error) {
+	written := 0
+	write := func(note, content string) error {
+		name := filepath.Join(dir, filepath.FromSlash(note))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			return err
+		}
+		written++
+		return nil
+	}
+
+	var dirs []string
+	byDir := make(map[string][]string)
+	tokens := 0
+	for _, file := range files {
+		note := vaultNote(file.Path)
+		if note == VaultIndex {
+			return written, fmt.Errorf("%s would replace the vault's index", file.Path)
+		}
+		if err := write(note, vaultFileNote(file)); err != nil {
+			return written, err
+		}
+		noteDir := path.Dir(note)
+		if _, ok := byDir[noteDir]; !ok {
+			dirs = append(dirs, noteDir)
+		}
+		byDir[noteDir] = append(byDir[noteDir], note)
+		tokens += FileTokens(file)
+	}
+
+	var index strings.Builder
+	fmt.Fprintf(&index, "---\nfiles: %d\ntokens: %d\n---\n\n# Index\n\n", len(files), tokens)
+	fmt.Fprintf(&index, "%s files, ~%s tokens.\n", FormatWithCommas(len(files)), FormatWithCommas(tokens))
+	for _, noteDir := range dirs {
+		if noteDir == "." {
+			index.WriteString("\n## /\n\n")
+		} else {
+			fmt.Fprintf(&index, "\n## %s/\n\n", noteDir)
+		}
+		for _, note := range byDir[noteDir] {
+			fmt.Fprintf(&index, "- %s\n", vaultLink(note))
+		}
+	}
+	if len(notes) > 0 {
+		index.WriteString("\n## Notes\n\n")
+		for _, note := range notes {
+			fmt.Fprintf(&index, "- %s\n", note)
+		}
+	}
+	if err := write(VaultIndex, index.String()); err != nil {
+		return written, err
+	}
+	return written, nil
+}
+
+// vaultFileNote renders the note for one file.
+func vaultFileNote(file File) string {
+	var buf strings.Builder
+	language := DetectLanguage(file.Path, file.Content)
+	buf.WriteString("---\n")
+	fmt.Fprintf(&buf, "path: %s\n", strconv.Quote(filepath.ToSlash(file.Path)))
+	fmt.Fprintf(&buf, "language: %s\n", strconv.Quote(language.Name))
+	fmt.Fprintf(&buf, "lines: %d\n", countLines(file.Content))
+	fmt.Fprintf(&buf, "tokens: %d\n", FileTokens(file))
+	if file.URL != "" {
+		fmt.Fprintf(&buf, "url: %s\n", strconv.Quote(file.URL))
+	}
+	buf.WriteString("---\n\n")
+	fmt.Fprintf(&buf, "Back to [[%s|Index]]\n\n", VaultIndex)
+
+	switch {
+	case file.DuplicateOf != "":
+		fmt.Fprintf(&buf, "Identical to %s.\n", vaultLink(vaultNote(file.DuplicateOf)))
+	case file.Omitted:
+		buf.Write(file.Content)
+	default:
+		fence := codeFence(file.Content)
+		fmt.Fprintf(&buf, "%s%s\n", fence, language.Fence)
+		buf.Write(file.Content)
+		if len(file.Content) > 0 && !strings.HasSuffix(string(file.Content), "\n") {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(fence + "\n")
+	}
+	return buf.String()
+}
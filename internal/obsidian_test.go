@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVaultNote(t *testing.T) {
+	tests := []struct{ path, want string }{
+		{"main.go", "main.go.md"},
+		{"internal/walker.go", "internal/walker.go.md"},
+		{`internal\walker.go`, "internal/walker.go.md"},
+		{"docs/[draft] #1.md", "docs/_draft_ _1.md.md"},
+		{"../outside.txt", "outside.txt.md"},
+		{".github/workflows/ci.yml", "_github/workflows/ci.yml.md"},
+		{"$ go vet ./...", "$ go vet ./_...md"},
+	}
+	for _, tt := range tests {
+		if got := vaultNote(tt.path); got != tt.want {
+			t.Errorf("vaultNote(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestWriteVault(t *testing.T) {
+	dir := t.TempDir()
+	files := []File{
+		{Path: "main.go", Content: []byte("package main\n")},
+		{Path: "internal/a.go", Content: []byte("package internal\n"), URL: "https://github.com/o/r/blob/c/internal/a.go"},
+		{Path: "internal/b.go", Content: []byte("see internal/a.go\n"), DuplicateOf: "internal/a.go"},
+	}
+	n, err := WriteVault(dir, files, []string{"Deduplicated: 1 identical files replaced with references"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 {
+		t.Errorf("WriteVault() = %d notes, want 4", n)
+	}
+
+	wants := map[string][]string{
+		VaultIndex: {
+			"files: 3\n",
+			"## /\n\n- [[main.go.md|main.go]]\n",
+			"## internal/\n\n- [[internal/a.go.md|internal/a.go]]\n- [[internal/b.go.md|internal/b.go]]\n",
+			"## Notes\n\n- Deduplicated: 1 identical files replaced with references\n",
+		},
+		"main.go.md": {
+			"path: \"main.go\"\nlanguage: \"Go\"\nlines: 1\n",
+			"Back to [[gopack-index.md|Index]]\n",
+			"```go\npackage main\n```\n",
+		},
+		"internal/a.go.md": {"url: \"https://github.com/o/r/blob/c/internal/a.go\"\n"},
+		"internal/b.go.md": {"Identical to [[internal/a.go.md|internal/a.go]].\n"},
+	}
+	for name, want := range wants {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range want {
+			if !strings.Contains(string(data), s) {
+				t.Errorf("%s missing %q:\n%s", name, s, data)
+			}
+		}
+	}
+
+	if _, err := WriteVault(dir, []File{{Path: "gopack-index"}}, nil); err == nil {
+		t.Error("WriteVault() over its index succeeded")
+	}
+}
@@ -0,0 +1,183 @@
+package internal
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"strings"
+)
+
+// declBoundaries reports, for each line of a file, whether a top-level
+// declaration (with its doc comment) starts there, so that splitting the
+// file at those lines keeps each declaration whole. Go files are parsed;
+// other files use an indentation heuristic.
+func declBoundaries(name string, content []byte, lines [][]byte) []bool {
+	if path.Ext(strings.ReplaceAll(name, `\`, "/")) == ".go" {
+		if boundaries, ok := goDeclBoundaries(content, len(lines)); ok {
+			return boundaries
+		}
+	}
+
+	// A line at column 0 after a blank line usually starts a new top-level
+	// block (function, class, section) in most languages
+	boundaries := make([]bool, len(lines))
+	for i, line := range lines {
+		if i == 0 || len(bytes.TrimSpace(lines[i-1])) != 0 || len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' || strings.IndexByte(")]}", line[0]) >= 0 {
+			continue
+		}
+		boundaries[i] = true
+	}
+	return boundaries
+}
+
+// goDeclBoundaries marks the first line of every top-level declaration in
+// Go source, including its doc comment. It fails if the source doesn't
+// parse.
+func goDeclBoundaries(content []byte, lineCount int) ([]bool, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.ParseComments)
+	if err != nil {
+		return nil, false
+	}
+
+	boundaries := make([]bool, lineCount)
+	for _, decl := range file.Decls {
+		start := decl.Pos()
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+		case *ast.GenDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+		}
+		if line := fset.Position(start).Line - 1; line >= 0 && line < lineCount {
+			boundaries[line] = true
+		}
+	}
+	return boundaries, true
+}
+
+// OutlineFiles reduces the files matching patterns (see MatchesPath) to
+// their outlines, such as the signatures of a dependency's functions
+// without their bodies, and returns the files with the number changed.
+// Dedupe references and collapsed directories are left alone.
+func OutlineFiles(files []File, patterns []string) ([]File, int) {
+	if len(patterns) == 0 {
+		return files, 0
+	}
+	result := make([]File, len(files))
+	changed := 0
+	for i, file := range files {
+		result[i] = file
+		if file.DuplicateOf != "" || file.Omitted || !MatchesPath(file.Path, patterns) {
+			continue
+		}
+		if content, ok := outlineFile(file.Path, file.Content); ok {
+			result[i].Content = content
+			changed++
+		}
+	}
+	return result, changed
+}
+
+// outlineFile reduces a file to its outline: in Go, every function keeps
+// its doc comment and signature but its body becomes "{ ... }"; in other
+// languages, lines indented deeper than one level, usually the bodies of
+// functions and methods, are replaced by a "..." line. It reports whether
+// anything was left out.
+func outlineFile(name string, content []byte) ([]byte, bool) {
+	if DetectLanguage(name, content) == goLang {
+		if outline, ok := outlineGo(content); ok {
+			return outline, !bytes.Equal(outline, content)
+		}
+	}
+
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	unit := indentUnit(lines)
+	var out bytes.Buffer
+	elided := false
+	for i := 0; i < len(lines); i++ {
+		indent := indentWidth(lines[i])
+		if len(bytes.TrimSpace(lines[i])) == 0 || indent <= unit {
+			out.Write(lines[i])
+			continue
+		}
+		// A run of deeper lines, blank lines within it included
+		j := i
+		for j < len(lines) && (len(bytes.TrimSpace(lines[j])) == 0 || indentWidth(lines[j]) > unit) {
+			j++
+		}
+		for j > i+1 && len(bytes.TrimSpace(lines[j-1])) == 0 {
+			j--
+		}
+		out.Write(lines[i][:len(lines[i])-len(bytes.TrimLeft(lines[i], " \t"))])
+		out.WriteString("...\n")
+		elided = true
+		i = j - 1
+	}
+	return out.Bytes(), elided
+}
+
+// outlineGo replaces the body of every function in Go source with
+// "{ ... }". It fails if the source doesn't parse.
+func outlineGo(content []byte) ([]byte, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.ParseComments)
+	if err != nil {
+		return nil, false
+	}
+	var out bytes.Buffer
+	last := 0
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		start, end := fset.Position(fn.Body.Lbrace).Offset, fset.Position(fn.Body.Rbrace).Offset+1
+		out.Write(content[last:start])
+		out.WriteString("{ ... }")
+		last = end
+	}
+	out.Write(content[last:])
+	return out.Bytes(), true
+}
+
+// indentUnit returns the width of the smallest indentation among lines, as
+// counted by indentWidth, or 4 if none are indented.
+func indentUnit(lines [][]byte) int {
+	unit := 0
+	for _, line := range lines {
+		if width := indentWidth(line); width > 0 && len(bytes.TrimSpace(line)) > 0 && (unit == 0 || width < unit) {
+			unit = width
+		}
+	}
+	if unit == 0 {
+		return 4
+	}
+	return unit
+}
+
+// indentWidth returns the width of a line's leading whitespace, counting a
+// tab as four spaces.
+func indentWidth(line []byte) int {
+	width := 0
+	for _, b := range line {
+		switch b {
+		case ' ':
+			width++
+		case '\t':
+			width += 4
+		default:
+			return width
+		}
+	}
+	return width
+}
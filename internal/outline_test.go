@@ -0,0 +1,56 @@
+package internal
+
+import "testing"
+
+func TestOutlineFile(t *testing.T) {
+	tests := []struct {
+		name, path, content, want string
+	}{
+		{
+			name:    "go",
+			path:    "a.go",
+			content: "package a\n\n// F adds.\nfunc F(a, b int) int {\n\treturn a + b\n}\n\ntype T struct{ X int }\n",
+			want:    "package a\n\n// F adds.\nfunc F(a, b int) int { ... }\n\ntype T struct{ X int }\n",
+		},
+		{
+			name:    "python",
+			path:    "a.py",
+			content: "class A:\n    def f(self):\n        x = 1\n\n        return x\n\n    def g(self):\n        pass\n",
+			want:    "class A:\n    def f(self):\n        ...\n\n    def g(self):\n        ...\n",
+		},
+		{
+			name:    "nothing deep",
+			path:    "a.txt",
+			content: "one\n  two\n",
+			want:    "one\n  two\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := outlineFile(tt.path, []byte(tt.content))
+			if string(got) != tt.want || changed != (tt.want != tt.content) {
+				t.Errorf("outlineFile() = %q, %v; want %q, %v", got, changed, tt.want, tt.want != tt.content)
+			}
+		})
+	}
+}
+
+func TestOutlineFiles(t *testing.T) {
+	body := "package a\n\nfunc F() {\n\treturn\n}\n"
+	files := []File{
+		{Path: "main.go", Content: []byte(body)},
+		{Path: "github.com/x/y@v1.0.0/y.go", Content: []byte(body)},
+		{Path: "libs/shared/z.go", Content: []byte(body)},
+		{Path: "libs/shared/dup.go", Content: []byte("[identical to libs/shared/z.go]\n"), DuplicateOf: "libs/shared/z.go"},
+	}
+	got, count := OutlineFiles(files, []string{"github.com/**", "libs/**"})
+	if count != 2 {
+		t.Errorf("OutlineFiles() changed %d files, want 2", count)
+	}
+	outlined := "package a\n\nfunc F() { ... }\n"
+	for i, want := range []string{body, outlined, outlined, string(files[3].Content)} {
+		if string(got[i].Content) != want {
+			t.Errorf("%s = %q, want %q", got[i].Path, got[i].Content, want)
+		}
+	}
+}
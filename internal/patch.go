@@ -0,0 +1,335 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// PatchFiles returns the paths of the files touched by a unified diff, in
+// order of first appearance. Git-style "a/" and "b/" prefixes are removed,
+// and deleted files are reported by their old path.
+func PatchFiles(r io.Reader) ([]string, error) {
+	var paths []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if path != "" && !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+
+	var oldPath string
+	var inHeader bool
+	var oldLines, newLines int // lines left in the current hunk
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Inside a hunk, "--- " and "+++ " are removed and added lines
+		if oldLines > 0 || newLines > 0 {
+			switch {
+			case strings.HasPrefix(line, "-"):
+				oldLines--
+			case strings.HasPrefix(line, "+"):
+				newLines--
+			case strings.HasPrefix(line, `\`):
+				// "\ No newline at end of file"
+			default:
+				oldLines--
+				newLines--
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "@@ "):
+			oldLines, newLines = hunkLengths(line)
+		case strings.HasPrefix(line, "--- "):
+			oldPath = patchPath(line[4:], "a/")
+			inHeader = true
+			continue
+		case inHeader && strings.HasPrefix(line, "+++ "):
+			newPath := patchPath(line[4:], "b/")
+			if newPath == "" {
+				// File was deleted; fall back to its old name
+				newPath = oldPath
+			}
+			add(newPath)
+		}
+		inHeader = false
+	}
+
+	return paths, scanner.Err()
+}
+
+// hunkLengths returns the old and new line counts from a hunk header such
+// as "@@ -12,5 +12,7 @@". An omitted count means one line.
+func hunkLengths(header string) (oldLines, newLines int) {
+	fields := strings.Fields(header)
+	if len(fields) < 3 {
+		return 0, 0
+	}
+	return hunkLength(fields[1], "-"), hunkLength(fields[2], "+")
+}
+
+// hunkLength parses one "-start,count" or "+start,count" range.
+func hunkLength(field, sign string) int {
+	field, ok := strings.CutPrefix(field, sign)
+	if !ok {
+		return 0
+	}
+	_, count, found := strings.Cut(field, ",")
+	if !found {
+		return 1
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// patchPath extracts a file path from a "---" or "+++" header value,
+// returning "" for /dev/null.
+func patchPath(value, prefix string) string {
+	// Plain diff -u appends a tab and timestamp
+	if i := strings.IndexByte(value, '\t'); i >= 0 {
+		value = value[:i]
+	}
+	value = strings.TrimSpace(value)
+	if value == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(value, prefix)
+}
+
+// patchHunk is one "@@" hunk of a unified diff.
+type patchHunk struct {
+	start    int      // first old line, counting from 1
+	old, new []string // lines without their prefix or line break
+	blanks   int      // trailing lines that were completely empty
+}
+
+// patchSection is the part of a unified diff that changes one file.
+type patchSection struct {
+	oldPath, newPath string // "" for /dev/null
+	hunks            []patchHunk
+}
+
+// ApplyPatch applies a unified diff to the files it touches, reading their
+// current content with read (given slash-separated paths as they appear in
+// the diff), and returns the new content of each, in order of first
+// appearance. Hunks are located by their content near the line they claim,
+// not by their line numbers and counts alone, since diffs written by hand
+// or by an LLM often get those wrong. Patches that delete files are
+// rejected.
+func ApplyPatch(patch string, read func(path string) ([]byte, error)) ([]File, error) {
+	sections, err := parsePatch(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []File
+	index := make(map[string]int)
+	for _, section := range sections {
+		if section.newPath == "" {
+			return nil, fmt.Errorf("%s: patches that delete files aren't supported", section.oldPath)
+		}
+		if err := checkPackPath(section.newPath); err != nil {
+			return nil, err
+		}
+
+		// Later sections for the same file apply on top of earlier ones
+		var content []byte
+		source := section.oldPath
+		if i, ok := index[source]; ok {
+			content = files[i].Content
+		} else if source != "" {
+			if err := checkPackPath(source); err != nil {
+				return nil, err
+			}
+			if content, err = read(source); err != nil {
+				return nil, fmt.Errorf("%s: %w", source, err)
+			}
+		}
+
+		content, err := applyHunks(content, section.hunks)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", section.newPath, err)
+		}
+		if i, ok := index[section.newPath]; ok {
+			files[i].Content = content
+		} else {
+			index[section.newPath] = len(files)
+			files = append(files, File{Path: section.newPath, Content: content})
+		}
+	}
+	return files, nil
+}
+
+// parsePatch splits a unified diff into per-file sections. A hunk ends at
+// the next hunk or file header, or at a line that isn't part of a diff; the
+// counts in hunk headers are ignored.
+func parsePatch(patch string) ([]patchSection, error) {
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	var sections []patchSection
+	var hunk *patchHunk
+
+	// Empty lines at the end of a hunk are more likely to separate it from
+	// what follows than to be context
+	endHunk := func() {
+		if hunk != nil {
+			hunk.old = hunk.old[:len(hunk.old)-hunk.blanks]
+			hunk.new = hunk.new[:len(hunk.new)-hunk.blanks]
+			hunk = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		// File headers
+		if strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") {
+			endHunk()
+			sections = append(sections, patchSection{
+				oldPath: patchPath(line[4:], "a/"),
+				newPath: patchPath(lines[i+1][4:], "b/"),
+			})
+			i++
+			continue
+		}
+
+		if strings.HasPrefix(line, "@@") {
+			if len(sections) == 0 {
+				return nil, fmt.Errorf("hunk without a \"---\"/\"+++\" file header")
+			}
+			endHunk()
+			section := &sections[len(sections)-1]
+			section.hunks = append(section.hunks, patchHunk{start: hunkStart(line)})
+			hunk = &section.hunks[len(section.hunks)-1]
+			continue
+		}
+		if hunk == nil {
+			continue
+		}
+
+		if line == "" {
+			// A blank context line whose leading space was lost
+			hunk.old = append(hunk.old, "")
+			hunk.new = append(hunk.new, "")
+			hunk.blanks++
+			continue
+		}
+		hunk.blanks = 0
+		switch {
+		case line[0] == ' ':
+			hunk.old = append(hunk.old, line[1:])
+			hunk.new = append(hunk.new, line[1:])
+		case line[0] == '-':
+			hunk.old = append(hunk.old, line[1:])
+		case line[0] == '+':
+			hunk.new = append(hunk.new, line[1:])
+		case line[0] == '\\':
+			// "\ No newline at end of file"
+		default:
+			endHunk()
+		}
+	}
+	endHunk()
+
+	if len(sections) == 0 {
+		return nil, fmt.Errorf("no \"---\"/\"+++\" file headers found")
+	}
+	return sections, nil
+}
+
+// hunkStart returns the first old line from a hunk header such as
+// "@@ -12,5 +12,7 @@", or 0 if it's missing.
+func hunkStart(header string) int {
+	fields := strings.Fields(header)
+	if len(fields) < 2 {
+		return 0
+	}
+	start, _, _ := strings.Cut(strings.TrimPrefix(fields[1], "-"), ",")
+	n, err := strconv.Atoi(start)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// applyHunks applies hunks in order to content.
+func applyHunks(content []byte, hunks []patchHunk) ([]byte, error) {
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+	trailingNewline := text == "" || strings.HasSuffix(text, "\n")
+	var lines []string
+	if text != "" {
+		lines = strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	}
+
+	offset := 0 // lines added minus lines removed by earlier hunks
+	for n, hunk := range hunks {
+		hint := min(max(hunk.start-1+offset, 0), len(lines))
+		if hunk.start == 0 && len(hunk.old) == 0 {
+			hint = 0
+		}
+		at := findLines(lines, hunk.old, hint)
+		if at < 0 {
+			return nil, fmt.Errorf("hunk %d doesn't match the file", n+1)
+		}
+		lines = slices.Concat(lines[:at], hunk.new, lines[at+len(hunk.old):])
+		offset += len(hunk.new) - len(hunk.old)
+	}
+
+	out := strings.Join(lines, "\n")
+	if trailingNewline && len(lines) > 0 {
+		out += "\n"
+	}
+	return []byte(out), nil
+}
+
+// findLines returns where want occurs in lines, preferring the occurrence
+// closest to hint, or -1. Trailing whitespace is ignored if there is no
+// exact match.
+func findLines(lines, want []string, hint int) int {
+	if len(want) == 0 {
+		return hint
+	}
+	for _, trim := range []bool{false, true} {
+		for d := 0; d <= len(lines); d++ {
+			for _, at := range []int{hint - d, hint + d} {
+				if at < 0 || at+len(want) > len(lines) || (d == 0 && at != hint) {
+					continue
+				}
+				if linesMatch(lines[at:at+len(want)], want, trim) {
+					return at
+				}
+			}
+		}
+	}
+	return -1
+}
+
+// linesMatch compares two runs of lines, optionally ignoring trailing
+// whitespace.
+func linesMatch(a, b []string, trim bool) bool {
+	for i := range a {
+		x, y := a[i], b[i]
+		if trim {
+			x, y = strings.TrimRight(x, " \t"), strings.TrimRight(y, " \t")
+		}
+		if x != y {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,215 @@
+package internal
+
+import (
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPatchFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  []string
+	}{
+		{
+			name: "git diff",
+			patch: `diff --git a/internal/a.go b/internal/a.go
+index 1111111..2222222 100644
+--- a/internal/a.go
++++ b/internal/a.go
+@@ -1,2 +1,2 @@
+ package internal
+-var x = 1
++var x = 2
+`,
+			want: []string{"internal/a.go"},
+		},
+		{
+			name:  "plain diff -u with timestamps",
+			patch: "--- main.go\t2024-06-01 10:00:00\n+++ main.go\t2024-06-02 10:00:00\n@@ -1 +1 @@\n-a\n+b\n",
+			want:  []string{"main.go"},
+		},
+		{
+			name: "deleted file uses old path",
+			patch: `--- a/old.go
++++ /dev/null
+@@ -1,1 +0,0 @@
+-package old
+`,
+			want: []string{"old.go"},
+		},
+		{
+			name: "new file",
+			patch: `--- /dev/null
++++ b/new.go
+@@ -0,0 +1 @@
++package new
+`,
+			want: []string{"new.go"},
+		},
+		{
+			name: "comment lines inside a hunk are not headers",
+			patch: `--- a/schema.sql
++++ b/schema.sql
+@@ -1,3 +1,3 @@
+ SELECT 1;
+--- old comment
++++ new comment
+ SELECT 2;
+--- a/second.sql
++++ b/second.sql
+@@ -1 +1 @@
+-x
++y
+`,
+			want: []string{"schema.sql", "second.sql"},
+		},
+		{
+			name: "no newline marker",
+			patch: `--- a/a.txt
++++ b/a.txt
+@@ -1 +1 @@
+-a
+\ No newline at end of file
++b
+\ No newline at end of file
+--- a/b.txt
++++ b/b.txt
+@@ -1 +1 @@
+-a
++b
+`,
+			want: []string{"a.txt", "b.txt"},
+		},
+		{
+			name: "repeated file listed once",
+			patch: `--- a/a.go
++++ b/a.go
+@@ -1 +1 @@
+-a
++b
+--- a/a.go
++++ b/a.go
+@@ -9 +9 @@
+-c
++d
+`,
+			want: []string{"a.go"},
+		},
+		{
+			name:  "not a patch",
+			patch: "hello\nworld\n",
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PatchFiles(strings.NewReader(tt.patch))
+			if err != nil {
+				t.Fatalf("PatchFiles() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("PatchFiles() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	disk := map[string]string{
+		"main.go": "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n\nfunc helper() {\n\treturn\n}\n",
+		"notes":   "one\r\ntwo\r\nthree\r\n",
+	}
+	read := func(path string) ([]byte, error) {
+		content, ok := disk[path]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return []byte(content), nil
+	}
+
+	tests := []struct {
+		name  string
+		patch string
+		want  map[string]string
+	}{
+		{
+			name: "git diff",
+			patch: `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -3,3 +3,3 @@
+ func main() {
+-	println("hi")
++	println("hello")
+ }
+`,
+			want: map[string]string{"main.go": "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n\nfunc helper() {\n\treturn\n}\n"},
+		},
+		{
+			name: "wrong line numbers and counts",
+			patch: `--- a/main.go
++++ b/main.go
+@@ -1,1 +1,1 @@
+ func helper() {
+-	return
++	return // done
++
+ }
+`,
+			want: map[string]string{"main.go": "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n\nfunc helper() {\n\treturn // done\n\n}\n"},
+		},
+		{
+			name:  "blank context lines without their space",
+			patch: "--- a/main.go\n+++ b/main.go\n@@ -5,3 +5,3 @@\n }\n\n-func helper() {\n+func helper2() {\n\n",
+			want:  map[string]string{"main.go": "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n\nfunc helper2() {\n\treturn\n}\n"},
+		},
+		{
+			name:  "new file",
+			patch: "--- /dev/null\n+++ b/docs/new.md\n@@ -0,0 +1,2 @@\n+# New\n+text\n",
+			want:  map[string]string{"docs/new.md": "# New\ntext\n"},
+		},
+		{
+			name:  "CRLF file",
+			patch: "--- a/notes\n+++ b/notes\n@@ -2 +2 @@\n-two\n+2\n",
+			want:  map[string]string{"notes": "one\n2\nthree\n"},
+		},
+		{
+			name:  "two sections for one file",
+			patch: "--- a/notes\n+++ b/notes\n@@ -1 +1 @@\n-one\n+1\n--- a/notes\n+++ b/notes\n@@ -3 +3 @@\n-three\n+3\n",
+			want:  map[string]string{"notes": "1\ntwo\n3\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		files, err := ApplyPatch(tt.patch, read)
+		if err != nil {
+			t.Errorf("%s: ApplyPatch() error = %v", tt.name, err)
+			continue
+		}
+		got := make(map[string]string)
+		for _, file := range files {
+			got[file.Path] = string(file.Content)
+		}
+		if !maps.Equal(got, tt.want) {
+			t.Errorf("%s: ApplyPatch() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	failures := map[string]string{
+		"context mismatch": "--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-package other\n+package main2\n",
+		"missing file":     "--- a/gone.go\n+++ b/gone.go\n@@ -1 +1 @@\n-a\n+b\n",
+		"deletion":         "--- a/main.go\n+++ /dev/null\n@@ -1 +0,0 @@\n-package main\n",
+		"unsafe path":      "--- /dev/null\n+++ b/../escape.go\n@@ -0,0 +1 @@\n+x\n",
+		"no headers":       "@@ -1 +1 @@\n-a\n+b\n",
+	}
+	for name, patch := range failures {
+		if _, err := ApplyPatch(patch, read); err == nil {
+			t.Errorf("%s: ApplyPatch() succeeded, want error", name)
+		}
+	}
+}
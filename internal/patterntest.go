@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PatternTester checks paths against ignore patterns with the matcher the
+// walker uses, without walking a tree, to show what a pattern does before
+// it goes into an ignore file. All patterns are matched as if written in
+// one ignore file at the root: later patterns take precedence, and a
+// negated pattern re-includes what earlier ones ignored.
+type PatternTester struct {
+	IgnoreCase bool // match case-insensitively, as on macOS and Windows
+	rules      []ignoreRule
+}
+
+// PatternMatch is the result of checking one path.
+type PatternMatch struct {
+	Path    string
+	Ignored bool
+	Pattern string // the deciding pattern, or "" if none matched
+	Source  string // where the deciding pattern was written, such as ".gitignore:3", or "" if given directly
+}
+
+// Add adds a pattern, following git's rules.
+func (t *PatternTester) Add(pattern string) {
+	t.rules = append(t.rules, newIgnoreRule(pattern, ""))
+}
+
+// AddFile adds the patterns in an ignore file, following Docker's rules
+// for a .dockerignore and git's otherwise.
+func (t *PatternTester) AddFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	t.rules = append(t.rules, parseIgnoreFile(file, filepath.ToSlash(path), isDockerignore(path))...)
+	return nil
+}
+
+// Patterns returns the patterns added so far, with where each was written.
+func (t *PatternTester) Patterns() []PatternMatch {
+	patterns := make([]PatternMatch, len(t.rules))
+	for i, rule := range t.rules {
+		patterns[i] = PatternMatch{Pattern: rule.pattern, Source: rule.source}
+	}
+	return patterns
+}
+
+// Match checks a slash-separated path, relative to where the patterns
+// apply. A trailing "/" marks a directory, which patterns ending in "/"
+// require.
+func (t *PatternTester) Match(path string) PatternMatch {
+	result := PatternMatch{Path: path}
+	relPath := NormalizePath(strings.TrimPrefix(filepath.ToSlash(path), "./"))
+	isDir := strings.HasSuffix(relPath, "/")
+	relPath = strings.TrimSuffix(relPath, "/")
+	if t.IgnoreCase {
+		relPath = strings.ToLower(relPath)
+	}
+
+	parts := strings.Split(relPath, "/")
+	for _, rule := range t.rules {
+		if rule.match(parts, isDir, t.IgnoreCase) {
+			result.Ignored = !rule.negated()
+			result.Pattern, result.Source = rule.pattern, rule.source
+		}
+	}
+	return result
+}
+
+// String describes the result for a person, such as
+// `ignored build/out.js (.gitignore:3: "build/")`.
+func (m PatternMatch) String() string {
+	verdict := "included"
+	if m.Ignored {
+		verdict = "ignored"
+	}
+	switch {
+	case m.Pattern == "":
+		return fmt.Sprintf("%-8s %s", verdict, m.Path)
+	case m.Source == "":
+		return fmt.Sprintf("%-8s %s (%q)", verdict, m.Path, m.Pattern)
+	}
+	return fmt.Sprintf("%-8s %s (%s: %q)", verdict, m.Path, m.Source, m.Pattern)
+}
@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPatternTester(t *testing.T) {
+	tests := []struct {
+		name       string
+		patterns   []string
+		ignoreCase bool
+		path       string
+		want       string
+	}{
+		{"double star", []string{"**/fixtures/**"}, false, "internal/fixtures/a.json", `ignored  internal/fixtures/a.json ("**/fixtures/**")`},
+		{"double star outside", []string{"**/fixtures/**"}, false, "internal/a.json", "included internal/a.json"},
+		{"unanchored name", []string{"*.log"}, false, "logs/debug.log", `ignored  logs/debug.log ("*.log")`},
+		{"anchored", []string{"/build"}, false, "sub/build/out.js", "included sub/build/out.js"},
+		{"inside ignored directory", []string{"build/"}, false, "build/out.js", `ignored  build/out.js ("build/")`},
+		{"directory only on a file", []string{"build/"}, false, "build", "included build"},
+		{"directory only on a directory", []string{"build/"}, false, "build/", `ignored  build/ ("build/")`},
+		{"negated", []string{"*.log", "!keep.log"}, false, "keep.log", `included keep.log ("!keep.log")`},
+		{"later wins", []string{"!keep.log", "*.log"}, false, "keep.log", `ignored  keep.log ("*.log")`},
+		{"case sensitive", []string{"*.LOG"}, false, "a.log", "included a.log"},
+		{"ignore case", []string{"*.LOG"}, true, "A.log", `ignored  A.log ("*.LOG")`},
+		{"dot slash", []string{"/a.go"}, false, "./a.go", `ignored  ./a.go ("/a.go")`},
+		{"decomposed accent", []string{"caf\u00e9.txt"}, false, "cafe\u0301.txt", "ignored  cafe\u0301.txt (\"caf\u00e9.txt\")"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tester := &PatternTester{IgnoreCase: tt.ignoreCase}
+			for _, pattern := range tt.patterns {
+				tester.Add(pattern)
+			}
+			if got := tester.Match(tt.path).String(); got != tt.want {
+				t.Errorf("Match(%q) = %s, want %s", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatternTesterAddFile(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".gitignore":    "# build output\n*.log\n\n!keep.log\n",
+		".dockerignore": "*.md\n",
+	})
+
+	tester := &PatternTester{}
+	for _, name := range []string{".gitignore", ".dockerignore"} {
+		if err := tester.AddFile(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	source := filepath.ToSlash(filepath.Join(dir, ".gitignore"))
+	docker := filepath.ToSlash(filepath.Join(dir, ".dockerignore"))
+	tests := []struct {
+		path    string
+		ignored bool
+		source  string
+	}{
+		{"a.log", true, source + ":2"},
+		{"keep.log", false, source + ":4"},
+		{"README.md", true, docker + ":1"},
+		{"docs/README.md", false, ""}, // Docker patterns match from the root
+	}
+	for _, tt := range tests {
+		got := tester.Match(tt.path)
+		if got.Ignored != tt.ignored || got.Source != tt.source {
+			t.Errorf("Match(%q) = ignored %v by %q, want ignored %v by %q", tt.path, got.Ignored, got.Source, tt.ignored, tt.source)
+		}
+	}
+	if got := len(tester.Patterns()); got != 3 {
+		t.Errorf("len(Patterns()) = %d, want 3", got)
+	}
+
+	if err := tester.AddFile(filepath.Join(dir, "missing")); err == nil {
+		t.Error("AddFile(missing) succeeded, want error")
+	}
+}
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// gitHubRemote matches the GitHub remote URL forms: https, ssh, and
+// scp-style ("git@github.com:org/repo.git").
+var gitHubRemote = regexp.MustCompile(`^(?:https?://(?:[^@/]+@)?|ssh://git@|git@)github\.com[:/]([^/]+)/([^/]+?)(?:\.git)?/?$`)
+
+// GitHubRepoURL returns the web URL of the GitHub repository a git remote
+// points to, or "" if it isn't on GitHub.
+func GitHubRepoURL(remote string) string {
+	m := gitHubRemote.FindStringSubmatch(remote)
+	if m == nil {
+		return ""
+	}
+	return "https://github.com/" + m[1] + "/" + m[2]
+}
+
+// AddPermalinks sets the URL of each file tracked in the git repository at
+// dir to a permalink to it on GitHub, at the commit checked out. Paths are
+// relative to dir, which may be a subdirectory of the repository; other
+// files, such as pseudo-files, are left without a link. It returns how
+// many files were linked, and fails if the repository has no GitHub
+// remote.
+func AddPermalinks(ctx context.Context, files []File, dir string) (int, error) {
+	remote, _, commit := RepoInfo(ctx, dir)
+	base := GitHubRepoURL(remote)
+	if base == "" {
+		return 0, fmt.Errorf("no GitHub origin remote in %s", dir)
+	}
+	if commit == "" {
+		return 0, fmt.Errorf("no commit checked out in %s", dir)
+	}
+	prefix, err := runGit(ctx, dir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return 0, err
+	}
+	out, err := runGit(ctx, dir, "ls-files", "-z")
+	if err != nil {
+		return 0, err
+	}
+	tracked := make(map[string]bool)
+	for _, name := range strings.Split(out, "\x00") {
+		tracked[name] = true
+	}
+
+	linked := 0
+	for i, file := range files {
+		name := strings.ReplaceAll(file.Path, `\`, "/")
+		if !tracked[name] {
+			continue
+		}
+		var escaped []string
+		for _, part := range strings.Split(path.Join(strings.TrimSpace(prefix), name), "/") {
+			escaped = append(escaped, url.PathEscape(part))
+		}
+		files[i].URL = base + "/blob/" + commit + "/" + strings.Join(escaped, "/")
+		linked++
+	}
+	return linked, nil
+}
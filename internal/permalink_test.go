@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestGitHubRepoURL(t *testing.T) {
+	tests := map[string]string{
+		"git@github.com:org/app.git":                "https://github.com/org/app",
+		"https://github.com/org/app.git":            "https://github.com/org/app",
+		"https://github.com/org/app":                "https://github.com/org/app",
+		"https://token@github.com/org/app.git":      "https://github.com/org/app",
+		"ssh://git@github.com/org/app.git":          "https://github.com/org/app",
+		"https://gitlab.com/org/app.git":            "",
+		"https://github.com.evil.example/org/app":   "",
+		"https://github.com/org/app/tree/main/docs": "",
+	}
+	for remote, want := range tests {
+		if got := GitHubRepoURL(remote); got != want {
+			t.Errorf("GitHubRepoURL(%q) = %q, want %q", remote, got, want)
+		}
+	}
+}
+
+func TestAddPermalinks(t *testing.T) {
+	dir := gitRepo(t)
+	files := []File{
+		{Path: "héllo.go"},
+		{Path: "b.go"},
+		{Path: "$ go vet ./..."},
+	}
+	if _, err := AddPermalinks(context.Background(), files, dir); err == nil {
+		t.Error("AddPermalinks() without a GitHub remote succeeded, want error")
+	}
+
+	if out, err := exec.Command("git", "-C", dir, "remote", "add", "origin", "git@github.com:org/app.git").CombinedOutput(); err != nil {
+		t.Fatalf("git remote add: %v\n%s", err, out)
+	}
+	n, err := AddPermalinks(context.Background(), files, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, commit := RepoInfo(context.Background(), dir)
+	want := "https://github.com/org/app/blob/" + commit + "/h%C3%A9llo.go"
+	if n != 2 || files[0].URL != want || !strings.HasSuffix(files[1].URL, "/b.go") || files[2].URL != "" {
+		t.Errorf("AddPermalinks() = %d, URLs %q, %q, %q; want 2 with %q first", n, files[0].URL, files[1].URL, files[2].URL, want)
+	}
+
+	formatter := NewFormatter(files[:1])
+	for _, format := range Formats {
+		formatter.OutputFormat = format
+		parsed, err := ParsePack([]byte(formatter.Format()))
+		if err != nil || len(parsed) != 1 || parsed[0].Path != "héllo.go" {
+			t.Errorf("ParsePack(%s with permalinks) = %v, %v", format, parsed, err)
+		}
+	}
+}
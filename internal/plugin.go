@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// PluginRequest is sent to a filter plugin for each file, as one JSON
+// object per line on its standard input.
+type PluginRequest struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// PluginResponse is a filter plugin's answer for one file, as one JSON
+// object per line on its standard output, in the order of the requests.
+// An empty object leaves the file unchanged.
+type PluginResponse struct {
+	// Content replaces the file's content when present.
+	Content *string `json:"content"`
+
+	// Skip drops the file from the pack.
+	Skip bool `json:"skip"`
+}
+
+// ApplyPlugin runs a filter plugin over files: it starts cmd, writes a
+// PluginRequest line for each file, and reads back one PluginResponse line
+// per file. It returns the files the plugin kept, with their new contents,
+// and the number it skipped. The plugin's stderr goes to gopack's unless
+// cmd.Stderr is already set.
+func ApplyPlugin(files []File, cmd *exec.Cmd) ([]File, int, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, 0, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, 0, err
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, 0, err
+	}
+
+	// Write requests while reading responses, so a plugin that answers as
+	// it goes never blocks on a full pipe
+	written := make(chan error, 1)
+	go func() {
+		writer := bufio.NewWriter(stdin)
+		encoder := json.NewEncoder(writer)
+		for _, file := range files {
+			req := PluginRequest{Path: filepath.ToSlash(file.Path), Content: string(file.Content)}
+			if err := encoder.Encode(req); err != nil {
+				stdin.Close()
+				written <- err
+				return
+			}
+		}
+		err := writer.Flush()
+		stdin.Close()
+		written <- err
+	}()
+
+	kept := files[:0:0]
+	var skipped int
+	decoder := json.NewDecoder(bufio.NewReader(stdout))
+	for i, file := range files {
+		var resp PluginResponse
+		if err := decoder.Decode(&resp); err != nil {
+			io.Copy(io.Discard, stdout)
+			waitErr := cmd.Wait()
+			if waitErr != nil {
+				return nil, 0, waitErr
+			}
+			if errors.Is(err, io.EOF) {
+				return nil, 0, fmt.Errorf("plugin answered %d of %d files", i, len(files))
+			}
+			return nil, 0, fmt.Errorf("invalid response for %s: %w", file.Path, err)
+		}
+		if resp.Skip {
+			skipped++
+			continue
+		}
+		if resp.Content != nil {
+			file.Content = []byte(*resp.Content)
+		}
+		kept = append(kept, file)
+	}
+
+	// Requests fail to write only when the plugin has stopped reading. That
+	// is fine once it has answered for every file, and otherwise its exit
+	// status explains more.
+	<-written
+	if err := cmd.Wait(); err != nil {
+		return nil, 0, err
+	}
+	return kept, skipped, nil
+}
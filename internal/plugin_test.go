@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestPluginProcess is not a real test: it acts as the filter plugin started
+// by the tests below, behaving as GOPACK_TEST_PLUGIN says.
+func TestPluginProcess(t *testing.T) {
+	mode := os.Getenv("GOPACK_TEST_PLUGIN")
+	if mode == "" {
+		return
+	}
+	decoder := json.NewDecoder(os.Stdin)
+	encoder := json.NewEncoder(os.Stdout)
+	for n := 0; ; n++ {
+		var req PluginRequest
+		if err := decoder.Decode(&req); err != nil {
+			break
+		}
+		switch {
+		case mode == "redact":
+			if strings.HasSuffix(req.Path, ".env") {
+				encoder.Encode(PluginResponse{Skip: true})
+				continue
+			}
+			content := strings.ReplaceAll(req.Content, "hunter2", "[REDACTED]")
+			encoder.Encode(PluginResponse{Content: &content})
+		case mode == "keep":
+			fmt.Println("{}")
+		case mode == "garbage":
+			fmt.Println("not json")
+		case mode == "crash" && n == 1:
+			fmt.Fprintln(os.Stderr, "plugin crashed")
+			os.Exit(3)
+		default:
+			fmt.Println("{}")
+		}
+	}
+	os.Exit(0)
+}
+
+// pluginCommand returns a command running TestPluginProcess in mode.
+func pluginCommand(mode string) *exec.Cmd {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestPluginProcess$")
+	cmd.Env = append(os.Environ(), "GOPACK_TEST_PLUGIN="+mode)
+	cmd.Stderr = &strings.Builder{}
+	return cmd
+}
+
+func TestApplyPlugin(t *testing.T) {
+	files := []File{
+		{Path: "main.go", Content: []byte("password := \"hunter2\"\n")},
+		{Path: "config/.env", Content: []byte("SECRET=hunter2\n")},
+		{Path: "README.md", Content: []byte("# App\n")},
+	}
+
+	kept, skipped, err := ApplyPlugin(files, pluginCommand("redact"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if skipped != 1 || len(kept) != 2 {
+		t.Fatalf("ApplyPlugin(redact) kept %d and skipped %d files, want 2 and 1", len(kept), skipped)
+	}
+	if got := string(kept[0].Content); got != "password := \"[REDACTED]\"\n" {
+		t.Errorf("redacted content = %q", got)
+	}
+	if kept[1].Path != "README.md" || string(kept[1].Content) != "# App\n" {
+		t.Errorf("second kept file = %s %q, want README.md unchanged", kept[1].Path, kept[1].Content)
+	}
+	if string(files[0].Content) != "password := \"hunter2\"\n" {
+		t.Error("ApplyPlugin modified its input")
+	}
+
+	// An empty response keeps the file as it was
+	kept, skipped, err = ApplyPlugin(files, pluginCommand("keep"))
+	if err != nil || skipped != 0 || len(kept) != 3 || string(kept[1].Content) != "SECRET=hunter2\n" {
+		t.Errorf("ApplyPlugin(keep) = %d files, %d skipped, %v; want all files unchanged", len(kept), skipped, err)
+	}
+
+	for _, mode := range []string{"garbage", "crash"} {
+		if _, _, err := ApplyPlugin(files, pluginCommand(mode)); err == nil {
+			t.Errorf("ApplyPlugin(%s) succeeded, want error", mode)
+		}
+	}
+}
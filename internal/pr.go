@@ -0,0 +1,192 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pullRequestURL matches links to a pull request, including its tabs
+// ("/files", "/commits").
+var pullRequestURL = regexp.MustCompile(`^https?://([^/]+)/([^/]+)/([^/]+)/pull/(\d+)(?:[/?#].*)?$`)
+
+// PullRequest is a GitHub pull request.
+type PullRequest struct {
+	Owner  string
+	Repo   string
+	Number int
+	api    githubAPI
+}
+
+// ParsePullRequest parses a pull request's URL, such as
+// "https://github.com/org/repo/pull/123", on github.com or a GitHub
+// Enterprise server named in GH_HOST.
+func ParsePullRequest(rawURL string) (PullRequest, error) {
+	m := pullRequestURL.FindStringSubmatch(rawURL)
+	if m == nil {
+		return PullRequest{}, fmt.Errorf("%q is not a pull request URL (expected e.g. https://github.com/org/repo/pull/123)", rawURL)
+	}
+	api, err := githubAPIFor(m[1])
+	if err != nil {
+		return PullRequest{}, err
+	}
+	number, _ := strconv.Atoi(m[4])
+	return PullRequest{Owner: m[2], Repo: m[3], Number: number, api: api}, nil
+}
+
+// String returns the pull request's short name, "org/repo#123".
+func (pr PullRequest) String() string {
+	return fmt.Sprintf("%s/%s#%d", pr.Owner, pr.Repo, pr.Number)
+}
+
+// PullRequestInfo describes a pull request and the files it changes.
+type PullRequestInfo struct {
+	Title   string
+	Body    string
+	Author  string
+	URL     string
+	Base    string // branch it merges into
+	Head    string // branch it merges from
+	HeadSHA string
+	Files   []PullRequestFile
+
+	headRepo string // "owner/repo" of the head branch, which may be a fork
+}
+
+// PullRequestFile is a file changed by a pull request.
+type PullRequestFile struct {
+	Path         string
+	Status       string // added, removed, modified, renamed, copied, changed, or unchanged
+	PreviousPath string // for renamed files
+}
+
+// FetchPullRequest fetches a pull request's description and the list of
+// files it changes from the GitHub API, authenticating with GITHUB_TOKEN or
+// GH_TOKEN if either is set, as private repositories need.
+func FetchPullRequest(ctx context.Context, pr PullRequest) (PullRequestInfo, error) {
+	var raw struct {
+		Title   string `json:"title"`
+		Body    string `json:"body"`
+		HTMLURL string `json:"html_url"`
+		User    struct {
+			Login string `json:"login"`
+		} `json:"user"`
+		Base struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+		Head struct {
+			Ref  string `json:"ref"`
+			SHA  string `json:"sha"`
+			Repo *struct {
+				FullName string `json:"full_name"`
+			} `json:"repo"`
+		} `json:"head"`
+	}
+	base := fmt.Sprintf("/repos/%s/%s/pulls/%d", pr.Owner, pr.Repo, pr.Number)
+	if err := pr.api.get(ctx, base, &raw); err != nil {
+		return PullRequestInfo{}, err
+	}
+	info := PullRequestInfo{
+		Title:    raw.Title,
+		Body:     raw.Body,
+		Author:   raw.User.Login,
+		URL:      raw.HTMLURL,
+		Base:     raw.Base.Ref,
+		Head:     raw.Head.Ref,
+		HeadSHA:  raw.Head.SHA,
+		headRepo: pr.Owner + "/" + pr.Repo,
+	}
+	if raw.Head.Repo != nil {
+		info.headRepo = raw.Head.Repo.FullName
+	}
+
+	// The API lists at most 3,000 files
+	files, err := getPages[struct {
+		Filename         string `json:"filename"`
+		Status           string `json:"status"`
+		PreviousFilename string `json:"previous_filename"`
+	}](ctx, pr.api, base+"/files")
+	if err != nil {
+		return info, err
+	}
+	for _, file := range files {
+		info.Files = append(info.Files, PullRequestFile{Path: file.Filename, Status: file.Status, PreviousPath: file.PreviousFilename})
+	}
+	return info, nil
+}
+
+// FetchPullRequestFiles downloads the full content of the files a pull
+// request adds or changes, as of its head commit, into a directory under
+// cacheDir named after the pull request, and returns that directory. Files
+// the pull request removes are left out. Any earlier copy is replaced.
+// The contents are downloaded through fetcher, within its concurrency and
+// rate limits and retrying as GitHub throttles them; its Get is set to
+// fetch from the API.
+func FetchPullRequestFiles(ctx context.Context, fetcher *Fetcher, pr PullRequest, info PullRequestInfo, cacheDir string) (string, error) {
+	var paths, urls []string
+	for _, file := range info.Files {
+		if file.Status == "removed" {
+			continue
+		}
+		if err := checkPackPath(file.Path); err != nil {
+			return "", err
+		}
+		paths = append(paths, file.Path)
+		urls = append(urls, fmt.Sprintf("%s/repos/%s/contents/%s?ref=%s", pr.api, info.headRepo, escapePath(file.Path), url.QueryEscape(info.HeadSHA)))
+	}
+	fetcher.Get = func(ctx context.Context, rawURL string) (File, error) {
+		content, err := pr.api.raw(ctx, strings.TrimPrefix(rawURL, string(pr.api)))
+		return File{Path: rawURL, Content: content}, err
+	}
+
+	name := fmt.Sprintf("%s-%s-pr%d", pr.Owner, pr.Repo, pr.Number)
+	return fetchInto(cacheDir, name, string(pr.api)+"/"+pr.String(), func(dir string) error {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		contents, err := fetcher.FetchAll(ctx, urls)
+		if err != nil {
+			return err
+		}
+		for i, content := range contents {
+			target := filepath.Join(dir, filepath.FromSlash(paths[i]))
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(target, content.Content, 0644); err != nil {
+				return fmt.Errorf("%s: %w", paths[i], err)
+			}
+		}
+		return nil
+	})
+}
+
+// PullRequestDiscussion fetches a pull request's conversation and returns
+// it as a Markdown pseudo-file labeled with the pull request's URL: its
+// title and description, then the comments on the conversation, the
+// reviews, and the review comments on lines of the diff, each in order.
+func PullRequestDiscussion(ctx context.Context, pr PullRequest, info PullRequestInfo) (File, error) {
+	thread, err := fetchThread(ctx, pr.api, pr.Owner, pr.Repo, pr.Number, true)
+	if err != nil {
+		return File{}, err
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "# %s (#%d)\n\n", info.Title, pr.Number)
+	fmt.Fprintf(&buf, "@%s wants to merge %s into %s.\n", info.Author, info.Head, info.Base)
+	if body := strings.TrimSpace(info.Body); body != "" {
+		fmt.Fprintf(&buf, "\n%s\n", body)
+	}
+	thread.write(&buf, "##")
+
+	label := info.URL
+	if label == "" {
+		label = pr.String()
+	}
+	return File{Path: label, Content: []byte(buf.String())}, nil
+}
@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePullRequest(t *testing.T) {
+	t.Setenv("GH_HOST", "ghe.other.example, Git.Corp.Example")
+	tests := []struct {
+		url     string
+		want    PullRequest
+		wantErr bool
+	}{
+		{"https://github.com/org/repo/pull/123", PullRequest{Owner: "org", Repo: "repo", Number: 123, api: "https://api.github.com"}, false},
+		{"https://github.com/org/repo/pull/7/files", PullRequest{Owner: "org", Repo: "repo", Number: 7, api: "https://api.github.com"}, false},
+		{"https://git.corp.example/team/svc/pull/42", PullRequest{Owner: "team", Repo: "svc", Number: 42, api: "https://git.corp.example/api/v3"}, false},
+		{"https://git.unknown.example/team/svc/pull/42", PullRequest{}, true},
+		{"https://github.com/org/repo/issues/123", PullRequest{}, true},
+		{"org/repo#123", PullRequest{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			got, err := ParsePullRequest(tt.url)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("ParsePullRequest(%q) = %+v, %v; want %+v, error %v", tt.url, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFetchPullRequest(t *testing.T) {
+	responses := map[string]string{
+		"/repos/org/repo/pulls/5": `{"title": "Add retries", "body": "Fixes #4.", "html_url": "https://github.com/org/repo/pull/5",
+			"user": {"login": "alice"}, "base": {"ref": "main"},
+			"head": {"ref": "retries", "sha": "abc123", "repo": {"full_name": "alice/repo"}}}`,
+		"/repos/org/repo/pulls/5/files":     `[{"filename": "client.go", "status": "modified"}, {"filename": "old.go", "status": "removed"}, {"filename": "docs/retry.md", "status": "renamed", "previous_filename": "docs/retries.md"}]`,
+		"/repos/org/repo/issues/5/comments": `[{"user": {"login": "bob"}, "body": "Nice."}]`,
+		"/repos/org/repo/pulls/5/reviews":   `[{"user": {"login": "carol"}, "state": "CHANGES_REQUESTED", "body": "See inline."}, {"user": {"login": "dan"}, "state": "COMMENTED", "body": ""}]`,
+		"/repos/org/repo/pulls/5/comments":  `[{"user": {"login": "carol"}, "path": "client.go", "line": 12, "body": "Cap the backoff."}]`,
+	}
+	contents := map[string]string{
+		"/repos/alice/repo/contents/client.go":     "package client\n",
+		"/repos/alice/repo/contents/docs/retry.md": "# Retries\n",
+	}
+	var auth string
+	throttled := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if content, ok := contents[r.URL.Path]; ok && r.URL.Query().Get("ref") == "abc123" {
+			// Throttle the first download, which the Fetcher retries
+			if !throttled {
+				throttled = true
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Write([]byte(content))
+			return
+		}
+		body, ok := responses[r.URL.Path]
+		if !ok || r.URL.Query().Get("page") != "1" && strings.HasPrefix(body, "[") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	t.Setenv("GITHUB_TOKEN", "secret")
+
+	pr := PullRequest{Owner: "org", Repo: "repo", Number: 5, api: githubAPI(server.URL)}
+	ctx := context.Background()
+	info, err := FetchPullRequest(ctx, pr)
+	if err != nil {
+		t.Fatalf("FetchPullRequest() error = %v", err)
+	}
+	if info.Title != "Add retries" || info.HeadSHA != "abc123" || len(info.Files) != 3 || info.Files[2].PreviousPath != "docs/retries.md" {
+		t.Errorf("FetchPullRequest() = %+v", info)
+	}
+	if auth != "Bearer secret" {
+		t.Errorf("Authorization = %q, want the GITHUB_TOKEN", auth)
+	}
+
+	fetcher := &Fetcher{Concurrency: 1, Retries: 1, backoff: time.Millisecond}
+	dir, err := FetchPullRequestFiles(ctx, fetcher, pr, info, t.TempDir())
+	if err != nil {
+		t.Fatalf("FetchPullRequestFiles() error = %v", err)
+	}
+	for name, want := range map[string]string{"client.go": "package client\n", "docs/retry.md": "# Retries\n"} {
+		if got := readFile(t, filepath.Join(dir, filepath.FromSlash(name))); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old.go")); !os.IsNotExist(err) {
+		t.Errorf("removed file was fetched (stat error %v)", err)
+	}
+
+	discussion, err := PullRequestDiscussion(ctx, pr, info)
+	if err != nil {
+		t.Fatalf("PullRequestDiscussion() error = %v", err)
+	}
+	want := `# Add retries (#5)
+
+@alice wants to merge retries into main.
+
+Fixes #4.
+
+## Conversation
+
+**@bob:**
+
+Nice.
+
+## Reviews
+
+**@carol (changes requested):**
+
+See inline.
+
+## Review comments
+
+**@carol on client.go:12:**
+
+Cap the backoff.
+`
+	if discussion.Path != "https://github.com/org/repo/pull/5" || string(discussion.Content) != want {
+		t.Errorf("PullRequestDiscussion() = %s:\n%s\nwant:\n%s", discussion.Path, discussion.Content, want)
+	}
+}
@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Preset bundles flag settings suited to a kind of task, such as a code
+// review. Flags set on the command line take precedence over the preset's.
+type Preset struct {
+	Description string `json:"description"`
+
+	// Flags maps flag names, without dashes, to their values: a string,
+	// number, or bool, or a list of them for repeatable flags.
+	Flags map[string]any `json:"flags"`
+}
+
+// Presets are the built-in presets. A project's ConfigFile can add more,
+// or replace these by name.
+var Presets = map[string]Preset{
+	"code-review": {
+		Description: "Review recent work: the most-changed files first, with review instructions",
+		Flags: map[string]any{
+			"format":        FormatMarkdown,
+			"sort":          "git-churn",
+			"churn-window":  "90d",
+			"strip-license": true,
+			"summary":       true,
+			"instructions": "Review this code as a senior engineer would. Point out bugs, unclear logic, missing error " +
+				"handling, and risky design choices, most serious first, naming the file and line of each.",
+		},
+	},
+	"bug-hunt": {
+		Description: "Look for bugs: code ordered bottom-up by dependencies, tests included, imports collapsed",
+		Flags: map[string]any{
+			"format":           FormatMarkdown,
+			"sort":             "deps",
+			"strip-license":    true,
+			"collapse-imports": DefaultCollapseLines,
+			"instructions": "Find bugs in this code: wrong logic, unhandled errors and edge cases, races, resource " +
+				"leaks, and security holes. For each, name the file and function, explain how it fails, and suggest a fix.",
+		},
+	},
+	"docs": {
+		Description: "Write documentation: entry points first, no tests, with an index of the exported API",
+		Flags: map[string]any{
+			"format":       FormatMarkdown,
+			"sort":         "go-entry",
+			"no-tests":     true,
+			"symbol-index": true,
+			"instructions": "Write documentation for this project: what it does and how it is organized, then how " +
+				"to use its main commands or APIs, with examples taken from the code.",
+		},
+	},
+}
+
+// LookupPreset returns the preset with the given name, from the project's
+// configuration or else the built-in ones.
+func LookupPreset(name string, config Config) (Preset, error) {
+	if preset, ok := config.Presets[name]; ok {
+		return preset, nil
+	}
+	if preset, ok := Presets[name]; ok {
+		return preset, nil
+	}
+	return Preset{}, fmt.Errorf("unknown preset %q (expected one of: %s)", name, strings.Join(PresetNames(config), ", "))
+}
+
+// PresetNames lists the names of the built-in presets and the project's,
+// sorted.
+func PresetNames(config Config) []string {
+	names := slices.Collect(maps.Keys(Presets))
+	for name := range config.Presets {
+		if _, ok := Presets[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// FlagValues returns the preset's flag values as the strings they'd be
+// given on the command line, a list of them for each flag.
+func (p Preset) FlagValues() (map[string][]string, error) {
+	values := make(map[string][]string, len(p.Flags))
+	for name, value := range p.Flags {
+		list, ok := value.([]any)
+		if !ok {
+			list = []any{value}
+		}
+		for _, item := range list {
+			switch item := item.(type) {
+			case string:
+				values[name] = append(values[name], item)
+			case bool:
+				values[name] = append(values[name], strconv.FormatBool(item))
+			case int:
+				values[name] = append(values[name], strconv.Itoa(item))
+			case float64:
+				values[name] = append(values[name], strconv.FormatFloat(item, 'f', -1, 64))
+			default:
+				return nil, fmt.Errorf("invalid value for --%s: %v", name, item)
+			}
+		}
+	}
+	return values, nil
+}
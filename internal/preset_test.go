@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestLookupPreset(t *testing.T) {
+	var config Config
+	err := json.Unmarshal([]byte(`{"presets": {
+		"docs": {"flags": {"format": "text"}},
+		"security": {"description": "Audit", "flags": {"ignore-pattern": ["docs/", "*.md"], "max-depth": 3, "no-tests": true}}
+	}}`), &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want map[string][]string
+	}{
+		{"docs", map[string][]string{"format": {"text"}}}, // the project's replaces the built-in
+		{"security", map[string][]string{"ignore-pattern": {"docs/", "*.md"}, "max-depth": {"3"}, "no-tests": {"true"}}},
+		{"bug-hunt", map[string][]string{
+			"format": {"markdown"}, "sort": {"deps"}, "strip-license": {"true"}, "collapse-imports": {"10"},
+			"instructions": {Presets["bug-hunt"].Flags["instructions"].(string)},
+		}},
+	}
+	for _, tt := range tests {
+		preset, err := LookupPreset(tt.name, config)
+		if err != nil {
+			t.Fatalf("LookupPreset(%q) error = %v", tt.name, err)
+		}
+		got, err := preset.FlagValues()
+		if err != nil {
+			t.Fatalf("LookupPreset(%q).FlagValues() error = %v", tt.name, err)
+		}
+		if !maps.EqualFunc(got, tt.want, slices.Equal) {
+			t.Errorf("LookupPreset(%q).FlagValues() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := LookupPreset("nope", config); err == nil || err.Error() != `unknown preset "nope" (expected one of: bug-hunt, code-review, docs, security)` {
+		t.Errorf("LookupPreset(nope) error = %v", err)
+	}
+	if _, err := (Preset{Flags: map[string]any{"format": map[string]any{}}}).FlagValues(); err == nil {
+		t.Error("FlagValues() with an object value succeeded, want error")
+	}
+}
@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Prioritize moves the files matching priority patterns to the front,
+// grouped by the first pattern each matches and in pattern order; other
+// files keep their relative order after them. Patterns are slash-separated
+// paths relative to the root and may be globs, including "**".
+func Prioritize(files []File, patterns []string) []File {
+	if len(patterns) == 0 {
+		return files
+	}
+
+	groups := make([][]File, len(patterns)+1)
+	for _, file := range files {
+		i := priorityIndex(file.Path, patterns)
+		if i < 0 {
+			i = len(patterns)
+		}
+		groups[i] = append(groups[i], file)
+	}
+
+	result := make([]File, 0, len(files))
+	for _, group := range groups {
+		result = append(result, group...)
+	}
+	return result
+}
+
+// IsPriority reports whether path matches one of the priority patterns.
+func IsPriority(path string, patterns []string) bool {
+	return MatchesPath(path, patterns)
+}
+
+// MatchesPath reports whether path matches one of patterns, which are
+// slash-separated paths relative to the root and may be globs, including
+// "**".
+func MatchesPath(path string, patterns []string) bool {
+	return priorityIndex(path, patterns) >= 0
+}
+
+// priorityIndex returns the index of the first pattern matching path, or
+// -1.
+func priorityIndex(path string, patterns []string) int {
+	path = NormalizePath(filepath.ToSlash(path))
+	for i, pattern := range patterns {
+		pattern = NormalizePath(strings.TrimPrefix(filepath.ToSlash(pattern), "./"))
+		if pattern == path || matchGlob(pattern, path) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,30 @@
+package internal
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPrioritize(t *testing.T) {
+	files := []File{{Path: "a.go"}, {Path: "cmd/root.go"}, {Path: "docs/x.md"}, {Path: "README.md"}, {Path: "docs/y.md"}}
+
+	tests := []struct {
+		patterns []string
+		want     []string
+	}{
+		{nil, []string{"a.go", "cmd/root.go", "docs/x.md", "README.md", "docs/y.md"}},
+		{[]string{"README.md", "./cmd/root.go"}, []string{"README.md", "cmd/root.go", "a.go", "docs/x.md", "docs/y.md"}},
+		{[]string{"docs/*.md", "a.go"}, []string{"docs/x.md", "docs/y.md", "a.go", "cmd/root.go", "README.md"}},
+		{[]string{"**/*.md"}, []string{"docs/x.md", "README.md", "docs/y.md", "a.go", "cmd/root.go"}},
+		{[]string{"missing.go"}, []string{"a.go", "cmd/root.go", "docs/x.md", "README.md", "docs/y.md"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, file := range Prioritize(files, tt.patterns) {
+			got = append(got, file.Path)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("Prioritize(%q) = %q, want %q", tt.patterns, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// RedactRule is a pattern of secrets to redact. If the pattern has a
+// capturing group, only what the first group matches is redacted, so a
+// rule can match "password = ..." but keep the name.
+type RedactRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// DefaultRedactRules catch the secrets most often committed by mistake.
+var DefaultRedactRules = []RedactRule{
+	{"private-key", regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{"aws-access-key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"github-token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"slack-token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{"stripe-key", regexp.MustCompile(`\b[rs]k_live_[A-Za-z0-9]{16,}\b`)},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{"password", regexp.MustCompile(`(?i)\b(?:password|passwd|pwd|secret|api[_-]?key|access[_-]?token|auth[_-]?token)["']?\s*[:=]\s*["']?([^\s"',;]{8,})`)},
+}
+
+// Redaction is a line with a secret in it.
+type Redaction struct {
+	Path string
+	Line int // 1-based
+	Rule string
+	Text string // the line as it was, without its line ending
+}
+
+// RedactRules returns DefaultRedactRules followed by custom rules, which
+// map names to regular expressions, in order of name.
+func RedactRules(custom map[string]string) ([]RedactRule, error) {
+	rules := slices.Clone(DefaultRedactRules)
+	names := make([]string, 0, len(custom))
+	for name := range custom {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		pattern, err := regexp.Compile(custom[name])
+		if err != nil {
+			return nil, fmt.Errorf("redact rule %q: %w", name, err)
+		}
+		rules = append(rules, RedactRule{Name: name, Pattern: pattern})
+	}
+	return rules, nil
+}
+
+// Redact replaces every secret the rules match in the files with
+// "[REDACTED:rule]", and returns the redacted files along with each line
+// that had one, in order. Where matches overlap, the earlier one wins. A
+// secret spanning lines, such as a private key, is listed on each. Dedupe
+// references and collapsed directories are left alone.
+func Redact(files []File, rules []RedactRule) ([]File, []Redaction) {
+	result := make([]File, len(files))
+	var redactions []Redaction
+	for i, file := range files {
+		result[i] = file
+		if file.DuplicateOf != "" || file.Omitted {
+			continue
+		}
+		content, found := redactContent(file, rules)
+		result[i].Content = content
+		redactions = append(redactions, found...)
+	}
+	return result, redactions
+}
+
+// redactSpan is a secret's position in a file's content.
+type redactSpan struct {
+	start, end int
+	rule       string
+}
+
+// redactContent redacts the secrets in one file.
+func redactContent(file File, rules []RedactRule) ([]byte, []Redaction) {
+	var spans []redactSpan
+	for _, rule := range rules {
+		for _, m := range rule.Pattern.FindAllSubmatchIndex(file.Content, -1) {
+			start, end := m[0], m[1]
+			if len(m) >= 4 && m[2] >= 0 {
+				start, end = m[2], m[3]
+			}
+			if end > start {
+				spans = append(spans, redactSpan{start, end, rule.Name})
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return file.Content, nil
+	}
+	slices.SortStableFunc(spans, func(a, b redactSpan) int { return a.start - b.start })
+
+	lines := bytes.SplitAfter(file.Content, []byte("\n"))
+	lineAt := func(offset int) int {
+		return bytes.Count(file.Content[:offset], []byte("\n"))
+	}
+	var out bytes.Buffer
+	var redactions []Redaction
+	last := 0
+	for _, span := range spans {
+		if span.start < last {
+			continue // overlaps a secret already redacted
+		}
+		out.Write(file.Content[last:span.start])
+		fmt.Fprintf(&out, "[REDACTED:%s]", span.rule)
+		last = span.end
+
+		for line := lineAt(span.start); line <= lineAt(span.end-1); line++ {
+			redactions = append(redactions, Redaction{
+				Path: file.Path,
+				Line: line + 1,
+				Rule: span.rule,
+				Text: strings.TrimRight(string(lines[line]), "\r\n"),
+			})
+		}
+	}
+	out.Write(file.Content[last:])
+	return out.Bytes(), redactions
+}
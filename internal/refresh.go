@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// RefreshResult is what RefreshPack did to a pack's sections.
+type RefreshResult struct {
+	Updated   []string // files whose sections were rewritten
+	Added     []string // files new since the pack, given sections of their own
+	Removed   []string // files no longer there, whose sections were dropped
+	Unchanged int
+	Skipped   int // pieces of split files, dedupe references, and placeholders
+
+	// Changes are the Updated, Added, and Removed files, with their old and
+	// new content, for Changelog
+	Changes []FileChange
+}
+
+// RefreshPack brings the file sections of a Markdown pack up to date with
+// the files as read now, and returns the refreshed pack. Only the code
+// blocks of files whose content changed are rewritten; everything else,
+// including notes added between the sections, is kept as it was. A file
+// that can't be found has its section dropped and is listed in Removed.
+// The files of present that the pack doesn't list yet, the files found
+// next to its own, get sections of their own before the summary and are
+// listed in Added. Pieces of split files, dedupe references, and the
+// placeholders of collapsed directories are left alone, as is the summary.
+//
+// Text packs can't be refreshed, as text added between their sections
+// can't be told apart from the files' content.
+func RefreshPack(data []byte, read func(path string) ([]byte, error), present []File) ([]byte, RefreshResult, error) {
+	var result RefreshResult
+	if format := PackFormat(data); format != FormatMarkdown {
+		return nil, result, fmt.Errorf("only Markdown packs can be refreshed, not %s", format)
+	}
+	lines := strings.SplitAfter(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+
+	var out bytes.Buffer
+	listed := make(map[string]bool)
+	summaryAt := -1 // where the summary starts in out
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSuffix(lines[i], "\n")
+		if line == "## Summary" && summaryAt < 0 {
+			summaryAt = out.Len()
+		}
+		sectionAt := out.Len()
+		out.WriteString(lines[i])
+		name, _, ok := markdownHeading(line)
+		if !ok {
+			continue
+		}
+		if m := pieceName.FindStringSubmatch(name); m != nil {
+			listed[m[1]] = true
+		} else {
+			listed[name] = true
+		}
+
+		// Keep the blank lines up to the opening fence
+		j := i + 1
+		for j < len(lines) && strings.TrimSpace(lines[j]) == "" {
+			out.WriteString(lines[j])
+			j++
+		}
+		if j == len(lines) {
+			return nil, result, fmt.Errorf("%s: missing content", name)
+		}
+		i = j - 1
+		if duplicateRef.MatchString(lines[j]) || omittedRef.MatchString(lines[j]) || pieceName.MatchString(name) {
+			result.Skipped++
+			continue
+		}
+
+		opening := strings.TrimSuffix(lines[j], "\n")
+		fence := opening[:len(opening)-len(strings.TrimLeft(opening, "`"))]
+		if len(fence) < 3 {
+			return nil, result, fmt.Errorf("%s: expected a code fence", name)
+		}
+		k := j + 1
+		for k < len(lines) && strings.TrimSuffix(lines[k], "\n") != fence {
+			k++
+		}
+		if k == len(lines) {
+			return nil, result, fmt.Errorf("%s: unterminated code fence", name)
+		}
+		if err := checkPackPath(name); err != nil {
+			return nil, result, err
+		}
+
+		old := []byte(strings.Join(lines[j+1:k], ""))
+		content, err := read(name)
+		switch {
+		case os.IsNotExist(err):
+			// Drop the section: its heading, the <details> around it in
+			// collapsible packs, and the blank line after it
+			if strings.HasPrefix(line, "<summary>") && bytes.HasSuffix(out.Bytes()[:sectionAt], []byte("<details>\n")) {
+				sectionAt -= len("<details>\n")
+			}
+			out.Truncate(sectionAt)
+			i = k
+			if i+1 < len(lines) && strings.TrimSpace(lines[i+1]) == "" {
+				i++
+			}
+			if i+1 < len(lines) && strings.TrimSuffix(lines[i+1], "\n") == "</details>" {
+				i++
+				if i+1 < len(lines) && strings.TrimSpace(lines[i+1]) == "" {
+					i++
+				}
+			}
+			result.Removed = append(result.Removed, name)
+			result.Changes = append(result.Changes, FileChange{Path: name, Kind: Removed, Old: old})
+			continue
+		case err != nil:
+			return nil, result, err
+		case SameContent(content, old):
+			result.Unchanged++
+			continue
+		}
+
+		// Rewrite the code block, keeping its info string
+		newFence := codeFence(content)
+		fmt.Fprintf(&out, "%s%s\n", newFence, opening[len(fence):])
+		out.Write(content)
+		if len(content) > 0 && !strings.HasSuffix(string(content), "\n") {
+			out.WriteString("\n")
+		}
+		out.WriteString(newFence)
+		if strings.HasSuffix(lines[k], "\n") {
+			out.WriteString("\n")
+		}
+		result.Updated = append(result.Updated, name)
+		result.Changes = append(result.Changes, FileChange{Path: name, Kind: Changed, Old: old, New: content})
+		i = k
+	}
+
+	// Add the new files before the summary, or at the end without one
+	var added bytes.Buffer
+	for _, file := range present {
+		if listed[file.Path] {
+			continue
+		}
+		if err := checkPackPath(file.Path); err != nil {
+			return nil, result, err
+		}
+		fence := codeFence(file.Content)
+		fmt.Fprintf(&added, "## File: %s\n\n%s%s\n", file.Path, fence, DetectLanguage(file.Path, file.Content).Fence)
+		added.Write(file.Content)
+		if len(file.Content) > 0 && !bytes.HasSuffix(file.Content, []byte("\n")) {
+			added.WriteString("\n")
+		}
+		added.WriteString(fence + "\n\n")
+		result.Added = append(result.Added, file.Path)
+		result.Changes = append(result.Changes, FileChange{Path: file.Path, Kind: Added, New: file.Content})
+	}
+	if added.Len() == 0 {
+		return out.Bytes(), result, nil
+	}
+	refreshed := out.Bytes()
+	if summaryAt < 0 {
+		if !bytes.HasSuffix(refreshed, []byte("\n")) {
+			refreshed = append(refreshed, '\n')
+		}
+		if !bytes.HasSuffix(refreshed, []byte("\n\n")) {
+			refreshed = append(refreshed, '\n')
+		}
+		return append(refreshed, bytes.TrimSuffix(added.Bytes(), []byte("\n"))...), result, nil
+	}
+	return slices.Concat(refreshed[:summaryAt], added.Bytes(), refreshed[summaryAt:]), result, nil
+}
@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRefreshPack(t *testing.T) {
+	pack := "## File: a.go\n\n```go\npackage a\n```\n\nNote: a is the entry point.\n\n" +
+		"## File: b.txt\n\n```text\nold\n```\n\n" +
+		"## File: c.go\n\n```go\npackage c\n```\n\n" +
+		"## File: gone.go\n\n```go\npackage gone\n```\n\n" +
+		"## File: copy.go\n\n[identical to a.go]\n\n" +
+		"## Summary\n\n- Files: 5\n"
+	disk := map[string]string{
+		"a.go":  "package a\n",
+		"b.txt": "new with ```fences```",
+		"c.go":  "package c\n\nfunc C() {}\n",
+	}
+	read := func(path string) ([]byte, error) {
+		content, ok := disk[path]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return []byte(content), nil
+	}
+
+	present := []File{
+		{Path: "a.go", Content: []byte("package a\n")},
+		{Path: "d.go", Content: []byte("package d\n")},
+	}
+	got, result, err := RefreshPack([]byte(pack), read, present)
+	if err != nil {
+		t.Fatalf("RefreshPack() error = %v", err)
+	}
+	want := "## File: a.go\n\n```go\npackage a\n```\n\nNote: a is the entry point.\n\n" +
+		"## File: b.txt\n\n````text\nnew with ```fences```\n````\n\n" +
+		"## File: c.go\n\n```go\npackage c\n\nfunc C() {}\n```\n\n" +
+		"## File: copy.go\n\n[identical to a.go]\n\n" +
+		"## File: d.go\n\n```go\npackage d\n```\n\n" +
+		"## Summary\n\n- Files: 5\n"
+	if string(got) != want {
+		t.Errorf("RefreshPack() =\n%s\nwant\n%s", got, want)
+	}
+	wantResult := RefreshResult{
+		Updated: []string{"b.txt", "c.go"}, Added: []string{"d.go"}, Removed: []string{"gone.go"}, Unchanged: 1, Skipped: 1,
+		Changes: []FileChange{
+			{Path: "b.txt", Kind: Changed, Old: []byte("old\n"), New: []byte("new with ```fences```")},
+			{Path: "c.go", Kind: Changed, Old: []byte("package c\n"), New: []byte("package c\n\nfunc C() {}\n")},
+			{Path: "gone.go", Kind: Removed, Old: []byte("package gone\n")},
+			{Path: "d.go", Kind: Added, New: []byte("package d\n")},
+		},
+	}
+	if !reflect.DeepEqual(result, wantResult) {
+		t.Errorf("RefreshPack() result = %+v, want %+v", result, wantResult)
+	}
+}
+
+func TestRefreshPackChangelog(t *testing.T) {
+	a := "## File: a.go\n\n```go\npackage a\n```\n\n"
+	b := "## File: b.go\n\n```go\npackage b\n```\n\n"
+	summary := "## Summary\n\n- Files: 2\n"
+	earlier := Changelog([]FileChange{{Path: "a.go", Kind: Changed, Old: []byte("x\n"), New: []byte("package a\n")}})
+
+	tests := []struct {
+		name    string
+		pack    string
+		disk    map[string]string
+		present []File
+		want    string
+	}{
+		{
+			name:    "added only",
+			pack:    a + summary,
+			disk:    map[string]string{"a.go": "package a\n", "b.go": "package b\n"},
+			present: []File{{Path: "a.go", Content: []byte("package a\n")}, {Path: "b.go", Content: []byte("package b\n")}},
+			want: ChangelogHeading + "\n\n- `b.go`: added, +1 -0\n\n1 file changed, 1 insertion(+), 0 deletions(-)\n\n" +
+				a + b + summary,
+		},
+		{
+			name: "removed only",
+			pack: a + b + summary,
+			disk: map[string]string{"a.go": "package a\n"},
+			want: ChangelogHeading + "\n\n- `b.go`: removed, +0 -1\n\n1 file changed, 0 insertions(+), 1 deletion(-)\n\n" +
+				a + summary,
+		},
+		{
+			name: "removed from a collapsible pack",
+			pack: "<details>\n<summary>a.go</summary>\n\n```go\npackage a\n```\n\n</details>\n\n" +
+				"<details>\n<summary>b.go</summary>\n\n```go\npackage b\n```\n\n</details>\n\n" + summary,
+			disk: map[string]string{"a.go": "package a\n"},
+			want: ChangelogHeading + "\n\n- `b.go`: removed, +0 -1\n\n1 file changed, 0 insertions(+), 1 deletion(-)\n\n" +
+				"<details>\n<summary>a.go</summary>\n\n```go\npackage a\n```\n\n</details>\n\n" + summary,
+		},
+		{
+			name: "no changes",
+			pack: earlier + a + b + summary,
+			disk: map[string]string{"a.go": "package a\n", "b.go": "package b\n"},
+			want: a + b + summary,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			read := func(path string) ([]byte, error) {
+				content, ok := tt.disk[path]
+				if !ok {
+					return nil, os.ErrNotExist
+				}
+				return []byte(content), nil
+			}
+			got, result, err := RefreshPack([]byte(tt.pack), read, tt.present)
+			if err != nil {
+				t.Fatalf("RefreshPack() error = %v", err)
+			}
+			got = PrependChangelog(got, Changelog(result.Changes))
+			if string(got) != tt.want {
+				t.Errorf("refreshed pack =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefreshPackErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		pack string
+		want string
+	}{
+		{"text pack", "File: a.go\npackage a\n", "only Markdown packs"},
+		{"unterminated fence", "## File: a.go\n\n```go\npackage a\n", "unterminated code fence"},
+		{"unsafe path", "## File: ../a.go\n\n```go\npackage a\n```\n", "unsafe path"},
+	}
+	read := func(path string) ([]byte, error) { return []byte("changed\n"), nil }
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := RefreshPack([]byte(tt.pack), read, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("RefreshPack() error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
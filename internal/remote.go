@@ -0,0 +1,205 @@
+package internal
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// remoteDirArg matches "[user@]host:/path". Hosts are at least two
+// characters, so Windows drive letters aren't taken for them, and the path
+// must be absolute, as relative ones are scp-style git addresses.
+var remoteDirArg = regexp.MustCompile(`^((?:\w[\w.-]*@)?\w[\w.-]+):(/.*)$`)
+
+// sshCommand is the program FetchRemoteDir runs.
+var sshCommand = "ssh"
+
+// RemoteDir is a directory on another machine, reached over SSH.
+type RemoteDir struct {
+	Host string // "[user@]host", as ssh takes it
+	Path string // absolute, slash-separated
+}
+
+// String returns the directory as it's written on the command line.
+func (r RemoteDir) String() string {
+	return r.Host + ":" + r.Path
+}
+
+// ParseRemoteDir parses an argument naming a directory on another machine,
+// such as "deploy@web1:/srv/app". It reports false for anything else,
+// including remote git repositories.
+func ParseRemoteDir(arg string) (RemoteDir, bool) {
+	if strings.Contains(arg, "://") {
+		return RemoteDir{}, false
+	}
+	m := remoteDirArg.FindStringSubmatch(arg)
+	if m == nil {
+		return RemoteDir{}, false
+	}
+	return RemoteDir{Host: m[1], Path: path.Clean(m[2])}, true
+}
+
+// RemoteCacheDir returns the directory FetchRemoteDir keeps its copies in:
+// gopack/remote under the user's cache directory.
+func RemoteCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gopack", "remote"), nil
+}
+
+// remoteTarScript writes a tar archive of the current directory to stdout.
+// In a git work tree, only the files git doesn't ignore are archived, as
+// "git ls-files" lists them, so build output and dependencies never leave
+// the server; elsewhere everything but .git directories is. Names are
+// given to tar as "./name", so none can be taken for an option.
+const remoteTarScript = `if git rev-parse --is-inside-work-tree >/dev/null 2>&1; then ` +
+	`git -c core.quotepath=off ls-files -co --exclude-standard | ` +
+	`while IFS= read -r f; do if [ -f "$f" ]; then printf './%s\n' "$f"; fi; done | tar -cf - -T -; ` +
+	`else tar -cf - --exclude=.git .; fi`
+
+// FetchRemoteDir copies a remote directory into a directory under cacheDir
+// named after it and a hash of its host and path, and returns that
+// directory. The files are streamed as a tar archive from "tar" run over
+// ssh, so the server needs nothing else installed, and ssh's own
+// configuration (keys, ports, jump hosts) applies. In a git work tree,
+// files git ignores are left behind on the server, as are .git
+// directories; symbolic links and other special files are skipped. Any
+// earlier copy is replaced, but only once the new one is complete.
+func FetchRemoteDir(ctx context.Context, remote RemoteDir, cacheDir string) (string, error) {
+	return fetchInto(cacheDir, path.Base(remote.Path), remote.String(), func(dir string) error {
+		script := "cd " + posixQuote(remote.Path) + " && { " + remoteTarScript + "; }"
+		return runTar(exec.CommandContext(ctx, sshCommand, remote.Host, script), dir)
+	})
+}
+
+// fetchInto fills a fresh directory with fetch and moves it into place
+// under cacheDir, named after name and a hash of key, replacing any earlier
+// copy only once the new one is complete. It returns the directory.
+func fetchInto(cacheDir, name, key string, fetch func(dir string) error) (string, error) {
+	sum := sha256.Sum256([]byte(key))
+	dir := filepath.Join(cacheDir, name+"-"+hex.EncodeToString(sum[:8]))
+
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return "", err
+	}
+	tmp, err := os.MkdirTemp(cacheDir, ".fetch-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+	if err := fetch(filepath.Join(tmp, "dir")); err != nil {
+		return "", err
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return "", fmt.Errorf("failed to remove the previous copy: %w", err)
+	}
+	if err := os.Rename(filepath.Join(tmp, "dir"), dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// runTar runs cmd and extracts the tar archive it writes to stdout into
+// dir.
+func runTar(cmd *exec.Cmd, dir string) error {
+	name := filepath.Base(cmd.Path)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to run %s: %w", name, err)
+	}
+	extractErr := extractTar(stdout, dir)
+	if extractErr != nil {
+		// Unblock the command if it's still writing
+		io.Copy(io.Discard, stdout)
+	}
+	if err := cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s: %s", name, msg)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return extractErr
+}
+
+// extractTar writes the directories and regular files of a tar archive
+// under dir, refusing entries that would land outside it.
+func extractTar(r io.Reader, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid archive: %w", err)
+		}
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		if name == "." {
+			continue
+		}
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return fmt.Errorf("unsafe path in archive: %q", header.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(file, archive)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ShellQuote quotes s as a single word for the local shell commands are
+// run through: cmd.exe on Windows, sh elsewhere.
+func ShellQuote(s string) string {
+	if runtime.GOOS == "windows" {
+		return `"` + s + `"`
+	}
+	return posixQuote(s)
+}
+
+// posixQuote quotes s for a POSIX shell, such as the one ssh runs commands
+// in on the server.
+func posixQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestParseRemoteDir(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want RemoteDir
+		ok   bool
+	}{
+		{"deploy@web1:/srv/app", RemoteDir{Host: "deploy@web1", Path: "/srv/app"}, true},
+		{"web1.example.com:/srv/app/", RemoteDir{Host: "web1.example.com", Path: "/srv/app"}, true},
+		{"prod:/", RemoteDir{Host: "prod", Path: "/"}, true},
+		{"git@github.com:org/repo.git", RemoteDir{}, false},
+		{"https://github.com/org/repo", RemoteDir{}, false},
+		{"C:/Users/me/app", RemoteDir{}, false},
+		{"-oProxyCommand=x@host:/srv", RemoteDir{}, false},
+		{"./src", RemoteDir{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			got, ok := ParseRemoteDir(tt.arg)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("ParseRemoteDir(%q) = %+v, %v; want %+v, %v", tt.arg, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+// fakeSSH replaces ssh with a script that runs the command locally.
+func fakeSSH(t *testing.T) {
+	t.Helper()
+	fake := filepath.Join(t.TempDir(), "ssh")
+	if err := os.WriteFile(fake, []byte("#!/bin/sh\nshift\nexec sh -c \"$1\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	old := sshCommand
+	t.Cleanup(func() { sshCommand = old })
+	sshCommand = fake
+}
+
+func TestFetchRemoteDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	if _, err := exec.LookPath("tar"); err != nil {
+		t.Skip("tar not installed")
+	}
+
+	fakeSSH(t)
+
+	src := t.TempDir()
+	writeTree(t, src, map[string]string{
+		"main.go":      "package main\n",
+		"pkg/a.go":     "package pkg\n",
+		".git/HEAD":    "ref: refs/heads/main\n",
+		"it's here.md": "quoted\n",
+	})
+
+	cacheDir := t.TempDir()
+	for range 2 { // the second fetch replaces the first copy
+		dir, err := FetchRemoteDir(context.Background(), RemoteDir{Host: "server", Path: src}, cacheDir)
+		if err != nil {
+			t.Fatalf("FetchRemoteDir() error = %v", err)
+		}
+		for name, want := range map[string]string{"main.go": "package main\n", "pkg/a.go": "package pkg\n", "it's here.md": "quoted\n"} {
+			got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+			if err != nil || string(got) != want {
+				t.Errorf("%s = %q, %v; want %q", name, got, err, want)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); !os.IsNotExist(err) {
+			t.Errorf(".git was copied (stat error %v)", err)
+		}
+	}
+
+	if _, err := FetchRemoteDir(context.Background(), RemoteDir{Host: "server", Path: filepath.Join(src, "missing")}, cacheDir); err == nil {
+		t.Error("FetchRemoteDir() of a missing directory succeeded")
+	}
+	entries, _ := os.ReadDir(cacheDir)
+	if len(entries) != 1 {
+		t.Errorf("cache has %d entries, want 1", len(entries))
+	}
+}
+
+func TestFetchRemoteDirGitignore(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	for _, tool := range []string{"tar", "git"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skip(tool + " not installed")
+		}
+	}
+	fakeSSH(t)
+
+	src := t.TempDir()
+	writeTree(t, src, map[string]string{
+		".gitignore":    "build/\n*.log\n",
+		"main.go":       "package main\n",
+		"deleted.go":    "package main\n",
+		"-dash.go":      "package main\n",
+		"build/out.bin": "binary\n",
+		"debug.log":     "noise\n",
+	})
+	if _, err := runGit(context.Background(), src, "init", "-q"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runGit(context.Background(), src, "add", "main.go", "deleted.go", ".gitignore"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(src, "deleted.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tracked and untracked files are copied, ignored and deleted ones not
+	dir, err := FetchRemoteDir(context.Background(), RemoteDir{Host: "server", Path: src}, t.TempDir())
+	if err != nil {
+		t.Fatalf("FetchRemoteDir() error = %v", err)
+	}
+	for name, want := range map[string]bool{
+		".gitignore": true, "main.go": true, "-dash.go": true,
+		"deleted.go": false, "build": false, "debug.log": false, ".git": false,
+	} {
+		if _, err := os.Stat(filepath.Join(dir, name)); (err == nil) != want {
+			t.Errorf("%s copied = %v, want %v", name, err == nil, want)
+		}
+	}
+}
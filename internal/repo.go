@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	// scpLikeRepo matches scp-style git addresses such as
+	// "git@github.com:org/repo.git".
+	scpLikeRepo = regexp.MustCompile(`^[\w.-]+@[\w.-]+:[^/\\]`)
+	// treeURL matches links to a directory in a repository's web UI, such
+	// as GitHub's ".../org/repo/tree/main/services/auth" or GitLab's
+	// ".../group/repo/-/tree/main/services/auth".
+	treeURL = regexp.MustCompile(`^(https?://[^/]+/.+?)(?:/-)?/tree/([^/]+)(?:/(.*))?$`)
+)
+
+// RemoteRepo is a remote git repository to pack.
+type RemoteRepo struct {
+	URL  string // clone URL
+	Ref  string // branch or tag to check out, or "" for the default branch
+	Path string // slash-separated subdirectory to fetch, or "" for all
+}
+
+// IsRemoteRepo reports whether arg names a remote git repository (a URL
+// or an scp-style address) rather than a local path.
+func IsRemoteRepo(arg string) bool {
+	for _, scheme := range []string{"https://", "http://", "ssh://", "git://", "file://"} {
+		if strings.HasPrefix(arg, scheme) {
+			return true
+		}
+	}
+	return scpLikeRepo.MatchString(arg)
+}
+
+// ParseRemoteRepo parses a remote repository argument: a clone URL,
+// optionally followed by "#ref", or a link to a directory on a branch such
+// as "https://github.com/org/mono/tree/main/services/auth".
+func ParseRemoteRepo(arg string) RemoteRepo {
+	if m := treeURL.FindStringSubmatch(strings.TrimSuffix(arg, "/")); m != nil {
+		return RemoteRepo{URL: m[1], Ref: m[2], Path: m[3]}
+	}
+	url, ref, _ := strings.Cut(arg, "#")
+	return RemoteRepo{URL: url, Ref: ref}
+}
+
+// RepoName returns a short name for a repository: the last element of its
+// path or URL, without a ".git" suffix.
+func RepoName(arg string) string {
+	if IsRemoteRepo(arg) {
+		if i := strings.Index(arg, "://"); i >= 0 {
+			arg = arg[i+3:]
+		} else {
+			_, arg, _ = strings.Cut(arg, ":")
+		}
+		arg = path.Base(strings.TrimRight(arg, "/"))
+	} else if abs, err := filepath.Abs(arg); err == nil {
+		arg = filepath.Base(abs)
+	}
+	return strings.TrimSuffix(arg, ".git")
+}
+
+// CloneRepo makes a shallow clone of a remote repository in dir. With a
+// Path, only that subdirectory is fetched and checked out, using a partial
+// clone and sparse checkout.
+func CloneRepo(ctx context.Context, repo RemoteRepo, dir string) error {
+	args := []string{"clone", "--depth", "1", "--quiet"}
+	if repo.Ref != "" {
+		args = append(args, "--branch", repo.Ref)
+	}
+	if repo.Path != "" {
+		args = append(args, "--filter=blob:none", "--sparse")
+	}
+	if _, err := runGit(ctx, ".", append(args, "--", repo.URL, dir)...); err != nil {
+		return err
+	}
+
+	if repo.Path != "" {
+		if _, err := runGit(ctx, dir, "sparse-checkout", "set", "--", repo.Path); err != nil {
+			return err
+		}
+		if info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(repo.Path))); err != nil || !info.IsDir() {
+			return fmt.Errorf("no directory %s in %s", repo.Path, repo.URL)
+		}
+	}
+	return nil
+}
+
+// RepoCacheDir returns the directory remote clones are cached in:
+// gopack/repos under the user's cache directory (~/.cache on Linux).
+func RepoCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gopack", "repos"), nil
+}
+
+// CachedRepoDir returns where CachedClone keeps the clone of a repository:
+// a directory under cacheDir named after it and a hash of its URL, ref,
+// and path.
+func CachedRepoDir(cacheDir string, repo RemoteRepo) string {
+	sum := sha256.Sum256([]byte(repo.URL + "#" + repo.Ref + ":" + repo.Path))
+	return filepath.Join(cacheDir, RepoName(repo.URL)+"-"+hex.EncodeToString(sum[:8]))
+}
+
+// CachedClone returns a shallow clone of a repository under cacheDir,
+// cloning it only if it isn't cached yet or refresh is set. It reports
+// whether the cached copy was used.
+func CachedClone(ctx context.Context, cacheDir string, repo RemoteRepo, refresh bool) (dir string, cached bool, err error) {
+	dir = CachedRepoDir(cacheDir, repo)
+	if _, err := os.Stat(dir); err == nil && !refresh {
+		return dir, true, nil
+	}
+
+	// Clone next to the cache entry and swap it in, so an interrupted clone
+	// never leaves a broken entry behind
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return "", false, err
+	}
+	tmp, err := os.MkdirTemp(cacheDir, ".clone-")
+	if err != nil {
+		return "", false, err
+	}
+	defer os.RemoveAll(tmp)
+	if err := CloneRepo(ctx, repo, filepath.Join(tmp, "repo")); err != nil {
+		return "", false, err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return "", false, fmt.Errorf("failed to remove cached clone: %w", err)
+	}
+	if err := os.Rename(filepath.Join(tmp, "repo"), dir); err != nil {
+		return "", false, err
+	}
+	return dir, false, nil
+}
@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRepoName(t *testing.T) {
+	tests := []struct {
+		arg    string
+		remote bool
+		name   string
+	}{
+		{"https://github.com/org/shared-lib", true, "shared-lib"},
+		{"https://github.com/org/shared-lib.git/", true, "shared-lib"},
+		{"git@github.com:org/service.git", true, "service"},
+		{"ssh://git@host:2222/team/tool.git", true, "tool"},
+		{"./service-a", false, "service-a"},
+		{"../service-b/", false, "service-b"},
+		{"C:/work/app", false, "app"},
+	}
+
+	for _, tt := range tests {
+		if got := IsRemoteRepo(tt.arg); got != tt.remote {
+			t.Errorf("IsRemoteRepo(%q) = %v, want %v", tt.arg, got, tt.remote)
+		}
+		if got := RepoName(tt.arg); got != tt.name {
+			t.Errorf("RepoName(%q) = %q, want %q", tt.arg, got, tt.name)
+		}
+	}
+}
+
+func TestParseRemoteRepo(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want RemoteRepo
+	}{
+		{"https://github.com/org/lib", RemoteRepo{URL: "https://github.com/org/lib"}},
+		{"https://github.com/org/lib#v1.2.0", RemoteRepo{URL: "https://github.com/org/lib", Ref: "v1.2.0"}},
+		{"git@github.com:org/lib.git#main", RemoteRepo{URL: "git@github.com:org/lib.git", Ref: "main"}},
+		{"https://github.com/org/mono/tree/main/services/auth", RemoteRepo{URL: "https://github.com/org/mono", Ref: "main", Path: "services/auth"}},
+		{"https://github.com/org/mono/tree/v2/", RemoteRepo{URL: "https://github.com/org/mono", Ref: "v2"}},
+		{"https://gitlab.com/group/sub/mono/-/tree/dev/api", RemoteRepo{URL: "https://gitlab.com/group/sub/mono", Ref: "dev", Path: "api"}},
+	}
+	for _, tt := range tests {
+		if got := ParseRemoteRepo(tt.arg); got != tt.want {
+			t.Errorf("ParseRemoteRepo(%q) = %+v, want %+v", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestCachedClone(t *testing.T) {
+	source := gitRepo(t)
+	repo := RemoteRepo{URL: "file://" + filepath.ToSlash(source)}
+	cache := t.TempDir()
+
+	dir, cached, err := CachedClone(context.Background(), cache, repo, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached {
+		t.Error("first CachedClone() reported a cached copy")
+	}
+	if _, err := runGit(context.Background(), dir, "rev-parse", "HEAD"); err != nil {
+		t.Errorf("clone has no commits: %v", err)
+	}
+
+	// A marker in the cached copy survives reuse but not a refresh
+	marker := filepath.Join(dir, "marker")
+	if err := writeFile(marker, "x"); err != nil {
+		t.Fatal(err)
+	}
+	again, cached, err := CachedClone(context.Background(), cache, repo, false)
+	if err != nil || !cached || again != dir {
+		t.Fatalf("second CachedClone() = %q, %v, %v, want cached %q", again, cached, err, dir)
+	}
+	if _, cached, err = CachedClone(context.Background(), cache, repo, true); err != nil || cached {
+		t.Fatalf("refreshed CachedClone() = %v, %v, want a fresh clone", cached, err)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Error("refresh kept the old clone")
+	}
+
+	// Another ref is cached separately
+	if other, _, err := CachedClone(context.Background(), cache, RemoteRepo{URL: repo.URL, Ref: "missing-branch"}, false); err == nil {
+		t.Errorf("CachedClone(missing-branch) = %q, want error", other)
+	}
+	entries, err := os.ReadDir(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("cache holds %d entries after a failed clone, want 1", len(entries))
+	}
+}
+
+func TestCloneRepo(t *testing.T) {
+	source := gitRepo(t)
+	writeTree(t, source, map[string]string{"sub/pkg/a.go": "package pkg\n", "other/c.go": "package other\n"})
+	if _, err := runGit(context.Background(), source, "add", "."); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runGit(context.Background(), source, "-c", "user.name=A", "-c", "user.email=a@example.com", "commit", "-q", "-m", "sub"); err != nil {
+		t.Fatal(err)
+	}
+	url := "file://" + filepath.ToSlash(source)
+
+	dir := filepath.Join(t.TempDir(), "clone")
+	if err := CloneRepo(context.Background(), RemoteRepo{URL: url}, dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.go")); err != nil {
+		t.Errorf("full clone is missing b.go: %v", err)
+	}
+
+	// A sparse clone checks out the subdirectory, and no other directories
+	dir = filepath.Join(t.TempDir(), "sparse")
+	if err := CloneRepo(context.Background(), RemoteRepo{URL: url, Path: "sub/pkg"}, dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub", "pkg", "a.go")); err != nil {
+		t.Errorf("sparse clone is missing sub/pkg/a.go: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "other")); !os.IsNotExist(err) {
+		t.Errorf("sparse clone checked out other/")
+	}
+
+	if err := CloneRepo(context.Background(), RemoteRepo{URL: url, Path: "missing"}, filepath.Join(t.TempDir(), "x")); err == nil {
+		t.Error("CloneRepo() with a missing path succeeded, want error")
+	}
+}
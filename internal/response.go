@@ -0,0 +1,207 @@
+package internal
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	// A fence line opening or closing a Markdown code block
+	fenceLine = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})(.*)$")
+	// A comment naming the file on the first line of a code block
+	pathComment = regexp.MustCompile(`^\s*(?://|#|--|/\*|<!--)\s*(?:(?i:file(?:name)?|path):\s*)?(\S+?)\s*(?:\*/|-->)?\s*$`)
+	// A label such as "File: path" before a code block
+	pathLabel = regexp.MustCompile(`^(?i:file(?:name)?|path):\s*`)
+	// A path in backticks in a sentence such as "Update `cmd/root.go`:"
+	backtickPath = regexp.MustCompile("`([^`\\s]+)`")
+)
+
+// diffLanguages lists code block languages that hold unified diffs.
+var diffLanguages = map[string]bool{"diff": true, "patch": true, "udiff": true}
+
+// ParseResponse extracts the files in an LLM's response to a pack. The
+// response may be a pack itself, a unified diff, or Markdown in which each
+// fenced code block holds a whole file or a diff. A block's file is named
+// by its info string ("```go cmd/main.go"), by the line before it ("File:
+// cmd/main.go", "### `cmd/main.go`"), or by a comment on its first line
+// ("// cmd/main.go", which is dropped). Diffs are applied to the current
+// content from read, or to an earlier block for the same file, and a later
+// block for a file replaces an earlier one.
+//
+// It also returns how many code blocks were ignored because they name no
+// file.
+func ParseResponse(text string, read func(path string) ([]byte, error)) (files []File, unlabeled int, err error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if strings.HasPrefix(text, "File: ") {
+		files, err := ParsePack([]byte(text))
+		return files, 0, err
+	}
+	if strings.HasPrefix(text, "## File: ") || strings.Contains(text, "\n## File: ") {
+		// Fall back to reading the blocks one by one when prose around
+		// them breaks the pack format
+		if files, err := ParsePack([]byte(text)); err == nil {
+			return files, 0, nil
+		}
+	}
+
+	index := make(map[string]int)
+	add := func(file File) {
+		if i, ok := index[file.Path]; ok {
+			files[i] = file
+			return
+		}
+		index[file.Path] = len(files)
+		files = append(files, file)
+	}
+	// Diffs apply on top of files earlier in the response
+	current := func(path string) ([]byte, error) {
+		if i, ok := index[path]; ok {
+			return files[i].Content, nil
+		}
+		return read(path)
+	}
+	applyDiff := func(diff string) error {
+		patched, err := ApplyPatch(diff, current)
+		for _, file := range patched {
+			add(file)
+		}
+		return err
+	}
+
+	lines := strings.Split(text, "\n")
+	if !slices.ContainsFunc(lines, fenceLine.MatchString) && isDiff(text) {
+		if err := applyDiff(text); err != nil {
+			return nil, 0, err
+		}
+		return files, 0, nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		m := fenceLine.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		fence, info := m[1], strings.TrimSpace(m[2])
+		if fence[0] == '`' && strings.Contains(info, "`") {
+			continue // inline code, not a fence
+		}
+
+		// Collect the block up to its closing fence
+		start := i
+		var body []string
+		for i++; i < len(lines); i++ {
+			if closing := fenceLine.FindStringSubmatch(lines[i]); closing != nil &&
+				closing[1][0] == fence[0] && len(closing[1]) >= len(fence) && strings.TrimSpace(closing[2]) == "" {
+				break
+			}
+			body = append(body, lines[i])
+		}
+		content := strings.Join(body, "\n")
+		if len(body) > 0 {
+			content += "\n"
+		}
+
+		language, _, _ := strings.Cut(info, " ")
+		if diffLanguages[strings.ToLower(language)] || isDiff(content) {
+			if err := applyDiff(content); err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
+
+		path := infoPath(info)
+		if path == "" {
+			path = labelPath(lines[:start])
+		}
+		if path == "" && len(body) > 0 {
+			if m := pathComment.FindStringSubmatch(body[0]); m != nil && looksLikePath(m[1]) {
+				path = m[1]
+				content = strings.Join(body[1:], "\n") + "\n"
+			}
+		}
+		if path == "" {
+			unlabeled++
+			continue
+		}
+		if err := checkPackPath(path); err != nil {
+			return nil, 0, err
+		}
+		add(File{Path: path, Content: []byte(content)})
+	}
+	return files, unlabeled, nil
+}
+
+// isDiff reports whether text is a unified diff.
+func isDiff(text string) bool {
+	if strings.HasPrefix(text, "diff --git ") {
+		return true
+	}
+	headers := strings.HasPrefix(text, "--- ") || strings.Contains(text, "\n--- ")
+	return headers && strings.Contains(text, "\n+++ ") && strings.Contains(text, "\n@@")
+}
+
+// infoPath returns the file named in a code block's info string, as in
+// "go cmd/main.go", "cmd/main.go", or `go title="cmd/main.go"`.
+func infoPath(info string) string {
+	for _, field := range strings.Fields(info) {
+		if _, value, ok := strings.Cut(field, "="); ok {
+			field = value
+		}
+		field = strings.Trim(field, `"'`)
+		if looksLikePath(field) {
+			return field
+		}
+	}
+	return ""
+}
+
+// labelPath returns the file named by the last non-blank line of before,
+// such as "File: cmd/main.go", "**cmd/main.go**", or "Update `cmd/main.go`:".
+func labelPath(before []string) string {
+	line := ""
+	for i := len(before) - 1; i >= 0 && i >= len(before)-2; i-- {
+		if line = strings.TrimSpace(before[i]); line != "" {
+			break
+		}
+	}
+	if line == "" {
+		return ""
+	}
+
+	// The line is the path, decorated with Markdown
+	trimmed := strings.TrimRight(strings.TrimLeft(line, "#>-*_ "), ":*_ ")
+	labeled := pathLabel.MatchString(trimmed)
+	trimmed = strings.Trim(pathLabel.ReplaceAllString(trimmed, ""), "`*_ :")
+	if !strings.ContainsAny(trimmed, " \t") && (looksLikePath(trimmed) || labeled && trimmed != "") {
+		return trimmed
+	}
+
+	// A sentence introducing the block, with the path in backticks
+	if strings.HasSuffix(line, ":") {
+		var found string
+		for _, m := range backtickPath.FindAllStringSubmatch(line, -1) {
+			if looksLikePath(m[1]) {
+				if found != "" {
+					return "" // ambiguous
+				}
+				found = m[1]
+			}
+		}
+		return found
+	}
+	return ""
+}
+
+// looksLikePath reports whether s is plausibly a relative file path: a
+// name with an extension or a directory, with no spaces and no URL scheme.
+func looksLikePath(s string) bool {
+	if s == "" || len(s) > 255 || strings.ContainsAny(s, " \t`<>|\"'") || strings.Contains(s, "://") || strings.ContainsAny(s[:1], "-!/") {
+		return false
+	}
+	if strings.Contains(s, "/") {
+		return !strings.HasSuffix(s, "/")
+	}
+	dot := strings.LastIndexByte(s, '.')
+	return dot > 0 && dot < len(s)-1
+}
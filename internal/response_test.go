@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"maps"
+	"os"
+	"testing"
+)
+
+func TestParseResponse(t *testing.T) {
+	read := func(path string) ([]byte, error) {
+		if path == "cmd/main.go" {
+			return []byte("package main\n\nfunc main() {}\n"), nil
+		}
+		return nil, os.ErrNotExist
+	}
+
+	tests := []struct {
+		name      string
+		response  string
+		want      map[string]string
+		unlabeled int
+	}{
+		{
+			name:     "path in the info string",
+			response: "Here is the fix:\n\n```go cmd/main.go\npackage main\n\nfunc main() { run() }\n```\n\nThat should do it.\n",
+			want:     map[string]string{"cmd/main.go": "package main\n\nfunc main() { run() }\n"},
+		},
+		{
+			name:     "labels before blocks",
+			response: "### `internal/a.go`\n```go\npackage a\n```\n\n**File: Makefile**\n\n```\nall:\n```\n\nUpdate `docs/x.md` as well:\n~~~markdown\n# X\n```go\ncode\n```\n~~~\n",
+			want: map[string]string{
+				"internal/a.go": "package a\n",
+				"Makefile":      "all:\n",
+				"docs/x.md":     "# X\n```go\ncode\n```\n",
+			},
+		},
+		{
+			name:     "path comment on the first line",
+			response: "```python\n# tools/gen.py\nprint(1)\n```\n```js\n// web/app.js\nrun()\n```\n",
+			want:     map[string]string{"tools/gen.py": "print(1)\n", "web/app.js": "run()\n"},
+		},
+		{
+			name:      "unlabeled blocks are counted",
+			response:  "Run this:\n\n```bash\ngo test ./...\n```\n\n```go\n// just a comment\nx := 1\n```\n",
+			want:      map[string]string{},
+			unlabeled: 2,
+		},
+		{
+			name:     "diff block",
+			response: "```diff\n--- a/cmd/main.go\n+++ b/cmd/main.go\n@@ -3 +3 @@\n-func main() {}\n+func main() { run() }\n```\n",
+			want:     map[string]string{"cmd/main.go": "package main\n\nfunc main() { run() }\n"},
+		},
+		{
+			name:     "diff on top of an earlier block",
+			response: "```go new/a.go\npackage a\nvar x = 1\n```\n```diff\n--- a/new/a.go\n+++ b/new/a.go\n@@ -2 +2 @@\n-var x = 1\n+var x = 2\n```\n",
+			want:     map[string]string{"new/a.go": "package a\nvar x = 2\n"},
+		},
+		{
+			name:     "bare diff",
+			response: "--- a/cmd/main.go\n+++ b/cmd/main.go\n@@ -1 +1 @@\n-package main\n+package app\n",
+			want:     map[string]string{"cmd/main.go": "package app\n\nfunc main() {}\n"},
+		},
+		{
+			name:     "gopack pack",
+			response: "File: a.txt\nhello\n\nFile: b/c.txt\nworld\n",
+			want:     map[string]string{"a.txt": "hello", "b/c.txt": "world\n"},
+		},
+		{
+			name:     "later block wins",
+			response: "```go a.go\nv1\n```\n```go a.go\nv2\n```\n",
+			want:     map[string]string{"a.go": "v2\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		files, unlabeled, err := ParseResponse(tt.response, read)
+		if err != nil {
+			t.Errorf("%s: ParseResponse() error = %v", tt.name, err)
+			continue
+		}
+		got := make(map[string]string)
+		for _, file := range files {
+			got[file.Path] = string(file.Content)
+		}
+		if !maps.Equal(got, tt.want) || unlabeled != tt.unlabeled {
+			t.Errorf("%s: ParseResponse() = %q, %d unlabeled; want %q, %d", tt.name, got, unlabeled, tt.want, tt.unlabeled)
+		}
+	}
+
+	for _, response := range []string{"File: ../../etc/passwd\nx\n", "```sh ../evil.sh\nrm -rf /\n```\n"} {
+		if _, _, err := ParseResponse(response, read); err == nil {
+			t.Errorf("ParseResponse(%q) succeeded, want an unsafe path error", response)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Kinds of invisible characters SanitizeUnicode removes.
+const (
+	InvisibleBOM       = "byte order mark"
+	InvisibleZeroWidth = "zero-width character"
+	InvisibleHyphen    = "soft hyphen"
+	InvisibleBidi      = "bidi control"
+)
+
+// invisibleRunes maps the characters SanitizeUnicode removes to their kind.
+// The zero-width joiner and non-joiner are kept, as emoji sequences and
+// scripts such as Persian need them.
+var invisibleRunes = map[rune]string{
+	'\uFEFF': InvisibleBOM, // a zero-width no-break space past the start
+	'\u200B': InvisibleZeroWidth,
+	'\u2060': InvisibleZeroWidth, // word joiner
+	'\u180E': InvisibleZeroWidth, // Mongolian vowel separator
+	'\u00AD': InvisibleHyphen,
+	'\u200E': InvisibleBidi, // left-to-right mark
+	'\u200F': InvisibleBidi, // right-to-left mark
+	'\u061C': InvisibleBidi, // Arabic letter mark
+	'\u202A': InvisibleBidi, // embeddings and overrides
+	'\u202B': InvisibleBidi,
+	'\u202C': InvisibleBidi,
+	'\u202D': InvisibleBidi,
+	'\u202E': InvisibleBidi,
+	'\u2066': InvisibleBidi, // isolates
+	'\u2067': InvisibleBidi,
+	'\u2068': InvisibleBidi,
+	'\u2069': InvisibleBidi,
+}
+
+// InvisibleChar is an invisible character SanitizeUnicode removed.
+type InvisibleChar struct {
+	Path string
+	Line int
+	Rune rune
+}
+
+// Kind names the kind of character, one of the Invisible constants.
+func (c InvisibleChar) Kind() string {
+	return invisibleRunes[c.Rune]
+}
+
+// String describes the character for warnings, as in
+// "main.go:3: bidi control U+202E".
+func (c InvisibleChar) String() string {
+	return fmt.Sprintf("%s:%d: %s %U", c.Path, c.Line, c.Kind(), c.Rune)
+}
+
+// SanitizeUnicode removes the invisible characters from files: byte order
+// marks, zero-width spaces, soft hyphens, and the bidirectional controls
+// that can make code read differently from how it runs ("Trojan Source").
+// They cost tokens without showing, and would otherwise be carried into
+// code generated from the pack. Dedupe references and collapsed
+// directories are left alone, as are bytes that aren't UTF-8. It returns
+// the files and the characters removed, in order.
+func SanitizeUnicode(files []File) ([]File, []InvisibleChar) {
+	result := make([]File, len(files))
+	var removed []InvisibleChar
+	for i, file := range files {
+		result[i] = file
+		if file.DuplicateOf != "" || file.Omitted {
+			continue
+		}
+		content, chars := sanitizeContent(file.Content)
+		for j := range chars {
+			chars[j].Path = file.Path
+		}
+		if len(chars) > 0 {
+			result[i].Content = content
+			removed = append(removed, chars...)
+		}
+	}
+	return result, removed
+}
+
+// sanitizeContent removes the invisible characters from content, returning
+// it with the characters removed, without their paths.
+func sanitizeContent(content []byte) ([]byte, []InvisibleChar) {
+	// All of them start with one of these bytes in UTF-8, which most files
+	// don't have at all
+	if bytes.IndexByte(content, 0xC2) < 0 && bytes.IndexByte(content, 0xD8) < 0 &&
+		bytes.IndexByte(content, 0xE1) < 0 && bytes.IndexByte(content, 0xE2) < 0 && bytes.IndexByte(content, 0xEF) < 0 {
+		return content, nil
+	}
+
+	var out bytes.Buffer
+	var removed []InvisibleChar
+	line := 1
+	for rest := content; len(rest) > 0; {
+		r, size := utf8.DecodeRune(rest)
+		if _, ok := invisibleRunes[r]; ok {
+			removed = append(removed, InvisibleChar{Line: line, Rune: r})
+		} else {
+			if r == '\n' {
+				line++
+			}
+			out.Write(rest[:size])
+		}
+		rest = rest[size:]
+	}
+	if len(removed) == 0 {
+		return content, nil
+	}
+	return out.Bytes(), removed
+}
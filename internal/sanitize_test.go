@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSanitizeUnicode(t *testing.T) {
+	files := []File{
+		{Path: "bom.go", Content: []byte("\uFEFFpackage main\n")},
+		{Path: "trojan.go", Content: []byte("package main\n\n// ok\u202E } \u2066if admin\u2069 \u2066 {\n")},
+		{Path: "prose.md", Content: []byte("hy\u00ADphen\u200Bated\n")},
+		{Path: "emoji.txt", Content: []byte("\U0001F468\u200D\U0001F469 and \u0645\u06CC\u200C\u062E\u0648\u0627\u0647\u0645\n")},
+		{Path: "latin1.txt", Content: []byte("caf\xe9 \xe2\x80\n")},
+		{Path: "copy.go", Content: []byte("[identical to bom.go]\n\u200B"), DuplicateOf: "bom.go"},
+	}
+
+	got, removed := SanitizeUnicode(files)
+	want := []string{
+		"package main\n",
+		"package main\n\n// ok } if admin  {\n",
+		"hyphenated\n",
+		string(files[3].Content), // joiners are kept
+		string(files[4].Content), // as are bytes that aren't UTF-8
+		string(files[5].Content),
+	}
+	for i, file := range got {
+		if string(file.Content) != want[i] {
+			t.Errorf("%s = %q, want %q", file.Path, file.Content, want[i])
+		}
+	}
+
+	var described []string
+	for _, c := range removed {
+		described = append(described, c.String())
+	}
+	wantRemoved := []string{
+		"bom.go:1: byte order mark U+FEFF",
+		"trojan.go:3: bidi control U+202E",
+		"trojan.go:3: bidi control U+2066",
+		"trojan.go:3: bidi control U+2069",
+		"trojan.go:3: bidi control U+2066",
+		"prose.md:1: soft hyphen U+00AD",
+		"prose.md:1: zero-width character U+200B",
+	}
+	if !slices.Equal(described, wantRemoved) {
+		t.Errorf("SanitizeUnicode() removed %q, want %q", described, wantRemoved)
+	}
+	if string(files[0].Content) != "\uFEFFpackage main\n" {
+		t.Error("SanitizeUnicode() changed the files it was given")
+	}
+}
@@ -0,0 +1,216 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// JSON-RPC 2.0 error codes used by Serve.
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000
+)
+
+// PackRequest holds the parameters of the editor server's "pack" method.
+// Relative paths are resolved against Root, or the server's working
+// directory when Root is empty.
+type PackRequest struct {
+	Root             string   `json:"root"`
+	Paths            []string `json:"paths"`
+	Format           string   `json:"format"`
+	IgnorePatterns   []string `json:"ignore_patterns"`
+	ExcludeRegex     []string `json:"exclude_regex"`
+	MaxDepth         int      `json:"max_depth"`
+	IncludeGenerated bool     `json:"include_generated"`
+	Dedupe           bool     `json:"dedupe"`
+	Summary          bool     `json:"summary"`
+}
+
+// PackResult is the result of the "pack" method.
+type PackResult struct {
+	Pack    string       `json:"pack"`
+	Tokens  int          `json:"tokens"`
+	Files   []PackedFile `json:"files"`
+	Skipped int          `json:"skipped"`
+}
+
+// PackedFile describes one file in a PackResult.
+type PackedFile struct {
+	Path   string `json:"path"`
+	Tokens int    `json:"tokens"`
+}
+
+// rpcRequest and rpcResponse are JSON-RPC 2.0 messages.
+type rpcRequest struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Serve answers JSON-RPC 2.0 requests read from r, one per line, writing
+// one response per line to w until r ends or a "shutdown" request arrives.
+// File contents are cached between requests, so repeated packs of the same
+// tree only re-read files that changed. The tree itself is walked again for
+// every request, as without watching the file system that's how files
+// added, removed, or newly ignored are noticed; listing a tree costs far
+// less than reading it. The cache keeps only the files the latest request
+// read, so it holds one tree at a time. Each request is logged to log, if
+// not nil, with its method, how long it took, and how it went.
+//
+// Methods:
+//
+//	pack(PackRequest) -> PackResult
+//	shutdown() -> null
+func Serve(r io.Reader, w io.Writer, log *slog.Logger) error {
+	if log == nil {
+		log = slog.New(slog.DiscardHandler)
+	}
+	cache := NewFileCache()
+	encoder := json.NewEncoder(w)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var req rpcRequest
+		var result any
+		var rpcErr *rpcError
+		start := time.Now()
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			rpcErr = &rpcError{Code: rpcParseError, Message: err.Error()}
+			req.ID = json.RawMessage("null")
+		} else {
+			switch req.Method {
+			case "pack":
+				var params PackRequest
+				if err := json.Unmarshal(req.Params, &params); err != nil || req.Params == nil {
+					rpcErr = &rpcError{Code: rpcInvalidParams, Message: "pack expects an object of parameters"}
+					break
+				}
+				packed, err := servePack(params, cache)
+				if err != nil {
+					rpcErr = &rpcError{Code: rpcServerError, Message: err.Error()}
+				} else {
+					result = packed
+					log.Debug("packed", "root", params.Root, "files", len(packed.Files), "skipped", packed.Skipped, "tokens", packed.Tokens)
+				}
+			case "shutdown":
+			default:
+				rpcErr = &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("unknown method %q", req.Method)}
+			}
+		}
+
+		if rpcErr != nil {
+			log.Warn("request failed", "method", req.Method, "code", rpcErr.Code, "error", rpcErr.Message, "duration", time.Since(start))
+		} else {
+			log.Info("request", "method", req.Method, "duration", time.Since(start))
+		}
+
+		// Notifications (requests without an id) get no response
+		if req.ID != nil {
+			resp := rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr}
+			if result == nil && rpcErr == nil {
+				resp.Result = json.RawMessage("null")
+			}
+			if err := encoder.Encode(resp); err != nil {
+				return err
+			}
+		}
+		if req.Method == "shutdown" {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// servePack walks and formats the files for a pack request.
+func servePack(req PackRequest, cache *FileCache) (PackResult, error) {
+	var result PackResult
+	if req.Format == "" {
+		req.Format = FormatText
+	}
+	if !slices.Contains(Formats, req.Format) {
+		return result, fmt.Errorf("unknown format %q (expected one of: %s)", req.Format, strings.Join(Formats, ", "))
+	}
+
+	paths := req.Paths
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	if req.Root != "" {
+		paths = slices.Clone(paths)
+		for i, path := range paths {
+			if !filepath.IsAbs(path) {
+				paths[i] = filepath.Join(req.Root, path)
+			}
+		}
+	}
+
+	walker, err := NewWalker(paths...)
+	if err != nil {
+		return result, err
+	}
+	if req.Root != "" {
+		if err := walker.SetRoot(req.Root); err != nil {
+			return result, err
+		}
+	}
+	walker.Cache = cache
+	walker.IgnorePatterns = req.IgnorePatterns
+	walker.MaxDepth = req.MaxDepth
+	walker.IncludeGenerated = req.IncludeGenerated
+	walker.OnSkip = func(path, reason string) { result.Skipped++ }
+	for _, expr := range req.ExcludeRegex {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return result, fmt.Errorf("invalid exclude_regex %q: %w", expr, err)
+		}
+		walker.ExcludeRegexps = append(walker.ExcludeRegexps, re)
+	}
+
+	files, err := walker.Walk()
+	if err != nil {
+		return result, err
+	}
+	cache.Prune()
+	if req.Dedupe {
+		files, _ = Dedupe(files)
+	}
+
+	formatter := NewFormatter(files)
+	formatter.OutputFormat = req.Format
+	formatter.Summary = req.Summary
+	result.Pack = formatter.Format()
+	result.Tokens = EstimateTokens(result.Pack)
+	result.Files = make([]PackedFile, len(files))
+	for i, file := range files {
+		result.Files[i] = PackedFile{Path: filepath.ToSlash(file.Path), Tokens: FileTokens(file)}
+	}
+	return result, nil
+}
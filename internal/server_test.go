@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestServe(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "a.go", "sub/b.go", "sub/c.txt")
+
+	requests := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"pack","params":{"root":` + quote(dir) + `,"paths":["sub"],"ignore_patterns":["*.txt"]}}`,
+		`{"jsonrpc":"2.0","method":"pack","params":{"root":` + quote(dir) + `}}`,
+		`not json`,
+		`{"jsonrpc":"2.0","id":"x","method":"bogus"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"pack","params":{"root":` + quote(dir) + `,"format":"xml"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"pack","params":{}}`,
+	}, "\n")
+
+	var out bytes.Buffer
+	if err := Serve(strings.NewReader(requests), &out, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	type response struct {
+		ID     json.RawMessage `json:"id"`
+		Result *PackResult     `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+	var responses []response
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var resp response
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("invalid response %q: %v", line, err)
+		}
+		responses = append(responses, resp)
+	}
+
+	// The notification gets no response, and nothing after shutdown runs
+	if len(responses) != 5 {
+		t.Fatalf("got %d responses, want 5:\n%s", len(responses), out.String())
+	}
+
+	packed := responses[0].Result
+	if packed == nil || len(packed.Files) != 1 || packed.Files[0].Path != "sub/b.go" {
+		t.Errorf("pack result = %+v, want only sub/b.go", packed)
+	} else if !strings.Contains(packed.Pack, "File: sub/b.go") || packed.Skipped != 1 || packed.Tokens == 0 {
+		t.Errorf("pack result = %+v", packed)
+	}
+
+	wantErrors := []struct {
+		id   string
+		code int
+	}{{"null", rpcParseError}, {`"x"`, rpcMethodNotFound}, {"2", rpcServerError}}
+	for i, want := range wantErrors {
+		resp := responses[i+1]
+		if string(resp.ID) != want.id || resp.Error == nil || resp.Error.Code != want.code {
+			t.Errorf("response %d = id %s, error %+v; want id %s, code %d", i+1, resp.ID, resp.Error, want.id, want.code)
+		}
+	}
+	if resp := responses[4]; string(resp.ID) != "3" || resp.Error != nil {
+		t.Errorf("shutdown response = %+v", resp)
+	}
+}
+
+func TestFileCache(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "a.go")
+
+	cache := NewFileCache()
+	walk := func() string {
+		walker, err := NewWalker(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		walker.Cache = cache
+		files, err := walker.Walk()
+		if err != nil || len(files) != 1 {
+			t.Fatalf("Walk() = %v, %v", files, err)
+		}
+		return string(files[0].Content)
+	}
+
+	if got := walk(); got != "x\n" {
+		t.Errorf("first walk = %q", got)
+	}
+	// A change in size invalidates the cached content
+	if err := writeFile(filepath.Join(dir, "a.go"), "package a\n"); err != nil {
+		t.Fatal(err)
+	}
+	if got := walk(); got != "package a\n" {
+		t.Errorf("walk after change = %q, want the new content", got)
+	}
+}
+
+func TestFileCachePrune(t *testing.T) {
+	dir, other := t.TempDir(), t.TempDir()
+	writeFiles(t, dir, "a.go", "b.go")
+	writeFiles(t, other, "c.go")
+
+	cache := NewFileCache()
+	pack := func(root string) {
+		if _, err := servePack(PackRequest{Root: root}, cache); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cached := func() []string {
+		var paths []string
+		for path := range cache.entries {
+			paths = append(paths, filepath.Base(path))
+		}
+		slices.Sort(paths)
+		return paths
+	}
+
+	pack(dir)
+	if got, want := cached(), []string{"a.go", "b.go"}; !slices.Equal(got, want) {
+		t.Errorf("cached %q, want %q", got, want)
+	}
+	// A deleted file is forgotten by the next pack
+	if err := os.Remove(filepath.Join(dir, "b.go")); err != nil {
+		t.Fatal(err)
+	}
+	pack(dir)
+	if got, want := cached(), []string{"a.go"}; !slices.Equal(got, want) {
+		t.Errorf("cached after deleting b.go %q, want %q", got, want)
+	}
+	// So is a tree once another is packed
+	pack(other)
+	if got, want := cached(), []string{"c.go"}; !slices.Equal(got, want) {
+		t.Errorf("cached after packing another root %q, want %q", got, want)
+	}
+}
+
+// quote returns s as a JSON string.
+func quote(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
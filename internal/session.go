@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Files of a session bundle.
+const (
+	SessionMetaFile     = "session.json"
+	SessionPackFile     = "pack.txt"
+	SessionPromptFile   = "prompt.txt"
+	SessionResponseFile = "response.md"
+)
+
+// Session describes a session bundle: a pack, the prompt written for it,
+// and, when it was sent with the built-in client, the model's response.
+type Session struct {
+	Time     time.Time `json:"time"`
+	Dir      string    `json:"dir"`  // working directory the pack was made in
+	Args     []string  `json:"args"` // command-line arguments, without the program name
+	Files    []string  `json:"files"`
+	Tokens   int       `json:"tokens"`             // estimated tokens of the prompt and pack together
+	Provider string    `json:"provider,omitempty"` // set when the prompt was sent
+	Model    string    `json:"model,omitempty"`
+}
+
+// SessionDir returns the directory session bundles are kept in by default:
+// gopack/sessions under the user's cache directory (~/.cache on Linux).
+func SessionDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gopack", "sessions"), nil
+}
+
+// WriteSession creates a bundle directory under base, named after the
+// session's time, and writes the pack, the prompt (the question asked about
+// it), and the session's description into it. It returns the directory, so
+// the response can be added once it arrives.
+func WriteSession(base string, session Session, pack, prompt string) (string, error) {
+	if err := os.MkdirAll(base, 0755); err != nil {
+		return "", err
+	}
+	name := session.Time.Format("2006-01-02T150405")
+	dir := filepath.Join(base, name)
+	for n := 2; ; n++ {
+		err := os.Mkdir(dir, 0755)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return "", err
+		}
+		dir = filepath.Join(base, fmt.Sprintf("%s-%d", name, n))
+	}
+
+	meta, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	for file, content := range map[string]string{
+		SessionMetaFile:   string(meta) + "\n",
+		SessionPackFile:   pack,
+		SessionPromptFile: prompt,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
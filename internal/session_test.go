@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWriteSession(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "sessions")
+	session := Session{
+		Time:   time.Date(2026, 3, 3, 9, 30, 15, 0, time.UTC),
+		Dir:    "/src/app",
+		Args:   []string{"session", "Why does it hang?", "internal"},
+		Files:  []string{"internal/server.go"},
+		Tokens: 420,
+	}
+
+	dir, err := WriteSession(base, session, "File: internal/server.go\npackage internal\n", "Why does it hang?\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(base, "2026-03-03T093015"); dir != want {
+		t.Errorf("WriteSession() = %s, want %s", dir, want)
+	}
+	for name, want := range map[string]string{
+		SessionPackFile:   "File: internal/server.go\npackage internal\n",
+		SessionPromptFile: "Why does it hang?\n",
+	} {
+		if got, err := os.ReadFile(filepath.Join(dir, name)); err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(dir, SessionMetaFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Session
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Time.Equal(session.Time) || got.Dir != session.Dir || !slices.Equal(got.Args, session.Args) ||
+		!slices.Equal(got.Files, session.Files) || got.Tokens != session.Tokens || got.Provider != "" {
+		t.Errorf("%s = %+v, want %+v", SessionMetaFile, got, session)
+	}
+
+	// A second session in the same second gets its own bundle
+	again, err := WriteSession(base, session, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(base, "2026-03-03T093015-2"); again != want {
+		t.Errorf("WriteSession() again = %s, want %s", again, want)
+	}
+}
@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"bytes"
+	"hash/fnv"
+	"maps"
+	"slices"
+)
+
+const (
+	// NearDuplicateSimilarity is the share of line shingles two files must
+	// have in common to be reported as near-duplicates.
+	NearDuplicateSimilarity = 0.8
+
+	// DuplicateBlockLines is the fewest non-blank lines a block repeated
+	// across files must have to be reported.
+	DuplicateBlockLines = 15
+
+	// shingleLines is the length of the runs of lines compared.
+	shingleLines = 4
+
+	// commonShingle is how many times a run of lines may occur before it is
+	// taken for boilerplate, such as closing braces, and not compared.
+	commonShingle = 16
+)
+
+// NearDuplicate is a pair of files with mostly the same lines.
+type NearDuplicate struct {
+	A, B       string
+	Similarity float64 // the Jaccard similarity of their shingles, 0 to 1
+}
+
+// DuplicateBlock is a run of lines repeated in two files, by line number
+// in each (1-based, inclusive).
+type DuplicateBlock struct {
+	A, B                       string
+	StartA, EndA, StartB, EndB int
+}
+
+// shingledFile is a file's non-blank lines, with surrounding whitespace
+// removed, and the hashes of each run of shingleLines of them.
+type shingledFile struct {
+	lines    [][]byte
+	numbers  []int // the line number of each line
+	shingles []uint64
+}
+
+// shingle prepares a file's content for comparison.
+func shingle(content []byte) shingledFile {
+	var file shingledFile
+	for i, line := range bytes.Split(content, []byte("\n")) {
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			file.lines = append(file.lines, line)
+			file.numbers = append(file.numbers, i+1)
+		}
+	}
+	for i := 0; i+shingleLines <= len(file.lines); i++ {
+		h := fnv.New64a()
+		for _, line := range file.lines[i : i+shingleLines] {
+			h.Write(line)
+			h.Write([]byte{'\n'})
+		}
+		file.shingles = append(file.shingles, h.Sum64())
+	}
+	return file
+}
+
+// FindDuplication compares files by hashing overlapping runs of lines
+// (shingling), ignoring blank lines and indentation. It reports pairs of
+// files at least NearDuplicateSimilarity alike, and, between other files,
+// blocks of at least DuplicateBlockLines lines that appear in both.
+// Dedupe references are skipped.
+func FindDuplication(files []File) ([]NearDuplicate, []DuplicateBlock) {
+	shingled := make([]shingledFile, len(files))
+	type occurrence struct{ file, index int }
+	seen := make(map[uint64][]occurrence)
+	for i, file := range files {
+		if file.DuplicateOf != "" {
+			continue
+		}
+		shingled[i] = shingle(file.Content)
+		for j, hash := range shingled[i].shingles {
+			seen[hash] = append(seen[hash], occurrence{i, j})
+		}
+	}
+
+	// Count the distinct shingles each pair of files shares, and note
+	// where they line up
+	type pair struct{ a, b int }
+	shared := make(map[pair]int)
+	matches := make(map[pair][][2]int)
+	for _, hash := range slices.Sorted(maps.Keys(seen)) {
+		occurrences := seen[hash]
+		if len(occurrences) > commonShingle {
+			continue
+		}
+		counted := make(map[pair]bool)
+		for x, first := range occurrences {
+			for _, second := range occurrences[x+1:] {
+				if first.file == second.file {
+					continue
+				}
+				p := pair{first.file, second.file}
+				if !counted[p] {
+					counted[p] = true
+					shared[p]++
+				}
+				matches[p] = append(matches[p], [2]int{first.index, second.index})
+			}
+		}
+	}
+
+	var near []NearDuplicate
+	var blocks []DuplicateBlock
+	pairs := slices.SortedFunc(maps.Keys(shared), func(x, y pair) int {
+		if x.a != y.a {
+			return x.a - y.a
+		}
+		return x.b - y.b
+	})
+	for _, p := range pairs {
+		a, b := shingled[p.a], shingled[p.b]
+		union := distinct(a.shingles) + distinct(b.shingles) - shared[p]
+		if similarity := float64(shared[p]) / float64(union); similarity >= NearDuplicateSimilarity {
+			near = append(near, NearDuplicate{A: files[p.a].Path, B: files[p.b].Path, Similarity: similarity})
+			continue
+		}
+
+		// Widen each match to the whole run of lines it is part of
+		found := make(map[[2]int]bool)
+		var pairBlocks []DuplicateBlock
+		for _, m := range matches[p] {
+			i, j := m[0], m[1]
+			for i > 0 && j > 0 && bytes.Equal(a.lines[i-1], b.lines[j-1]) {
+				i, j = i-1, j-1
+			}
+			if found[[2]int{i, j}] {
+				continue
+			}
+			found[[2]int{i, j}] = true
+			n := 0
+			for i+n < len(a.lines) && j+n < len(b.lines) && bytes.Equal(a.lines[i+n], b.lines[j+n]) {
+				n++
+			}
+			if n >= DuplicateBlockLines {
+				pairBlocks = append(pairBlocks, DuplicateBlock{
+					A: files[p.a].Path, StartA: a.numbers[i], EndA: a.numbers[i+n-1],
+					B: files[p.b].Path, StartB: b.numbers[j], EndB: b.numbers[j+n-1],
+				})
+			}
+		}
+		slices.SortFunc(pairBlocks, func(x, y DuplicateBlock) int { return x.StartA - y.StartA })
+		blocks = append(blocks, pairBlocks...)
+	}
+	return near, blocks
+}
+
+// distinct counts the distinct hashes in a list.
+func distinct(hashes []uint64) int {
+	set := make(map[uint64]bool, len(hashes))
+	for _, hash := range hashes {
+		set[hash] = true
+	}
+	return len(set)
+}
@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// numberedLines returns n distinct lines, "line start" onwards.
+func numberedLines(start, n int) string {
+	var b strings.Builder
+	for i := start; i < start+n; i++ {
+		fmt.Fprintf(&b, "\tvalue := compute(%d)\n", i)
+	}
+	return b.String()
+}
+
+func TestFindDuplication(t *testing.T) {
+	original := numberedLines(0, 40)
+	files := []File{
+		{Path: "a.go", Content: []byte(original)},
+		// The same lines, reindented, one changed and a blank line added
+		{Path: "b.go", Content: []byte(strings.ReplaceAll(strings.Replace(original, "compute(20)", "compute(-1)", 1), "\t", "    ") + "\n")},
+		// 20 of a.go's lines (a.go:11-30) starting at line 4
+		{Path: "c.go", Content: []byte("package c\n\n" + numberedLines(100, 1) + numberedLines(10, 20) + numberedLines(200, 30))},
+		// Too short a repeat to report
+		{Path: "d.go", Content: []byte(numberedLines(300, 30) + numberedLines(5, 10))},
+		{Path: "e.go", Content: []byte("[identical to a.go]\n"), DuplicateOf: "a.go"},
+	}
+
+	near, blocks := FindDuplication(files)
+	if len(near) != 1 || near[0].A != "a.go" || near[0].B != "b.go" || near[0].Similarity < NearDuplicateSimilarity || near[0].Similarity == 1 {
+		t.Errorf("FindDuplication() near-duplicates = %+v, want a.go and b.go", near)
+	}
+
+	// b.go differs from a.go at compute(20), so the runs it shares with
+	// c.go are too short to report
+	want := []DuplicateBlock{{A: "a.go", StartA: 11, EndA: 30, B: "c.go", StartB: 4, EndB: 23}}
+	if fmt.Sprint(blocks) != fmt.Sprint(want) {
+		t.Errorf("FindDuplication() blocks = %+v, want %+v", blocks, want)
+	}
+}
@@ -0,0 +1,258 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"maps"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SortOrders lists the orders accepted by SortFiles.
+var SortOrders = []string{"path", "size", "mtime", "tokens", "git-churn", "go-entry", "deps"}
+
+// SortFiles orders files in place. "path" sorts alphabetically; "size" and
+// "tokens" put the largest files first; "mtime" puts the most recently
+// modified first; "git-churn" puts files with the most commits first (root
+// must be inside a git repository), counting only commits after churnSince
+// if it is non-zero; "go-entry" reads a Go tree top-down, from main
+// packages through exported APIs to internal helpers, with tests last; "deps"
+// puts each Go package after the packages of the module it imports. Ties are
+// broken by path so the result is deterministic, and reverse inverts the
+// whole order.
+func SortFiles(ctx context.Context, files []File, order string, reverse bool, root string, churnSince time.Time) error {
+	var less func(a, b File) bool
+
+	switch order {
+	case "path":
+		less = func(a, b File) bool { return false }
+	case "size":
+		less = func(a, b File) bool { return len(a.Content) > len(b.Content) }
+	case "mtime":
+		less = func(a, b File) bool { return a.ModTime.After(b.ModTime) }
+	case "tokens":
+		less = func(a, b File) bool { return FileTokens(a) > FileTokens(b) }
+	case "git-churn":
+		counts, err := commitCounts(ctx, root, churnSince)
+		if err != nil {
+			return fmt.Errorf("failed to read git history: %w", err)
+		}
+		less = func(a, b File) bool {
+			return counts[filepath.ToSlash(a.Path)] > counts[filepath.ToSlash(b.Path)]
+		}
+	case "go-entry":
+		ranks := make(map[string]int, len(files))
+		for _, file := range files {
+			ranks[file.Path] = goEntryRank(file)
+		}
+		less = func(a, b File) bool {
+			if ranks[a.Path] != ranks[b.Path] {
+				return ranks[a.Path] < ranks[b.Path]
+			}
+			return pathDepth(a.Path) < pathDepth(b.Path)
+		}
+	case "deps":
+		ranks := dependencyRanks(files, root)
+		less = func(a, b File) bool { return ranks[a.Path] < ranks[b.Path] }
+	default:
+		return fmt.Errorf("unknown sort order %q (expected one of: %s)", order, strings.Join(SortOrders, ", "))
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		a, b := files[i], files[j]
+		if reverse {
+			a, b = b, a
+		}
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return filepath.ToSlash(a.Path) < filepath.ToSlash(b.Path)
+	})
+	return nil
+}
+
+// goEntryRank places a file in the "go-entry" order: main packages, then
+// files exporting API from public packages, then their unexported helpers,
+// then anything under an internal/ directory, then non-Go files, and
+// tests last.
+func goEntryRank(file File) int {
+	path := filepath.ToSlash(file.Path)
+	if strings.HasSuffix(path, "_test.go") {
+		return 5
+	}
+	if !strings.HasSuffix(path, ".go") {
+		return 4
+	}
+
+	parsed, err := parser.ParseFile(token.NewFileSet(), path, file.Content, parser.SkipObjectResolution)
+	if err != nil {
+		// Fall back to the package clause for files that don't fully parse
+		parsed, err = parser.ParseFile(token.NewFileSet(), path, file.Content, parser.PackageClauseOnly)
+		if err != nil {
+			return 4
+		}
+	}
+	switch {
+	case parsed.Name.Name == "main":
+		return 0
+	case path == "internal" || strings.HasPrefix(path, "internal/") || strings.Contains(path, "/internal/"):
+		return 3
+	case exportsAPI(parsed):
+		return 1
+	}
+	return 2
+}
+
+// dependencyRanks places each file in the "deps" order: Go packages
+// bottom-up, each after the packages it imports from the same module (read
+// from a packed go.mod, or the one in root), then non-Go files. Packages in
+// an import cycle keep their path order.
+func dependencyRanks(files []File, root string) map[string]int {
+	module := modulePath(root)
+	imports := make(map[string][]string) // package directory to import paths
+	for _, file := range files {
+		name := filepath.ToSlash(file.Path)
+		if name == "go.mod" {
+			module = parseModulePath(file.Content)
+		}
+		if !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		dir := path.Dir(name)
+		if _, ok := imports[dir]; !ok {
+			imports[dir] = nil // a package, even if it imports nothing
+		}
+		parsed, err := parser.ParseFile(token.NewFileSet(), name, file.Content, parser.ImportsOnly)
+		if err != nil {
+			continue
+		}
+		for _, spec := range parsed.Imports {
+			if imported, err := strconv.Unquote(spec.Path.Value); err == nil {
+				imports[dir] = append(imports[dir], imported)
+			}
+		}
+	}
+
+	// resolve finds the packed package an import path names: by the module
+	// path if known, otherwise the longest directory it ends with.
+	resolve := func(imported string) (string, bool) {
+		if module != "" {
+			if imported == module {
+				imported = "."
+			} else if rest, ok := strings.CutPrefix(imported, module+"/"); ok {
+				imported = rest
+			} else {
+				return "", false
+			}
+			_, ok := imports[imported]
+			return imported, ok
+		}
+		match := ""
+		for dir := range imports {
+			if (imported == dir || strings.HasSuffix(imported, "/"+dir)) && len(dir) > len(match) {
+				match = dir
+			}
+		}
+		return match, match != ""
+	}
+
+	// Visit the packages in path order, each after its imports
+	dirs := slices.Sorted(maps.Keys(imports))
+	order := make(map[string]int, len(dirs))
+	visiting := make(map[string]bool)
+	var visit func(dir string)
+	visit = func(dir string) {
+		if _, done := order[dir]; done || visiting[dir] {
+			return
+		}
+		visiting[dir] = true
+		deps := slices.Clone(imports[dir])
+		slices.Sort(deps)
+		for _, imported := range deps {
+			if dep, ok := resolve(imported); ok && dep != dir {
+				visit(dep)
+			}
+		}
+		order[dir] = len(order)
+	}
+	for _, dir := range dirs {
+		visit(dir)
+	}
+
+	ranks := make(map[string]int, len(files))
+	for _, file := range files {
+		name := filepath.ToSlash(file.Path)
+		if rank, ok := order[path.Dir(name)]; ok && strings.HasSuffix(name, ".go") {
+			ranks[file.Path] = rank
+		} else {
+			ranks[file.Path] = len(order)
+		}
+	}
+	return ranks
+}
+
+// exportsAPI reports whether a Go file declares any exported identifier.
+func exportsAPI(file *ast.File) bool {
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Name.IsExported() && (decl.Recv == nil || receiverExported(decl.Recv)) {
+				return true
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if spec.Name.IsExported() {
+						return true
+					}
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						if name.IsExported() {
+							return true
+						}
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// receiverExported reports whether a method's receiver type is exported.
+func receiverExported(recv *ast.FieldList) bool {
+	if len(recv.List) == 0 {
+		return false
+	}
+	expr := recv.List[0].Type
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.IsExported()
+		default:
+			return false
+		}
+	}
+}
+
+// pathDepth returns the number of directories above a path.
+func pathDepth(path string) int {
+	return strings.Count(filepath.ToSlash(path), "/")
+}
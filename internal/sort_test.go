@@ -0,0 +1,154 @@
+package internal
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSortFiles(t *testing.T) {
+	base := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	files := []File{
+		{Path: "b.go", Content: []byte(strings.Repeat("x", 20)), ModTime: base.Add(2 * time.Hour)},
+		{Path: "a.go", Content: []byte(strings.Repeat("x", 40)), ModTime: base},
+		{Path: "c.go", Content: []byte(strings.Repeat("x", 4)), ModTime: base.Add(time.Hour)},
+		{Path: "d.go", Content: []byte(strings.Repeat("x", 20)), ModTime: base.Add(time.Hour)},
+	}
+
+	tests := []struct {
+		order   string
+		reverse bool
+		want    []string
+	}{
+		{"path", false, []string{"a.go", "b.go", "c.go", "d.go"}},
+		{"path", true, []string{"d.go", "c.go", "b.go", "a.go"}},
+		{"size", false, []string{"a.go", "b.go", "d.go", "c.go"}}, // ties by path
+		{"size", true, []string{"c.go", "d.go", "b.go", "a.go"}},
+		{"mtime", false, []string{"b.go", "c.go", "d.go", "a.go"}},
+		{"tokens", false, []string{"a.go", "b.go", "d.go", "c.go"}},
+	}
+
+	for _, tt := range tests {
+		sorted := slices.Clone(files)
+		if err := SortFiles(context.Background(), sorted, tt.order, tt.reverse, "", time.Time{}); err != nil {
+			t.Fatalf("SortFiles(%q) error = %v", tt.order, err)
+		}
+		var got []string
+		for _, file := range sorted {
+			got = append(got, file.Path)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("SortFiles(%q, reverse=%v) = %q, want %q", tt.order, tt.reverse, got, tt.want)
+		}
+	}
+
+	if err := SortFiles(context.Background(), slices.Clone(files), "random", false, "", time.Time{}); err == nil {
+		t.Error("SortFiles(\"random\") succeeded, want error")
+	}
+}
+
+func TestSortFilesPathBytes(t *testing.T) {
+	// Paths compare byte by byte with forward slashes, without folding
+	// case or using the locale, so every platform gets the same order
+	files := []File{{Path: "ab.go"}, {Path: "a/z.go"}, {Path: "a.go"}, {Path: "a-b.go"}, {Path: "B.go"}, {Path: "é.go"}}
+	if err := SortFiles(context.Background(), files, "path", false, "", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, file := range files {
+		got = append(got, file.Path)
+	}
+	want := []string{"B.go", "a-b.go", "a.go", "a/z.go", "ab.go", "é.go"}
+	if !slices.Equal(got, want) {
+		t.Errorf("SortFiles(path) = %q, want %q", got, want)
+	}
+}
+
+func TestSortFilesGoEntry(t *testing.T) {
+	files := []File{
+		{Path: "README.md", Content: []byte("# Project\n")},
+		{Path: "api/api_test.go", Content: []byte("package api\n")},
+		{Path: "api/helpers.go", Content: []byte("package api\n\nfunc helper() {}\n")},
+		{Path: "internal/store/store.go", Content: []byte("package store\n\nfunc Open() {}\n")},
+		{Path: "api/api.go", Content: []byte("package api\n\ntype Client struct{}\n")},
+		{Path: "api/method.go", Content: []byte("package api\n\ntype client struct{}\n\nfunc (c *client) Do() {}\n")},
+		{Path: "cmd/tool/main.go", Content: []byte("package main\n\nfunc main() {}\n")},
+		{Path: "main.go", Content: []byte("package main\n\nfunc main() {}\n")},
+		{Path: "broken.go", Content: []byte("package main\n\nfunc {\n")},
+	}
+
+	if err := SortFiles(context.Background(), files, "go-entry", false, "", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"broken.go", "main.go", "cmd/tool/main.go", // main packages, shallowest first
+		"api/api.go",                      // exported API
+		"api/helpers.go", "api/method.go", // unexported helpers
+		"internal/store/store.go", // internal packages
+		"README.md",               // non-Go files
+		"api/api_test.go",         // tests
+	}
+	for i, file := range files {
+		if file.Path != want[i] {
+			t.Errorf("SortFiles(go-entry)[%d] = %q, want %q", i, file.Path, want[i])
+		}
+	}
+}
+
+func TestSortFilesGitChurn(t *testing.T) {
+	dir := gitRepo(t)
+	files := []File{{Path: "héllo.go"}, {Path: "b.go"}, {Path: "untracked.go"}}
+
+	if err := SortFiles(context.Background(), files, "git-churn", false, dir, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"b.go", "héllo.go", "untracked.go"}
+	for i, file := range files {
+		if file.Path != want[i] {
+			t.Errorf("SortFiles(git-churn)[%d] = %q, want %q", i, file.Path, want[i])
+		}
+	}
+}
+
+func TestSortFilesDeps(t *testing.T) {
+	files := []File{
+		{Path: "README.md", Content: []byte("# Project\n")},
+		{Path: "main.go", Content: []byte("package main\n\nimport (\n\t\"fmt\"\n\n\t\"example.com/app/api\"\n)\n")},
+		{Path: "api/api.go", Content: []byte("package api\n\nimport \"example.com/app/internal/store\"\n")},
+		{Path: "api/api_test.go", Content: []byte("package api\n")},
+		{Path: "internal/store/store.go", Content: []byte("package store\n\nimport \"example.com/app/internal/log\"\n")},
+		{Path: "internal/log/log.go", Content: []byte("package log\n\nimport \"os\"\n")},
+		{Path: "go.mod", Content: []byte("module example.com/app\n\ngo 1.24\n")},
+		// A cycle is broken in path order
+		{Path: "cycle/a/a.go", Content: []byte("package a\n\nimport \"example.com/app/cycle/b\"\n")},
+		{Path: "cycle/b/b.go", Content: []byte("package b\n\nimport \"example.com/app/cycle/a\"\n")},
+	}
+
+	if err := SortFiles(context.Background(), files, "deps", false, "", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"internal/log/log.go", "internal/store/store.go", "api/api.go", "api/api_test.go", // api's dependencies first
+		"main.go",
+		"cycle/b/b.go", "cycle/a/a.go",
+		"README.md", "go.mod", // non-Go files
+	}
+	for i, file := range files {
+		if file.Path != want[i] {
+			t.Errorf("SortFiles(deps)[%d] = %q, want %q", i, file.Path, want[i])
+		}
+	}
+}
+
+func TestDependencyRanksWithoutModule(t *testing.T) {
+	files := []File{
+		{Path: "app.go", Content: []byte("package app\n\nimport \"github.com/someone/app/util\"\n")},
+		{Path: "util/util.go", Content: []byte("package util\n")},
+	}
+	ranks := dependencyRanks(files, t.TempDir())
+	if ranks["util/util.go"] >= ranks["app.go"] {
+		t.Errorf("dependencyRanks() = %v, want util/util.go before app.go", ranks)
+	}
+}
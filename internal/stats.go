@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"bytes"
+	"path"
+	"sort"
+	"strings"
+)
+
+// LanguageStats summarizes the files of a single language.
+type LanguageStats struct {
+	Language string
+	Files    int
+	Lines    int
+	Bytes    int
+	Tokens   int
+}
+
+// add accumulates a file into the summary.
+func (s *LanguageStats) add(file File) {
+	s.Files++
+	s.Lines += countLines(file.Content)
+	s.Bytes += len(file.Content)
+	s.Tokens += FileTokens(file)
+}
+
+// Stats groups files by language and returns one summary per language,
+// ordered by token count (largest first), along with the overall totals.
+func Stats(files []File) ([]LanguageStats, LanguageStats) {
+	byLanguage := make(map[string]*LanguageStats)
+	total := LanguageStats{Language: "Total"}
+
+	for _, file := range files {
+		language := DetectLanguage(file.Path, file.Content).Name
+		stats, ok := byLanguage[language]
+		if !ok {
+			stats = &LanguageStats{Language: language}
+			byLanguage[language] = stats
+		}
+		stats.add(file)
+		total.add(file)
+	}
+
+	result := make([]LanguageStats, 0, len(byLanguage))
+	for _, stats := range byLanguage {
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Tokens != result[j].Tokens {
+			return result[i].Tokens > result[j].Tokens
+		}
+		return result[i].Language < result[j].Language
+	})
+
+	return result, total
+}
+
+// TokenShare is the estimated tokens of a group of files.
+type TokenShare struct {
+	Name   string
+	Tokens int
+}
+
+// RootGroup names the files at the top of the tree in DirectoryTokens.
+const RootGroup = "(root files)"
+
+// DirectoryTokens groups files by their top-level directory, with the files
+// outside any directory under RootGroup, ordered by token count (largest
+// first).
+func DirectoryTokens(files []File) []TokenShare {
+	byDir := make(map[string]int)
+	for _, file := range files {
+		name := strings.TrimPrefix(path.Clean(strings.ReplaceAll(file.Path, `\`, "/")), "/")
+		dir, _, ok := strings.Cut(name, "/")
+		if !ok {
+			dir = RootGroup
+		} else {
+			dir += "/"
+		}
+		byDir[dir] += FileTokens(file)
+	}
+
+	result := make([]TokenShare, 0, len(byDir))
+	for name, tokens := range byDir {
+		result = append(result, TokenShare{Name: name, Tokens: tokens})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Tokens != result[j].Tokens {
+			return result[i].Tokens > result[j].Tokens
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// countLines returns the number of lines in content, counting a final line
+// without a trailing newline.
+func countLines(content []byte) int {
+	lines := bytes.Count(content, []byte("\n"))
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		lines++
+	}
+	return lines
+}
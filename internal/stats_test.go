@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	files := []File{
+		{Path: "a.go", Content: []byte("package a\n\nfunc A() {}\n" + strings.Repeat("// x\n", 40))},
+		{Path: "sub/b.go", Content: []byte("package b")},
+		{Path: "README.md", Content: []byte("# Demo\n\nSome words.\n")},
+		{Path: "run", Content: []byte("#!/bin/sh\necho hi\n")},
+	}
+	languages, total := Stats(files)
+
+	// Languages come largest first, ties by name, sniffing files without
+	// an extension
+	want := []LanguageStats{
+		{Language: "Go", Files: 2, Lines: 44, Bytes: len(files[0].Content) + len(files[1].Content), Tokens: FileTokens(files[0]) + FileTokens(files[1])},
+		{Language: "Markdown", Files: 1, Lines: 3, Bytes: len(files[2].Content), Tokens: FileTokens(files[2])},
+		{Language: "Shell", Files: 1, Lines: 2, Bytes: len(files[3].Content), Tokens: FileTokens(files[3])},
+	}
+	if len(languages) != len(want) {
+		t.Fatalf("Stats() = %+v, want %+v", languages, want)
+	}
+	for i := range want {
+		if languages[i] != want[i] {
+			t.Errorf("Stats()[%d] = %+v, want %+v", i, languages[i], want[i])
+		}
+	}
+
+	if total.Language != "Total" || total.Files != 4 || total.Lines != 49 {
+		t.Errorf("total = %+v, want 4 files and 49 lines", total)
+	}
+	if sum := want[0].Tokens + want[1].Tokens + want[2].Tokens; total.Tokens != sum {
+		t.Errorf("total tokens = %d, want %d", total.Tokens, sum)
+	}
+}
+
+func TestCountLines(t *testing.T) {
+	tests := []struct {
+		content string
+		want    int
+	}{
+		{"", 0},
+		{"one", 1},
+		{"one\n", 1},
+		{"one\ntwo", 2},
+		{"\n\n", 2},
+	}
+	for _, tt := range tests {
+		if got := countLines([]byte(tt.content)); got != tt.want {
+			t.Errorf("countLines(%q) = %d, want %d", tt.content, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Suggestion is an ignore pattern SuggestIgnores recommends, with what it
+// would leave out of the pack.
+type Suggestion struct {
+	Pattern string `json:"pattern"` // gitignore-style, relative to the root
+	Reason  string `json:"reason"`
+	Files   int    `json:"files"`
+	Tokens  int    `json:"tokens"` // estimated tokens saved
+}
+
+// suggestLargeTokens is the size at which a single file is worth
+// suggesting on its own: about a tenth of a typical context window.
+const suggestLargeTokens = 20_000
+
+// Directories whose contents are rarely worth packing, by why.
+var (
+	vendoredDirs  = []string{"vendor", "third_party", "third-party", "node_modules", "bower_components", "Pods", "external"}
+	generatedDirs = []string{"generated", "__generated__", "build", "out", "target", "__snapshots__"}
+)
+
+// lockfiles are dependency lockfiles the built-in defaults don't already
+// leave out.
+var lockfiles = []string{
+	"npm-shrinkwrap.json", "bun.lock", "Pipfile.lock", "uv.lock", "pdm.lock", "mix.lock",
+	"pubspec.lock", "Podfile.lock", "Package.resolved", "flake.lock", "packages.lock.json",
+	"gradle.lockfile", "go.work.sum", "deno.lock",
+}
+
+// SuggestIgnores looks through files about to be packed for ones that are
+// rarely worth their tokens (vendored dependencies, generated or build
+// output, lockfiles, test snapshots, and single very large files) and
+// recommends an ignore pattern for each group, largest saving first. Each
+// file is counted under one suggestion at most.
+func SuggestIgnores(files []File) []Suggestion {
+	byPattern := make(map[string]*Suggestion)
+	add := func(pattern, reason string, file File) {
+		s, ok := byPattern[pattern]
+		if !ok {
+			s = &Suggestion{Pattern: pattern, Reason: reason}
+			byPattern[pattern] = s
+		}
+		s.Files++
+		s.Tokens += FileTokens(file)
+	}
+
+	for _, file := range files {
+		name := strings.TrimPrefix(path.Clean(strings.ReplaceAll(file.Path, `\`, "/")), "/")
+		parts := strings.Split(name, "/")
+		base := parts[len(parts)-1]
+		if dir, reason, ok := suggestDir(parts[:len(parts)-1]); ok {
+			add("/"+dir+"/", reason, file)
+			continue
+		}
+		switch {
+		case slices.Contains(lockfiles, base):
+			add(base, "lockfile", file)
+		case strings.HasSuffix(base, ".snap"):
+			add("*.snap", "test snapshots", file)
+		case FileTokens(file) >= suggestLargeTokens:
+			add("/"+name, "large file", file)
+		}
+	}
+
+	suggestions := make([]Suggestion, 0, len(byPattern))
+	for _, s := range byPattern {
+		suggestions = append(suggestions, *s)
+	}
+	slices.SortFunc(suggestions, func(a, b Suggestion) int {
+		if c := cmp.Compare(b.Tokens, a.Tokens); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Pattern, b.Pattern)
+	})
+	return suggestions
+}
+
+// suggestDir returns the outermost of a file's directories that holds
+// vendored or generated code, as a slash-separated path, and why.
+func suggestDir(dirs []string) (string, string, bool) {
+	for i, dir := range dirs {
+		switch {
+		case slices.Contains(vendoredDirs, dir):
+			return strings.Join(dirs[:i+1], "/"), "vendored dependencies", true
+		case slices.Contains(generatedDirs, dir):
+			return strings.Join(dirs[:i+1], "/"), "generated or build output", true
+		}
+	}
+	return "", "", false
+}
+
+// WriteSuggestions writes suggestions as ignore file lines, each pattern
+// after a comment giving its reason and saving.
+func WriteSuggestions(w io.Writer, suggestions []Suggestion) error {
+	var out strings.Builder
+	out.WriteString("# Suggested by gopack suggest\n")
+	for _, s := range suggestions {
+		fmt.Fprintf(&out, "# %s: %d files, ~%s tokens\n%s\n", s.Reason, s.Files, FormatWithCommas(s.Tokens), s.Pattern)
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSuggestIgnores(t *testing.T) {
+	files := []File{
+		{Path: "main.go", Content: []byte("package main\n")},
+		{Path: "vendor/github.com/x/a.go", Content: []byte(strings.Repeat("x", 4000))},
+		{Path: "vendor/github.com/x/b.go", Content: []byte(strings.Repeat("x", 4000))},
+		{Path: "web/node_modules/react/index.js", Content: []byte(strings.Repeat("x", 400))},
+		{Path: "web/build/app.js", Content: []byte(strings.Repeat("x", 200))},
+		{Path: "web/Pipfile.lock", Content: []byte("{}\n")},
+		{Path: "ui/__tests__/app.test.js.snap", Content: []byte("exports[`a`] = 1;\n")},
+		{Path: "data/dump.sql", Content: []byte(strings.Repeat("x", suggestLargeTokens*4))},
+		{Path: "vendor/huge.go", Content: []byte(strings.Repeat("x", suggestLargeTokens*4))},
+	}
+
+	got := SuggestIgnores(files)
+	want := []Suggestion{
+		{Pattern: "/vendor/", Reason: "vendored dependencies", Files: 3},
+		{Pattern: "/data/dump.sql", Reason: "large file", Files: 1},
+		{Pattern: "/web/node_modules/", Reason: "vendored dependencies", Files: 1},
+		{Pattern: "/web/build/", Reason: "generated or build output", Files: 1},
+		{Pattern: "*.snap", Reason: "test snapshots", Files: 1},
+		{Pattern: "Pipfile.lock", Reason: "lockfile", Files: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("SuggestIgnores() = %+v, want %d suggestions", got, len(want))
+	}
+	for i, s := range got {
+		if s.Pattern != want[i].Pattern || s.Reason != want[i].Reason || s.Files != want[i].Files {
+			t.Errorf("SuggestIgnores()[%d] = %+v, want %+v", i, s, want[i])
+		}
+	}
+	if tokens := FileTokens(files[1]) + FileTokens(files[2]) + FileTokens(files[8]); got[0].Tokens != tokens {
+		t.Errorf("/vendor/ saves %d tokens, want %d", got[0].Tokens, tokens)
+	}
+
+	var out strings.Builder
+	if err := WriteSuggestions(&out, got[:2]); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "\n/vendor/\n") || !strings.HasSuffix(out.String(), "\n/data/dump.sql\n") {
+		t.Errorf("WriteSuggestions() = %q", out.String())
+	}
+}
@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"html"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// symbolSection is the heading of the text format's symbol index.
+const symbolSection = "=== Symbol Index ===\n"
+
+// goSymbol is an exported declaration listed in the symbol index.
+type goSymbol struct {
+	kind string // "type", "func", "method", "const", or "var"
+	name string // the method's name is qualified by its receiver type
+	file int    // index of the declaring file in the pack
+	line int
+}
+
+// goPackage is a package's exported declarations, in the order declared.
+type goPackage struct {
+	dir, name string
+	symbols   []goSymbol
+}
+
+// goSymbols lists the exported declarations of the Go files, tests
+// excluded, grouped by package in the order the packages first appear.
+// Files that don't parse are skipped.
+func goSymbols(files []File) []*goPackage {
+	var packages []*goPackage
+	byDir := make(map[string]*goPackage)
+	for i, file := range files {
+		name := filepath.ToSlash(file.Path)
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") || file.DuplicateOf != "" {
+			continue
+		}
+		fset := token.NewFileSet()
+		parsed, err := parser.ParseFile(fset, name, file.Content, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+
+		dir := path.Dir(name)
+		pkg := byDir[dir]
+		if pkg == nil {
+			pkg = &goPackage{dir: dir, name: parsed.Name.Name}
+			byDir[dir] = pkg
+			packages = append(packages, pkg)
+		}
+		add := func(kind, name string, pos token.Pos) {
+			pkg.symbols = append(pkg.symbols, goSymbol{kind: kind, name: name, file: i, line: fset.Position(pos).Line})
+		}
+		for _, decl := range parsed.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				switch {
+				case !decl.Name.IsExported():
+				case decl.Recv == nil:
+					add("func", decl.Name.Name, decl.Pos())
+				case receiverExported(decl.Recv):
+					add("method", receiverName(decl.Recv)+"."+decl.Name.Name, decl.Pos())
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						if spec.Name.IsExported() {
+							add("type", spec.Name.Name, spec.Pos())
+						}
+					case *ast.ValueSpec:
+						for _, ident := range spec.Names {
+							if ident.IsExported() {
+								add(decl.Tok.String(), ident.Name, ident.Pos())
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// Packages without exports have nothing to list
+	listed := packages[:0]
+	for _, pkg := range packages {
+		if len(pkg.symbols) > 0 {
+			listed = append(listed, pkg)
+		}
+	}
+	return listed
+}
+
+// receiverName returns the name of a method's receiver type, without any
+// pointer or type parameters.
+func receiverName(recv *ast.FieldList) string {
+	expr := recv.List[0].Type
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// writeSymbolIndex writes the index of the exported Go declarations in the
+// pack, each with the file and line declaring it. Nothing is written if
+// there are none.
+func (f *Formatter) writeSymbolIndex(w io.Writer) {
+	packages := goSymbols(f.files)
+	if len(packages) == 0 {
+		return
+	}
+
+	switch f.OutputFormat {
+	case FormatHTML:
+		io.WriteString(w, "<h2>Symbol Index</h2>\n")
+		for _, pkg := range packages {
+			fmt.Fprintf(w, "<h3>package %s <small>%s</small></h3>\n<ul>\n", html.EscapeString(pkg.name), html.EscapeString(pkg.dir))
+			for _, sym := range pkg.symbols {
+				fmt.Fprintf(w, "<li><code>%s %s</code> <a href=\"#f%d\">%s:%d</a></li>\n",
+					sym.kind, html.EscapeString(sym.name), sym.file, html.EscapeString(f.files[sym.file].Path), sym.line)
+			}
+			io.WriteString(w, "</ul>\n")
+		}
+	case FormatMarkdown:
+		io.WriteString(w, "\n## Symbol Index\n")
+		for _, pkg := range packages {
+			fmt.Fprintf(w, "\n### package %s (`%s`)\n\n", pkg.name, pkg.dir)
+			for _, sym := range pkg.symbols {
+				fmt.Fprintf(w, "- `%s %s` %s:%d\n", sym.kind, sym.name, f.files[sym.file].Path, sym.line)
+			}
+		}
+	default:
+		io.WriteString(w, "\n\n"+symbolSection)
+		for _, pkg := range packages {
+			fmt.Fprintf(w, "package %s (%s)\n", pkg.name, pkg.dir)
+			for _, sym := range pkg.symbols {
+				fmt.Fprintf(w, "  %s %s %s:%d\n", sym.kind, sym.name, f.files[sym.file].Path, sym.line)
+			}
+		}
+	}
+}
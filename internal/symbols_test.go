@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestGoSymbols(t *testing.T) {
+	files := []File{
+		{Path: "README.md", Content: []byte("# API\n")},
+		{Path: "api/client.go", Content: []byte(`package api
+
+// Client calls the API.
+type Client struct{}
+
+type options struct{}
+
+func New() *Client { return nil }
+
+func (c *Client) Do() {}
+
+func (o options) Apply() {}
+
+func (c *Client) send() {}
+
+const (
+	Version = "1"
+	retries = 3
+)
+
+var ErrClosed, errInternal error
+`)},
+		{Path: "api/client_test.go", Content: []byte("package api\n\nfunc TestNew() {}\n")},
+		{Path: "api/list.go", Content: []byte("package api\n\ntype List[T any] struct{}\n\nfunc (l *List[T]) Len() int { return 0 }\n")},
+		{Path: "main.go", Content: []byte("package main\n\nfunc main() {}\n")},
+		{Path: "broken.go", Content: []byte("package broken\n\nfunc Exported( {\n")},
+	}
+
+	var got []string
+	for _, pkg := range goSymbols(files) {
+		for _, sym := range pkg.symbols {
+			got = append(got, fmt.Sprintf("%s %s %s %s %s:%d", pkg.name, pkg.dir, sym.kind, sym.name, files[sym.file].Path, sym.line))
+		}
+	}
+	want := []string{
+		"api api type Client api/client.go:4",
+		"api api func New api/client.go:8",
+		"api api method Client.Do api/client.go:10",
+		"api api const Version api/client.go:17",
+		"api api var ErrClosed api/client.go:21",
+		"api api type List api/list.go:3",
+		"api api method List.Len api/list.go:5",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("goSymbols() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestSymbolIndex(t *testing.T) {
+	files := []File{
+		{Path: "api/api.go", Content: []byte("package api\n\nfunc New() {}\n")},
+		{Path: "notes.txt", Content: []byte("New\n")},
+	}
+	wantLine := map[string]string{
+		FormatText:     "  func New api/api.go:3\n",
+		FormatMarkdown: "- `func New` api/api.go:3\n",
+		FormatHTML:     "<li><code>func New</code> <a href=\"#f0\">api/api.go:3</a></li>\n",
+	}
+
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			formatter := NewFormatter(files)
+			formatter.OutputFormat = format
+			formatter.SymbolIndex = true
+			formatter.Summary = true
+			output := formatter.Format()
+			if !strings.Contains(output, wantLine[format]) {
+				t.Errorf("output is missing %q:\n%s", wantLine[format], output)
+			}
+
+			// The index isn't mistaken for part of the last file
+			got, err := ParsePack([]byte(output))
+			if err != nil {
+				t.Fatalf("ParsePack() error = %v", err)
+			}
+			assertFiles(t, got, files, format)
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package internal
+
+import (
+	"go/parser"
+	"go/token"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// layoutDirs are directory names that only say where code or its tests
+// live (src/main/java against src/test/java, lib/ against spec/), so they're
+// left out when matching a test's directory to its subject's.
+var layoutDirs = []string{"src", "main", "lib", "app", "test", "tests", "spec", "__tests__"}
+
+var (
+	// jsRelativeImport matches relative module specifiers of imports,
+	// re-exports, and require and dynamic import calls.
+	jsRelativeImport = regexp.MustCompile(`(?m)(?:\bfrom\s*|\brequire\(\s*|\bimport\(\s*|^\s*import\s+)["'](\.{1,2}/[^"']*)["']`)
+	// pyImport matches "import a.b" and "from a.b import c, d".
+	pyImport = regexp.MustCompile(`(?m)^\s*(?:from\s+(\.*[\w.]*)\s+import[ \t]+(\([\w\s,]+|[\w \t,]+)|import\s+([\w.]+))`)
+)
+
+// TestsFor returns the test files under root that go with the given
+// implementation files: those named after one of them by the conventions
+// of its language (walker.go and walker_test.go, Button.tsx and
+// __tests__/Button.test.tsx, Foo.java and src/test/.../FooTest.java), and
+// those that import one of them (Go packages, relative JavaScript and
+// TypeScript imports, and Python modules). Test files are found with the
+// walker's usual ignore rules. Paths are slash-separated and relative to
+// root.
+func TestsFor(root string, paths []string) ([]string, error) {
+	walker, err := NewWalker(root)
+	if err != nil {
+		return nil, err
+	}
+	files, err := walker.Walk()
+	if err != nil {
+		return nil, err
+	}
+	var candidates []File
+	for _, file := range files {
+		if isTest(file.Path, false) {
+			candidates = append(candidates, file)
+		}
+	}
+	return pairTests(paths, candidates, modulePath(root)), nil
+}
+
+// pairTests returns the paths of the candidate test files that go with the
+// implementation files, in the candidates' order. module is the Go module
+// path of the root, if it has one.
+func pairTests(impls []string, candidates []File, module string) []string {
+	var tests []string
+	for _, test := range candidates {
+		testPath := path.Clean(strings.ReplaceAll(test.Path, `\`, "/"))
+		for _, impl := range impls {
+			impl = path.Clean(strings.ReplaceAll(impl, `\`, "/"))
+			if impl != testPath && (namedAfter(testPath, impl) || imports(testPath, test.Content, impl, module)) {
+				tests = append(tests, testPath)
+				break
+			}
+		}
+	}
+	return tests
+}
+
+// namedAfter reports whether test is named as a test of impl: it has the
+// same name once its test marker is removed, the same kind of extension,
+// and lives in the same directory, a test directory within it, or the
+// matching place of a separate test tree.
+func namedAfter(test, impl string) bool {
+	testStem, testExt := splitExt(path.Base(test))
+	implStem, implExt := splitExt(path.Base(impl))
+	if extFamily(testExt) != extFamily(implExt) || trimTestMarker(testStem) != implStem {
+		return false
+	}
+	testDir, implDir := layoutDir(path.Dir(test)), layoutDir(path.Dir(impl))
+	return testDir == implDir || testDir == "" || strings.HasSuffix(implDir, "/"+testDir)
+}
+
+// splitExt splits a file name at the start of its extension.
+func splitExt(name string) (string, string) {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext), ext
+}
+
+// extFamily groups extensions whose files are tested by each other's tests.
+func extFamily(ext string) string {
+	switch ext {
+	case ".ts", ".tsx", ".mts", ".cts", ".js", ".jsx", ".mjs", ".cjs":
+		return ".js"
+	}
+	return ext
+}
+
+// trimTestMarker removes the marker that makes a file name a test's, such
+// as _test, .spec, or test_, from a name without its extension.
+func trimTestMarker(stem string) string {
+	for _, suffix := range []string{"_test", ".test", ".spec", "_spec", "Tests", "Test"} {
+		if s, ok := strings.CutSuffix(stem, suffix); ok && s != "" {
+			return s
+		}
+	}
+	if s, ok := strings.CutPrefix(stem, "test_"); ok {
+		return s
+	}
+	return stem
+}
+
+// layoutDir removes layoutDirs from a directory, leaving the part that
+// mirrors between code and tests.
+func layoutDir(dir string) string {
+	var kept []string
+	for _, part := range strings.Split(dir, "/") {
+		if part != "." && !slices.Contains(layoutDirs, part) {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, "/")
+}
+
+// imports reports whether the test file at test imports impl.
+func imports(test string, content []byte, impl, module string) bool {
+	switch path.Ext(test) {
+	case ".go":
+		if module == "" || path.Ext(impl) != ".go" {
+			return false
+		}
+		pkg := module
+		if dir := path.Dir(impl); dir != "." {
+			pkg += "/" + dir
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), test, content, parser.ImportsOnly)
+		if err != nil {
+			return false
+		}
+		for _, spec := range file.Imports {
+			if p, err := strconv.Unquote(spec.Path.Value); err == nil && p == pkg {
+				return true
+			}
+		}
+	case ".py":
+		if path.Ext(impl) != ".py" {
+			return false
+		}
+		module := strings.TrimSuffix(strings.TrimSuffix(impl, ".py"), "/__init__")
+		for _, m := range pyImport.FindAllStringSubmatch(string(content), -1) {
+			for _, name := range pyModules(path.Dir(test), m) {
+				if name == module || strings.HasSuffix(module, "/"+name) {
+					return true
+				}
+			}
+		}
+	default:
+		if extFamily(path.Ext(test)) != ".js" || extFamily(path.Ext(impl)) != ".js" {
+			return false
+		}
+		stem, _ := splitExt(impl)
+		for _, m := range jsRelativeImport.FindAllStringSubmatch(string(content), -1) {
+			target := path.Join(path.Dir(test), m[1])
+			if targetStem, ext := splitExt(target); extFamily(ext) == ".js" {
+				target = targetStem
+			}
+			if target == stem || target+"/index" == stem {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pyModules returns the slash-separated modules a pyImport match may
+// import: "from a import b, c" may import a, a/b, or a/c. Relative imports
+// are resolved from dir.
+func pyModules(dir string, m []string) []string {
+	if m[3] != "" {
+		return []string{strings.ReplaceAll(m[3], ".", "/")}
+	}
+	from := m[1]
+	base := ""
+	if dots := len(from) - len(strings.TrimLeft(from, ".")); dots > 0 {
+		base = dir
+		for range dots - 1 {
+			base = path.Dir(base)
+		}
+		from = from[dots:]
+	}
+	module := path.Join(base, strings.ReplaceAll(from, ".", "/"))
+	modules := []string{module}
+	for _, name := range strings.Split(strings.TrimPrefix(m[2], "("), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			modules = append(modules, path.Join(module, strings.Fields(name)[0]))
+		}
+	}
+	return modules
+}
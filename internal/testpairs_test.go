@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPairTests(t *testing.T) {
+	candidates := []File{
+		{Path: "internal/walker_test.go", Content: []byte("package internal\n")},
+		{Path: "internal/ignore_test.go", Content: []byte("package internal\n")},
+		{Path: "cmd/walk_test.go", Content: []byte("package main\n\nimport (\n\t\"testing\"\n\n\t\"example.com/app/internal\"\n)\n")},
+		{Path: "web/src/components/__tests__/Button.test.tsx", Content: []byte("it('renders', () => {})\n")},
+		{Path: "web/src/form.spec.ts", Content: []byte("import { Button } from './components/Button'\n")},
+		{Path: "web/src/other.spec.ts", Content: []byte("import { x } from './other'\n")},
+		{Path: "src/test/java/com/app/FooTest.java", Content: []byte("class FooTest {}\n")},
+		{Path: "tests/test_models.py", Content: []byte("import os\n")},
+		{Path: "tests/test_api.py", Content: []byte("from app.db import models, session\n")},
+		{Path: "tests/test_cli.py", Content: []byte("from app import cli\n")},
+		{Path: "tests/test_web.py", Content: []byte("from app import db\nfrom app.web import (\n    views,\n)\n")},
+		{Path: "spec/app/user_spec.rb", Content: []byte("describe User do\nend\n")},
+	}
+
+	tests := []struct {
+		name  string
+		impls []string
+		want  []string
+	}{
+		{"go by name", []string{"internal/walker.go"}, []string{"internal/walker_test.go", "cmd/walk_test.go"}},
+		{"go other package", []string{"cmd/root.go"}, nil},
+		{"js tests dir and import", []string{"web/src/components/Button.tsx"}, []string{"web/src/components/__tests__/Button.test.tsx", "web/src/form.spec.ts"}},
+		{"java test tree", []string{"src/main/java/com/app/Foo.java"}, []string{"src/test/java/com/app/FooTest.java"}},
+		{"java other package", []string{"src/main/java/com/other/Foo.java"}, nil},
+		{"python by name and import", []string{"app/db/models.py"}, []string{"tests/test_models.py", "tests/test_api.py"}},
+		{"python import after another", []string{"app/web/views.py"}, []string{"tests/test_web.py"}},
+		{"ruby spec tree", []string{"lib/app/user.rb"}, []string{"spec/app/user_spec.rb"}},
+		{"several", []string{"internal/ignore.go", `app\cli.py`}, []string{"internal/ignore_test.go", "cmd/walk_test.go", "tests/test_cli.py"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pairTests(tt.impls, candidates, "example.com/app"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pairTests(%q) = %q, want %q", tt.impls, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTestsFor(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"go.mod":          "module example.com/app\n",
+		"a/a.go":          "package a\n",
+		"a/a_test.go":     "package a\n",
+		"a/b_test.go":     "package a\n",
+		"b/b_test.go":     "package b\n\nimport \"example.com/app/a\"\n",
+		".gitignore":      "old/\n",
+		"old/a/a_test.go": "package a\n",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := TestsFor(dir, []string{"a/a.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a/a_test.go", "b/b_test.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("TestsFor() = %q, want %q", got, want)
+	}
+}
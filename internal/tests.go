@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"path"
+	"strings"
+)
+
+// testNames are file name patterns for tests in common languages.
+var testNames = []string{
+	"*_test.go",
+	"*.spec.ts", "*.spec.tsx", "*.spec.js", "*.spec.jsx", "*.spec.mjs", "*.spec.cjs",
+	"*.test.ts", "*.test.tsx", "*.test.js", "*.test.jsx", "*.test.mjs", "*.test.cjs",
+	"test_*.py", "*_test.py", "conftest.py",
+	"*_spec.rb", "*_test.rb",
+	"*Test.java", "*Tests.java", "*Test.kt",
+	"*Tests.cs",
+}
+
+// testDirs are directory names that only hold tests and their fixtures.
+var testDirs = []string{"__tests__", "__snapshots__", "testdata"}
+
+// isTest reports whether a slash-separated relative path is a test file, or
+// a directory that only holds tests.
+func isTest(relPath string, isDir bool) bool {
+	base := path.Base(strings.ReplaceAll(relPath, `\`, "/"))
+	patterns := testNames
+	if isDir {
+		patterns = testDirs
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, base); matched {
+			return true
+		}
+	}
+	return false
+}
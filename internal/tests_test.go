@@ -0,0 +1,32 @@
+package internal
+
+import "testing"
+
+func TestIsTest(t *testing.T) {
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"internal/walker_test.go", false, true},
+		{"internal/walker.go", false, false},
+		{"web/src/app.spec.ts", false, true},
+		{"web/src/app.test.jsx", false, true},
+		{"web/src/app.ts", false, false},
+		{"pkg/test_utils.py", false, true},
+		{"pkg/utils_test.py", false, true},
+		{"pkg/testing.py", false, false},
+		{"src/main/java/FooTest.java", false, true},
+		{"web/src/__tests__", true, true},
+		{"internal/testdata", true, true},
+		{"internal/testdata", false, false},
+		{"latest", true, false},
+		{`pkg\sub\x_test.go`, false, true},
+	}
+
+	for _, tt := range tests {
+		if got := isTest(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("isTest(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package internal
+
+import "time"
+
+// Phase is the time spent in one phase of packing, and how many times it
+// ran.
+type Phase struct {
+	Duration time.Duration
+	Count    int
+}
+
+// WalkTimings records where walks spend their time. Reading includes
+// Binary, the time spent deciding whether what was read is text, and
+// whatever Total leaves after Ignores and Reading went on discovery:
+// listing directories, examining paths, and the walk's other filters.
+type WalkTimings struct {
+	Total   Phase // whole walks
+	Ignores Phase // reading ignore files and matching paths against them
+	Reading Phase // opening and reading files
+	Binary  Phase // checking the files read for binary content
+
+	Paths int   // files and directories visited
+	Bytes int64 // content read
+}
+
+// Discovery returns the time Total spent on neither Ignores nor Reading.
+func (t *WalkTimings) Discovery() time.Duration {
+	return max(t.Total.Duration-t.Ignores.Duration-t.Reading.Duration, 0)
+}
+
+// Add records a run of the phase that began at start.
+func (p *Phase) Add(start time.Time) {
+	p.Duration += time.Since(start)
+	p.Count++
+}
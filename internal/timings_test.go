@@ -0,0 +1,56 @@
+package internal
+
+import "testing"
+
+func TestWalkTimings(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".gitignore": "*.log\n",
+		"a.go":       "package a\n",
+		"b.bin":      "\x00\x01\x02\x03",
+		"debug.log":  "x\n",
+		"sub/c.go":   "package sub\n",
+	})
+
+	walker, err := NewWalker(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timings := &WalkTimings{}
+	walker.Timings = timings
+	files, err := walker.Walk()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The root, sub/, and the five files are visited and matched against
+	// the ignore rules; debug.log is ignored unread, and .gitignore, a.go,
+	// b.bin, and sub/c.go are read
+	if timings.Total.Count != 1 || timings.Paths != 7 {
+		t.Errorf("Total.Count, Paths = %d, %d; want 1, 7", timings.Total.Count, timings.Paths)
+	}
+	if timings.Ignores.Count != 7 {
+		t.Errorf("Ignores.Count = %d, want 7", timings.Ignores.Count)
+	}
+	if timings.Reading.Count != 4 || timings.Binary.Count != 4 {
+		t.Errorf("Reading.Count, Binary.Count = %d, %d; want 4, 4", timings.Reading.Count, timings.Binary.Count)
+	}
+	var size int64
+	for _, file := range files {
+		size += int64(len(file.Content))
+	}
+	if timings.Bytes != size {
+		t.Errorf("Bytes = %d, want %d", timings.Bytes, size)
+	}
+	if timings.Total.Duration <= 0 || timings.Discovery() > timings.Total.Duration {
+		t.Errorf("Total = %s, Discovery() = %s", timings.Total.Duration, timings.Discovery())
+	}
+
+	// Walks add up
+	if _, err := walker.Walk(); err != nil {
+		t.Fatal(err)
+	}
+	if timings.Total.Count != 2 || timings.Paths != 14 {
+		t.Errorf("after two walks, Total.Count, Paths = %d, %d; want 2, 14", timings.Total.Count, timings.Paths)
+	}
+}
@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Characters per token by kind of file, as current BPE tokenizers
+// typically split them. Code splits on its punctuation and identifiers,
+// JSON on its quotes and braces, so both take more tokens than the flat 4
+// of EstimateTokens, while English prose takes fewer.
+const (
+	codeCharsPerToken  = 3.2
+	proseCharsPerToken = 4.3
+	jsonCharsPerToken  = 2.5
+	textCharsPerToken  = 4.0 // headers, separators, and everything else
+)
+
+// proseExtensions are the extensions of files of mostly English prose.
+var proseExtensions = map[string]bool{
+	".md": true, ".markdown": true, ".mdx": true, ".txt": true, ".rst": true,
+	".adoc": true, ".asciidoc": true, ".org": true, ".tex": true,
+}
+
+// jsonExtensions are the extensions of files of JSON and the like.
+var jsonExtensions = map[string]bool{
+	".json": true, ".jsonl": true, ".ndjson": true, ".geojson": true,
+	".ipynb": true, ".har": true, ".jsonc": true, ".json5": true,
+}
+
+// proseNames are files without a prose extension that hold prose.
+var proseNames = map[string]bool{
+	"readme": true, "license": true, "licence": true, "copying": true,
+	"authors": true, "contributors": true, "changelog": true, "notice": true,
+}
+
+// CharsPerToken returns how many characters a token of the file at path
+// covers: 2.5 for JSON, 4.3 for prose such as Markdown and READMEs, and
+// 3.2 for code and anything else.
+func CharsPerToken(path string) float64 {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch {
+	case jsonExtensions[ext]:
+		return jsonCharsPerToken
+	case proseExtensions[ext], ext == "" && proseNames[strings.ToLower(filepath.Base(path))]:
+		return proseCharsPerToken
+	}
+	return codeCharsPerToken
+}
+
+// WeightedTokens estimates the tokens of text, a pack of files, counting
+// each file's content at the ratio of its kind (see CharsPerToken) and the
+// rest of the text, headers and summary included, at 4 characters per
+// token. It is as fast as EstimateTokens but closer to what a tokenizer
+// counts for packs that are mostly code or JSON.
+func WeightedTokens(files []File, text string) int {
+	rest := len(text)
+	var tokens float64
+	for _, file := range files {
+		// Duplicates and collapsed directories don't show their content
+		if file.DuplicateOf != "" || file.Omitted {
+			continue
+		}
+		n := min(len(file.Content), rest)
+		tokens += float64(n) / CharsPerToken(file.Path)
+		rest -= n
+	}
+	return int(tokens + float64(rest)/textCharsPerToken)
+}
+
+// WeightedTokenizer returns a tokenizer for Formatter.Tokenizer that counts
+// the tokens of a pack of files as WeightedTokens does.
+func WeightedTokenizer(files []File) func(text string) int {
+	return func(text string) int {
+		return WeightedTokens(files, text)
+	}
+}
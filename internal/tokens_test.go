@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCharsPerToken(t *testing.T) {
+	tests := []struct {
+		path string
+		want float64
+	}{
+		{"main.go", codeCharsPerToken},
+		{"src/App.tsx", codeCharsPerToken},
+		{"Makefile", codeCharsPerToken},
+		{"docs/guide.md", proseCharsPerToken},
+		{"README", proseCharsPerToken},
+		{"NOTES.TXT", proseCharsPerToken},
+		{"package.json", jsonCharsPerToken},
+		{"data/events.jsonl", jsonCharsPerToken},
+	}
+
+	for _, tt := range tests {
+		if got := CharsPerToken(tt.path); got != tt.want {
+			t.Errorf("CharsPerToken(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestWeightedTokens(t *testing.T) {
+	code := strings.Repeat("x", 320)
+	prose := strings.Repeat("y", 430)
+	files := []File{
+		{Path: "main.go", Content: []byte(code)},
+		{Path: "README.md", Content: []byte(prose)},
+		{Path: "copy.go", Content: []byte(code), DuplicateOf: "main.go"},
+	}
+	text := "File: main.go\n" + code + "\nFile: README.md\n" + prose + "\nFile: copy.go (duplicate of main.go)\n"
+	framing := len(text) - len(code) - len(prose)
+
+	// 100 tokens of code, 100 of prose, and the headers at 4 characters
+	want := 200 + framing/4
+	if got := WeightedTokens(files, text); got != want {
+		t.Errorf("WeightedTokens() = %d, want %d", got, want)
+	}
+
+	// Content missing from the text, as when it's cut short, isn't counted
+	if got := WeightedTokens(files, code[:64]); got != 20 {
+		t.Errorf("WeightedTokens() of a truncated pack = %d, want 20", got)
+	}
+}
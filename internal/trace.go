@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// Python tracebacks: File "/app/service.py", line 12, in handler
+	pythonFrame = regexp.MustCompile(`File "([^"]+)", line \d+`)
+	// Everything else: path/to/file.ext:LINE (Go panics, pytest, JS, compilers)
+	pathWithLine = regexp.MustCompile(`((?:[A-Za-z]:)?[\w./\\-]*[\w-]+\.\w+):\d+`)
+)
+
+// TraceFiles extracts the source files mentioned in a stack trace or log
+// and resolves them to paths relative to root. Paths from other machines
+// (e.g. /home/ci/build/internal/walker.go) are matched by their longest
+// suffix that exists under root. Files outside root are ignored. The result
+// is ordered by how often each file appears, most frequent first.
+func TraceFiles(r io.Reader, root string) ([]string, error) {
+	counts := make(map[string]int)
+	var order []string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		var refs []string
+		for _, m := range pythonFrame.FindAllStringSubmatch(line, -1) {
+			refs = append(refs, m[1])
+		}
+		if len(refs) == 0 {
+			for _, m := range pathWithLine.FindAllStringSubmatch(line, -1) {
+				refs = append(refs, m[1])
+			}
+		}
+
+		for _, ref := range refs {
+			path, ok := resolveTracePath(ref, root)
+			if !ok {
+				continue
+			}
+			if counts[path] == 0 {
+				order = append(order, path)
+			}
+			counts[path]++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return counts[order[i]] > counts[order[j]]
+	})
+	return order, nil
+}
+
+// resolveTracePath maps a path from a trace to an existing file under root.
+func resolveTracePath(ref, root string) (string, bool) {
+	ref = filepath.Clean(filepath.FromSlash(strings.ReplaceAll(ref, `\`, "/")))
+
+	if !filepath.IsAbs(ref) && isWithin(filepath.Join(root, ref), root) && isFile(filepath.Join(root, ref)) {
+		return ref, true
+	}
+	if filepath.IsAbs(ref) && isWithin(ref, root) && isFile(ref) {
+		rel, err := filepath.Rel(root, ref)
+		return rel, err == nil
+	}
+
+	// Try progressively shorter suffixes; a bare file name only counts if
+	// that's all the trace gave us
+	segments := strings.Split(filepath.ToSlash(ref), "/")
+	minSegments := 2
+	if len(segments) == 1 {
+		minSegments = 1
+	}
+	for i := 1; len(segments)-i >= minSegments; i++ {
+		rel := filepath.Join(segments[i:]...)
+		if isWithin(filepath.Join(root, rel), root) && isFile(filepath.Join(root, rel)) {
+			return rel, true
+		}
+	}
+	return "", false
+}
+
+// isFile reports whether path exists and is a regular file.
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
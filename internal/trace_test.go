@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestResolveTracePath(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "repo")
+	writeFiles(t, root, "main.go", "internal/walker.go", "internal/sub/b.go", "other/b.go")
+	writeFiles(t, dir, "outside/secret.go")
+
+	tests := []struct {
+		ref    string
+		want   string
+		wantOK bool
+	}{
+		{"main.go", "main.go", true},
+		{"internal/walker.go", "internal/walker.go", true},
+		{"./internal/walker.go", "internal/walker.go", true},
+		{`internal\walker.go`, "internal/walker.go", true},
+		{"/home/ci/build/internal/sub/b.go", "internal/sub/b.go", true},
+		{filepath.ToSlash(filepath.Join(root, "internal", "walker.go")), "internal/walker.go", true},
+		{"../outside/secret.go", "", false},
+		{filepath.ToSlash(filepath.Join(dir, "outside", "secret.go")), "", false},
+		{"internal/../../outside/secret.go", "", false},
+		{"/somewhere/else/b.go", "", false}, // a bare name needs at least two segments to match
+		{"missing.go", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, ok := resolveTracePath(tt.ref, root)
+			if ok != tt.wantOK || filepath.ToSlash(got) != tt.want {
+				t.Errorf("resolveTracePath(%q) = %q, %v; want %q, %v", tt.ref, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestTraceFiles(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, "app/service.py", "app/db.py", "internal/walker.go", "src/index.ts")
+
+	tests := []struct {
+		name  string
+		trace string
+		want  []string
+	}{
+		{
+			name: "python traceback",
+			trace: `Traceback (most recent call last):
+  File "/srv/app/service.py", line 12, in handler
+  File "/srv/app/db.py", line 40, in query
+  File "/usr/lib/python3.12/socket.py", line 1, in connect
+`,
+			want: []string{"app/service.py", "app/db.py"},
+		},
+		{
+			name: "go panic",
+			trace: `panic: runtime error
+goroutine 1 [running]:
+gopack/internal.(*Walker).Walk(...)
+	/home/ci/build/internal/walker.go:212 +0x1c
+`,
+			want: []string{"internal/walker.go"},
+		},
+		{
+			name: "ordered by frequency",
+			trace: `src/index.ts:3:1 error
+internal/walker.go:10
+src/index.ts:9:2 error
+`,
+			want: []string{"src/index.ts", "internal/walker.go"},
+		},
+		{
+			name:  "files outside root are ignored",
+			trace: "error at ../outside/secret.go:3\n",
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TraceFiles(strings.NewReader(tt.trace), root)
+			if err != nil {
+				t.Fatalf("TraceFiles() error = %v", err)
+			}
+			for i := range got {
+				got[i] = filepath.ToSlash(got[i])
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("TraceFiles() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultCollapseLines is the import block length above which the
+// collapse-imports transform applies when no length is given.
+const DefaultCollapseLines = 10
+
+// Transforms lists the names of the per-file transforms a pipeline may use.
+// collapse-imports takes an optional length, as in "collapse-imports=5".
+var Transforms = []string{"strip-comments", "collapse-imports", "minify"}
+
+// transformStep is one parsed step of a pipeline.
+type transformStep struct {
+	name string
+	arg  int
+}
+
+// ApplyTransforms passes each file through the transform pipeline for its
+// type. pipelines maps file types to transform names, where a type is a
+// file name ("Makefile"), an extension ("json" or ".json"), a language
+// ("go", "python"), or "*" for every other file; the most specific match
+// wins and an empty pipeline leaves the file alone. Files without a match
+// use fallback. It returns the updated files and how many files each
+// transform changed.
+func ApplyTransforms(files []File, pipelines map[string][]string, fallback []string) ([]File, map[string]int, error) {
+	parsed := make(map[string][]transformStep, len(pipelines))
+	for key, names := range pipelines {
+		steps, err := parsePipeline(names)
+		if err != nil {
+			return nil, nil, fmt.Errorf("transforms for %q: %w", key, err)
+		}
+		parsed[strings.TrimPrefix(strings.ToLower(key), ".")] = steps
+	}
+	defaults, err := parsePipeline(fallback)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := make([]File, len(files))
+	changed := make(map[string]int)
+	for i, file := range files {
+		result[i] = file
+		steps, ok := pipelineFor(parsed, file)
+		if !ok {
+			steps = defaults
+		}
+		for _, step := range steps {
+			content, ok := applyTransform(step, file.Path, result[i].Content)
+			if ok {
+				result[i].Content = content
+				changed[step.name]++
+			}
+		}
+	}
+	return result, changed, nil
+}
+
+// parsePipeline parses and checks a list of transform names.
+func parsePipeline(names []string) ([]transformStep, error) {
+	var steps []transformStep
+	for _, name := range names {
+		name, value, hasValue := strings.Cut(strings.TrimSpace(name), "=")
+		if !slices.Contains(Transforms, name) {
+			return nil, fmt.Errorf("unknown transform %q (expected one of: %s)", name, strings.Join(Transforms, ", "))
+		}
+		step := transformStep{name: name}
+		if name == "collapse-imports" {
+			step.arg = DefaultCollapseLines
+		}
+		if hasValue {
+			n, err := strconv.Atoi(value)
+			if name != "collapse-imports" || err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid transform %q", name+"="+value)
+			}
+			step.arg = n
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// pipelineFor finds the pipeline for a file: by file name, then extension,
+// then language, then "*".
+func pipelineFor(pipelines map[string][]transformStep, file File) ([]transformStep, bool) {
+	if len(pipelines) == 0 {
+		return nil, false
+	}
+	base := strings.ToLower(path.Base(strings.ReplaceAll(file.Path, `\`, "/")))
+	language := DetectLanguage(file.Path, file.Content)
+	for _, key := range []string{base, strings.TrimPrefix(path.Ext(base), "."), language.Fence, strings.ToLower(language.Name), "*"} {
+		if steps, ok := pipelines[key]; ok && key != "" {
+			return steps, true
+		}
+	}
+	return nil, false
+}
+
+// applyTransform runs one step on a file, reporting whether it changed.
+func applyTransform(step transformStep, name string, content []byte) ([]byte, bool) {
+	var result []byte
+	switch step.name {
+	case "strip-comments":
+		result = stripComments(name, content)
+	case "collapse-imports":
+		var n int
+		if result, n = collapseImports(name, content, step.arg); n == 0 {
+			return content, false
+		}
+	case "minify":
+		result = minify(name, content)
+	}
+	return result, !bytes.Equal(result, content)
+}
+
+// lineComments maps code fence tags (see Language) to their line comment
+// marker. The languages using "//" also have /* */ block comments.
+var lineComments = map[string]string{
+	"go": "//", "c": "//", "cpp": "//", "csharp": "//", "java": "//",
+	"kotlin": "//", "scala": "//", "groovy": "//", "swift": "//", "rust": "//",
+	"javascript": "//", "typescript": "//", "jsx": "//", "tsx": "//",
+	"php": "//", "dart": "//", "zig": "//", "protobuf": "//", "css": "//", "scss": "//",
+	"python": "#", "ruby": "#", "bash": "#", "fish": "#", "powershell": "#",
+	"perl": "#", "r": "#", "elixir": "#", "yaml": "#", "toml": "#", "hcl": "#",
+	"makefile": "#", "dockerfile": "#", "cmake": "#", "graphql": "#",
+	"sql": "--", "lua": "--", "haskell": "--",
+}
+
+// stripComments removes the lines of a file that hold only a comment,
+// keeping "#!" lines and directives such as "//go:build". Comments after
+// code on the same line are left alone, since telling them apart from
+// strings needs a parser for each language.
+func stripComments(name string, content []byte) []byte {
+	language := DetectLanguage(name, content)
+	marker, ok := lineComments[language.Fence]
+	if !ok {
+		return content
+	}
+	if language.Fence == "css" {
+		marker = "/*" // CSS has only block comments
+	}
+
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	var out bytes.Buffer
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := bytes.TrimSpace(line)
+		switch {
+		case i == 0 && bytes.HasPrefix(line, []byte("#!")), isDirective(string(line)):
+		case marker == "//" || marker == "/*":
+			if bytes.HasPrefix(trimmed, []byte("//")) && marker == "//" {
+				continue
+			}
+			if !bytes.HasPrefix(trimmed, []byte("/*")) {
+				break
+			}
+			// A block comment, dropped if nothing follows it on its last line
+			end := i
+			for end < len(lines)-1 && !bytes.Contains(lines[end], []byte("*/")) {
+				end++
+			}
+			if bytes.HasSuffix(bytes.TrimSpace(lines[end]), []byte("*/")) {
+				i = end
+				continue
+			}
+		case bytes.HasPrefix(trimmed, []byte(marker)):
+			continue
+		}
+		out.Write(line)
+	}
+	return out.Bytes()
+}
+
+// minify compacts JSON files and removes trailing whitespace and blank
+// lines from other files.
+func minify(name string, content []byte) []byte {
+	if DetectLanguage(name, content).Fence == "json" {
+		var out bytes.Buffer
+		if err := json.Compact(&out, content); err == nil {
+			return append(out.Bytes(), '\n')
+		}
+	}
+
+	var out bytes.Buffer
+	for _, line := range bytes.SplitAfter(content, []byte("\n")) {
+		if line = bytes.TrimRight(line, " \t\r\n"); len(line) > 0 {
+			out.Write(line)
+			out.WriteByte('\n')
+		}
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,60 @@
+package internal
+
+import "testing"
+
+func TestApplyTransforms(t *testing.T) {
+	goSource := "//go:build linux\n\n// Package a does things.\npackage a\n\n/*\nblock\n*/\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nvar x = 1 // trailing\n"
+	files := []File{
+		{Path: "a.go", Content: []byte(goSource)},
+		{Path: "data.json", Content: []byte("{\n  \"a\": [1, 2]\n}\n")},
+		{Path: "README.md", Content: []byte("# Title\n\n\nText   \n")},
+		{Path: "run.py", Content: []byte("#!/usr/bin/env python\n# comment\nimport os\nimport sys\n")},
+	}
+	pipelines := map[string][]string{
+		"go":    {"strip-comments", "collapse-imports=1"},
+		".JSON": {"minify"},
+		"md":    {},
+	}
+
+	got, changed, err := ApplyTransforms(files, pipelines, []string{"strip-comments", "collapse-imports=1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"//go:build linux\n\npackage a\n\nimport ( ...2 imports... )\n\nvar x = 1 // trailing\n",
+		"{\"a\":[1,2]}\n",
+		"# Title\n\n\nText   \n",
+		"#!/usr/bin/env python\n# ...2 imports...\n",
+	}
+	for i, file := range got {
+		if string(file.Content) != want[i] {
+			t.Errorf("ApplyTransforms(%s) = %q, want %q", file.Path, file.Content, want[i])
+		}
+	}
+	if changed["strip-comments"] != 2 || changed["collapse-imports"] != 2 || changed["minify"] != 1 {
+		t.Errorf("ApplyTransforms() changed = %v", changed)
+	}
+
+	for _, pipeline := range [][]string{{"uglify"}, {"minify=2"}, {"collapse-imports=x"}} {
+		if _, _, err := ApplyTransforms(files, map[string][]string{"go": pipeline}, nil); err == nil {
+			t.Errorf("ApplyTransforms(%q) succeeded, want error", pipeline)
+		}
+	}
+}
+
+func TestMinify(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"a.json", "{\n  \"a\": 1\n}\n", "{\"a\":1}\n"},
+		{"bad.json", "{\n  \"a\": \n", "{\n  \"a\":\n"},
+		{"a.txt", "one  \n\n\ntwo\r\n", "one\ntwo\n"},
+	}
+	for _, tt := range tests {
+		if got := string(minify(tt.name, []byte(tt.content))); got != tt.want {
+			t.Errorf("minify(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// Headers added by --chunk-tokens; pieces of a split file carry their
+	// line range
+	textPartHeader = regexp.MustCompile(`(?m)^=== Part \d+/\d+ ===\n\n`)
+	pieceName      = regexp.MustCompile(`^(.*) \(lines (\d+)-(\d+)\)$`)
+	// Dedupe references
+	duplicateRef = regexp.MustCompile(`^\[identical to (.+)\]\n?$`)
+	// The heading of a file in collapsible Markdown
+	summaryLine = regexp.MustCompile(`^<summary>(.*)</summary>$`)
+	// File names linked to their source by --permalinks
+	textLink     = regexp.MustCompile(`^(.+) \((https?://\S+)\)$`)
+	markdownLink = regexp.MustCompile(`^\[(.+)\]\((https?://\S+)\)$`)
+	htmlLink     = regexp.MustCompile(`^<a href="[^"]*">(.*)</a>$`)
+	// Permission bits added by --file-modes
+	modeSuffixRe = regexp.MustCompile(`^(.*) \(mode ([0-7]{4})\)$`)
+	// A file's section in HTML output, and the highlighting within it
+	htmlSection = regexp.MustCompile(`(?s)<section id="f\d+"><details open><summary>(.*?)</summary>\n(?:<pre><code class="[^"]*">(.*?)</code></pre>|<p>(.*?)</p>)\n</details></section>`)
+	htmlSpan    = regexp.MustCompile(`<span class="[a-z]+">|</span>`)
+)
+
+// ParsePack parses output produced by Formatter (text, Markdown, HTML, or JSON Lines) back into
+// files. Part headers, summaries, symbol indexes, and pieces of split files are handled,
+// dedupe references are replaced with the content they refer to, and collapsed directories
+// are left out.
+func ParsePack(data []byte) ([]File, error) {
+	files, err := parseSections(data)
+	if err != nil {
+		return nil, err
+	}
+	return joinPieces(files)
+}
+
+// parseSections parses a pack into its file sections as written, with
+// pieces of split files still separate and dedupe references unresolved.
+func parseSections(data []byte) ([]File, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+	switch PackFormat(data) {
+	case FormatHTML:
+		return parseHTMLPack(text)
+	case FormatMarkdown:
+		return parseMarkdownPack(text)
+	case FormatJSONL:
+		return parseJSONLPack(text)
+	}
+	return parseTextPack(text)
+}
+
+// PackFormat returns the format (one of Formats) a pack was written in,
+// going by its content.
+func PackFormat(data []byte) string {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	switch {
+	case strings.HasPrefix(text, "<!DOCTYPE html>"):
+		return FormatHTML
+	case strings.HasPrefix(text, `{"path":`):
+		return FormatJSONL
+	case strings.HasPrefix(text, "## File: ") || strings.Contains(text, "\n## File: ") || strings.Contains(text, "\n<summary>"):
+		return FormatMarkdown
+	}
+	return FormatText
+}
+
+// parseTextPack parses the text format: "File: path" headers followed by
+// content, with a blank line between files. The format is ambiguous when a
+// file contains a blank line followed by "File: ", which is read as the
+// start of another file; Markdown packs don't have this problem.
+func parseTextPack(text string) ([]File, error) {
+	// Parts pasted one after another, each possibly with a summary
+	var parts []string
+	chunks := textPartHeader.Split(text, -1)
+	for i, part := range chunks {
+		if i < len(chunks)-1 {
+			// Blank line separating pasted parts
+			part = strings.TrimSuffix(part, "\n\n")
+		}
+		if j := strings.LastIndex(part, "\n\n=== Pack Summary ===\n"); j >= 0 {
+			part = part[:j]
+		} else if strings.HasPrefix(part, "=== Pack Summary ===\n") {
+			part = ""
+		}
+		if j := strings.LastIndex(part, "\n\n"+symbolSection); j >= 0 {
+			part = part[:j]
+		}
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	text = strings.Join(parts, "\n\n")
+
+	// The instructions, threads, and license headers stripped from the
+	// files come first, each under a "=== Heading ===" line
+	if strings.HasPrefix(text, "=== ") {
+		if i := strings.Index(text, "\n\nFile: "); i >= 0 {
+			text = text[i+2:]
+		}
+	}
+
+	if !strings.HasPrefix(text, "File: ") {
+		return nil, fmt.Errorf("not a gopack pack: expected a \"File: \" header")
+	}
+
+	var files []File
+	for _, section := range strings.Split(text[len("File: "):], "\n\nFile: ") {
+		name, content, _ := strings.Cut(section, "\n")
+		name, mode := cutFileMode(name)
+		if m := textLink.FindStringSubmatch(name); m != nil {
+			name = m[1]
+		}
+		files = append(files, File{Path: name, Content: []byte(content), Mode: mode})
+	}
+	return files, nil
+}
+
+// parseMarkdownPack parses the Markdown format: "## File: path" headings,
+// or "<summary>path</summary>" lines in collapsible packs, followed by a
+// fenced code block (or a bare dedupe reference or placeholder).
+func parseMarkdownPack(text string) ([]File, error) {
+	lines := strings.SplitAfter(text, "\n")
+
+	var files []File
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSuffix(lines[i], "\n")
+		name, mode, ok := markdownHeading(line)
+		if !ok {
+			continue
+		}
+
+		// Skip to the opening fence, a dedupe reference, or a placeholder
+		i++
+		for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+			i++
+		}
+		if i == len(lines) {
+			return nil, fmt.Errorf("%s: missing content", name)
+		}
+		if duplicateRef.MatchString(lines[i]) || omittedRef.MatchString(lines[i]) {
+			files = append(files, File{Path: name, Content: []byte(lines[i]), Mode: mode})
+			continue
+		}
+
+		opening := strings.TrimSuffix(lines[i], "\n")
+		fence := opening[:len(opening)-len(strings.TrimLeft(opening, "`"))]
+		if len(fence) < 3 {
+			return nil, fmt.Errorf("%s: expected a code fence", name)
+		}
+
+		var content strings.Builder
+		closed := false
+		for i++; i < len(lines); i++ {
+			if strings.TrimSuffix(lines[i], "\n") == fence {
+				closed = true
+				break
+			}
+			content.WriteString(lines[i])
+		}
+		if !closed {
+			return nil, fmt.Errorf("%s: unterminated code fence", name)
+		}
+		files = append(files, File{Path: name, Content: []byte(content.String()), Mode: mode})
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("not a gopack pack: no \"## File: \" headings")
+	}
+	return files, nil
+}
+
+// parseHTMLPack parses the HTML format: a section per file holding its
+// highlighted content (or a dedupe reference), in one or more documents.
+func parseHTMLPack(text string) ([]File, error) {
+	var files []File
+	for _, m := range htmlSection.FindAllStringSubmatch(text, -1) {
+		content := m[3]
+		if m[2] != "" || m[3] == "" {
+			content = htmlSpan.ReplaceAllString(m[2], "")
+		}
+		name, mode := cutFileMode(m[1])
+		name = htmlLink.ReplaceAllString(name, "$1")
+		files = append(files, File{Path: html.UnescapeString(name), Content: []byte(html.UnescapeString(content)), Mode: mode})
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("not a gopack pack: no file sections in the HTML")
+	}
+	return files, nil
+}
+
+// markdownHeading returns the name of the file a line of a Markdown pack
+// heads, a "## File: " heading or a "<summary>" line, and its mode, if the
+// heading has one.
+func markdownHeading(line string) (string, os.FileMode, bool) {
+	if name, ok := strings.CutPrefix(line, "## File: "); ok {
+		name, mode := cutFileMode(name)
+		if m := markdownLink.FindStringSubmatch(name); m != nil {
+			name = m[1]
+		}
+		return name, mode, true
+	}
+	if m := summaryLine.FindStringSubmatch(line); m != nil {
+		name, mode := cutFileMode(m[1])
+		return html.UnescapeString(htmlLink.ReplaceAllString(name, "$1")), mode, true
+	}
+	return "", 0, false
+}
+
+// cutFileMode splits the mode --file-modes adds to a file's header off its
+// name.
+func cutFileMode(name string) (string, os.FileMode) {
+	m := modeSuffixRe.FindStringSubmatch(name)
+	if m == nil {
+		return name, 0
+	}
+	mode, _ := parseFileMode(m[2])
+	return m[1], mode
+}
+
+// parseFileMode parses permission bits written by FileModeString, or ""
+// for none.
+func parseFileMode(s string) (os.FileMode, error) {
+	if s == "" {
+		return 0, nil
+	}
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("invalid file mode %q", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// joinPieces reassembles files split across parts, resolves dedupe
+// references, and drops the placeholders of collapsed directories.
+func joinPieces(files []File) ([]File, error) {
+	var joined []File
+	index := make(map[string]int)
+	lastLine := make(map[string]int) // last line of each file joined so far
+	for _, file := range files {
+		if omittedRef.Match(file.Content) {
+			continue
+		}
+		name, start, end := pieceRange(file)
+		if i, ok := index[name]; ok && file.Path != name {
+			// Drop lines repeated from the previous part (--chunk-overlap)
+			content := file.Content
+			if repeated := lastLine[name] - start + 1; repeated > 0 {
+				content = dropLines(content, repeated)
+			}
+			joined[i].Content = append(joined[i].Content, content...)
+			lastLine[name] = max(lastLine[name], end)
+			continue
+		}
+		if err := checkPackPath(name); err != nil {
+			return nil, err
+		}
+		file.Path = name
+		index[name] = len(joined)
+		lastLine[name] = end
+		joined = append(joined, file)
+	}
+
+	for i, file := range joined {
+		m := duplicateRef.FindSubmatch(file.Content)
+		if m == nil {
+			continue
+		}
+		if original, ok := index[string(m[1])]; ok {
+			joined[i].Content = bytes.Clone(joined[original].Content)
+		}
+	}
+	return joined, nil
+}
+
+// checkPackPath rejects paths that would escape the directory a pack is
+// unpacked into.
+func checkPackPath(name string) error {
+	clean := path.Clean(strings.ReplaceAll(name, `\`, "/"))
+	if name == "" || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(clean, ":") {
+		return fmt.Errorf("unsafe path in pack: %q", name)
+	}
+	return nil
+}
+
+// dropLines returns content without its first n lines.
+func dropLines(content []byte, n int) []byte {
+	for ; n > 0 && len(content) > 0; n-- {
+		i := bytes.IndexByte(content, '\n')
+		if i < 0 {
+			return nil
+		}
+		content = content[i+1:]
+	}
+	return content
+}
@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParsePackRoundTrip(t *testing.T) {
+	files := []File{
+		{Path: "main.go", Content: []byte("package main\n\nfunc main() {}\n")},
+		{Path: "docs/README.md", Content: []byte("# Title\n\n```go\nx := 1\n```\nFile: not a header\n")},
+		{Path: "empty.txt", Content: []byte("")},
+		{Path: "no-newline.txt", Content: []byte("last line")},
+		{Path: "copy/main.go", Content: []byte("package main\n\nfunc main() {}\n")},
+	}
+
+	for _, format := range Formats {
+		for _, summary := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/summary=%v", format, summary), func(t *testing.T) {
+				deduped, _ := Dedupe(files)
+				formatter := NewFormatter(deduped)
+				formatter.OutputFormat = format
+				formatter.Summary = summary
+
+				got, err := ParsePack([]byte(formatter.Format()))
+				if err != nil {
+					t.Fatalf("ParsePack() error = %v", err)
+				}
+				assertFiles(t, got, files, format)
+			})
+		}
+	}
+}
+
+func TestParsePackCollapsible(t *testing.T) {
+	files := []File{
+		{Path: "main.go", Content: []byte("package main\n")},
+		{Path: "a&b <c>.md", Content: []byte("<summary>not a file</summary>\n")},
+		{Path: "copy/main.go", Content: []byte("package main\n")},
+	}
+	deduped, _ := Dedupe(files)
+	formatter := NewFormatter(deduped)
+	formatter.OutputFormat = FormatMarkdown
+	formatter.Collapsible = true
+	output := formatter.Format()
+
+	want := "<details>\n<summary>main.go</summary>\n\n```go\npackage main\n```\n\n</details>\n\n<details>\n<summary>a&amp;b &lt;c&gt;.md</summary>\n"
+	if !strings.HasPrefix(output, want) {
+		t.Errorf("Format() = %q, want prefix %q", output, want)
+	}
+	got, err := ParsePack([]byte(output))
+	if err != nil {
+		t.Fatalf("ParsePack() error = %v", err)
+	}
+	assertFiles(t, got, files, "collapsible")
+}
+
+func TestParsePackFileModes(t *testing.T) {
+	files := []File{
+		{Path: "run.sh", Content: []byte("#!/bin/sh\necho hi\n"), Mode: 0755},
+		{Path: "main.go", Content: []byte("package main\n"), Mode: 0644, URL: "https://example.com/main.go"},
+		{Path: "plain.txt", Content: []byte("no mode\n")},
+	}
+
+	for _, format := range append(slices.Clone(Formats), "collapsible") {
+		t.Run(format, func(t *testing.T) {
+			formatter := NewFormatter(files)
+			formatter.OutputFormat = format
+			if format == "collapsible" {
+				formatter.OutputFormat, formatter.Collapsible = FormatMarkdown, true
+			}
+			output := formatter.Format()
+			got, err := ParsePack([]byte(output))
+			if err != nil {
+				t.Fatalf("ParsePack() error = %v", err)
+			}
+			assertFiles(t, got, files, formatter.OutputFormat)
+			for i, file := range got {
+				if file.Mode != files[i].Mode {
+					t.Errorf("%s: mode = %v, want %v", file.Path, file.Mode, files[i].Mode)
+				}
+			}
+			if format == FormatText && !strings.HasPrefix(output, "File: run.sh (mode 0755)\n") {
+				t.Errorf("Format() = %q, want the mode in the header", output)
+			}
+		})
+	}
+}
+
+func TestParsePackInstructions(t *testing.T) {
+	files := []File{{Path: "main.go", Content: []byte("package main\n")}}
+	for _, format := range Formats {
+		formatter := NewFormatter(files)
+		formatter.OutputFormat = format
+		formatter.Instructions = "Review this.\n\nList the bugs first.\n"
+		formatter.Threads = []Thread{{Title: "Issue #1: Crash", URL: "https://github.com/org/repo/issues/1", Content: "It crashes.\n\n### Conversation\n\n**@bob:**\n\nSame here.\n"}}
+		formatter.LicenseHeaders = []LicenseHeader{{Text: "// Copyright", Files: 2}}
+
+		got, err := ParsePack([]byte(formatter.Format()))
+		if err != nil {
+			t.Fatalf("ParsePack(%s) error = %v", format, err)
+		}
+		assertFiles(t, got, files, format)
+	}
+}
+
+func TestParsePackParts(t *testing.T) {
+	var content strings.Builder
+	for i := 1; i <= 20; i++ {
+		fmt.Fprintf(&content, "line %02d %s\n", i, strings.Repeat("x", 30))
+	}
+	files := []File{
+		{Path: "a.go", Content: []byte("package a\n")},
+		{Path: "big.go", Content: []byte(content.String())},
+		{Path: "z.go", Content: []byte("package z\n")},
+	}
+
+	for _, format := range Formats {
+		for _, overlap := range []int{0, 2} {
+			t.Run(fmt.Sprintf("%s/overlap=%d", format, overlap), func(t *testing.T) {
+				parts := AddOverlap(SplitFiles(files, 60), overlap)
+				var pack strings.Builder
+				for i, part := range parts {
+					formatter := NewFormatter(part)
+					formatter.OutputFormat = format
+					formatter.Part, formatter.Parts = i+1, len(parts)
+					if format == FormatText && i > 0 {
+						pack.WriteString("\n\n")
+					}
+					pack.WriteString(formatter.Format())
+				}
+
+				got, err := ParsePack([]byte(pack.String()))
+				if err != nil {
+					t.Fatalf("ParsePack() error = %v", err)
+				}
+				assertFiles(t, got, files, format)
+			})
+		}
+	}
+}
+
+func TestParsePackErrors(t *testing.T) {
+	tests := map[string]string{
+		"not a pack":       "hello world\n",
+		"escaping path":    "File: ../etc/passwd\nroot\n",
+		"absolute path":    "File: /etc/passwd\nroot\n",
+		"unclosed fence":   "## File: a.go\n\n```go\npackage a\n",
+		"windows absolute": "File: C:\\Windows\\x.txt\nx\n",
+	}
+
+	for name, pack := range tests {
+		if _, err := ParsePack([]byte(pack)); err == nil {
+			t.Errorf("%s: ParsePack() succeeded, want error", name)
+		}
+	}
+}
+
+// assertFiles compares parsed files with the originals.
+func assertFiles(t *testing.T, got, want []File, format string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d files, want %d: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		wantContent := string(want[i].Content)
+		// Markdown fences always end content with a newline; Formatter lays
+		// out obsidian like Markdown
+		if (format == FormatMarkdown || format == FormatObsidian) && wantContent != "" && !strings.HasSuffix(wantContent, "\n") {
+			wantContent += "\n"
+		}
+		if got[i].Path != want[i].Path || string(got[i].Content) != wantContent {
+			t.Errorf("file %d = {%q, %q}, want {%q, %q}", i, got[i].Path, got[i].Content, want[i].Path, wantContent)
+		}
+	}
+}
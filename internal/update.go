@@ -0,0 +1,213 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// ReleasesURL is the GitHub API endpoint for gopack's latest release.
+var ReleasesURL = "https://api.github.com/repos/jameswgrant/gopack/releases/latest"
+
+// checksumsAsset is the release asset listing the SHA-256 of every binary,
+// one "<hex>  <name>" line each (the sha256sum format).
+const checksumsAsset = "checksums.txt"
+
+// Release is a published gopack release.
+type Release struct {
+	Tag    string         `json:"tag_name"`
+	Assets []ReleaseAsset `json:"assets"`
+}
+
+// ReleaseAsset is a file attached to a release.
+type ReleaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// LatestRelease fetches the latest release from ReleasesURL.
+func LatestRelease(ctx context.Context) (Release, error) {
+	var release Release
+	data, err := download(ctx, ReleasesURL)
+	if err != nil {
+		return release, err
+	}
+	if err := json.Unmarshal(data, &release); err != nil {
+		return release, fmt.Errorf("invalid release metadata: %w", err)
+	}
+	return release, nil
+}
+
+// AssetName returns the name of the release binary for a platform, e.g.
+// "gopack_linux_amd64" or "gopack_windows_arm64.exe".
+func AssetName(goos, goarch string) string {
+	name := "gopack_" + goos + "_" + goarch
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// DownloadBinary downloads the release binary for the running platform and
+// verifies it against the release's checksums.
+func (r Release) DownloadBinary(ctx context.Context) ([]byte, error) {
+	name := AssetName(runtime.GOOS, runtime.GOARCH)
+	binary, ok := r.asset(name)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no binary for %s/%s", r.Tag, runtime.GOOS, runtime.GOARCH)
+	}
+	checksums, ok := r.asset(checksumsAsset)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no %s to verify the download against", r.Tag, checksumsAsset)
+	}
+
+	sums, err := download(ctx, checksums.URL)
+	if err != nil {
+		return nil, err
+	}
+	want, ok := ParseChecksums(sums)[name]
+	if !ok {
+		return nil, fmt.Errorf("%s doesn't list %s", checksumsAsset, name)
+	}
+
+	data, err := download(ctx, binary.URL)
+	if err != nil {
+		return nil, err
+	}
+	if got := hashContent(data); got != want {
+		return nil, fmt.Errorf("checksum mismatch for %s: got %s, want %s", name, got, want)
+	}
+	return data, nil
+}
+
+// asset returns the release asset with the given name.
+func (r Release) asset(name string) (ReleaseAsset, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return ReleaseAsset{}, false
+}
+
+// ParseChecksums parses sha256sum output into a map from file name to
+// lowercase hex digest.
+func ParseChecksums(data []byte) map[string]string {
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		// A leading "*" marks binary mode
+		sums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+	return sums
+}
+
+// ReplaceExecutable atomically replaces the file at path with data, keeping
+// its permissions, or making it executable if it doesn't exist.
+func ReplaceExecutable(path string, data []byte) error {
+	mode := fs.FileMode(0755)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".gopack-update-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+
+	// Windows can't replace a running executable, but it can rename it; if
+	// the new one can't take its place, the old one is put back
+	if runtime.GOOS == "windows" {
+		old := path + ".old"
+		os.Remove(old)
+		if err := os.Rename(path, old); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp.Name(), path); err != nil {
+			os.Rename(old, path)
+			return err
+		}
+		return nil
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// download fetches url and returns the response body.
+func download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// IsNewer reports whether release version latest (e.g. "v1.4.0") is newer
+// than current. Versions that aren't "vX.Y.Z", such as development builds,
+// are older than any release.
+func IsNewer(latest, current string) bool {
+	l, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	c, ok := parseVersion(current)
+	if !ok {
+		return true
+	}
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return false
+}
+
+// parseVersion parses "vX.Y.Z" (the "v" is optional).
+func parseVersion(version string) ([3]int, bool) {
+	var parts [3]int
+	fields := strings.Split(strings.TrimPrefix(version, "v"), ".")
+	if len(fields) != 3 {
+		return parts, false
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
@@ -0,0 +1,118 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseChecksums(t *testing.T) {
+	sums := ParseChecksums([]byte("ABC123  gopack_linux_amd64\ndef456 *gopack_windows_amd64.exe\n\nbogus line here\n"))
+	want := map[string]string{
+		"gopack_linux_amd64":       "abc123",
+		"gopack_windows_amd64.exe": "def456",
+	}
+	if fmt.Sprint(sums) != fmt.Sprint(want) {
+		t.Errorf("ParseChecksums() = %v, want %v", sums, want)
+	}
+}
+
+func TestIsNewer(t *testing.T) {
+	tests := []struct {
+		latest, current string
+		want            bool
+	}{
+		{"v1.2.0", "v1.1.9", true},
+		{"v1.10.0", "v1.9.0", true},
+		{"v2.0.0", "1.99.99", true},
+		{"v1.2.0", "v1.2.0", false},
+		{"v1.2.0", "v1.3.0", false},
+		{"v1.2.0", "dev", true},
+		{"nightly", "v1.0.0", false},
+	}
+	for _, tt := range tests {
+		if got := IsNewer(tt.latest, tt.current); got != tt.want {
+			t.Errorf("IsNewer(%q, %q) = %v, want %v", tt.latest, tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadBinary(t *testing.T) {
+	binary := []byte("new gopack binary")
+	name := AssetName(runtime.GOOS, runtime.GOARCH)
+
+	var checksum string
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	mux.HandleFunc("/latest", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"tag_name":"v9.9.9","assets":[{"name":%q,"browser_download_url":%q},{"name":"checksums.txt","browser_download_url":%q}]}`,
+			name, server.URL+"/bin", server.URL+"/sums")
+	})
+	mux.HandleFunc("/bin", func(w http.ResponseWriter, r *http.Request) { w.Write(binary) })
+	mux.HandleFunc("/sums", func(w http.ResponseWriter, r *http.Request) { fmt.Fprintf(w, "%s  %s\n", checksum, name) })
+
+	defer func(url string) { ReleasesURL = url }(ReleasesURL)
+	ReleasesURL = server.URL + "/latest"
+
+	release, err := LatestRelease(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.Tag != "v9.9.9" {
+		t.Errorf("Tag = %q, want v9.9.9", release.Tag)
+	}
+
+	checksum = hashContent(binary)
+	data, err := release.DownloadBinary(context.Background())
+	if err != nil || string(data) != string(binary) {
+		t.Errorf("DownloadBinary() = %q, %v; want %q", data, err, binary)
+	}
+
+	checksum = strings.Repeat("0", 64)
+	if _, err := release.DownloadBinary(context.Background()); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("DownloadBinary() with a bad checksum: error = %v, want checksum mismatch", err)
+	}
+}
+
+func TestReplaceExecutable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gopack")
+	if err := os.WriteFile(path, []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ReplaceExecutable(path, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path); got != "new" {
+		t.Errorf("content = %q, want %q", got, "new")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0111 == 0 {
+		t.Errorf("mode = %v, want executable", info.Mode())
+	}
+
+	// An executable installed with other permissions keeps them
+	if runtime.GOOS == "windows" {
+		return
+	}
+	if err := os.Chmod(path, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ReplaceExecutable(path, []byte("newer")); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0700 {
+		t.Errorf("mode = %v, want 0700", info.Mode().Perm())
+	}
+}
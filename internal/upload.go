@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// uploadCommands maps object storage URL schemes to the command that
+// copies stdin to such a URL. Running the providers' own CLIs picks up
+// credentials from their standard chains (environment variables, profiles,
+// instance and workload identity) without bundling either SDK.
+var uploadCommands = map[string][]string{
+	"s3://": {"aws", "s3", "cp", "-"},
+	"gs://": {"gcloud", "storage", "cp", "-"},
+}
+
+// IsBucketURL reports whether path names an object in S3 (s3://) or Google
+// Cloud Storage (gs://) rather than a local file.
+func IsBucketURL(path string) bool {
+	for scheme := range uploadCommands {
+		if strings.HasPrefix(path, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// BucketObject returns the object a bucket URL names, taking defaultName
+// under it if it ends in a slash like a directory does.
+func BucketObject(url, defaultName string) string {
+	if strings.HasSuffix(url, "/") {
+		return url + defaultName
+	}
+	return url
+}
+
+// Upload writes data to the object a bucket URL names, replacing any
+// object already there.
+func Upload(ctx context.Context, url string, data []byte) error {
+	var args []string
+	for scheme, command := range uploadCommands {
+		if bucket, ok := strings.CutPrefix(url, scheme); ok {
+			if strings.Trim(bucket, "/") == "" {
+				return fmt.Errorf("%s names no bucket", url)
+			}
+			args = command
+		}
+	}
+	if args == nil {
+		return fmt.Errorf("%s is not an s3:// or gs:// URL", url)
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return fmt.Errorf("uploading to %s requires the %s command in PATH", url, args[0])
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], append(args[1:], url)...)
+	cmd.Stdin = bytes.NewReader(data)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s: %s", args[0], msg)
+		}
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	return nil
+}
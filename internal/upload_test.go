@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestBucketObject(t *testing.T) {
+	tests := []struct{ url, want string }{
+		{"s3://bucket/packs/context.md", "s3://bucket/packs/context.md"},
+		{"s3://bucket/packs/", "s3://bucket/packs/context.txt"},
+		{"gs://bucket/", "gs://bucket/context.txt"},
+	}
+	for _, tt := range tests {
+		if got := BucketObject(tt.url, "context.txt"); got != tt.want {
+			t.Errorf("BucketObject(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+	if !IsBucketURL("gs://bucket/x") || IsBucketURL("s3/context.md") || IsBucketURL("https://example.com/x") {
+		t.Error("IsBucketURL misclassified a path")
+	}
+}
+
+func TestUpload(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	dir := t.TempDir()
+	fake := filepath.Join(dir, "aws")
+	script := "#!/bin/sh\necho \"$@\" > " + filepath.Join(dir, "args") + "\ncat > " + filepath.Join(dir, "body") + "\n"
+	if err := os.WriteFile(fake, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	old := uploadCommands["s3://"]
+	uploadCommands["s3://"] = []string{fake, "s3", "cp", "-"}
+	t.Cleanup(func() { uploadCommands["s3://"] = old })
+
+	if err := Upload(context.Background(), "s3://bucket/context.md", []byte("File: a.go\n")); err != nil {
+		t.Fatal(err)
+	}
+	args, _ := os.ReadFile(filepath.Join(dir, "args"))
+	body, _ := os.ReadFile(filepath.Join(dir, "body"))
+	if string(args) != "s3 cp - s3://bucket/context.md\n" || string(body) != "File: a.go\n" {
+		t.Errorf("uploaded %q with args %q", body, args)
+	}
+
+	if err := Upload(context.Background(), "s3://", nil); err == nil {
+		t.Error("Upload to a URL without a bucket succeeded")
+	}
+}
@@ -0,0 +1,398 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// goSource is a parsed Go file of a pack.
+type goSource struct {
+	index   int // of the file in the pack
+	path    string
+	dir     string
+	content []byte
+	fset    *token.FileSet
+	file    *ast.File
+}
+
+// symbolRef names the symbol FindUses looks for: a package-level
+// declaration, or a method when recv is set.
+type symbolRef struct {
+	pkg  string // package name or import path, if qualified
+	recv string
+	name string
+}
+
+// parseSymbolRef parses a symbol as written in Go, optionally qualified by
+// its package name or import path: "Walker", "internal.Walker",
+// "gopack/internal.Walker", or for a method "Walker.Walk" or
+// "internal.Walker.Walk".
+func parseSymbolRef(symbol string) (symbolRef, error) {
+	slash := strings.LastIndex(symbol, "/") + 1
+	parts := strings.Split(symbol[slash:], ".")
+	for _, part := range parts {
+		if !token.IsIdentifier(part) {
+			return symbolRef{}, fmt.Errorf("invalid symbol %q (expected e.g. Name, pkg.Name, or pkg.Type.Method)", symbol)
+		}
+	}
+	switch len(parts) {
+	case 1:
+		if slash > 0 {
+			return symbolRef{}, fmt.Errorf("invalid symbol %q (expected e.g. Name, pkg.Name, or pkg.Type.Method)", symbol)
+		}
+		return symbolRef{name: parts[0]}, nil
+	case 2:
+		return symbolRef{pkg: symbol[:slash] + parts[0], name: parts[1]}, nil
+	case 3:
+		return symbolRef{pkg: symbol[:slash] + parts[0], recv: parts[1], name: parts[2]}, nil
+	}
+	return symbolRef{}, fmt.Errorf("invalid symbol %q (expected e.g. Name, pkg.Name, or pkg.Type.Method)", symbol)
+}
+
+// goImportPrefix returns the import path of the directory root, found from
+// the nearest go.mod file at or above it, or "" if there is none.
+func goImportPrefix(root string) string {
+	dir, err := filepath.Abs(root)
+	if err != nil {
+		return ""
+	}
+	var rel []string
+	for {
+		if module := modulePath(dir); module != "" {
+			slices.Reverse(rel)
+			return path.Join(append([]string{module}, rel...)...)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		rel = append(rel, filepath.Base(dir))
+		dir = parent
+	}
+}
+
+// SymbolUses is what FindUses found for a symbol.
+type SymbolUses struct {
+	Package    string // import path (or name, outside a module) of the declaring package
+	Files      []File // the declaring files first, then those referring to it
+	References int
+}
+
+// FindUses finds the declaration of a Go symbol among the files of a pack
+// walked from root, and the files that refer to it, using the syntax of
+// the files rather than type checking: a reference is the symbol's name
+// in its own package, or qualified by the name the package is imported
+// under elsewhere, and for methods any selector of the method's name in
+// files that can see the package. The symbol is written as
+// parseSymbolRef accepts; an unqualified name must be declared in one
+// package only.
+//
+// With enclosing set, each file is cut down to its package clause and the
+// declarations that declare or refer to the symbol, for a prompt that
+// needs the call sites but not the rest of their files.
+func FindUses(files []File, root, symbol string, enclosing bool) (SymbolUses, error) {
+	sources := parseGoSources(files)
+	decl, err := resolveSymbol(sources, root, symbol)
+	if err != nil {
+		return SymbolUses{}, err
+	}
+	ref, decls, dir, pkgName, prefix := decl.ref, decl.decls, decl.dir, decl.pkgName, decl.prefix
+	uses := SymbolUses{Package: decl.pkg}
+
+	// Find the references to it
+	var declaring, referring []File
+	for _, src := range sources {
+		local := ""
+		if src.dir == dir && src.file.Name.Name == pkgName {
+			local = "."
+		} else if local = importedAs(src.file, uses.Package, pkgName, prefix == ""); local == "" {
+			continue
+		}
+
+		keep := decls[src.index]
+		found := 0
+		for _, decl := range src.file.Decls {
+			n := countRefs(decl, ref, local)
+			if n > 0 && !slices.Contains(keep, decl) {
+				keep = append(keep, decl)
+			}
+			found += n
+		}
+		if found == 0 && len(decls[src.index]) == 0 {
+			continue
+		}
+		uses.References += found
+
+		file := files[src.index]
+		if enclosing {
+			file.Content = keepDecls(src, keep)
+		}
+		if len(decls[src.index]) > 0 {
+			declaring = append(declaring, file)
+		} else {
+			referring = append(referring, file)
+		}
+	}
+	uses.Files = append(declaring, referring...)
+	return uses, nil
+}
+
+// symbolDecl is where resolveSymbol found a symbol declared.
+type symbolDecl struct {
+	ref     symbolRef
+	decls   map[int][]ast.Decl // by file index
+	dir     string             // of the declaring package
+	pkgName string
+	pkg     string // import path, or outside a module the package name
+	prefix  string // import path of the root, "" outside a module
+}
+
+// resolveSymbol finds the one package declaring a symbol, written as
+// parseSymbolRef accepts, among the parsed files of a pack walked from
+// root.
+func resolveSymbol(sources []goSource, root, symbol string) (symbolDecl, error) {
+	ref, err := parseSymbolRef(symbol)
+	if err != nil {
+		return symbolDecl{}, err
+	}
+	prefix := goImportPrefix(root)
+	importPath := func(dir string) string {
+		if prefix == "" {
+			return ""
+		}
+		return path.Join(prefix, dir)
+	}
+
+	// Find the declaring package. "A.B" may be a method B of type A too.
+	var decls map[int][]ast.Decl
+	var dirs []string
+	refs := []symbolRef{ref}
+	if ref.recv == "" && token.IsIdentifier(ref.pkg) {
+		refs = append(refs, symbolRef{recv: ref.pkg, name: ref.name})
+	}
+	for _, candidate := range refs {
+		ref = candidate
+		if decls, dirs = declarations(sources, ref, importPath); len(dirs) > 0 {
+			break
+		}
+	}
+	if len(dirs) == 0 {
+		return symbolDecl{}, fmt.Errorf("no declaration of %s found", symbol)
+	}
+	if len(dirs) > 1 {
+		return symbolDecl{}, fmt.Errorf("%s is declared in several packages (%s); qualify it with one", symbol, strings.Join(dirs, ", "))
+	}
+	decl := symbolDecl{ref: ref, decls: decls, dir: dirs[0], prefix: prefix}
+	for _, src := range sources {
+		if _, ok := decls[src.index]; ok {
+			decl.pkgName = src.file.Name.Name
+		}
+	}
+	decl.pkg = importPath(decl.dir)
+	if decl.pkg == "" {
+		decl.pkg = decl.pkgName
+	}
+	return decl, nil
+}
+
+// parseGoSources parses the Go files of a pack, skipping dedupe references
+// and files that don't parse.
+func parseGoSources(files []File) []goSource {
+	var sources []goSource
+	for i, file := range files {
+		name := filepath.ToSlash(file.Path)
+		if !strings.HasSuffix(name, ".go") || file.DuplicateOf != "" {
+			continue
+		}
+		fset := token.NewFileSet()
+		parsed, err := parser.ParseFile(fset, name, file.Content, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		sources = append(sources, goSource{index: i, path: name, dir: path.Dir(name), content: file.Content, fset: fset, file: parsed})
+	}
+	return sources
+}
+
+// declarations finds the top-level declarations of the symbol, by file
+// index, and the directories of the packages declaring it.
+func declarations(sources []goSource, ref symbolRef, importPath func(dir string) string) (map[int][]ast.Decl, []string) {
+	decls := make(map[int][]ast.Decl)
+	var dirs []string
+	for _, src := range sources {
+		if strings.HasSuffix(src.file.Name.Name, "_test") {
+			continue
+		}
+		if ref.pkg != "" && ref.pkg != src.file.Name.Name && ref.pkg != importPath(src.dir) &&
+			!strings.HasSuffix(importPath(src.dir), "/"+ref.pkg) {
+			continue
+		}
+		for _, decl := range src.file.Decls {
+			if declares(decl, ref) {
+				decls[src.index] = append(decls[src.index], decl)
+				if !slices.Contains(dirs, src.dir) {
+					dirs = append(dirs, src.dir)
+				}
+			}
+		}
+	}
+	return decls, dirs
+}
+
+// declares reports whether a top-level declaration declares the symbol.
+func declares(decl ast.Decl, ref symbolRef) bool {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		if decl.Name.Name != ref.name {
+			return false
+		}
+		if decl.Recv == nil {
+			return ref.recv == ""
+		}
+		return ref.recv == receiverName(decl.Recv)
+	case *ast.GenDecl:
+		if ref.recv != "" {
+			return false
+		}
+		for _, spec := range decl.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				if spec.Name.Name == ref.name {
+					return true
+				}
+			case *ast.ValueSpec:
+				for _, ident := range spec.Names {
+					if ident.Name == ref.name {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// importedAs returns the name a file imports the package under: its
+// explicit name, the package's own name, or "." for a dot import. Outside
+// a module the package can only be recognized by its name, so byName
+// matches imports whose last element is pkgName. It returns "" if the file
+// doesn't import the package.
+func importedAs(file *ast.File, importPath, pkgName string, byName bool) string {
+	for _, spec := range file.Imports {
+		p, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || (p != importPath && !(byName && path.Base(p) == pkgName)) {
+			continue
+		}
+		if spec.Name == nil {
+			return pkgName
+		}
+		if spec.Name.Name == "_" {
+			return ""
+		}
+		return spec.Name.Name
+	}
+	return ""
+}
+
+// countRefs counts the references to the symbol in a declaration of a file
+// that sees its package under local ("." when unqualified).
+func countRefs(decl ast.Decl, ref symbolRef, local string) int {
+	count := 0
+	ast.Inspect(decl, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			if n.Sel.Name != ref.name {
+				return true
+			}
+			if ref.recv != "" {
+				count++
+				return false
+			}
+			if x, ok := n.X.(*ast.Ident); ok && x.Name == local {
+				count++
+				return false
+			}
+			// Only the selector's operand can refer to a package-level name
+			ast.Inspect(n.X, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok && local == "." && id.Name == ref.name {
+					count++
+				}
+				return true
+			})
+			return false
+		case *ast.Ident:
+			if local == "." && ref.recv == "" && n.Name == ref.name && !declaresIdent(decl, n) {
+				count++
+			}
+		}
+		return true
+	})
+	return count
+}
+
+// declaresIdent reports whether id is the name a declaration declares,
+// rather than a use of it.
+func declaresIdent(decl ast.Decl, id *ast.Ident) bool {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		return decl.Name == id
+	case *ast.GenDecl:
+		for _, spec := range decl.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				if spec.Name == id {
+					return true
+				}
+			case *ast.ValueSpec:
+				if slices.Contains(spec.Names, id) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// keepDecls renders a file's package clause and the given declarations,
+// with their doc comments, in the order they appear in the file.
+func keepDecls(src goSource, keep []ast.Decl) []byte {
+	var out bytes.Buffer
+	out.Write(declSource(src.content, src.fset, src.file.Package, src.file.Name.End(), src.file.Doc))
+	for _, decl := range src.file.Decls {
+		if !slices.Contains(keep, decl) {
+			continue
+		}
+		var doc *ast.CommentGroup
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			doc = decl.Doc
+		case *ast.GenDecl:
+			doc = decl.Doc
+		}
+		line := src.fset.Position(decl.Pos()).Line
+		if doc != nil {
+			line = src.fset.Position(doc.Pos()).Line
+		}
+		fmt.Fprintf(&out, "\n\n// line %d\n", line)
+		out.Write(declSource(src.content, src.fset, decl.Pos(), decl.End(), doc))
+	}
+	out.WriteByte('\n')
+	return out.Bytes()
+}
+
+// declSource returns the source from start to end, starting at the doc
+// comment instead if there is one.
+func declSource(content []byte, fset *token.FileSet, start, end token.Pos, doc *ast.CommentGroup) []byte {
+	if doc != nil {
+		start = doc.Pos()
+	}
+	return content[fset.Position(start).Offset:fset.Position(end).Offset]
+}
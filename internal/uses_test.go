@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindUses(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files := []File{
+		{Path: "cmd/main.go", Content: []byte("package main\n\nimport \"example.com/app/store\"\n\nfunc main() {\n\ts := store.New()\n\ts.Get(\"a\")\n}\n\nfunc other() {}\n")},
+		{Path: "store/store.go", Content: []byte("package store\n\n// Store keeps values.\ntype Store struct{}\n\n// New returns a Store.\nfunc New() *Store { return &Store{} }\n\nfunc (s *Store) Get(key string) string { return key }\n")},
+		{Path: "store/cache.go", Content: []byte("package store\n\nvar shared = New()\n\nfunc unrelated() {}\n")},
+		{Path: "store/store_test.go", Content: []byte("package store_test\n\nimport st \"example.com/app/store\"\n\nvar _ = st.New()\n")},
+		{Path: "web/web.go", Content: []byte("package web\n\nfunc New() {}\n")},
+		{Path: "README.md", Content: []byte("store.New\n")},
+	}
+
+	tests := []struct {
+		symbol    string
+		wantPaths []string
+		wantRefs  int
+		wantErr   string
+	}{
+		{"store.New", []string{"store/store.go", "cmd/main.go", "store/cache.go", "store/store_test.go"}, 3, ""},
+		{"example.com/app/store.New", []string{"store/store.go", "cmd/main.go", "store/cache.go", "store/store_test.go"}, 3, ""},
+		{"Store", []string{"store/store.go"}, 3, ""},
+		{"Store.Get", []string{"store/store.go", "cmd/main.go"}, 1, ""},
+		{"store.Store.Get", []string{"store/store.go", "cmd/main.go"}, 1, ""},
+		{"New", nil, 0, "declared in several packages"},
+		{"store.Missing", nil, 0, "no declaration"},
+		{"store.", nil, 0, "invalid symbol"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			uses, err := FindUses(files, root, tt.symbol, false)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("FindUses() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var paths []string
+			for _, file := range uses.Files {
+				paths = append(paths, file.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.wantPaths) || uses.References != tt.wantRefs {
+				t.Errorf("FindUses() = %q, %d references; want %q, %d", paths, uses.References, tt.wantPaths, tt.wantRefs)
+			}
+			if uses.Package != "example.com/app/store" {
+				t.Errorf("FindUses().Package = %q", uses.Package)
+			}
+		})
+	}
+}
+
+func TestFindUsesFunctions(t *testing.T) {
+	files := []File{
+		{Path: "main.go", Content: []byte("package main\n\nimport \"fmt\"\n\n// greet says hello.\nfunc greet() { fmt.Println(\"hi\") }\n\nfunc main() {\n\tgreet()\n}\n\nfunc other() {}\n")},
+	}
+	uses, err := FindUses(files, t.TempDir(), "greet", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "package main\n\n// line 5\n// greet says hello.\nfunc greet() { fmt.Println(\"hi\") }\n\n// line 8\nfunc main() {\n\tgreet()\n}\n"
+	if got := string(uses.Files[0].Content); got != want {
+		t.Errorf("FindUses() content =\n%s\nwant\n%s", got, want)
+	}
+	if uses.Package != "main" || uses.References != 1 {
+		t.Errorf("FindUses() = package %q, %d references; want main, 1", uses.Package, uses.References)
+	}
+}
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// summaryFiles matches the file count in a pack's summary, in any format.
+var summaryFiles = regexp.MustCompile(`(?m)^(?:- |<li>)?Files: ([\d,]+)(?:</li>)?$`)
+
+// VerifyPack checks that data is a well-formed pack and returns the
+// problems found, or nil if there are none: sections that don't parse
+// (such as a truncated code fence), unsafe or repeated paths, pieces of
+// split files with lines missing between them, dedupe references to files
+// not in the pack, and a file count that disagrees with the summary.
+//
+// Given a manifest, it also checks that every packed file is listed with
+// the hash of its content. Files listed but not packed aren't a problem, as
+// a pack trimmed to a token budget leaves some out; transformations such as
+// --strip-license do change the content the manifest recorded.
+func VerifyPack(data []byte, manifest *Manifest) []string {
+	sections, err := parseSections(data)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	problems := missingPieces(sections)
+
+	// A single pack's summary counts its sections
+	if counts := summaryFiles.FindAllStringSubmatch(string(data), -1); len(counts) == 1 {
+		if n, err := strconv.Atoi(strings.ReplaceAll(counts[0][1], ",", "")); err == nil && n != len(sections) {
+			problems = append(problems, fmt.Sprintf("the summary counts %d files, but the pack has %d", n, len(sections)))
+		}
+	}
+
+	files, err := joinPieces(sections)
+	if err != nil {
+		return append(problems, err.Error())
+	}
+	seen := make(map[string]bool, len(files))
+	for _, file := range files {
+		if seen[file.Path] {
+			problems = append(problems, fmt.Sprintf("%s: packed more than once", file.Path))
+		}
+		seen[file.Path] = true
+		if m := duplicateRef.FindSubmatch(file.Content); m != nil {
+			problems = append(problems, fmt.Sprintf("%s: refers to %s, which isn't in the pack", file.Path, m[1]))
+		}
+	}
+
+	if manifest != nil {
+		hashes := make(map[string]string, len(manifest.Files))
+		for _, entry := range manifest.Files {
+			hashes[entry.Path] = entry.SHA256
+		}
+		for _, file := range files {
+			want, ok := hashes[file.Path]
+			switch {
+			case !ok:
+				problems = append(problems, fmt.Sprintf("%s: not in the manifest", file.Path))
+			case hashContent(file.Content) != want && !matchesWithoutNewline(file.Content, want):
+				problems = append(problems, fmt.Sprintf("%s: content doesn't match the manifest's hash", file.Path))
+			}
+		}
+	}
+	return problems
+}
+
+// matchesWithoutNewline reports whether content hashes to want without its
+// final newline, which Markdown packs add to files that lack one.
+func matchesWithoutNewline(content []byte, want string) bool {
+	trimmed, ok := strings.CutSuffix(string(content), "\n")
+	return ok && hashContent([]byte(trimmed)) == want
+}
+
+// missingPieces reports gaps in the line ranges of the pieces of split
+// files.
+func missingPieces(sections []File) []string {
+	var problems []string
+	next := make(map[string]int) // the line each file's next piece should start by
+	for _, section := range sections {
+		name, start, end := pieceRange(section)
+		if name == section.Path {
+			continue
+		}
+		want, ok := next[name]
+		if !ok {
+			want = 1
+		}
+		if start > want {
+			problems = append(problems, fmt.Sprintf("%s: lines %d-%d are missing", name, want, start-1))
+		}
+		next[name] = max(want, end+1)
+	}
+	return problems
+}
@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestVerifyPack(t *testing.T) {
+	files := []File{
+		{Path: "main.go", Content: []byte("package main\n\nfunc main() {}\n")},
+		{Path: "notes.txt", Content: []byte("no newline")},
+	}
+	pack := func(format string, summary bool) string {
+		formatter := NewFormatter(files)
+		formatter.OutputFormat = format
+		formatter.Summary = summary
+		return formatter.Format()
+	}
+	manifest := NewManifest(files)
+	stale := NewManifest([]File{{Path: "main.go", Content: []byte("package old\n")}})
+
+	tests := []struct {
+		name     string
+		pack     string
+		manifest *Manifest
+		want     []string
+	}{
+		{"markdown", pack(FormatMarkdown, true), &manifest, nil},
+		{"text", pack(FormatText, false), &manifest, nil},
+		{"html", pack(FormatHTML, true), &manifest, nil},
+		{"truncated fence", strings.TrimSuffix(pack(FormatMarkdown, false), "```\n"), nil, []string{"notes.txt: unterminated code fence"}},
+		{"summary count", strings.Replace(pack(FormatText, true), "Files: 2", "Files: 3", 1), nil, []string{"the summary counts 3 files, but the pack has 2"}},
+		{"stale manifest", pack(FormatText, false), &stale, []string{
+			"main.go: content doesn't match the manifest's hash",
+			"notes.txt: not in the manifest",
+		}},
+		{"missing piece", "File: big.go (lines 1-10)\nx\n\nFile: big.go (lines 21-30)\ny\n", nil, []string{"big.go: lines 11-20 are missing"}},
+		{"dangling reference", "File: a.go\n[identical to gone.go]\n", nil, []string{"a.go: refers to gone.go, which isn't in the pack"}},
+		{"repeated path", "File: a.go\nx\n\nFile: a.go\ny\n", nil, []string{"a.go: packed more than once"}},
+		{"unsafe path", "File: ../etc/passwd\nx\n", nil, []string{`unsafe path in pack: "../etc/passwd"`}},
+		{"not a pack", "hello\n", nil, []string{`not a gopack pack: expected a "File: " header`}},
+	}
+	for _, tt := range tests {
+		if got := VerifyPack([]byte(tt.pack), tt.manifest); !slices.Equal(got, tt.want) {
+			t.Errorf("VerifyPack(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
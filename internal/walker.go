@@ -1,193 +1,1057 @@
 package internal
 
 import (
-	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/build"
 	"io"
+	"io/fs"
+	"math"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
 	"strings"
+	"time"
 )
 
 // File represents a file to be included in the output.
 type File struct {
 	Path    string
 	Content []byte
+	ModTime time.Time
+
+	// Mode holds the file's permission bits when the walker records them
+	// (FileModes) or the pack it was parsed from has them, and is 0
+	// otherwise.
+	Mode os.FileMode
+
+	// DuplicateOf is set by Dedupe to the path of an identical earlier
+	// file when Content has been replaced by a reference to it.
+	DuplicateOf string
+
+	// Omitted is set by CollapseDirs on the placeholder standing in for a
+	// directory's files, whose Content is a note rather than a file.
+	Omitted bool
+
+	// URL, when set by AddPermalinks, links to the file's canonical source
+	// and is shown in its header.
+	URL string
 }
 
-// Walker traverses a directory and filters files based on .gitignore rules.
+// DefaultIgnorePatterns lists files that are almost never useful context
+// (lockfiles, minified and generated assets, build and coverage output).
+// They are ignored in addition to .gitignore rules unless disabled.
+var DefaultIgnorePatterns = []string{
+	"package-lock.json",
+	"yarn.lock",
+	"pnpm-lock.yaml",
+	"go.sum",
+	"Cargo.lock",
+	"Gemfile.lock",
+	"poetry.lock",
+	"composer.lock",
+	"*.min.js",
+	"*.min.css",
+	"*.map",
+	"dist/",
+	"coverage/",
+}
+
+// DefaultVCSDirs are the names of version control metadata directories
+// (and files, such as the .git file of a submodule) a walker skips by
+// default.
+var DefaultVCSDirs = []string{".git", ".hg", ".svn", ".jj", ".bzr"}
+
+// Walker traverses one or more paths and filters files based on .gitignore rules.
 type Walker struct {
-	rootPath string
-	patterns map[string][]string // dir -> patterns
+	// ExcludeRegexps excludes any file or directory whose slash-separated
+	// relative path matches one of the expressions.
+	ExcludeRegexps []*regexp.Regexp
+
+	// IgnoreCase makes ignore patterns and include globs match paths
+	// case-insensitively, like git's core.ignoreCase.
+	IgnoreCase bool
+
+	// MaxDepth limits how many directory levels below each target are
+	// walked; 1 includes only a target's direct children. Zero means no limit.
+	MaxDepth int
+
+	// Since, when non-zero, excludes files last modified before it.
+	Since time.Time
+
+	// SinceGit makes Since compare against each file's last git commit
+	// instead of its modification time.
+	SinceGit bool
+
+	// Author, when set, only includes files whose most recent commit was
+	// made by a matching author (case-insensitive substring of "Name <email>").
+	Author string
+
+	// AuthorShare, when non-zero, matches Author against git blame instead:
+	// a file is included if at least this fraction of its lines were last
+	// changed by the author.
+	AuthorShare float64
+
+	// FollowSymlinks descends into symlinked directories and includes
+	// symlinked files. Symlinks are otherwise skipped, except for those
+	// named explicitly as paths. Links that resolve outside the root are
+	// skipped unless AllowExternalSymlinks is set.
+	FollowSymlinks bool
+
+	// AllowExternalSymlinks includes what followed symlinks outside the
+	// root point to, such as a shared config repository, under
+	// ExternalPrefix so the pack doesn't pass it off as part of the tree.
+	AllowExternalSymlinks bool
+
+	// SkipHidden excludes dotfiles and dot-directories, except for paths
+	// named explicitly.
+	SkipHidden bool
+
+	// DefaultIgnores holds gitignore-style patterns applied from the root in
+	// addition to .gitignore files. NewWalker sets it to
+	// DefaultIgnorePatterns; set it to nil to disable the defaults.
+	DefaultIgnores []string
+
+	// IgnoreFiles names the per-directory files ignore patterns are read
+	// from, later ones taking precedence. NewWalker sets it to .gitignore
+	// and .gopackignore. Files named .dockerignore are read with Docker's
+	// rules instead of git's: every pattern is relative to the file's
+	// directory, and a trailing "/" doesn't restrict it to directories.
+	IgnoreFiles []string
+
+	// VCSDirs names the version control metadata that's always skipped,
+	// however hidden files and ignore rules are set. NewWalker sets it to
+	// DefaultVCSDirs.
+	VCSDirs []string
+
+	// IncludeGenerated keeps files that look machine-generated (a
+	// "Code generated ... DO NOT EDIT" header, protobuf output, mocks),
+	// which are otherwise skipped unless named explicitly.
+	IncludeGenerated bool
+
+	// NoDirectives disregards the gopack:ignore directives of files, which
+	// otherwise leave them out unless named explicitly.
+	NoDirectives bool
+
+	// FileModes records each file's permission bits in File.Mode, so the
+	// pack shows which files are executable and unpacking can restore them.
+	FileModes bool
+
+	// IncludeVendored keeps files a .gitattributes file marks
+	// linguist-vendored, which are otherwise skipped unless named
+	// explicitly. (Files marked linguist-generated follow IncludeGenerated.)
+	IncludeVendored bool
+
+	// IncludeEmpty keeps files that are empty or only whitespace, such as
+	// .gitkeep and bare __init__.py files, which are otherwise skipped
+	// unless named explicitly.
+	IncludeEmpty bool
+
+	// MinBytes, if positive, excludes files smaller than this many bytes,
+	// except for paths named explicitly.
+	MinBytes int64
+
+	// SkipTests excludes test files and test-only directories in common
+	// languages (*_test.go, *.spec.ts, test_*.py, __tests__/), except for
+	// paths named explicitly.
+	SkipTests bool
+
+	// Submodules descends into initialized git submodules, which are
+	// otherwise skipped. Ignore files above a submodule don't apply inside
+	// it, as in git.
+	Submodules bool
+
+	// LFS says how to handle Git LFS pointer files: skip them ("" or
+	// LFSSkip), keep the pointer with a note giving the object's size
+	// (LFSPointer), or replace it with the object itself (LFSSmudge), which
+	// needs git-lfs installed.
+	LFS string
+
+	// MaxBytes, if positive, aborts the walk with a SizeLimitError once the
+	// files read add up to more than this many bytes, before reading the
+	// file that would cross it.
+	MaxBytes int64
+
+	// MaxFiles, if positive, aborts the walk with a FileLimitError once it
+	// has found more than this many files to pack.
+	MaxFiles int
+
+	// GoBuild, if set, excludes Go files that wouldn't be compiled for its
+	// platform and tags (see GoBuildContext), except for paths named
+	// explicitly.
+	GoBuild *build.Context
+
+	// IgnorePatterns holds extra gitignore-style patterns applied from the
+	// root, typically given on the command line.
+	IgnorePatterns []string
+
+	// OnSkip, if set, is called with the slash-separated relative path of
+	// every file or directory the walker excludes and the reason why.
+	OnSkip func(path, reason string)
+
+	// OnRead, if set, is called with the slash-separated relative path and
+	// size of every file the walker reads, e.g. to report progress.
+	OnRead func(path string, size int)
+
+	// OnFile, if set, is called with every file as it's included, before
+	// the walk finishes, e.g. to stream the files out.
+	OnFile func(file File)
+
+	// IsBinary, if set, decides whether a file is binary, and left out,
+	// from its slash-separated relative path and the first 512 bytes of its
+	// content, in place of the check that the content looks like text.
+	// Files with a binary extension, such as .png, are still left out
+	// unread unless named explicitly.
+	IsBinary func(path string, head []byte) bool
+
+	// Cache, if set, is used to avoid re-reading unchanged files when the
+	// same tree is walked repeatedly.
+	Cache *FileCache
+
+	// Strict makes Walk fail on the first file or directory it can't read.
+	// Otherwise such paths are skipped and listed by ReadErrors.
+	Strict bool
+
+	// Timings, if set, is added to with how long each walk takes and where
+	// the time goes, e.g. to see why a walk of a network drive is slow.
+	Timings *WalkTimings
+
+	ctx        context.Context         // the current walk's context, checked before each path
+	rootPath   string                  // common parent of all targets; output paths are relative to it
+	fsys       fs.FS                   // walked instead of the host's file system (NewFSWalker only)
+	targets    []target                // directories, files, and globs to walk
+	patterns   map[string][]ignoreRule // dir -> rules from its ignore files
+	attributes map[string][]attrRule   // dir -> linguist rules from its .gitattributes
+
+	defaultRules []ignoreRule // compiled DefaultIgnores
+	flagRules    []ignoreRule // compiled IgnorePatterns
+
+	submodules map[string]bool   // directories of submodules being walked
+	recent     map[string]bool   // files committed after Since (SinceGit only)
+	authors    map[string]string // file -> last commit author (Author only)
+	unread     []ReadError       // paths skipped because they couldn't be read
+	matched    map[string]bool   // ignore rules that matched a path (LintIgnores only)
+	read       int64             // bytes of content read, for MaxBytes
+}
+
+// SizeLimitError reports that a walk stopped at MaxBytes.
+type SizeLimitError struct {
+	Limit int64  // the Walker's MaxBytes
+	Path  string // the file that would have crossed it
+}
+
+func (e SizeLimitError) Error() string {
+	return fmt.Sprintf("files add up to more than %s at %s", FormatBytes(e.Limit), e.Path)
+}
+
+// FileLimitError reports that a walk stopped at MaxFiles.
+type FileLimitError struct {
+	Limit int    // the Walker's MaxFiles
+	Path  string // the file that would have crossed it
+}
+
+func (e FileLimitError) Error() string {
+	return fmt.Sprintf("more than %s files to pack at %s", FormatWithCommas(e.Limit), e.Path)
+}
+
+// ReadError is a file or directory that couldn't be read during a walk.
+type ReadError struct {
+	Path string // slash-separated, relative to the root
+	Err  error
+}
+
+func (e ReadError) Error() string { return e.Path + ": " + e.Err.Error() }
+func (e ReadError) Unwrap() error { return e.Err }
+
+// target is a single path to walk. When glob is set, only files whose
+// path relative to the target matches it are included.
+type target struct {
+	arg      string // path as given to NewWalker
+	path     string // absolute directory or file
+	glob     string // slash-separated include pattern, relative to path
+	external bool   // walking through a symlink to outside the root
 }
 
-// NewWalker creates a new Walker for the given root path.
-func NewWalker(rootPath string) (*Walker, error) {
-	if rootPath == "" {
-		rootPath = "."
+// ExternalPrefix starts the paths of files reached through a symlink to
+// outside the root, followed with AllowExternalSymlinks, as in
+// "@external/config/shared.yaml" for the link config/shared.yaml.
+const ExternalPrefix = "@external"
+
+// NewWalker creates a new Walker for the given paths. Paths may be
+// directories, individual files, or include globs such as
+// "internal/**/*.go" (rooted at the current directory); with no paths the
+// current directory is walked.
+func NewWalker(paths ...string) (*Walker, error) {
+	if len(paths) == 0 {
+		paths = []string{"."}
 	}
 
-	// Resolve to absolute path
-	absPath, err := filepath.Abs(rootPath)
-	if err != nil {
-		return nil, err
+	var targets []target
+	var dirs []string
+	for _, arg := range paths {
+		p := arg
+		if p == "" {
+			p = "."
+		}
+
+		var glob string
+		if _, err := os.Stat(p); os.IsNotExist(err) && isGlob(p) {
+			// A glob is rooted at the current directory, whose ignore
+			// files apply and which its paths are relative to, even when
+			// its walk starts further down
+			if !filepath.IsAbs(p) {
+				cwd, err := filepath.Abs(".")
+				if err != nil {
+					return nil, err
+				}
+				dirs = append(dirs, cwd)
+			}
+			p, glob = splitGlob(p)
+		}
+
+		// Resolve to absolute path
+		absPath, err := filepath.Abs(p)
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := os.Stat(LongPath(absPath))
+		if err != nil {
+			return nil, err
+		}
+
+		targets = append(targets, target{arg: arg, path: absPath, glob: glob})
+		if info.IsDir() {
+			dirs = append(dirs, absPath)
+		} else {
+			dirs = append(dirs, filepath.Dir(absPath))
+		}
 	}
 
 	w := &Walker{
-		rootPath: absPath,
-		patterns: make(map[string][]string),
+		DefaultIgnores: append([]string(nil), DefaultIgnorePatterns...),
+		IgnoreFiles:    append([]string(nil), ignoreFiles...),
+		VCSDirs:        append([]string(nil), DefaultVCSDirs...),
+		rootPath:       commonDir(dirs),
+		targets:        targets,
+		patterns:       make(map[string][]ignoreRule),
+		attributes:     make(map[string][]attrRule),
 	}
 
-	// Load root .gitignore
-	w.loadGitignore(absPath)
+	// Load root ignore files
+	w.loadIgnoreFiles(w.rootPath)
 
 	return w, nil
 }
 
-// Walk traverses the directory and returns a slice of File structs.
+// SetRoot makes output paths relative to dir instead of the common parent
+// of the targets. dir must contain every target.
+func (w *Walker) SetRoot(dir string) error {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	for _, t := range w.targets {
+		if !isWithin(t.path, absDir) {
+			return fmt.Errorf("%s is not under %s", t.arg, dir)
+		}
+	}
+
+	w.rootPath = absDir
+	w.patterns = make(map[string][]ignoreRule)
+	w.attributes = make(map[string][]attrRule)
+	w.loadIgnoreFiles(w.rootPath)
+	return nil
+}
+
+// Root returns the directory that output paths are relative to.
+func (w *Walker) Root() string {
+	return w.rootPath
+}
+
+// Walk traverses every target and returns a slice of File structs.
+// Files reachable from more than one target are only returned once.
 func (w *Walker) Walk() ([]File, error) {
+	return w.WalkContext(context.Background())
+}
+
+// WalkContext is like Walk, but stops between paths once ctx is done,
+// returning the files found so far and ctx's error.
+func (w *Walker) WalkContext(ctx context.Context) ([]File, error) {
+	if w.Timings != nil {
+		defer w.Timings.Total.Add(time.Now())
+	}
+	w.ctx = ctx
+	defer func() { w.ctx = nil }()
 	var files []File
+	seen := make(map[string]bool)
+	w.unread = nil
+	w.read = 0
+	w.compileRules()
+	if err := w.checkFS(); err != nil {
+		return nil, err
+	}
+	w.patterns = make(map[string][]ignoreRule)
+	w.attributes = make(map[string][]attrRule)
+	w.loadIgnoreFiles(w.rootPath)
 
-	err := filepath.Walk(w.rootPath, func(path string, info os.FileInfo, err error) error {
+	if !w.Since.IsZero() && w.SinceGit {
+		recent, err := changedSince(w.ctx, w.rootPath, w.Since)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("failed to read git history: %w", err)
+		}
+		w.recent = recent
+	}
+
+	if w.Author != "" {
+		authors, err := lastAuthors(w.ctx, w.rootPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read git history: %w", err)
 		}
+		w.authors = authors
+	}
+
+	for _, t := range w.targets {
+		if err := w.walkTarget(t, seen, &files); err != nil {
+			return files, err
+		}
+	}
+
+	return files, nil
+}
+
+// ReadErrors returns the paths the last Walk skipped because they couldn't
+// be read (always empty with Strict).
+func (w *Walker) ReadErrors() []ReadError {
+	return w.unread
+}
 
+// unreadable records a path that couldn't be read and skips it, or returns
+// the error in strict mode.
+func (w *Walker) unreadable(relPath string, err error) error {
+	readErr := ReadError{Path: filepath.ToSlash(relPath), Err: err}
+	if w.Strict {
+		return readErr
+	}
+	w.unread = append(w.unread, readErr)
+	w.skip(relPath, "unreadable ("+err.Error()+")")
+	return nil
+}
+
+// walkTarget walks a single target, appending matching files to files.
+func (w *Walker) walkTarget(t target, seen map[string]bool, files *[]File) error {
+	visited := make(map[string]bool)
+	return w.walkPath(t, t.path, t.path, nil, visited, seen, files)
+}
+
+// walkPath walks the tree at root as though it were located at logical,
+// which differs from root when walking through a followed symlink.
+// linkDirs holds the resolved directories containing each symlink followed
+// to get here; a link to one of them or their parents would be a cycle.
+// visited holds the resolved paths of directories already walked, so that
+// a directory reached through several links is only walked once.
+func (w *Walker) walkPath(t target, root, logical string, linkDirs []string, visited, seen map[string]bool, files *[]File) error {
+	return w.walkDir(root, func(realPath string, info os.FileInfo, err error) error {
+		path := logical
+		if rel, _ := filepath.Rel(root, realPath); rel != "." {
+			path = filepath.Join(logical, rel)
+		}
 		relPath, _ := filepath.Rel(w.rootPath, path)
+		if err := w.ctx.Err(); err != nil {
+			return err
+		}
+		if w.Timings != nil {
+			w.Timings.Paths++
+		}
 
-		// Skip .git directory
-		if info.IsDir() && info.Name() == ".git" {
-			return filepath.SkipDir
+		// The path couldn't be examined, or is a directory that couldn't be
+		// listed; either way there's nothing more to do with it
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) && realPath == root {
+				return err
+			}
+			return w.unreadable(relPath, err)
 		}
 
-		// For directories, try to load .gitignore
-		if info.IsDir() {
-			w.loadGitignore(path)
+		// Windows describes a file named like a device (aux.js) as the
+		// device, unless asked with the \\?\ prefix
+		if runtime.GOOS == "windows" && w.fsys == nil && IsReservedName(info.Name()) {
+			if fileInfo, err := os.Lstat(LongPath(realPath)); err == nil {
+				info = fileInfo
+			}
 		}
 
-		// Check if path is ignored
-		if w.isIgnored(relPath) {
+		// skip reports why path is excluded and skips it
+		skip := func(reason string) error {
+			w.skip(relPath, reason)
 			if info.IsDir() {
 				return filepath.SkipDir
 			}
 			return nil
 		}
 
+		// Skip version control directories, or the .git file linking a
+		// submodule to its repository
+		if slices.Contains(w.VCSDirs, info.Name()) {
+			if info.IsDir() {
+				return skip("version control directory")
+			}
+			return skip("version control file")
+		}
+
+		// Skip hidden files and directories if requested
+		if w.SkipHidden && path != t.path && isHidden(filepath.Base(path)) {
+			return skip("hidden (--no-hidden)")
+		}
+
+		// Don't descend past the depth limit
+		if info.IsDir() && w.MaxDepth > 0 && depth(t.path, path) >= w.MaxDepth {
+			return skip(fmt.Sprintf("below --max-depth %d", w.MaxDepth))
+		}
+
+		// Only walk each directory once when following symlinks
+		if info.IsDir() && w.FollowSymlinks && w.fsys == nil {
+			resolved, err := filepath.EvalSymlinks(realPath)
+			if err != nil {
+				return err
+			}
+			if visited[resolved] {
+				return skip("already walked through another path")
+			}
+			visited[resolved] = true
+		}
+
+		// For directories, try to load ignore files
+		ignoreStart := time.Now()
+		if info.IsDir() {
+			w.loadIgnoreFiles(path)
+		}
+
+		// Check if path is ignored (files named explicitly are always included)
+		explicit := path == t.path && !info.IsDir() && t.glob == ""
+		if !explicit {
+			rule, ok := w.ignoredBy(relPath, info.IsDir())
+			if w.Timings != nil {
+				w.Timings.Ignores.Add(ignoreStart)
+			}
+			if ok {
+				return skip("matched " + rule.String())
+			}
+			if expr, ok := w.excludedBy(relPath); ok {
+				return skip(fmt.Sprintf("matched --exclude-regex %q", expr))
+			}
+			if w.SkipTests && path != t.path && isTest(relPath, info.IsDir()) {
+				return skip("test file (--no-tests)")
+			}
+		}
+
+		// Submodules are separate repositories, only walked on request
+		if info.IsDir() && path != t.path && w.isSubmodule(path) {
+			if !w.Submodules {
+				return skip("git submodule (use --submodules)")
+			}
+			if w.submodules == nil {
+				w.submodules = make(map[string]bool)
+			}
+			w.submodules[path] = true
+		}
+
+		// Symlinks are skipped unless following is enabled or the link was
+		// named explicitly
+		if info.Mode()&os.ModeSymlink != 0 {
+			if !w.FollowSymlinks && path != t.path {
+				return skip("symlink (use --follow-symlinks)")
+			}
+			if w.fsys != nil {
+				return skip("symlink (can't be followed in this file system)")
+			}
+			resolved, err := filepath.EvalSymlinks(realPath)
+			if err != nil {
+				return skip("dangling symlink")
+			}
+			linkDir, err := filepath.EvalSymlinks(filepath.Dir(realPath))
+			if err != nil {
+				return err
+			}
+			ancestors := append(slices.Clip(linkDirs), linkDir)
+			for _, dir := range ancestors {
+				if isWithin(dir, resolved) {
+					return skip("symlink cycle (points to a parent directory)")
+				}
+			}
+			// Links named explicitly were asked for wherever they point
+			if path != t.path && !t.external {
+				realRoot, err := filepath.EvalSymlinks(w.rootPath)
+				if err != nil {
+					return err
+				}
+				if !isWithin(resolved, realRoot) {
+					if !w.AllowExternalSymlinks {
+						return skip("symlink to outside the root (use --allow-external-symlinks)")
+					}
+					external := t
+					external.external = true
+					return w.walkPath(external, resolved, path, ancestors, visited, seen, files)
+				}
+			}
+			return w.walkPath(t, resolved, path, ancestors, visited, seen, files)
+		}
+
 		// Only process regular files
 		if !info.IsDir() && info.Mode().IsRegular() {
-			// Check if file is binary
-			if isBinary(path) {
+			if t.glob != "" && !t.matches(path, w.IgnoreCase) {
+				return skip(fmt.Sprintf("doesn't match include glob %q", t.arg))
+			}
+			if seen[path] {
 				return nil
 			}
+			seen[path] = true
 
-			// Read file content
-			content, err := os.ReadFile(path)
+			// Skip files that haven't changed recently enough
+			if !w.modifiedSince(relPath, info) {
+				return skip("not modified since " + w.Since.Format(time.RFC3339))
+			}
+
+			// Skip files owned by other authors
+			byAuthor, err := w.byAuthor(relPath)
 			if err != nil {
 				return err
 			}
+			if !byAuthor {
+				return skip(fmt.Sprintf("not by author %q", w.Author))
+			}
+
+			// Skip well-known binary formats without opening them, unless
+			// the file could be a Git LFS pointer that's wanted
+			binaryName := !explicit && hasBinaryExtension(relPath)
+			if binaryName && (w.LFS == "" || w.LFS == LFSSkip || info.Size() > lfsPointerMax) {
+				return skip("binary")
+			}
+
+			if w.MinBytes > 0 && !explicit && info.Size() < w.MinBytes {
+				return skip(fmt.Sprintf("smaller than --min-bytes (%d bytes)", info.Size()))
+			}
+
+			// Skip files the repository marks as vendored or generated
+			generated, vendored := w.linguist(relPath)
+			if !w.IncludeVendored && !explicit && vendored.state == attrSet {
+				return skip(fmt.Sprintf("marked %s by %s (use --include-vendored)", attrVendored, vendored.source))
+			}
+			if !w.IncludeGenerated && !explicit && generated.state == attrSet {
+				return skip(fmt.Sprintf("marked %s by %s (use --include-generated)", attrGenerated, generated.source))
+			}
+
+			// Stop before reading more than the size limit allows
+			if w.MaxBytes > 0 && w.read+info.Size() > w.MaxBytes {
+				return SizeLimitError{Limit: w.MaxBytes, Path: filepath.ToSlash(relPath)}
+			}
 
-			files = append(files, File{
-				Path:    relPath,
+			// Read file content, skipping binary files
+			readStart := time.Now()
+			binary, content, err := w.readFile(path, relPath, info)
+			if w.Timings != nil {
+				w.Timings.Reading.Add(readStart)
+				w.Timings.Bytes += int64(len(content))
+			}
+			if err != nil {
+				return w.unreadable(relPath, err)
+			}
+			if binary {
+				return skip("binary")
+			}
+			w.read += int64(len(content))
+			if w.OnRead != nil {
+				w.OnRead(filepath.ToSlash(relPath), len(content))
+			}
+
+			// Git LFS pointers stand in for content stored elsewhere
+			if size, ok := parseLFSPointer(content); ok {
+				switch w.LFS {
+				case LFSPointer:
+					content = lfsNote(content, size)
+				case LFSSmudge:
+					object, err := lfsSmudge(w.ctx, filepath.Dir(realPath), content)
+					if err != nil {
+						return w.unreadable(relPath, err)
+					}
+					if isBinaryContent(object) {
+						return skip(fmt.Sprintf("binary Git LFS object (%s)", FormatBytes(size)))
+					}
+					content = object
+				default:
+					return skip(fmt.Sprintf("Git LFS pointer to a %s object (use --lfs)", FormatBytes(size)))
+				}
+			} else if binaryName {
+				return skip("binary")
+			}
+
+			// Skip files with nothing in them but a header's worth of noise
+			if !w.IncludeEmpty && !explicit && len(bytes.TrimSpace(content)) == 0 {
+				if len(content) == 0 {
+					return skip("empty (use --include-empty)")
+				}
+				return skip("whitespace only (use --include-empty)")
+			}
+
+			// Skip generated code, unless the repository says it isn't
+			if !w.IncludeGenerated && !explicit && generated.state != attrUnset && isGenerated(relPath, content) {
+				return skip("generated code (use --include-generated)")
+			}
+
+			// Skip files that ask to be left out
+			if !w.NoDirectives && !explicit && ParseDirectives(content).Ignore {
+				return skip("gopack:ignore directive (use --no-directives)")
+			}
+
+			// Skip Go files built only for other platforms or tags
+			if w.GoBuild != nil && !explicit && !matchesGoBuild(*w.GoBuild, relPath, content) {
+				return skip(fmt.Sprintf("not built for %s/%s (--go-tags)", w.GoBuild.GOOS, w.GoBuild.GOARCH))
+			}
+
+			if w.MaxFiles > 0 && len(*files) >= w.MaxFiles {
+				return FileLimitError{Limit: w.MaxFiles, Path: filepath.ToSlash(relPath)}
+			}
+			// Paths are written in NFC, however the file system spells them
+			if t.external {
+				relPath = filepath.Join(ExternalPrefix, relPath)
+			}
+			file := File{
+				Path:    NormalizePath(relPath),
 				Content: content,
-			})
+				ModTime: info.ModTime(),
+			}
+			if w.FileModes {
+				file.Mode = info.Mode().Perm()
+			}
+			*files = append(*files, file)
+			if w.OnFile != nil {
+				w.OnFile(file)
+			}
 		}
 
 		return nil
 	})
+}
 
-	return files, err
+// skip reports an excluded path to OnSkip.
+func (w *Walker) skip(relPath, reason string) {
+	if w.OnSkip != nil {
+		w.OnSkip(filepath.ToSlash(relPath), reason)
+	}
 }
 
-// loadGitignore loads patterns from a .gitignore file in the directory.
-func (w *Walker) loadGitignore(dirPath string) {
-	gitignorePath := filepath.Join(dirPath, ".gitignore")
-	file, err := os.Open(gitignorePath)
+// Explain walks the tree and describes why path is or isn't included in
+// the pack, naming the rule responsible.
+func (w *Walker) Explain(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if w.fsys != nil {
+		absPath, err = filepath.FromSlash(fsPath(path)), nil
+	}
 	if err != nil {
-		return // .gitignore doesn't exist or can't be read
+		return "", err
 	}
-	defer file.Close()
+	if _, err := w.lstat(absPath); err != nil {
+		return "does not exist", nil
+	}
+	if !isWithin(absPath, w.rootPath) {
+		return "not under any of the given paths", nil
+	}
+	relPath, err := filepath.Rel(w.rootPath, absPath)
+	if err != nil {
+		return "", err
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	// Record the first reason each path was skipped
+	reasons := make(map[string]string)
+	onSkip := w.OnSkip
+	w.OnSkip = func(path, reason string) {
+		if _, ok := reasons[path]; !ok {
+			reasons[path] = reason
+		}
+		if onSkip != nil {
+			onSkip(path, reason)
+		}
+	}
+	defer func() { w.OnSkip = onSkip }()
 
-	var patterns []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+	files, err := w.Walk()
+	if err != nil {
+		return "", err
+	}
+
+	for _, file := range files {
+		if filepath.ToSlash(file.Path) == relPath {
+			return w.explainIncluded(absPath, relPath), nil
 		}
-		patterns = append(patterns, line)
 	}
 
-	if len(patterns) > 0 {
-		w.patterns[dirPath] = patterns
+	// Report the path's own reason, or that of the nearest skipped parent
+	for dir := relPath; ; dir = filepath.ToSlash(filepath.Dir(dir)) {
+		if reason, ok := reasons[dir]; ok {
+			if dir == relPath {
+				return "excluded: " + reason, nil
+			}
+			return fmt.Sprintf("excluded: parent directory %s %s", dir, reason), nil
+		}
+		if dir == "." || dir == "/" {
+			break
+		}
 	}
+
+	return "not under any of the given paths", nil
 }
 
-// isIgnored checks if a path matches any gitignore patterns.
-func (w *Walker) isIgnored(relPath string) bool {
-	// Normalize path separators
-	relPath = strings.ReplaceAll(relPath, string(filepath.Separator), "/")
-	parts := strings.Split(relPath, "/")
+// explainIncluded describes which target brought an included file in.
+func (w *Walker) explainIncluded(absPath, relPath string) string {
+	noRule := "no ignore rule matched"
+	if rule, ok := w.decidingRule(relPath, false); ok && rule.negated() {
+		noRule = "re-included by " + rule.String()
+	}
 
-	// Check patterns from root directory
-	for _, pattern := range w.patterns[w.rootPath] {
-		if matchPattern(relPath, parts, pattern) {
-			return true
+	for _, t := range w.targets {
+		switch {
+		case t.glob != "" && isWithin(absPath, t.path) && t.matches(absPath, w.IgnoreCase):
+			return fmt.Sprintf("included: matched include glob %q and %s", t.arg, noRule)
+		case t.glob == "" && absPath == t.path:
+			if rule, ok := w.ignoredBy(relPath, false); ok {
+				return fmt.Sprintf("included: named explicitly (overrides %s)", rule)
+			}
+			return "included: named explicitly"
+		case t.glob == "" && isWithin(absPath, t.path):
+			return fmt.Sprintf("included: found under %q and %s", t.arg, noRule)
 		}
 	}
+	return "included"
+}
+
+// matches reports whether path satisfies the target's include glob.
+func (t target) matches(path string, ignoreCase bool) bool {
+	rel, err := filepath.Rel(t.path, path)
+	if err != nil {
+		return false
+	}
+	glob, rel := t.glob, filepath.ToSlash(rel)
+	if ignoreCase {
+		glob, rel = strings.ToLower(glob), strings.ToLower(rel)
+	}
+	return matchGlob(glob, rel)
+}
 
-	return false
+// isSubmodule reports whether dir is the working tree of an initialized git
+// submodule, which has a .git file pointing at its repository.
+func (w *Walker) isSubmodule(dir string) bool {
+	info, err := w.lstat(filepath.Join(dir, ".git"))
+	return err == nil && info.Mode().IsRegular()
 }
 
-// matchPattern checks if a path matches a gitignore pattern.
-func matchPattern(fullPath string, parts []string, pattern string) bool {
-	// Remove trailing slash from pattern
-	pattern = strings.TrimSuffix(pattern, "/")
+// isHidden reports whether a file or directory name is a dotfile.
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".") && name != "." && name != ".."
+}
 
-	// If pattern starts with /, it's relative to root
-	if strings.HasPrefix(pattern, "/") {
-		pattern = strings.TrimPrefix(pattern, "/")
-		return simpleMatch(fullPath, pattern)
+// depth returns how many levels path is below root.
+func depth(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return 0
 	}
+	return strings.Count(filepath.ToSlash(rel), "/") + 1
+}
+
+// isGlob reports whether s contains glob metacharacters.
+func isGlob(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
 
-	// Pattern can match any part of the path
-	if strings.Contains(pattern, "/") {
-		return simpleMatch(fullPath, pattern)
+// splitGlob splits a glob into its literal directory prefix and the
+// remaining pattern, e.g. "internal/**/*.go" -> ("internal", "**/*.go").
+func splitGlob(glob string) (dir, pattern string) {
+	segments := strings.Split(filepath.ToSlash(glob), "/")
+	for i, segment := range segments {
+		if isGlob(segment) {
+			dir = strings.Join(segments[:i], "/")
+			if dir == "" && i > 0 {
+				dir = "/"
+			} else if dir == "" {
+				dir = "."
+			}
+			return filepath.FromSlash(dir), strings.Join(segments[i:], "/")
+		}
 	}
+	return glob, ""
+}
 
-	// Pattern matches any path component
-	for _, part := range parts {
-		if simpleMatch(part, pattern) {
-			return true
+// commonDir returns the deepest directory containing all of the given
+// absolute directories.
+func commonDir(dirs []string) string {
+	common := dirs[0]
+	for _, dir := range dirs[1:] {
+		for !isWithin(dir, common) {
+			parent := filepath.Dir(common)
+			if parent == common {
+				break
+			}
+			common = parent
 		}
 	}
+	return common
+}
 
-	return false
+// isWithin reports whether path is dir or is located beneath it.
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
 }
 
-// simpleMatch performs a simple glob-style match.
-// Supports * (any chars) and ? (single char).
-func simpleMatch(name, pattern string) bool {
-	matched, _ := filepath.Match(pattern, name)
-	return matched
+// modifiedSince reports whether a file was modified after w.Since.
+func (w *Walker) modifiedSince(relPath string, info os.FileInfo) bool {
+	if w.Since.IsZero() {
+		return true
+	}
+	if w.SinceGit {
+		return w.recent[NormalizePath(filepath.ToSlash(relPath))]
+	}
+	return info.ModTime().After(w.Since)
 }
 
-// isBinary detects if a file is binary by reading its first 512 bytes.
-func isBinary(filePath string) bool {
-	file, err := os.Open(filePath)
+// byAuthor reports whether a file belongs to w.Author.
+func (w *Walker) byAuthor(relPath string) (bool, error) {
+	if w.Author == "" {
+		return true, nil
+	}
+	relPath = filepath.ToSlash(relPath)
+	author, tracked := w.authors[NormalizePath(relPath)]
+	if w.AuthorShare == 0 || !tracked {
+		return authorMatches(author, w.Author), nil
+	}
+
+	share, err := blameShare(w.ctx, w.rootPath, relPath, w.Author)
 	if err != nil {
-		return true // Assume binary on error
+		return false, fmt.Errorf("failed to blame %s: %w", relPath, err)
+	}
+	return share >= w.AuthorShare, nil
+}
+
+// matchGlob matches a slash-separated path against a glob pattern.
+// In addition to the filepath.Match syntax, a "**" segment matches any
+// number of path segments (including none). Both are compared in Unicode
+// NFC.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(NormalizePath(pattern), "/"), strings.Split(NormalizePath(name), "/"))
+}
+
+// matchSegments matches path segments against pattern segments.
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(pattern[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], parts[0]); !matched {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// readFile reads the file at path, relPath from the root, through the
+// cache, if any. Binary files are reported without being read in full.
+func (w *Walker) readFile(path, relPath string, info os.FileInfo) (bool, []byte, error) {
+	isBinary := isBinaryContent
+	if w.IsBinary != nil {
+		isBinary = func(head []byte) bool { return w.IsBinary(filepath.ToSlash(relPath), head) }
+	}
+	if w.Timings != nil {
+		check := isBinary
+		isBinary = func(head []byte) bool {
+			defer w.Timings.Binary.Add(time.Now())
+			return check(head)
+		}
+	}
+	if w.fsys != nil {
+		file, err := w.open(path)
+		if err != nil {
+			return false, nil, err
+		}
+		defer file.Close()
+		return readFrom(file, isBinary)
+	}
+	if w.Cache != nil && w.IsBinary == nil {
+		return w.Cache.read(path, info)
+	}
+	return readContent(path, isBinary)
+}
+
+// readContent reports whether the file at path is binary, as decided by
+// isBinary from its first 512 bytes, and if not, returns its content. The
+// file is read once: only text files are read to the end, into a buffer
+// sized from the file so large files aren't copied as it grows.
+func readContent(path string, isBinary func(head []byte) bool) (bool, []byte, error) {
+	file, err := os.Open(LongPath(path))
+	if err != nil {
+		return false, nil, err
 	}
 	defer file.Close()
+	return readFrom(file, isBinary)
+}
 
-	// Read first 512 bytes
-	buffer := make([]byte, 512)
-	n, err := io.ReadFull(file, buffer)
+// readFrom reads an open file as readContent does.
+func readFrom(file fs.File, isBinary func(head []byte) bool) (bool, []byte, error) {
+	var size int
+	if info, err := file.Stat(); err == nil && info.Size() < math.MaxInt32 {
+		size = int(info.Size())
+	}
+	buffer := make([]byte, max(size, 512)+1) // +1 to reach EOF in one read
+	n, err := io.ReadFull(file, buffer[:512])
+	if err == io.EOF {
+		return false, []byte{}, nil
+	}
 	if err != nil && err != io.ErrUnexpectedEOF {
-		return true // Assume binary on error
+		return false, nil, err
 	}
+	if isBinary(buffer[:n]) {
+		return true, nil, nil
+	}
+
+	// Read the rest, growing the buffer if the file grew since Stat
+	content := buffer[:n]
+	for {
+		if len(content) == cap(content) {
+			content = append(content, 0)[:len(content)]
+		}
+		m, err := file.Read(content[len(content):cap(content)])
+		content = content[:len(content)+m]
+		if err == io.EOF {
+			return false, content, nil
+		}
+		if err != nil {
+			return false, nil, err
+		}
+	}
+}
 
+// isBinaryContent reports whether data doesn't look like text, going by
+// its first 512 bytes.
+func isBinaryContent(data []byte) bool {
 	// Use http.DetectContentType to check if it's a text file
-	contentType := http.DetectContentType(buffer[:n])
+	contentType := http.DetectContentType(data)
 	return !strings.HasPrefix(contentType, "text/")
 }
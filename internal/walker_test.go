@@ -0,0 +1,778 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "a/b/c/main.go", true},
+		{"**/*.go", "a/b/README.md", false},
+		{"internal/**/*.go", "internal/walker.go", true},
+		{"internal/**/*.go", "internal/x/y/walker.go", true},
+		{"internal/**/*.go", "cmd/walker.go", false},
+		{"**/testdata/**", "a/testdata/b/c.txt", true},
+		{"**", "anything/at/all", true},
+		{"a/*/c", "a/b/c", true},
+		{"a/*/c", "a/b/x/c", false},
+		{"file?.txt", "file1.txt", true},
+		{"file[0-9].txt", "filex.txt", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSplitGlob(t *testing.T) {
+	tests := []struct {
+		glob        string
+		wantDir     string
+		wantPattern string
+	}{
+		{"internal/**/*.go", "internal", "**/*.go"},
+		{"*.go", ".", "*.go"},
+		{"**/*.md", ".", "**/*.md"},
+		{"a/b/c*/d.go", "a/b", "c*/d.go"},
+		{"/abs/*.go", "/abs", "*.go"},
+		{"/*.go", "/", "*.go"},
+		{"no/glob/here", "no/glob/here", ""},
+	}
+
+	for _, tt := range tests {
+		dir, pattern := splitGlob(tt.glob)
+		if dir != filepath.FromSlash(tt.wantDir) || pattern != tt.wantPattern {
+			t.Errorf("splitGlob(%q) = %q, %q; want %q, %q", tt.glob, dir, pattern, tt.wantDir, tt.wantPattern)
+		}
+	}
+}
+
+func TestWalkUnreadable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read files without permission")
+	}
+	dir := t.TempDir()
+	writeFiles(t, dir, "ok.go", "secret.go", "locked/inner.go")
+	for _, name := range []string{"secret.go", "locked"} {
+		if err := os.Chmod(filepath.Join(dir, name), 0); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Chmod(filepath.Join(dir, name), 0755) })
+	}
+
+	walker, err := NewWalker(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := walker.Walk()
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "ok.go" {
+		t.Errorf("Walk() files = %v, want only ok.go", files)
+	}
+	var unread []string
+	for _, e := range walker.ReadErrors() {
+		unread = append(unread, e.Path)
+	}
+	if want := []string{"locked", "secret.go"}; !slices.Equal(unread, want) {
+		t.Errorf("ReadErrors() = %q, want %q", unread, want)
+	}
+
+	walker.Strict = true
+	if _, err := walker.Walk(); err == nil {
+		t.Error("Walk() with Strict succeeded, want error")
+	}
+}
+
+func TestWalkSubmodules(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".gitignore":         "*.log\nbuild/\n",
+		"main.go":            "package main\n",
+		"app.log":            "x\n",
+		"lib/.git":           "gitdir: ../.git/modules/lib\n",
+		"lib/.gitignore":     "*.tmp\n",
+		"lib/lib.go":         "package lib\n",
+		"lib/debug.log":      "x\n",
+		"lib/scratch.tmp":    "x\n",
+		"lib/build/out.go":   "package build\n",
+		"nested/.git/HEAD":   "ref: refs/heads/main\n",
+		"nested/nested.go":   "package nested\n",
+		"lib/sub/.git":       "gitdir: ../../.git/modules/lib/modules/sub\n",
+		"lib/sub/sub.go":     "package sub\n",
+		"lib/sub/.gitignore": "sub.go\n",
+	})
+
+	tests := []struct {
+		submodules bool
+		want       []string
+	}{
+		{false, []string{".gitignore", "main.go", "nested/nested.go"}},
+		{true, []string{
+			".gitignore", "lib/.gitignore", "lib/build/out.go", "lib/debug.log", "lib/lib.go",
+			"lib/sub/.gitignore", "main.go", "nested/nested.go",
+		}},
+	}
+	for _, tt := range tests {
+		walker, err := NewWalker(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		walker.Submodules = tt.submodules
+		files, err := walker.Walk()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, file := range files {
+			got = append(got, filepath.ToSlash(file.Path))
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Walk(Submodules=%v) = %q, want %q", tt.submodules, got, tt.want)
+		}
+	}
+}
+
+func TestWalkVCSDirs(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"main.go":        "package main\n",
+		".git/HEAD":      "ref: refs/heads/main\n",
+		".hg/requires":   "store\n",
+		".svn/entries":   "12\n",
+		".jj/repo/store": "x\n",
+		".bzr/README":    "x\n",
+		".hgignore":      "*.orig\n",
+	})
+
+	tests := []struct {
+		name    string
+		vcsDirs []string
+		want    []string
+	}{
+		{"default", DefaultVCSDirs, []string{".hgignore", "main.go"}},
+		{"custom", []string{".git", ".jj"}, []string{".bzr/README", ".hg/requires", ".hgignore", ".svn/entries", "main.go"}},
+		{"none", nil, []string{".bzr/README", ".git/HEAD", ".hg/requires", ".hgignore", ".jj/repo/store", ".svn/entries", "main.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			walker, err := NewWalker(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			walker.VCSDirs = tt.vcsDirs
+			files, err := walker.Walk()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, file := range files {
+				got = append(got, filepath.ToSlash(file.Path))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Walk(VCSDirs=%q) = %q, want %q", tt.vcsDirs, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkMaxBytes(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a.txt": "0123456789\n", "b.txt": "0123456789\n", "c.txt": "0123456789\n"})
+
+	tests := []struct {
+		max     int64
+		wantErr string // path the walk stops at, or "" to finish
+	}{
+		{0, ""},
+		{33, ""},
+		{32, "c.txt"},
+		{5, "a.txt"},
+	}
+	for _, tt := range tests {
+		walker, err := NewWalker(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		walker.MaxBytes = tt.max
+		_, err = walker.Walk()
+
+		var limitErr SizeLimitError
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("Walk(MaxBytes=%d) error = %v", tt.max, err)
+		case tt.wantErr != "" && !errors.As(err, &limitErr):
+			t.Errorf("Walk(MaxBytes=%d) error = %v, want SizeLimitError", tt.max, err)
+		case tt.wantErr != "" && (limitErr.Path != tt.wantErr || limitErr.Limit != tt.max):
+			t.Errorf("Walk(MaxBytes=%d) = %+v, want stop at %s", tt.max, limitErr, tt.wantErr)
+		}
+	}
+}
+
+func TestWalkContext(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a.txt": "a\n", "b.txt": "b\n", "c.txt": "c\n"})
+	walker, err := NewWalker(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Canceling mid-walk keeps what was read and stops before the next path
+	ctx, cancel := context.WithCancel(context.Background())
+	walker.OnRead = func(path string, size int) { cancel() }
+	files, err := walker.WalkContext(ctx)
+	if !errors.Is(err, context.Canceled) || len(files) != 1 {
+		t.Errorf("WalkContext(canceled after a.txt) = %d files, %v; want 1 file, context.Canceled", len(files), err)
+	}
+
+	// The walker can be used again afterwards
+	walker.OnRead = nil
+	if files, err = walker.Walk(); err != nil || len(files) != 3 {
+		t.Errorf("Walk() after cancellation = %d files, %v; want 3 files", len(files), err)
+	}
+}
+
+func TestWalkMaxFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a.txt": "a\n", "b.txt": "b\n", "c.txt": "c\n"})
+
+	tests := []struct {
+		max     int
+		wantErr string // path the walk stops at, or "" to finish
+	}{
+		{0, ""},
+		{3, ""},
+		{2, "c.txt"},
+		{1, "b.txt"},
+	}
+	for _, tt := range tests {
+		walker, err := NewWalker(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		walker.MaxFiles = tt.max
+		files, err := walker.Walk()
+
+		var limitErr FileLimitError
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("Walk(MaxFiles=%d) error = %v", tt.max, err)
+		case tt.wantErr != "" && !errors.As(err, &limitErr):
+			t.Errorf("Walk(MaxFiles=%d) error = %v, want FileLimitError", tt.max, err)
+		case tt.wantErr != "" && (limitErr.Path != tt.wantErr || len(files) != tt.max):
+			t.Errorf("Walk(MaxFiles=%d) = %d files, %+v; want %d files and a stop at %s", tt.max, len(files), limitErr, tt.max, tt.wantErr)
+		}
+	}
+}
+
+func TestReadContent(t *testing.T) {
+	dir := t.TempDir()
+	large := strings.Repeat("0123456789abcdef\n", 10000)
+	writeTree(t, dir, map[string]string{
+		"empty.txt": "",
+		"small.txt": "hello\n",
+		"exact.txt": strings.Repeat("x", 512),
+		"large.txt": large,
+		"data.bin":  "\x00\x01\x02binary" + large,
+	})
+
+	tests := []struct {
+		name       string
+		wantBinary bool
+		want       string
+	}{
+		{"empty.txt", false, ""}, // text, left to IncludeEmpty
+		{"small.txt", false, "hello\n"},
+		{"exact.txt", false, strings.Repeat("x", 512)},
+		{"large.txt", false, large},
+		{"data.bin", true, ""},
+	}
+	for _, tt := range tests {
+		binary, content, err := readContent(filepath.Join(dir, tt.name), isBinaryContent)
+		if err != nil {
+			t.Fatalf("readContent(%s) error = %v", tt.name, err)
+		}
+		if binary != tt.wantBinary || string(content) != tt.want {
+			t.Errorf("readContent(%s) = %v, %d bytes; want %v, %d bytes", tt.name, binary, len(content), tt.wantBinary, len(tt.want))
+		}
+	}
+}
+
+func TestWalkEmptyAndSmallFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"assets/.gitkeep":  "",
+		"pkg/__init__.py":  "\n  \n",
+		"pkg/version.py":   "V = 1\n",
+		"pkg/models.py":    "class Model:\n    pass\n",
+		"keep/explicit.md": "",
+	})
+
+	tests := []struct {
+		includeEmpty bool
+		minBytes     int64
+		want         []string
+	}{
+		{false, 0, []string{"keep/explicit.md", "pkg/models.py", "pkg/version.py"}},
+		{true, 0, []string{"assets/.gitkeep", "keep/explicit.md", "pkg/__init__.py", "pkg/models.py", "pkg/version.py"}},
+		{true, 10, []string{"keep/explicit.md", "pkg/models.py"}},
+	}
+	for _, tt := range tests {
+		walker, err := NewWalker(filepath.Join(dir, "assets"), filepath.Join(dir, "pkg"), filepath.Join(dir, "keep", "explicit.md"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		walker.IncludeEmpty = tt.includeEmpty
+		walker.MinBytes = tt.minBytes
+		files, err := walker.Walk()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, file := range files {
+			got = append(got, filepath.ToSlash(file.Path))
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Walk(IncludeEmpty=%v, MinBytes=%d) = %q, want %q", tt.includeEmpty, tt.minBytes, got, tt.want)
+		}
+	}
+}
+
+func TestWalkSymlinkCycles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on Windows")
+	}
+	tests := []struct {
+		name        string
+		links       map[string]string // link -> target, relative to the link's directory
+		want        []string
+		wantSkipped []string
+	}{
+		{
+			name:        "link to a parent",
+			links:       map[string]string{"sub/loop": ".."},
+			want:        []string{"a/a.go", "b/b.go", "main.go", "sub/sub.go"},
+			wantSkipped: []string{"sub/loop: symlink cycle (points to a parent directory)"},
+		},
+		{
+			name:        "link to itself",
+			links:       map[string]string{"sub/self": "."},
+			want:        []string{"a/a.go", "b/b.go", "main.go", "sub/sub.go"},
+			wantSkipped: []string{"sub/self: symlink cycle (points to a parent directory)"},
+		},
+		{
+			name:        "links to each other",
+			links:       map[string]string{"a/to-b": "../b", "b/to-a": "../a"},
+			want:        []string{"a/a.go", "a/to-b/b.go", "main.go", "sub/sub.go"},
+			wantSkipped: []string{"a/to-b/to-a: symlink cycle (points to a parent directory)", "b: already walked through another path"},
+		},
+		{
+			name:        "sibling links to one directory",
+			links:       map[string]string{"link1": "sub", "link2": "sub"},
+			want:        []string{"a/a.go", "b/b.go", "link1/sub.go", "main.go"},
+			wantSkipped: []string{"link2: already walked through another path", "sub: already walked through another path"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTree(t, dir, map[string]string{
+				"main.go":    "package main\n",
+				"sub/sub.go": "package sub\n",
+				"a/a.go":     "package a\n",
+				"b/b.go":     "package b\n",
+			})
+			for link, to := range tt.links {
+				if err := os.Symlink(to, filepath.Join(dir, filepath.FromSlash(link))); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			walker, err := NewWalker(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			walker.FollowSymlinks = true
+			var skipped []string
+			walker.OnSkip = func(path, reason string) { skipped = append(skipped, path+": "+reason) }
+			files, err := walker.Walk()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, file := range files {
+				got = append(got, filepath.ToSlash(file.Path))
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Walk() = %q, want %q", got, tt.want)
+			}
+			slices.Sort(skipped)
+			if !slices.Equal(skipped, tt.wantSkipped) {
+				t.Errorf("skipped %q, want %q", skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestWalkExternalSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on Windows")
+	}
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"repo/main.go":          "package main\n",
+		"repo/lib/lib.go":       "package lib\n",
+		"shared/config.yaml":    "key: value\n",
+		"shared/nested/app.env": "A=1\n",
+	})
+	for link, to := range map[string]string{
+		"repo/config":   filepath.Join(dir, "shared"),
+		"repo/lib.link": filepath.Join(dir, "repo", "lib"),
+	} {
+		if err := os.Symlink(to, filepath.Join(dir, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		allow bool
+		want  []string
+	}{
+		{false, []string{"lib/lib.go", "main.go"}},
+		{true, []string{"@external/config/config.yaml", "@external/config/nested/app.env", "lib/lib.go", "main.go"}},
+	}
+	for _, tt := range tests {
+		walker, err := NewWalker(filepath.Join(dir, "repo"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		walker.FollowSymlinks = true
+		walker.AllowExternalSymlinks = tt.allow
+		var skipped []string
+		walker.OnSkip = func(path, reason string) { skipped = append(skipped, path+": "+reason) }
+		files, err := walker.Walk()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, file := range files {
+			got = append(got, filepath.ToSlash(file.Path))
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Walk(AllowExternalSymlinks=%v) = %q, want %q", tt.allow, got, tt.want)
+		}
+		if want := "config: symlink to outside the root (use --allow-external-symlinks)"; !tt.allow && !slices.Contains(skipped, want) {
+			t.Errorf("skipped %q, want %q", skipped, want)
+		}
+	}
+}
+
+func TestWalkFileModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("files have no executable bit on Windows")
+	}
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"run.sh":  "#!/bin/sh\necho hi\n",
+		"main.go": "package main\n",
+	})
+	if err := os.Chmod(filepath.Join(dir, "run.sh"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(dir, "main.go"), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, record := range []bool{false, true} {
+		walker, err := NewWalker(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		walker.FileModes = record
+		files, err := walker.Walk()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]os.FileMode{"run.sh": 0755, "main.go": 0640}
+		for _, file := range files {
+			if !record {
+				want[file.Path] = 0
+			}
+			if file.Mode != want[file.Path] {
+				t.Errorf("Walk(FileModes=%v): %s mode = %v, want %v", record, file.Path, file.Mode, want[file.Path])
+			}
+		}
+	}
+}
+
+func TestWalkMultipleTargets(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"cmd/main.go":     "package main\n",
+		"internal/lib.go": "package internal\n",
+		"docs/api.md":     "# API\n",
+		"docs/guide.md":   "# Guide\n",
+		"README.md":       "# Demo\n",
+	})
+
+	// Directories and single files combine into one pack, with paths
+	// relative to the directory holding them all
+	walker, err := NewWalker(filepath.Join(dir, "cmd"), filepath.Join(dir, "internal"), filepath.Join(dir, "docs", "api.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"cmd/main.go", "docs/api.md", "internal/lib.go"}
+	if got := walkedPaths(t, walker); !slices.Equal(got, want) {
+		t.Errorf("Walk() = %q, want %q", got, want)
+	}
+	if walker.Root() != dir {
+		t.Errorf("Root() = %s, want %s", walker.Root(), dir)
+	}
+}
+
+func TestWalkGlob(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".gitignore":    "src/gen/\n",
+		"src/main.go":   "package main\n",
+		"src/gen/x.go":  "package gen\n",
+		"src/README.md": "# Src\n",
+		"docs/a.md":     "# A\n",
+	})
+	t.Chdir(dir)
+
+	// A glob is rooted at the current directory, alone or not, so its
+	// ignore files apply and paths keep their prefix
+	tests := []struct {
+		paths []string
+		want  []string
+	}{
+		{[]string{"src/**/*.go"}, []string{"src/main.go"}},
+		{[]string{"src/**/*.go", "docs/a.md"}, []string{"docs/a.md", "src/main.go"}},
+	}
+	for _, tt := range tests {
+		walker, err := NewWalker(tt.paths...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := walkedPaths(t, walker); !slices.Equal(got, tt.want) {
+			t.Errorf("Walk(%q) = %q, want %q", tt.paths, got, tt.want)
+		}
+	}
+}
+
+func TestWalkExcludeRegexps(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"main.go":                 "package main\n",
+		"store_mock.go":           "package main\n",
+		"api_gen.go":              "package main\n",
+		"testdata/golden/1.txt":   "one\n",
+		"testdata/golden/new.txt": "new\n",
+		"sub/sub_mock.go":         "package sub\n",
+	})
+
+	// Expressions match the relative path, for what globs can't say
+	walker, err := NewWalker(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	walker.ExcludeRegexps = []*regexp.Regexp{regexp.MustCompile(`.*_(mock|gen)\.go$|testdata/golden/\d+`)}
+	var skipped []string
+	walker.OnSkip = func(path, reason string) { skipped = append(skipped, filepath.ToSlash(path)) }
+	want := []string{"main.go", "testdata/golden/new.txt"}
+	if got := walkedPaths(t, walker); !slices.Equal(got, want) {
+		t.Errorf("Walk() = %q, want %q", got, want)
+	}
+	slices.Sort(skipped)
+	wantSkipped := []string{"api_gen.go", "store_mock.go", "sub/sub_mock.go", "testdata/golden/1.txt"}
+	if !slices.Equal(skipped, wantSkipped) {
+		t.Errorf("skipped %q, want %q", skipped, wantSkipped)
+	}
+}
+
+func TestWalkIgnoreCase(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"README.md":     "# Demo\n",
+		"main.go":       "package main\n",
+		"Docs/Guide.MD": "# Guide\n",
+	})
+
+	tests := []struct {
+		name       string
+		target     string // relative to dir
+		ignore     []string
+		ignoreCase bool
+		want       []string
+	}{
+		{"pattern, case-sensitive", ".", []string{"readme.md"}, false, []string{"Docs/Guide.MD", "README.md", "main.go"}},
+		{"pattern, ignoring case", ".", []string{"readme.md"}, true, []string{"Docs/Guide.MD", "main.go"}},
+		{"directory pattern, ignoring case", ".", []string{"docs/"}, true, []string{"README.md", "main.go"}},
+		{"glob, case-sensitive", "**/*.md", nil, false, []string{"README.md"}},
+		{"glob, ignoring case", "**/*.md", nil, true, []string{"Docs/Guide.MD", "README.md"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			walker, err := NewWalker(filepath.Join(dir, filepath.FromSlash(tt.target)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := walker.SetRoot(dir); err != nil {
+				t.Fatal(err)
+			}
+			walker.IgnorePatterns = tt.ignore
+			walker.IgnoreCase = tt.ignoreCase
+			if got := walkedPaths(t, walker); !slices.Equal(got, tt.want) {
+				t.Errorf("Walk() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkMaxDepth(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"main.go":            "package main\n",
+		"sub/sub.go":         "package sub\n",
+		"sub/deep/deep.go":   "package deep\n",
+		"other/deep/more.go": "package deep\n",
+	})
+
+	tests := []struct {
+		maxDepth int
+		want     []string
+	}{
+		{0, []string{"main.go", "other/deep/more.go", "sub/deep/deep.go", "sub/sub.go"}},
+		{1, []string{"main.go"}},
+		{2, []string{"main.go", "sub/sub.go"}},
+		{3, []string{"main.go", "other/deep/more.go", "sub/deep/deep.go", "sub/sub.go"}},
+	}
+	for _, tt := range tests {
+		walker, err := NewWalker(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		walker.MaxDepth = tt.maxDepth
+		if got := walkedPaths(t, walker); !slices.Equal(got, tt.want) {
+			t.Errorf("Walk(MaxDepth=%d) = %q, want %q", tt.maxDepth, got, tt.want)
+		}
+	}
+
+	// The depth counts from each target, not the root they share
+	walker, err := NewWalker(filepath.Join(dir, "sub"), filepath.Join(dir, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	walker.MaxDepth = 1
+	want := []string{"main.go", "sub/sub.go"}
+	if got := walkedPaths(t, walker); !slices.Equal(got, want) {
+		t.Errorf("Walk(sub, main.go, MaxDepth=1) = %q, want %q", got, want)
+	}
+}
+
+func TestWalkSince(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"old.go": "package a\n", "new.go": "package a\n"})
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "old.go"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	// Files are compared by modification time
+	walker, err := NewWalker(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	walker.Since = time.Now().Add(-14 * 24 * time.Hour)
+	var skipped []string
+	walker.OnSkip = func(path, reason string) { skipped = append(skipped, path+": "+reason) }
+	if got, want := walkedPaths(t, walker), []string{"new.go"}; !slices.Equal(got, want) {
+		t.Errorf("Walk(Since=2w ago) = %q, want %q", got, want)
+	}
+	if len(skipped) != 1 || !strings.HasPrefix(skipped[0], "old.go: not modified since ") {
+		t.Errorf("skipped %q, want old.go as not modified", skipped)
+	}
+
+	// or by their last commit, which touching a file doesn't change
+	repo := gitRepo(t)
+	now := time.Now()
+	if err := os.Chtimes(filepath.Join(repo, "b.go"), now.Add(time.Hour), now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		since time.Time
+		want  []string
+	}{
+		{now.Add(-time.Hour), []string{"b.go", "héllo.go"}},
+		{now.Add(30 * time.Minute), nil},
+	} {
+		walker, err := NewWalker(repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		walker.Since, walker.SinceGit = tt.since, true
+		if got := walkedPaths(t, walker); !slices.Equal(got, tt.want) {
+			t.Errorf("Walk(Since=%s, SinceGit) = %q, want %q", tt.since.Format(time.Kitchen), got, tt.want)
+		}
+	}
+}
+
+func TestWalkSkipHidden(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"main.go":                  "package main\n",
+		".editorconfig":            "root = true\n",
+		".github/workflows/ci.yml": "on: push\n",
+		".git/config":              "[core]\n",
+	})
+
+	tests := []struct {
+		name       string
+		targets    []string // relative to dir
+		skipHidden bool
+		want       []string
+	}{
+		{"included", []string{"."}, false, []string{".editorconfig", ".github/workflows/ci.yml", "main.go"}},
+		{"skipped", []string{"."}, true, []string{"main.go"}},
+		{"named explicitly", []string{".github", "main.go"}, true, []string{".github/workflows/ci.yml", "main.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var targets []string
+			for _, target := range tt.targets {
+				targets = append(targets, filepath.Join(dir, target))
+			}
+			walker, err := NewWalker(targets...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			walker.SkipHidden = tt.skipHidden
+			if got := walkedPaths(t, walker); !slices.Equal(got, tt.want) {
+				t.Errorf("Walk() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
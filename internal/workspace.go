@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// WorkspaceFile is the name of a Go workspace file.
+const WorkspaceFile = "go.work"
+
+// WorkspaceModule is a module listed in a go.work file.
+type WorkspaceModule struct {
+	Dir  string // slash-separated directory, relative to the workspace
+	Path string // module path from its go.mod, or "" if unreadable
+}
+
+// Name returns the module's short name: the last element of its directory,
+// or "." for a module at the workspace root.
+func (m WorkspaceModule) Name() string {
+	return path.Base(m.Dir)
+}
+
+// ReadWorkspace returns the modules listed by the use directives of the
+// go.work file in dir.
+func ReadWorkspace(dir string) ([]WorkspaceModule, error) {
+	data, err := os.ReadFile(filepath.Join(dir, WorkspaceFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var modules []WorkspaceModule
+	var inUse bool
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "//")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		var use string
+		switch {
+		case inUse && fields[0] == ")":
+			inUse = false
+		case inUse:
+			use = fields[0]
+		case fields[0] == "use" && len(fields) > 1 && fields[1] == "(":
+			inUse = true
+		case fields[0] == "use" && len(fields) > 1:
+			use = fields[1]
+		}
+		if use == "" {
+			continue
+		}
+
+		use = path.Clean(filepath.ToSlash(strings.Trim(use, "\"`")))
+		modules = append(modules, WorkspaceModule{Dir: use, Path: modulePath(filepath.Join(dir, use))})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return modules, nil
+}
+
+// SelectModules picks modules by name, matching each against a module's
+// short name, directory, or module path, in the order the names are given.
+func SelectModules(modules []WorkspaceModule, names []string) ([]WorkspaceModule, error) {
+	var selected []WorkspaceModule
+	for _, name := range names {
+		name = strings.TrimSuffix(strings.TrimPrefix(name, "./"), "/")
+		found := false
+		for _, module := range modules {
+			if name == module.Name() || name == module.Dir || name == module.Path {
+				selected = append(selected, module)
+				found = true
+				break
+			}
+		}
+		if !found {
+			var available []string
+			for _, module := range modules {
+				available = append(available, module.Name())
+			}
+			return nil, fmt.Errorf("no module %q in %s (available: %s)", name, WorkspaceFile, strings.Join(available, ", "))
+		}
+	}
+	return selected, nil
+}
+
+// modulePath reads the module path from the go.mod file in dir.
+func modulePath(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	return parseModulePath(data)
+}
+
+// parseModulePath reads the module path from the contents of a go.mod file.
+func parseModulePath(data []byte) string {
+	for _, line := range strings.Split(string(data), "\n") {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "module"); ok {
+			return strings.Trim(strings.TrimSpace(rest), "\"`")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReadWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"go.work": `go 1.22
+
+use (
+	./services/api // the public API
+	./worker
+	"./tools"
+)
+
+use ./lib
+
+replace example.com/x => ./x
+`,
+		"services/api/go.mod": "module example.com/api\n\ngo 1.22\n",
+		"worker/go.mod":       "module \"example.com/worker\"\n",
+		"lib/go.mod":          "// shared code\nmodule example.com/lib\n",
+	})
+
+	modules, err := ReadWorkspace(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []WorkspaceModule{
+		{Dir: "services/api", Path: "example.com/api"},
+		{Dir: "worker", Path: "example.com/worker"},
+		{Dir: "tools"},
+		{Dir: "lib", Path: "example.com/lib"},
+	}
+	if !reflect.DeepEqual(modules, want) {
+		t.Fatalf("ReadWorkspace() = %+v, want %+v", modules, want)
+	}
+
+	tests := []struct {
+		names []string
+		want  []string
+	}{
+		{[]string{"api", "worker"}, []string{"services/api", "worker"}},
+		{[]string{"example.com/lib", "./services/api/"}, []string{"lib", "services/api"}},
+		{[]string{"tools"}, []string{"tools"}},
+	}
+	for _, tt := range tests {
+		selected, err := SelectModules(modules, tt.names)
+		if err != nil {
+			t.Fatalf("SelectModules(%q) error = %v", tt.names, err)
+		}
+		var dirs []string
+		for _, module := range selected {
+			dirs = append(dirs, module.Dir)
+		}
+		if !reflect.DeepEqual(dirs, tt.want) {
+			t.Errorf("SelectModules(%q) = %q, want %q", tt.names, dirs, tt.want)
+		}
+	}
+
+	if _, err := SelectModules(modules, []string{"billing"}); err == nil {
+		t.Error("SelectModules(billing) succeeded, want error")
+	}
+	if _, err := ReadWorkspace(t.TempDir()); err == nil {
+		t.Error("ReadWorkspace() without go.work succeeded, want error")
+	}
+}
@@ -0,0 +1,181 @@
+// Package pack reads the packs gopack writes, so other tools can consume
+// them without depending on the output format, and makes packs from any
+// file system, such as an archive or an in-memory tree.
+package pack
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"slices"
+
+	"gopack/internal"
+)
+
+// Pack is a parsed pack.
+type Pack struct {
+	// Format is the format the pack was written in: "text", "markdown",
+	// "html", or "jsonl".
+	Format string `json:"format"`
+
+	// Files holds the packed files in order, with pieces of files split
+	// across parts joined and dedupe references replaced by the content
+	// they refer to.
+	Files []File `json:"files"`
+}
+
+// File is a packed file.
+type File struct {
+	Path    string `json:"path"` // relative, slash-separated
+	Content string `json:"content"`
+}
+
+// Parse reads a pack in any of gopack's formats, gzip or zstd compressed
+// or not, and returns its files. Markdown packs end every file with a
+// newline. zstd needs the zstd command installed.
+func Parse(r io.Reader) (*Pack, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if compression := internal.SniffCompression(data); compression != "" {
+		if data, err = internal.Decompress(data, compression); err != nil {
+			return nil, fmt.Errorf("failed to decompress pack: %w", err)
+		}
+	}
+
+	files, err := internal.ParsePack(data)
+	if err != nil {
+		return nil, err
+	}
+	pack := &Pack{Format: internal.PackFormat(data), Files: make([]File, len(files))}
+	for i, file := range files {
+		pack.Files[i] = File{Path: file.Path, Content: string(file.Content)}
+	}
+	return pack, nil
+}
+
+// FromFS packs the text files in fsys as gopack packs a directory: files
+// matched by .gitignore, .gopackignore, or the built-in defaults are left
+// out, and so are binary, empty, and generated files. Paths limit the pack
+// to directories, files, or include globs such as "internal/**/*.go",
+// slash-separated and relative to the root of fsys; with none all of fsys
+// is packed. The pack's Format is "text". To change how files are chosen,
+// use a Walker.
+func FromFS(fsys fs.FS, paths ...string) (*Pack, error) {
+	return Walker{Paths: paths}.Walk(fsys)
+}
+
+// Walker chooses the files to pack as FromFS does, with the parts it
+// decides by swapped out. The zero Walker behaves like FromFS.
+type Walker struct {
+	// Paths limit the pack as FromFS's paths do.
+	Paths []string
+
+	// IgnoreFiles names the files in each directory that ignore patterns
+	// are read from, later ones taking precedence; nil means .gitignore and
+	// .gopackignore. Files named .dockerignore follow Docker's rules.
+	IgnoreFiles []string
+
+	// IgnorePatterns are gitignore-style patterns applied from the root,
+	// in addition to the ignore files, such as patterns kept elsewhere.
+	IgnorePatterns []string
+
+	// NoDefaultIgnores packs the files gopack leaves out by default, such
+	// as lockfiles and minified assets.
+	NoDefaultIgnores bool
+
+	// IsBinary, if set, decides whether a file is binary, and left out,
+	// from its slash-separated path and the first 512 bytes of its content,
+	// in place of the check that the content looks like text. Files with a
+	// binary extension, such as .png, are still left out unread.
+	IsBinary func(path string, head []byte) bool
+}
+
+// Walk packs the text files in fsys. The pack's Format is "text".
+func (w Walker) Walk(fsys fs.FS) (*Pack, error) {
+	walker, err := internal.NewFSWalker(fsys, w.Paths...)
+	if err != nil {
+		return nil, err
+	}
+	if w.IgnoreFiles != nil {
+		walker.IgnoreFiles = w.IgnoreFiles
+	}
+	walker.IgnorePatterns = w.IgnorePatterns
+	if w.NoDefaultIgnores {
+		walker.DefaultIgnores = nil
+	}
+	walker.IsBinary = w.IsBinary
+
+	files, err := walker.Walk()
+	if err != nil {
+		return nil, err
+	}
+	pack := &Pack{Format: internal.FormatText, Files: make([]File, len(files))}
+	for i, file := range files {
+		pack.Files[i] = File{Path: filepath.ToSlash(file.Path), Content: string(file.Content)}
+	}
+	return pack, nil
+}
+
+// WriteTo writes the pack to w in its Format, as gopack would.
+func (p *Pack) WriteTo(w io.Writer) (int64, error) {
+	return Formatter{Format: p.Format}.Write(w, p)
+}
+
+// Formatter writes packs, with the parts it decides by swapped out. The
+// zero Formatter writes text packs.
+type Formatter struct {
+	// Format is the format to write: "text", "markdown", "html", or
+	// "jsonl"; empty means "text".
+	Format string
+
+	// Summary appends a section with the file count, line count, and
+	// tokens.
+	Summary bool
+
+	// Tokenizer, if set, counts the tokens of text for the summary and
+	// Tokens, such as with a model's own tokenizer, in place of gopack's
+	// estimate of a token per four bytes.
+	Tokenizer func(text string) int
+}
+
+// Write writes the files of p to w as the Formatter is set up, ignoring
+// p's Format.
+func (f Formatter) Write(w io.Writer, p *Pack) (int64, error) {
+	formatter, err := f.formatter(p)
+	if err != nil {
+		return 0, err
+	}
+	return formatter.WriteTo(w)
+}
+
+// Tokens returns the tokens of p written as the Formatter is set up.
+func (f Formatter) Tokens(p *Pack) (int, error) {
+	formatter, err := f.formatter(p)
+	if err != nil {
+		return 0, err
+	}
+	return formatter.TokenCount(), nil
+}
+
+// formatter sets up the internal formatter for p.
+func (f Formatter) formatter(p *Pack) (*internal.Formatter, error) {
+	format := f.Format
+	if format == "" {
+		format = internal.FormatText
+	}
+	if !slices.Contains(internal.Formats, format) {
+		return nil, fmt.Errorf("unknown format %q", f.Format)
+	}
+	files := make([]internal.File, len(p.Files))
+	for i, file := range p.Files {
+		files[i] = internal.File{Path: file.Path, Content: []byte(file.Content)}
+	}
+	formatter := internal.NewFormatter(files)
+	formatter.OutputFormat = format
+	formatter.Summary = f.Summary
+	formatter.Tokenizer = f.Tokenizer
+	return formatter, nil
+}
@@ -0,0 +1,147 @@
+package pack
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParse(t *testing.T) {
+	markdown := "## File: main.go\n\n```go\npackage main\n```\n\n## File: docs/a.md\n\n````markdown\n```sh\nls\n```\n````\n"
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte(markdown))
+	writer.Close()
+
+	tests := []struct {
+		name       string
+		input      []byte
+		wantFormat string
+	}{
+		{"markdown", []byte(markdown), "markdown"},
+		{"gzip", compressed.Bytes(), "markdown"},
+		{"text", []byte("File: main.go\npackage main\n\n\nFile: docs/a.md\n```sh\nls\n```\n"), "text"},
+	}
+	for _, tt := range tests {
+		got, err := Parse(bytes.NewReader(tt.input))
+		if err != nil {
+			t.Fatalf("Parse(%s) error = %v", tt.name, err)
+		}
+		want := []File{{"main.go", "package main\n"}, {"docs/a.md", "```sh\nls\n```\n"}}
+		if got.Format != tt.wantFormat || len(got.Files) != 2 || got.Files[0] != want[0] || got.Files[1] != want[1] {
+			t.Errorf("Parse(%s) = %+v, want %s with %+v", tt.name, got, tt.wantFormat, want)
+		}
+	}
+
+	if _, err := Parse(strings.NewReader("not a pack\n")); err == nil {
+		t.Error("Parse(not a pack) succeeded, want error")
+	}
+}
+
+func TestFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitignore":     {Data: []byte("build/\n")},
+		"main.go":        {Data: []byte("package main\n")},
+		"build/out.txt":  {Data: []byte("x\n")},
+		"docs/readme.md": {Data: []byte("# Readme\n")},
+	}
+	got, err := FromFS(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []File{{".gitignore", "build/\n"}, {"docs/readme.md", "# Readme\n"}, {"main.go", "package main\n"}}
+	if got.Format != "text" || !slices.Equal(got.Files, want) {
+		t.Errorf("FromFS() = %+v, want text with %+v", got, want)
+	}
+
+	// Written out, it parses back to the same files
+	got.Format = "markdown"
+	var buf bytes.Buffer
+	if _, err := got.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Format != "markdown" || !slices.Equal(parsed.Files, want) {
+		t.Errorf("Parse(WriteTo()) = %+v, want markdown with %+v", parsed, want)
+	}
+}
+
+func TestWalker(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitignore":  {Data: []byte("*.log\n")},
+		".packignore": {Data: []byte("docs/\n")},
+		"main.go":     {Data: []byte("package main\n")},
+		"app.log":     {Data: []byte("started\n")},
+		"docs/a.md":   {Data: []byte("# A\n")},
+		"data.bin":    {Data: []byte("MAGIC header\n")},
+		"go.sum":      {Data: []byte("example.com v1\n")},
+	}
+	isBinary := func(path string, head []byte) bool { return bytes.HasPrefix(head, []byte("MAGIC")) }
+
+	tests := []struct {
+		name   string
+		walker Walker
+		want   []string
+	}{
+		{"zero", Walker{}, []string{".gitignore", ".packignore", "data.bin", "docs/a.md", "main.go"}},
+		{"ignore files", Walker{IgnoreFiles: []string{".packignore"}}, []string{".gitignore", ".packignore", "app.log", "data.bin", "main.go"}},
+		{"ignore patterns", Walker{IgnorePatterns: []string{"*.md", ".gitignore", ".packignore"}}, []string{"data.bin", "main.go"}},
+		{"binary detector", Walker{IsBinary: isBinary}, []string{".gitignore", ".packignore", "docs/a.md", "main.go"}},
+		{"no default ignores", Walker{Paths: []string{"go.sum", "main.go"}, NoDefaultIgnores: true}, []string{"go.sum", "main.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := tt.walker.Walk(fsys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, file := range p.Files {
+				got = append(got, file.Path)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Walk() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatter(t *testing.T) {
+	p := &Pack{Format: "text", Files: []File{{"a.go", "package a\n"}, {"b.go", "package b\n"}}}
+	words := func(text string) int { return len(strings.Fields(text)) }
+
+	var buf bytes.Buffer
+	if _, err := (Formatter{Format: "markdown", Summary: true, Tokenizer: words}).Write(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Format != "markdown" || !slices.Equal(parsed.Files, p.Files) {
+		t.Errorf("Parse(Write()) = %+v, want markdown with %+v", parsed, p.Files)
+	}
+
+	tokens, err := Formatter{Tokenizer: words}.Tokens(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text bytes.Buffer
+	if _, err := p.WriteTo(&text); err != nil {
+		t.Fatal(err)
+	}
+	if want := words(text.String()); tokens != want {
+		t.Errorf("Tokens() = %d, want %d", tokens, want)
+	}
+
+	if _, err := (Formatter{Format: "yaml"}).Write(io.Discard, p); err == nil {
+		t.Error("Write() with format yaml succeeded, want error")
+	}
+}